@@ -2,11 +2,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realdialog"
@@ -28,12 +31,15 @@ func main() {
 		showVersion bool
 		debug       bool
 		formMode    bool
+
+		allowRuntimeSecurity bool
 	)
 
 	flag.StringVar(&configPath, "config", "", "Path to configuration file")
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
 	flag.BoolVar(&debug, "debug", false, "Enable debug mode with verbose PTY logging")
 	flag.BoolVar(&formMode, "form", false, "Run as TUI form helper (internal use)")
+	flag.BoolVar(&allowRuntimeSecurity, "allow-runtime-security-changes", false, "Allow shell_security_set to change the command filter at runtime")
 	flag.Parse()
 
 	if formMode {
@@ -50,10 +56,29 @@ func main() {
 
 	cfg := loadConfig(configPath, debug)
 
-	logging.Setup(cfg.Logging.Level, cfg.Logging.Sanitize)
+	logCloser, err := logging.Configure(logging.Options{
+		Level:      cfg.Logging.Level,
+		Sanitize:   cfg.Logging.Sanitize,
+		Format:     cfg.Logging.Format,
+		File:       expandHome(cfg.Logging.File),
+		MaxSizeMB:  cfg.Logging.MaxSizeMB,
+		MaxAge:     cfg.Logging.MaxAge,
+		MaxBackups: cfg.Logging.MaxBackups,
+
+		RedactPatterns: cfg.Logging.RedactPatterns,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up logging: %v\n", err)
+		os.Exit(1)
+	}
+	defer logCloser.Close()
+
 	slog.Info("starting claude-shell-mcp", slog.String("version", Version))
 
-	server := mcp.NewServer(cfg, mcp.WithConfigPath(configPath))
+	server := mcp.NewServer(cfg,
+		mcp.WithConfigPath(configPath),
+		mcp.WithRuntimeSecurityChanges(allowRuntimeSecurity),
+	)
 	watcher := setupConfigWatcher(configPath, debug, server)
 
 	sigChan := make(chan os.Signal, 1)
@@ -61,14 +86,31 @@ func main() {
 
 	go func() {
 		<-sigChan
-		slog.Info("received shutdown signal")
+		grace := server.ShutdownGracePeriod()
+		slog.Info("received shutdown signal, draining", slog.Duration("grace_period", grace))
+
+		// A second signal skips the graceful drain.
+		go func() {
+			<-sigChan
+			slog.Warn("received second shutdown signal, exiting immediately")
+			os.Exit(1)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Warn("graceful shutdown incomplete", slog.String("error", err.Error()))
+		}
+		cancel()
+
 		closeWatcher(watcher)
+		logCloser.Close()
 		os.Exit(0)
 	}()
 
 	if err := server.Run(); err != nil {
 		slog.Error("server error", slog.String("error", err.Error()))
 		closeWatcher(watcher)
+		logCloser.Close()
 		os.Exit(1)
 	}
 }
@@ -132,3 +174,15 @@ func closeWatcher(w *config.Watcher) {
 		w.Close()
 	}
 }
+
+// expandHome expands a leading "~/" to the user's home directory.
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -57,6 +57,11 @@ func (f *FS) Chtimes(name string, atime, mtime time.Time) error {
 	return os.Chtimes(name, atime, mtime)
 }
 
+// Chmod changes the mode of the named file or directory.
+func (f *FS) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
 // UserHomeDir returns the current user's home directory.
 func (f *FS) UserHomeDir() (string, error) {
 	return os.UserHomeDir()
@@ -97,6 +102,11 @@ func (f *FS) Readlink(name string) (string, error) {
 	return os.Readlink(name)
 }
 
+// ReadDir reads the named directory and returns its entries sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
 // Executable returns the path of the current executable.
 func (f *FS) Executable() (string, error) {
 	return os.Executable()
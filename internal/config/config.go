@@ -3,8 +3,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/acolita/claude-shell-mcp/internal/ports"
@@ -27,14 +31,33 @@ func DefaultConfigPath() string {
 
 // Config represents the top-level configuration.
 type Config struct {
-	Servers         []ServerConfig  `yaml:"servers"`
-	Security        SecurityConfig  `yaml:"security"`
-	Logging         LoggingConfig   `yaml:"logging"`
-	Recording       RecordingConfig `yaml:"recording"`
-	Shell           ShellConfig     `yaml:"shell"`
-	PromptDetection PromptConfig    `yaml:"prompt_detection"`
+	Servers         []ServerConfig     `yaml:"servers"`
+	Security        SecurityConfig     `yaml:"security"`
+	Logging         LoggingConfig      `yaml:"logging"`
+	Recording       RecordingConfig    `yaml:"recording"`
+	Shell           ShellConfig        `yaml:"shell"`
+	PromptDetection PromptConfig       `yaml:"prompt_detection"`
+	PTY             PTYConfig          `yaml:"pty"`
+	Output          OutputConfig       `yaml:"output"`
+	Shutdown        ShutdownConfig     `yaml:"shutdown"`
+	Transfer        TransferConfig     `yaml:"transfer"`
+	Session         SessionConfig      `yaml:"session"`
+	LocalSession    LocalSessionConfig `yaml:"local_session"`
+	DirList         DirListConfig      `yaml:"dir_list"`
+
+	// OnDuplicateSession decides what shell_session_create does when an SSH
+	// session to the same user@host:port is already open: see
+	// DuplicateSession* constants.
+	OnDuplicateSession string `yaml:"on_duplicate_session"`
 }
 
+// Values for Config.OnDuplicateSession.
+const (
+	DuplicateSessionAllow = "allow" // open another session (default)
+	DuplicateSessionReuse = "reuse" // return the existing session instead
+	DuplicateSessionWarn  = "warn"  // open another session and report the existing ones
+)
+
 // ServerConfig defines an SSH server connection.
 type ServerConfig struct {
 	Name            string     `yaml:"name"`
@@ -44,14 +67,119 @@ type ServerConfig struct {
 	KeyPath         string     `yaml:"key_path"`
 	Auth            AuthConfig `yaml:"auth"`
 	SudoPasswordEnv string     `yaml:"sudo_password_env"` // env var containing sudo password
+
+	// Identities are alternative named credentials for this server, e.g. a
+	// personal and a deploy key. shell_session_create picks one with its
+	// identity parameter; without it Auth is used.
+	Identities []IdentityConfig `yaml:"identities"`
+
+	// CommandWrapper wraps every command run on this server, e.g.
+	// "nice -n 19 {{cmd}}" or "timeout 600 {{cmd}}". {{cmd}} is replaced by
+	// the command run through bash -c, so pipes and lists stay inside it.
+	CommandWrapper string `yaml:"command_wrapper"`
+
+	// ForwardX11 forwards X11 from every session on this server to the
+	// local DISPLAY, like ssh -X. Needs a local X server.
+	ForwardX11 bool `yaml:"forward_x11"`
+
+	// Proxy routes the SSH connection through an HTTP CONNECT or SOCKS5
+	// proxy, for networks where outbound SSH must go through one.
+	Proxy ProxyConfig `yaml:"proxy"`
+}
+
+// IdentityConfig is a named set of credentials for a server.
+type IdentityConfig struct {
+	Name       string `yaml:"name"`
+	User       string `yaml:"user"` // login user when the session does not name one (optional)
+	AuthConfig `yaml:",inline"`
+}
+
+// IdentityNames returns the names of the server's identities.
+func (s ServerConfig) IdentityNames() []string {
+	names := make([]string, len(s.Identities))
+	for i, id := range s.Identities {
+		names[i] = id.Name
+	}
+	return names
+}
+
+// Identity returns the named identity, or false if the server has none by
+// that name.
+func (s ServerConfig) Identity(name string) (IdentityConfig, bool) {
+	for _, id := range s.Identities {
+		if id.Name == name {
+			return id, true
+		}
+	}
+	return IdentityConfig{}, false
+}
+
+// AuthFor returns the credentials of the named identity, or Auth when
+// identity is empty.
+func (s ServerConfig) AuthFor(identity string) (AuthConfig, error) {
+	if identity == "" {
+		return s.Auth, nil
+	}
+	id, ok := s.Identity(identity)
+	if !ok {
+		return AuthConfig{}, fmt.Errorf("server %q has no identity %q (available: %s)",
+			s.Name, identity, strings.Join(s.IdentityNames(), ", "))
+	}
+	return id.AuthConfig, nil
+}
+
+// ProxyConfig is an HTTP CONNECT or SOCKS5 proxy for SSH connections.
+type ProxyConfig struct {
+	// URL is the proxy's scheme and address, e.g. "http://proxy.corp:3128"
+	// or "socks5://proxy.corp:1080". Empty connects directly.
+	URL         string `yaml:"url"`
+	User        string `yaml:"user"`         // proxy username (optional)
+	PasswordEnv string `yaml:"password_env"` // env var containing the proxy password
 }
 
+// Schemes of ProxyConfig.URL.
+const (
+	ProxySchemeHTTP   = "http"
+	ProxySchemeSOCKS5 = "socks5"
+)
+
+// Parse splits URL into its scheme and host:port address. Credentials go
+// in User and PasswordEnv, not in the URL, so it can be shown as is.
+func (p ProxyConfig) Parse() (scheme, address string, err error) {
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid proxy url %q: %w", p.URL, err)
+	}
+	if u.Scheme != ProxySchemeHTTP && u.Scheme != ProxySchemeSOCKS5 {
+		return "", "", fmt.Errorf("invalid proxy url %q: scheme must be %s or %s", p.URL, ProxySchemeHTTP, ProxySchemeSOCKS5)
+	}
+	if u.User != nil {
+		return "", "", fmt.Errorf("invalid proxy url %q: put credentials in user and password_env", u.Redacted())
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return "", "", fmt.Errorf("invalid proxy url %q: must be scheme://host:port", p.URL)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", "", fmt.Errorf("invalid proxy url %q: must not have a path", p.URL)
+	}
+	return u.Scheme, u.Host, nil
+}
+
+// CommandPlaceholder marks where ServerConfig.CommandWrapper puts the command.
+const CommandPlaceholder = "{{cmd}}"
+
 // AuthConfig defines authentication settings.
 type AuthConfig struct {
-	Type          string `yaml:"type"`           // "key" or "password"
-	Path          string `yaml:"path"`           // path to key file
-	PassphraseEnv string `yaml:"passphrase_env"` // env var containing key passphrase
-	PasswordEnv   string `yaml:"password_env"`   // env var containing SSH password
+	Type           string `yaml:"type"`             // "key" or "password"
+	Path           string `yaml:"path"`             // path to key file
+	PassphraseEnv  string `yaml:"passphrase_env"`   // env var containing key passphrase
+	PasswordEnv    string `yaml:"password_env"`     // env var containing SSH password
+	NewPasswordEnv string `yaml:"new_password_env"` // env var containing the password to set when a change is forced at login
+
+	// CertPath is an OpenSSH user certificate (e.g. ~/.ssh/id_ed25519-cert.pub)
+	// signed by a CA the servers trust, for the key at Path. It is checked
+	// against the key and its validity period when connecting.
+	CertPath string `yaml:"cert_path"`
 }
 
 // SecurityConfig defines security settings.
@@ -64,12 +192,42 @@ type SecurityConfig struct {
 	MaxAuthFailures     int           `yaml:"max_auth_failures"`     // Max failed auth attempts before lockout
 	AuthLockoutDuration time.Duration `yaml:"auth_lockout_duration"` // Duration of auth lockout
 	UseKeyring          bool          `yaml:"use_keyring"`           // Use OS keyring for credential storage
+	ReadOnly            bool          `yaml:"read_only"`             // Reject uploads, moves and mutating commands
+
+	// AutoSudoPatterns are regexes for commands that always need sudo (e.g.
+	// "^systemctl (restart|reload) "). shell_exec prefixes a matching
+	// command with sudo when a sudo password is cached or configured; a
+	// compound line runs whole as sudo sh -c.
+	AutoSudoPatterns []string `yaml:"auto_sudo_patterns"`
+
+	// Per-session quotas; 0 means unlimited. Once one is reached, the session
+	// rejects new commands until it is closed and a new one created.
+	MaxCommandsPerSession    int   `yaml:"max_commands_per_session"`
+	MaxOutputBytesPerSession int64 `yaml:"max_output_bytes_per_session"`
+
+	// SudoCacheScope decides whether a cached sudo password is injected into
+	// a password prompt: see SudoScope* constants.
+	SudoCacheScope string `yaml:"sudo_cache_scope"`
 }
 
+// Values for SecurityConfig.SudoCacheScope.
+const (
+	SudoScopeSession = "session" // trust the server's per-session sudo cache (default)
+	SudoScopeTTY     = "tty"     // ask sudo with "sudo -n true" before commands that run sudo
+)
+
 // LoggingConfig defines logging settings.
 type LoggingConfig struct {
-	Level    string `yaml:"level"`    // "debug", "info", "warn", "error"
-	Sanitize bool   `yaml:"sanitize"` // sanitize sensitive data from logs
+	Level      string        `yaml:"level"`       // "debug", "info", "warn", "error"
+	Sanitize   bool          `yaml:"sanitize"`    // sanitize sensitive data from logs
+	Format     string        `yaml:"format"`      // "json" (default) or "text"
+	File       string        `yaml:"file"`        // log file path (empty = stderr)
+	MaxSizeMB  int           `yaml:"max_size_mb"` // rotate log file after this size
+	MaxAge     time.Duration `yaml:"max_age"`     // rotate log file after this age
+	MaxBackups int           `yaml:"max_backups"` // rotated log files to keep
+
+	// RedactPatterns are extra regexes redacted from log messages and values.
+	RedactPatterns []string `yaml:"redact_patterns"`
 }
 
 // RecordingConfig defines session recording settings.
@@ -80,21 +238,255 @@ type RecordingConfig struct {
 
 // ShellConfig defines shell behavior settings.
 type ShellConfig struct {
-	SourceRC bool   `yaml:"source_rc"` // source .bashrc/.zshrc (default: true)
-	Path     string `yaml:"path"`      // custom shell path (overrides detection)
+	SourceRC        bool   `yaml:"source_rc"`        // source .bashrc/.zshrc (default: true)
+	Path            string `yaml:"path"`             // custom shell path (overrides detection)
+	NormalizePrompt bool   `yaml:"normalize_prompt"` // replace the user's prompt with "$ " at startup (default: true)
+}
+
+// PTYConfig defines terminal I/O settings.
+type PTYConfig struct {
+	ReadBufferBytes int `yaml:"read_buffer_bytes"` // bytes per PTY read (default: 4096)
+	StartupDrainMs  int `yaml:"startup_drain_ms"`  // discard shell startup output for this long (0 = 300 local, 500 SSH)
+	ReadyTimeoutMs  int `yaml:"ready_timeout_ms"`  // wait this long for a new shell to answer the readiness probe (0 = 10000)
+	PollIntervalMs  int `yaml:"poll_interval_ms"`  // how long each read of command output waits for data (0 = 100)
+
+	// ShellFallbacks are shells (paths or names looked up in PATH) tried in
+	// order when a local session's shell ($SHELL or shell.path) fails to
+	// start.
+	ShellFallbacks []string `yaml:"shell_fallbacks"`
+}
+
+// OutputConfig defines safeguards for command output.
+type OutputConfig struct {
+	RunawayBytesPerSec int           `yaml:"runaway_bytes_per_sec"` // interrupt commands producing output faster than this (on by default; 0 = off)
+	RunawayWindow      time.Duration `yaml:"runaway_window"`        // how long the rate must be sustained
+	RunawaySampleBytes int           `yaml:"runaway_sample_bytes"`  // output kept in a runaway_output result
+
+	// InteractiveExitPatterns are regexes for lines a pager or editor leaves
+	// behind when it exits (e.g. less's "(END)"). Matching lines are trimmed
+	// from the start of the next output, in addition to the built-in ones.
+	InteractiveExitPatterns []string `yaml:"interactive_exit_patterns"`
+
+	// MaxInlineFileBytes is the largest file shell_file_get returns in its
+	// result. Bigger files must be saved with local_path or fetched with
+	// shell_file_get_chunked.
+	MaxInlineFileBytes int64 `yaml:"max_inline_file_bytes"`
+
+	// SaveDir is where shell_exec saves output too large to return inline
+	// (default <cwd>/.claude-shell-mcp). If it is not writable, outputs go
+	// to the temp dir, and failing that are returned truncated.
+	SaveDir string `yaml:"save_dir"`
+
+	// StripSudoPrompts removes sudo's password prompt ("[sudo] password
+	// for ...") and the lecture it prints on first use from command output,
+	// leaving only what the command printed.
+	StripSudoPrompts bool `yaml:"strip_sudo_prompts"`
+}
+
+// SessionConfig defines what sessions keep about their shell.
+type SessionConfig struct {
+	// CaptureEnvAllowlist and CaptureEnvDenylist are regexes matched against
+	// the names of the environment variables a session captures (EnvVars,
+	// shown by shell_session_status). With an allowlist only matching
+	// variables are kept; denylisted ones are always dropped. Both empty
+	// keep everything.
+	CaptureEnvAllowlist []string `yaml:"capture_env_allowlist"`
+	CaptureEnvDenylist  []string `yaml:"capture_env_denylist"`
+
+	// AutoCaptureOnConnect selects what a new session captures from its
+	// shell before it is handed out. Each capture costs a round-trip at
+	// create time; what is skipped is captured on demand instead (env and
+	// aliases by shell_session_status detail="full", cwd by the first
+	// command).
+	AutoCaptureOnConnect AutoCaptureConfig `yaml:"auto_capture_on_connect"`
+
+	// InterruptGracePeriod is how long shell_interrupt keeps reading after
+	// Ctrl-C for the command's last output (cleanup messages, a final
+	// error), returning as soon as the shell prompt is back. 0 returns
+	// right after sending Ctrl-C.
+	InterruptGracePeriod time.Duration `yaml:"interrupt_grace_period"`
+
+	// DisablePagers are NAME=value environment variables exported in every
+	// new shell so git, systemctl, man and similar tools print their output
+	// instead of opening a pager that waits for keys. An empty list exports
+	// nothing; shell_session_create's disable_pagers=false skips them for
+	// one session.
+	DisablePagers []string `yaml:"disable_pagers"`
+
+	// AdaptiveTimeout makes a command's timeout count from its last output
+	// rather than from its start: each time new output arrives the timeout
+	// starts over, so a build that prints steadily keeps running while a
+	// command that goes silent for the whole timeout is still stopped.
+	// AdaptiveTimeoutMax bounds the total run time; it is never shorter
+	// than the command's own timeout.
+	AdaptiveTimeout    bool          `yaml:"adaptive_timeout"`
+	AdaptiveTimeoutMax time.Duration `yaml:"adaptive_timeout_max"`
+}
+
+// LocalSessionConfig defines settings for local sessions only.
+type LocalSessionConfig struct {
+	// DefaultCwd is the directory new local sessions start in instead of
+	// the server's working directory; shell_session_create's cwd overrides
+	// it. It must be absolute or start with ~.
+	DefaultCwd string `yaml:"default_cwd"`
 }
 
+// DirListConfig defines settings for shell_dir_list.
+type DirListConfig struct {
+	// Cache keeps listings on the server: a repeated shell_dir_list of the
+	// same directory in the same session within CacheTTL is answered from
+	// the cache as long as the directory's mtime has not changed. The mtime
+	// only changes when entries are added, removed or renamed, so sizes and
+	// mtimes of the entries may be up to CacheTTL old.
+	Cache    bool          `yaml:"cache"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// DefaultDirListCacheTTL is the default DirListConfig.CacheTTL.
+const DefaultDirListCacheTTL = 30 * time.Second
+
+// DefaultAdaptiveTimeoutMax is the default SessionConfig.AdaptiveTimeoutMax.
+const DefaultAdaptiveTimeoutMax = 10 * time.Minute
+
+// DefaultInterruptGracePeriod is the default SessionConfig.InterruptGracePeriod.
+const DefaultInterruptGracePeriod = time.Second
+
+// DefaultDisablePagers is the default SessionConfig.DisablePagers. An empty
+// SYSTEMD_PAGER or AWS_PAGER turns their pager off; the others page through
+// cat.
+var DefaultDisablePagers = []string{
+	"PAGER=cat",
+	"GIT_PAGER=cat",
+	"MANPAGER=cat",
+	"SYSTEMD_PAGER=",
+	"AWS_PAGER=",
+	"GH_PAGER=cat",
+	"PSQL_PAGER=cat",
+}
+
+// envNamePattern matches a valid environment variable name.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AutoCaptureConfig holds the per-item switches of
+// SessionConfig.AutoCaptureOnConnect. By default only the env of SSH and
+// container sessions is captured.
+type AutoCaptureConfig struct {
+	// Env captures the environment variables of SSH and container sessions
+	// (and, over SSH, the PTY name from SSH_TTY, which is probed on its own
+	// when Env is off).
+	Env bool `yaml:"env"`
+	// LocalEnv captures the environment variables of local sessions, which
+	// otherwise start from the server's own environment.
+	LocalEnv bool `yaml:"local_env"`
+	// Aliases captures the shell's aliases.
+	Aliases bool `yaml:"aliases"`
+	// Cwd runs pwd in the new shell. Without it an SSH session's cwd is "~"
+	// and a local one's is the server's working directory until the first
+	// command reports the real one.
+	Cwd bool `yaml:"cwd"`
+}
+
+// DefaultMaxInlineFileBytes is the default OutputConfig.MaxInlineFileBytes.
+const DefaultMaxInlineFileBytes = 1024 * 1024
+
+// ShutdownConfig defines how the server stops on SIGTERM/SIGINT.
+type ShutdownConfig struct {
+	GracePeriod time.Duration `yaml:"grace_period"` // wait this long for running commands and transfers (0 = don't wait)
+
+	// ExitOnClientDisconnect shuts the server down, closing all sessions,
+	// when the MCP client closes stdin (default: true).
+	ExitOnClientDisconnect bool `yaml:"exit_on_client_disconnect"`
+}
+
+// TransferConfig defines file transfer settings.
+type TransferConfig struct {
+	MaxConcurrentTransfers int    `yaml:"max_concurrent_transfers"` // chunked and directory transfers running at once (0 = unlimited)
+	OnLimit                string `yaml:"on_limit"`                 // what happens past the limit: see TransferLimit* constants
+
+	// TextInvalidThreshold is the fraction of bytes (0-1) that may be invalid
+	// UTF-8 before a text-encoded shell_file_get falls back to base64. Files
+	// with NUL bytes always fall back. 0 (default) tolerates no invalid bytes.
+	TextInvalidThreshold float64 `yaml:"text_invalid_threshold"`
+
+	// AllowControlChars lets a text-encoded shell_file_put write content
+	// with NUL, other control characters or invalid UTF-8 instead of
+	// rejecting it in favor of base64.
+	AllowControlChars bool `yaml:"allow_control_chars"`
+
+	// Umask masks the permissions of files that shell_file_put and
+	// shell_file_put_chunked create without an explicit mode, as an octal
+	// string like a shell umask (default "022", giving 0644).
+	Umask string `yaml:"umask"`
+
+	// CleanupManifestOnComplete removes the .transfer manifest of a chunked
+	// transfer once all of its chunks are done. Incomplete transfers keep
+	// theirs for shell_transfer_resume.
+	CleanupManifestOnComplete bool `yaml:"cleanup_manifest_on_complete"`
+
+	// AuditDir, when set, receives a JSON audit record for every completed
+	// file or directory transfer: source, destination, size, SHA-256,
+	// start/end time and the sessions involved. Records never contain file
+	// content or credentials.
+	AuditDir string `yaml:"audit_dir"`
+
+	// AuditSigningKeyEnv names an environment variable holding a key used to
+	// sign audit records with HMAC-SHA256. Records are unsigned when empty.
+	AuditSigningKeyEnv string `yaml:"audit_signing_key_env"`
+}
+
+// DefaultUmask is the default TransferConfig.Umask.
+const DefaultUmask = "022"
+
+// FileMode returns the permissions for a new file under Umask.
+func (t TransferConfig) FileMode() os.FileMode {
+	umask := t.Umask
+	if umask == "" {
+		umask = DefaultUmask
+	}
+	mask, err := ParseOctalMode(umask)
+	if err != nil {
+		mask, _ = ParseOctalMode(DefaultUmask)
+	}
+	return 0666 &^ mask
+}
+
+// ParseOctalMode parses permission bits written in octal, such as "0644"
+// or "022".
+func ParseOctalMode(s string) (os.FileMode, error) {
+	var mode uint32
+	if _, err := fmt.Sscanf(s, "%o", &mode); err != nil {
+		return 0, err
+	}
+	return os.FileMode(mode), nil
+}
+
+// Values for TransferConfig.OnLimit.
+const (
+	TransferLimitQueue  = "queue"  // wait for a running transfer to finish (default)
+	TransferLimitReject = "reject" // fail the tool call immediately
+)
+
 // PromptConfig defines prompt detection settings.
 type PromptConfig struct {
 	CustomPatterns []PatternConfig `yaml:"custom_patterns"`
+	InputTimeout   time.Duration   `yaml:"input_timeout"` // auto-interrupt unanswered prompts (0 = never)
+	OnAmbiguous    string          `yaml:"on_ambiguous"`  // quiet output with no recognized prompt: see Ambiguous* constants
 }
 
+// Values for PromptConfig.OnAmbiguous.
+const (
+	AmbiguousAssumeRunning = "assume_running" // keep waiting for output or the timeout (default)
+	AmbiguousAssumePrompt  = "assume_prompt"  // report awaiting_input
+	AmbiguousReturnPartial = "return_partial" // return the output so far with status "indeterminate"
+)
+
 // PatternConfig defines a custom prompt pattern.
 type PatternConfig struct {
 	Name      string `yaml:"name"`
 	Regex     string `yaml:"regex"`
 	Type      string `yaml:"type"`       // "password", "confirmation", "text"
 	MaskInput bool   `yaml:"mask_input"` // mask input in logs
+	Priority  int    `yaml:"priority"`   // higher is tried first; built-ins are 0
 }
 
 // DefaultConfig returns the default configuration.
@@ -104,14 +496,55 @@ func DefaultConfig() *Config {
 			SudoCacheTTL:       5 * time.Minute,
 			IdleTimeout:        30 * time.Minute,
 			MaxSessionsPerUser: 10,
+			SudoCacheScope:     SudoScopeSession,
 		},
 		Logging: LoggingConfig{
-			Level:    "info",
-			Sanitize: true,
+			Level:      "info",
+			Sanitize:   true,
+			Format:     "json",
+			MaxSizeMB:  10,
+			MaxAge:     7 * 24 * time.Hour,
+			MaxBackups: 5,
 		},
 		Shell: ShellConfig{
-			SourceRC: true, // Source shell rc files by default
+			SourceRC:        true, // Source shell rc files by default
+			NormalizePrompt: true,
+		},
+		PromptDetection: PromptConfig{
+			InputTimeout: 10 * time.Minute,
+			OnAmbiguous:  AmbiguousAssumeRunning,
+		},
+		PTY: PTYConfig{
+			ReadBufferBytes: 4096,
+			ShellFallbacks:  []string{"bash", "sh"},
+		},
+		Output: OutputConfig{
+			RunawayBytesPerSec: 4 * 1024 * 1024,
+			RunawayWindow:      5 * time.Second,
+			RunawaySampleBytes: 4096,
+			MaxInlineFileBytes: DefaultMaxInlineFileBytes,
+			StripSudoPrompts:   true,
 		},
+		Shutdown: ShutdownConfig{
+			GracePeriod:            30 * time.Second,
+			ExitOnClientDisconnect: true,
+		},
+		Transfer: TransferConfig{
+			MaxConcurrentTransfers:    4,
+			OnLimit:                   TransferLimitQueue,
+			Umask:                     DefaultUmask,
+			CleanupManifestOnComplete: true,
+		},
+		Session: SessionConfig{
+			AutoCaptureOnConnect: AutoCaptureConfig{Env: true},
+			InterruptGracePeriod: DefaultInterruptGracePeriod,
+			DisablePagers:        slices.Clone(DefaultDisablePagers),
+			AdaptiveTimeoutMax:   DefaultAdaptiveTimeoutMax,
+		},
+		DirList: DirListConfig{
+			CacheTTL: DefaultDirListCacheTTL,
+		},
+		OnDuplicateSession: DuplicateSessionAllow,
 	}
 }
 
@@ -151,6 +584,168 @@ func (c *Config) Validate() error {
 	if c.Security.MaxSessionsPerUser <= 0 {
 		c.Security.MaxSessionsPerUser = 10
 	}
+	if c.PTY.ReadBufferBytes <= 0 {
+		c.PTY.ReadBufferBytes = 4096
+	}
+	if c.PTY.StartupDrainMs < 0 {
+		c.PTY.StartupDrainMs = 0
+	}
+	if c.PTY.ReadyTimeoutMs < 0 {
+		c.PTY.ReadyTimeoutMs = 0
+	}
+	if c.PTY.PollIntervalMs < 0 {
+		c.PTY.PollIntervalMs = 0
+	}
+	if c.Output.RunawayBytesPerSec < 0 {
+		c.Output.RunawayBytesPerSec = 0
+	}
+	if c.Output.RunawayWindow <= 0 {
+		c.Output.RunawayWindow = 5 * time.Second
+	}
+	if c.Output.RunawaySampleBytes <= 0 {
+		c.Output.RunawaySampleBytes = 4096
+	}
+	if c.Output.MaxInlineFileBytes <= 0 {
+		c.Output.MaxInlineFileBytes = DefaultMaxInlineFileBytes
+	}
+	if c.Shutdown.GracePeriod < 0 {
+		c.Shutdown.GracePeriod = 0
+	}
+	if c.Session.InterruptGracePeriod < 0 {
+		c.Session.InterruptGracePeriod = 0
+	}
+	if c.Session.AdaptiveTimeoutMax <= 0 {
+		c.Session.AdaptiveTimeoutMax = DefaultAdaptiveTimeoutMax
+	}
+	if c.DirList.CacheTTL <= 0 {
+		c.DirList.CacheTTL = DefaultDirListCacheTTL
+	}
+	for _, assignment := range c.Session.DisablePagers {
+		if name, _, ok := strings.Cut(assignment, "="); !ok || !envNamePattern.MatchString(name) {
+			return fmt.Errorf("session.disable_pagers entries must be NAME=value, got %q", assignment)
+		}
+	}
+	if cwd := c.LocalSession.DefaultCwd; cwd != "" && !strings.HasPrefix(cwd, "/") && cwd != "~" && !strings.HasPrefix(cwd, "~/") {
+		return fmt.Errorf("local_session.default_cwd must be absolute or start with ~, got %q", cwd)
+	}
+
+	switch c.OnDuplicateSession {
+	case "":
+		c.OnDuplicateSession = DuplicateSessionAllow
+	case DuplicateSessionAllow, DuplicateSessionReuse, DuplicateSessionWarn:
+	default:
+		return fmt.Errorf("on_duplicate_session must be %q, %q or %q, got %q",
+			DuplicateSessionAllow, DuplicateSessionReuse, DuplicateSessionWarn, c.OnDuplicateSession)
+	}
+
+	switch c.PromptDetection.OnAmbiguous {
+	case "":
+		c.PromptDetection.OnAmbiguous = AmbiguousAssumeRunning
+	case AmbiguousAssumeRunning, AmbiguousAssumePrompt, AmbiguousReturnPartial:
+	default:
+		return fmt.Errorf("prompt_detection.on_ambiguous must be %q, %q or %q, got %q",
+			AmbiguousAssumeRunning, AmbiguousAssumePrompt, AmbiguousReturnPartial, c.PromptDetection.OnAmbiguous)
+	}
+
+	if c.Transfer.MaxConcurrentTransfers < 0 {
+		c.Transfer.MaxConcurrentTransfers = 0
+	}
+	switch c.Transfer.OnLimit {
+	case "":
+		c.Transfer.OnLimit = TransferLimitQueue
+	case TransferLimitQueue, TransferLimitReject:
+	default:
+		return fmt.Errorf("transfer.on_limit must be %q or %q, got %q",
+			TransferLimitQueue, TransferLimitReject, c.Transfer.OnLimit)
+	}
+	if c.Transfer.Umask == "" {
+		c.Transfer.Umask = DefaultUmask
+	}
+	if mask, err := ParseOctalMode(c.Transfer.Umask); err != nil || mask > 0777 {
+		return fmt.Errorf("transfer.umask must be an octal mask between 000 and 777, got %q", c.Transfer.Umask)
+	}
+	if c.Transfer.TextInvalidThreshold < 0 || c.Transfer.TextInvalidThreshold > 1 {
+		return fmt.Errorf("transfer.text_invalid_threshold must be between 0 and 1, got %v", c.Transfer.TextInvalidThreshold)
+	}
+
+	for i, srv := range c.Servers {
+		if srv.CommandWrapper != "" && !strings.Contains(srv.CommandWrapper, CommandPlaceholder) {
+			return fmt.Errorf("servers[%d] (%s): command_wrapper must contain %s, got %q",
+				i, srv.Name, CommandPlaceholder, srv.CommandWrapper)
+		}
+		if srv.Proxy.URL != "" {
+			if _, _, err := srv.Proxy.Parse(); err != nil {
+				return fmt.Errorf("servers[%d] (%s): %w", i, srv.Name, err)
+			}
+		}
+		if srv.Auth.CertPath != "" && srv.Auth.Path == "" && srv.KeyPath == "" {
+			return fmt.Errorf("servers[%d] (%s): auth.cert_path needs the certificate's private key in auth.path", i, srv.Name)
+		}
+		seen := make(map[string]bool, len(srv.Identities))
+		for j, id := range srv.Identities {
+			if id.Name == "" {
+				return fmt.Errorf("servers[%d] (%s): identities[%d] needs a name", i, srv.Name, j)
+			}
+			if id.CertPath != "" && id.Path == "" && srv.KeyPath == "" {
+				return fmt.Errorf("servers[%d] (%s): identity %q: cert_path needs the certificate's private key in path", i, srv.Name, id.Name)
+			}
+			if seen[id.Name] {
+				return fmt.Errorf("servers[%d] (%s): duplicate identity %q", i, srv.Name, id.Name)
+			}
+			seen[id.Name] = true
+		}
+	}
+
+	switch c.Logging.Format {
+	case "", "json", "text":
+	default:
+		return fmt.Errorf("logging.format must be \"json\" or \"text\", got %q", c.Logging.Format)
+	}
+
+	if c.Security.MaxCommandsPerSession < 0 {
+		c.Security.MaxCommandsPerSession = 0
+	}
+	if c.Security.MaxOutputBytesPerSession < 0 {
+		c.Security.MaxOutputBytesPerSession = 0
+	}
+
+	switch c.Security.SudoCacheScope {
+	case "":
+		c.Security.SudoCacheScope = SudoScopeSession
+	case SudoScopeSession, SudoScopeTTY:
+	default:
+		return fmt.Errorf("security.sudo_cache_scope must be %q or %q, got %q",
+			SudoScopeSession, SudoScopeTTY, c.Security.SudoCacheScope)
+	}
+
+	for _, expr := range c.Security.AutoSudoPatterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("security.auto_sudo_patterns: invalid regex %q: %w", expr, err)
+		}
+	}
+
+	for _, expr := range c.Logging.RedactPatterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("logging.redact_patterns: invalid regex %q: %w", expr, err)
+		}
+	}
+
+	for _, expr := range c.Output.InteractiveExitPatterns {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("output.interactive_exit_patterns: invalid regex %q: %w", expr, err)
+		}
+	}
+
+	for _, expr := range c.Session.CaptureEnvAllowlist {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("session.capture_env_allowlist: invalid regex %q: %w", expr, err)
+		}
+	}
+	for _, expr := range c.Session.CaptureEnvDenylist {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("session.capture_env_denylist: invalid regex %q: %w", expr, err)
+		}
+	}
 
 	return nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -30,6 +32,21 @@ func TestDefaultConfig(t *testing.T) {
 	if !cfg.Shell.SourceRC {
 		t.Error("Shell.SourceRC = false, want true")
 	}
+	if !cfg.Shell.NormalizePrompt {
+		t.Error("Shell.NormalizePrompt = false, want true")
+	}
+	if cfg.PromptDetection.InputTimeout != 10*time.Minute {
+		t.Errorf("PromptDetection.InputTimeout = %v, want %v", cfg.PromptDetection.InputTimeout, 10*time.Minute)
+	}
+	if got := strings.Join(cfg.PTY.ShellFallbacks, ","); got != "bash,sh" {
+		t.Errorf("PTY.ShellFallbacks = %v, want [bash sh]", cfg.PTY.ShellFallbacks)
+	}
+	if cfg.PTY.ReadBufferBytes != 4096 {
+		t.Errorf("PTY.ReadBufferBytes = %d, want 4096", cfg.PTY.ReadBufferBytes)
+	}
+	if cfg.Output.RunawayBytesPerSec != 4*1024*1024 || cfg.Output.RunawayWindow != 5*time.Second {
+		t.Errorf("Output = %+v, want 4MiB/s over 5s", cfg.Output)
+	}
 }
 
 func TestLoadEmptyPath(t *testing.T) {
@@ -102,6 +119,7 @@ recording:
 shell:
   source_rc: false
   path: /bin/zsh
+  normalize_prompt: false
 prompt_detection:
   custom_patterns:
     - name: vault
@@ -204,6 +222,9 @@ prompt_detection:
 	if cfg.Shell.Path != "/bin/zsh" {
 		t.Errorf("Shell.Path = %q, want %q", cfg.Shell.Path, "/bin/zsh")
 	}
+	if cfg.Shell.NormalizePrompt {
+		t.Error("Shell.NormalizePrompt = true, want false")
+	}
 
 	// Prompt detection
 	if len(cfg.PromptDetection.CustomPatterns) != 1 {
@@ -274,6 +295,119 @@ func TestValidateFixesNegativeMaxSessions(t *testing.T) {
 	}
 }
 
+func TestValidateFixesReadBufferBytes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PTY.ReadBufferBytes = 0
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if cfg.PTY.ReadBufferBytes != 4096 {
+		t.Errorf("PTY.ReadBufferBytes = %d, want 4096 (corrected)", cfg.PTY.ReadBufferBytes)
+	}
+}
+
+func TestValidateFixesStartupDrain(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PTY.StartupDrainMs = -100
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if cfg.PTY.StartupDrainMs != 0 {
+		t.Errorf("PTY.StartupDrainMs = %d, want 0 (mode default)", cfg.PTY.StartupDrainMs)
+	}
+}
+
+func TestValidateFixesReadyTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PTY.ReadyTimeoutMs = -1
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if cfg.PTY.ReadyTimeoutMs != 0 {
+		t.Errorf("PTY.ReadyTimeoutMs = %d, want 0 (default)", cfg.PTY.ReadyTimeoutMs)
+	}
+}
+
+func TestValidateFixesPollInterval(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PTY.PollIntervalMs = -50
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if cfg.PTY.PollIntervalMs != 0 {
+		t.Errorf("PTY.PollIntervalMs = %d, want 0 (default)", cfg.PTY.PollIntervalMs)
+	}
+}
+
+func TestValidateFixesOutputGuard(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Output = OutputConfig{RunawayBytesPerSec: -1}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	if cfg.Output.RunawayBytesPerSec != 0 {
+		t.Errorf("RunawayBytesPerSec = %d, want 0 (disabled)", cfg.Output.RunawayBytesPerSec)
+	}
+	if cfg.Output.RunawayWindow != 5*time.Second || cfg.Output.RunawaySampleBytes != 4096 {
+		t.Errorf("Output = %+v, want defaults for window and sample", cfg.Output)
+	}
+	if cfg.Output.MaxInlineFileBytes != DefaultMaxInlineFileBytes {
+		t.Errorf("MaxInlineFileBytes = %d, want default %d", cfg.Output.MaxInlineFileBytes, DefaultMaxInlineFileBytes)
+	}
+}
+
+func TestValidateRejectsUnknownLogFormat(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Logging.Format = "xml"
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject unknown logging.format")
+	}
+
+	cfg.Logging.Format = "text"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error for text format: %v", err)
+	}
+}
+
+func TestValidateRejectsInvalidRedactPattern(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Logging.RedactPatterns = []string{`tok_[a-z]+`, `([unclosed`}
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject invalid logging.redact_patterns")
+	}
+
+	cfg.Logging.RedactPatterns = cfg.Logging.RedactPatterns[:1]
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error for valid redact pattern: %v", err)
+	}
+}
+
+func TestValidateRejectsInvalidInteractiveExitPattern(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Output.InteractiveExitPatterns = []string{`^Press any key`, `(unclosed`}
+
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject invalid output.interactive_exit_patterns")
+	}
+
+	cfg.Output.InteractiveExitPatterns = cfg.Output.InteractiveExitPatterns[:1]
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error for valid pattern: %v", err)
+	}
+}
+
 // --- Watcher tests ---
 
 func writeConfigFile(t *testing.T, path, content string) {
@@ -465,3 +599,581 @@ func TestWatcherClose(t *testing.T) {
 		t.Errorf("Close() error: %v", err)
 	}
 }
+
+func TestValidateOnAmbiguous(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.PromptDetection.OnAmbiguous != AmbiguousAssumeRunning {
+		t.Errorf("default OnAmbiguous = %q, want %q", cfg.PromptDetection.OnAmbiguous, AmbiguousAssumeRunning)
+	}
+
+	cfg.PromptDetection.OnAmbiguous = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.PromptDetection.OnAmbiguous != AmbiguousAssumeRunning {
+		t.Errorf("OnAmbiguous = %q, want %q (corrected)", cfg.PromptDetection.OnAmbiguous, AmbiguousAssumeRunning)
+	}
+
+	cfg.PromptDetection.OnAmbiguous = "guess"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown on_ambiguous value")
+	}
+}
+
+func TestValidateFixesShutdownGracePeriod(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Shutdown.GracePeriod != 30*time.Second {
+		t.Errorf("default GracePeriod = %v, want 30s", cfg.Shutdown.GracePeriod)
+	}
+
+	cfg.Shutdown.GracePeriod = -time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Shutdown.GracePeriod != 0 {
+		t.Errorf("GracePeriod = %v, want 0 (corrected)", cfg.Shutdown.GracePeriod)
+	}
+}
+
+func TestDefaultExitOnClientDisconnect(t *testing.T) {
+	if !DefaultConfig().Shutdown.ExitOnClientDisconnect {
+		t.Error("default ExitOnClientDisconnect = false, want true")
+	}
+}
+
+func TestValidateTransferLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Transfer.MaxConcurrentTransfers != 4 {
+		t.Errorf("default MaxConcurrentTransfers = %d, want 4", cfg.Transfer.MaxConcurrentTransfers)
+	}
+	if cfg.Transfer.OnLimit != TransferLimitQueue {
+		t.Errorf("default OnLimit = %q, want %q", cfg.Transfer.OnLimit, TransferLimitQueue)
+	}
+
+	cfg.Transfer.MaxConcurrentTransfers = -1
+	cfg.Transfer.OnLimit = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Transfer.MaxConcurrentTransfers != 0 {
+		t.Errorf("MaxConcurrentTransfers = %d, want 0 (corrected)", cfg.Transfer.MaxConcurrentTransfers)
+	}
+	if cfg.Transfer.OnLimit != TransferLimitQueue {
+		t.Errorf("OnLimit = %q, want %q (corrected)", cfg.Transfer.OnLimit, TransferLimitQueue)
+	}
+
+	cfg.Transfer.OnLimit = "drop"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown on_limit value")
+	}
+}
+
+func TestValidateOnDuplicateSession(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.OnDuplicateSession != DuplicateSessionAllow {
+		t.Errorf("default OnDuplicateSession = %q, want %q", cfg.OnDuplicateSession, DuplicateSessionAllow)
+	}
+
+	cfg.OnDuplicateSession = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.OnDuplicateSession != DuplicateSessionAllow {
+		t.Errorf("OnDuplicateSession = %q, want %q (corrected)", cfg.OnDuplicateSession, DuplicateSessionAllow)
+	}
+
+	cfg.OnDuplicateSession = "replace"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown on_duplicate_session value")
+	}
+}
+
+func TestValidateTransferUmask(t *testing.T) {
+	cfg := DefaultConfig()
+	if got := cfg.Transfer.FileMode(); got != 0644 {
+		t.Errorf("default FileMode() = %o, want 644", got)
+	}
+
+	cfg.Transfer.Umask = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Transfer.Umask != DefaultUmask {
+		t.Errorf("Umask = %q, want %q (corrected)", cfg.Transfer.Umask, DefaultUmask)
+	}
+
+	cfg.Transfer.Umask = "077"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error for 077: %v", err)
+	}
+	if got := cfg.Transfer.FileMode(); got != 0600 {
+		t.Errorf("FileMode() with umask 077 = %o, want 600", got)
+	}
+
+	for _, bad := range []string{"rw-r--r--", "999", "1777"} {
+		cfg.Transfer.Umask = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for umask %q", bad)
+		}
+	}
+}
+
+func TestValidateSudoCacheScope(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Security.SudoCacheScope != SudoScopeSession {
+		t.Errorf("default SudoCacheScope = %q, want %q", cfg.Security.SudoCacheScope, SudoScopeSession)
+	}
+
+	cfg.Security.SudoCacheScope = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Security.SudoCacheScope != SudoScopeSession {
+		t.Errorf("SudoCacheScope = %q, want %q (corrected)", cfg.Security.SudoCacheScope, SudoScopeSession)
+	}
+
+	cfg.Security.SudoCacheScope = SudoScopeTTY
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error for tty scope: %v", err)
+	}
+
+	cfg.Security.SudoCacheScope = "global"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error for unknown sudo_cache_scope value")
+	}
+}
+
+func TestValidateSessionQuotas(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Security.MaxCommandsPerSession != 0 || cfg.Security.MaxOutputBytesPerSession != 0 {
+		t.Errorf("default quotas = %d/%d, want unlimited", cfg.Security.MaxCommandsPerSession, cfg.Security.MaxOutputBytesPerSession)
+	}
+
+	cfg.Security.MaxCommandsPerSession = -1
+	cfg.Security.MaxOutputBytesPerSession = -5
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Security.MaxCommandsPerSession != 0 || cfg.Security.MaxOutputBytesPerSession != 0 {
+		t.Errorf("quotas = %d/%d, want negative values corrected to 0", cfg.Security.MaxCommandsPerSession, cfg.Security.MaxOutputBytesPerSession)
+	}
+}
+
+func TestValidateTextInvalidThreshold(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Transfer.TextInvalidThreshold = 0.05
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error: %v", err)
+	}
+	for _, bad := range []float64{-0.1, 1.5} {
+		cfg.Transfer.TextInvalidThreshold = bad
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected error for text_invalid_threshold %v", bad)
+		}
+	}
+}
+
+func TestValidateCommandWrapper(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Servers = []ServerConfig{{Name: "batch", Host: "batch.example.com", CommandWrapper: "nice -n 19 {{cmd}}"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cfg.Servers[0].CommandWrapper = "nice -n 19"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for command_wrapper without {{cmd}}")
+	}
+	if !strings.Contains(err.Error(), "batch") {
+		t.Errorf("error should name the server: %v", err)
+	}
+}
+
+func TestProxyConfigParse(t *testing.T) {
+	tests := []struct {
+		url         string
+		wantScheme  string
+		wantAddress string
+		wantErr     string
+	}{
+		{"http://proxy.corp:3128", ProxySchemeHTTP, "proxy.corp:3128", ""},
+		{"socks5://10.0.0.5:1080", ProxySchemeSOCKS5, "10.0.0.5:1080", ""},
+		{"https://proxy.corp:3128", "", "", "scheme must be"},
+		{"http://proxy.corp", "", "", "scheme://host:port"},
+		{"http://bob:pw@proxy.corp:3128", "", "", "password_env"},
+		{"socks5://proxy.corp:1080/x", "", "", "path"},
+	}
+	for _, tt := range tests {
+		scheme, address, err := ProxyConfig{URL: tt.url}.Parse()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.url, err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "pw@") {
+				t.Errorf("Parse(%q) error leaks the password: %v", tt.url, err)
+			}
+			continue
+		}
+		if err != nil || scheme != tt.wantScheme || address != tt.wantAddress {
+			t.Errorf("Parse(%q) = %q, %q, %v; want %q, %q", tt.url, scheme, address, err, tt.wantScheme, tt.wantAddress)
+		}
+	}
+}
+
+func TestValidateServerProxy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Servers = []ServerConfig{{Name: "db", Host: "db.internal", Proxy: ProxyConfig{URL: "socks5://proxy.corp:1080"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cfg.Servers[0].Proxy.URL = "ftp://proxy.corp:21"
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "db") {
+		t.Errorf("Validate() error = %v, want an error naming the server", err)
+	}
+}
+
+func TestValidateRejectsInvalidAutoSudoPattern(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Security.AutoSudoPatterns = []string{"^systemctl restart ", "[unclosed"}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error for invalid auto_sudo pattern")
+	}
+	if !strings.Contains(err.Error(), "auto_sudo_patterns") {
+		t.Errorf("error should name the setting: %v", err)
+	}
+}
+
+func TestLoadServerIdentities(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+servers:
+  - name: web
+    host: web.example.com
+    user: alice
+    auth:
+      type: key
+      path: ~/.ssh/id_ed25519
+    identities:
+      - name: deploy
+        user: deploy
+        type: key
+        path: ~/.ssh/deploy_key
+        passphrase_env: DEPLOY_PASSPHRASE
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	srv := cfg.Servers[0]
+	if names := srv.IdentityNames(); len(names) != 1 || names[0] != "deploy" {
+		t.Fatalf("IdentityNames() = %v, want [deploy]", names)
+	}
+	id, ok := srv.Identity("deploy")
+	if !ok || id.User != "deploy" || id.Path != "~/.ssh/deploy_key" || id.PassphraseEnv != "DEPLOY_PASSPHRASE" {
+		t.Errorf("Identity(deploy) = %+v, %v", id, ok)
+	}
+
+	auth, err := srv.AuthFor("")
+	if err != nil || auth.Path != "~/.ssh/id_ed25519" {
+		t.Errorf("AuthFor(\"\") = %+v, %v; want the auth block", auth, err)
+	}
+	auth, err = srv.AuthFor("deploy")
+	if err != nil || auth.Path != "~/.ssh/deploy_key" {
+		t.Errorf("AuthFor(deploy) = %+v, %v", auth, err)
+	}
+	_, err = srv.AuthFor("personal")
+	if err == nil || !strings.Contains(err.Error(), "available: deploy") {
+		t.Errorf("AuthFor(personal) error = %v, want one listing the identities", err)
+	}
+}
+
+func TestValidateServerIdentities(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Servers = []ServerConfig{{Name: "web", Host: "web.example.com", Identities: []IdentityConfig{
+		{Name: "deploy"}, {Name: "personal"},
+	}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cfg.Servers[0].Identities[1].Name = "deploy"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "duplicate identity") {
+		t.Errorf("Validate() error = %v, want a duplicate identity error", err)
+	}
+	cfg.Servers[0].Identities[1].Name = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "needs a name") {
+		t.Errorf("Validate() error = %v, want an unnamed identity error", err)
+	}
+}
+
+func TestValidateCaptureEnvPatterns(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Session.CaptureEnvAllowlist = []string{`^AWS_`}
+	cfg.Session.CaptureEnvDenylist = []string{`(?i)secret|token`}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cfg.Session.CaptureEnvDenylist = []string{"[unclosed"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "session.capture_env_denylist") {
+		t.Errorf("Validate() error = %v, want one naming the setting", err)
+	}
+	cfg.Session.CaptureEnvDenylist = nil
+	cfg.Session.CaptureEnvAllowlist = []string{"(unclosed"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "session.capture_env_allowlist") {
+		t.Errorf("Validate() error = %v, want one naming the setting", err)
+	}
+}
+
+func TestLoadAutoCaptureOnConnect(t *testing.T) {
+	if ac := DefaultConfig().Session.AutoCaptureOnConnect; ac != (AutoCaptureConfig{Env: true}) {
+		t.Errorf("default auto_capture_on_connect = %+v, want env only", ac)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+session:
+  auto_capture_on_connect:
+    aliases: true
+    cwd: true
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if ac := cfg.Session.AutoCaptureOnConnect; ac != (AutoCaptureConfig{Env: true, Aliases: true, Cwd: true}) {
+		t.Errorf("auto_capture_on_connect = %+v, want env kept and aliases, cwd added", ac)
+	}
+}
+
+func TestValidateCertPath(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Servers = []ServerConfig{{Name: "web", Host: "web.example.com",
+		Auth: AuthConfig{Type: "key", Path: "~/.ssh/id_ed25519", CertPath: "~/.ssh/id_ed25519-cert.pub"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+
+	cfg.Servers[0].Auth.Path = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "auth.cert_path needs") {
+		t.Errorf("Validate() error = %v, want a cert without key error", err)
+	}
+
+	cfg.Servers[0].Auth.CertPath = ""
+	cfg.Servers[0].Identities = []IdentityConfig{{Name: "deploy", AuthConfig: AuthConfig{CertPath: "/keys/deploy-cert.pub"}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `identity "deploy": cert_path needs`) {
+		t.Errorf("Validate() error = %v, want a cert without key error for the identity", err)
+	}
+	cfg.Servers[0].KeyPath = "/keys/shared"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want the server key_path to back the certificate", err)
+	}
+}
+
+func TestValidateFixesInterruptGracePeriod(t *testing.T) {
+	cfg := DefaultConfig()
+	if cfg.Session.InterruptGracePeriod != DefaultInterruptGracePeriod {
+		t.Errorf("default InterruptGracePeriod = %v, want %v", cfg.Session.InterruptGracePeriod, DefaultInterruptGracePeriod)
+	}
+
+	cfg.Session.InterruptGracePeriod = -time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Session.InterruptGracePeriod != 0 {
+		t.Errorf("InterruptGracePeriod = %v, want 0 (corrected)", cfg.Session.InterruptGracePeriod)
+	}
+}
+
+func TestDefaultDisablePagers(t *testing.T) {
+	cfg := DefaultConfig()
+	if len(cfg.Session.DisablePagers) == 0 || cfg.Session.DisablePagers[0] != "PAGER=cat" {
+		t.Errorf("DisablePagers = %v, want the defaults", cfg.Session.DisablePagers)
+	}
+	cfg.Session.DisablePagers[0] = "PAGER=less"
+	if DefaultDisablePagers[0] != "PAGER=cat" {
+		t.Error("DefaultConfig shares DefaultDisablePagers")
+	}
+}
+
+func TestValidateDisablePagers(t *testing.T) {
+	tests := []struct {
+		entries []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"GIT_PAGER=cat", "SYSTEMD_PAGER="}, false},
+		{[]string{"GIT_PAGER"}, true},
+		{[]string{"1PAGER=cat"}, true},
+		{[]string{"MY PAGER=cat"}, true},
+		{[]string{"=cat"}, true},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Session.DisablePagers = tt.entries
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DisablePagers %q: Validate() error = %v, wantErr %v", tt.entries, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadDisablePagers(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want []string
+	}{
+		{"session:\n  disable_pagers: []\n", []string{}},
+		{"session:\n  disable_pagers: [GIT_PAGER=cat]\n", []string{"GIT_PAGER=cat"}},
+		{"session:\n  interrupt_grace_period: 2s\n", DefaultDisablePagers},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load() error: %v", err)
+		}
+		if !slices.Equal(cfg.Session.DisablePagers, tt.want) {
+			t.Errorf("%q: DisablePagers = %v, want %v", tt.yaml, cfg.Session.DisablePagers, tt.want)
+		}
+	}
+}
+
+func TestValidateLocalSessionDefaultCwd(t *testing.T) {
+	tests := []struct {
+		cwd     string
+		wantErr bool
+	}{
+		{"", false},
+		{"/srv/app", false},
+		{"~", false},
+		{"~/projects", false},
+		{"projects", true},
+		{"~other/projects", true},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.LocalSession.DefaultCwd = tt.cwd
+		err := cfg.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DefaultCwd %q: Validate() error = %v, wantErr %v", tt.cwd, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadLocalSessionDefaultCwd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("local_session:\n  default_cwd: ~/work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.LocalSession.DefaultCwd != "~/work" {
+		t.Errorf("DefaultCwd = %q, want ~/work", cfg.LocalSession.DefaultCwd)
+	}
+}
+
+func TestLoadDirListCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("dir_list:\n  cache: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !cfg.DirList.Cache || cfg.DirList.CacheTTL != DefaultDirListCacheTTL {
+		t.Errorf("DirList = %+v, want cache on with the default TTL", cfg.DirList)
+	}
+	if DefaultConfig().DirList.Cache {
+		t.Error("dir list cache on by default")
+	}
+
+	cfg.DirList.CacheTTL = -time.Second
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.DirList.CacheTTL != DefaultDirListCacheTTL {
+		t.Errorf("CacheTTL = %v after Validate, want %v", cfg.DirList.CacheTTL, DefaultDirListCacheTTL)
+	}
+}
+
+func TestStripSudoPromptsDefault(t *testing.T) {
+	if !DefaultConfig().Output.StripSudoPrompts {
+		t.Error("StripSudoPrompts off by default")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("output:\n  strip_sudo_prompts: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Output.StripSudoPrompts {
+		t.Error("StripSudoPrompts = true, want false from the file")
+	}
+}
+
+func TestLoadAdaptiveTimeout(t *testing.T) {
+	if cfg := DefaultConfig(); cfg.Session.AdaptiveTimeout || cfg.Session.AdaptiveTimeoutMax != DefaultAdaptiveTimeoutMax {
+		t.Errorf("default Session = %+v, want adaptive timeout off with the default max", cfg.Session)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("session:\n  adaptive_timeout: true\n  adaptive_timeout_max: 45m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !cfg.Session.AdaptiveTimeout || cfg.Session.AdaptiveTimeoutMax != 45*time.Minute {
+		t.Errorf("Session = %+v, want adaptive timeout on up to 45m", cfg.Session)
+	}
+
+	cfg.Session.AdaptiveTimeoutMax = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if cfg.Session.AdaptiveTimeoutMax != DefaultAdaptiveTimeoutMax {
+		t.Errorf("AdaptiveTimeoutMax = %v after Validate, want %v", cfg.Session.AdaptiveTimeoutMax, DefaultAdaptiveTimeoutMax)
+	}
+}
+
+func TestLoadCleanupManifestOnComplete(t *testing.T) {
+	if !DefaultConfig().Transfer.CleanupManifestOnComplete {
+		t.Error("CleanupManifestOnComplete is off by default, want on")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("transfer:\n  cleanup_manifest_on_complete: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if cfg.Transfer.CleanupManifestOnComplete {
+		t.Error("CleanupManifestOnComplete = true, want false from the file")
+	}
+	if cfg.Transfer.Umask != DefaultUmask {
+		t.Errorf("Umask = %q, want the default kept", cfg.Transfer.Umask)
+	}
+}
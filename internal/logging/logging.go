@@ -3,9 +3,13 @@ package logging
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // sensitiveKeys are keys that should be sanitized in logs.
@@ -19,10 +23,14 @@ var sensitiveKeys = []string{
 	"auth",
 }
 
+// redactedValue replaces sanitized values and redact pattern matches.
+const redactedValue = "[REDACTED]"
+
 // SanitizingHandler wraps a slog.Handler to sanitize sensitive data.
 type SanitizingHandler struct {
 	handler  slog.Handler
 	sanitize bool
+	redact   []*regexp.Regexp // applied to messages and string values
 }
 
 // NewSanitizingHandler creates a new sanitizing handler.
@@ -33,6 +41,30 @@ func NewSanitizingHandler(handler slog.Handler, sanitize bool) *SanitizingHandle
 	}
 }
 
+// WithRedactPatterns returns a copy of h that also replaces every match of
+// patterns in log messages and string attribute values with [REDACTED].
+func (h *SanitizingHandler) WithRedactPatterns(patterns []*regexp.Regexp) *SanitizingHandler {
+	return &SanitizingHandler{
+		handler:  h.handler,
+		sanitize: h.sanitize,
+		redact:   patterns,
+	}
+}
+
+// CompileRedactPatterns compiles redaction regular expressions, reporting
+// the first invalid one.
+func CompileRedactPatterns(exprs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
 // Enabled implements slog.Handler.
 func (h *SanitizingHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return h.handler.Enabled(ctx, level)
@@ -40,12 +72,12 @@ func (h *SanitizingHandler) Enabled(ctx context.Context, level slog.Level) bool
 
 // Handle implements slog.Handler.
 func (h *SanitizingHandler) Handle(ctx context.Context, r slog.Record) error {
-	if !h.sanitize {
+	if !h.sanitize && len(h.redact) == 0 {
 		return h.handler.Handle(ctx, r)
 	}
 
 	// Create a new record with sanitized attributes
-	newRecord := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	newRecord := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
 	r.Attrs(func(a slog.Attr) bool {
 		newRecord.AddAttrs(h.sanitizeAttr(a))
 		return true
@@ -56,7 +88,7 @@ func (h *SanitizingHandler) Handle(ctx context.Context, r slog.Record) error {
 
 // WithAttrs implements slog.Handler.
 func (h *SanitizingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	if h.sanitize {
+	if h.sanitize || len(h.redact) > 0 {
 		sanitized := make([]slog.Attr, len(attrs))
 		for i, a := range attrs {
 			sanitized[i] = h.sanitizeAttr(a)
@@ -66,6 +98,7 @@ func (h *SanitizingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &SanitizingHandler{
 		handler:  h.handler.WithAttrs(attrs),
 		sanitize: h.sanitize,
+		redact:   h.redact,
 	}
 }
 
@@ -74,15 +107,19 @@ func (h *SanitizingHandler) WithGroup(name string) slog.Handler {
 	return &SanitizingHandler{
 		handler:  h.handler.WithGroup(name),
 		sanitize: h.sanitize,
+		redact:   h.redact,
 	}
 }
 
-// sanitizeAttr sanitizes an attribute if its key matches a sensitive key.
+// sanitizeAttr sanitizes an attribute if its key matches a sensitive key
+// and redacts pattern matches in string values.
 func (h *SanitizingHandler) sanitizeAttr(a slog.Attr) slog.Attr {
-	key := strings.ToLower(a.Key)
-	for _, sensitive := range sensitiveKeys {
-		if strings.Contains(key, sensitive) {
-			return slog.String(a.Key, "[REDACTED]")
+	if h.sanitize {
+		key := strings.ToLower(a.Key)
+		for _, sensitive := range sensitiveKeys {
+			if strings.Contains(key, sensitive) {
+				return slog.String(a.Key, redactedValue)
+			}
 		}
 	}
 
@@ -96,33 +133,105 @@ func (h *SanitizingHandler) sanitizeAttr(a slog.Attr) slog.Attr {
 		return slog.Attr{Key: a.Key, Value: slog.GroupValue(sanitized...)}
 	}
 
+	if a.Value.Kind() == slog.KindString && len(h.redact) > 0 {
+		return slog.String(a.Key, h.redactString(a.Value.String()))
+	}
+
 	return a
 }
 
+// redactString replaces every redact pattern match in s.
+func (h *SanitizingHandler) redactString(s string) string {
+	for _, re := range h.redact {
+		s = re.ReplaceAllString(s, redactedValue)
+	}
+	return s
+}
+
+// Options configures the global logger.
+type Options struct {
+	Level    string // "debug", "info", "warn", "error"
+	Sanitize bool   // redact sensitive attributes
+	Format   string // "json" (default) or "text"
+
+	// RedactPatterns are regular expressions whose matches are replaced
+	// with [REDACTED] in messages and string values.
+	RedactPatterns []string
+
+	// File, if set, sends logs to a rotating file instead of stderr.
+	File       string
+	MaxSizeMB  int
+	MaxAge     time.Duration
+	MaxBackups int
+}
+
 // Setup initializes the global logger with the given level and sanitization setting.
 func Setup(level string, sanitize bool) {
-	var logLevel slog.Level
+	Configure(Options{Level: level, Sanitize: sanitize})
+}
+
+// Configure installs the global logger described by opts. The returned
+// closer releases the log file, if any, and is safe to call when logging to
+// stderr.
+func Configure(opts Options) (io.Closer, error) {
+	redact, err := CompileRedactPatterns(opts.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var out io.Writer = os.Stderr
+	var closer io.Closer = nopCloser{}
+
+	if opts.File != "" {
+		rf, err := NewRotatingFile(opts.File, RotateOptions{
+			MaxSize:    int64(opts.MaxSizeMB) * 1024 * 1024,
+			MaxAge:     opts.MaxAge,
+			MaxBackups: opts.MaxBackups,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out, closer = rf, rf
+	}
+
+	handler, err := newFormatHandler(out, opts.Format, parseLevel(opts.Level))
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+
+	slog.SetDefault(slog.New(NewSanitizingHandler(handler, opts.Sanitize).WithRedactPatterns(redact)))
+	return closer, nil
+}
+
+// newFormatHandler returns the slog handler for the named output format.
+func newFormatHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.NewJSONHandler(w, handlerOpts), nil
+	case "text":
+		return slog.NewTextHandler(w, handlerOpts), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (want \"json\" or \"text\")", format)
+}
+
+// parseLevel maps a level name to a slog level, defaulting to info.
+func parseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelDebug
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelError
 	}
+	return slog.LevelInfo
+}
 
-	jsonHandler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-		Level: logLevel,
-	})
+type nopCloser struct{}
 
-	handler := NewSanitizingHandler(jsonHandler, sanitize)
-	logger := slog.New(handler)
-	slog.SetDefault(logger)
-}
+func (nopCloser) Close() error { return nil }
 
 // DebugPTYRead logs PTY read data in debug mode.
 func DebugPTYRead(sessionID string, data []byte, n int) {
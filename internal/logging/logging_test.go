@@ -733,3 +733,71 @@ func TestHandle_SanitizeTrue_NoAttributes(t *testing.T) {
 		t.Errorf("expected msg 'no attrs', got %v", result["msg"])
 	}
 }
+
+// ============================================================
+// Redact pattern tests
+// ============================================================
+
+func TestCompileRedactPatterns_Invalid(t *testing.T) {
+	if _, err := CompileRedactPatterns([]string{`ok`, `([bad`}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
+
+func TestHandle_RedactPatterns_MessageAndValues(t *testing.T) {
+	patterns, err := CompileRedactPatterns([]string{`tok_[a-z0-9]+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := slog.New(NewSanitizingHandler(inner, true).WithRedactPatterns(patterns))
+
+	logger.Info("got tok_abc123 from server",
+		slog.String("output", "export API=tok_xyz9"),
+		slog.Group("cmd", slog.String("line", "curl -H tok_q1")),
+		slog.Int("count", 2),
+	)
+
+	result := parseLogOutput(t, &buf)
+	if result["msg"] != "got [REDACTED] from server" {
+		t.Errorf("msg = %v", result["msg"])
+	}
+	if result["output"] != "export API=[REDACTED]" {
+		t.Errorf("output = %v", result["output"])
+	}
+	group, _ := result["cmd"].(map[string]interface{})
+	if group["line"] != "curl -H [REDACTED]" {
+		t.Errorf("cmd.line = %v", group["line"])
+	}
+	if result["count"] != float64(2) {
+		t.Errorf("count = %v", result["count"])
+	}
+}
+
+func TestHandle_RedactPatterns_AppliedWithoutSanitize(t *testing.T) {
+	patterns, _ := CompileRedactPatterns([]string{`secret-\d+`})
+
+	var buf bytes.Buffer
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := slog.New(NewSanitizingHandler(inner, false).WithRedactPatterns(patterns)).
+		With(slog.String("ctx", "secret-42"))
+
+	logger.Info("msg", slog.String("password", "plain"))
+
+	result := parseLogOutput(t, &buf)
+	if result["ctx"] != "[REDACTED]" {
+		t.Errorf("ctx = %v", result["ctx"])
+	}
+	// Key-based sanitization stays off.
+	if result["password"] != "plain" {
+		t.Errorf("password = %v", result["password"])
+	}
+}
+
+func TestConfigure_RejectsInvalidRedactPattern(t *testing.T) {
+	if _, err := Configure(Options{Level: "info", RedactPatterns: []string{`(`}}); err == nil {
+		t.Error("expected Configure to reject invalid redact pattern")
+	}
+}
@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realclock"
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realfs"
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+)
+
+// logFileMode is the permission for log files; logs may contain command output.
+const logFileMode = 0600
+
+// backupTimeFormat is appended to rotated log file names.
+const backupTimeFormat = "20060102T150405.000"
+
+// RotateOptions configures a RotatingFile.
+type RotateOptions struct {
+	// MaxSize rotates the file once it would grow beyond this many bytes (0 = no limit).
+	MaxSize int64
+	// MaxAge rotates the file once it is this old (0 = no limit). An
+	// existing file's age counts from its modification time, so restarting
+	// the server does not postpone rotation.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep (0 = keep all).
+	MaxBackups int
+
+	FS    ports.FileSystem
+	Clock ports.Clock
+}
+
+// RotatingFile is an io.WriteCloser that writes to a log file and rotates it
+// by size and age. Rotated files are renamed to "<path>.<timestamp>".
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	opts     RotateOptions
+	file     ports.FileHandle
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile opens (or creates with mode 0600) the log file at path.
+func NewRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	if opts.FS == nil {
+		opts.FS = realfs.New()
+	}
+	if opts.Clock == nil {
+		opts.Clock = realclock.New()
+	}
+
+	if err := opts.FS.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+
+	r := &RotatingFile{path: path, opts: opts}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file for appending and records its current size.
+func (r *RotatingFile) open() error {
+	f, err := r.opts.FS.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	r.size = 0
+	r.openedAt = r.opts.Clock.Now()
+	if info, err := r.opts.FS.Stat(r.path); err == nil && info.Size() > 0 {
+		r.size = info.Size()
+		r.openedAt = info.ModTime()
+	}
+	r.file = f
+	return nil
+}
+
+// Write implements io.Writer, rotating first if the write would exceed the policy.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, fmt.Errorf("log file is closed")
+	}
+
+	if r.shouldRotate(len(p)) {
+		if err := r.rotate(); err != nil {
+			if r.file == nil {
+				return 0, err
+			}
+			// Rotation failed but a file is open: keep the line.
+			n, _ := r.file.Write(p)
+			r.size += int64(n)
+			return n, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) shouldRotate(next int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+int64(next) > r.opts.MaxSize {
+		return true
+	}
+	if r.opts.MaxAge > 0 && r.opts.Clock.Now().Sub(r.openedAt) >= r.opts.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate closes the current file, renames it with a timestamp suffix,
+// reopens a fresh file and prunes old backups. If the rename or reopen
+// fails, the log file is opened again so writes can go on.
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+	r.file = nil
+
+	backup := r.path + "." + r.opts.Clock.Now().UTC().Format(backupTimeFormat)
+	if err := r.opts.FS.Rename(r.path, backup); err != nil {
+		return errors.Join(fmt.Errorf("rotate log file: %w", err), r.open())
+	}
+
+	if err := r.open(); err != nil {
+		// Put the old file back and append to it instead.
+		if renameErr := r.opts.FS.Rename(backup, r.path); renameErr != nil {
+			return errors.Join(err, renameErr)
+		}
+		return errors.Join(err, r.open())
+	}
+
+	r.pruneBackups()
+	return nil
+}
+
+// pruneBackups removes the oldest rotated files beyond MaxBackups.
+func (r *RotatingFile) pruneBackups() {
+	if r.opts.MaxBackups <= 0 {
+		return
+	}
+
+	entries, err := r.opts.FS.ReadDir(filepath.Dir(r.path))
+	if err != nil {
+		return
+	}
+
+	prefix := filepath.Base(r.path) + "."
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			backups = append(backups, e.Name())
+		}
+	}
+	if len(backups) <= r.opts.MaxBackups {
+		return
+	}
+
+	// Timestamp suffixes sort chronologically.
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-r.opts.MaxBackups] {
+		r.opts.FS.Remove(filepath.Join(filepath.Dir(r.path), name))
+	}
+}
+
+// Close closes the underlying file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+)
+
+func newTestRotatingFile(t *testing.T, opts RotateOptions) (*RotatingFile, *fakefs.FS, *fakeclock.Clock) {
+	t.Helper()
+	ffs := fakefs.New()
+	clk := fakeclock.New(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	opts.FS = ffs
+	opts.Clock = clk
+	rf, err := NewRotatingFile("/var/log/mcp/server.log", opts)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	return rf, ffs, clk
+}
+
+func backups(ffs *fakefs.FS) []string {
+	var names []string
+	for _, f := range ffs.Files() {
+		if strings.HasPrefix(f, "/var/log/mcp/server.log.") {
+			names = append(names, f)
+		}
+	}
+	return names
+}
+
+func TestRotatingFile_CreatesWith0600(t *testing.T) {
+	rf, ffs, _ := newTestRotatingFile(t, RotateOptions{})
+	rf.Write([]byte("hello\n"))
+	rf.Close()
+
+	info, err := ffs.Stat("/var/log/mcp/server.log")
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %04o, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestRotatingFile_RotatesBySize(t *testing.T) {
+	rf, ffs, clk := newTestRotatingFile(t, RotateOptions{MaxSize: 10})
+
+	rf.Write([]byte("12345678\n"))
+	clk.Advance(time.Second)
+	rf.Write([]byte("abcdefgh\n")) // would exceed 10 bytes -> rotate first
+	rf.Close()
+
+	b := backups(ffs)
+	if len(b) != 1 {
+		t.Fatalf("backups = %v, want 1", b)
+	}
+	old, _ := ffs.ReadFile(b[0])
+	if string(old) != "12345678\n" {
+		t.Errorf("backup content = %q", old)
+	}
+	cur, _ := ffs.ReadFile("/var/log/mcp/server.log")
+	if string(cur) != "abcdefgh\n" {
+		t.Errorf("current content = %q", cur)
+	}
+}
+
+func TestRotatingFile_RotatesByAge(t *testing.T) {
+	rf, ffs, clk := newTestRotatingFile(t, RotateOptions{MaxAge: time.Hour})
+
+	rf.Write([]byte("first\n"))
+	clk.Advance(30 * time.Minute)
+	rf.Write([]byte("second\n"))
+	if len(backups(ffs)) != 0 {
+		t.Fatal("rotated before MaxAge")
+	}
+
+	clk.Advance(31 * time.Minute)
+	rf.Write([]byte("third\n"))
+	rf.Close()
+
+	if len(backups(ffs)) != 1 {
+		t.Errorf("backups = %v, want 1 after MaxAge", backups(ffs))
+	}
+}
+
+func TestRotatingFile_AgeCountsFromModTime(t *testing.T) {
+	ffs := fakefs.New()
+	clk := fakeclock.New(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+	ffs.AddFile("/var/log/mcp/server.log", []byte("old\n"), 0600)
+	ffs.Chtimes("/var/log/mcp/server.log", clk.Now().Add(-2*time.Hour), clk.Now().Add(-2*time.Hour))
+
+	rf, err := NewRotatingFile("/var/log/mcp/server.log", RotateOptions{MaxAge: time.Hour, FS: ffs, Clock: clk})
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	rf.Write([]byte("new\n"))
+	rf.Close()
+
+	if len(backups(ffs)) != 1 {
+		t.Errorf("backups = %v, want the reopened file rotated by its age", backups(ffs))
+	}
+}
+
+func TestRotatingFile_KeepsWritingWhenRotationFails(t *testing.T) {
+	rf, ffs, clk := newTestRotatingFile(t, RotateOptions{MaxSize: 10})
+
+	rf.Write([]byte("12345678\n"))
+	// The file is removed behind the writer's back, so renaming it fails.
+	ffs.Remove("/var/log/mcp/server.log")
+	clk.Advance(time.Second)
+	if _, err := rf.Write([]byte("abcdefgh\n")); err == nil {
+		t.Error("Write() error = nil, want the rotation error")
+	}
+	if _, err := rf.Write([]byte("ijklmnop\n")); err != nil {
+		t.Errorf("Write() after a failed rotation: %v", err)
+	}
+	rf.Close()
+
+	// The line written when the rotation failed went to a reopened file,
+	// which the next write rotated normally.
+	b := backups(ffs)
+	if len(b) != 1 {
+		t.Fatalf("backups = %v, want 1", b)
+	}
+	if old, _ := ffs.ReadFile(b[0]); string(old) != "abcdefgh\n" {
+		t.Errorf("backup content = %q", old)
+	}
+	if cur, _ := ffs.ReadFile("/var/log/mcp/server.log"); string(cur) != "ijklmnop\n" {
+		t.Errorf("current content = %q", cur)
+	}
+}
+
+func TestRotatingFile_PrunesOldBackups(t *testing.T) {
+	rf, ffs, clk := newTestRotatingFile(t, RotateOptions{MaxSize: 4, MaxBackups: 2})
+
+	for i := 0; i < 5; i++ {
+		rf.Write([]byte("data"))
+		clk.Advance(time.Second)
+	}
+	rf.Close()
+
+	b := backups(ffs)
+	if len(b) != 2 {
+		t.Fatalf("backups = %v, want 2", b)
+	}
+	// The newest backups are kept.
+	if !strings.HasSuffix(b[1], "T100004.000") {
+		t.Errorf("newest backup = %s", b[1])
+	}
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	rf, _, _ := newTestRotatingFile(t, RotateOptions{})
+	rf.Close()
+	if _, err := rf.Write([]byte("x")); err == nil {
+		t.Error("expected error writing to closed file")
+	}
+	if err := rf.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestConfigure_FileTextFormatSanitized(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	path := filepath.Join(t.TempDir(), "logs", "mcp.log")
+	closer, err := Configure(Options{Level: "info", Sanitize: true, Format: "text", File: path})
+	if err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	slog.Info("login", slog.String("password", "hunter2"))
+	closer.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %04o, want 0600", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	out := string(data)
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("password leaked into log file: %s", out)
+	}
+	if !strings.Contains(out, "msg=login") {
+		t.Errorf("expected text format, got: %s", out)
+	}
+}
+
+func TestConfigure_UnknownFormat(t *testing.T) {
+	if _, err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,35 @@
+package mcp
+
+import "github.com/mark3labs/mcp-go/mcp"
+
+// Tool annotations tell clients whether a tool changes anything, so they can
+// e.g. auto-approve read-only calls and confirm destructive ones. Every tool
+// definition takes exactly one of these.
+
+// readOnlyTool marks a tool that only inspects sessions, hosts or files.
+func readOnlyTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.Annotations.ReadOnlyHint = mcp.ToBoolPtr(true)
+		t.Annotations.DestructiveHint = mcp.ToBoolPtr(false)
+		t.Annotations.IdempotentHint = mcp.ToBoolPtr(true)
+	}
+}
+
+// additiveTool marks a tool that creates or changes state without
+// destroying anything: new sessions, tunnels, recordings, local copies.
+func additiveTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.Annotations.ReadOnlyHint = mcp.ToBoolPtr(false)
+		t.Annotations.DestructiveHint = mcp.ToBoolPtr(false)
+	}
+}
+
+// destructiveTool marks a tool that runs arbitrary commands, overwrites or
+// moves files, tears down sessions, connections and processes, or can
+// remove guardrails.
+func destructiveTool() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		t.Annotations.ReadOnlyHint = mcp.ToBoolPtr(false)
+		t.Annotations.DestructiveHint = mcp.ToBoolPtr(true)
+	}
+}
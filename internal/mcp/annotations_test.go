@@ -0,0 +1,61 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestToolAnnotations(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+	tools := srv.mcpServer.ListTools()
+
+	tests := []struct {
+		name        string
+		readOnly    bool
+		destructive bool
+	}{
+		{"shell_exec", false, true},
+		{"shell_assert", false, true},
+		{"shell_exec_pipeline", false, true},
+		{"shell_file_get", true, false},
+		{"shell_session_list", true, false},
+		{"shell_session_status", true, false},
+		{"shell_outputs_list", true, false},
+		{"shell_output_read", true, false},
+		{"shell_peak_tty_screen", true, false},
+		{"shell_file_put", false, true},
+		{"shell_file_mv", false, true},
+		{"shell_file_rm", false, true},
+		{"shell_dir_put", false, true},
+		{"shell_dir_chmod", false, true},
+		{"shell_prompt_patterns_list", true, false},
+		{"shell_sudo_shell", false, true},
+		{"shell_sudo_exit", false, false},
+		{"shell_session_close", false, true},
+		{"shell_session_close_all", false, true},
+		{"shell_session_create", false, false},
+		{"shell_tunnel_create", false, false},
+		{"shell_authorize_key", false, false},
+		{"shell_security_status", true, false},
+		{"shell_security_set", false, true},
+	}
+	for _, tt := range tests {
+		st, ok := tools[tt.name]
+		if !ok {
+			t.Errorf("%s is not registered", tt.name)
+			continue
+		}
+		a := st.Tool.Annotations
+		if *a.ReadOnlyHint != tt.readOnly || *a.DestructiveHint != tt.destructive {
+			t.Errorf("%s: readOnly=%v destructive=%v, want %v/%v", tt.name, *a.ReadOnlyHint, *a.DestructiveHint, tt.readOnly, tt.destructive)
+		}
+	}
+
+	for name, st := range tools {
+		a := st.Tool.Annotations
+		if *a.ReadOnlyHint && (*a.DestructiveHint || !*a.IdempotentHint) {
+			t.Errorf("%s: read-only tools must be non-destructive and idempotent", name)
+		}
+	}
+}
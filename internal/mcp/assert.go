@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxAssertOutput bounds the output shell_assert returns; the comparison
+// always sees all of it.
+const maxAssertOutput = 8 * 1024
+
+// Match modes of shell_assert.
+const (
+	assertMatchExact    = "exact"
+	assertMatchContains = "contains"
+	assertMatchRegex    = "regex"
+)
+
+// registerAssertTools registers the output assertion tool.
+func (s *Server) registerAssertTools() {
+	s.mcpServer.AddTool(shellAssertTool(), s.handleShellAssert)
+}
+
+func shellAssertTool() mcp.Tool {
+	return mcp.NewTool("shell_assert",
+		mcp.WithDescription(`Run a command and check its output (and optionally its exit code) against an expectation.
+
+A verification primitive: instead of reading free-form output, ask e.g.
+command="systemctl is-active nginx", expect="active" and look at "passed".
+
+The output is cleaned before comparing: ANSI escapes and carriage returns are
+removed and, for match="exact", leading and trailing whitespace is trimmed on
+both sides. match="contains" looks for expect anywhere in the output and
+match="regex" searches it with a Go regular expression (use (?m) for ^/$ per
+line). With exit_code the command's exit code must equal it too; give
+exit_code alone to assert only on that.
+
+The result has passed, the cleaned output (its last 8KB if longer), the exit
+code and, when it failed, a reason. If the command stops at a prompt or times
+out, passed is false and status and result are the command's; answer it with
+shell_provide_input as usual.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("Command to run"),
+		),
+		mcp.WithString("expect",
+			mcp.Description("Expected output, or a regex with match=regex"),
+		),
+		mcp.WithString("match",
+			mcp.Description("How expect is compared: exact (default), contains or regex"),
+			mcp.Enum(assertMatchExact, assertMatchContains, assertMatchRegex),
+		),
+		mcp.WithNumber("exit_code",
+			mcp.Description("Exit code the command must return (default: not checked)"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Timeout in milliseconds (default: 30000)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// AssertResult is the result of shell_assert.
+type AssertResult struct {
+	Status           string              `json:"status"`
+	Passed           bool                `json:"passed"`
+	Reason           string              `json:"reason,omitempty"`
+	Output           string              `json:"output"`
+	OutputTruncated  bool                `json:"output_truncated,omitempty"`
+	ExitCode         *int                `json:"exit_code,omitempty"`
+	ExpectedExitCode *int                `json:"expected_exit_code,omitempty"`
+	Result           *session.ExecResult `json:"result,omitempty"`
+}
+
+// assertion is what shell_assert checks a command's result against.
+type assertion struct {
+	expect   string
+	match    string
+	re       *regexp.Regexp
+	exitCode *int
+}
+
+func (s *Server) handleShellAssert(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	command := mcp.ParseString(req, "command", "")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if command == "" {
+		return mcp.NewToolResultError("command is required"), nil
+	}
+	a, err := parseAssertion(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if allowed, reason := s.filter().IsAllowed(command); !allowed {
+		slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+		return mcp.NewToolResultError("command blocked: " + reason), nil
+	}
+	if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+		return errResult, nil
+	}
+
+	execResult, err := s.execInSessionFull(ctx, sessionID, command, timeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := a.check(execResult)
+	slog.Info("assertion",
+		slog.String("session_id", sessionID),
+		slog.String("command", command),
+		slog.Bool("passed", result.Passed),
+	)
+	return jsonResult(result)
+}
+
+// parseAssertion reads expect, match and exit_code.
+func parseAssertion(req mcp.CallToolRequest) (*assertion, error) {
+	a := &assertion{
+		expect: mcp.ParseString(req, "expect", ""),
+		match:  mcp.ParseString(req, "match", assertMatchExact),
+	}
+	_, hasExpect := req.GetArguments()["expect"]
+	if _, ok := req.GetArguments()["exit_code"]; ok {
+		code := mcp.ParseInt(req, "exit_code", 0)
+		a.exitCode = &code
+	}
+	if !hasExpect && a.exitCode == nil {
+		return nil, fmt.Errorf("at least one of expect or exit_code is required")
+	}
+	if !hasExpect {
+		a.match = ""
+		return a, nil
+	}
+
+	switch a.match {
+	case assertMatchExact, assertMatchContains:
+	case assertMatchRegex:
+		re, err := regexp.Compile(a.expect)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expect regex: %v", err)
+		}
+		a.re = re
+	default:
+		return nil, fmt.Errorf("invalid match %q: must be exact, contains or regex", a.match)
+	}
+	return a, nil
+}
+
+// check compares a command's result with the assertion.
+func (a *assertion) check(r *session.ExecResult) AssertResult {
+	output := cleanAssertOutput(r.Stdout)
+	result := AssertResult{
+		Status:           r.Status,
+		Output:           output,
+		ExitCode:         r.ExitCode,
+		ExpectedExitCode: a.exitCode,
+	}
+	if len(output) > maxAssertOutput {
+		result.Output = output[len(output)-maxAssertOutput:]
+		result.OutputTruncated = true
+	}
+
+	if r.Status != "completed" {
+		result.Reason = fmt.Sprintf("command did not complete (status %s)", r.Status)
+		result.Result = r
+		return result
+	}
+	if a.exitCode != nil {
+		if r.ExitCode == nil {
+			result.Reason = "exit code not available"
+			return result
+		}
+		if *r.ExitCode != *a.exitCode {
+			result.Reason = fmt.Sprintf("exit code %d, want %d", *r.ExitCode, *a.exitCode)
+			return result
+		}
+	}
+
+	switch a.match {
+	case assertMatchExact:
+		if strings.TrimSpace(output) != strings.TrimSpace(a.expect) {
+			result.Reason = "output does not equal expect"
+			return result
+		}
+	case assertMatchContains:
+		if !strings.Contains(output, a.expect) {
+			result.Reason = "output does not contain expect"
+			return result
+		}
+	case assertMatchRegex:
+		if !a.re.MatchString(output) {
+			result.Reason = "output does not match expect"
+			return result
+		}
+	}
+	result.Passed = true
+	return result
+}
+
+// cleanAssertOutput removes terminal noise from command output: ANSI escapes
+// and carriage returns.
+func cleanAssertOutput(output string) string {
+	return strings.ReplaceAll(session.StripANSI(output), "\r", "")
+}
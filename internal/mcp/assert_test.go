@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+)
+
+func callAssert(t *testing.T, srv *Server, args map[string]any) map[string]any {
+	t.Helper()
+	args["session_id"] = "sess_if"
+	result, err := srv.handleShellAssert(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestHandleShellAssert(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		exitCode   int
+		args       map[string]any
+		wantPassed bool
+		wantReason string
+	}{
+		{"exact", "active", 0, map[string]any{"expect": "active"}, true, ""},
+		{"exact trims", "\x1b[32mactive\x1b[0m\r", 0, map[string]any{"expect": " active\n"}, true, ""},
+		{"exact mismatch", "inactive", 3, map[string]any{"expect": "active"}, false, "output does not equal expect"},
+		{"contains", "nginx: the configuration file syntax is ok", 0, map[string]any{"expect": "syntax is ok", "match": "contains"}, true, ""},
+		{"regex", "Active: active (running) since Mon", 0, map[string]any{"expect": `(?m)^Active: active \(running\)`, "match": "regex"}, true, ""},
+		{"regex mismatch", "Active: failed", 0, map[string]any{"expect": `active \(running\)`, "match": "regex"}, false, "output does not match expect"},
+		{"exit code only", "", 0, map[string]any{"exit_code": 0}, true, ""},
+		{"exit code mismatch", "active", 3, map[string]any{"expect": "active", "exit_code": 0}, false, "exit code 3, want 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, pty := newExecIfServer(t)
+			pty.AddResponse(commandResponse("00010203", tt.output, tt.exitCode))
+			pty.AddResponse("/home/user\n")
+
+			tt.args["command"] = "systemctl is-active nginx"
+			m := callAssert(t, srv, tt.args)
+			if m["passed"] != tt.wantPassed {
+				t.Errorf("passed = %v, want %v (reason %v)", m["passed"], tt.wantPassed, m["reason"])
+			}
+			if reason, _ := m["reason"].(string); reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+			if m["status"] != "completed" || m["exit_code"] != float64(tt.exitCode) {
+				t.Errorf("status=%v exit_code=%v", m["status"], m["exit_code"])
+			}
+			if out, _ := m["output"].(string); strings.ContainsAny(out, "\x1b\r") {
+				t.Errorf("output not cleaned: %q", out)
+			}
+		})
+	}
+}
+
+func TestHandleShellAssert_InvalidArgs(t *testing.T) {
+	srv, _ := newExecIfServer(t)
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"no expectation", map[string]any{"command": "true"}, "at least one of expect or exit_code"},
+		{"bad regex", map[string]any{"command": "true", "expect": "(", "match": "regex"}, "invalid expect regex"},
+		{"bad match", map[string]any{"command": "true", "expect": "x", "match": "glob"}, "invalid match"},
+		{"no command", map[string]any{"expect": "x"}, "command is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["session_id"] = "sess_if"
+			result, _ := srv.handleShellAssert(context.Background(), makeRequest(tt.args))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertionCheck_NotCompleted(t *testing.T) {
+	a := &assertion{expect: "ok", match: assertMatchExact}
+	r := a.check(&session.ExecResult{Status: "awaiting_input", Stdout: "Password: "})
+	if r.Passed || r.Status != "awaiting_input" || r.Result == nil {
+		t.Errorf("result = %+v, want a failed assertion carrying the exec result", r)
+	}
+	if !strings.Contains(r.Reason, "did not complete") {
+		t.Errorf("reason = %q", r.Reason)
+	}
+}
+
+func TestAssertionCheck_TruncatesReturnedOutputOnly(t *testing.T) {
+	long := strings.Repeat("x", maxAssertOutput) + "READY"
+	a := &assertion{expect: "READY", match: assertMatchContains}
+	code := 0
+	r := a.check(&session.ExecResult{Status: "completed", Stdout: long, ExitCode: &code})
+	if !r.Passed || !r.OutputTruncated || len(r.Output) != maxAssertOutput || !strings.HasSuffix(r.Output, "READY") {
+		t.Errorf("passed=%v truncated=%v len=%d", r.Passed, r.OutputTruncated, len(r.Output))
+	}
+}
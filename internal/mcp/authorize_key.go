@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	// maxAuthorizedKeysSize bounds the authorized_keys file read back for
+	// de-duplication.
+	maxAuthorizedKeysSize = 1024 * 1024
+
+	sshDirPerm         os.FileMode = 0700
+	authorizedKeysPerm os.FileMode = 0600
+)
+
+// registerAuthorizeKeyTools registers the public key installation tool.
+func (s *Server) registerAuthorizeKeyTools() {
+	s.mcpServer.AddTool(shellAuthorizeKeyTool(), s.handleShellAuthorizeKey)
+}
+
+func shellAuthorizeKeyTool() mcp.Tool {
+	return mcp.NewTool("shell_authorize_key",
+		mcp.WithDescription(`Add an SSH public key to ~/.ssh/authorized_keys on a session's host.
+
+The key is appended unless the file already holds the same key (compared by
+type and key data, ignoring comments and options), so calling it twice is
+safe. ~/.ssh is created if missing, and the directory and file are set to
+0700 and 0600 as sshd's StrictModes expects; the resulting modes are read
+back and reported. The file is written to a temp file and renamed into place.
+
+"added" tells whether the key was newly added or already present. Local
+sessions use the server user's home directory, SSH sessions the login
+user's.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("public_key",
+			mcp.Required(),
+			mcp.Description("Public key in authorized_keys format, e.g. the contents of id_ed25519.pub"),
+		),
+		additiveTool(),
+	)
+}
+
+// AuthorizeKeyResult is the result of shell_authorize_key.
+type AuthorizeKeyResult struct {
+	Status      string `json:"status"`
+	SessionID   string `json:"session_id"`
+	Path        string `json:"path"`
+	Added       bool   `json:"added"`
+	KeyType     string `json:"key_type"`
+	Fingerprint string `json:"fingerprint"`
+	DirCreated  bool   `json:"dir_created,omitempty"`
+	DirMode     string `json:"dir_mode"`
+	FileMode    string `json:"file_mode"`
+	Warning     string `json:"warning,omitempty"`
+}
+
+func (s *Server) handleShellAuthorizeKey(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_authorize_key"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	publicKey := strings.TrimSpace(mcp.ParseString(req, "public_key", ""))
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if publicKey == "" {
+		return mcp.NewToolResultError("public_key is required"), nil
+	}
+	key, err := parseSinglePublicKey(publicKey)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+	home, err := ep.home()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("home directory: %v", err)), nil
+	}
+
+	sshDir := strings.TrimSuffix(home, "/") + "/.ssh"
+	keysPath := sshDir + "/authorized_keys"
+	result := AuthorizeKeyResult{
+		Status:      "completed",
+		SessionID:   sessionID,
+		Path:        keysPath,
+		KeyType:     key.Type(),
+		Fingerprint: gossh.FingerprintSHA256(key),
+	}
+
+	if info, err := ep.stat(sshDir); errors.Is(err, fs.ErrNotExist) {
+		if err := ep.mkdirAll(sshDir); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf(errCreateDirs, err)), nil
+		}
+		result.DirCreated = true
+	} else if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("stat %s: %v", sshDir, err)), nil
+	} else if !info.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("%s is not a directory", sshDir)), nil
+	}
+	if err := ep.chmod(sshDir, sshDirPerm); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("chmod %s: %v", sshDir, err)), nil
+	}
+
+	var existing []byte
+	if info, err := ep.stat(keysPath); err == nil {
+		if info.IsDir() {
+			return mcp.NewToolResultError(fmt.Sprintf("path is a directory: %s", keysPath)), nil
+		}
+		if info.Size() > maxAuthorizedKeysSize {
+			return mcp.NewToolResultError(fmt.Sprintf("%s too large: %d bytes (max %d)", keysPath, info.Size(), maxAuthorizedKeysSize)), nil
+		}
+		if existing, err = readEndpointFile(ep, keysPath); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read file: %v", err)), nil
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return mcp.NewToolResultError(fmt.Sprintf("stat file: %v", err)), nil
+	}
+
+	if !authorizedKeysContain(existing, key) {
+		data := existing
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		data = append(data, publicKey+"\n"...)
+
+		tempPath := fmt.Sprintf("%s/.authorized_keys.tmp.%s", sshDir, randomSuffix())
+		if err := writeEndpointFile(ep, tempPath, data, authorizedKeysPerm); err != nil {
+			ep.remove(tempPath)
+			return mcp.NewToolResultError(fmt.Sprintf("write temp file: %v", err)), nil
+		}
+		if err := ep.rename(tempPath, keysPath); err != nil {
+			ep.remove(tempPath)
+			return mcp.NewToolResultError(fmt.Sprintf("rename to final path: %v", err)), nil
+		}
+		result.Added = true
+	}
+	if err := ep.chmod(keysPath, authorizedKeysPerm); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("chmod %s: %v", keysPath, err)), nil
+	}
+
+	// Read the modes back: a umask, ACL or odd server can leave them other
+	// than requested, and sshd ignores keys it considers exposed.
+	dirInfo, err := ep.stat(sshDir)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("verify %s: %v", sshDir, err)), nil
+	}
+	fileInfo, err := ep.stat(keysPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("verify %s: %v", keysPath, err)), nil
+	}
+	result.DirMode = fmt.Sprintf("%04o", dirInfo.Mode().Perm())
+	result.FileMode = fmt.Sprintf("%04o", fileInfo.Mode().Perm())
+	if dirInfo.Mode().Perm() != sshDirPerm || fileInfo.Mode().Perm() != authorizedKeysPerm {
+		result.Warning = fmt.Sprintf("modes are %s and %s, want %04o and %04o; sshd may ignore the key",
+			result.DirMode, result.FileMode, sshDirPerm, authorizedKeysPerm)
+	}
+
+	slog.Info("authorized key",
+		slog.String("session_id", sessionID),
+		slog.String("path", keysPath),
+		slog.String("fingerprint", result.Fingerprint),
+		slog.Bool("added", result.Added),
+	)
+	return jsonResult(result)
+}
+
+// parseSinglePublicKey parses one authorized_keys line.
+func parseSinglePublicKey(line string) (gossh.PublicKey, error) {
+	if strings.ContainsAny(line, "\r\n") {
+		return nil, fmt.Errorf("public_key must be a single key on one line")
+	}
+	key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public_key: %v", err)
+	}
+	return key, nil
+}
+
+// authorizedKeysContain reports whether an authorized_keys file holds key,
+// whatever its options and comment. Lines that do not parse are skipped.
+func authorizedKeysContain(data []byte, key gossh.PublicKey) bool {
+	want := key.Marshal()
+	for len(data) > 0 {
+		k, _, _, rest, err := gossh.ParseAuthorizedKey(data)
+		if err != nil {
+			return false
+		}
+		if bytes.Equal(k.Marshal(), want) {
+			return true
+		}
+		data = rest
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// testAuthorizedKey returns a fresh ed25519 public key in authorized_keys
+// format with the given comment.
+func testAuthorizedKey(t *testing.T, comment string) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key))) + " " + comment
+}
+
+func newAuthorizeKeyServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_keys"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func authorizeKey(t *testing.T, srv *Server, publicKey string) *mcpgo.CallToolResult {
+	t.Helper()
+	result, err := srv.handleShellAuthorizeKey(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_keys",
+		"public_key": publicKey,
+	}))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	return result
+}
+
+func TestAuthorizeKey_CreatesDirAndFile(t *testing.T) {
+	srv, ffs := newAuthorizeKeyServer()
+	key := testAuthorizedKey(t, "deploy@ci")
+
+	result := authorizeKey(t, srv, key)
+	if result.IsError {
+		t.Fatalf("authorize failed: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["added"] != true || m["dir_created"] != true {
+		t.Errorf("added=%v dir_created=%v, want both true", m["added"], m["dir_created"])
+	}
+	if m["path"] != "/home/test/.ssh/authorized_keys" || m["key_type"] != "ssh-ed25519" {
+		t.Errorf("path=%v key_type=%v", m["path"], m["key_type"])
+	}
+	if m["dir_mode"] != "0700" || m["file_mode"] != "0600" || m["warning"] != nil {
+		t.Errorf("dir_mode=%v file_mode=%v warning=%v", m["dir_mode"], m["file_mode"], m["warning"])
+	}
+	if fp, _ := m["fingerprint"].(string); !strings.HasPrefix(fp, "SHA256:") {
+		t.Errorf("fingerprint = %v", m["fingerprint"])
+	}
+
+	data, _ := ffs.ReadFile("/home/test/.ssh/authorized_keys")
+	if string(data) != key+"\n" {
+		t.Errorf("authorized_keys = %q, want %q", data, key+"\n")
+	}
+	for _, name := range ffs.Files() {
+		if strings.Contains(name, ".tmp.") {
+			t.Errorf("temp file left behind: %s", name)
+		}
+	}
+}
+
+func TestAuthorizeKey_AppendsAndFixesModes(t *testing.T) {
+	srv, ffs := newAuthorizeKeyServer()
+	other := testAuthorizedKey(t, "alice@laptop")
+	ffs.AddFile("/home/test/.ssh/authorized_keys", []byte("# team keys\n"+other), 0644)
+	key := testAuthorizedKey(t, "deploy@ci")
+
+	m := resultJSON(t, authorizeKey(t, srv, key))
+	if m["added"] != true || m["dir_created"] != nil {
+		t.Errorf("added=%v dir_created=%v", m["added"], m["dir_created"])
+	}
+	if m["dir_mode"] != "0700" || m["file_mode"] != "0600" {
+		t.Errorf("dir_mode=%v file_mode=%v, want 0700/0600", m["dir_mode"], m["file_mode"])
+	}
+	data, _ := ffs.ReadFile("/home/test/.ssh/authorized_keys")
+	if want := "# team keys\n" + other + "\n" + key + "\n"; string(data) != want {
+		t.Errorf("authorized_keys = %q, want %q", data, want)
+	}
+}
+
+func TestAuthorizeKey_AlreadyPresent(t *testing.T) {
+	srv, ffs := newAuthorizeKeyServer()
+	key := testAuthorizedKey(t, "deploy@ci")
+	fields := strings.Fields(key)
+	// The same key with options and another comment is still a duplicate.
+	existing := `from="10.0.0.0/8" ` + fields[0] + " " + fields[1] + " old-comment\n"
+	ffs.AddFile("/home/test/.ssh/authorized_keys", []byte(existing), 0600)
+
+	m := resultJSON(t, authorizeKey(t, srv, key))
+	if m["added"] != false {
+		t.Errorf("added = %v, want false", m["added"])
+	}
+	data, _ := ffs.ReadFile("/home/test/.ssh/authorized_keys")
+	if string(data) != existing {
+		t.Errorf("authorized_keys changed to %q", data)
+	}
+}
+
+func TestAuthorizeKey_InvalidKey(t *testing.T) {
+	srv, ffs := newAuthorizeKeyServer()
+	key := testAuthorizedKey(t, "a")
+
+	for _, bad := range []string{"", "not a key", key + "\n" + key} {
+		result := authorizeKey(t, srv, bad)
+		if !result.IsError {
+			t.Errorf("public_key %q accepted: %s", bad, resultText(result))
+		}
+	}
+	if len(ffs.Files()) != 0 {
+		t.Errorf("files written for invalid keys: %v", ffs.Files())
+	}
+}
+
+func TestAuthorizeKey_ReadOnly(t *testing.T) {
+	srv, ffs := newAuthorizeKeyServer()
+	srv.config.Security.ReadOnly = true
+
+	result := authorizeKey(t, srv, testAuthorizedKey(t, "a"))
+	if !result.IsError || !strings.Contains(resultText(result), "read_only") {
+		t.Errorf("result = %s, want read_only error", resultText(result))
+	}
+	if len(ffs.Files()) != 0 {
+		t.Errorf("files written in read-only mode: %v", ffs.Files())
+	}
+}
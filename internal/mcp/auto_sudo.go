@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// compileAutoSudoPatterns compiles security.auto_sudo_patterns. Invalid
+// patterns are rejected by config validation; any that slip through are
+// logged and skipped.
+func compileAutoSudoPatterns(cfg *config.Config) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, expr := range cfg.Security.AutoSudoPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			slog.Warn("ignoring invalid auto_sudo pattern",
+				slog.String("pattern", expr),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// autoSudoSeparators split a command line into simple commands. Quotes are
+// not parsed, so a quoted separator only makes a line look compound, which
+// gets the sh -c form. Redirections count too: the calling shell opens
+// their files, outside sudo.
+var autoSudoSeparators = regexp.MustCompile(`&&|\|\||[;|&\n()<>]|\$\(|` + "`")
+
+// shellAssignment matches a leading variable assignment (FOO=1 cmd).
+var shellAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// applyAutoSudo runs command with sudo when one of its simple commands
+// matches an auto-sudo pattern and a sudo password is available to answer
+// the prompt, either cached for the session or from the server's
+// sudo_password_env. A single simple command is prefixed with sudo; any
+// other line runs whole as sudo sh -c '<command>', since a sudo prefix would
+// only cover its first command. Lines that already use sudo are left alone.
+// The sudo form must pass the command filter and read-only checks too, so a
+// blocklist entry for sudo keeps auto-sudo off.
+func (s *Server) applyAutoSudo(sessionID string, sess *session.Session, command string) (string, bool, *mcp.CallToolResult) {
+	if len(s.autoSudoPatterns) == 0 {
+		return command, false, nil
+	}
+	segments := autoSudoSeparators.Split(command, -1)
+	simple := len(segments) == 1
+	var matched *regexp.Regexp
+	for _, segment := range segments {
+		words := strings.Fields(segment)
+		for len(words) > 0 && shellAssignment.MatchString(words[0]) {
+			words = words[1:]
+			simple = false
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if path.Base(words[0]) == "sudo" {
+			return command, false, nil
+		}
+		if matched == nil {
+			matched = s.autoSudoPattern(strings.Join(words, " "))
+		}
+	}
+	if matched == nil {
+		return command, false, nil
+	}
+	if !s.sudoCache.IsValid(sessionID) && s.lookupSudoPasswordFromConfig(sess.ConfigServer()) == nil {
+		slog.Debug("auto-sudo pattern matched but no sudo password is available",
+			slog.String("session_id", sessionID),
+			slog.String("pattern", matched.String()),
+		)
+		return command, false, nil
+	}
+
+	sudoCommand := "sudo " + command
+	if !simple {
+		sudoCommand = "sudo sh -c " + shellQuote(command)
+	}
+	if allowed, reason := s.filter().IsAllowed(sudoCommand); !allowed {
+		slog.Warn("command blocked by filter", slog.String("command", sudoCommand), slog.String("reason", reason))
+		return "", false, mcp.NewToolResultError("command blocked: " + reason)
+	}
+	if errResult := s.checkReadOnlyCommand(sudoCommand); errResult != nil {
+		return "", false, errResult
+	}
+
+	slog.Info("auto-sudo applied",
+		slog.String("session_id", sessionID),
+		slog.String("command", command),
+		slog.String("pattern", matched.String()),
+	)
+	return sudoCommand, true, nil
+}
+
+// autoSudoPattern returns the first auto-sudo pattern matching the simple
+// command cmd, or nil.
+func (s *Server) autoSudoPattern(cmd string) *regexp.Regexp {
+	for _, re := range s.autoSudoPatterns {
+		if re.MatchString(cmd) {
+			return re
+		}
+	}
+	return nil
+}
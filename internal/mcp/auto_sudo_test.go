@@ -0,0 +1,191 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+const autoSudoOutput = "___CMD_START_00010203___\nrestarted\n___CMD_END_00010203___0\n"
+
+func newAutoSudoServer(t *testing.T, sm *fakesessionmgr.Manager, ffs *fakefs.FS, mutate func(*config.Config)) *Server {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Security.AutoSudoPatterns = []string{`^systemctl (restart|reload) `}
+	if mutate != nil {
+		mutate(cfg)
+	}
+	return NewServer(cfg,
+		WithSessionManager(sm),
+		WithFileSystem(ffs),
+		WithClock(fakeclock.New(time.Now())),
+	)
+}
+
+func TestHandleShellExec_AutoSudoWithCachedPassword(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_autosudo")
+	sm.AddSession(sess)
+	srv := newAutoSudoServer(t, sm, fakefs.New(), nil)
+	srv.sudoCache.Set("sess_autosudo", []byte("cachedpw"))
+	pty.AddResponse(autoSudoOutput)
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_autosudo",
+		"command":    "systemctl restart nginx",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["auto_sudo"] != true {
+		t.Errorf("auto_sudo = %v, want true", m["auto_sudo"])
+	}
+	if !strings.Contains(pty.Written(), "sudo systemctl restart nginx") {
+		t.Errorf("written = %q, want sudo prefix", pty.Written())
+	}
+}
+
+func TestHandleShellExec_AutoSudoWithConfigPassword(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_autosudo_cfg")
+	sess.Host = "web1.example.com"
+	sm.AddSession(sess)
+	ffs := fakefs.New()
+	ffs.SetEnv("WEB1_SUDO", "envpw")
+	srv := newAutoSudoServer(t, sm, ffs, func(cfg *config.Config) {
+		cfg.Servers = []config.ServerConfig{{Name: "web1", Host: "web1.example.com", SudoPasswordEnv: "WEB1_SUDO"}}
+	})
+	pty.AddResponse(autoSudoOutput)
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_autosudo_cfg",
+		"command":    "systemctl reload nginx",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %s", err, resultText(result))
+	}
+	if !strings.Contains(pty.Written(), "sudo systemctl reload nginx") {
+		t.Errorf("written = %q, want sudo prefix", pty.Written())
+	}
+}
+
+func TestHandleShellExec_AutoSudoNeedsPassword(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_autosudo_nopw")
+	sm.AddSession(sess)
+	srv := newAutoSudoServer(t, sm, fakefs.New(), nil)
+	pty.AddResponse(autoSudoOutput)
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_autosudo_nopw",
+		"command":    "systemctl restart nginx",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("unexpected error: %v %s", err, resultText(result))
+	}
+	if _, ok := resultJSON(t, result)["auto_sudo"]; ok {
+		t.Error("auto_sudo should not be set without a sudo password")
+	}
+	if strings.Contains(pty.Written(), "sudo ") {
+		t.Errorf("written = %q, want no sudo prefix", pty.Written())
+	}
+}
+
+func TestApplyAutoSudo(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, _ := newFakeSessionWithRand("sess_apply")
+	srv := newAutoSudoServer(t, sm, fakefs.New(), nil)
+	srv.sudoCache.Set("sess_apply", []byte("pw"))
+
+	tests := []struct {
+		command string
+		want    string
+		applied bool
+	}{
+		{"systemctl restart nginx", "sudo systemctl restart nginx", true},
+		{"systemctl status nginx", "systemctl status nginx", false},
+		{"sudo systemctl restart nginx", "sudo systemctl restart nginx", false},
+		{"FOO=1 sudo systemctl restart nginx", "FOO=1 sudo systemctl restart nginx", false},
+		{"cd /etc && sudo systemctl restart nginx", "cd /etc && sudo systemctl restart nginx", false},
+		// A sudo prefix would only cover the first command of these.
+		{"cd /etc && systemctl restart nginx", `sudo sh -c 'cd /etc && systemctl restart nginx'`, true},
+		{"echo x; systemctl restart foo", `sudo sh -c 'echo x; systemctl restart foo'`, true},
+		{"systemctl reload nginx | tee /tmp/out", `sudo sh -c 'systemctl reload nginx | tee /tmp/out'`, true},
+		{"echo 'a;b'; systemctl restart nginx", `sudo sh -c 'echo '\''a;b'\''; systemctl restart nginx'`, true},
+		{"FOO=1 systemctl restart nginx", `sudo sh -c 'FOO=1 systemctl restart nginx'`, true},
+		{"echo systemctl restart nginx && ls", "echo systemctl restart nginx && ls", false},
+	}
+	for _, tt := range tests {
+		got, applied, errResult := srv.applyAutoSudo("sess_apply", sess, tt.command)
+		if errResult != nil {
+			t.Fatalf("applyAutoSudo(%q) error: %s", tt.command, resultText(errResult))
+		}
+		if got != tt.want || applied != tt.applied {
+			t.Errorf("applyAutoSudo(%q) = %q, %v; want %q, %v", tt.command, got, applied, tt.want, tt.applied)
+		}
+	}
+}
+
+func TestApplyAutoSudo_RespectsCommandFilter(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_blocked")
+	sm.AddSession(sess)
+	srv := newAutoSudoServer(t, sm, fakefs.New(), func(cfg *config.Config) {
+		cfg.Security.CommandBlocklist = []string{`^sudo\b`}
+	})
+	srv.sudoCache.Set("sess_blocked", []byte("pw"))
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_blocked",
+		"command":    "systemctl restart nginx",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "command blocked") {
+		t.Errorf("expected command blocked, got %s", resultText(result))
+	}
+	if pty.Written() != "" {
+		t.Errorf("nothing should be written, got %q", pty.Written())
+	}
+}
+
+func TestApplyAutoSudo_CompoundCommandFiltered(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_compound")
+	sm.AddSession(sess)
+	srv := newAutoSudoServer(t, sm, fakefs.New(), func(cfg *config.Config) {
+		cfg.Security.CommandBlocklist = []string{`^sudo sh -c`}
+	})
+	srv.sudoCache.Set("sess_compound", []byte("pw"))
+
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_compound",
+		"command":    "cd /etc && systemctl restart nginx",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "command blocked") {
+		t.Errorf("expected the sh -c form to be blocked, got %s", resultText(result))
+	}
+	if pty.Written() != "" {
+		t.Errorf("nothing should be written, got %q", pty.Written())
+	}
+}
+
+func TestUpdateConfig_ReloadsAutoSudoPatterns(t *testing.T) {
+	srv := newAutoSudoServer(t, fakesessionmgr.New(), fakefs.New(), nil)
+
+	cfg := config.DefaultConfig()
+	srv.UpdateConfig(cfg)
+	if len(srv.autoSudoPatterns) != 0 {
+		t.Errorf("autoSudoPatterns = %v, want none after reload", srv.autoSudoPatterns)
+	}
+}
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// defaultBroadcastParallel is how many sessions shell_exec_broadcast runs
+	// the command on at once.
+	defaultBroadcastParallel = 8
+	maxBroadcastParallel     = 64
+)
+
+// registerBroadcastTools registers the tag-based bulk tools.
+func (s *Server) registerBroadcastTools() {
+	s.mcpServer.AddTool(shellExecBroadcastTool(), s.handleShellExecBroadcast)
+}
+
+func shellExecBroadcastTool() mcp.Tool {
+	return mcp.NewTool("shell_exec_broadcast",
+		mcp.WithDescription(`Run the same command in every session with a tag.
+
+Sessions get tags at shell_session_create. The command runs concurrently
+(up to max_parallel sessions at a time) and the result maps each session_id
+to its shell_exec result. Sessions that could not run the command are listed
+under "errors"; "succeeded" counts commands that completed with exit code 0.
+
+Commands that stop at a prompt report awaiting_input for that session;
+answer them individually with shell_provide_input.`),
+		mcp.WithString("tag",
+			mcp.Required(),
+			mcp.Description("Run in all sessions with this tag"),
+		),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("The command to execute"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Per-session timeout in milliseconds (default: 30000)"),
+		),
+		mcp.WithNumber("max_parallel",
+			mcp.Description(fmt.Sprintf("Sessions to run at once (default: %d, max: %d)", defaultBroadcastParallel, maxBroadcastParallel)),
+		),
+		destructiveTool(),
+	)
+}
+
+// BroadcastResult is the result of shell_exec_broadcast.
+type BroadcastResult struct {
+	Tag       string                         `json:"tag"`
+	Command   string                         `json:"command"`
+	Sessions  int                            `json:"sessions"`
+	Succeeded int                            `json:"succeeded"`
+	Failed    int                            `json:"failed"`
+	Results   map[string]*session.ExecResult `json:"results"`
+	Errors    map[string]string              `json:"errors,omitempty"`
+}
+
+func (s *Server) handleShellExecBroadcast(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	tag := mcp.ParseString(req, "tag", "")
+	command := mcp.ParseString(req, "command", "")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
+	maxParallel := mcp.ParseInt(req, "max_parallel", defaultBroadcastParallel)
+
+	if tag == "" {
+		return mcp.NewToolResultError("tag is required"), nil
+	}
+	if command == "" {
+		return mcp.NewToolResultError("command is required"), nil
+	}
+	maxParallel = min(max(maxParallel, 1), maxBroadcastParallel)
+
+	if allowed, reason := s.filter().IsAllowed(command); !allowed {
+		slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+		return mcp.NewToolResultError("command blocked: " + reason), nil
+	}
+	if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+		return errResult, nil
+	}
+
+	var ids []string
+	for _, info := range s.sessionManager.ListDetailed() {
+		if slices.Contains(info.Tags, tag) {
+			ids = append(ids, info.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no sessions tagged %q", tag)), nil
+	}
+	slices.Sort(ids)
+
+	slog.Info("broadcasting command",
+		slog.String("tag", tag),
+		slog.String("command", command),
+		slog.Int("sessions", len(ids)),
+	)
+
+	result := BroadcastResult{
+		Tag:      tag,
+		Command:  command,
+		Sessions: len(ids),
+		Results:  make(map[string]*session.ExecResult, len(ids)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	work := make(chan string)
+	for range min(maxParallel, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				execResult, err := s.execInSession(ctx, id, command, timeoutMs)
+				mu.Lock()
+				if err != nil {
+					if result.Errors == nil {
+						result.Errors = make(map[string]string)
+					}
+					result.Errors[id] = err.Error()
+					result.Failed++
+				} else {
+					result.Results[id] = execResult
+					if execResult.Status == "completed" && execResult.ExitCode != nil && *execResult.ExitCode == 0 {
+						result.Succeeded++
+					} else {
+						result.Failed++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range ids {
+		work <- id
+	}
+	close(work)
+	wg.Wait()
+
+	return jsonResult(result)
+}
+
+// execInSession runs command in one session the way shell_exec does.
+func (s *Server) execInSession(ctx context.Context, sessionID, command string, timeoutMs int) (*session.ExecResult, error) {
+	result, err := s.execInSessionFull(ctx, sessionID, command, timeoutMs)
+	if err != nil {
+		return nil, err
+	}
+	s.applyAutoTruncation(sessionID, command, result)
+	return result, nil
+}
+
+// execInSessionFull is execInSession without the auto-truncation of large
+// output, for callers that inspect all of it.
+func (s *Server) execInSessionFull(ctx context.Context, sessionID, command string, timeoutMs int) (*session.ExecResult, error) {
+	return s.execInSessionWith(ctx, sessionID, command, timeoutMs, session.ExecOptions{})
+}
+
+// execInSessionWith is execInSessionFull with per-call exec options.
+func (s *Server) execInSessionWith(ctx context.Context, sessionID, command string, timeoutMs int, opts session.ExecOptions) (*session.ExecResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("not run: %w", err)
+	}
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	sudoCheck := s.sudoCheckBefore(sessionID, sess, command)
+	s.recordingManager.RecordInput(sessionID, command+"\n", false)
+	started := s.clock.Now()
+	result, err := sess.ExecWithOptions(command, timeoutMs, opts)
+	if err != nil {
+		return nil, err
+	}
+	s.recordingManager.RecordOutput(sessionID, result.Stdout)
+
+	result, err = s.tryCachedSudoInjection(sessionID, sess, result, sudoCheck)
+	if err != nil {
+		return nil, err
+	}
+	result.DurationMs = s.clock.Now().Sub(started).Milliseconds()
+	return result, nil
+}
+
+// parseStringArray reads an array-of-strings argument. Clients that send
+// arrays as a JSON-encoded string or a comma-separated list are accepted too.
+func parseStringArray(req mcp.CallToolRequest, name string) ([]string, error) {
+	raw, ok := req.GetArguments()[name]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	switch v := raw.(type) {
+	case []string:
+		return v, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array of strings", name)
+			}
+			out = append(out, str)
+		}
+		return out, nil
+	case string:
+		var out []string
+		if strings.HasPrefix(strings.TrimSpace(v), "[") {
+			if err := json.Unmarshal([]byte(v), &out); err != nil {
+				return nil, fmt.Errorf("%s must be an array of strings: %w", name, err)
+			}
+			return out, nil
+		}
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s must be an array of strings", name)
+}
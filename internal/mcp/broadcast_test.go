@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// addTaggedSession adds a session whose next command prints output and exits
+// with exitCode.
+func addTaggedSession(sm *fakesessionmgr.Manager, id string, tags []string, output string, exitCode int) *fakepty.PTY {
+	sess, pty := newFakeSessionWithRand(id)
+	sess.Tags = tags
+	cmdID := "00010203"
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n" + output + "\n___CMD_END_" + cmdID + "___" + string(rune('0'+exitCode)) + "\n")
+	sm.AddSession(sess)
+	return pty
+}
+
+func TestHandleShellExecBroadcast(t *testing.T) {
+	sm := fakesessionmgr.New()
+	addTaggedSession(sm, "sess_web1", []string{"prod", "web"}, "ok-1", 0)
+	addTaggedSession(sm, "sess_web2", []string{"web"}, "disk full", 1)
+	dbPTY := addTaggedSession(sm, "sess_db", []string{"db"}, "never", 0)
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellExecBroadcast(context.Background(), makeRequest(map[string]any{
+		"tag":          "web",
+		"command":      "df -h /",
+		"max_parallel": float64(2),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["sessions"] != float64(2) || m["succeeded"] != float64(1) || m["failed"] != float64(1) {
+		t.Errorf("sessions=%v succeeded=%v failed=%v, want 2/1/1", m["sessions"], m["succeeded"], m["failed"])
+	}
+	results := m["results"].(map[string]any)
+	if r, ok := results["sess_web1"].(map[string]any); !ok || !strings.Contains(r["stdout"].(string), "ok-1") {
+		t.Errorf("sess_web1 result = %v", results["sess_web1"])
+	}
+	if r, ok := results["sess_web2"].(map[string]any); !ok || r["exit_code"] != float64(1) {
+		t.Errorf("sess_web2 result = %v", results["sess_web2"])
+	}
+	if _, ok := results["sess_db"]; ok {
+		t.Error("untagged session should not run the command")
+	}
+	if strings.Contains(dbPTY.Written(), "df -h") {
+		t.Error("command was written to an untagged session")
+	}
+}
+
+func TestHandleShellExecBroadcast_SessionErrors(t *testing.T) {
+	sm := fakesessionmgr.New()
+	addTaggedSession(sm, "sess_ok", []string{"fleet"}, "fine", 0)
+	broken, _ := newFakeSessionWithRand("sess_closed")
+	broken.Tags = []string{"fleet"}
+	broken.State = session.StateClosed
+	sm.AddSession(broken)
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellExecBroadcast(context.Background(), makeRequest(map[string]any{
+		"tag":     "fleet",
+		"command": "uptime",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	errs, _ := m["errors"].(map[string]any)
+	if _, ok := errs["sess_closed"]; !ok {
+		t.Errorf("errors = %v, want entry for sess_closed", m["errors"])
+	}
+	if m["succeeded"] != float64(1) || m["failed"] != float64(1) {
+		t.Errorf("succeeded=%v failed=%v, want 1/1", m["succeeded"], m["failed"])
+	}
+}
+
+func TestHandleShellExecBroadcast_Validation(t *testing.T) {
+	sm := fakesessionmgr.New()
+	addTaggedSession(sm, "sess_1", []string{"web"}, "", 0)
+	srv := newTestServer(sm)
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"command": "ls"}, "tag is required"},
+		{map[string]any{"tag": "web"}, "command is required"},
+		{map[string]any{"tag": "nope", "command": "ls"}, `no sessions tagged "nope"`},
+	}
+	for _, tt := range tests {
+		result, err := srv.handleShellExecBroadcast(context.Background(), makeRequest(tt.args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
+
+func TestHandleShellSessionList_FilterByTag(t *testing.T) {
+	sm := fakesessionmgr.New()
+	addTaggedSession(sm, "sess_web", []string{"web"}, "", 0)
+	addTaggedSession(sm, "sess_db", []string{"db"}, "", 0)
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionList(context.Background(), makeRequest(map[string]any{"tag": "db"}))
+	m := resultJSON(t, result)
+	sessions := m["sessions"].([]any)
+	if len(sessions) != 1 || sessions[0].(map[string]any)["session_id"] != "sess_db" {
+		t.Errorf("sessions = %v, want only sess_db", sessions)
+	}
+}
+
+func TestHandleShellSessionCreate_Tags(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"tags": []any{"web", "prod", "web"},
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	if strings.Join(got.Tags, ",") != "prod,web" {
+		t.Errorf("Tags = %v, want [prod web]", got.Tags)
+	}
+
+	result, _ = srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"tags": []any{"has space"},
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid tag") {
+		t.Errorf("expected invalid tag error, got: %s", resultText(result))
+	}
+}
+
+func TestParseStringArray(t *testing.T) {
+	tests := []struct {
+		raw  any
+		want string
+	}{
+		{[]any{"a", "b"}, "a,b"},
+		{`["a","b"]`, "a,b"},
+		{"a, b", "a,b"},
+	}
+	for _, tt := range tests {
+		got, err := parseStringArray(makeRequest(map[string]any{"tags": tt.raw}), "tags")
+		if err != nil {
+			t.Errorf("%v: %v", tt.raw, err)
+			continue
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("%v: got %v, want %s", tt.raw, got, tt.want)
+		}
+	}
+	if _, err := parseStringArray(makeRequest(map[string]any{"tags": []any{1}}), "tags"); err == nil {
+		t.Error("expected error for non-string items")
+	}
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	// clientEOFConfirmations is how many EOFs in a row, clientEOFRetryDelay
+	// apart, mean the client is gone. A terminal on stdin reports EOF once
+	// per Ctrl-D and then keeps reading.
+	clientEOFConfirmations = 3
+	clientEOFRetryDelay    = 200 * time.Millisecond
+
+	// maxTransientReadErrors bounds retries of interrupted or would-block
+	// reads before the error is treated as fatal.
+	maxTransientReadErrors = 5
+	transientReadDelay     = 100 * time.Millisecond
+)
+
+// clientReader wraps the MCP input so that only a lasting EOF reads as the
+// client going away. Transient read errors are retried.
+type clientReader struct {
+	r     io.Reader
+	clock ports.Clock
+}
+
+func (c *clientReader) Read(p []byte) (int, error) {
+	eofs, transient := 0, 0
+	for {
+		n, err := c.r.Read(p)
+		if n > 0 {
+			// An error that came with data is returned by the next Read.
+			return n, nil
+		}
+		switch {
+		case err == nil:
+			c.clock.Sleep(transientReadDelay)
+		case errors.Is(err, io.EOF):
+			eofs++
+			if eofs >= clientEOFConfirmations {
+				return 0, io.EOF
+			}
+			c.clock.Sleep(clientEOFRetryDelay)
+		case isTransientReadError(err):
+			transient++
+			if transient > maxTransientReadErrors {
+				return 0, err
+			}
+			slog.Debug("retrying MCP input read", slog.String("error", err.Error()))
+			c.clock.Sleep(transientReadDelay)
+		default:
+			return 0, err
+		}
+	}
+}
+
+// isTransientReadError reports whether a read error is worth retrying.
+func isTransientReadError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EAGAIN) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// serveStdio serves MCP over stdin/stdout until the client disconnects.
+// With shutdown.exit_on_client_disconnect the server then shuts down,
+// closing all sessions; otherwise it keeps them running until Shutdown is
+// called (e.g. on SIGTERM).
+func (s *Server) serveStdio(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	stdio := server.NewStdioServer(s.mcpServer)
+	if err := stdio.Listen(ctx, &clientReader{r: stdin, clock: s.clock}, stdout); err != nil {
+		return err
+	}
+
+	if !s.config.Shutdown.ExitOnClientDisconnect {
+		slog.Warn("MCP client disconnected; sessions keep running until the server is stopped",
+			slog.Bool("exit_on_client_disconnect", false),
+		)
+		<-s.stopped
+		return nil
+	}
+
+	slog.Info("MCP client disconnected, shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownGracePeriod())
+	defer cancel()
+	err := s.Shutdown(shutdownCtx)
+	if errors.Is(err, errShutdownInProgress) {
+		// A signal got there first; let that shutdown finish.
+		<-s.stopped
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// scriptedReader returns each step's data or error in turn, then EOF.
+type scriptedReader struct {
+	steps []readStep
+	reads int
+}
+
+type readStep struct {
+	data string
+	err  error
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	r.reads++
+	if len(r.steps) == 0 {
+		return 0, io.EOF
+	}
+	step := r.steps[0]
+	r.steps = r.steps[1:]
+	return copy(p, step.data), step.err
+}
+
+func newClientReader(steps ...readStep) (*clientReader, *scriptedReader) {
+	src := &scriptedReader{steps: steps}
+	return &clientReader{r: src, clock: fakeclock.New(time.Now())}, src
+}
+
+func TestClientReader_RetriesTransientErrors(t *testing.T) {
+	r, _ := newClientReader(
+		readStep{err: os.NewSyscallError("read", syscall.EINTR)},
+		readStep{err: os.NewSyscallError("read", syscall.EAGAIN)},
+		readStep{data: "hello\n"},
+	)
+
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "hello\n" {
+		t.Fatalf("Read = %q, %v; want data after transient errors", buf[:n], err)
+	}
+}
+
+func TestClientReader_SingleEOFIsNotADisconnect(t *testing.T) {
+	r, _ := newClientReader(
+		readStep{err: io.EOF},
+		readStep{data: "still here\n"},
+	)
+
+	buf := make([]byte, 16)
+	n, err := r.Read(buf)
+	if err != nil || string(buf[:n]) != "still here\n" {
+		t.Fatalf("Read = %q, %v; want data after one EOF", buf[:n], err)
+	}
+}
+
+func TestClientReader_RepeatedEOFIsADisconnect(t *testing.T) {
+	r, src := newClientReader()
+
+	if _, err := r.Read(make([]byte, 16)); err != io.EOF {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+	if src.reads != clientEOFConfirmations {
+		t.Errorf("reads = %d, want %d confirmations", src.reads, clientEOFConfirmations)
+	}
+}
+
+func TestClientReader_PersistentErrorIsReturned(t *testing.T) {
+	eintr := os.NewSyscallError("read", syscall.EINTR)
+	steps := make([]readStep, maxTransientReadErrors+1)
+	for i := range steps {
+		steps[i] = readStep{err: eintr}
+	}
+	r, _ := newClientReader(steps...)
+	if _, err := r.Read(make([]byte, 16)); !errors.Is(err, syscall.EINTR) {
+		t.Errorf("err = %v, want EINTR after retries run out", err)
+	}
+
+	r, _ = newClientReader(readStep{err: syscall.EBADF})
+	if _, err := r.Read(make([]byte, 16)); !errors.Is(err, syscall.EBADF) {
+		t.Errorf("err = %v, want EBADF returned at once", err)
+	}
+}
+
+const initializeMessage = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}` + "\n"
+
+func newDisconnectServer(sm *fakesessionmgr.Manager, exit bool) *Server {
+	cfg := config.DefaultConfig()
+	cfg.Shutdown.GracePeriod = time.Second
+	cfg.Shutdown.ExitOnClientDisconnect = exit
+	return newTestServerWithConfig(sm, fakefs.New(), cfg)
+}
+
+func TestServeStdio_ClientDisconnectShutsDown(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_orphan"))
+	srv := newDisconnectServer(sm, true)
+
+	if err := srv.serveStdio(context.Background(), strings.NewReader(initializeMessage), io.Discard); err != nil {
+		t.Fatalf("serveStdio error: %v", err)
+	}
+	if n := len(sm.ListDetailed()); n != 0 {
+		t.Errorf("%d sessions left open after client disconnect, want 0", n)
+	}
+}
+
+func TestServeStdio_KeepsSessionsWhenExitDisabled(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_kept"))
+	srv := newDisconnectServer(sm, false)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.serveStdio(context.Background(), strings.NewReader(initializeMessage), io.Discard)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("serveStdio returned %v before shutdown", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if n := len(sm.ListDetailed()); n != 1 {
+		t.Errorf("%d sessions open, want 1 kept running", n)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveStdio error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveStdio did not return after Shutdown")
+	}
+}
@@ -52,6 +52,7 @@ Requires a config file path (--config flag at startup).`),
 		mcp.WithString("sudo_password_env",
 			mcp.Description("Environment variable name containing the sudo password (optional)"),
 		),
+		additiveTool(),
 	)
 }
 
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerConnectionTools registers the SSH connection pool tools.
+func (s *Server) registerConnectionTools() {
+	s.mcpServer.AddTool(shellConnectionListTool(), s.handleShellConnectionList)
+	s.mcpServer.AddTool(shellConnectionCloseTool(), s.handleShellConnectionClose)
+}
+
+func shellConnectionListTool() mcp.Tool {
+	return mcp.NewTool("shell_connection_list",
+		mcp.WithDescription(`List the pooled SSH connections shared by sessions.
+
+SSH sessions to the same user@host:port share one authenticated connection
+only when they also use the same configured server, credentials and proxy.
+Each entry reports:
+- key: user@host:port, with the server, identity, key, password digest and
+  proxy appended when set (e.g. #server:prod#key:~/.ssh/id_ed25519), used
+  with shell_connection_close
+- refs: number of sessions holding the connection
+- connected: whether the underlying transport is up
+- closed: force-closed and waiting for its sessions to reconnect
+- sessions: IDs of the sessions using it
+
+Use this to find a wedged shared connection.`),
+		readOnlyTool(),
+	)
+}
+
+func shellConnectionCloseTool() mcp.Tool {
+	return mcp.NewTool("shell_connection_close",
+		mcp.WithDescription(`Force-close a pooled SSH connection, even if sessions still use it.
+
+Recovers from a wedged shared connection without restarting the server.
+Sessions using the connection are marked disconnected (see connection_error in
+shell_session_status) and reconnect on their next command; running commands
+on them are lost. The result lists the affected sessions with a warning.`),
+		mcp.WithString("key",
+			mcp.Required(),
+			mcp.Description("Connection key (e.g. user@host:port) from shell_connection_list"),
+		),
+		destructiveTool(),
+	)
+}
+
+// ConnectionListResult is the result of shell_connection_list.
+type ConnectionListResult struct {
+	Connections []session.PooledConnection  `json:"connections"`
+	Pool        session.ConnectionPoolStats `json:"pool"`
+}
+
+// ConnectionCloseResult is the result of shell_connection_close.
+type ConnectionCloseResult struct {
+	Key             string   `json:"key"`
+	Closed          bool     `json:"closed"`
+	DroppedSessions []string `json:"dropped_sessions,omitempty"`
+	Warning         string   `json:"warning,omitempty"`
+}
+
+func (s *Server) handleShellConnectionList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	conns := s.sessionManager.Connections()
+	if conns == nil {
+		conns = []session.PooledConnection{}
+	}
+	return jsonResult(ConnectionListResult{
+		Connections: conns,
+		Pool:        s.sessionManager.PoolStats(),
+	})
+}
+
+func (s *Server) handleShellConnectionClose(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	key := mcp.ParseString(req, "key", "")
+	if key == "" {
+		return mcp.NewToolResultError("key is required"), nil
+	}
+
+	dropped, err := s.sessionManager.CloseConnection(key)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := ConnectionCloseResult{Key: key, Closed: true, DroppedSessions: dropped}
+	if len(dropped) > 0 {
+		result.Warning = fmt.Sprintf("%d session(s) were using this connection; they are disconnected and will reconnect on their next command", len(dropped))
+	}
+	slog.Info("pooled connection force-closed",
+		slog.String("key", key),
+		slog.Int("dropped_sessions", len(dropped)),
+	)
+	return jsonResult(result)
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newConnectionTestManager() *fakesessionmgr.Manager {
+	sm := fakesessionmgr.New()
+	sm.Pool = session.ConnectionPoolStats{Connections: 2, Sessions: 3, Reuses: 1}
+	sm.Conns = []session.PooledConnection{
+		{Key: "deploy@a.example.com:22", Refs: 2, Connected: true, Sessions: []string{"sess_1", "sess_2"}},
+		{Key: "root@b.example.com:22", Refs: 0, Connected: true},
+	}
+	return sm
+}
+
+func TestHandleShellConnectionList(t *testing.T) {
+	srv := newTestServer(newConnectionTestManager())
+
+	result, err := srv.handleShellConnectionList(context.Background(), makeRequest(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := resultJSON(t, result)
+	conns, ok := m["connections"].([]any)
+	if !ok || len(conns) != 2 {
+		t.Fatalf("connections = %v, want 2 entries", m["connections"])
+	}
+	first := conns[0].(map[string]any)
+	if first["key"] != "deploy@a.example.com:22" || first["refs"] != float64(2) {
+		t.Errorf("first connection = %v", first)
+	}
+	if pool := m["pool"].(map[string]any); pool["connections"] != float64(2) {
+		t.Errorf("pool = %v, want connections=2", pool)
+	}
+}
+
+func TestHandleShellConnectionList_Empty(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	result, err := srv.handleShellConnectionList(context.Background(), makeRequest(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := resultJSON(t, result)
+	if conns, ok := m["connections"].([]any); !ok || len(conns) != 0 {
+		t.Errorf("connections = %v, want empty array", m["connections"])
+	}
+}
+
+func TestHandleShellConnectionClose_WarnsAboutDroppedSessions(t *testing.T) {
+	sm := newConnectionTestManager()
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellConnectionClose(context.Background(), makeRequest(map[string]any{
+		"key": "deploy@a.example.com:22",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["closed"] != true {
+		t.Errorf("closed = %v, want true", m["closed"])
+	}
+	if dropped, _ := m["dropped_sessions"].([]any); len(dropped) != 2 {
+		t.Errorf("dropped_sessions = %v, want 2", m["dropped_sessions"])
+	}
+	if w, _ := m["warning"].(string); !strings.Contains(w, "2 session(s)") {
+		t.Errorf("warning = %q, want mention of 2 sessions", w)
+	}
+	if len(sm.Conns) != 1 {
+		t.Errorf("connections after close = %v, want 1", sm.Conns)
+	}
+}
+
+func TestHandleShellConnectionClose_Unreferenced(t *testing.T) {
+	srv := newTestServer(newConnectionTestManager())
+
+	result, err := srv.handleShellConnectionClose(context.Background(), makeRequest(map[string]any{
+		"key": "root@b.example.com:22",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := resultJSON(t, result)
+	if _, ok := m["warning"]; ok {
+		t.Errorf("unexpected warning for unreferenced connection: %v", m["warning"])
+	}
+}
+
+func TestHandleShellConnectionClose_Errors(t *testing.T) {
+	srv := newTestServer(newConnectionTestManager())
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing key", nil, "key is required"},
+		{"unknown key", map[string]any{"key": "nobody@nowhere:22"}, "no pooled connection"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := srv.handleShellConnectionClose(context.Background(), makeRequest(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ const (
 	errWalkDir           = "walk directory: %v"
 	errOpenRemoteFile    = "open remote file: %v"
 	errOpenLocalFile     = "open local file: %v"
+	errPathIsDirectory   = "path is a directory, use format 'tar' or 'tar.gz' or shell_dir_get for directories"
+	errFormatRequiresDir = "format is only supported when the path is a directory"
 
 	// Peak-tty constants
 	peakTTYPgrepCmd   = "pgrep -x peak-tty 2>/dev/null || true"
@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxChmodListed bounds the entries listed in DirChmodResult.Changed; the
+// counts cover all of them.
+const maxChmodListed = 100
+
+// registerDirChmodTools registers the recursive permission repair tool.
+func (s *Server) registerDirChmodTools() {
+	s.mcpServer.AddTool(shellDirChmodTool(), s.handleShellDirChmod)
+}
+
+func shellDirChmodTool() mcp.Tool {
+	return mcp.NewTool("shell_dir_chmod",
+		mcp.WithDescription(`Set permissions recursively on a directory tree, e.g. to make scripts executable again after a transfer.
+
+For SSH sessions, changes modes over SFTP; for local sessions, through the
+server's filesystem. file_mode is applied to regular files and dir_mode to
+directories, including path itself; give either or both. Entries that already
+have the mode are left alone and counted as unchanged. Symlinks are skipped,
+never followed.
+
+pattern (e.g. "**/*.sh") limits which files get file_mode; directories get
+dir_mode whatever the pattern. The same names as shell_dir_put (.git,
+node_modules, ...) are excluded and not descended into.
+
+With dry_run, reports what would change without changing anything. Entries
+that fail don't stop the walk; the result then has status
+"completed_with_errors" and the failures in errors, like a directory transfer.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Directory to walk (relative paths use session's cwd)"),
+		),
+		mcp.WithString("file_mode",
+			mcp.Description("Permissions for files in octal (e.g. '0644', '0755')"),
+		),
+		mcp.WithString("dir_mode",
+			mcp.Description("Permissions for directories in octal (e.g. '0755'); directories are left alone without it"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern selecting the files to change (e.g. '*.sh', '**/bin/*')"),
+		),
+		mcp.WithNumber("max_depth",
+			mcp.Description("Maximum directory depth to traverse (default: 20)"),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Report the changes without making them (default: false)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// DirChmodOptions contains options for shell_dir_chmod. A nil mode leaves
+// that kind of entry alone.
+type DirChmodOptions struct {
+	FileMode   *os.FileMode
+	DirMode    *os.FileMode
+	Pattern    string
+	MaxDepth   int
+	DryRun     bool
+	Exclusions []string
+}
+
+// DirChmodChange is an entry whose mode was (or, with dry_run, would be)
+// changed.
+type DirChmodChange struct {
+	Path string `json:"path"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DirChmodResult is the result of shell_dir_chmod. Errors are recorded and
+// summarized as for a directory transfer.
+type DirChmodResult struct {
+	Status           string           `json:"status"`
+	Path             string           `json:"path"`
+	DryRun           bool             `json:"dry_run,omitempty"`
+	FilesChanged     int              `json:"files_changed"`
+	DirsChanged      int              `json:"dirs_changed"`
+	Unchanged        int              `json:"unchanged"`
+	SymlinksSkipped  int              `json:"symlinks_skipped,omitempty"`
+	Changed          []DirChmodChange `json:"changed,omitempty"`
+	ChangedTruncated bool             `json:"changed_truncated,omitempty"`
+	Errors           []TransferError  `json:"errors,omitempty"`
+	ErrorSummary     map[string]int   `json:"error_summary,omitempty"`
+	ErrorsOmitted    int              `json:"errors_omitted,omitempty"`
+	DurationMs       int64            `json:"duration_ms,omitempty"`
+}
+
+func (s *Server) handleShellDirChmod(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_dir_chmod"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	target := mcp.ParseString(req, "path", "")
+	opts := DirChmodOptions{
+		Pattern:    mcp.ParseString(req, "pattern", ""),
+		MaxDepth:   mcp.ParseInt(req, "max_depth", 20),
+		DryRun:     mcp.ParseBoolean(req, "dry_run", false),
+		Exclusions: defaultExclusions,
+	}
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if strings.TrimSpace(target) == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+	var err error
+	if opts.FileMode, err = parseChmodMode(req, "file_mode"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if opts.DirMode, err = parseChmodMode(req, "dir_mode"); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if opts.FileMode == nil && opts.DirMode == nil {
+		return mcp.NewToolResultError("file_mode or dir_mode is required"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	root, _, err := resolveRmPath(sess, ep, target)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	info, err := ep.stat(root)
+	if err != nil {
+		return fileStatError(root, err), nil
+	}
+	if !info.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("%s is not a directory", root)), nil
+	}
+
+	slog.Info("changing permissions recursively",
+		slog.String("session_id", sessionID),
+		slog.String("path", root),
+		slog.Bool("dry_run", opts.DryRun),
+	)
+
+	return jsonResult(s.chmodTree(ep, root, info, opts))
+}
+
+// parseChmodMode reads an octal mode argument. Only permission bits are
+// accepted: setuid, setgid and sticky are not carried by the chmod of
+// either endpoint.
+func parseChmodMode(req mcp.CallToolRequest, name string) (*os.FileMode, error) {
+	modeStr := mcp.ParseString(req, name, "")
+	if modeStr == "" {
+		return nil, nil
+	}
+	mode, err := config.ParseOctalMode(modeStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s '%s': %v", name, modeStr, err)
+	}
+	if mode > os.ModePerm {
+		return nil, fmt.Errorf("invalid %s '%s': only permission bits up to 0777 are supported", name, modeStr)
+	}
+	return &mode, nil
+}
+
+// chmodWalk holds the state of a shell_dir_chmod walk.
+type chmodWalk struct {
+	ep       relayEndpoint
+	root     string
+	opts     DirChmodOptions
+	result   *DirChmodResult
+	transfer DirTransferResult // collects errors for finalizeTransferResult
+}
+
+// chmodTree applies opts to root and everything under it.
+func (s *Server) chmodTree(ep relayEndpoint, root string, info os.FileInfo, opts DirChmodOptions) *DirChmodResult {
+	startTime := s.clock.Now()
+	w := &chmodWalk{
+		ep:       ep,
+		root:     root,
+		opts:     opts,
+		result:   &DirChmodResult{Path: root, DryRun: opts.DryRun},
+		transfer: DirTransferResult{Status: "completed"},
+	}
+	w.walkDir(root, info, 0)
+
+	s.finalizeTransferResult(&w.transfer, startTime)
+	w.result.Status = w.transfer.Status
+	w.result.Errors = w.transfer.Errors
+	w.result.ErrorSummary = w.transfer.ErrorSummary
+	w.result.ErrorsOmitted = w.transfer.ErrorsOmitted
+	w.result.DurationMs = w.transfer.DurationMs
+	return w.result
+}
+
+// walkDir changes dir's own mode and walks its entries. A directory whose
+// new mode keeps it readable and searchable by its owner is changed first;
+// otherwise only after its entries, which could not be read afterwards.
+func (w *chmodWalk) walkDir(dir string, info os.FileInfo, depth int) {
+	dirMode := w.opts.DirMode
+	before := dirMode != nil && *dirMode&0500 == 0500
+	if before {
+		w.apply(dir, info, *dirMode)
+	}
+	if depth <= w.opts.MaxDepth {
+		w.walkEntries(dir, depth)
+	}
+	if dirMode != nil && !before {
+		w.apply(dir, info, *dirMode)
+	}
+}
+
+func (w *chmodWalk) walkEntries(dir string, depth int) {
+	entries, err := w.ep.readDir(dir)
+	if err != nil {
+		w.transfer.addError(dir, err.Error())
+		return
+	}
+	for _, entry := range entries {
+		if shouldExclude(entry.Name(), w.opts.Exclusions) {
+			continue
+		}
+		p := path.Join(dir, entry.Name())
+		switch {
+		case entry.Mode()&os.ModeSymlink != 0:
+			w.result.SymlinksSkipped++
+		case entry.IsDir():
+			w.walkDir(p, entry, depth+1)
+		case entry.Mode().IsRegular() && w.opts.FileMode != nil:
+			if matchesPattern(strings.TrimPrefix(p, w.root+"/"), w.opts.Pattern) {
+				w.apply(p, entry, *w.opts.FileMode)
+			}
+		}
+	}
+}
+
+// apply sets mode on p unless it already has it.
+func (w *chmodWalk) apply(p string, info os.FileInfo, mode os.FileMode) {
+	from := info.Mode().Perm()
+	if from == mode {
+		w.result.Unchanged++
+		return
+	}
+	if !w.opts.DryRun {
+		if err := w.ep.chmod(p, mode); err != nil {
+			w.transfer.addError(p, fmt.Sprintf("chmod: %v", err))
+			return
+		}
+	}
+	if info.IsDir() {
+		w.result.DirsChanged++
+	} else {
+		w.result.FilesChanged++
+	}
+	if len(w.result.Changed) < maxChmodListed {
+		w.result.Changed = append(w.result.Changed, DirChmodChange{
+			Path: p,
+			From: fmt.Sprintf("%04o", from),
+			To:   fmt.Sprintf("%04o", mode),
+		})
+	} else {
+		w.result.ChangedTruncated = true
+	}
+}
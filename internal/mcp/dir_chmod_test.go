@@ -0,0 +1,271 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func newChmodServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.SetHomeDir("/home/dev")
+	ffs.AddFile("/proj/run.sh", []byte("#!/bin/sh\n"), 0644)
+	ffs.AddFile("/proj/README", []byte("docs\n"), 0644)
+	ffs.AddFile("/proj/bin/deploy.sh", []byte("#!/bin/sh\n"), 0644)
+	ffs.AddFile("/proj/bin/tool", []byte("\x7fELF"), 0755)
+	ffs.AddFile("/proj/.git/hooks/pre-commit.sh", []byte("#!/bin/sh\n"), 0644)
+	ffs.AddSymlink("/proj/latest.sh", "/proj/run.sh")
+	ffs.AddFile("/home/dev/scripts/a.sh", []byte("#!/bin/sh\n"), 0600)
+
+	sess := newLocalSession("sess_chmod")
+	sess.Cwd = "/proj"
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func callDirChmod(t *testing.T, srv *Server, args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	args["session_id"] = "sess_chmod"
+	result, err := srv.handleShellDirChmod(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func modeOf(t *testing.T, ffs *fakefs.FS, p string) os.FileMode {
+	t.Helper()
+	info, err := ffs.Stat(p)
+	if err != nil {
+		t.Fatalf("stat %s: %v", p, err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestDirChmod_FileModeWithPattern(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	result := callDirChmod(t, srv, map[string]any{"path": ".", "file_mode": "0755", "pattern": "**/*.sh"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["status"] != "completed" || m["path"] != "/proj" {
+		t.Errorf("result = %v", m)
+	}
+	if m["files_changed"] != float64(2) || m["dirs_changed"] != float64(0) || m["symlinks_skipped"] != float64(1) {
+		t.Errorf("counts = %v files, %v dirs, %v symlinks", m["files_changed"], m["dirs_changed"], m["symlinks_skipped"])
+	}
+	for _, p := range []string{"/proj/run.sh", "/proj/bin/deploy.sh"} {
+		if got := modeOf(t, ffs, p); got != 0755 {
+			t.Errorf("%s mode = %04o, want 0755", p, got)
+		}
+	}
+	if got := modeOf(t, ffs, "/proj/README"); got != 0644 {
+		t.Errorf("README outside the pattern changed to %04o", got)
+	}
+	if got := modeOf(t, ffs, "/proj/.git/hooks/pre-commit.sh"); got != 0644 {
+		t.Errorf("excluded .git file changed to %04o", got)
+	}
+
+	changed, _ := m["changed"].([]any)
+	if len(changed) != 2 {
+		t.Fatalf("changed = %v", m["changed"])
+	}
+	first, _ := changed[0].(map[string]any)
+	if first["from"] != "0644" || first["to"] != "0755" {
+		t.Errorf("changed[0] = %v", first)
+	}
+}
+
+func TestDirChmod_DirMode(t *testing.T) {
+	srv, ffs := newChmodServer()
+	_ = ffs.Chmod("/proj/bin", 0777)
+
+	result := callDirChmod(t, srv, map[string]any{"path": "/proj", "file_mode": "0644", "dir_mode": "0750"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	// /proj and /proj/bin; run.sh, README and deploy.sh are 0644 already.
+	if m["dirs_changed"] != float64(2) || m["files_changed"] != float64(1) || m["unchanged"] != float64(3) {
+		t.Errorf("result = %v", m)
+	}
+	for _, p := range []string{"/proj", "/proj/bin"} {
+		if got := modeOf(t, ffs, p); got != 0750 {
+			t.Errorf("%s mode = %04o, want 0750", p, got)
+		}
+	}
+	if got := modeOf(t, ffs, "/proj/bin/tool"); got != 0644 {
+		t.Errorf("tool mode = %04o, want 0644", got)
+	}
+}
+
+func TestDirChmod_DirModeOnly(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	result := callDirChmod(t, srv, map[string]any{"path": "/proj", "dir_mode": "0700"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["dirs_changed"] != float64(2) || m["files_changed"] != float64(0) {
+		t.Errorf("result = %v", m)
+	}
+	if got := modeOf(t, ffs, "/proj/bin/tool"); got != 0755 {
+		t.Errorf("file changed to %04o without file_mode", got)
+	}
+}
+
+func TestDirChmod_DirModeWithoutSearch(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	// A mode that takes away the owner's search permission is set on the way
+	// back up, or the entries below could not be reached.
+	result := callDirChmod(t, srv, map[string]any{"path": "/proj/bin", "file_mode": "0600", "dir_mode": "0600"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	changed, _ := m["changed"].([]any)
+	if len(changed) != 3 {
+		t.Fatalf("changed = %v", m["changed"])
+	}
+	if last, _ := changed[2].(map[string]any); last["path"] != "/proj/bin" {
+		t.Errorf("last change = %v, want the directory itself", last)
+	}
+	if got := modeOf(t, ffs, "/proj/bin"); got != 0600 {
+		t.Errorf("directory mode = %04o", got)
+	}
+}
+
+func TestDirChmod_DryRun(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	result := callDirChmod(t, srv, map[string]any{"path": "/proj", "file_mode": "0755", "dry_run": true})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["dry_run"] != true || m["files_changed"] != float64(3) || m["unchanged"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if got := modeOf(t, ffs, "/proj/run.sh"); got != 0644 {
+		t.Errorf("dry run changed run.sh to %04o", got)
+	}
+}
+
+func TestDirChmod_HomePath(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	result := callDirChmod(t, srv, map[string]any{"path": "~/scripts", "file_mode": "0700"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["path"] != "/home/dev/scripts" || m["files_changed"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if got := modeOf(t, ffs, "/home/dev/scripts/a.sh"); got != 0700 {
+		t.Errorf("a.sh mode = %04o", got)
+	}
+}
+
+func TestDirChmod_MaxDepth(t *testing.T) {
+	srv, ffs := newChmodServer()
+
+	result := callDirChmod(t, srv, map[string]any{"path": "/proj", "file_mode": "0700", "max_depth": 0})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got := modeOf(t, ffs, "/proj/run.sh"); got != 0700 {
+		t.Errorf("run.sh mode = %04o", got)
+	}
+	if got := modeOf(t, ffs, "/proj/bin/deploy.sh"); got != 0644 {
+		t.Errorf("file below max_depth changed to %04o", got)
+	}
+}
+
+// failingChmodEndpoint fails chmod on one path.
+type failingChmodEndpoint struct {
+	relayEndpoint
+	fail string
+}
+
+func (e failingChmodEndpoint) chmod(p string, mode os.FileMode) error {
+	if p == e.fail {
+		return &os.PathError{Op: "chmod", Path: p, Err: errors.New("operation not permitted")}
+	}
+	return e.relayEndpoint.chmod(p, mode)
+}
+
+func TestDirChmod_ErrorsDoNotStopWalk(t *testing.T) {
+	srv, ffs := newChmodServer()
+	ep := failingChmodEndpoint{relayEndpoint: localRelayEndpoint{fs: ffs}, fail: "/proj/bin/deploy.sh"}
+	info, err := ffs.Stat("/proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mode := os.FileMode(0700)
+	result := srv.chmodTree(ep, "/proj", info, DirChmodOptions{FileMode: &mode, MaxDepth: 20, Exclusions: defaultExclusions})
+	if result.Status != "completed_with_errors" {
+		t.Errorf("Status = %q", result.Status)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Path != "/proj/bin/deploy.sh" || !strings.Contains(result.Errors[0].Error, "not permitted") {
+		t.Errorf("Errors = %+v", result.Errors)
+	}
+	if result.FilesChanged != 3 {
+		t.Errorf("FilesChanged = %d, want the other files changed", result.FilesChanged)
+	}
+	if got := modeOf(t, ffs, "/proj/run.sh"); got != 0700 {
+		t.Errorf("run.sh mode = %04o", got)
+	}
+}
+
+func TestDirChmod_ChangedTruncated(t *testing.T) {
+	srv, ffs := newChmodServer()
+	for i := 0; i < maxChmodListed+5; i++ {
+		ffs.AddFile("/many/f"+strings.Repeat("x", i), nil, 0644)
+	}
+
+	result := callDirChmod(t, srv, map[string]any{"path": "/many", "file_mode": "0600"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	changed, _ := m["changed"].([]any)
+	if m["files_changed"] != float64(maxChmodListed+5) || len(changed) != maxChmodListed || m["changed_truncated"] != true {
+		t.Errorf("files_changed = %v, %d listed, truncated = %v", m["files_changed"], len(changed), m["changed_truncated"])
+	}
+}
+
+func TestDirChmod_InvalidArguments(t *testing.T) {
+	srv, _ := newChmodServer()
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"no path", map[string]any{"file_mode": "0644"}, "path is required"},
+		{"no mode", map[string]any{"path": "/proj"}, "file_mode or dir_mode is required"},
+		{"bad file_mode", map[string]any{"path": "/proj", "file_mode": "rwx"}, "invalid file_mode 'rwx'"},
+		{"special bits", map[string]any{"path": "/proj", "dir_mode": "1777"}, "up to 0777"},
+		{"file", map[string]any{"path": "/proj/run.sh", "file_mode": "0644"}, "/proj/run.sh is not a directory"},
+		{"missing", map[string]any{"path": "/nope", "file_mode": "0644"}, "/nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := callDirChmod(t, srv, tt.args)
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// statListFormat is the GNU stat format used for directory listings.
+// The name comes last so names containing the separator still parse.
+const statListFormat = "%s|%f|%u|%g|%Y|%n"
+
+// Listing sources reported in DirListResult.Source.
+const (
+	listSourceStat = "stat"
+	listSourceLs   = "ls"
+)
+
+// registerDirListTools registers the directory listing tool.
+func (s *Server) registerDirListTools() {
+	s.mcpServer.AddTool(shellDirListTool(), s.handleShellDirList)
+}
+
+func shellDirListTool() mcp.Tool {
+	return mcp.NewTool("shell_dir_list",
+		mcp.WithDescription(`List a directory with parsed file metadata.
+
+Runs stat with a machine-readable format in the session and returns one entry
+per file with name, type, size, mode, uid/gid and mtime (unix seconds). This
+avoids parsing locale-dependent "ls -l" columns.
+
+If stat is unavailable (e.g. BSD/busybox without GNU stat), falls back to
+parsing "LC_ALL=C TZ=UTC0 ls -lan" and sets fallback: true with source: "ls".
+In that case mtime only has minute precision.
+
+When dir_list.cache is enabled in the server config, a repeated listing of the
+same directory within dir_list.cache_ttl is returned from a server-side cache
+(cached: true, cached_at) unless the directory's mtime changed since. The
+mtime only changes when entries are added, removed or renamed, so a cached
+listing can show stale sizes and mtimes for files changed in place. Set
+no_cache to force a fresh listing.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Directory to list (relative paths use session's cwd)"),
+		),
+		mcp.WithBoolean("no_cache",
+			mcp.Description("Bypass the listing cache and list the directory afresh (default: false)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// DirListEntry describes one entry of a directory listing.
+type DirListEntry struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"` // "file", "dir", "symlink" or "other"
+	Size    int64  `json:"size"`
+	Mode    string `json:"mode"` // permission bits in octal, e.g. "0644"
+	UID     int    `json:"uid"`
+	GID     int    `json:"gid"`
+	ModTime int64  `json:"mod_time"`
+}
+
+// DirListResult is the result of shell_dir_list.
+type DirListResult struct {
+	Status   string         `json:"status"`
+	Path     string         `json:"path"`
+	Count    int            `json:"count"`
+	Entries  []DirListEntry `json:"entries"`
+	Source   string         `json:"source"`             // "stat" or "ls"
+	Fallback bool           `json:"fallback,omitempty"` // true when stat was unavailable
+	Warning  string         `json:"warning,omitempty"`
+	Cached   bool           `json:"cached,omitempty"`    // true when returned from the listing cache
+	CachedAt string         `json:"cached_at,omitempty"` // when the cached listing was taken
+}
+
+func (s *Server) handleShellDirList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	path := mcp.ParseString(req, "path", "")
+	noCache := mcp.ParseBoolean(req, "no_cache", false)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if path == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+
+	resolvedPath := sess.ResolvePath(path)
+
+	slog.Info("listing directory",
+		slog.String("session_id", sessionID),
+		slog.String("path", resolvedPath),
+	)
+
+	result, err := s.listDirectoryCached(sess, resolvedPath, noCache)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(result)
+}
+
+// listDirectory lists dir via stat, falling back to ls when stat fails.
+func (s *Server) listDirectory(sess *session.Session, dir string) (*DirListResult, error) {
+	result := &DirListResult{Status: "completed", Path: dir, Source: listSourceStat}
+
+	statCmd := fmt.Sprintf("find %s -mindepth 1 -maxdepth 1 -exec stat -c '%s' {} + 2>/dev/null", shellQuote(dir), statListFormat)
+	stdout, exitCode, err := execForOutput(sess, statCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := parseStatListing(stdout)
+	if exitCode != 0 && len(entries) == 0 {
+		lsOut, lsExit, err := execForOutput(sess, lsListCommand+" "+shellQuote(dir))
+		if err != nil {
+			return nil, err
+		}
+		if lsExit != 0 {
+			return nil, fmt.Errorf("list directory: %s", strings.TrimSpace(lsOut))
+		}
+		entries = parseLsListing(lsOut, s.clock.Now())
+		result.Source = listSourceLs
+		result.Fallback = true
+		result.Warning = "stat unavailable; parsed ls -l output (mtime has minute precision)"
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	result.Entries = entries
+	result.Count = len(entries)
+	return result, nil
+}
+
+// execForOutput runs a helper command in the session and returns its output
+// and exit code. Prompts and timeouts are reported as errors.
+func execForOutput(sess *session.Session, command string) (string, int, error) {
+	res, err := sess.Exec(command, 10000)
+	if err != nil {
+		return "", 0, fmt.Errorf("exec: %w", err)
+	}
+	if res.Status != "completed" || res.ExitCode == nil {
+		return "", 0, fmt.Errorf("command did not complete (status: %s)", res.Status)
+	}
+	return res.Stdout, *res.ExitCode, nil
+}
+
+// shellQuote quotes s for safe use as a single POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseStatListing parses lines produced by stat -c statListFormat.
+// Unparseable lines are skipped.
+func parseStatListing(output string) []DirListEntry {
+	entries := make([]DirListEntry, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		fields := strings.SplitN(line, "|", 6)
+		if len(fields) != 6 {
+			continue
+		}
+
+		size, err1 := strconv.ParseInt(fields[0], 10, 64)
+		rawMode, err2 := strconv.ParseUint(fields[1], 16, 32)
+		uid, err3 := strconv.Atoi(fields[2])
+		gid, err4 := strconv.Atoi(fields[3])
+		mtime, err5 := strconv.ParseInt(fields[4], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+			continue
+		}
+
+		name := fields[5]
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		entries = append(entries, DirListEntry{
+			Name:    name,
+			Type:    fileTypeFromMode(uint32(rawMode)),
+			Size:    size,
+			Mode:    fmt.Sprintf("%04o", rawMode&07777),
+			UID:     uid,
+			GID:     gid,
+			ModTime: mtime,
+		})
+	}
+	return entries
+}
+
+// fileTypeFromMode maps the S_IFMT bits of a raw st_mode to an entry type.
+func fileTypeFromMode(mode uint32) string {
+	switch mode & 0170000 {
+	case 0040000:
+		return "dir"
+	case 0100000:
+		return "file"
+	case 0120000:
+		return "symlink"
+	}
+	return "other"
+}
+
+// lsListCommand is the ls fallback of shell_dir_list. ls prints times in the
+// session's local zone without saying which; TZ=UTC0 makes them UTC so
+// parseLsTime can read them.
+const lsListCommand = "LC_ALL=C TZ=UTC0 ls -lan"
+
+// lsLineRe matches a line of lsListCommand output for regular entries.
+var lsLineRe = regexp.MustCompile(`^([-dlcbps])([rwxsStT-]{9})[.+@]?\s+\d+\s+(\d+)\s+(\d+)\s+(\d+)\s+([A-Z][a-z]{2})\s+(\d{1,2})\s+(\d{1,2}:\d{2}|\d{4})\s(.+)$`)
+
+// parseLsListing parses lsListCommand output. now is used to infer the
+// year for recent files, which ls prints as "Mon DD HH:MM".
+func parseLsListing(output string, now time.Time) []DirListEntry {
+	entries := make([]DirListEntry, 0)
+	for _, line := range strings.Split(output, "\n") {
+		m := lsLineRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+
+		name := m[9]
+		if name == "." || name == ".." {
+			continue
+		}
+
+		entryType := lsEntryType(m[1])
+		if entryType == "symlink" {
+			if i := strings.Index(name, " -> "); i >= 0 {
+				name = name[:i]
+			}
+		}
+
+		uid, _ := strconv.Atoi(m[3])
+		gid, _ := strconv.Atoi(m[4])
+		size, _ := strconv.ParseInt(m[5], 10, 64)
+
+		entries = append(entries, DirListEntry{
+			Name:    name,
+			Type:    entryType,
+			Size:    size,
+			Mode:    fmt.Sprintf("%04o", lsPermBits(m[2])),
+			UID:     uid,
+			GID:     gid,
+			ModTime: parseLsTime(m[6], m[7], m[8], now),
+		})
+	}
+	return entries
+}
+
+func lsEntryType(c string) string {
+	switch c {
+	case "-":
+		return "file"
+	case "d":
+		return "dir"
+	case "l":
+		return "symlink"
+	}
+	return "other"
+}
+
+// lsPermBits converts an ls permission string like "rwsr-xr-t" to mode bits.
+func lsPermBits(perms string) uint32 {
+	var mode uint32
+	bits := []uint32{0400, 0200, 0100, 040, 020, 010, 04, 02, 01}
+	for i, c := range perms {
+		if c != '-' && c != 'S' && c != 'T' {
+			mode |= bits[i]
+		}
+		switch {
+		case i == 2 && (c == 's' || c == 'S'):
+			mode |= 04000
+		case i == 5 && (c == 's' || c == 'S'):
+			mode |= 02000
+		case i == 8 && (c == 't' || c == 'T'):
+			mode |= 01000
+		}
+	}
+	return mode
+}
+
+// parseLsTime converts ls date columns printed in UTC to unix seconds.
+func parseLsTime(month, day, timeOrYear string, now time.Time) int64 {
+	if strings.Contains(timeOrYear, ":") {
+		t, err := time.Parse("Jan 2 15:04 2006", fmt.Sprintf("%s %s %s %d", month, day, timeOrYear, now.Year()))
+		if err != nil {
+			return 0
+		}
+		// ls shows a time instead of a year for the last six months; a date
+		// in the future therefore belongs to the previous year.
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		return t.Unix()
+	}
+	t, err := time.Parse("Jan 2 2006", fmt.Sprintf("%s %s %s", month, day, timeOrYear))
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+)
+
+// maxDirListCacheEntries bounds the listings kept by dirListCache; the
+// oldest is evicted to make room.
+const maxDirListCacheEntries = 256
+
+// dirListSettleTime is how old a directory's mtime must be for its listing
+// to be cached. Filesystems with one-second mtimes would not show another
+// change within the same second.
+const dirListSettleTime = time.Second
+
+type dirListCacheKey struct {
+	sessionID string
+	path      string
+}
+
+type dirListCacheEntry struct {
+	result  DirListResult
+	mtime   time.Time // of the directory when it was listed
+	fetched time.Time
+}
+
+// dirListCache keeps recent shell_dir_list results (see
+// config.DirListConfig).
+type dirListCache struct {
+	mu      sync.Mutex
+	entries map[dirListCacheKey]*dirListCacheEntry
+}
+
+func newDirListCache() *dirListCache {
+	return &dirListCache{entries: make(map[dirListCacheKey]*dirListCacheEntry)}
+}
+
+// get returns the listing of path in a session if it was fetched less than
+// ttl before now and the directory's mtime is still mtime.
+func (c *dirListCache) get(sessionID, path string, mtime, now time.Time, ttl time.Duration) (*DirListResult, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := dirListCacheKey{sessionID, path}
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	if now.Sub(e.fetched) >= ttl || !e.mtime.Equal(mtime) {
+		delete(c.entries, key)
+		return nil, time.Time{}, false
+	}
+	result := e.result
+	result.Entries = append([]DirListEntry(nil), e.result.Entries...)
+	return &result, e.fetched, true
+}
+
+func (c *dirListCache) put(sessionID, path string, result *DirListResult, mtime, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := dirListCacheKey{sessionID, path}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxDirListCacheEntries {
+		c.evictOldest()
+	}
+	entry := &dirListCacheEntry{result: *result, mtime: mtime, fetched: now}
+	entry.result.Entries = append([]DirListEntry(nil), result.Entries...)
+	c.entries[key] = entry
+}
+
+// evictOldest removes the entry fetched first (must be called with mu held).
+func (c *dirListCache) evictOldest() {
+	var oldest dirListCacheKey
+	var oldestAt time.Time
+	for key, e := range c.entries {
+		if oldestAt.IsZero() || e.fetched.Before(oldestAt) {
+			oldest, oldestAt = key, e.fetched
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// dropSession removes all listings of a session.
+func (c *dirListCache) dropSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.sessionID == sessionID {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// dirModTime returns the mtime of dir, looked up over SFTP for SSH sessions
+// and on the server's filesystem for local ones, so checking a cached
+// listing does not run a command in the shell. ok is false if the mtime
+// cannot be had this way (container sessions, ~ paths, stat errors); the
+// listing is then not cached.
+func (s *Server) dirModTime(sess *session.Session, dir string) (time.Time, bool) {
+	if sess.Container != "" {
+		return time.Time{}, false
+	}
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return time.Time{}, false
+	}
+	info, err := ep.stat(dir)
+	if err != nil || !info.IsDir() {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// listDirectoryCached lists dir through the cache when caching is enabled.
+// noCache skips the lookup but still stores the fresh listing.
+func (s *Server) listDirectoryCached(sess *session.Session, dir string, noCache bool) (*DirListResult, error) {
+	cfg := s.config.DirList
+	if !cfg.Cache {
+		return s.listDirectory(sess, dir)
+	}
+	mtime, ok := s.dirModTime(sess, dir)
+	if !ok {
+		return s.listDirectory(sess, dir)
+	}
+	if !noCache {
+		if result, fetched, hit := s.dirListCache.get(sess.ID, dir, mtime, s.clock.Now(), cfg.CacheTTL); hit {
+			result.Cached = true
+			result.CachedAt = fetched.UTC().Format(time.RFC3339)
+			return result, nil
+		}
+	}
+
+	result, err := s.listDirectory(sess, dir)
+	if err != nil {
+		return nil, err
+	}
+	if now := s.clock.Now(); now.Sub(mtime) >= dirListSettleTime {
+		s.dirListCache.put(sess.ID, dir, result, mtime, now)
+	}
+	return result, nil
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+var dirListCacheMtime = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// newDirListCacheServer returns a server with the listing cache set to
+// enabled, a local session whose /data directory exists in the fake
+// filesystem, and the session's PTY.
+func newDirListCacheServer(enabled bool) (*Server, *fakefs.FS, *fakeclock.Clock, *fakepty.PTY) {
+	ffs := fakefs.New()
+	ffs.AddFile("/data/b.txt", []byte("0123456789"), 0644)
+	_ = ffs.Chtimes("/data", dirListCacheMtime, dirListCacheMtime)
+
+	sess, pty := newFakeSessionWithRand("sess_ls")
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+
+	cfg := config.DefaultConfig()
+	cfg.DirList.Cache = enabled
+	clk := fakeclock.New(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	srv := NewServer(cfg, WithSessionManager(sm), WithFileSystem(ffs), WithClock(clk))
+	return srv, ffs, clk, pty
+}
+
+// queueListing queues the stat output of a listing of /data with the given
+// command ID, then the pwd that follows it.
+func queueListing(pty *fakepty.PTY, cmdID string, names ...string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "___CMD_START_%s___\n", cmdID)
+	for _, name := range names {
+		fmt.Fprintf(&b, "10|81a4|1000|1000|1700000000|/data/%s\n", name)
+	}
+	fmt.Fprintf(&b, "___CMD_END_%s___0\n", cmdID)
+	pty.AddResponse(b.String())
+	pty.AddResponse("/home/user\n")
+}
+
+func callDirList(t *testing.T, srv *Server, args map[string]any) map[string]any {
+	t.Helper()
+	args["session_id"] = "sess_ls"
+	args["path"] = "/data"
+	result, err := srv.handleShellDirList(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestDirListCache_Hit(t *testing.T) {
+	srv, _, clk, pty := newDirListCacheServer(true)
+	queueListing(pty, "00010203", "b.txt")
+
+	first := callDirList(t, srv, map[string]any{})
+	if first["cached"] != nil || first["count"] != float64(1) {
+		t.Fatalf("first listing = %v, want a fresh listing", first)
+	}
+	written := pty.Written()
+
+	clk.Advance(10 * time.Second)
+	second := callDirList(t, srv, map[string]any{})
+	if second["cached"] != true || second["count"] != float64(1) {
+		t.Errorf("second listing = %v, want it cached", second)
+	}
+	if second["cached_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("cached_at = %v", second["cached_at"])
+	}
+	if pty.Written() != written {
+		t.Errorf("a cached listing ran a command: %q", strings.TrimPrefix(pty.Written(), written))
+	}
+}
+
+func TestDirListCache_MtimeChange(t *testing.T) {
+	srv, ffs, _, pty := newDirListCacheServer(true)
+	queueListing(pty, "00010203", "b.txt")
+	callDirList(t, srv, map[string]any{})
+
+	changed := dirListCacheMtime.Add(time.Second)
+	_ = ffs.Chtimes("/data", changed, changed)
+	queueListing(pty, "04050607", "a.txt", "b.txt")
+
+	m := callDirList(t, srv, map[string]any{})
+	if m["cached"] != nil || m["count"] != float64(2) {
+		t.Errorf("listing after an mtime change = %v, want a fresh one", m)
+	}
+}
+
+func TestDirListCache_Expired(t *testing.T) {
+	srv, _, clk, pty := newDirListCacheServer(true)
+	queueListing(pty, "00010203", "b.txt")
+	callDirList(t, srv, map[string]any{})
+
+	clk.Advance(config.DefaultDirListCacheTTL)
+	queueListing(pty, "04050607", "a.txt", "b.txt")
+
+	m := callDirList(t, srv, map[string]any{})
+	if m["cached"] != nil || m["count"] != float64(2) {
+		t.Errorf("listing after the TTL = %v, want a fresh one", m)
+	}
+}
+
+func TestDirListCache_NoCache(t *testing.T) {
+	srv, _, _, pty := newDirListCacheServer(true)
+	queueListing(pty, "00010203", "b.txt")
+	callDirList(t, srv, map[string]any{})
+
+	queueListing(pty, "04050607", "a.txt", "b.txt")
+	m := callDirList(t, srv, map[string]any{"no_cache": true})
+	if m["cached"] != nil || m["count"] != float64(2) {
+		t.Errorf("no_cache listing = %v, want a fresh one", m)
+	}
+
+	// The fresh listing replaced the cached one.
+	m = callDirList(t, srv, map[string]any{})
+	if m["cached"] != true || m["count"] != float64(2) {
+		t.Errorf("listing after no_cache = %v, want the refreshed listing cached", m)
+	}
+}
+
+func TestDirListCache_Disabled(t *testing.T) {
+	srv, _, _, pty := newDirListCacheServer(false)
+	queueListing(pty, "00010203", "b.txt")
+	callDirList(t, srv, map[string]any{})
+
+	queueListing(pty, "04050607", "a.txt", "b.txt")
+	m := callDirList(t, srv, map[string]any{})
+	if m["cached"] != nil || m["count"] != float64(2) {
+		t.Errorf("listing with the cache disabled = %v, want a fresh one", m)
+	}
+}
+
+func TestDirModTime_NotADirectory(t *testing.T) {
+	srv, _, _, pty := newDirListCacheServer(true)
+	sess, err := srv.sessionManager.Get("sess_ls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := srv.dirModTime(sess, "/nope"); ok {
+		t.Error("dirModTime succeeded for a missing directory")
+	}
+	if _, ok := srv.dirModTime(sess, "/data/b.txt"); ok {
+		t.Error("dirModTime succeeded for a file")
+	}
+	if pty.Written() != "" {
+		t.Errorf("dirModTime wrote to the shell: %q", pty.Written())
+	}
+}
+
+func TestDirListCache_EvictAndDrop(t *testing.T) {
+	c := newDirListCache()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < maxDirListCacheEntries; i++ {
+		c.put("s1", fmt.Sprintf("/d%d", i), &DirListResult{Path: fmt.Sprintf("/d%d", i)}, start, start.Add(time.Duration(i)*time.Millisecond))
+	}
+	c.put("s2", "/other", &DirListResult{Path: "/other"}, start, start.Add(time.Second))
+
+	now := start.Add(2 * time.Second)
+	if _, _, ok := c.get("s1", "/d0", start, now, time.Minute); ok {
+		t.Error("oldest entry was not evicted")
+	}
+	if _, _, ok := c.get("s1", "/d1", start, now, time.Minute); !ok {
+		t.Error("second oldest entry was evicted")
+	}
+
+	c.dropSession("s1")
+	if len(c.entries) != 1 {
+		t.Errorf("%d entries after dropSession, want 1", len(c.entries))
+	}
+	if _, _, ok := c.get("s2", "/other", start, now, time.Minute); !ok {
+		t.Error("dropSession removed another session's entry")
+	}
+}
+
+func TestDirListCache_RecentMtimeNotCached(t *testing.T) {
+	srv, ffs, clk, pty := newDirListCacheServer(true)
+	_ = ffs.Chtimes("/data", clk.Now(), clk.Now())
+	queueListing(pty, "00010203", "b.txt")
+	callDirList(t, srv, map[string]any{})
+
+	queueListing(pty, "04050607", "a.txt", "b.txt")
+	m := callDirList(t, srv, map[string]any{})
+	if m["cached"] != nil || m["count"] != float64(2) {
+		t.Errorf("listing = %v, want a fresh one while the mtime may still change", m)
+	}
+}
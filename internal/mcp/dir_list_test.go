@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestParseStatListing(t *testing.T) {
+	output := "1234|81a4|1000|1000|1700000000|/srv/app/main.go\r\n" +
+		"4096|41ed|0|0|1700000100|/srv/app/bin\n" +
+		"7|a1ff|1000|1000|1700000200|/srv/app/cur\n" +
+		"0|81a4|1000|1000|1700000300|/srv/app/odd|name\n" +
+		"garbage line\n"
+
+	entries := parseStatListing(output)
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(entries), entries)
+	}
+
+	want := []DirListEntry{
+		{Name: "main.go", Type: "file", Size: 1234, Mode: "0644", UID: 1000, GID: 1000, ModTime: 1700000000},
+		{Name: "bin", Type: "dir", Size: 4096, Mode: "0755", UID: 0, GID: 0, ModTime: 1700000100},
+		{Name: "cur", Type: "symlink", Size: 7, Mode: "0777", UID: 1000, GID: 1000, ModTime: 1700000200},
+		{Name: "odd|name", Type: "file", Size: 0, Mode: "0644", UID: 1000, GID: 1000, ModTime: 1700000300},
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestParseLsListing(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	output := "total 12\n" +
+		"drwxr-xr-x  3 1000 1000 4096 Mar  9 08:30 .\n" +
+		"drwxr-xr-x  5 0    0    4096 Jan  1  2023 ..\n" +
+		"-rw-r--r--  1 1000 1000  120 Mar  9 08:30 notes file.txt\n" +
+		"-rwsr-xr-x  1 0    0    9000 Dec 24 23:59 suid\n" +
+		"lrwxrwxrwx  1 1000 1000    7 Jun  5  2022 cur -> release\n" +
+		"crw-rw-rw-  1 0    0    1, 3 Mar  9 08:30 null\n"
+
+	entries := parseLsListing(output, now)
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %+v", len(entries), entries)
+	}
+
+	notes := entries[0]
+	if notes.Name != "notes file.txt" || notes.Type != "file" || notes.Size != 120 || notes.Mode != "0644" {
+		t.Errorf("notes = %+v", notes)
+	}
+	if notes.ModTime != time.Date(2024, 3, 9, 8, 30, 0, 0, time.UTC).Unix() {
+		t.Errorf("notes mtime = %d", notes.ModTime)
+	}
+
+	suid := entries[1]
+	if suid.Mode != "4755" {
+		t.Errorf("suid mode = %s, want 4755", suid.Mode)
+	}
+	// December with a time and a March "now" belongs to the previous year.
+	if suid.ModTime != time.Date(2023, 12, 24, 23, 59, 0, 0, time.UTC).Unix() {
+		t.Errorf("suid mtime = %d", suid.ModTime)
+	}
+
+	link := entries[2]
+	if link.Name != "cur" || link.Type != "symlink" {
+		t.Errorf("link = %+v", link)
+	}
+	if link.ModTime != time.Date(2022, 6, 5, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("link mtime = %d", link.ModTime)
+	}
+}
+
+func TestLsPermBits(t *testing.T) {
+	tests := map[string]uint32{
+		"rwxr-xr-x": 0755,
+		"rw-------": 0600,
+		"rwsr-sr-t": 07755,
+		"rwSr--r-T": 05644,
+	}
+	for perms, want := range tests {
+		if got := lsPermBits(perms); got != want {
+			t.Errorf("lsPermBits(%q) = %04o, want %04o", perms, got, want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("/tmp/it's here"); got != `'/tmp/it'\''s here'` {
+		t.Errorf("shellQuote = %s", got)
+	}
+}
+
+func TestHandleShellDirList_Stat(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_ls")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	pty.AddResponse("___CMD_START_00010203___\n" +
+		"10|81a4|1000|1000|1700000000|/data/b.txt\n" +
+		"4096|41ed|1000|1000|1700000000|/data/a\n" +
+		"___CMD_END_00010203___0\n")
+
+	result, err := srv.handleShellDirList(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ls",
+		"path":       "/data",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["source"] != "stat" || m["fallback"] != nil {
+		t.Errorf("source=%v fallback=%v, want stat without fallback", m["source"], m["fallback"])
+	}
+	entries := m["entries"].([]any)
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v", entries)
+	}
+	first := entries[0].(map[string]any)
+	if first["name"] != "a" || first["type"] != "dir" {
+		t.Errorf("first entry = %v, want dir a (sorted)", first)
+	}
+	if !strings.Contains(pty.Written(), "stat -c") {
+		t.Errorf("expected stat command, wrote: %s", pty.Written())
+	}
+}
+
+func TestHandleShellDirList_LsFallback(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_ls2")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___1\n")
+	pty.AddResponse("/home/user\n") // pwd after first command
+	pty.AddResponse("___CMD_START_04050607___\n" +
+		"total 4\n" +
+		"-rw-r--r--  1 501 20  42 Jan  1  2020 readme\n" +
+		"___CMD_END_04050607___0\n")
+
+	result, _ := srv.handleShellDirList(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ls2",
+		"path":       "/data",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["source"] != "ls" || m["fallback"] != true {
+		t.Errorf("source=%v fallback=%v, want ls fallback", m["source"], m["fallback"])
+	}
+	if m["count"] != float64(1) {
+		t.Errorf("count = %v, want 1", m["count"])
+	}
+	if !strings.Contains(pty.Written(), "LC_ALL=C TZ=UTC0 ls -lan ") {
+		t.Errorf("written = %q, want ls run with times in UTC", pty.Written())
+	}
+}
+
+func TestHandleShellDirList_MissingParams(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	result, _ := srv.handleShellDirList(context.Background(), makeRequest(map[string]any{"path": "/x"}))
+	if !result.IsError {
+		t.Error("expected error without session_id")
+	}
+	result, _ = srv.handleShellDirList(context.Background(), makeRequest(map[string]any{"session_id": "s"}))
+	if !result.IsError {
+		t.Error("expected error without path")
+	}
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerExecIfTools registers the conditional exec tool.
+func (s *Server) registerExecIfTools() {
+	s.mcpServer.AddTool(shellExecIfTool(), s.handleShellExecIf)
+}
+
+func shellExecIfTool() mcp.Tool {
+	return mcp.NewTool("shell_exec_if",
+		mcp.WithDescription(`Run a condition command, then one of two commands depending on its exit code.
+
+Saves a round-trip for "probe then act" patterns such as
+condition="which jq", then="jq . data.json", else="python3 -m json.tool data.json".
+The condition runs first; if it completes with exit code 0 the "then" command
+runs, otherwise the "else" command runs. Both run in the same session one
+after the other, so cwd and environment carry over.
+
+The result holds the condition's result, condition_met, the branch that ran
+("then", "else" or "" if none) and that branch's result. If the condition
+stops at a prompt or times out, no branch runs and status is the condition's
+status; answer it with shell_provide_input as usual.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("condition",
+			mcp.Required(),
+			mcp.Description("Command whose exit code picks the branch (0 runs then, anything else runs else)"),
+		),
+		mcp.WithString("then",
+			mcp.Description("Command to run when the condition exits 0"),
+		),
+		mcp.WithString("else",
+			mcp.Description("Command to run when the condition exits non-zero"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Timeout for each command in milliseconds (default: 30000)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// ExecIfResult is the result of shell_exec_if.
+type ExecIfResult struct {
+	Status       string              `json:"status"`
+	ConditionMet bool                `json:"condition_met"`
+	Branch       string              `json:"branch"`
+	Condition    *session.ExecResult `json:"condition"`
+	Result       *session.ExecResult `json:"result,omitempty"`
+}
+
+func (s *Server) handleShellExecIf(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	condition := mcp.ParseString(req, "condition", "")
+	thenCmd := mcp.ParseString(req, "then", "")
+	elseCmd := mcp.ParseString(req, "else", "")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if condition == "" {
+		return mcp.NewToolResultError("condition is required"), nil
+	}
+	if thenCmd == "" && elseCmd == "" {
+		return mcp.NewToolResultError("at least one of then or else is required"), nil
+	}
+	for _, command := range []string{condition, thenCmd, elseCmd} {
+		if command == "" {
+			continue
+		}
+		if allowed, reason := s.filter().IsAllowed(command); !allowed {
+			slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+			return mcp.NewToolResultError("command blocked: " + reason), nil
+		}
+		if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+			return errResult, nil
+		}
+	}
+
+	slog.Info("executing conditional command",
+		slog.String("session_id", sessionID),
+		slog.String("condition", condition),
+	)
+
+	condResult, err := s.execInSession(ctx, sessionID, condition, timeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	setExecSuccess(condResult, []int{0})
+
+	result := ExecIfResult{Status: condResult.Status, Condition: condResult}
+	if condResult.Status != "completed" {
+		return jsonResult(result)
+	}
+
+	result.ConditionMet = condResult.ExitCode != nil && *condResult.ExitCode == 0
+	command := elseCmd
+	result.Branch = "else"
+	if result.ConditionMet {
+		command = thenCmd
+		result.Branch = "then"
+	}
+	if command == "" {
+		result.Branch = ""
+		return jsonResult(result)
+	}
+
+	branchResult, err := s.execInSession(ctx, sessionID, command, timeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	setExecSuccess(branchResult, []int{0})
+	result.Status = branchResult.Status
+	result.Result = branchResult
+	return jsonResult(result)
+}
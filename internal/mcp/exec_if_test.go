@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newExecIfServer returns a server with one initialized session "sess_if".
+func newExecIfServer(t *testing.T) (*Server, *fakepty.PTY) {
+	t.Helper()
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_if")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	return newTestServer(sm), pty
+}
+
+func commandResponse(cmdID, output string, exitCode int) string {
+	return fmt.Sprintf("___CMD_START_%s___\n%s\n___CMD_END_%s___%d\n", cmdID, output, cmdID, exitCode)
+}
+
+func TestHandleShellExecIf(t *testing.T) {
+	tests := []struct {
+		name       string
+		condExit   int
+		wantBranch string
+		wantOutput string
+	}{
+		{"condition met", 0, "then", "then-output"},
+		{"condition failed", 1, "else", "else-output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, pty := newExecIfServer(t)
+			pty.AddResponse(commandResponse("00010203", "/usr/bin/jq", tt.condExit))
+			pty.AddResponse("/home/user\n")
+			pty.AddResponse(commandResponse("04050607", tt.wantOutput, 0))
+
+			result, err := srv.handleShellExecIf(context.Background(), makeRequest(map[string]any{
+				"session_id": "sess_if",
+				"condition":  "which jq",
+				"then":       "echo then-output",
+				"else":       "echo else-output",
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("unexpected error result: %s", resultText(result))
+			}
+
+			m := resultJSON(t, result)
+			if m["branch"] != tt.wantBranch || m["condition_met"] != (tt.condExit == 0) {
+				t.Errorf("branch=%v condition_met=%v, want %s/%v", m["branch"], m["condition_met"], tt.wantBranch, tt.condExit == 0)
+			}
+			if m["status"] != "completed" {
+				t.Errorf("status = %v, want completed", m["status"])
+			}
+			cond := m["condition"].(map[string]any)
+			if cond["exit_code"] != float64(tt.condExit) {
+				t.Errorf("condition exit_code = %v, want %d", cond["exit_code"], tt.condExit)
+			}
+			branch, ok := m["result"].(map[string]any)
+			if !ok || !strings.Contains(branch["stdout"].(string), tt.wantOutput) {
+				t.Errorf("result = %v, want stdout %q", m["result"], tt.wantOutput)
+			}
+			if written := pty.Written(); !strings.Contains(written, "which jq") || !strings.Contains(written, "echo "+tt.wantBranch) {
+				t.Errorf("written = %q, want the condition and the %s branch", written, tt.wantBranch)
+			}
+		})
+	}
+}
+
+func TestHandleShellExecIf_NoBranchToRun(t *testing.T) {
+	srv, pty := newExecIfServer(t)
+	pty.AddResponse(commandResponse("00010203", "", 1))
+
+	result, _ := srv.handleShellExecIf(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_if",
+		"condition":  "test -f /etc/motd",
+		"then":       "cat /etc/motd",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["branch"] != "" || m["condition_met"] != false || m["result"] != nil {
+		t.Errorf("branch=%v condition_met=%v result=%v, want no branch", m["branch"], m["condition_met"], m["result"])
+	}
+	if strings.Contains(pty.Written(), "cat /etc/motd") {
+		t.Error("then branch ran although the condition failed")
+	}
+}
+
+func TestHandleShellExecIf_ConditionAwaitingInput(t *testing.T) {
+	srv, pty := newExecIfServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n[sudo] password for user: ")
+
+	result, _ := srv.handleShellExecIf(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_if",
+		"condition":  "sudo -v",
+		"then":       "echo yes",
+		"else":       "echo no",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["status"] != "awaiting_input" || m["branch"] != "" {
+		t.Errorf("status=%v branch=%v, want awaiting_input and no branch", m["status"], m["branch"])
+	}
+	if written := pty.Written(); strings.Contains(written, "echo yes") || strings.Contains(written, "echo no") {
+		t.Errorf("a branch ran while the condition awaits input: %q", written)
+	}
+}
+
+func TestHandleShellExecIf_Validation(t *testing.T) {
+	srv, _ := newExecIfServer(t)
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"condition": "true", "then": "ls"}, errSessionIDRequired},
+		{map[string]any{"session_id": "sess_if", "then": "ls"}, "condition is required"},
+		{map[string]any{"session_id": "sess_if", "condition": "true"}, "at least one of then or else"},
+		{map[string]any{"session_id": "nope", "condition": "true", "then": "ls"}, "not found"},
+	}
+	for _, tt := range tests {
+		result, err := srv.handleShellExecIf(context.Background(), makeRequest(tt.args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// execLimitsParam is the limits parameter of shell_exec.
+func execLimitsParam() mcp.ToolOption {
+	return mcp.WithObject("limits",
+		mcp.Description(`Resource limits for this command only, applied with ulimit in a subshell before it runs: cpu_seconds (CPU time), max_memory_mb (virtual memory) and max_file_size_mb (largest file it may write). A command stopped by a limit returns status "limit_exceeded" with limit_exceeded naming it and its exit_code and output. Not for direct commands`),
+		mcp.Properties(map[string]any{
+			"cpu_seconds":      map[string]any{"type": "integer", "minimum": 1},
+			"max_memory_mb":    map[string]any{"type": "integer", "minimum": 1},
+			"max_file_size_mb": map[string]any{"type": "integer", "minimum": 1},
+		}),
+		mcp.AdditionalProperties(false),
+	)
+}
+
+// parseExecLimits reads the limits argument, accepting a JSON-encoded
+// string from clients that send objects that way. It returns nil if the
+// argument is absent.
+func parseExecLimits(req mcp.CallToolRequest) (*session.ResourceLimits, error) {
+	raw, ok := req.GetArguments()["limits"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	encoded, isString := raw.(string)
+	data := []byte(encoded)
+	if !isString {
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("limits: %w", err)
+		}
+	}
+	var limits session.ResourceLimits
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&limits); err != nil {
+		return nil, fmt.Errorf("limits must be an object with integer cpu_seconds, max_memory_mb and max_file_size_mb: %w", err)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return &limits, nil
+}
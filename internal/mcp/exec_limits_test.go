@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellExec_Limits(t *testing.T) {
+	tests := []struct {
+		name     string
+		limits   any
+		written  string
+		exceeded string // "" when the limit hit is not one that was set
+	}{
+		{"object", map[string]any{"cpu_seconds": float64(5), "max_file_size_mb": float64(10)}, "(ulimit -S -t 5 && ulimit -H -t 6 && ulimit -f 10240 || exit; dd if=/dev/zero of=out.img)", "max_file_size_mb"},
+		{"json string", `{"max_memory_mb": 256}`, "(ulimit -v 262144 || exit; dd if=/dev/zero of=out.img)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := fakesessionmgr.New()
+			sess, pty := newFakeSessionWithRand("sess_lim")
+			if err := sess.Initialize(); err != nil {
+				t.Fatalf("Initialize error: %v", err)
+			}
+			sm.AddSession(sess)
+			srv := newTestServer(sm)
+			pty.AddResponse("___CMD_START_00010203___\nFile size limit exceeded (core dumped)\n___CMD_END_00010203___153\n")
+
+			result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+				"session_id": "sess_lim",
+				"command":    "dd if=/dev/zero of=out.img",
+				"limits":     tt.limits,
+			}))
+			if result.IsError {
+				t.Fatalf("unexpected error result: %s", resultText(result))
+			}
+			if !strings.Contains(pty.Written(), tt.written) {
+				t.Errorf("written = %q, want %q", pty.Written(), tt.written)
+			}
+			m := resultJSON(t, result)
+			if m["exit_code"] != float64(153) {
+				t.Errorf("exit_code = %v, want 153", m["exit_code"])
+			}
+			if tt.exceeded == "" {
+				if m["status"] != "completed" || m["limit_exceeded"] != nil {
+					t.Errorf("status = %v, limit_exceeded = %v, want completed", m["status"], m["limit_exceeded"])
+				}
+				return
+			}
+			if m["status"] != "limit_exceeded" || m["limit_exceeded"] != tt.exceeded || m["success"] != nil {
+				t.Errorf("status = %v, limit_exceeded = %v, success = %v, want limit_exceeded, %s", m["status"], m["limit_exceeded"], m["success"], tt.exceeded)
+			}
+		})
+	}
+}
+
+func TestHandleShellExec_InvalidLimits(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_lim")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	for _, limits := range []any{
+		map[string]any{},
+		map[string]any{"cpu_seconds": float64(-1)},
+		map[string]any{"cpu_secs": float64(5)},
+		map[string]any{"cpu_seconds": "five"},
+		"nope",
+	} {
+		result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+			"session_id": "sess_lim",
+			"command":    "true",
+			"limits":     limits,
+		}))
+		if !result.IsError || !strings.Contains(resultText(result), "limits") {
+			t.Errorf("limits=%v: got %q, want a limits error", limits, resultText(result))
+		}
+	}
+	if pty.Written() != "" {
+		t.Errorf("wrote %q for invalid limits", pty.Written())
+	}
+}
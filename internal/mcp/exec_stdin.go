@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerExecStdinTools registers the tool that runs a command with stdin content.
+func (s *Server) registerExecStdinTools() {
+	s.mcpServer.AddTool(shellExecStdinTool(), s.handleShellExecStdin)
+}
+
+func shellExecStdinTool() mcp.Tool {
+	return mcp.NewTool("shell_exec_stdin",
+		mcp.WithDescription(`Execute a command and feed content to its stdin.
+
+Use for commands that read their input from stdin, e.g. "kubectl apply -f -",
+"psql mydb" or "sort", without first uploading a temp file with shell_file_put.
+
+The content is typed into the terminal once the command has started, followed
+by Ctrl-D to signal end of input. The terminal echo of the content is removed
+from stdout. Returns the same statuses as shell_exec.
+
+Limitations (terminal line discipline):
+- Text only: control characters other than tab/newline are rejected. Use
+  encoding "base64" to transport text with awkward quoting, not binary data.
+- Lines longer than 4095 bytes are rejected; use shell_file_put instead.
+- The command must consume its stdin. Unread content is read by the shell.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("The command to execute"),
+		),
+		mcp.WithString("stdin",
+			mcp.Required(),
+			mcp.Description("Content to send to the command's stdin"),
+		),
+		mcp.WithString("encoding",
+			mcp.Description("Encoding of stdin: 'text' (default) or 'base64'"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Command timeout in milliseconds (default: 30000)"),
+		),
+		destructiveTool(),
+	)
+}
+
+func (s *Server) handleShellExecStdin(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	command := mcp.ParseString(req, "command", "")
+	stdin := mcp.ParseString(req, "stdin", "")
+	encoding := mcp.ParseString(req, "encoding", "text")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if command == "" {
+		return mcp.NewToolResultError("command is required"), nil
+	}
+
+	switch encoding {
+	case "text":
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(stdin)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("decode base64 stdin: %v", err)), nil
+		}
+		stdin = string(decoded)
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unsupported encoding %q (use 'text' or 'base64')", encoding)), nil
+	}
+
+	if allowed, reason := s.filter().IsAllowed(command); !allowed {
+		slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+		return mcp.NewToolResultError("command blocked: " + reason), nil
+	}
+	if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+		return errResult, nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	slog.Info("executing command with stdin",
+		slog.String("session_id", sessionID),
+		slog.String("command", command),
+		slog.Int("stdin_bytes", len(stdin)),
+	)
+	s.recordingManager.RecordInput(sessionID, command+"\n", false)
+
+	result, err := sess.ExecWithStdin(command, stdin, timeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	s.recordingManager.RecordOutput(sessionID, result.Stdout)
+	s.applyAutoTruncation(sessionID, command, result)
+
+	return jsonResult(result)
+}
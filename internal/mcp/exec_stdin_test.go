@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellExecStdin_Base64(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_stdin")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	cmdID := "00010203"
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n")
+	pty.AddResponse("apiVersion: v1\r\ncreated\r\n___CMD_END_" + cmdID + "___0\n")
+
+	result, err := srv.handleShellExecStdin(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_stdin",
+		"command":    "kubectl apply -f -",
+		"stdin":      base64.StdEncoding.EncodeToString([]byte("apiVersion: v1\n")),
+		"encoding":   "base64",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "completed" {
+		t.Errorf("status = %v, want completed", m["status"])
+	}
+	if m["stdout"] != "created" {
+		t.Errorf("stdout = %q, want %q", m["stdout"], "created")
+	}
+	if !strings.Contains(pty.Written(), "apiVersion: v1\n\x04") {
+		t.Errorf("stdin not written with Ctrl-D: %q", pty.Written())
+	}
+}
+
+func TestHandleShellExecStdin_Validation(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, _ := newFakeSessionWithRand("sess_stdin")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing session", map[string]any{"command": "cat", "stdin": "x"}, "session_id is required"},
+		{"missing command", map[string]any{"session_id": "sess_stdin", "stdin": "x"}, "command is required"},
+		{"bad encoding", map[string]any{"session_id": "sess_stdin", "command": "cat", "stdin": "x", "encoding": "hex"}, "unsupported encoding"},
+		{"bad base64", map[string]any{"session_id": "sess_stdin", "command": "cat", "stdin": "!!", "encoding": "base64"}, "decode base64"},
+		{"control chars", map[string]any{"session_id": "sess_stdin", "command": "cat", "stdin": "a\x03b"}, "control character"},
+		{"unknown session", map[string]any{"session_id": "nope", "command": "cat", "stdin": "x"}, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := srv.handleShellExecStdin(context.Background(), makeRequest(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("expected error result, got %s", resultText(result))
+			}
+			if !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
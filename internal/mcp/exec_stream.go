@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// outputProgress returns the session.ExecOptions.OnOutput for a shell_exec
+// with stream=true: each chunk of output goes to the client as a
+// notifications/progress message, with progress counting the bytes sent.
+// It returns nil, so the command runs as usual, when the request has no
+// progress token or the transport has no client session to notify.
+func (s *Server) outputProgress(ctx context.Context, req mcp.CallToolRequest) func(string) {
+	if req.Params.Meta == nil || req.Params.Meta.ProgressToken == nil || server.ClientSessionFromContext(ctx) == nil {
+		return nil
+	}
+	token := req.Params.Meta.ProgressToken
+	sent := 0
+	return func(chunk string) {
+		sent += len(chunk)
+		err := s.mcpServer.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+			"progressToken": token,
+			"progress":      sent,
+			"message":       chunk,
+		})
+		if err != nil {
+			slog.Debug("output progress not sent", slog.String("error", err.Error()))
+		}
+	}
+}
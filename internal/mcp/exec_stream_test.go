@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+// fakeClientSession is an initialized MCP client session that collects
+// the notifications sent to it.
+type fakeClientSession struct {
+	notifications chan mcpgo.JSONRPCNotification
+}
+
+func newFakeClientSession() *fakeClientSession {
+	return &fakeClientSession{notifications: make(chan mcpgo.JSONRPCNotification, 16)}
+}
+
+func (f *fakeClientSession) Initialize()       {}
+func (f *fakeClientSession) Initialized() bool { return true }
+func (f *fakeClientSession) SessionID() string { return "client_1" }
+func (f *fakeClientSession) NotificationChannel() chan<- mcpgo.JSONRPCNotification {
+	return f.notifications
+}
+
+func streamExecRequest(token mcpgo.ProgressToken) mcpgo.CallToolRequest {
+	req := makeRequest(map[string]any{
+		"session_id": "sess_stream",
+		"command":    "make",
+		"stream":     true,
+	})
+	if token != nil {
+		req.Params.Meta = &mcpgo.Meta{ProgressToken: token}
+	}
+	return req
+}
+
+func newStreamServer() (*Server, *fakeClientSession, context.Context) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_stream")
+	sm.AddSession(sess)
+	pty.AddResponse("___CMD_START_00010203___\nbuilding a\nbuilding b\n___CMD_END_00010203___0\n")
+	srv := newTestServer(sm)
+	client := newFakeClientSession()
+	return srv, client, srv.mcpServer.WithContext(context.Background(), client)
+}
+
+func TestHandleShellExec_StreamProgress(t *testing.T) {
+	srv, client, ctx := newStreamServer()
+
+	result, err := srv.handleShellExec(ctx, streamExecRequest("tok-1"))
+	if err != nil || result.IsError {
+		t.Fatalf("handleShellExec() = %s, %v", resultText(result), err)
+	}
+	if m := resultJSON(t, result); m["stdout"] != "building a\nbuilding b" {
+		t.Errorf("stdout = %q, want all of the output", m["stdout"])
+	}
+
+	select {
+	case n := <-client.notifications:
+		params := n.Params.AdditionalFields
+		if n.Method != "notifications/progress" || params["progressToken"] != "tok-1" {
+			t.Errorf("notification = %s %v", n.Method, params)
+		}
+		if params["message"] != "building a\nbuilding b\n" || params["progress"] != len("building a\nbuilding b\n") {
+			t.Errorf("message = %q, progress = %v", params["message"], params["progress"])
+		}
+	default:
+		t.Fatal("no progress notification sent")
+	}
+}
+
+func TestHandleShellExec_StreamWithoutProgressToken(t *testing.T) {
+	srv, client, ctx := newStreamServer()
+
+	result, err := srv.handleShellExec(ctx, streamExecRequest(nil))
+	if err != nil || result.IsError {
+		t.Fatalf("handleShellExec() = %s, %v", resultText(result), err)
+	}
+	if m := resultJSON(t, result); m["stdout"] != "building a\nbuilding b" {
+		t.Errorf("stdout = %q", m["stdout"])
+	}
+	if len(client.notifications) != 0 {
+		t.Errorf("%d notifications sent without a progress token", len(client.notifications))
+	}
+}
+
+func TestOutputProgress_NoClientSession(t *testing.T) {
+	srv, _, _ := newStreamServer()
+	if srv.outputProgress(context.Background(), streamExecRequest("tok-1")) != nil {
+		t.Error("outputProgress() without a client session should be nil")
+	}
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultOkExitCodes are the exit codes that count as success when
+// ok_exit_codes is not given.
+var defaultOkExitCodes = []int{0}
+
+// parseOkExitCodes reads the ok_exit_codes argument, accepting a
+// JSON-encoded string from clients that send arrays that way.
+func parseOkExitCodes(req mcp.CallToolRequest) ([]int, error) {
+	raw, ok := req.GetArguments()["ok_exit_codes"]
+	if !ok || raw == nil {
+		return defaultOkExitCodes, nil
+	}
+	encoded, isString := raw.(string)
+	data := []byte(encoded)
+	if !isString {
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("ok_exit_codes: %w", err)
+		}
+	}
+	var codes []int
+	if err := json.Unmarshal(data, &codes); err != nil {
+		return nil, fmt.Errorf("ok_exit_codes must be an array of integers: %w", err)
+	}
+	if len(codes) == 0 {
+		return defaultOkExitCodes, nil
+	}
+	for _, code := range codes {
+		if code < 0 || code > 255 {
+			return nil, fmt.Errorf("ok_exit_codes: %d is not an exit code (0-255)", code)
+		}
+	}
+	return codes, nil
+}
+
+// setExecSuccess sets result.Success for a completed command from whether
+// its exit code is one of ok.
+func setExecSuccess(result *session.ExecResult, ok []int) {
+	if result.Status != "completed" || result.ExitCode == nil {
+		return
+	}
+	success := slices.Contains(ok, *result.ExitCode)
+	result.Success = &success
+}
@@ -17,7 +17,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/acolita/claude-shell-mcp/internal/config"
 	"github.com/acolita/claude-shell-mcp/internal/session"
 	"github.com/acolita/claude-shell-mcp/internal/sftp"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -31,19 +33,35 @@ func (s *Server) registerFileTransferTools() {
 	s.mcpServer.AddTool(shellFileGetTool(), s.handleShellFileGet)
 	s.mcpServer.AddTool(shellFilePutTool(), s.handleShellFilePut)
 	s.mcpServer.AddTool(shellFileMvTool(), s.handleShellFileMv)
+	s.mcpServer.AddTool(shellFileRmTool(), s.handleShellFileRm)
+	s.mcpServer.AddTool(shellFileRelayTool(), s.handleShellFileRelay)
+	s.mcpServer.AddTool(shellFilePatchTool(), s.handleShellFilePatch)
+	s.mcpServer.AddTool(shellFileChecksumTool(), s.handleShellFileChecksum)
+	s.mcpServer.AddTool(shellFileWatchTool(), s.handleShellFileWatch)
+	s.mcpServer.AddTool(shellFileWatchPollTool(), s.handleShellFileWatchPoll)
 }
 
 func shellFileGetTool() mcp.Tool {
 	return mcp.NewTool("shell_file_get",
 		mcp.WithDescription(`Download a file from a remote SSH session.
 
-For small files (<1MB), returns the content directly in the response.
-For large files, use the local_path parameter to save to a local file.
+For small files (up to output.max_inline_file_bytes, 1MB by default), returns
+the content directly in the response. Larger files fail with file_too_large
+before anything is read: use local_path to save them to a local file or
+shell_file_get_chunked.
 
 For local sessions, use this tool to read files using the session's working directory context.
 
 Returns file metadata (size, permissions, modification time) along with content.
-Optionally calculates SHA256 checksum for verification.`),
+Optionally calculates SHA256 checksum for verification.
+
+If remote_path is a directory, set format to "tar" or "tar.gz" to receive the
+whole tree as a base64-encoded archive (symlinks are stored, not followed).
+This is a lightweight alternative to shell_dir_get for small directories.
+
+Set start_line/end_line to fetch only a slice of a text file (e.g. lines
+10000-10050 of a large log) along with its total_lines. The size limit then
+applies to the selected lines only.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
 			mcp.Description(descSessionID),
@@ -53,11 +71,11 @@ Optionally calculates SHA256 checksum for verification.`),
 			mcp.Description("Path to the file on the remote server (relative paths use session's cwd)"),
 		),
 		mcp.WithString("encoding",
-			mcp.Description("Content encoding: 'text' (default) or 'base64' for binary files"),
+			mcp.Description("Content encoding: 'text' (default) or 'base64' for binary files. Text falls back to base64 (auto_base64: true) for files that are not valid UTF-8"),
 			mcp.DefaultString("text"),
 		),
 		mcp.WithString("local_path",
-			mcp.Description("Local path to save the file (required for files >1MB)"),
+			mcp.Description("Local path to save the file (required for files over output.max_inline_file_bytes, 1MB by default)"),
 		),
 		mcp.WithBoolean("checksum",
 			mcp.Description("Calculate and return SHA256 checksum (default: true)"),
@@ -71,6 +89,25 @@ Optionally calculates SHA256 checksum for verification.`),
 		mcp.WithBoolean("compress",
 			mcp.Description("Compress content with gzip (for text files, reduces transfer size)"),
 		),
+		mcp.WithString("format",
+			mcp.Description("Archive format for directories: 'tar' or 'tar.gz'"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Glob pattern to filter files when archiving a directory (e.g., '**/*.go')"),
+		),
+		mcp.WithNumber("start_line",
+			mcp.Description("Return only lines from this line on (1-based). Streams the file, so slices of huge logs are cheap. Not allowed with base64 encoding."),
+		),
+		mcp.WithNumber("end_line",
+			mcp.Description("Return only lines up to this line, inclusive (default: end of file). Response includes total_lines."),
+		),
+		mcp.WithBoolean("decompress",
+			mcp.Description("Decompress a gzip or bzip2 file (detected from its contents) and return the uncompressed data; size is then the decompressed size and original_size the compressed one. Fails if the file is not compressed."),
+		),
+		mcp.WithString("progress_file",
+			mcp.Description("Local path of a manifest updated while the file is read; poll it with shell_transfer_status(manifest_path=...) like a chunked transfer"),
+		),
+		readOnlyTool(),
 	)
 }
 
@@ -79,10 +116,20 @@ func shellFilePutTool() mcp.Tool {
 		mcp.WithDescription(`Upload a file to a remote SSH session.
 
 Provide content directly for small files, or use local_path to upload from a local file.
+local_path uploads of 8MB or more to SSH sessions are streamed rather than read into memory.
 Uses atomic writes (temp file + rename) by default to prevent partial files.
 
 For local sessions, use this tool to write files using the session's working directory context.
 
+With sudo=true, files in directories the login user cannot write to (e.g.
+/etc) are uploaded to a temp file and moved into place with sudo, with the
+given mode and owner. The sudo password is taken from the session's sudo
+cache (shell_provide_input with cache_for_sudo) or the server's
+sudo_password_env; without one the upload fails and nothing is written.
+
+Set normalize_line_endings and strip_bom to clean Windows-originated text
+(CRLF line endings, a UTF-8 BOM) before it is written.
+
 Returns upload status, file metadata, and SHA256 checksum.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
@@ -96,14 +143,14 @@ Returns upload status, file metadata, and SHA256 checksum.`),
 			mcp.Description("File content to upload (for small files)"),
 		),
 		mcp.WithString("encoding",
-			mcp.Description("Content encoding: 'text' (default) or 'base64' for binary content"),
+			mcp.Description("Content encoding: 'text' (default) or 'base64' for binary content. Text with NUL bytes, control characters or invalid UTF-8 is rejected; use base64 for it"),
 			mcp.DefaultString("text"),
 		),
 		mcp.WithString("local_path",
 			mcp.Description("Local file path to upload from (alternative to content)"),
 		),
 		mcp.WithString("mode",
-			mcp.Description("File permissions in octal (e.g., '0644')"),
+			mcp.Description("File permissions in octal (e.g., '0644'; default: 0666 minus transfer.umask, 0644 unless configured)"),
 		),
 		mcp.WithBoolean("overwrite",
 			mcp.Description("Whether to overwrite if file exists (default: false)"),
@@ -123,6 +170,22 @@ Returns upload status, file metadata, and SHA256 checksum.`),
 		mcp.WithBoolean("compress",
 			mcp.Description("Compress content with gzip before upload (for text files)"),
 		),
+		mcp.WithBoolean("verify_readback",
+			mcp.Description("After writing, re-read the remote file over SFTP and compare its SHA256 to the source; fails with readback_mismatch if they differ. Local sessions only compare the size (default: false)"),
+		),
+		mcp.WithBoolean("normalize_line_endings",
+			mcp.Description("Rewrite CRLF line endings as LF before writing, for content that came from Windows (default: false)"),
+		),
+		mcp.WithBoolean("strip_bom",
+			mcp.Description("Remove a leading UTF-8 byte order mark before writing (default: false)"),
+		),
+		mcp.WithBoolean("sudo",
+			mcp.Description("If the destination is not writable, upload to a temp file and move it into place with sudo in the session's shell, using the cached sudo password. sudo_used in the result says whether sudo was needed (default: false)"),
+		),
+		mcp.WithString("owner",
+			mcp.Description("With sudo: owner of the written file as 'user' or 'user:group'; the file is always placed with sudo. Default: root for files placed with sudo"),
+		),
+		destructiveTool(),
 	)
 }
 
@@ -155,6 +218,7 @@ Returns the old and new paths along with file metadata.`),
 		mcp.WithBoolean("create_dirs",
 			mcp.Description("Create parent directories of destination if they don't exist (default: false)"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -174,6 +238,15 @@ type FileGetResult struct {
 	ChecksumVerified bool    `json:"checksum_verified,omitempty"`
 	Compressed       bool    `json:"compressed,omitempty"`
 	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+	Format           string  `json:"format,omitempty"`
+	FileCount        int     `json:"file_count,omitempty"`
+	StartLine        int     `json:"start_line,omitempty"`
+	EndLine          int     `json:"end_line,omitempty"`
+	TotalLines       int     `json:"total_lines,omitempty"`
+	Decompressed     string  `json:"decompressed,omitempty"`  // "gzip" or "bzip2"
+	OriginalSize     int64   `json:"original_size,omitempty"` // compressed size when decompressed
+	AutoBase64       bool    `json:"auto_base64,omitempty"`   // text was requested but the file is binary
+	InvalidBytes     int     `json:"invalid_utf8_bytes,omitempty"`
 }
 
 // FilePutResult represents the result of a file put operation.
@@ -189,6 +262,12 @@ type FilePutResult struct {
 	Compressed       bool    `json:"compressed,omitempty"`
 	OriginalSize     int64   `json:"original_size,omitempty"`
 	CompressionRatio float64 `json:"compression_ratio,omitempty"`
+	Verified         string  `json:"verified,omitempty"`  // verify_readback method: "sha256" or "size"
+	Streamed         bool    `json:"streamed,omitempty"`  // local_path was streamed instead of read into memory
+	SudoUsed         *bool   `json:"sudo_used,omitempty"` // set when sudo was requested
+	Owner            string  `json:"owner,omitempty"`
+
+	Normalized *ContentNormalization `json:"normalized,omitempty"`
 }
 
 // FileMvResult represents the result of a file move operation.
@@ -216,6 +295,15 @@ type FileGetOptions struct {
 	ExpectedChecksum string
 	Preserve         bool
 	Compress         bool
+	Format           string // "tar" or "tar.gz" to archive a directory
+	Pattern          string // glob filter for archived files
+	StartLine        int    // first line to return (1-based)
+	EndLine          int    // last line to return, inclusive (0 = EOF)
+	Decompress       bool   // gunzip/bunzip2 the file before returning it
+	ProgressFile     string // manifest updated while reading, for shell_transfer_status
+
+	// TextInvalidThreshold is transfer.text_invalid_threshold: see textContent.
+	TextInvalidThreshold float64
 }
 
 func (s *Server) handleShellFileGet(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -229,6 +317,15 @@ func (s *Server) handleShellFileGet(ctx context.Context, req mcp.CallToolRequest
 		ExpectedChecksum: mcp.ParseString(req, "expected_checksum", ""),
 		Preserve:         mcp.ParseBoolean(req, "preserve", true),
 		Compress:         mcp.ParseBoolean(req, "compress", false),
+		Format:           mcp.ParseString(req, "format", ""),
+		Pattern:          mcp.ParseString(req, "pattern", ""),
+		StartLine:        mcp.ParseInt(req, "start_line", 0),
+		EndLine:          mcp.ParseInt(req, "end_line", 0),
+		Decompress:       mcp.ParseBoolean(req, "decompress", false),
+		ProgressFile:     mcp.ParseString(req, "progress_file", ""),
+	}
+	if s.config != nil {
+		opts.TextInvalidThreshold = s.config.Transfer.TextInvalidThreshold
 	}
 
 	if sessionID == "" {
@@ -237,11 +334,23 @@ func (s *Server) handleShellFileGet(ctx context.Context, req mcp.CallToolRequest
 	if remotePath == "" {
 		return mcp.NewToolResultError("remote_path is required"), nil
 	}
+	if opts.Format != "" && !validArchiveFormat(opts.Format) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid format %q: must be 'tar' or 'tar.gz'", opts.Format)), nil
+	}
+	if errResult := validateLineRange(opts); errResult != nil {
+		return errResult, nil
+	}
+	if errResult := validateDecompress(opts); errResult != nil {
+		return errResult, nil
+	}
 
 	sess, err := s.sessionManager.Get(sessionID)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	// Resolve relative path using session's cwd
 	resolvedPath := sess.ResolvePath(remotePath)
@@ -270,14 +379,30 @@ func (s *Server) handleSSHFileGet(sess *session.Session, remotePath string, opts
 		return mcp.NewToolResultError(fmt.Sprintf("stat remote file: %v", err)), nil
 	}
 	if info.IsDir() {
-		return mcp.NewToolResultError("path is a directory, use recursive option for directories"), nil
+		if opts.Format == "" {
+			return mcp.NewToolResultError(errPathIsDirectory), nil
+		}
+		return s.archiveDirectory(sftpArchiveSource{client: sftpClient}, remotePath, info, opts)
+	}
+	if opts.Format != "" {
+		return mcp.NewToolResultError(errFormatRequiresDir), nil
+	}
+	if opts.wantsLineRange() {
+		f, err := sftpClient.Open(remotePath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf(errOpenRemoteFile, err)), nil
+		}
+		defer f.Close()
+		return s.getFileLines(f, remotePath, info, opts)
 	}
 
-	if info.Size() > maxContentSize && opts.LocalPath == "" {
-		return mcp.NewToolResultError(fmt.Sprintf("file size (%d bytes) exceeds limit (%d bytes), please specify local_path to save the file", info.Size(), maxContentSize)), nil
+	if opts.LocalPath == "" {
+		if errResult := s.checkInlineFileSize(remotePath, info.Size()); errResult != nil {
+			return errResult, nil
+		}
 	}
 
-	data, _, err := sftpClient.GetFile(remotePath)
+	data, err := s.readSSHFile(sftpClient, sess.ID, remotePath, info.Size(), opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("download file: %v", err)), nil
 	}
@@ -290,6 +415,11 @@ func (s *Server) handleSSHFileGet(sess *session.Session, remotePath string, opts
 		ModTime:    info.ModTime().Unix(),
 	}
 
+	data, errResult := applyDecompress(data, remotePath, opts, &result)
+	if errResult != nil {
+		return errResult, nil
+	}
+
 	if errResult := processFileChecksum(data, opts, &result); errResult != nil {
 		return errResult, nil
 	}
@@ -312,14 +442,30 @@ func (s *Server) handleLocalFileGet(path string, opts FileGetOptions) (*mcp.Call
 		return fileStatError(path, err), nil
 	}
 	if info.IsDir() {
-		return mcp.NewToolResultError("path is a directory, use recursive option for directories"), nil
+		if opts.Format == "" {
+			return mcp.NewToolResultError(errPathIsDirectory), nil
+		}
+		return s.archiveDirectory(localArchiveSource{fs: s.fs}, path, info, opts)
+	}
+	if opts.Format != "" {
+		return mcp.NewToolResultError(errFormatRequiresDir), nil
+	}
+	if opts.wantsLineRange() {
+		f, err := s.fs.Open(path)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf(errOpenLocalFile, err)), nil
+		}
+		defer f.Close()
+		return s.getFileLines(f, path, info, opts)
 	}
 
-	if info.Size() > maxContentSize && opts.LocalPath == "" {
-		return mcp.NewToolResultError(fmt.Sprintf("file size (%d bytes) exceeds limit (%d bytes), please specify local_path", info.Size(), maxContentSize)), nil
+	if opts.LocalPath == "" {
+		if errResult := s.checkInlineFileSize(path, info.Size()); errResult != nil {
+			return errResult, nil
+		}
 	}
 
-	data, err := s.fs.ReadFile(path)
+	data, err := s.readLocalFile(path, info.Size(), opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("read file: %v", err)), nil
 	}
@@ -332,6 +478,11 @@ func (s *Server) handleLocalFileGet(path string, opts FileGetOptions) (*mcp.Call
 		ModTime:    info.ModTime().Unix(),
 	}
 
+	data, errResult := applyDecompress(data, path, opts, &result)
+	if errResult != nil {
+		return errResult, nil
+	}
+
 	if errResult := processFileChecksum(data, opts, &result); errResult != nil {
 		return errResult, nil
 	}
@@ -348,6 +499,38 @@ func (s *Server) handleLocalFileGet(path string, opts FileGetOptions) (*mcp.Call
 	return jsonResult(result)
 }
 
+// maxInlineFileBytes returns output.max_inline_file_bytes, the largest file
+// returned in a tool result.
+func (s *Server) maxInlineFileBytes() int64 {
+	if s.config != nil && s.config.Output.MaxInlineFileBytes > 0 {
+		return s.config.Output.MaxInlineFileBytes
+	}
+	return int64(maxContentSize)
+}
+
+// checkInlineFileSize refuses to return a file of size bytes in the result
+// when it is over output.max_inline_file_bytes. It is checked against the
+// stat size, before anything is read.
+func (s *Server) checkInlineFileSize(path string, size int64) *mcp.CallToolResult {
+	limit := s.maxInlineFileBytes()
+	if size <= limit {
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf(
+		"file_too_large: %s is %d bytes, exceeds limit of %d bytes for inline content; save it with local_path, read part of it with start_line/end_line, or use shell_file_get_chunked",
+		path, size, limit))
+}
+
+// checkFileAccess rejects file tools on command mode sessions, whose files
+// are on the far side of the command (a container, a namespace) and reachable
+// neither through SFTP nor the local filesystem.
+func checkFileAccess(sess *session.Session) *mcp.CallToolResult {
+	if sess.Mode != "command" {
+		return nil
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("file tools are not supported for command mode session %s; use shell_exec (e.g. cat, tee, base64) instead", sess.ID))
+}
+
 // fileStatError returns appropriate error for file stat failures.
 func fileStatError(path string, err error) *mcp.CallToolResult {
 	if errors.Is(err, fs.ErrNotExist) {
@@ -393,6 +576,9 @@ func (s *Server) copyToLocalPath(data []byte, localPath string, info os.FileInfo
 // setContentWithEncoding sets result content with appropriate encoding and compression.
 func setContentWithEncoding(data []byte, path string, opts FileGetOptions, result *FileGetResult) {
 	contentData := data
+	if result.Decompressed != "" {
+		path = decompressedName(path)
+	}
 	if opts.Compress && isCompressible(path) {
 		compressed, err := compressData(data)
 		if err == nil && len(compressed) < len(data) {
@@ -403,13 +589,42 @@ func setContentWithEncoding(data []byte, path string, opts FileGetOptions, resul
 	}
 
 	result.ContentSize = len(contentData)
-	if opts.Encoding == "base64" || result.Compressed {
-		result.Content = base64.StdEncoding.EncodeToString(contentData)
-		result.Encoding = "base64"
-	} else {
-		result.Content = string(contentData)
-		result.Encoding = "text"
+	if opts.Encoding != "base64" && !result.Compressed {
+		text, invalid, ok := textContent(contentData, opts.TextInvalidThreshold)
+		if ok {
+			result.Content = text
+			result.Encoding = "text"
+			result.InvalidBytes = invalid
+			return
+		}
+		result.AutoBase64 = true
 	}
+	result.Content = base64.StdEncoding.EncodeToString(contentData)
+	result.Encoding = "base64"
+}
+
+// textContent returns data as a string if it looks like text: no NUL bytes
+// and at most threshold (a fraction of len(data)) bytes of invalid UTF-8,
+// which are replaced with U+FFFD so the JSON result stays intact.
+func textContent(data []byte, threshold float64) (string, int, bool) {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", 0, false
+	}
+	if utf8.Valid(data) {
+		return string(data), 0, true
+	}
+	invalid := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			invalid++
+		}
+		i += size
+	}
+	if float64(invalid) > threshold*float64(len(data)) {
+		return "", invalid, false
+	}
+	return strings.ToValidUTF8(string(data), string(utf8.RuneError)), invalid, true
 }
 
 // FilePutOptions contains options for file put operations.
@@ -424,6 +639,17 @@ type FilePutOptions struct {
 	Checksum   bool
 	Preserve   bool
 	Compress   bool
+	// VerifyReadback re-reads the written file and compares it to the source.
+	VerifyReadback bool
+	// Sudo falls back to moving the upload into place with sudo when the
+	// destination is not writable; Owner ("user" or "user:group") forces it.
+	Sudo  bool
+	Owner string
+	// NormalizeLineEndings and StripBOM clean text content before it is
+	// written; resolvePutContent records what they changed in normalized.
+	NormalizeLineEndings bool
+	StripBOM             bool
+	normalized           *ContentNormalization
 }
 
 // parseFilePutMode parses the mode string and updates opts.Mode.
@@ -431,11 +657,11 @@ func parseFilePutMode(modeStr string, opts *FilePutOptions) *mcp.CallToolResult
 	if modeStr == "" {
 		return nil
 	}
-	var mode uint32
-	if _, err := fmt.Sscanf(modeStr, "%o", &mode); err != nil {
+	mode, err := config.ParseOctalMode(modeStr)
+	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("invalid mode '%s': %v", modeStr, err))
 	}
-	opts.Mode = os.FileMode(mode)
+	opts.Mode = mode
 	return nil
 }
 
@@ -474,10 +700,42 @@ func (s *Server) resolveFileContent(opts FilePutOptions) ([]byte, time.Time, *mc
 		}
 		return data, time.Time{}, nil
 	}
+	if s.config == nil || !s.config.Transfer.AllowControlChars {
+		if problem := textProblem(opts.Content); problem != "" {
+			return nil, time.Time{}, mcp.NewToolResultError(fmt.Sprintf(
+				"content is not plain text (%s) and may have been altered in transit; send it base64-encoded with encoding: \"base64\" to write it byte for byte", problem))
+		}
+	}
 	return []byte(opts.Content), time.Time{}, nil
 }
 
+// textProblem describes the first thing in content that a text-encoded
+// upload should not contain: invalid UTF-8, U+FFFD (left where a decoder
+// already replaced invalid bytes) or a control character other than tab,
+// newline, carriage return, form feed, vertical tab and escape. It returns
+// "" for plain text.
+func textProblem(content string) string {
+	for i, r := range content {
+		switch {
+		case r == utf8.RuneError:
+			if _, size := utf8.DecodeRuneInString(content[i:]); size == 1 {
+				return fmt.Sprintf("invalid UTF-8 at byte %d", i)
+			}
+			return fmt.Sprintf("U+FFFD replacement character at byte %d", i)
+		case r == 0:
+			return fmt.Sprintf("NUL byte at byte %d", i)
+		case (r < 0x20 || r == 0x7f) && !strings.ContainsRune("\t\n\r\f\v\x1b", r):
+			return fmt.Sprintf("control character %U at byte %d", r, i)
+		}
+	}
+	return ""
+}
+
 func (s *Server) handleShellFilePut(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_put"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	remotePath := mcp.ParseString(req, "remote_path", "")
 
@@ -485,13 +743,20 @@ func (s *Server) handleShellFilePut(ctx context.Context, req mcp.CallToolRequest
 		Content:    mcp.ParseString(req, "content", ""),
 		Encoding:   mcp.ParseString(req, "encoding", "text"),
 		LocalPath:  mcp.ParseString(req, "local_path", ""),
-		Mode:       0644,
+		Mode:       s.config.Transfer.FileMode(),
 		Overwrite:  mcp.ParseBoolean(req, "overwrite", false),
 		CreateDirs: mcp.ParseBoolean(req, "create_dirs", false),
 		Atomic:     mcp.ParseBoolean(req, "atomic", true),
 		Checksum:   mcp.ParseBoolean(req, "checksum", true),
 		Preserve:   mcp.ParseBoolean(req, "preserve", false),
 		Compress:   mcp.ParseBoolean(req, "compress", false),
+
+		VerifyReadback: mcp.ParseBoolean(req, "verify_readback", false),
+		Sudo:           mcp.ParseBoolean(req, "sudo", false),
+		Owner:          mcp.ParseString(req, "owner", ""),
+
+		NormalizeLineEndings: mcp.ParseBoolean(req, "normalize_line_endings", false),
+		StripBOM:             mcp.ParseBoolean(req, "strip_bom", false),
 	}
 
 	if errResult := parseFilePutMode(mcp.ParseString(req, "mode", ""), &opts); errResult != nil {
@@ -501,16 +766,36 @@ func (s *Server) handleShellFilePut(ctx context.Context, req mcp.CallToolRequest
 	if errResult := validateFilePutInputs(sessionID, remotePath, opts); errResult != nil {
 		return errResult, nil
 	}
+	if errResult := validateSudoPut(opts); errResult != nil {
+		return errResult, nil
+	}
 
 	sess, err := s.sessionManager.Get(sessionID)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	resolvedPath := sess.ResolvePath(remotePath)
 	slog.Info("uploading file", slog.String("session_id", sessionID), slog.String("remote_path", resolvedPath), slog.Bool("atomic", opts.Atomic))
 
-	data, sourceModTime, errResult := s.resolveFileContent(opts)
+	if opts.Sudo {
+		data, sourceModTime, errResult := s.resolvePutContent(&opts)
+		if errResult != nil {
+			return errResult, nil
+		}
+		return s.handleFilePutSudo(ctx, sessionID, sess, resolvedPath, data, opts, sourceModTime)
+	}
+
+	if info, stream, errResult := s.streamPutSource(sess, opts); errResult != nil {
+		return errResult, nil
+	} else if stream {
+		return s.handleSSHFilePutStream(sess, resolvedPath, opts, info)
+	}
+
+	data, sourceModTime, errResult := s.resolvePutContent(&opts)
 	if errResult != nil {
 		return errResult, nil
 	}
@@ -528,25 +813,25 @@ func (s *Server) handleSSHFilePut(sess *session.Session, remotePath string, data
 	}
 
 	result := newFilePutResult(remotePath, data, opts.Mode)
+	result.Normalized = opts.normalized
 	setPutChecksum(data, opts.Checksum, &result)
 
-	if errResult := checkSSHFileOverwrite(sftpClient, remotePath, opts.Overwrite, &result); errResult != nil {
+	dir, errResult := prepareSSHPut(sftpClient, remotePath, opts, &result)
+	if errResult != nil {
 		return errResult, nil
 	}
 
-	dir := strings.ReplaceAll(filepath.Dir(remotePath), "\\", "/")
-	if opts.CreateDirs {
-		if err := sftpClient.MkdirAll(dir); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf(errCreateDirs, err)), nil
-		}
-		result.DirsCreated = true
-	}
-
 	if errResult := writeSSHFile(sftpClient, remotePath, dir, data, opts, &result); errResult != nil {
 		return errResult, nil
 	}
 
 	preserveSSHTimestamp(sftpClient, remotePath, opts.Preserve, sourceModTime)
+
+	if opts.VerifyReadback {
+		if errResult := verifySSHReadback(sftpClient, remotePath, data, &result); errResult != nil {
+			return errResult, nil
+		}
+	}
 	return jsonResult(result)
 }
 
@@ -568,6 +853,23 @@ func setPutChecksum(data []byte, calculate bool, result *FilePutResult) {
 	}
 }
 
+// prepareSSHPut applies the overwrite check and create_dirs for an upload to
+// remotePath and returns the destination directory.
+func prepareSSHPut(client *sftp.Client, remotePath string, opts FilePutOptions, result *FilePutResult) (string, *mcp.CallToolResult) {
+	if errResult := checkSSHFileOverwrite(client, remotePath, opts.Overwrite, result); errResult != nil {
+		return "", errResult
+	}
+
+	dir := strings.ReplaceAll(filepath.Dir(remotePath), "\\", "/")
+	if opts.CreateDirs {
+		if err := client.MkdirAll(dir); err != nil {
+			return "", mcp.NewToolResultError(fmt.Sprintf(errCreateDirs, err))
+		}
+		result.DirsCreated = true
+	}
+	return dir, nil
+}
+
 // checkSSHFileOverwrite checks if SSH file exists and handles overwrite logic.
 func checkSSHFileOverwrite(client *sftp.Client, path string, overwrite bool, result *FilePutResult) *mcp.CallToolResult {
 	_, err := client.Stat(path)
@@ -583,15 +885,23 @@ func checkSSHFileOverwrite(client *sftp.Client, path string, overwrite bool, res
 
 // writeSSHFile writes data to SSH server with optional atomic write.
 func writeSSHFile(client *sftp.Client, remotePath, dir string, data []byte, opts FilePutOptions, result *FilePutResult) *mcp.CallToolResult {
+	return writeSSHFileWith(client, remotePath, dir, opts, result, func(path string) error {
+		return client.PutFile(path, data, opts.Mode)
+	})
+}
+
+// writeSSHFileWith uploads with put, writing to a temp file that is renamed
+// into place when opts.Atomic is set.
+func writeSSHFileWith(client *sftp.Client, remotePath, dir string, opts FilePutOptions, result *FilePutResult, put func(path string) error) *mcp.CallToolResult {
 	if !opts.Atomic {
-		if err := client.PutFile(remotePath, data, opts.Mode); err != nil {
+		if err := put(remotePath); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("upload file: %v", err))
 		}
 		return nil
 	}
 
 	tempPath := fmt.Sprintf("%s/.%s.tmp.%s", dir, filepath.Base(remotePath), randomSuffix())
-	if err := client.PutFile(tempPath, data, opts.Mode); err != nil {
+	if err := put(tempPath); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("upload temp file: %v", err))
 	}
 
@@ -671,6 +981,7 @@ func isCompressible(filename string) bool {
 
 func (s *Server) handleLocalFilePut(path string, data []byte, opts FilePutOptions, sourceModTime time.Time) (*mcp.CallToolResult, error) {
 	result := newFilePutResult(path, data, opts.Mode)
+	result.Normalized = opts.normalized
 	setPutChecksum(data, opts.Checksum, &result)
 
 	if errResult := s.checkLocalFileOverwrite(path, opts.Overwrite, &result); errResult != nil {
@@ -690,6 +1001,12 @@ func (s *Server) handleLocalFilePut(path string, data []byte, opts FilePutOption
 	}
 
 	s.preserveLocalTimestamp(path, opts.Preserve, sourceModTime)
+
+	if opts.VerifyReadback {
+		if errResult := s.verifyLocalReadback(path, data, &result); errResult != nil {
+			return errResult, nil
+		}
+	}
 	return jsonResult(result)
 }
 
@@ -738,6 +1055,10 @@ func (s *Server) preserveLocalTimestamp(path string, preserve bool, modTime time
 }
 
 func (s *Server) handleShellFileMv(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_mv"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	source := mcp.ParseString(req, "source", "")
 	destination := mcp.ParseString(req, "destination", "")
@@ -761,6 +1082,9 @@ func (s *Server) handleShellFileMv(ctx context.Context, req mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	// Resolve relative paths using session's cwd
 	resolvedSource := sess.ResolvePath(source)
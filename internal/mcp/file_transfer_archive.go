@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"github.com/acolita/claude-shell-mcp/internal/sftp"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Archive formats accepted by shell_file_get for directories.
+const (
+	archiveFormatTar   = "tar"
+	archiveFormatTarGz = "tar.gz"
+)
+
+// maxArchiveInputSize caps the uncompressed tar stream built in memory.
+// The encoded result is additionally subject to maxContentSize unless it is
+// written to local_path.
+const maxArchiveInputSize = 8 * maxContentSize
+
+var errArchiveTooLarge = fmt.Errorf("directory exceeds archive limit (%d bytes), use shell_dir_get instead", maxArchiveInputSize)
+
+// archiveSource abstracts directory reads so the same tar builder works over
+// SFTP and the local filesystem port.
+type archiveSource interface {
+	readDir(path string) ([]os.FileInfo, error)
+	readFile(path string) ([]byte, error)
+	readLink(path string) (string, error)
+}
+
+// sftpArchiveSource reads a directory tree over SFTP.
+type sftpArchiveSource struct {
+	client *sftp.Client
+}
+
+func (a sftpArchiveSource) readDir(path string) ([]os.FileInfo, error) {
+	return a.client.ReadDir(path)
+}
+
+func (a sftpArchiveSource) readFile(path string) ([]byte, error) {
+	return a.client.ReadFile(path)
+}
+
+func (a sftpArchiveSource) readLink(path string) (string, error) {
+	return a.client.ReadLink(path)
+}
+
+// localArchiveSource reads a directory tree through the filesystem port.
+type localArchiveSource struct {
+	fs ports.FileSystem
+}
+
+func (a localArchiveSource) readDir(path string) ([]os.FileInfo, error) {
+	entries, err := a.fs.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (a localArchiveSource) readFile(path string) ([]byte, error) {
+	return a.fs.ReadFile(path)
+}
+
+func (a localArchiveSource) readLink(path string) (string, error) {
+	return a.fs.Readlink(path)
+}
+
+// validArchiveFormat reports whether format is a supported directory archive format.
+func validArchiveFormat(format string) bool {
+	return format == archiveFormatTar || format == archiveFormatTarGz
+}
+
+// tarBuilder accumulates a tar archive of a directory tree in memory.
+type tarBuilder struct {
+	src       archiveSource
+	root      string
+	pattern   string
+	buf       bytes.Buffer
+	tw        *tar.Writer
+	fileCount int
+}
+
+// buildDirArchive walks root through src and returns the archive bytes in the
+// requested format along with the number of regular files included.
+// Symlinks are stored as links and never followed.
+func buildDirArchive(src archiveSource, root, format, pattern string) ([]byte, int, error) {
+	b := &tarBuilder{src: src, root: root, pattern: pattern}
+	b.tw = tar.NewWriter(&b.buf)
+
+	if err := b.walk(""); err != nil {
+		return nil, 0, err
+	}
+	if err := b.tw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("close tar: %w", err)
+	}
+
+	data := b.buf.Bytes()
+	if format == archiveFormatTarGz {
+		compressed, err := compressData(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		data = compressed
+	}
+	return data, b.fileCount, nil
+}
+
+func (b *tarBuilder) walk(relPath string) error {
+	dir := b.root
+	if relPath != "" {
+		dir = b.root + "/" + relPath
+	}
+
+	entries, err := b.src.readDir(dir)
+	if err != nil {
+		return fmt.Errorf("read directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if shouldExclude(entry.Name(), defaultExclusions) {
+			continue
+		}
+		if err := b.addEntry(entry, buildRelPath(relPath, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *tarBuilder) addEntry(info os.FileInfo, relPath string) error {
+	fullPath := b.root + "/" + relPath
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		if !matchesPattern(relPath, b.pattern) {
+			return nil
+		}
+		target, err := b.src.readLink(fullPath)
+		if err != nil {
+			return fmt.Errorf("read symlink %s: %w", fullPath, err)
+		}
+		return b.writeHeader(info, relPath, target)
+
+	case info.IsDir():
+		if err := b.writeHeader(info, relPath+"/", ""); err != nil {
+			return err
+		}
+		return b.walk(relPath)
+
+	case info.Mode().IsRegular():
+		if !matchesPattern(relPath, b.pattern) {
+			return nil
+		}
+		if int64(b.buf.Len())+info.Size() > maxArchiveInputSize {
+			return errArchiveTooLarge
+		}
+		data, err := b.src.readFile(fullPath)
+		if err != nil {
+			return fmt.Errorf("read file %s: %w", fullPath, err)
+		}
+		if err := b.writeHeader(info, relPath, ""); err != nil {
+			return err
+		}
+		if _, err := b.tw.Write(data); err != nil {
+			return fmt.Errorf("write tar data %s: %w", relPath, err)
+		}
+		b.fileCount++
+		return nil
+	}
+
+	// Devices, sockets and pipes are skipped.
+	return nil
+}
+
+func (b *tarBuilder) writeHeader(info os.FileInfo, name, link string) error {
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("tar header %s: %w", name, err)
+	}
+	hdr.Name = filepath.ToSlash(name)
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("write tar header %s: %w", name, err)
+	}
+	return nil
+}
+
+// archiveDirectory builds an archive of dir, writing it to
+// opts.LocalPath when set and returning it inline as base64 otherwise.
+func (s *Server) archiveDirectory(src archiveSource, dir string, info os.FileInfo, opts FileGetOptions) (*mcp.CallToolResult, error) {
+	data, fileCount, err := buildDirArchive(src, dir, opts.Format, opts.Pattern)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("archive directory: %v", err)), nil
+	}
+
+	if opts.LocalPath == "" && len(data) > maxContentSize {
+		return mcp.NewToolResultError(fmt.Sprintf("archive size (%d bytes) exceeds limit (%d bytes), please specify local_path or use shell_dir_get", len(data), maxContentSize)), nil
+	}
+
+	result := FileGetResult{
+		Status:     "completed",
+		RemotePath: dir,
+		Size:       int64(len(data)),
+		Mode:       fmt.Sprintf("%04o", info.Mode().Perm()),
+		ModTime:    info.ModTime().Unix(),
+		Format:     opts.Format,
+		FileCount:  fileCount,
+		Compressed: opts.Format == archiveFormatTarGz,
+	}
+
+	if errResult := processFileChecksum(data, opts, &result); errResult != nil {
+		return errResult, nil
+	}
+
+	if opts.LocalPath != "" {
+		if err := s.fs.MkdirAll(filepath.Dir(opts.LocalPath), 0755); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("create directory: %v", err)), nil
+		}
+		if err := s.fs.WriteFile(opts.LocalPath, data, 0644); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("write file: %v", err)), nil
+		}
+		result.LocalPath = opts.LocalPath
+		return jsonResult(result)
+	}
+
+	result.ContentSize = len(data)
+	result.Content = base64.StdEncoding.EncodeToString(data)
+	result.Encoding = "base64"
+	return jsonResult(result)
+}
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// readTarEntries decodes a tar stream and returns entry name -> content.
+func readTarEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	entries := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("tar read: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		if hdr.Typeflag == tar.TypeSymlink {
+			entries[hdr.Name] = "->" + hdr.Linkname
+			continue
+		}
+		entries[hdr.Name] = string(body)
+	}
+	return entries
+}
+
+func newArchiveTestServer(t *testing.T) (*Server, *fakefs.FS) {
+	t.Helper()
+	ffs := fakefs.New()
+	ffs.AddFile("/proj/main.go", []byte("package main"), 0644)
+	ffs.AddFile("/proj/README.md", []byte("# readme"), 0644)
+	ffs.AddFile("/proj/pkg/util.go", []byte("package pkg"), 0644)
+	ffs.AddFile("/proj/.git/HEAD", []byte("ref"), 0644)
+	ffs.AddSymlink("/proj/link", "main.go")
+
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_tar"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func TestHandleShellFileGet_DirectoryAsTar(t *testing.T) {
+	srv, _ := newArchiveTestServer(t)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj",
+		"format":      "tar",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["format"] != "tar" || m["encoding"] != "base64" {
+		t.Errorf("format=%v encoding=%v", m["format"], m["encoding"])
+	}
+	if m["file_count"] != float64(3) {
+		t.Errorf("file_count=%v, want 3", m["file_count"])
+	}
+
+	data, err := base64.StdEncoding.DecodeString(m["content"].(string))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	entries := readTarEntries(t, data)
+	if entries["main.go"] != "package main" {
+		t.Errorf("main.go = %q", entries["main.go"])
+	}
+	if entries["pkg/util.go"] != "package pkg" {
+		t.Errorf("pkg/util.go = %q", entries["pkg/util.go"])
+	}
+	if _, ok := entries["pkg/"]; !ok {
+		t.Error("expected directory entry pkg/")
+	}
+	if entries["link"] != "->main.go" {
+		t.Errorf("link = %q, want symlink to main.go", entries["link"])
+	}
+	for name := range entries {
+		if strings.HasPrefix(name, ".git") {
+			t.Errorf("excluded entry %q present in archive", name)
+		}
+	}
+}
+
+func TestHandleShellFileGet_DirectoryAsTarGzWithPattern(t *testing.T) {
+	srv, _ := newArchiveTestServer(t)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj",
+		"format":      "tar.gz",
+		"pattern":     "**/*.go",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["compressed"] != true {
+		t.Errorf("compressed=%v, want true", m["compressed"])
+	}
+	raw, _ := base64.StdEncoding.DecodeString(m["content"].(string))
+	data, err := decompressData(raw)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	entries := readTarEntries(t, data)
+	if _, ok := entries["README.md"]; ok {
+		t.Error("README.md should be filtered out by pattern")
+	}
+	if entries["main.go"] != "package main" || entries["pkg/util.go"] != "package pkg" {
+		t.Errorf("missing .go files: %v", entries)
+	}
+}
+
+func TestHandleShellFileGet_DirectoryArchiveToLocalPath(t *testing.T) {
+	srv, ffs := newArchiveTestServer(t)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj",
+		"format":      "tar",
+		"local_path":  "/out/proj.tar",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if _, ok := m["content"]; ok {
+		t.Error("content should be omitted when local_path is set")
+	}
+	data, err := ffs.ReadFile("/out/proj.tar")
+	if err != nil {
+		t.Fatalf("archive not written: %v", err)
+	}
+	if entries := readTarEntries(t, data); entries["main.go"] != "package main" {
+		t.Errorf("archive content wrong: %v", entries)
+	}
+}
+
+func TestHandleShellFileGet_DirectoryWithoutFormat(t *testing.T) {
+	srv, _ := newArchiveTestServer(t)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "directory") {
+		t.Errorf("expected directory error, got %s", resultText(result))
+	}
+}
+
+func TestHandleShellFileGet_FormatOnFile(t *testing.T) {
+	srv, _ := newArchiveTestServer(t)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj/main.go",
+		"format":      "tar",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "only supported") {
+		t.Errorf("expected format error, got %s", resultText(result))
+	}
+}
+
+func TestHandleShellFileGet_InvalidFormat(t *testing.T) {
+	srv, _ := newArchiveTestServer(t)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_tar",
+		"remote_path": "/proj",
+		"format":      "zip",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid format") {
+		t.Errorf("expected invalid format error, got %s", resultText(result))
+	}
+}
+
+func TestBuildDirArchive_SizeCap(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/big/blob.bin", make([]byte, maxArchiveInputSize+1), 0644)
+
+	_, _, err := buildDirArchive(localArchiveSource{fs: ffs}, "/big", archiveFormatTar, "")
+	if err != errArchiveTooLarge {
+		t.Errorf("err = %v, want errArchiveTooLarge", err)
+	}
+}
@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Checksum methods reported in FileChecksumResult.Method.
+const (
+	checksumMethodCommand    = "remote_command"
+	checksumMethodSFTP       = "sftp"
+	checksumMethodFilesystem = "filesystem"
+)
+
+// checksumAlgorithm is a hash shell_file_checksum supports.
+type checksumAlgorithm struct {
+	newHash func() hash.Hash
+	// commands are tried in order on the remote host; each prints the hex
+	// digest as the first field of its output.
+	commands []string
+}
+
+var checksumAlgorithms = map[string]checksumAlgorithm{
+	"sha256": {sha256.New, []string{"sha256sum --", "shasum -a 256 --"}},
+	"sha1":   {sha1.New, []string{"sha1sum --", "shasum -a 1 --"}},
+	"md5":    {md5.New, []string{"md5sum --", "md5 -q"}},
+}
+
+func shellFileChecksumTool() mcp.Tool {
+	return mcp.NewTool("shell_file_checksum",
+		mcp.WithDescription(`Compute the checksum of a file without downloading it.
+
+For SSH sessions the hash is computed on the remote host with sha256sum,
+shasum or md5sum (whichever is installed); if none works the file is read
+over SFTP and hashed here. For local sessions the file is hashed directly.
+
+Set expected to compare against a known digest: the result then has
+matches: true/false.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File to hash (relative paths use the session's cwd)"),
+		),
+		mcp.WithString("algorithm",
+			mcp.Description("Hash algorithm: sha256 (default), sha1 or md5"),
+		),
+		mcp.WithString("expected",
+			mcp.Description("Expected hex digest to compare with (case-insensitive)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// FileChecksumResult is the result of shell_file_checksum.
+type FileChecksumResult struct {
+	Status    string `json:"status"`
+	Path      string `json:"path"`
+	Algorithm string `json:"algorithm"`
+	Checksum  string `json:"checksum"`
+	Size      int64  `json:"size,omitempty"`
+	Method    string `json:"method"` // "remote_command", "sftp" or "filesystem"
+	Matches   *bool  `json:"matches,omitempty"`
+}
+
+func (s *Server) handleShellFileChecksum(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	filePath := mcp.ParseString(req, "path", "")
+	algorithm := strings.ToLower(mcp.ParseString(req, "algorithm", "sha256"))
+	expected := strings.ToLower(strings.TrimSpace(mcp.ParseString(req, "expected", "")))
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if filePath == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	algo, ok := checksumAlgorithms[algorithm]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid algorithm %q: must be sha256, sha1 or md5", algorithm)), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	filePath = sess.ResolvePath(filePath)
+
+	slog.Info("computing file checksum",
+		slog.String("session_id", sessionID),
+		slog.String("path", filePath),
+		slog.String("algorithm", algorithm),
+	)
+
+	result := FileChecksumResult{Status: "completed", Path: filePath, Algorithm: algorithm}
+	if sess.IsSSH() {
+		if sum, ok := remoteChecksum(sess, filePath, algo); ok {
+			result.Checksum = sum
+			result.Method = checksumMethodCommand
+		}
+	}
+
+	if result.Checksum == "" {
+		ep, err := s.relayEndpointFor(sess)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+		}
+		info, err := ep.stat(filePath)
+		if err != nil {
+			return fileStatError(filePath, err), nil
+		}
+		if info.IsDir() {
+			return mcp.NewToolResultError(fmt.Sprintf("path is a directory: %s", filePath)), nil
+		}
+		if result.Checksum, err = hashEndpointFile(ep, filePath, algo.newHash()); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("hash file: %v", err)), nil
+		}
+		result.Size = info.Size()
+		result.Method = checksumMethodFilesystem
+		if sess.IsSSH() {
+			result.Method = checksumMethodSFTP
+		}
+	}
+
+	if expected != "" {
+		matches := result.Checksum == expected
+		result.Matches = &matches
+	}
+	return jsonResult(result)
+}
+
+// remoteChecksum hashes path on the remote host with the first of algo's
+// commands that prints a well-formed digest. ok is false when none does,
+// e.g. because no hashing tool is installed or the file can't be read.
+func remoteChecksum(sess *session.Session, path string, algo checksumAlgorithm) (string, bool) {
+	want := algo.newHash().Size() * 2
+	for _, command := range algo.commands {
+		stdout, exitCode, err := execForOutput(sess, command+" "+shellQuote(path)+" 2>/dev/null")
+		if err != nil {
+			// The session is not at a prompt; don't queue more commands.
+			break
+		}
+		if exitCode != 0 {
+			continue
+		}
+		fields := strings.Fields(stdout)
+		if len(fields) == 0 {
+			continue
+		}
+		sum := strings.ToLower(fields[0])
+		if _, err := hex.DecodeString(sum); err == nil && len(sum) == want {
+			return sum, true
+		}
+	}
+	return "", false
+}
+
+// hashEndpointFile streams p through h and returns the hex digest.
+func hashEndpointFile(ep relayEndpoint, p string, h hash.Hash) (string, error) {
+	r, err := ep.open(p)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
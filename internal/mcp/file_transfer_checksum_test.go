@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+const helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func TestHandleShellFileChecksum_Local(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/data/hello.txt", []byte("hello\n"), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_sum"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"", helloSHA256},
+		{"sha1", "f572d396fae9206628714fb2ce00f72e94f2258f"},
+		{"MD5", "b1946ac92492d2347c6235b4d2611184"},
+	}
+	for _, tt := range tests {
+		result, err := srv.handleShellFileChecksum(context.Background(), makeRequest(map[string]any{
+			"session_id": "sess_sum",
+			"path":       "/data/hello.txt",
+			"algorithm":  tt.algorithm,
+		}))
+		if err != nil || result.IsError {
+			t.Fatalf("%s: checksum failed: %v %s", tt.algorithm, err, resultText(result))
+		}
+		m := resultJSON(t, result)
+		if m["checksum"] != tt.want || m["method"] != "filesystem" || m["size"] != float64(6) {
+			t.Errorf("%s: checksum=%v method=%v size=%v, want %s/filesystem/6", tt.algorithm, m["checksum"], m["method"], m["size"], tt.want)
+		}
+		if _, ok := m["matches"]; ok {
+			t.Errorf("%s: matches set without expected", tt.algorithm)
+		}
+	}
+}
+
+func TestHandleShellFileChecksum_Expected(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/data/hello.txt", []byte("hello\n"), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_sum"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	for expected, want := range map[string]bool{strings.ToUpper(helloSHA256): true, "deadbeef": false} {
+		result, _ := srv.handleShellFileChecksum(context.Background(), makeRequest(map[string]any{
+			"session_id": "sess_sum",
+			"path":       "/data/hello.txt",
+			"expected":   expected,
+		}))
+		if m := resultJSON(t, result); m["matches"] != want {
+			t.Errorf("expected %s: matches = %v, want %v", expected, m["matches"], want)
+		}
+	}
+}
+
+// newChecksumSSHServer returns a server with an initialized ssh-mode
+// session "sess_ssh" that has no SFTP connection.
+func newChecksumSSHServer(t *testing.T) (*Server, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := session.NewSession("sess_ssh", "ssh",
+		session.WithPTY(pty),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+		session.WithSessionRandom(fakerand.NewSequential()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	return newTestServer(sm), pty
+}
+
+func TestHandleShellFileChecksum_RemoteCommand(t *testing.T) {
+	srv, pty := newChecksumSSHServer(t)
+	pty.AddResponse(commandResponse("00010203", helloSHA256+"  /data/hello.txt", 0))
+
+	result, err := srv.handleShellFileChecksum(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ssh",
+		"path":       "/data/hello.txt",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("checksum failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["checksum"] != helloSHA256 || m["method"] != "remote_command" {
+		t.Errorf("checksum=%v method=%v, want the remote digest", m["checksum"], m["method"])
+	}
+	if written := pty.Written(); !strings.Contains(written, "sha256sum -- ") || !strings.Contains(written, "/data/hello.txt") {
+		t.Errorf("written = %q, want a sha256sum command", written)
+	}
+}
+
+func TestHandleShellFileChecksum_RemoteFallsBackToSFTP(t *testing.T) {
+	srv, pty := newChecksumSSHServer(t)
+	pty.AddResponse(commandResponse("00010203", "", 127))
+	pty.AddResponse("/home/user\n")
+	pty.AddResponse(commandResponse("04050607", "", 127))
+
+	result, _ := srv.handleShellFileChecksum(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ssh",
+		"path":       "/data/hello.txt",
+	}))
+	// Both commands failed, so the SFTP fallback is tried; this session has none.
+	if !result.IsError || !strings.Contains(resultText(result), "get SFTP client") {
+		t.Errorf("got %q, want the SFTP fallback error", resultText(result))
+	}
+	if written := pty.Written(); !strings.Contains(written, "shasum -a 256 --") {
+		t.Errorf("written = %q, want the shasum fallback command", written)
+	}
+}
+
+func TestHandleShellFileChecksum_Errors(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/data/hello.txt", []byte("hello\n"), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_sum"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"path": "/data/hello.txt"}, errSessionIDRequired},
+		{map[string]any{"session_id": "sess_sum"}, "path is required"},
+		{map[string]any{"session_id": "sess_sum", "path": "/data/hello.txt", "algorithm": "crc32"}, "invalid algorithm"},
+		{map[string]any{"session_id": "sess_sum", "path": "/data/missing"}, "file not found"},
+		{map[string]any{"session_id": "sess_sum", "path": "/data"}, "is a directory"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellFileChecksum(context.Background(), makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/xxhash"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Chunk checksum algorithms of a chunked transfer (checksum_algo). sha256
+// is the default; crc32 and xxhash only detect corruption, but are much
+// cheaper on large transfers, and none skips checksums.
+const (
+	chunkChecksumSHA256 = "sha256"
+	chunkChecksumCRC32  = "crc32"
+	chunkChecksumXXHash = "xxhash"
+	chunkChecksumNone   = "none"
+)
+
+// parseChunkChecksumAlgo reads the checksum_algo argument.
+func parseChunkChecksumAlgo(req mcp.CallToolRequest) (string, *mcp.CallToolResult) {
+	algo := strings.ToLower(mcp.ParseString(req, "checksum_algo", chunkChecksumSHA256))
+	switch algo {
+	case chunkChecksumSHA256, chunkChecksumCRC32, chunkChecksumXXHash, chunkChecksumNone:
+		return algo, nil
+	case "":
+		return chunkChecksumSHA256, nil
+	}
+	return "", mcp.NewToolResultError(fmt.Sprintf("invalid checksum_algo %q: must be sha256, crc32, xxhash or none", algo))
+}
+
+// checksumAlgo returns the chunk checksum algorithm of the transfer.
+// Manifests written before checksum_algo existed used sha256.
+func (m *TransferManifest) checksumAlgo() string {
+	if m.ChecksumAlgo == "" {
+		return chunkChecksumSHA256
+	}
+	return m.ChecksumAlgo
+}
+
+// chunkChecksum returns the checksum of a chunk's data with algo, hex
+// encoded, or "" for none.
+func chunkChecksum(algo string, data []byte) string {
+	switch algo {
+	case chunkChecksumNone:
+		return ""
+	case chunkChecksumCRC32:
+		return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+	case chunkChecksumXXHash:
+		return fmt.Sprintf("%016x", xxhash.Sum64(data))
+	default:
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestChunkChecksum(t *testing.T) {
+	tests := []struct {
+		algo string
+		want string
+	}{
+		{chunkChecksumSHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{chunkChecksumCRC32, "352441c2"},
+		{chunkChecksumXXHash, "44bc2cf5ad770999"},
+		{chunkChecksumNone, ""},
+	}
+	for _, tt := range tests {
+		if got := chunkChecksum(tt.algo, []byte("abc")); got != tt.want {
+			t.Errorf("chunkChecksum(%s) = %q, want %q", tt.algo, got, tt.want)
+		}
+	}
+}
+
+func TestParseChunkChecksumAlgo(t *testing.T) {
+	for args, want := range map[string]string{"": "sha256", "CRC32": "crc32", "xxhash": "xxhash", "none": "none"} {
+		req := makeRequest(map[string]any{})
+		if args != "" {
+			req = makeRequest(map[string]any{"checksum_algo": args})
+		}
+		if got, errResult := parseChunkChecksumAlgo(req); errResult != nil || got != want {
+			t.Errorf("parseChunkChecksumAlgo(%q) = %q, %v; want %q", args, got, errResult, want)
+		}
+	}
+
+	srv := newTestServer(fakesessionmgr.New())
+	result, _ := srv.handleShellFileGetChunked(context.Background(), makeRequest(map[string]any{
+		"session_id":    "sess_x",
+		"remote_path":   "/data/big.iso",
+		"local_path":    "/tmp/big.iso",
+		"checksum_algo": "md5",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid checksum_algo") {
+		t.Errorf("result = %s, want invalid checksum_algo", resultText(result))
+	}
+}
+
+func TestTransferChunksGet_ChecksumAlgo(t *testing.T) {
+	ffs := fakefs.New()
+	clk := fakeclock.New(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	cfg := config.DefaultConfig()
+	cfg.Transfer.CleanupManifestOnComplete = false // the saved manifest is checked below
+	srv := NewServer(cfg, WithFileSystem(ffs), WithClock(clk))
+
+	data := []byte("abcabc")
+	ffs.AddFile("/local/out.bin", make([]byte, len(data)), 0644)
+	localFile, err := ffs.OpenFile("/local/out.bin", os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("open local file: %v", err)
+	}
+	defer localFile.Close()
+
+	manifest := &TransferManifest{
+		Version:      1,
+		Direction:    "get",
+		TotalSize:    int64(len(data)),
+		ChunkSize:    3,
+		TotalChunks:  2,
+		ChecksumAlgo: chunkChecksumCRC32,
+		Chunks: []ChunkInfo{
+			{Index: 0, Offset: 0, Size: 3},
+			{Index: 1, Offset: 3, Size: 3},
+		},
+	}
+	result, err := srv.transferChunksGet(localFile, bytes.NewReader(data), manifest, "/local/out.bin.transfer", clk.Now())
+	if err != nil || result.IsError {
+		t.Fatalf("transferChunksGet() = %s, %v", resultText(result), err)
+	}
+	for i, chunk := range manifest.Chunks {
+		if chunk.Checksum != "352441c2" {
+			t.Errorf("chunk %d checksum = %q, want the crc32", i, chunk.Checksum)
+		}
+	}
+
+	// The algorithm is saved with the manifest, so a resume uses it too.
+	saved, err := srv.loadManifest("/local/out.bin.transfer")
+	if err != nil {
+		t.Fatalf("loadManifest: %v", err)
+	}
+	if saved.checksumAlgo() != chunkChecksumCRC32 {
+		t.Errorf("saved checksum_algo = %q, want crc32", saved.ChecksumAlgo)
+	}
+}
+
+func TestTransferManifest_ChecksumAlgoDefault(t *testing.T) {
+	// Manifests from before checksum_algo used sha256.
+	if got := (&TransferManifest{}).checksumAlgo(); got != chunkChecksumSHA256 {
+		t.Errorf("checksumAlgo() = %q, want sha256", got)
+	}
+}
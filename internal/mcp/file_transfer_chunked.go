@@ -2,11 +2,11 @@ package mcp
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -41,10 +41,11 @@ func shellFileGetChunkedTool() mcp.Tool {
 For files larger than a few MB, this tool provides:
 - Chunked transfer (configurable chunk size)
 - Resume capability if transfer is interrupted
-- Per-chunk checksum verification
+- Per-chunk checksums (checksum_algo)
 - Progress tracking via manifest file
 
-The manifest file (.transfer) tracks progress and enables resume.
+The manifest file (.transfer) tracks progress and enables resume. It is
+deleted once the transfer completes (transfer.cleanup_manifest_on_complete).
 Use shell_transfer_status to check progress, shell_transfer_resume to continue.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
@@ -59,8 +60,13 @@ Use shell_transfer_status to check progress, shell_transfer_resume to continue.`
 			mcp.Description("Local path to save the file"),
 		),
 		mcp.WithNumber("chunk_size",
-			mcp.Description("Chunk size in bytes (default: 1MB, max: 10MB)"),
+			mcp.Description("Chunk size in bytes (default: the session's shell_net_probe recommendation, else 1MB; max: 10MB)"),
 		),
+		mcp.WithString("checksum_algo",
+			mcp.Description("Per-chunk checksum: sha256 (default), crc32 or xxhash (corruption detection only, faster on large files) or none. Kept in the manifest for resume"),
+			mcp.Enum(chunkChecksumSHA256, chunkChecksumCRC32, chunkChecksumXXHash, chunkChecksumNone),
+		),
+		additiveTool(),
 	)
 }
 
@@ -71,10 +77,11 @@ func shellFilePutChunkedTool() mcp.Tool {
 For files larger than a few MB, this tool provides:
 - Chunked transfer (configurable chunk size)
 - Resume capability if transfer is interrupted
-- Per-chunk checksum verification
+- Per-chunk checksums (checksum_algo)
 - Progress tracking via manifest file
 
-The manifest file (.transfer) tracks progress and enables resume.
+The manifest file (.transfer) tracks progress and enables resume. It is
+deleted once the transfer completes (transfer.cleanup_manifest_on_complete).
 Use shell_transfer_status to check progress, shell_transfer_resume to continue.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
@@ -89,8 +96,13 @@ Use shell_transfer_status to check progress, shell_transfer_resume to continue.`
 			mcp.Description("Destination path on the remote server"),
 		),
 		mcp.WithNumber("chunk_size",
-			mcp.Description("Chunk size in bytes (default: 1MB, max: 10MB)"),
+			mcp.Description("Chunk size in bytes (default: the session's shell_net_probe recommendation, else 1MB; max: 10MB)"),
+		),
+		mcp.WithString("checksum_algo",
+			mcp.Description("Per-chunk checksum: sha256 (default), crc32 or xxhash (corruption detection only, faster on large files) or none. Kept in the manifest for resume"),
+			mcp.Enum(chunkChecksumSHA256, chunkChecksumCRC32, chunkChecksumXXHash, chunkChecksumNone),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -102,11 +114,15 @@ Returns progress information including:
 - Total chunks and completed chunks
 - Bytes transferred
 - Estimated time remaining
-- Transfer rate`),
+- Transfer rate
+
+A transfer waiting for a free slot (transfer.max_concurrent_transfers)
+reports status "queued" with its queue_position. Without manifest_path,
+lists all running and queued chunked and directory transfers.`),
 		mcp.WithString("manifest_path",
-			mcp.Required(),
-			mcp.Description("Path to the .transfer manifest file"),
+			mcp.Description("Path to the .transfer manifest file (omit to list running and queued transfers)"),
 		),
+		readOnlyTool(),
 	)
 }
 
@@ -124,6 +140,7 @@ Verifies completed chunks and resumes from the first incomplete chunk.`),
 			mcp.Required(),
 			mcp.Description("Path to the .transfer manifest file"),
 		),
+		additiveTool(),
 	)
 }
 
@@ -137,6 +154,7 @@ type TransferManifest struct {
 	ChunkSize      int         `json:"chunk_size"`
 	TotalChunks    int         `json:"total_chunks"`
 	FileChecksum   string      `json:"file_checksum,omitempty"`
+	ChecksumAlgo   string      `json:"checksum_algo,omitempty"`
 	Chunks         []ChunkInfo `json:"chunks"`
 	StartedAt      time.Time   `json:"started_at"`
 	LastUpdatedAt  time.Time   `json:"last_updated_at"`
@@ -159,6 +177,7 @@ type ChunkInfo struct {
 type ChunkedTransferResult struct {
 	Status           string  `json:"status"`
 	ManifestPath     string  `json:"manifest_path"`
+	ManifestRetained *bool   `json:"manifest_retained,omitempty"` // false once a completed transfer's manifest is removed
 	ChunksCompleted  int     `json:"chunks_completed"`
 	TotalChunks      int     `json:"total_chunks"`
 	BytesTransferred int64   `json:"bytes_transferred"`
@@ -166,6 +185,7 @@ type ChunkedTransferResult struct {
 	Progress         float64 `json:"progress_percent"`
 	BytesPerSecond   int64   `json:"bytes_per_second,omitempty"`
 	DurationMs       int64   `json:"duration_ms,omitempty"`
+	QueuePosition    int     `json:"queue_position,omitempty"`
 	Error            string  `json:"error,omitempty"`
 }
 
@@ -173,7 +193,11 @@ func (s *Server) handleShellFileGetChunked(ctx context.Context, req mcp.CallTool
 	sessionID := mcp.ParseString(req, "session_id", "")
 	remotePath := mcp.ParseString(req, "remote_path", "")
 	localPath := mcp.ParseString(req, "local_path", "")
-	chunkSize := mcp.ParseInt(req, "chunk_size", DefaultChunkSize)
+	chunkSize := mcp.ParseInt(req, "chunk_size", s.defaultChunkSize(sessionID))
+	checksumAlgo, errResult := parseChunkChecksumAlgo(req)
+	if errResult != nil {
+		return errResult, nil
+	}
 
 	if sessionID == "" {
 		return mcp.NewToolResultError(errSessionIDRequired), nil
@@ -196,6 +220,9 @@ func (s *Server) handleShellFileGetChunked(ctx context.Context, req mcp.CallTool
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	if !sess.IsSSH() {
 		return mcp.NewToolResultError("chunked transfer is only supported for SSH sessions"), nil
@@ -209,16 +236,37 @@ func (s *Server) handleShellFileGetChunked(ctx context.Context, req mcp.CallTool
 		slog.String("remote_path", resolvedPath),
 		slog.String("local_path", localPath),
 		slog.Int("chunk_size", chunkSize),
+		slog.String("checksum_algo", checksumAlgo),
 	)
 
-	return s.performChunkedGet(sess, resolvedPath, localPath, manifestPath, chunkSize)
+	release, errResult := s.acquireTransferSlot(ctx, TransferSlot{
+		Tool:         "shell_file_get_chunked",
+		SessionID:    sessionID,
+		Source:       resolvedPath,
+		Destination:  localPath,
+		ManifestPath: manifestPath,
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	return s.performChunkedGet(sess, resolvedPath, localPath, manifestPath, chunkSize, checksumAlgo)
 }
 
 func (s *Server) handleShellFilePutChunked(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_put_chunked"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	localPath := mcp.ParseString(req, "local_path", "")
 	remotePath := mcp.ParseString(req, "remote_path", "")
-	chunkSize := mcp.ParseInt(req, "chunk_size", DefaultChunkSize)
+	chunkSize := mcp.ParseInt(req, "chunk_size", s.defaultChunkSize(sessionID))
+	checksumAlgo, errResult := parseChunkChecksumAlgo(req)
+	if errResult != nil {
+		return errResult, nil
+	}
 
 	if sessionID == "" {
 		return mcp.NewToolResultError(errSessionIDRequired), nil
@@ -241,6 +289,9 @@ func (s *Server) handleShellFilePutChunked(ctx context.Context, req mcp.CallTool
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	if !sess.IsSSH() {
 		return mcp.NewToolResultError("chunked transfer is only supported for SSH sessions"), nil
@@ -254,16 +305,37 @@ func (s *Server) handleShellFilePutChunked(ctx context.Context, req mcp.CallTool
 		slog.String("local_path", localPath),
 		slog.String("remote_path", resolvedRemote),
 		slog.Int("chunk_size", chunkSize),
+		slog.String("checksum_algo", checksumAlgo),
 	)
 
-	return s.performChunkedPut(sess, localPath, resolvedRemote, manifestPath, chunkSize)
+	release, errResult := s.acquireTransferSlot(ctx, TransferSlot{
+		Tool:         "shell_file_put_chunked",
+		SessionID:    sessionID,
+		Source:       localPath,
+		Destination:  resolvedRemote,
+		ManifestPath: manifestPath,
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	return s.performChunkedPut(sess, localPath, resolvedRemote, manifestPath, chunkSize, checksumAlgo)
 }
 
 func (s *Server) handleShellTransferStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	manifestPath := mcp.ParseString(req, "manifest_path", "")
 
 	if manifestPath == "" {
-		return mcp.NewToolResultError("manifest_path is required"), nil
+		return s.transferQueueStatus()
+	}
+
+	if slot, ok := s.transferLimiter.queuedByManifest(manifestPath); ok {
+		return jsonResult(ChunkedTransferResult{
+			Status:        "queued",
+			ManifestPath:  manifestPath,
+			QueuePosition: slot.QueuePosition,
+		})
 	}
 
 	manifest, err := s.loadManifest(manifestPath)
@@ -316,11 +388,19 @@ func (s *Server) handleShellTransferResume(ctx context.Context, req mcp.CallTool
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	manifest, err := s.loadManifest(manifestPath)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("load manifest: %v", err)), nil
 	}
+	if manifest.Direction == "put" {
+		if errResult := s.checkReadOnly("shell_transfer_resume (put)"); errResult != nil {
+			return errResult, nil
+		}
+	}
 
 	slog.Info("resuming chunked transfer",
 		slog.String("session_id", sessionID),
@@ -328,13 +408,29 @@ func (s *Server) handleShellTransferResume(ctx context.Context, req mcp.CallTool
 		slog.String("direction", manifest.Direction),
 	)
 
+	slot := TransferSlot{
+		Tool:         "shell_transfer_resume",
+		SessionID:    sessionID,
+		Source:       manifest.RemotePath,
+		Destination:  manifest.LocalPath,
+		ManifestPath: manifestPath,
+	}
+	if manifest.Direction == "put" {
+		slot.Source, slot.Destination = manifest.LocalPath, manifest.RemotePath
+	}
+	release, errResult := s.acquireTransferSlot(ctx, slot)
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
 	if manifest.Direction == "get" {
 		return s.resumeChunkedGet(sess, manifest, manifestPath)
 	}
 	return s.resumeChunkedPut(sess, manifest, manifestPath)
 }
 
-func (s *Server) performChunkedGet(sess *session.Session, remotePath, localPath, manifestPath string, chunkSize int) (*mcp.CallToolResult, error) {
+func (s *Server) performChunkedGet(sess *session.Session, remotePath, localPath, manifestPath string, chunkSize int, checksumAlgo string) (*mcp.CallToolResult, error) {
 	startTime := s.clock.Now()
 
 	sftpClient, err := sess.SFTPClient()
@@ -360,6 +456,7 @@ func (s *Server) performChunkedGet(sess *session.Session, remotePath, localPath,
 		TotalSize:     totalSize,
 		ChunkSize:     chunkSize,
 		TotalChunks:   totalChunks,
+		ChecksumAlgo:  checksumAlgo,
 		StartedAt:     startTime,
 		LastUpdatedAt: startTime,
 		SessionID:     sess.ID,
@@ -414,6 +511,9 @@ func (s *Server) transferChunksGet(localFile ports.FileHandle, remoteFile io.Rea
 		if manifest.Chunks[i].Completed {
 			continue
 		}
+		if s.transfersAborted() {
+			return s.interruptedTransfer(manifest, manifestPath)
+		}
 
 		chunk := &manifest.Chunks[i]
 
@@ -430,9 +530,7 @@ func (s *Server) transferChunksGet(localFile ports.FileHandle, remoteFile io.Rea
 			return mcp.NewToolResultError(fmt.Sprintf("read chunk %d: %v", i, err)), nil
 		}
 
-		// Calculate chunk checksum
-		hash := sha256.Sum256(buf[:n])
-		chunk.Checksum = hex.EncodeToString(hash[:])
+		chunk.Checksum = chunkChecksum(manifest.checksumAlgo(), buf[:n])
 
 		// Write to local file
 		if _, err := localFile.WriteAt(buf[:n], chunk.Offset); err != nil {
@@ -450,31 +548,10 @@ func (s *Server) transferChunksGet(localFile ports.FileHandle, remoteFile io.Rea
 		}
 	}
 
-	// Calculate final stats
-	duration := s.clock.Now().Sub(startTime)
-	if duration.Seconds() > 0 {
-		manifest.BytesPerSecond = int64(float64(manifest.BytesSent) / duration.Seconds())
-	}
-	now := s.clock.Now()
-	manifest.CompletedAt = &now
-	s.saveManifest(manifest, manifestPath)
-
-	result := ChunkedTransferResult{
-		Status:           "completed",
-		ManifestPath:     manifestPath,
-		ChunksCompleted:  manifest.TotalChunks,
-		TotalChunks:      manifest.TotalChunks,
-		BytesTransferred: manifest.BytesSent,
-		TotalBytes:       manifest.TotalSize,
-		Progress:         100,
-		BytesPerSecond:   manifest.BytesPerSecond,
-		DurationMs:       duration.Milliseconds(),
-	}
-
-	return jsonResult(result)
+	return jsonResult(s.finalizeChunkedTransfer(manifest, manifestPath, startTime))
 }
 
-func (s *Server) performChunkedPut(sess *session.Session, localPath, remotePath, manifestPath string, chunkSize int) (*mcp.CallToolResult, error) {
+func (s *Server) performChunkedPut(sess *session.Session, localPath, remotePath, manifestPath string, chunkSize int, checksumAlgo string) (*mcp.CallToolResult, error) {
 	startTime := s.clock.Now()
 
 	sftpClient, err := sess.SFTPClient()
@@ -500,6 +577,7 @@ func (s *Server) performChunkedPut(sess *session.Session, localPath, remotePath,
 		TotalSize:     totalSize,
 		ChunkSize:     chunkSize,
 		TotalChunks:   totalChunks,
+		ChecksumAlgo:  checksumAlgo,
 		StartedAt:     startTime,
 		LastUpdatedAt: startTime,
 		SessionID:     sess.ID,
@@ -539,6 +617,9 @@ func (s *Server) performChunkedPut(sess *session.Session, localPath, remotePath,
 		return mcp.NewToolResultError(fmt.Sprintf("create remote file: %v", err)), nil
 	}
 	defer remoteFile.Close()
+	if err := remoteFile.Chmod(s.config.Transfer.FileMode()); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("chmod remote file: %v", err)), nil
+	}
 
 	// Transfer chunks
 	return s.transferChunksPut(localFile, remoteFile, manifest, manifestPath, startTime)
@@ -556,8 +637,7 @@ func (s *Server) uploadChunk(localFile ports.FileHandle, remoteFile io.WriteSeek
 		return fmt.Errorf("read chunk %d: %v", chunkIndex, err)
 	}
 
-	hash := sha256.Sum256(buf[:n])
-	chunk.Checksum = hex.EncodeToString(hash[:])
+	chunk.Checksum = chunkChecksum(manifest.checksumAlgo(), buf[:n])
 
 	if _, err := remoteFile.Seek(chunk.Offset, io.SeekStart); err != nil {
 		s.saveManifest(manifest, manifestPath)
@@ -575,7 +655,8 @@ func (s *Server) uploadChunk(localFile ports.FileHandle, remoteFile io.WriteSeek
 	return nil
 }
 
-// finalizeChunkedTransfer calculates final stats and saves the manifest.
+// finalizeChunkedTransfer calculates final stats and saves the manifest,
+// then removes it if transfer.cleanup_manifest_on_complete is set.
 func (s *Server) finalizeChunkedTransfer(manifest *TransferManifest, manifestPath string, startTime time.Time) *ChunkedTransferResult {
 	duration := s.clock.Now().Sub(startTime)
 	if duration.Seconds() > 0 {
@@ -584,10 +665,12 @@ func (s *Server) finalizeChunkedTransfer(manifest *TransferManifest, manifestPat
 	now := s.clock.Now()
 	manifest.CompletedAt = &now
 	s.saveManifest(manifest, manifestPath)
+	retained := s.cleanupManifest(manifestPath)
 
 	return &ChunkedTransferResult{
 		Status:           "completed",
 		ManifestPath:     manifestPath,
+		ManifestRetained: &retained,
 		ChunksCompleted:  manifest.TotalChunks,
 		TotalChunks:      manifest.TotalChunks,
 		BytesTransferred: manifest.BytesSent,
@@ -598,6 +681,33 @@ func (s *Server) finalizeChunkedTransfer(manifest *TransferManifest, manifestPat
 	}
 }
 
+// cleanupManifest removes the manifest of a completed transfer unless
+// transfer.cleanup_manifest_on_complete is off, and reports whether it is
+// kept. With auditing on, the audit record is built from the manifest, so
+// it is left for auditTransfers to remove once the record is written.
+func (s *Server) cleanupManifest(manifestPath string) bool {
+	if !s.config.Transfer.CleanupManifestOnComplete {
+		return true
+	}
+	if s.config.Transfer.AuditDir != "" {
+		return false
+	}
+	return !s.removeManifest(manifestPath)
+}
+
+// removeManifest deletes a transfer manifest and reports whether it is
+// gone.
+func (s *Server) removeManifest(manifestPath string) bool {
+	if err := s.fs.Remove(manifestPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to remove transfer manifest",
+			slog.String("manifest", manifestPath),
+			slog.String("error", err.Error()),
+		)
+		return false
+	}
+	return true
+}
+
 func (s *Server) transferChunksPut(localFile ports.FileHandle, remoteFile io.WriteSeeker, manifest *TransferManifest, manifestPath string, startTime time.Time) (*mcp.CallToolResult, error) {
 	buf := make([]byte, manifest.ChunkSize)
 
@@ -605,6 +715,9 @@ func (s *Server) transferChunksPut(localFile ports.FileHandle, remoteFile io.Wri
 		if manifest.Chunks[i].Completed {
 			continue
 		}
+		if s.transfersAborted() {
+			return s.interruptedTransfer(manifest, manifestPath)
+		}
 
 		if err := s.uploadChunk(localFile, remoteFile, &manifest.Chunks[i], i, buf, manifest, manifestPath); err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
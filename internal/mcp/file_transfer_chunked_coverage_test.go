@@ -274,7 +274,7 @@ func TestChunked_PutChunked_SessionNotFound(t *testing.T) {
 
 // ==================== handleShellTransferStatus ====================
 
-func TestChunked_TransferStatus_MissingManifestPath(t *testing.T) {
+func TestChunked_TransferStatus_MissingManifestPathListsQueue(t *testing.T) {
 	sm := fakesessionmgr.New()
 	srv := newTestServer(sm)
 
@@ -284,12 +284,15 @@ func TestChunked_TransferStatus_MissingManifestPath(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if !result.IsError {
-		t.Fatal("expected error for missing manifest_path")
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
 	}
-	text := resultText(result)
-	if !strings.Contains(text, "manifest_path is required") {
-		t.Errorf("error should mention manifest_path, got: %s", text)
+	m := resultJSON(t, result)
+	if _, ok := m["running"].([]any); !ok {
+		t.Errorf("expected running list, got: %v", m)
+	}
+	if _, ok := m["queued"].([]any); !ok {
+		t.Errorf("expected queued list, got: %v", m)
 	}
 }
 
@@ -1526,6 +1529,7 @@ func TestChunked_TransferChunksGet_ManifestSavedPeriodically(t *testing.T) {
 	ffs := fakefs.New()
 	clk := fakeclock.New(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
 	cfg := config.DefaultConfig()
+	cfg.Transfer.CleanupManifestOnComplete = false // the saved manifest is checked below
 	srv := NewServer(cfg, WithFileSystem(ffs), WithClock(clk))
 
 	// Create data with 15 chunks to test periodic save (every 10 chunks)
@@ -2135,6 +2139,7 @@ func TestChunked_TransferChunksPut_ManifestSavedPeriodically(t *testing.T) {
 	ffs := fakefs.New()
 	clk := fakeclock.New(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
 	cfg := config.DefaultConfig()
+	cfg.Transfer.CleanupManifestOnComplete = false // the saved manifest is checked below
 	srv := NewServer(cfg, WithFileSystem(ffs), WithClock(clk))
 
 	// 15 chunks to trigger periodic save every 10 chunks
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxDecompressedSize bounds decompress output saved to local_path, so a
+// small archive cannot expand without limit in memory.
+const maxDecompressedSize = 512 * 1024 * 1024
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// compressionFormat identifies gzip or bzip2 data by its magic bytes. The
+// file extension is not trusted: a .gz file that isn't gzip is reported as
+// uncompressed.
+func compressionFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return "gzip"
+	case bytes.HasPrefix(data, bzip2Magic):
+		return "bzip2"
+	}
+	return ""
+}
+
+// decompressedName strips a compression extension, so content type
+// decisions (e.g. compress) apply to the inner file: app.log.gz -> app.log.
+func decompressedName(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip", ".bz2", ".bzip2":
+		return strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return path
+}
+
+// validateDecompress checks the options decompress excludes.
+func validateDecompress(opts FileGetOptions) *mcp.CallToolResult {
+	switch {
+	case !opts.Decompress:
+		return nil
+	case opts.Format != "":
+		return mcp.NewToolResultError("decompress cannot be combined with format")
+	case opts.wantsLineRange():
+		return mcp.NewToolResultError("decompress cannot be combined with start_line/end_line")
+	}
+	return nil
+}
+
+// applyDecompress replaces data with its decompressed form when decompress
+// was requested, recording the compressed size in result.OriginalSize.
+// Inline content is held to maxContentSize after decompression.
+func applyDecompress(data []byte, path string, opts FileGetOptions, result *FileGetResult) ([]byte, *mcp.CallToolResult) {
+	if !opts.Decompress {
+		return data, nil
+	}
+
+	limit := int64(maxDecompressedSize)
+	if opts.LocalPath == "" {
+		limit = maxContentSize
+	}
+
+	var decompressed []byte
+	var err error
+	format := compressionFormat(data)
+	switch format {
+	case "gzip":
+		// Like decompressData, but bounded: a 1MB download can inflate to
+		// gigabytes.
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			decompressed, err = readLimited(zr, limit, opts)
+			zr.Close()
+		}
+	case "bzip2":
+		decompressed, err = readLimited(bzip2.NewReader(bytes.NewReader(data)), limit, opts)
+	default:
+		return nil, mcp.NewToolResultError(fmt.Sprintf("%s is not gzip or bzip2 compressed; retry without decompress", path))
+	}
+	if err != nil {
+		return nil, mcp.NewToolResultError(fmt.Sprintf("decompress %s: %v", format, err))
+	}
+
+	result.OriginalSize = int64(len(data))
+	result.Size = int64(len(decompressed))
+	result.Decompressed = format
+	return decompressed, nil
+}
+
+// readLimited reads r to EOF, failing if it yields more than limit bytes.
+func readLimited(r io.Reader, limit int64, opts FileGetOptions) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errDecompressedTooLarge(limit, opts)
+	}
+	return data, nil
+}
+
+func errDecompressedTooLarge(limit int64, opts FileGetOptions) error {
+	if opts.LocalPath == "" {
+		return fmt.Errorf("decompressed size exceeds limit (%d bytes), please specify local_path", limit)
+	}
+	return fmt.Errorf("decompressed size exceeds limit (%d bytes)", limit)
+}
@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// bzip2Hello is `printf 'hello bzip2\n' | bzip2 -9`.
+var bzip2Hello = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xab, 0x6b,
+	0xa1, 0xf1, 0x00, 0x00, 0x02, 0xd9, 0x80, 0x00, 0x10, 0x40, 0x00, 0x10,
+	0x00, 0x12, 0x64, 0xc0, 0x10, 0x20, 0x00, 0x31, 0x00, 0xd3, 0x4d, 0x04,
+	0x00, 0x1e, 0xa3, 0xef, 0x4e, 0x51, 0xa2, 0x07, 0x8b, 0xb9, 0x22, 0x9c,
+	0x28, 0x48, 0x55, 0xb5, 0xd0, 0xf8, 0x80,
+}
+
+func newDecompressTestServer(t *testing.T) (*Server, *fakefs.FS) {
+	t.Helper()
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_gz"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func TestHandleShellFileGet_DecompressGzip(t *testing.T) {
+	srv, ffs := newDecompressTestServer(t)
+	text := strings.Repeat("GET /index.html 200\n", 100)
+	gz, err := compressData([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ffs.AddFile("/var/log/access.log.gz", gz, 0644)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_gz",
+		"remote_path": "/var/log/access.log.gz",
+		"decompress":  true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["content"] != text {
+		t.Errorf("content = %q, want decompressed text", m["content"])
+	}
+	if m["decompressed"] != "gzip" {
+		t.Errorf("decompressed = %v, want gzip", m["decompressed"])
+	}
+	if m["size"] != float64(len(text)) {
+		t.Errorf("size = %v, want %d", m["size"], len(text))
+	}
+	if m["original_size"] != float64(len(gz)) {
+		t.Errorf("original_size = %v, want %d", m["original_size"], len(gz))
+	}
+	sum := sha256.Sum256([]byte(text))
+	if m["checksum"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum should cover decompressed content")
+	}
+}
+
+func TestHandleShellFileGet_DecompressBzip2ToLocalPath(t *testing.T) {
+	srv, ffs := newDecompressTestServer(t)
+	ffs.AddFile("/data/hello.bz2", bzip2Hello, 0644)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_gz",
+		"remote_path": "/data/hello.bz2",
+		"local_path":  "/out/hello.txt",
+		"decompress":  true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	got, err := ffs.ReadFile("/out/hello.txt")
+	if err != nil {
+		t.Fatalf("read local copy: %v", err)
+	}
+	if string(got) != "hello bzip2\n" {
+		t.Errorf("local copy = %q, want decompressed text", got)
+	}
+	if m := resultJSON(t, result); m["decompressed"] != "bzip2" {
+		t.Errorf("decompressed = %v, want bzip2", m["decompressed"])
+	}
+}
+
+func TestHandleShellFileGet_DecompressNotCompressed(t *testing.T) {
+	srv, ffs := newDecompressTestServer(t)
+	// The extension says gzip but the content is plain text.
+	ffs.AddFile("/data/fake.gz", []byte("plain text"), 0644)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_gz",
+		"remote_path": "/data/fake.gz",
+		"decompress":  true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "not gzip or bzip2 compressed") {
+		t.Errorf("expected not-compressed error, got: %s", resultText(result))
+	}
+}
+
+func TestHandleShellFileGet_DecompressedTooLarge(t *testing.T) {
+	srv, ffs := newDecompressTestServer(t)
+	gz, err := compressData(make([]byte, maxContentSize+1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ffs.AddFile("/data/zeros.gz", gz, 0644)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_gz",
+		"remote_path": "/data/zeros.gz",
+		"decompress":  true,
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "local_path") {
+		t.Errorf("expected size limit error suggesting local_path, got: %s", resultText(result))
+	}
+}
+
+func TestHandleShellFileGet_DecompressConflicts(t *testing.T) {
+	srv, _ := newDecompressTestServer(t)
+	for _, extra := range []map[string]any{
+		{"format": "tar"},
+		{"start_line": float64(1)},
+	} {
+		args := map[string]any{"session_id": "sess_gz", "remote_path": "/data/x.gz", "decompress": true}
+		for k, v := range extra {
+			args[k] = v
+		}
+		result, _ := srv.handleShellFileGet(context.Background(), makeRequest(args))
+		if !result.IsError || !strings.Contains(resultText(result), "decompress cannot be combined") {
+			t.Errorf("%v: expected conflict error, got: %s", extra, resultText(result))
+		}
+	}
+}
+
+func TestDecompressedName(t *testing.T) {
+	tests := map[string]string{
+		"/var/log/app.log.gz": "/var/log/app.log",
+		"dump.sql.bz2":        "dump.sql",
+		"notes.txt":           "notes.txt",
+	}
+	for in, want := range tests {
+		if got := decompressedName(in); got != want {
+			t.Errorf("decompressedName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
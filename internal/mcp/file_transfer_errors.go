@@ -0,0 +1,71 @@
+package mcp
+
+import "strings"
+
+// maxDetailedTransferErrors caps DirTransferResult.Errors; the rest are
+// only counted in ErrorSummary and ErrorsOmitted.
+const maxDetailedTransferErrors = 100
+
+// Categories of DirTransferResult.ErrorSummary.
+const (
+	transferErrPermission = "permission_denied"
+	transferErrNotFound   = "not_found"
+	transferErrExists     = "already_exists"
+	transferErrNoSpace    = "no_space"
+	transferErrWrongType  = "wrong_type"
+	transferErrConnection = "connection"
+	transferErrOther      = "other"
+)
+
+// transferErrorCategories maps error message fragments to categories, most
+// specific first. Messages come from os, SFTP and local filesystem errors,
+// so they are matched as text.
+var transferErrorCategories = []struct {
+	fragment string
+	category string
+}{
+	{"permission denied", transferErrPermission},
+	{"operation not permitted", transferErrPermission},
+	{"no such file", transferErrNotFound},
+	{"does not exist", transferErrNotFound},
+	{"not exist", transferErrNotFound},
+	{"file exists", transferErrExists},
+	{"already exists", transferErrExists},
+	{"no space left", transferErrNoSpace},
+	{"quota exceeded", transferErrNoSpace},
+	{"is a directory", transferErrWrongType},
+	{"not a directory", transferErrWrongType},
+	{"connection lost", transferErrConnection},
+	{"connection reset", transferErrConnection},
+	{"broken pipe", transferErrConnection},
+	{"use of closed", transferErrConnection},
+	{"unexpected eof", transferErrConnection},
+}
+
+// transferErrorCategory classifies a transfer error message.
+func transferErrorCategory(msg string) string {
+	msg = strings.ToLower(msg)
+	for _, c := range transferErrorCategories {
+		if strings.Contains(msg, c.fragment) {
+			return c.category
+		}
+	}
+	return transferErrOther
+}
+
+// summarizeErrors fills in ErrorSummary and truncates Errors to
+// maxDetailedTransferErrors, so a transfer where hundreds of files fail the
+// same way reads as one line per cause.
+func (r *DirTransferResult) summarizeErrors() {
+	if len(r.Errors) == 0 {
+		return
+	}
+	r.ErrorSummary = make(map[string]int)
+	for _, e := range r.Errors {
+		r.ErrorSummary[transferErrorCategory(e.Error)]++
+	}
+	if len(r.Errors) > maxDetailedTransferErrors {
+		r.ErrorsOmitted = len(r.Errors) - maxDetailedTransferErrors
+		r.Errors = r.Errors[:maxDetailedTransferErrors]
+	}
+}
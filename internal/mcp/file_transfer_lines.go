@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// lineReaderSize is the buffer used when scanning a file for a line range.
+const lineReaderSize = 64 * 1024
+
+var errLineRangeTooLarge = fmt.Errorf("selected lines exceed limit (%d bytes), narrow the line range", maxContentSize)
+
+// wantsLineRange reports whether a line slice was requested.
+func (o FileGetOptions) wantsLineRange() bool {
+	return o.StartLine > 0 || o.EndLine > 0
+}
+
+// validateLineRange checks start_line/end_line and the options they exclude.
+func validateLineRange(opts FileGetOptions) *mcp.CallToolResult {
+	switch {
+	case opts.StartLine < 0 || opts.EndLine < 0:
+		return mcp.NewToolResultError("start_line and end_line must be positive")
+	case !opts.wantsLineRange():
+		return nil
+	case opts.EndLine > 0 && opts.StartLine > opts.EndLine:
+		return mcp.NewToolResultError(fmt.Sprintf("start_line (%d) is after end_line (%d)", opts.StartLine, opts.EndLine))
+	case opts.Encoding == "base64":
+		return mcp.NewToolResultError("start_line/end_line are line-oriented and cannot be combined with base64 encoding")
+	case opts.Format != "":
+		return mcp.NewToolResultError("start_line/end_line cannot be combined with format")
+	}
+	return nil
+}
+
+// readLineRange streams r and returns lines start..end (1-based, inclusive;
+// end 0 reads through EOF) together with the file's total line count. Only
+// the selected lines are kept in memory.
+func readLineRange(r io.Reader, start, end int) ([]byte, int, error) {
+	if start < 1 {
+		start = 1
+	}
+
+	br := bufio.NewReaderSize(r, lineReaderSize)
+	var out bytes.Buffer
+	line := 1
+	partial := false // last chunk did not end with a newline
+
+	for {
+		chunk, err := br.ReadSlice('\n')
+		if len(chunk) > 0 {
+			if line >= start && (end == 0 || line <= end) {
+				if out.Len()+len(chunk) > maxContentSize {
+					return nil, 0, errLineRangeTooLarge
+				}
+				out.Write(chunk)
+			}
+			partial = chunk[len(chunk)-1] != '\n'
+			if !partial {
+				line++
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	total := line - 1
+	if partial {
+		total++
+	}
+	return out.Bytes(), total, nil
+}
+
+// getFileLines returns a line slice of the file read from r.
+func (s *Server) getFileLines(r io.Reader, path string, info os.FileInfo, opts FileGetOptions) (*mcp.CallToolResult, error) {
+	data, total, err := readLineRange(r, opts.StartLine, opts.EndLine)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("read lines: %v", err)), nil
+	}
+
+	start := max(opts.StartLine, 1)
+	if start > total {
+		return mcp.NewToolResultError(fmt.Sprintf("start_line %d is beyond end of file (%d lines)", start, total)), nil
+	}
+	end := total
+	if opts.EndLine > 0 && opts.EndLine < total {
+		end = opts.EndLine
+	}
+
+	result := FileGetResult{
+		Status:     "completed",
+		RemotePath: path,
+		Size:       info.Size(),
+		Mode:       fmt.Sprintf("%04o", info.Mode().Perm()),
+		ModTime:    info.ModTime().Unix(),
+		StartLine:  start,
+		EndLine:    end,
+		TotalLines: total,
+	}
+
+	if errResult := processFileChecksum(data, opts, &result); errResult != nil {
+		return errResult, nil
+	}
+
+	if opts.LocalPath != "" {
+		if errResult := s.copyToLocalPath(data, opts.LocalPath, info, opts.Preserve); errResult != nil {
+			return errResult, nil
+		}
+		result.LocalPath = opts.LocalPath
+		return jsonResult(result)
+	}
+
+	setContentWithEncoding(data, path, opts, &result)
+	return jsonResult(result)
+}
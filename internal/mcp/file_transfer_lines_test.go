@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestReadLineRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		start, end int
+		want       string
+		wantTotal  int
+	}{
+		{"middle", "a\nb\nc\nd\n", 2, 3, "b\nc\n", 4},
+		{"to EOF", "a\nb\nc\n", 2, 0, "b\nc\n", 3},
+		{"no trailing newline", "a\nb\nc", 3, 3, "c", 3},
+		{"end past EOF", "a\nb\n", 1, 10, "a\nb\n", 2},
+		{"start past EOF", "a\nb\n", 5, 6, "", 2},
+		{"empty file", "", 1, 1, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total, err := readLineRange(strings.NewReader(tt.input), tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("readLineRange error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestReadLineRange_LongLines(t *testing.T) {
+	long := strings.Repeat("x", lineReaderSize*2+10)
+	input := long + "\nshort\n" + long + "\n"
+
+	got, total, err := readLineRange(strings.NewReader(input), 2, 2)
+	if err != nil {
+		t.Fatalf("readLineRange error: %v", err)
+	}
+	if string(got) != "short\n" || total != 3 {
+		t.Errorf("got %q (total %d), want %q (total 3)", got, total, "short\n")
+	}
+}
+
+func TestReadLineRange_TooLarge(t *testing.T) {
+	input := strings.Repeat(strings.Repeat("y", 1023)+"\n", 1100)
+	if _, _, err := readLineRange(strings.NewReader(input), 1, 0); err != errLineRangeTooLarge {
+		t.Errorf("error = %v, want errLineRangeTooLarge", err)
+	}
+}
+
+func newLineTestServer(t *testing.T) *Server {
+	t.Helper()
+	var b strings.Builder
+	for i := 1; i <= 20000; i++ {
+		fmt.Fprintf(&b, "log line %d\n", i)
+	}
+	ffs := fakefs.New()
+	ffs.AddFile("/var/log/app.log", []byte(b.String()), 0644)
+
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_lines"))
+	return newTestServerWithFS(sm, ffs)
+}
+
+func TestHandleShellFileGet_LineRange(t *testing.T) {
+	srv := newLineTestServer(t)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_lines",
+		"remote_path": "/var/log/app.log",
+		"start_line":  float64(10000),
+		"end_line":    float64(10002),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["content"] != "log line 10000\nlog line 10001\nlog line 10002\n" {
+		t.Errorf("content = %q", m["content"])
+	}
+	if m["total_lines"] != float64(20000) {
+		t.Errorf("total_lines = %v, want 20000", m["total_lines"])
+	}
+	if m["start_line"] != float64(10000) || m["end_line"] != float64(10002) {
+		t.Errorf("range = %v-%v, want 10000-10002", m["start_line"], m["end_line"])
+	}
+	if m["encoding"] != "text" {
+		t.Errorf("encoding = %v, want text", m["encoding"])
+	}
+}
+
+func TestHandleShellFileGet_LineRangeErrors(t *testing.T) {
+	srv := newLineTestServer(t)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"base64", map[string]any{"start_line": float64(1), "encoding": "base64"}, "base64"},
+		{"reversed", map[string]any{"start_line": float64(5), "end_line": float64(2)}, "after end_line"},
+		{"negative", map[string]any{"start_line": float64(-1)}, "positive"},
+		{"beyond EOF", map[string]any{"start_line": float64(30000)}, "beyond end of file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]any{"session_id": "sess_lines", "remote_path": "/var/log/app.log"}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			result, err := srv.handleShellFileGet(context.Background(), makeRequest(args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
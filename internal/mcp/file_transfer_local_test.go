@@ -225,6 +225,34 @@ func TestLocal_HandleLocalFileGet_LargeFileNoLocalPath(t *testing.T) {
 	}
 }
 
+func TestLocal_HandleLocalFileGet_MaxInlineFileBytes(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/data.txt", []byte(strings.Repeat("x", 100)), 0644)
+	cfg := config.DefaultConfig()
+	cfg.Output.MaxInlineFileBytes = 64
+	srv := NewServer(cfg, WithFileSystem(ffs))
+
+	result, _ := srv.handleLocalFileGet("/data.txt", FileGetOptions{Encoding: "text"})
+	if !result.IsError || !strings.Contains(resultText(result), "file_too_large") {
+		t.Fatalf("error=%q, want file_too_large", resultText(result))
+	}
+	for _, want := range []string{"100 bytes", "limit of 64 bytes", "local_path", "shell_file_get_chunked"} {
+		if !strings.Contains(resultText(result), want) {
+			t.Errorf("error=%q, want %q", resultText(result), want)
+		}
+	}
+
+	// Copies to local_path and line ranges are not returned inline.
+	result, _ = srv.handleLocalFileGet("/data.txt", FileGetOptions{LocalPath: "/out/data.txt"})
+	if result.IsError {
+		t.Errorf("local_path copy failed: %s", resultText(result))
+	}
+	result, _ = srv.handleLocalFileGet("/data.txt", FileGetOptions{StartLine: 1, EndLine: 1})
+	if result.IsError {
+		t.Errorf("line range failed: %s", resultText(result))
+	}
+}
+
 func TestLocal_HandleLocalFileGet_LargeFileWithLocalPath(t *testing.T) {
 	ffs := fakefs.New()
 	largeData := make([]byte, maxContentSize+1)
@@ -739,6 +767,51 @@ func TestLocal_HandleLocalFilePut_InvalidMode(t *testing.T) {
 	}
 }
 
+func TestLocal_HandleLocalFilePut_Umask(t *testing.T) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_umask"))
+	cfg := config.DefaultConfig()
+	cfg.Transfer.Umask = "077"
+	srv := newTestServerWithConfig(sm, ffs, cfg)
+
+	put := func(path string, args map[string]any) {
+		t.Helper()
+		req := map[string]any{
+			"session_id":  "sess_umask",
+			"remote_path": path,
+			"content":     "secret",
+			"create_dirs": true,
+		}
+		for k, v := range args {
+			req[k] = v
+		}
+		result, err := srv.handleShellFilePut(context.Background(), makeRequest(req))
+		if err != nil || result.IsError {
+			t.Fatalf("put %s failed: %v %s", path, err, resultText(result))
+		}
+	}
+
+	put("/output/token.txt", nil)
+	info, err := ffs.Stat("/output/token.txt")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %04o, want 0600 from umask 077", info.Mode().Perm())
+	}
+
+	// An explicit mode overrides the umask.
+	put("/output/run.sh", map[string]any{"mode": "0755"})
+	info, err = ffs.Stat("/output/run.sh")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %04o, want the explicit 0755", info.Mode().Perm())
+	}
+}
+
 func TestLocal_HandleLocalFilePut_NoContentOrLocalPath(t *testing.T) {
 	ffs := fakefs.New()
 	sm := fakesessionmgr.New()
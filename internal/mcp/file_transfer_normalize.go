@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ContentNormalization reports what shell_file_put changed in the content
+// before writing it, when normalize_line_endings or strip_bom was set.
+type ContentNormalization struct {
+	BOMStripped   bool  `json:"bom_stripped"`
+	CRLFConverted int   `json:"crlf_converted"` // CRLF line endings rewritten as LF
+	OriginalSize  int64 `json:"original_size"`
+}
+
+// resolvePutContent resolves the content of an upload like
+// resolveFileContent, then applies opts.NormalizeLineEndings and
+// opts.StripBOM and records what they changed in opts.normalized.
+func (s *Server) resolvePutContent(opts *FilePutOptions) ([]byte, time.Time, *mcp.CallToolResult) {
+	data, sourceModTime, errResult := s.resolveFileContent(*opts)
+	if errResult != nil {
+		return nil, time.Time{}, errResult
+	}
+	data, opts.normalized = normalizeContent(data, *opts)
+	return data, sourceModTime, nil
+}
+
+// normalizeContent strips a leading UTF-8 BOM and rewrites CRLF line endings
+// as LF, as opts asks. Lone CRs are kept: outside Windows line endings they
+// are usually meant, as in progress output. It returns nil when opts asks
+// for neither.
+func normalizeContent(data []byte, opts FilePutOptions) ([]byte, *ContentNormalization) {
+	if !opts.NormalizeLineEndings && !opts.StripBOM {
+		return data, nil
+	}
+	n := &ContentNormalization{OriginalSize: int64(len(data))}
+	if opts.StripBOM && bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		n.BOMStripped = true
+	}
+	if opts.NormalizeLineEndings {
+		if n.CRLFConverted = bytes.Count(data, []byte("\r\n")); n.CRLFConverted > 0 {
+			data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		}
+	}
+	return data, n
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestNormalizeContent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts FilePutOptions
+		want string
+		norm *ContentNormalization
+	}{
+		{"off", "\xEF\xBB\xBFa\r\nb\r\n", FilePutOptions{}, "\xEF\xBB\xBFa\r\nb\r\n", nil},
+		{"bom", "\xEF\xBB\xBFa\r\n", FilePutOptions{StripBOM: true}, "a\r\n", &ContentNormalization{BOMStripped: true, OriginalSize: 6}},
+		{"crlf", "a\r\nb\r\nc", FilePutOptions{NormalizeLineEndings: true}, "a\nb\nc", &ContentNormalization{CRLFConverted: 2, OriginalSize: 7}},
+		{"both", "\xEF\xBB\xBFa\r\n", FilePutOptions{NormalizeLineEndings: true, StripBOM: true}, "a\n", &ContentNormalization{BOMStripped: true, CRLFConverted: 1, OriginalSize: 6}},
+		{"lone cr kept", "10%\r50%\r\n", FilePutOptions{NormalizeLineEndings: true}, "10%\r50%\n", &ContentNormalization{CRLFConverted: 1, OriginalSize: 9}},
+		{"bom not at start", "a\xEF\xBB\xBF", FilePutOptions{StripBOM: true}, "a\xEF\xBB\xBF", &ContentNormalization{OriginalSize: 4}},
+		{"already clean", "a\nb\n", FilePutOptions{NormalizeLineEndings: true, StripBOM: true}, "a\nb\n", &ContentNormalization{OriginalSize: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, norm := normalizeContent([]byte(tt.in), tt.opts)
+			if string(got) != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+			if (norm == nil) != (tt.norm == nil) || (norm != nil && *norm != *tt.norm) {
+				t.Errorf("normalization = %+v, want %+v", norm, tt.norm)
+			}
+		})
+	}
+}
+
+func TestFilePut_NormalizesContent(t *testing.T) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_norm"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, err := srv.handleShellFilePut(context.Background(), makeRequest(map[string]any{
+		"session_id":             "sess_norm",
+		"remote_path":            "/srv/deploy.sh",
+		"content":                "\uFEFF#!/bin/sh\r\necho ok\r\n",
+		"normalize_line_endings": true,
+		"strip_bom":              true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+
+	want := "#!/bin/sh\necho ok\n"
+	data, _ := ffs.ReadFile("/srv/deploy.sh")
+	if string(data) != want {
+		t.Errorf("written %q, want %q", data, want)
+	}
+
+	m := resultJSON(t, result)
+	norm, _ := m["normalized"].(map[string]any)
+	if norm["bom_stripped"] != true || norm["crlf_converted"] != float64(2) || norm["original_size"] != float64(len(want)+5) {
+		t.Errorf("normalized = %v", m["normalized"])
+	}
+	sum := sha256.Sum256([]byte(want))
+	if m["size"] != float64(len(want)) || m["checksum"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("size = %v, checksum = %v, want those of the normalized content", m["size"], m["checksum"])
+	}
+}
+
+func TestFilePut_NoNormalizationByDefault(t *testing.T) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_norm"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, _ := srv.handleShellFilePut(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_norm",
+		"remote_path": "/srv/notes.txt",
+		"content":     "a\r\nb\r\n",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if data, _ := ffs.ReadFile("/srv/notes.txt"); string(data) != "a\r\nb\r\n" {
+		t.Errorf("written %q, want the content unchanged", data)
+	}
+	if m := resultJSON(t, result); m["normalized"] != nil {
+		t.Errorf("normalized = %v, want it absent", m["normalized"])
+	}
+}
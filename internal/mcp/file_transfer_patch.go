@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/acolita/claude-shell-mcp/internal/patch"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxPatchFileSize is the largest file shell_file_patch loads into memory.
+const maxPatchFileSize = 10 * maxContentSize
+
+func shellFilePatchTool() mcp.Tool {
+	return mcp.NewTool("shell_file_patch",
+		mcp.WithDescription(`Edit a file in place by applying a unified diff or search/replace edits.
+
+The file is read, changed in memory and written back atomically (temp file +
+rename) in one call, so there is no window for a lost update between a
+shell_file_get and shell_file_put, and only the change is sent.
+
+Give exactly one of:
+- patch: a unified diff of this one file ("---"/"+++" headers optional). The
+  context and removed lines of every hunk must match; the @@ start line is a
+  hint, the nearest match is used.
+- edits: [{"search": text, "replace": text, "all": bool}], applied in order.
+  Each search must occur exactly once unless all=true.
+
+If anything does not match the call fails with "patch_conflict" and the file
+is left unchanged. The file keeps its permissions. It fails too if the file
+is modified by something else while the patch is being applied.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File to patch (relative paths use the session's cwd)"),
+		),
+		mcp.WithString("patch",
+			mcp.Description("Unified diff to apply"),
+		),
+		mcp.WithArray("edits",
+			mcp.Description(`Search/replace operations: [{"search": "old", "replace": "new", "all": false}]`),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"search":  map[string]any{"type": "string"},
+					"replace": map[string]any{"type": "string"},
+					"all":     map[string]any{"type": "boolean"},
+				},
+				"required": []string{"search", "replace"},
+			}),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Check that the patch applies without writing the file (default: false)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// FilePatchResult is the result of shell_file_patch.
+type FilePatchResult struct {
+	Status       string `json:"status"`
+	Path         string `json:"path"`
+	HunksApplied int    `json:"hunks_applied,omitempty"`
+	LinesAdded   int    `json:"lines_added,omitempty"`
+	LinesRemoved int    `json:"lines_removed,omitempty"`
+	Replacements int    `json:"replacements,omitempty"`
+	Size         int64  `json:"size"`
+	Checksum     string `json:"checksum"`
+	AtomicWrite  bool   `json:"atomic_write,omitempty"`
+	DryRun       bool   `json:"dry_run,omitempty"`
+}
+
+func (s *Server) handleShellFilePatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_patch"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	filePath := mcp.ParseString(req, "path", "")
+	diff := mcp.ParseString(req, "patch", "")
+	dryRun := mcp.ParseBoolean(req, "dry_run", false)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if filePath == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+	edits, err := parsePatchEdits(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if (diff == "") == (len(edits) == 0) {
+		return mcp.NewToolResultError("exactly one of patch or edits is required"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	filePath = sess.ResolvePath(filePath)
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	info, err := ep.stat(filePath)
+	if err != nil {
+		return fileStatError(filePath, err), nil
+	}
+	if info.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("path is a directory: %s", filePath)), nil
+	}
+	if info.Size() > maxPatchFileSize {
+		return mcp.NewToolResultError(fmt.Sprintf("file too large to patch: %d bytes (max %d)", info.Size(), maxPatchFileSize)), nil
+	}
+
+	original, err := readEndpointFile(ep, filePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("read file: %v", err)), nil
+	}
+
+	result := FilePatchResult{Status: "completed", Path: filePath, DryRun: dryRun}
+	var patched []byte
+	if diff != "" {
+		hunks, err := patch.ParseUnified(diff)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid patch: %v", err)), nil
+		}
+		var stats patch.Stats
+		patched, stats, err = patch.ApplyUnified(original, hunks)
+		if err != nil {
+			return patchError(filePath, err), nil
+		}
+		result.HunksApplied = stats.Hunks
+		result.LinesAdded = stats.Added
+		result.LinesRemoved = stats.Removed
+	} else {
+		patched, result.Replacements, err = patch.ApplyEdits(original, edits)
+		if err != nil {
+			return patchError(filePath, err), nil
+		}
+	}
+	result.Size = int64(len(patched))
+	sum := sha256.Sum256(patched)
+	result.Checksum = hex.EncodeToString(sum[:])
+
+	if dryRun {
+		return jsonResult(result)
+	}
+
+	slog.Info("patching file",
+		slog.String("session_id", sessionID),
+		slog.String("path", filePath),
+	)
+
+	dir := ep.dir(filePath)
+	tempPath := fmt.Sprintf("%s/.%s.tmp.%s", dir, filepath.Base(filePath), randomSuffix())
+	if err := writeEndpointFile(ep, tempPath, patched, info.Mode().Perm()); err != nil {
+		ep.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("write temp file: %v", err)), nil
+	}
+
+	// Refuse to replace a file that changed since it was read.
+	if now, err := ep.stat(filePath); err != nil || now.Size() != info.Size() || !now.ModTime().Equal(info.ModTime()) {
+		ep.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("file changed while patching: %s was modified by another writer and was not replaced; retry the patch", filePath)), nil
+	}
+
+	if err := ep.rename(tempPath, filePath); err != nil {
+		ep.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("rename to final path: %v", err)), nil
+	}
+	result.AtomicWrite = true
+	return jsonResult(result)
+}
+
+// parsePatchEdits reads the edits argument, accepting a JSON-encoded string
+// from clients that send arrays that way.
+func parsePatchEdits(req mcp.CallToolRequest) ([]patch.Edit, error) {
+	raw, ok := req.GetArguments()["edits"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	encoded, isString := raw.(string)
+	data := []byte(encoded)
+	if !isString {
+		var err error
+		if data, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("edits: %w", err)
+		}
+	}
+	var edits []patch.Edit
+	if err := json.Unmarshal(data, &edits); err != nil {
+		return nil, fmt.Errorf("edits must be an array of {search, replace} objects: %w", err)
+	}
+	return edits, nil
+}
+
+// patchError reports a failed patch, marking conflicts as patch_conflict.
+func patchError(path string, err error) *mcp.CallToolResult {
+	var conflict *patch.ConflictError
+	if errors.As(err, &conflict) {
+		return mcp.NewToolResultError(fmt.Sprintf("patch_conflict: %s: %v; file unchanged", path, err))
+	}
+	return mcp.NewToolResultError(fmt.Sprintf("invalid patch: %v", err))
+}
+
+func readEndpointFile(ep relayEndpoint, p string) ([]byte, error) {
+	r, err := ep.open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func writeEndpointFile(ep relayEndpoint, p string, data []byte, perm fs.FileMode) error {
+	w, err := ep.create(p, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+const patchTestConfig = "listen 80;\nserver_name example.com;\nroot /var/www;\n"
+
+func newPatchTestServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.AddFile("/etc/app.conf", []byte(patchTestConfig), 0640)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_patch"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func patchArgs(extra map[string]any) map[string]any {
+	args := map[string]any{
+		"session_id": "sess_patch",
+		"path":       "/etc/app.conf",
+	}
+	for k, v := range extra {
+		args[k] = v
+	}
+	return args
+}
+
+func TestHandleShellFilePatch_UnifiedDiff(t *testing.T) {
+	srv, ffs := newPatchTestServer()
+
+	result, err := srv.handleShellFilePatch(context.Background(), makeRequest(patchArgs(map[string]any{
+		"patch": "--- a/app.conf\n+++ b/app.conf\n@@ -1,2 +1,2 @@\n-listen 80;\n+listen 8080;\n server_name example.com;\n",
+	})))
+	if err != nil || result.IsError {
+		t.Fatalf("patch failed: %v %s", err, resultText(result))
+	}
+
+	got, _ := ffs.ReadFile("/etc/app.conf")
+	want := strings.Replace(patchTestConfig, "listen 80;", "listen 8080;", 1)
+	if string(got) != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	m := resultJSON(t, result)
+	if m["hunks_applied"] != float64(1) || m["lines_added"] != float64(1) || m["lines_removed"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if m["atomic_write"] != true || m["size"] != float64(len(want)) {
+		t.Errorf("result = %v", m)
+	}
+	if info, _ := ffs.Stat("/etc/app.conf"); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want 0640 kept", info.Mode().Perm())
+	}
+	for _, name := range ffs.Files() {
+		if strings.Contains(name, ".tmp.") {
+			t.Errorf("temp file left behind: %s", name)
+		}
+	}
+}
+
+func TestHandleShellFilePatch_Edits(t *testing.T) {
+	srv, ffs := newPatchTestServer()
+
+	result, _ := srv.handleShellFilePatch(context.Background(), makeRequest(patchArgs(map[string]any{
+		"edits": []any{
+			map[string]any{"search": "example.com", "replace": "example.org"},
+			map[string]any{"search": ";", "replace": " ;", "all": true},
+		},
+	})))
+	if result.IsError {
+		t.Fatalf("patch failed: %s", resultText(result))
+	}
+	got, _ := ffs.ReadFile("/etc/app.conf")
+	if want := "listen 80 ;\nserver_name example.org ;\nroot /var/www ;\n"; string(got) != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+	if m := resultJSON(t, result); m["replacements"] != float64(4) {
+		t.Errorf("replacements = %v, want 4", m["replacements"])
+	}
+}
+
+func TestHandleShellFilePatch_EditsAsJSONString(t *testing.T) {
+	srv, ffs := newPatchTestServer()
+
+	result, _ := srv.handleShellFilePatch(context.Background(), makeRequest(patchArgs(map[string]any{
+		"edits": `[{"search": "/var/www", "replace": "/srv/www"}]`,
+	})))
+	if result.IsError {
+		t.Fatalf("patch failed: %s", resultText(result))
+	}
+	if got, _ := ffs.ReadFile("/etc/app.conf"); !strings.Contains(string(got), "root /srv/www;") {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestHandleShellFilePatch_ConflictLeavesFileUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+	}{
+		{"diff", map[string]any{"patch": "@@ -1,1 +1,1 @@\n-listen 443;\n+listen 8443;\n"}},
+		{"edit", map[string]any{"edits": `[{"search": "listen 443;", "replace": "listen 8443;"}]`}},
+		{"ambiguous edit", map[string]any{"edits": `[{"search": ";", "replace": ","}]`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, ffs := newPatchTestServer()
+			result, _ := srv.handleShellFilePatch(context.Background(), makeRequest(patchArgs(tt.args)))
+			if !result.IsError || !strings.HasPrefix(resultText(result), "patch_conflict:") {
+				t.Fatalf("expected patch_conflict, got %q", resultText(result))
+			}
+			if got, _ := ffs.ReadFile("/etc/app.conf"); string(got) != patchTestConfig {
+				t.Errorf("file changed on conflict: %q", got)
+			}
+		})
+	}
+}
+
+func TestHandleShellFilePatch_DryRun(t *testing.T) {
+	srv, ffs := newPatchTestServer()
+
+	result, _ := srv.handleShellFilePatch(context.Background(), makeRequest(patchArgs(map[string]any{
+		"patch":   "@@ -3 +3 @@\n-root /var/www;\n+root /srv;\n",
+		"dry_run": true,
+	})))
+	if result.IsError {
+		t.Fatalf("dry run failed: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["dry_run"] != true || m["hunks_applied"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if _, ok := m["atomic_write"]; ok {
+		t.Error("dry run reports a write")
+	}
+	if got, _ := ffs.ReadFile("/etc/app.conf"); string(got) != patchTestConfig {
+		t.Errorf("dry run changed the file: %q", got)
+	}
+}
+
+func TestHandleShellFilePatch_InvalidArgs(t *testing.T) {
+	srv, _ := newPatchTestServer()
+	ffs := srv.fs.(*fakefs.FS)
+	ffs.MkdirAll("/etc/conf.d", 0755)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"no patch or edits", patchArgs(nil), "exactly one of patch or edits"},
+		{"both", patchArgs(map[string]any{"patch": "@@ -1 +1 @@\n-a\n+b\n", "edits": `[{"search": "a", "replace": "b"}]`}), "exactly one of patch or edits"},
+		{"bad edits", patchArgs(map[string]any{"edits": "not json"}), "edits must be an array"},
+		{"bad diff", patchArgs(map[string]any{"patch": "just text"}), "invalid patch"},
+		{"missing file", patchArgs(map[string]any{"path": "/etc/missing.conf", "patch": "@@ -1 +1 @@\n-a\n+b\n"}), "missing.conf"},
+		{"directory", patchArgs(map[string]any{"path": "/etc/conf.d", "patch": "@@ -1 +1 @@\n-a\n+b\n"}), "is a directory"},
+		{"no path", map[string]any{"session_id": "sess_patch", "patch": "@@ -1 +1 @@\n-a\n+b\n"}, "path is required"},
+		{"no session", map[string]any{"path": "/etc/app.conf"}, errSessionIDRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := srv.handleShellFilePatch(context.Background(), makeRequest(tt.args))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("got %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/sftp"
+)
+
+// progressWriteInterval is how often shell_file_get rewrites its
+// progress_file while reading.
+const progressWriteInterval = 500 * time.Millisecond
+
+// getProgress records the progress of an inline shell_file_get in a
+// single-chunk transfer manifest, so shell_transfer_status can poll it like a
+// chunked transfer.
+type getProgress struct {
+	s         *Server
+	path      string
+	manifest  *TransferManifest
+	lastWrite time.Time
+}
+
+func (s *Server) newGetProgress(path, sessionID, remotePath, localPath string, size int64) *getProgress {
+	now := s.clock.Now()
+	p := &getProgress{
+		s:    s,
+		path: path,
+		manifest: &TransferManifest{
+			Version:       1,
+			Direction:     "get",
+			RemotePath:    remotePath,
+			LocalPath:     localPath,
+			TotalSize:     size,
+			ChunkSize:     int(size),
+			TotalChunks:   1,
+			Chunks:        []ChunkInfo{{Index: 0, Offset: 0, Size: int(size)}},
+			StartedAt:     now,
+			LastUpdatedAt: now,
+			SessionID:     sessionID,
+		},
+		lastWrite: now,
+	}
+	p.save()
+	return p
+}
+
+// Write counts bytes read and rewrites the progress file at most every
+// progressWriteInterval. It never fails, so a broken progress file does not
+// abort the download.
+func (p *getProgress) Write(b []byte) (int, error) {
+	p.manifest.BytesSent += int64(len(b))
+	if now := p.s.clock.Now(); now.Sub(p.lastWrite) >= progressWriteInterval {
+		p.lastWrite = now
+		p.save()
+	}
+	return len(b), nil
+}
+
+// finish marks the transfer complete and writes the final progress.
+func (p *getProgress) finish() {
+	now := p.s.clock.Now()
+	p.manifest.Chunks[0].Completed = true
+	p.manifest.CompletedAt = &now
+	p.save()
+}
+
+func (p *getProgress) save() {
+	now := p.s.clock.Now()
+	p.manifest.LastUpdatedAt = now
+	if elapsed := now.Sub(p.manifest.StartedAt); elapsed.Seconds() > 0 {
+		p.manifest.BytesPerSecond = int64(float64(p.manifest.BytesSent) / elapsed.Seconds())
+	}
+	if err := p.s.saveManifest(p.manifest, p.path); err != nil {
+		slog.Warn("failed to write progress file",
+			slog.String("progress_file", p.path),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// readWithProgress reads r to the end, reporting progress to opts.ProgressFile.
+func (s *Server) readWithProgress(r io.Reader, sessionID, remotePath string, size int64, opts FileGetOptions) ([]byte, error) {
+	progress := s.newGetProgress(opts.ProgressFile, sessionID, remotePath, opts.LocalPath, size)
+
+	var buf bytes.Buffer
+	buf.Grow(int(size))
+	if _, err := buf.ReadFrom(io.TeeReader(r, progress)); err != nil {
+		progress.save()
+		return nil, fmt.Errorf("read after %d bytes: %w", progress.manifest.BytesSent, err)
+	}
+	progress.finish()
+	return buf.Bytes(), nil
+}
+
+// readSSHFile downloads remotePath whole, through readWithProgress when a
+// progress_file is set.
+func (s *Server) readSSHFile(client *sftp.Client, sessionID, remotePath string, size int64, opts FileGetOptions) ([]byte, error) {
+	if opts.ProgressFile == "" {
+		data, _, err := client.GetFile(remotePath)
+		return data, err
+	}
+	f, err := client.Open(remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.readWithProgress(f, sessionID, remotePath, size, opts)
+}
+
+// readLocalFile is readSSHFile for local sessions.
+func (s *Server) readLocalFile(path string, size int64, opts FileGetOptions) ([]byte, error) {
+	if opts.ProgressFile == "" {
+		return s.fs.ReadFile(path)
+	}
+	f, err := s.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return s.readWithProgress(f, "", path, size, opts)
+}
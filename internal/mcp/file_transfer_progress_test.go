@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellFileGet_ProgressFile(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/var/log/app.log", []byte(strings.Repeat("x", 5000)), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_p"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":    "sess_p",
+		"remote_path":   "/var/log/app.log",
+		"progress_file": "/tmp/app.progress.json",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("file_get failed: %v %s", err, resultText(result))
+	}
+	if m := resultJSON(t, result); m["size"] != float64(5000) {
+		t.Errorf("size = %v, want 5000", m["size"])
+	}
+
+	status, _ := srv.handleShellTransferStatus(context.Background(), makeRequest(map[string]any{
+		"manifest_path": "/tmp/app.progress.json",
+	}))
+	m := resultJSON(t, status)
+	if m["status"] != "completed" || m["bytes_transferred"] != float64(5000) || m["progress_percent"] != float64(100) {
+		t.Errorf("transfer status = %v, want completed 5000/5000", m)
+	}
+}
+
+// tickingReader returns the data n bytes at a time, advancing the clock
+// before each read.
+type tickingReader struct {
+	data   []byte
+	n      int
+	clock  *fakeclock.Clock
+	step   time.Duration
+	onRead func()
+}
+
+func (r *tickingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	r.clock.Advance(r.step)
+	if r.onRead != nil {
+		r.onRead()
+	}
+	n := copy(p[:min(len(p), r.n)], r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReadWithProgress_WritesIntermediateProgress(t *testing.T) {
+	ffs := fakefs.New()
+	clock := fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	srv := NewServer(config.DefaultConfig(),
+		WithSessionManager(fakesessionmgr.New()),
+		WithFileSystem(ffs),
+		WithClock(clock),
+	)
+	opts := FileGetOptions{ProgressFile: "/tmp/p.json"}
+
+	var seen []int64
+	r := &tickingReader{data: make([]byte, 300), n: 100, clock: clock, step: progressWriteInterval}
+	r.onRead = func() {
+		if m, err := srv.loadManifest(opts.ProgressFile); err == nil {
+			seen = append(seen, m.BytesSent)
+			if m.Chunks[0].Completed {
+				t.Error("progress marked complete before the read finished")
+			}
+		}
+	}
+
+	data, err := srv.readWithProgress(r, "sess_p", "/data.bin", 300, opts)
+	if err != nil || len(data) != 300 {
+		t.Fatalf("readWithProgress = %d bytes, %v", len(data), err)
+	}
+	if len(seen) != 3 || seen[0] != 0 || seen[1] != 100 || seen[2] != 200 {
+		t.Errorf("progress seen during read = %v, want [0 100 200]", seen)
+	}
+
+	m, err := srv.loadManifest(opts.ProgressFile)
+	if err != nil {
+		t.Fatalf("load progress: %v", err)
+	}
+	if !m.Chunks[0].Completed || m.CompletedAt == nil || m.BytesSent != 300 {
+		t.Errorf("final progress = %+v, want completed 300 bytes", m)
+	}
+	if m.BytesPerSecond == 0 {
+		t.Error("bytes_per_second not recorded")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestReadWithProgress_Error(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+
+	_, err := srv.readWithProgress(failingReader{}, "", "/data.bin", 10, FileGetOptions{ProgressFile: "/tmp/p.json"})
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("err = %v, want read error", err)
+	}
+	m, err := srv.loadManifest("/tmp/p.json")
+	if err != nil {
+		t.Fatalf("load progress: %v", err)
+	}
+	if m.Chunks[0].Completed {
+		t.Error("failed read marked complete")
+	}
+}
@@ -74,6 +74,7 @@ Returns transfer summary including file count, total size, and any errors.`),
 		mcp.WithNumber("max_depth",
 			mcp.Description("Maximum directory depth to traverse (default: 20)"),
 		),
+		additiveTool(),
 	)
 }
 
@@ -118,6 +119,7 @@ Returns transfer summary including file count, total size, and any errors.`),
 		mcp.WithBoolean("overwrite",
 			mcp.Description("Overwrite existing files (default: false)"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -129,8 +131,12 @@ type DirTransferResult struct {
 	TotalBytes       int64           `json:"total_bytes"`
 	SymlinksHandled  int             `json:"symlinks_handled,omitempty"`
 	Errors           []TransferError `json:"errors,omitempty"`
-	DurationMs       int64           `json:"duration_ms,omitempty"`
-	BytesPerSecond   int64           `json:"bytes_per_second,omitempty"`
+	// ErrorSummary counts Errors by category (permission_denied,
+	// not_found, ...), including any beyond the ErrorsOmitted cut.
+	ErrorSummary   map[string]int `json:"error_summary,omitempty"`
+	ErrorsOmitted  int            `json:"errors_omitted,omitempty"`
+	DurationMs     int64          `json:"duration_ms,omitempty"`
+	BytesPerSecond int64          `json:"bytes_per_second,omitempty"`
 }
 
 // TransferError represents an error during transfer of a specific file.
@@ -345,6 +351,9 @@ func (s *Server) handleShellDirGet(ctx context.Context, req mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	resolvedPath := sess.ResolvePath(remotePath)
 
@@ -354,6 +363,17 @@ func (s *Server) handleShellDirGet(ctx context.Context, req mcp.CallToolRequest)
 		slog.String("local_path", opts.LocalPath),
 	)
 
+	release, errResult := s.acquireTransferSlot(ctx, TransferSlot{
+		Tool:        "shell_dir_get",
+		SessionID:   sessionID,
+		Source:      resolvedPath,
+		Destination: opts.LocalPath,
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
 	if sess.IsSSH() {
 		return s.handleSSHDirGet(sess, resolvedPath, opts)
 	}
@@ -563,6 +583,10 @@ func (s *Server) copyLocalFile(srcPath, dstPath string, d fs.DirEntry, preserve
 }
 
 func (s *Server) handleShellDirPut(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_dir_put"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	localPath := mcp.ParseString(req, "local_path", "")
 
@@ -590,6 +614,9 @@ func (s *Server) handleShellDirPut(ctx context.Context, req mcp.CallToolRequest)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
 
 	resolvedRemote := sess.ResolvePath(opts.RemotePath)
 
@@ -599,6 +626,17 @@ func (s *Server) handleShellDirPut(ctx context.Context, req mcp.CallToolRequest)
 		slog.String("remote_path", resolvedRemote),
 	)
 
+	release, errResult := s.acquireTransferSlot(ctx, TransferSlot{
+		Tool:        "shell_dir_put",
+		SessionID:   sessionID,
+		Source:      localPath,
+		Destination: resolvedRemote,
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
 	if sess.IsSSH() {
 		return s.handleSSHDirPut(sess, localPath, resolvedRemote, opts)
 	}
@@ -701,7 +739,8 @@ func (s *Server) processUploadEntry(ctx *uploadWalkContext, path string, d fs.Di
 	return nil
 }
 
-// finalizeTransferResult calculates duration and sets final status.
+// finalizeTransferResult calculates duration, summarizes errors and sets
+// final status.
 func (s *Server) finalizeTransferResult(result *DirTransferResult, startTime time.Time) {
 	duration := s.clock.Now().Sub(startTime)
 	result.DurationMs = duration.Milliseconds()
@@ -711,6 +750,7 @@ func (s *Server) finalizeTransferResult(result *DirTransferResult, startTime tim
 	if len(result.Errors) > 0 {
 		result.Status = "completed_with_errors"
 	}
+	result.summarizeErrors()
 }
 
 func (s *Server) handleLocalDirCopyPut(srcPath, dstPath string, opts DirPutOptions) (*mcp.CallToolResult, error) {
@@ -1,9 +1,11 @@
 package mcp
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -445,6 +447,67 @@ func TestRecur_FinalizeTransferResult_ZeroBytes(t *testing.T) {
 	}
 }
 
+func TestRecur_FinalizeTransferResult_ErrorSummary(t *testing.T) {
+	startTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := NewServer(config.DefaultConfig(), WithClock(fakeclock.New(startTime)))
+
+	result := &DirTransferResult{Status: "completed"}
+	for i := 0; i < maxDetailedTransferErrors+50; i++ {
+		result.addError(fmt.Sprintf("/srv/data/%d.log", i), fmt.Sprintf("open /srv/data/%d.log: permission denied", i))
+	}
+	result.addError("/srv/data/gone", "stat /srv/data/gone: no such file or directory")
+	result.addError("/srv/data/out", "write /srv/data/out: no space left on device")
+	result.addError("/srv/data/odd", "something unexpected")
+
+	srv.finalizeTransferResult(result, startTime)
+
+	want := map[string]int{
+		transferErrPermission: maxDetailedTransferErrors + 50,
+		transferErrNotFound:   1,
+		transferErrNoSpace:    1,
+		transferErrOther:      1,
+	}
+	if !reflect.DeepEqual(result.ErrorSummary, want) {
+		t.Errorf("ErrorSummary = %v, want %v", result.ErrorSummary, want)
+	}
+	if len(result.Errors) != maxDetailedTransferErrors || result.ErrorsOmitted != 53 {
+		t.Errorf("len(Errors) = %d, ErrorsOmitted = %d; want %d and 53", len(result.Errors), result.ErrorsOmitted, maxDetailedTransferErrors)
+	}
+	if result.Status != "completed_with_errors" {
+		t.Errorf("Status=%q, want 'completed_with_errors'", result.Status)
+	}
+}
+
+func TestRecur_FinalizeTransferResult_NoErrorSummary(t *testing.T) {
+	startTime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	srv := NewServer(config.DefaultConfig(), WithClock(fakeclock.New(startTime)))
+
+	result := &DirTransferResult{Status: "completed"}
+	srv.finalizeTransferResult(result, startTime)
+
+	if result.ErrorSummary != nil || result.ErrorsOmitted != 0 {
+		t.Errorf("ErrorSummary = %v, ErrorsOmitted = %d; want none", result.ErrorSummary, result.ErrorsOmitted)
+	}
+}
+
+func TestRecur_TransferErrorCategory(t *testing.T) {
+	tests := map[string]string{
+		"open /a: permission denied":                transferErrPermission,
+		"chmod /a: operation not permitted":         transferErrPermission,
+		"file does not exist":                       transferErrNotFound,
+		"file exists (use overwrite=true)":          transferErrExists,
+		"read /a: is a directory":                   transferErrWrongType,
+		"sftp: connection lost":                     transferErrConnection,
+		"copy: unexpected EOF":                      transferErrConnection,
+		"open /home/geoffrey/notes: invalid format": transferErrOther,
+	}
+	for msg, want := range tests {
+		if got := transferErrorCategory(msg); got != want {
+			t.Errorf("transferErrorCategory(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
 // ==================== DirTransferResult.addError ====================
 
 func TestRecur_AddError(t *testing.T) {
@@ -0,0 +1,370 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/sftp"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func shellFileRelayTool() mcp.Tool {
+	return mcp.NewTool("shell_file_relay",
+		mcp.WithDescription(`Copy a file from one session to another without downloading it first.
+
+Bytes are streamed from the source session's SFTP read straight into the
+destination session's SFTP write (local sessions use the server's filesystem),
+so the file is never held in memory whole. Use this instead of shell_file_get
+followed by shell_file_put for host-to-host copies.
+
+The destination is written to a temp file and renamed into place. The SHA-256
+of the bytes read is returned, and with verify=true (the default) the
+destination is read back and compared with it.
+
+Counts towards transfer.max_concurrent_transfers like chunked transfers.`),
+		mcp.WithString("source_session_id",
+			mcp.Required(),
+			mcp.Description("Session to copy from"),
+		),
+		mcp.WithString("source_path",
+			mcp.Required(),
+			mcp.Description("File to copy (relative paths use the source session's cwd)"),
+		),
+		mcp.WithString("dest_session_id",
+			mcp.Required(),
+			mcp.Description("Session to copy to"),
+		),
+		mcp.WithString("dest_path",
+			mcp.Required(),
+			mcp.Description("Destination file (relative paths use the destination session's cwd)"),
+		),
+		mcp.WithString("mode",
+			mcp.Description("Destination permissions in octal (default: the source file's)"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Replace an existing destination file (default: false)"),
+		),
+		mcp.WithBoolean("create_dirs",
+			mcp.Description("Create missing parent directories at the destination (default: false)"),
+		),
+		mcp.WithBoolean("preserve",
+			mcp.Description("Copy the source modification time (default: false)"),
+		),
+		mcp.WithBoolean("verify",
+			mcp.Description("Read the destination back and compare its SHA-256 (default: true)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// FileRelayResult is the result of shell_file_relay.
+type FileRelayResult struct {
+	Status        string `json:"status"`
+	SourceSession string `json:"source_session_id"`
+	SourcePath    string `json:"source_path"`
+	DestSession   string `json:"dest_session_id"`
+	DestPath      string `json:"dest_path"`
+	Size          int64  `json:"size"`
+	Mode          string `json:"mode"`
+	Checksum      string `json:"checksum"`
+	Overwritten   bool   `json:"overwritten,omitempty"`
+	DirsCreated   bool   `json:"dirs_created,omitempty"`
+	Verified      string `json:"verified,omitempty"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// relayEndpoint is one side of a relay: SFTP for SSH sessions, the server's
+// filesystem for local ones.
+type relayEndpoint interface {
+	open(p string) (io.ReadCloser, error)
+	stat(p string) (os.FileInfo, error)
+	lstat(p string) (os.FileInfo, error)
+	readDir(p string) ([]os.FileInfo, error)
+	create(p string, perm os.FileMode) (io.WriteCloser, error)
+	mkdirAll(dir string) error
+	rename(oldPath, newPath string) error
+	remove(p string) error
+	chtimes(p string, mtime time.Time) error
+	chmod(p string, perm os.FileMode) error
+	home() (string, error)
+	dir(p string) string
+}
+
+type sftpRelayEndpoint struct {
+	client *sftp.Client
+}
+
+func (e sftpRelayEndpoint) open(p string) (io.ReadCloser, error) { return e.client.Open(p) }
+func (e sftpRelayEndpoint) stat(p string) (os.FileInfo, error)   { return e.client.Stat(p) }
+func (e sftpRelayEndpoint) lstat(p string) (os.FileInfo, error)  { return e.client.Lstat(p) }
+func (e sftpRelayEndpoint) mkdirAll(dir string) error            { return e.client.MkdirAll(dir) }
+func (e sftpRelayEndpoint) remove(p string) error                { return e.client.Remove(p) }
+func (e sftpRelayEndpoint) home() (string, error)                { return e.client.Getwd() }
+func (e sftpRelayEndpoint) dir(p string) string                  { return path.Dir(p) }
+
+func (e sftpRelayEndpoint) create(p string, perm os.FileMode) (io.WriteCloser, error) {
+	f, err := e.client.Create(p)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("chmod: %w", err)
+	}
+	return f, nil
+}
+
+// rename falls back to remove-then-rename for servers without the
+// posix-rename@openssh.com extension, as writeSSHFileWith does.
+func (e sftpRelayEndpoint) rename(oldPath, newPath string) error {
+	if err := e.client.PosixRename(oldPath, newPath); err != nil {
+		e.client.Remove(newPath)
+		return e.client.Rename(oldPath, newPath)
+	}
+	return nil
+}
+
+func (e sftpRelayEndpoint) chtimes(p string, mtime time.Time) error {
+	return e.client.Chtimes(p, mtime, mtime)
+}
+
+func (e sftpRelayEndpoint) chmod(p string, perm os.FileMode) error {
+	return e.client.Chmod(p, perm)
+}
+
+func (e sftpRelayEndpoint) readDir(p string) ([]os.FileInfo, error) {
+	return e.client.ReadDir(p)
+}
+
+type localRelayEndpoint struct {
+	fs ports.FileSystem
+}
+
+func (e localRelayEndpoint) open(p string) (io.ReadCloser, error) { return e.fs.Open(p) }
+func (e localRelayEndpoint) stat(p string) (os.FileInfo, error)   { return e.fs.Stat(p) }
+func (e localRelayEndpoint) lstat(p string) (os.FileInfo, error)  { return e.fs.Lstat(p) }
+func (e localRelayEndpoint) mkdirAll(dir string) error            { return e.fs.MkdirAll(dir, 0755) }
+func (e localRelayEndpoint) rename(from, to string) error         { return e.fs.Rename(from, to) }
+func (e localRelayEndpoint) remove(p string) error                { return e.fs.Remove(p) }
+func (e localRelayEndpoint) home() (string, error)                { return e.fs.UserHomeDir() }
+func (e localRelayEndpoint) dir(p string) string                  { return filepath.Dir(p) }
+
+func (e localRelayEndpoint) create(p string, perm os.FileMode) (io.WriteCloser, error) {
+	return e.fs.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func (e localRelayEndpoint) chtimes(p string, mtime time.Time) error {
+	return e.fs.Chtimes(p, mtime, mtime)
+}
+
+func (e localRelayEndpoint) chmod(p string, perm os.FileMode) error {
+	return e.fs.Chmod(p, perm)
+}
+
+func (e localRelayEndpoint) readDir(p string) ([]os.FileInfo, error) {
+	entries, err := e.fs.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// relayEndpointFor returns the endpoint for sess.
+func (s *Server) relayEndpointFor(sess *session.Session) (relayEndpoint, error) {
+	if !sess.IsSSH() {
+		return localRelayEndpoint{fs: s.fs}, nil
+	}
+	client, err := sess.SFTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return sftpRelayEndpoint{client: client}, nil
+}
+
+func (s *Server) handleShellFileRelay(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_relay"); errResult != nil {
+		return errResult, nil
+	}
+
+	srcID := mcp.ParseString(req, "source_session_id", "")
+	srcPath := mcp.ParseString(req, "source_path", "")
+	dstID := mcp.ParseString(req, "dest_session_id", "")
+	dstPath := mcp.ParseString(req, "dest_path", "")
+	modeStr := mcp.ParseString(req, "mode", "")
+	overwrite := mcp.ParseBoolean(req, "overwrite", false)
+	createDirs := mcp.ParseBoolean(req, "create_dirs", false)
+	preserve := mcp.ParseBoolean(req, "preserve", false)
+	verify := mcp.ParseBoolean(req, "verify", true)
+
+	if srcID == "" || dstID == "" {
+		return mcp.NewToolResultError("source_session_id and dest_session_id are required"), nil
+	}
+	if srcPath == "" || dstPath == "" {
+		return mcp.NewToolResultError("source_path and dest_path are required"), nil
+	}
+	var opts FilePutOptions
+	if errResult := parseFilePutMode(modeStr, &opts); errResult != nil {
+		return errResult, nil
+	}
+
+	srcSess, err := s.sessionManager.Get(srcID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	dstSess, err := s.sessionManager.Get(dstID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for _, sess := range []*session.Session{srcSess, dstSess} {
+		if errResult := checkFileAccess(sess); errResult != nil {
+			return errResult, nil
+		}
+	}
+	srcPath = srcSess.ResolvePath(srcPath)
+	dstPath = dstSess.ResolvePath(dstPath)
+	if srcID == dstID && srcPath == dstPath {
+		return mcp.NewToolResultError("source and destination are the same file"), nil
+	}
+
+	src, err := s.relayEndpointFor(srcSess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("source: "+errGetSFTPClient, err)), nil
+	}
+	dst, err := s.relayEndpointFor(dstSess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("destination: "+errGetSFTPClient, err)), nil
+	}
+
+	srcInfo, err := src.stat(srcPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("stat source: %v", err)), nil
+	}
+	if srcInfo.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("source is a directory: %s (use shell_dir_get/shell_dir_put)", srcPath)), nil
+	}
+	perm := srcInfo.Mode().Perm()
+	if modeStr != "" {
+		perm = opts.Mode
+	}
+
+	result := FileRelayResult{
+		Status:        "completed",
+		SourceSession: srcID,
+		SourcePath:    srcPath,
+		DestSession:   dstID,
+		DestPath:      dstPath,
+		Mode:          fmt.Sprintf("%04o", perm),
+	}
+	if _, err := dst.stat(dstPath); err == nil {
+		if !overwrite {
+			return mcp.NewToolResultError(fmt.Sprintf("file exists: %s (use overwrite=true to replace)", dstPath)), nil
+		}
+		result.Overwritten = true
+	}
+	dir := dst.dir(dstPath)
+	if createDirs {
+		if err := dst.mkdirAll(dir); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf(errCreateDirs, err)), nil
+		}
+		result.DirsCreated = true
+	}
+
+	release, errResult := s.acquireTransferSlot(ctx, TransferSlot{
+		Tool:        "shell_file_relay",
+		SessionID:   srcID,
+		Source:      srcID + ":" + srcPath,
+		Destination: dstID + ":" + dstPath,
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	defer release()
+
+	slog.Info("relaying file",
+		slog.String("source_session_id", srcID),
+		slog.String("source_path", srcPath),
+		slog.String("dest_session_id", dstID),
+		slog.String("dest_path", dstPath),
+	)
+	started := s.clock.Now()
+
+	tempPath := fmt.Sprintf("%s/.%s.tmp.%s", dir, filepath.Base(dstPath), randomSuffix())
+	size, sum, err := relayCopy(src, srcPath, dst, tempPath, perm)
+	if err != nil {
+		dst.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("relay: %v", err)), nil
+	}
+	if err := dst.rename(tempPath, dstPath); err != nil {
+		dst.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("rename to final path: %v", err)), nil
+	}
+	result.Size = size
+	result.Checksum = sum
+
+	if preserve {
+		if err := dst.chtimes(dstPath, srcInfo.ModTime()); err != nil {
+			slog.Warn(errPreserveTimestamp, slog.String("error", err.Error()))
+		}
+	}
+
+	if verify {
+		f, err := dst.open(dstPath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("verify readback: open %s: %v", dstPath, err)), nil
+		}
+		var check FilePutResult
+		errResult := checkReadbackSum(f, dstPath, sum, &check)
+		f.Close()
+		if errResult != nil {
+			return errResult, nil
+		}
+		result.Verified = check.Verified
+	}
+
+	result.DurationMs = s.clock.Now().Sub(started).Milliseconds()
+	return jsonResult(result)
+}
+
+// relayCopy streams srcPath into a new file at dstPath, returning the bytes
+// copied and their hex SHA-256.
+func relayCopy(src relayEndpoint, srcPath string, dst relayEndpoint, dstPath string, perm os.FileMode) (int64, string, error) {
+	r, err := src.open(srcPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("open source: %w", err)
+	}
+	defer r.Close()
+
+	w, err := dst.create(dstPath, perm)
+	if err != nil {
+		return 0, "", fmt.Errorf("create destination: %w", err)
+	}
+
+	h := sha256.New()
+	n, err := io.Copy(w, io.TeeReader(r, h))
+	if err != nil {
+		w.Close()
+		return n, "", fmt.Errorf("copy after %d bytes: %w", n, err)
+	}
+	if err := w.Close(); err != nil {
+		return n, "", fmt.Errorf("close destination: %w", err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newRelayTestServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.AddFile("/src/data.bin", []byte("relayed payload\n"), 0640)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_a"))
+	sm.AddSession(newLocalSession("sess_b"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func relayArgs(extra map[string]any) map[string]any {
+	args := map[string]any{
+		"source_session_id": "sess_a",
+		"source_path":       "/src/data.bin",
+		"dest_session_id":   "sess_b",
+		"dest_path":         "/dst/data.bin",
+	}
+	for k, v := range extra {
+		args[k] = v
+	}
+	return args
+}
+
+func TestHandleShellFileRelay_CopiesAndVerifies(t *testing.T) {
+	srv, ffs := newRelayTestServer()
+
+	result, err := srv.handleShellFileRelay(context.Background(), makeRequest(relayArgs(nil)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("relay failed: %s", resultText(result))
+	}
+
+	got, err := ffs.ReadFile("/dst/data.bin")
+	if err != nil || string(got) != "relayed payload\n" {
+		t.Fatalf("destination = %q, %v", got, err)
+	}
+	sum := sha256.Sum256(got)
+	m := resultJSON(t, result)
+	if m["checksum"] != hex.EncodeToString(sum[:]) {
+		t.Errorf("checksum = %v", m["checksum"])
+	}
+	if m["size"] != float64(len(got)) || m["verified"] != verifiedSHA256 {
+		t.Errorf("result = %v", m)
+	}
+	if m["mode"] != "0640" {
+		t.Errorf("mode = %v, want source mode 0640", m["mode"])
+	}
+	if info, _ := ffs.Stat("/dst/data.bin"); info.Mode().Perm() != 0640 {
+		t.Errorf("destination mode = %v, want 0640", info.Mode().Perm())
+	}
+	for _, name := range ffs.Files() {
+		if strings.Contains(name, ".tmp.") {
+			t.Errorf("temp file left behind: %s", name)
+		}
+	}
+}
+
+func TestHandleShellFileRelay_Overwrite(t *testing.T) {
+	srv, ffs := newRelayTestServer()
+	ffs.AddFile("/dst/data.bin", []byte("old"), 0644)
+
+	result, _ := srv.handleShellFileRelay(context.Background(), makeRequest(relayArgs(nil)))
+	if !result.IsError || !strings.Contains(resultText(result), "file exists") {
+		t.Fatalf("expected file exists error, got %q", resultText(result))
+	}
+
+	result, _ = srv.handleShellFileRelay(context.Background(), makeRequest(relayArgs(map[string]any{
+		"overwrite": true,
+		"mode":      "600",
+	})))
+	if result.IsError {
+		t.Fatalf("relay failed: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["overwritten"] != true || m["mode"] != "0600" {
+		t.Errorf("result = %v, want overwritten with mode 0600", m)
+	}
+	if got, _ := ffs.ReadFile("/dst/data.bin"); string(got) != "relayed payload\n" {
+		t.Errorf("destination = %q", got)
+	}
+}
+
+func TestHandleShellFileRelay_Preserve(t *testing.T) {
+	srv, ffs := newRelayTestServer()
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ffs.Chtimes("/src/data.bin", mtime, mtime)
+
+	result, _ := srv.handleShellFileRelay(context.Background(), makeRequest(relayArgs(map[string]any{"preserve": true})))
+	if result.IsError {
+		t.Fatalf("relay failed: %s", resultText(result))
+	}
+	if info, _ := ffs.Stat("/dst/data.bin"); !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime = %v, want %v", info.ModTime(), mtime)
+	}
+}
+
+func TestHandleShellFileRelay_Errors(t *testing.T) {
+	srv, ffs := newRelayTestServer()
+	ffs.MkdirAll("/src/dir", 0755)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing session", map[string]any{"dest_session_id": ""}, "dest_session_id are required"},
+		{"missing path", map[string]any{"source_path": ""}, "source_path and dest_path are required"},
+		{"unknown session", map[string]any{"dest_session_id": "sess_nope"}, "not found"},
+		{"missing source", map[string]any{"source_path": "/src/nope"}, "stat source"},
+		{"directory", map[string]any{"source_path": "/src/dir"}, "source is a directory"},
+		{"same file", map[string]any{"dest_session_id": "sess_a", "dest_path": "/src/data.bin"}, "same file"},
+		{"bad mode", map[string]any{"mode": "rw"}, "invalid mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := srv.handleShellFileRelay(context.Background(), makeRequest(relayArgs(tt.args)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("got %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxRmListed bounds the paths listed in a FileRmResult; the counts cover
+// everything removed.
+const maxRmListed = 100
+
+func shellFileRmTool() mcp.Tool {
+	return mcp.NewTool("shell_file_rm",
+		mcp.WithDescription(`Remove a file, symlink or directory in a shell session.
+
+For SSH sessions, removes over SFTP; for local sessions, through the server's
+filesystem. A symlink is removed itself, never what it points to.
+
+A directory is only removed if it is empty, unless recursive is true, which
+removes everything under it first. With force, a path that does not exist is
+not an error (status "not_found"), as with rm -f.
+
+The filesystem root and the home directory are never removed unless force is
+true and confirm repeats the resolved path exactly.
+
+Returns the removed paths (the first 100, deepest first) and counts of files,
+directories and bytes removed.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path to remove (relative paths use session's cwd, ~ is the home directory)"),
+		),
+		mcp.WithBoolean("recursive",
+			mcp.Description("Remove a non-empty directory and everything under it (default: false)"),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Do not fail if path does not exist; with confirm, allow removing / or the home directory (default: false)"),
+		),
+		mcp.WithString("confirm",
+			mcp.Description("The resolved path again, required with force to remove / or the home directory"),
+		),
+		destructiveTool(),
+	)
+}
+
+// FileRmResult is the result of shell_file_rm.
+type FileRmResult struct {
+	Status           string   `json:"status"`
+	Path             string   `json:"path"`
+	Type             string   `json:"type,omitempty"`
+	Removed          []string `json:"removed"`
+	RemovedTruncated bool     `json:"removed_truncated,omitempty"`
+	Files            int      `json:"files"`
+	Dirs             int      `json:"dirs"`
+	Bytes            int64    `json:"bytes"`
+}
+
+// record adds a removed entry to the result.
+func (r *FileRmResult) record(p string, info os.FileInfo) {
+	if len(r.Removed) < maxRmListed {
+		r.Removed = append(r.Removed, p)
+	} else {
+		r.RemovedTruncated = true
+	}
+	if info.IsDir() {
+		r.Dirs++
+		return
+	}
+	r.Files++
+	if info.Mode().IsRegular() {
+		r.Bytes += info.Size()
+	}
+}
+
+// FileRmOptions contains options for shell_file_rm.
+type FileRmOptions struct {
+	Recursive bool
+	Force     bool
+	Confirm   string
+}
+
+func (s *Server) handleShellFileRm(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_file_rm"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	target := mcp.ParseString(req, "path", "")
+	opts := FileRmOptions{
+		Recursive: mcp.ParseBoolean(req, "recursive", false),
+		Force:     mcp.ParseBoolean(req, "force", false),
+		Confirm:   mcp.ParseString(req, "confirm", ""),
+	}
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if strings.TrimSpace(target) == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	resolved, home, err := resolveRmPath(sess, ep, target)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if resolved == "/" || (home != "" && resolved == home) {
+		if !opts.Force || opts.Confirm != resolved {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"refusing to remove %s: it is the filesystem root or the home directory; set force=true and confirm=%q to remove it anyway",
+				resolved, resolved)), nil
+		}
+	}
+
+	slog.Info("removing path",
+		slog.String("session_id", sessionID),
+		slog.String("path", resolved),
+		slog.Bool("recursive", opts.Recursive),
+	)
+
+	result, errResult := removePath(ep, resolved, opts)
+	if errResult != nil {
+		return errResult, nil
+	}
+	return jsonResult(result)
+}
+
+// resolveRmPath resolves target against the session's cwd and expands a
+// leading ~, which ResolvePath leaves for the remote side but SFTP does not
+// understand. It also returns the home directory, or "" if it is unknown.
+func resolveRmPath(sess *session.Session, ep relayEndpoint, target string) (string, string, error) {
+	home, homeErr := ep.home()
+	if homeErr != nil {
+		home = ""
+	}
+	resolved := sess.ResolvePath(target)
+	if resolved == "~" || strings.HasPrefix(resolved, "~/") {
+		if home == "" {
+			return "", "", fmt.Errorf("resolve %s: home directory unknown: %v", target, homeErr)
+		}
+		resolved = home + strings.TrimPrefix(resolved, "~")
+	}
+	if home != "" {
+		home = path.Clean(home)
+	}
+	return path.Clean(resolved), home, nil
+}
+
+// removePath removes p, and with opts.Recursive everything under it, from
+// ep. Entries are removed deepest first; if one fails, what was removed
+// before is reported with the error.
+func removePath(ep relayEndpoint, p string, opts FileRmOptions) (*FileRmResult, *mcp.CallToolResult) {
+	result := &FileRmResult{Status: "completed", Path: p, Removed: []string{}}
+
+	info, err := ep.lstat(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && opts.Force {
+			result.Status = "not_found"
+			return result, nil
+		}
+		return nil, fileStatError(p, err)
+	}
+	result.Type = rmEntryType(info)
+
+	if info.IsDir() {
+		children, err := ep.readDir(p)
+		if err != nil {
+			return nil, mcp.NewToolResultError(fmt.Sprintf("read directory %s: %v", p, err))
+		}
+		if len(children) > 0 && !opts.Recursive {
+			return nil, mcp.NewToolResultError(fmt.Sprintf("directory not empty: %s (use recursive=true to remove it and its contents)", p))
+		}
+		if err := removeChildren(ep, p, children, result); err != nil {
+			return nil, rmError(err, result)
+		}
+	}
+
+	if err := ep.remove(p); err != nil {
+		return nil, rmError(fmt.Errorf("remove %s: %w", p, err), result)
+	}
+	result.record(p, info)
+	return result, nil
+}
+
+// removeChildren removes the entries of dir and everything under them.
+func removeChildren(ep relayEndpoint, dir string, children []os.FileInfo, result *FileRmResult) error {
+	for _, child := range children {
+		p := path.Join(dir, child.Name())
+		// Entries of a directory listing are not followed: a symlink to a
+		// directory is removed as a link.
+		if child.IsDir() {
+			grandchildren, err := ep.readDir(p)
+			if err != nil {
+				return fmt.Errorf("read directory %s: %w", p, err)
+			}
+			if err := removeChildren(ep, p, grandchildren, result); err != nil {
+				return err
+			}
+		}
+		if err := ep.remove(p); err != nil {
+			return fmt.Errorf("remove %s: %w", p, err)
+		}
+		result.record(p, child)
+	}
+	return nil
+}
+
+// rmError reports a failed removal along with how much was removed before.
+func rmError(err error, result *FileRmResult) *mcp.CallToolResult {
+	if result.Files+result.Dirs > 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("%v (%d files and %d directories were removed before the error)", err, result.Files, result.Dirs))
+	}
+	return mcp.NewToolResultError(err.Error())
+}
+
+// rmEntryType names the kind of entry removed.
+func rmEntryType(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "symlink"
+	case info.IsDir():
+		return "directory"
+	default:
+		return "file"
+	}
+}
@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func newRmServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.SetHomeDir("/home/dev")
+	ffs.AddFile("/work/app.log", []byte("0123456789"), 0644)
+	ffs.AddFile("/work/build/out/app.bin", []byte("binary"), 0755)
+	ffs.AddFile("/work/build/out/app.map", []byte("map"), 0644)
+	ffs.AddFile("/work/build/stamp", []byte(""), 0644)
+	ffs.AddSymlink("/work/build/latest", "/work/build/out")
+	ffs.MkdirAll("/work/empty", 0755)
+	ffs.AddFile("/home/dev/.bashrc", []byte("export A=1\n"), 0644)
+
+	sess := newLocalSession("sess_rm")
+	sess.Cwd = "/work"
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func callFileRm(t *testing.T, srv *Server, args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	args["session_id"] = "sess_rm"
+	result, err := srv.handleShellFileRm(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func TestFileRm_File(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "app.log"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["status"] != "completed" || m["path"] != "/work/app.log" || m["type"] != "file" {
+		t.Errorf("result = %v", m)
+	}
+	if m["files"] != float64(1) || m["dirs"] != float64(0) || m["bytes"] != float64(10) {
+		t.Errorf("counts = %v files, %v dirs, %v bytes", m["files"], m["dirs"], m["bytes"])
+	}
+	if _, err := ffs.Stat("/work/app.log"); err == nil {
+		t.Error("file still exists")
+	}
+}
+
+func TestFileRm_EmptyDirectory(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "/work/empty"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["type"] != "directory" || m["dirs"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if _, err := ffs.Stat("/work/empty"); err == nil {
+		t.Error("directory still exists")
+	}
+}
+
+func TestFileRm_NonEmptyDirectoryNeedsRecursive(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "build"})
+	if !result.IsError || !strings.Contains(resultText(result), "directory not empty: /work/build") {
+		t.Fatalf("result = %s, want directory not empty", resultText(result))
+	}
+	if _, err := ffs.Stat("/work/build/stamp"); err != nil {
+		t.Error("contents removed without recursive")
+	}
+}
+
+func TestFileRm_Recursive(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "build", "recursive": true})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	// app.bin, app.map, stamp and the latest symlink; out and build.
+	if m["files"] != float64(4) || m["dirs"] != float64(2) || m["bytes"] != float64(9) {
+		t.Errorf("counts = %v files, %v dirs, %v bytes", m["files"], m["dirs"], m["bytes"])
+	}
+	removed, _ := m["removed"].([]any)
+	if len(removed) != 6 || removed[len(removed)-1] != "/work/build" {
+		t.Errorf("removed = %v, want six paths ending with the directory itself", removed)
+	}
+	for _, p := range []string{"/work/build", "/work/build/out/app.bin"} {
+		if _, err := ffs.Lstat(p); err == nil {
+			t.Errorf("%s still exists", p)
+		}
+	}
+	if _, err := ffs.Stat("/work/app.log"); err != nil {
+		t.Error("removed a file outside the directory")
+	}
+}
+
+func TestFileRm_SymlinkNotFollowed(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "build/latest", "recursive": true})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["type"] != "symlink" || m["files"] != float64(1) {
+		t.Errorf("result = %v", m)
+	}
+	if _, err := ffs.Stat("/work/build/out/app.bin"); err != nil {
+		t.Error("removed the symlink's target")
+	}
+}
+
+func TestFileRm_Missing(t *testing.T) {
+	srv, _ := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "nope.txt"})
+	if !result.IsError || !strings.Contains(resultText(result), "file not found: /work/nope.txt") {
+		t.Errorf("result = %s, want file not found", resultText(result))
+	}
+
+	result = callFileRm(t, srv, map[string]any{"path": "nope.txt", "force": true})
+	if result.IsError {
+		t.Fatalf("force should ignore a missing path: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["status"] != "not_found" || m["files"] != float64(0) {
+		t.Errorf("result = %v", m)
+	}
+}
+
+func TestFileRm_DangerousPaths(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		path string
+	}{
+		{"root", map[string]any{"path": "/", "recursive": true}, "/"},
+		{"root via dots", map[string]any{"path": "/work/..", "recursive": true, "force": true}, "/"},
+		{"tilde", map[string]any{"path": "~", "recursive": true, "force": true}, "/home/dev"},
+		{"home", map[string]any{"path": "/home/dev/", "recursive": true, "force": true, "confirm": "/home"}, "/home/dev"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, ffs := newRmServer()
+			result := callFileRm(t, srv, tt.args)
+			if !result.IsError || !strings.Contains(resultText(result), "refusing to remove "+tt.path) {
+				t.Errorf("result = %s, want refusal for %s", resultText(result), tt.path)
+			}
+			if _, err := ffs.Stat("/home/dev/.bashrc"); err != nil {
+				t.Error("files removed despite the refusal")
+			}
+		})
+	}
+}
+
+func TestFileRm_HomeWithConfirm(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "~", "recursive": true, "force": true, "confirm": "/home/dev"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if _, err := ffs.Stat("/home/dev"); err == nil {
+		t.Error("home directory still exists")
+	}
+}
+
+func TestFileRm_TildePath(t *testing.T) {
+	srv, ffs := newRmServer()
+
+	result := callFileRm(t, srv, map[string]any{"path": "~/.bashrc"})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["path"] != "/home/dev/.bashrc" {
+		t.Errorf("path = %v", m["path"])
+	}
+	if _, err := ffs.Stat("/home/dev/.bashrc"); err == nil {
+		t.Error("file still exists")
+	}
+}
+
+func TestFileRm_ListTruncated(t *testing.T) {
+	srv, ffs := newRmServer()
+	for i := 0; i < maxRmListed+5; i++ {
+		ffs.AddFile("/work/many/f"+strings.Repeat("x", i+1), []byte("x"), 0644)
+	}
+
+	m := resultJSON(t, callFileRm(t, srv, map[string]any{"path": "many", "recursive": true}))
+	if removed, _ := m["removed"].([]any); len(removed) != maxRmListed || m["removed_truncated"] != true {
+		t.Errorf("listed %d, removed_truncated = %v", len(removed), m["removed_truncated"])
+	}
+	if m["files"] != float64(maxRmListed+5) || m["dirs"] != float64(1) {
+		t.Errorf("counts = %v files, %v dirs", m["files"], m["dirs"])
+	}
+}
+
+func TestFileRm_Invalid(t *testing.T) {
+	srv, _ := newRmServer()
+
+	result, _ := srv.handleShellFileRm(context.Background(), makeRequest(map[string]any{"path": "/work/app.log"}))
+	if !result.IsError || !strings.Contains(resultText(result), "session_id") {
+		t.Errorf("result = %s, want session_id error", resultText(result))
+	}
+	result = callFileRm(t, srv, map[string]any{"path": " "})
+	if !result.IsError || !strings.Contains(resultText(result), "path is required") {
+		t.Errorf("result = %s, want path error", resultText(result))
+	}
+}
+
+func TestFileRm_ReadOnly(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/work/app.log", []byte("x"), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_rm"))
+	cfg := config.DefaultConfig()
+	cfg.Security.ReadOnly = true
+	srv := newTestServerWithConfig(sm, ffs, cfg)
+
+	result := callFileRm(t, srv, map[string]any{"path": "/work/app.log"})
+	if !result.IsError || !strings.HasPrefix(resultText(result), "read_only:") {
+		t.Errorf("result = %s, want read_only error", resultText(result))
+	}
+	if _, err := ffs.Stat("/work/app.log"); err != nil {
+		t.Error("file removed in read-only mode")
+	}
+}
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// streamPutThreshold is the local_path size from which shell_file_put streams
+// the file to an SSH session instead of reading it into memory first.
+const streamPutThreshold = 8 * 1024 * 1024
+
+// streamPutSource reports whether a put should be streamed: a local_path
+// upload to an SSH session of at least streamPutThreshold bytes. Smaller
+// files and content uploads keep the in-memory path.
+func (s *Server) streamPutSource(sess *session.Session, opts FilePutOptions) (os.FileInfo, bool, *mcp.CallToolResult) {
+	// Normalizing needs the whole content in memory.
+	if opts.LocalPath == "" || !sess.IsSSH() || opts.NormalizeLineEndings || opts.StripBOM {
+		return nil, false, nil
+	}
+	info, err := s.fs.Stat(opts.LocalPath)
+	if err != nil {
+		return nil, false, mcp.NewToolResultError(fmt.Sprintf("stat local file: %v", err))
+	}
+	return info, info.Size() >= streamPutThreshold, nil
+}
+
+// handleSSHFilePutStream copies local_path to the remote over SFTP, hashing
+// it on the way through so the whole file is never held in memory.
+func (s *Server) handleSSHFilePutStream(sess *session.Session, remotePath string, opts FilePutOptions, info os.FileInfo) (*mcp.CallToolResult, error) {
+	sftpClient, err := sess.SFTPClient()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	local, err := s.fs.Open(opts.LocalPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("open local file: %v", err)), nil
+	}
+	defer local.Close()
+
+	result := FilePutResult{
+		Status:     "completed",
+		RemotePath: remotePath,
+		Mode:       fmt.Sprintf("%04o", opts.Mode),
+		Streamed:   true,
+	}
+
+	dir, errResult := prepareSSHPut(sftpClient, remotePath, opts, &result)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	h := sha256.New()
+	src := io.TeeReader(local, h)
+	errResult = writeSSHFileWith(sftpClient, remotePath, dir, opts, &result, func(path string) error {
+		n, err := sftpClient.PutFileFrom(path, src, opts.Mode)
+		result.Size = n
+		return err
+	})
+	if errResult != nil {
+		return errResult, nil
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if opts.Checksum {
+		result.Checksum = sum
+	}
+
+	slog.Info("streamed file upload",
+		slog.String("session_id", sess.ID),
+		slog.String("remote_path", remotePath),
+		slog.Int64("size", result.Size),
+	)
+	if result.Size != info.Size() {
+		slog.Warn("local file changed size during upload",
+			slog.String("local_path", opts.LocalPath),
+			slog.Int64("stat_size", info.Size()),
+			slog.Int64("uploaded", result.Size),
+		)
+	}
+
+	preserveSSHTimestamp(sftpClient, remotePath, opts.Preserve, info.ModTime())
+
+	if opts.VerifyReadback {
+		if errResult := verifySSHReadbackSum(sftpClient, remotePath, sum, &result); errResult != nil {
+			return errResult, nil
+		}
+	}
+	return jsonResult(result)
+}
@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newUninitializedSSHSession(id string) *session.Session {
+	return session.NewSession(id, "ssh",
+		session.WithPTY(fakepty.New()),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+	)
+}
+
+func TestStreamPutSource(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/tmp/small.bin", make([]byte, 1024), 0644)
+	ffs.AddFile("/tmp/large.bin", make([]byte, streamPutThreshold), 0644)
+	srv := newTestServerWithFS(fakesessionmgr.New(), ffs)
+
+	ssh := newUninitializedSSHSession("ssh1")
+	local := newLocalSession("local1")
+
+	tests := []struct {
+		name string
+		sess *session.Session
+		opts FilePutOptions
+		want bool
+	}{
+		{"large to ssh", ssh, FilePutOptions{LocalPath: "/tmp/large.bin"}, true},
+		{"small to ssh", ssh, FilePutOptions{LocalPath: "/tmp/small.bin"}, false},
+		{"large to local", local, FilePutOptions{LocalPath: "/tmp/large.bin"}, false},
+		{"content", ssh, FilePutOptions{Content: "hello"}, false},
+		{"large to ssh normalized", ssh, FilePutOptions{LocalPath: "/tmp/large.bin", NormalizeLineEndings: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, errResult := srv.streamPutSource(tt.sess, tt.opts)
+			if errResult != nil {
+				t.Fatalf("unexpected error: %s", resultText(errResult))
+			}
+			if got != tt.want {
+				t.Errorf("stream = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamPutSource_MissingLocalFile(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+
+	_, _, errResult := srv.streamPutSource(newUninitializedSSHSession("ssh1"), FilePutOptions{LocalPath: "/tmp/missing.bin"})
+	if errResult == nil || !strings.Contains(resultText(errResult), "stat local file") {
+		t.Errorf("expected stat error, got %v", errResult)
+	}
+}
+
+// noReadFileFS fails ReadFile, so a test can prove a file was never loaded
+// into memory whole.
+type noReadFileFS struct {
+	*fakefs.FS
+}
+
+func (noReadFileFS) ReadFile(name string) ([]byte, error) {
+	return nil, errors.New("ReadFile called on " + name)
+}
+
+func TestHandleShellFilePut_LargeLocalPathStreams(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/tmp/large.bin", make([]byte, streamPutThreshold), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newUninitializedSSHSession("ssh1"))
+	srv := NewServer(config.DefaultConfig(),
+		WithSessionManager(sm),
+		WithFileSystem(noReadFileFS{ffs}),
+		WithClock(fakeclock.New(time.Now())),
+	)
+
+	// The session has no SSH connection, so the upload fails at the SFTP
+	// client; the in-memory path would have failed at ReadFile first.
+	result, err := srv.handleShellFilePut(context.Background(), makeRequest(map[string]any{
+		"session_id":  "ssh1",
+		"remote_path": "/srv/large.bin",
+		"local_path":  "/tmp/large.bin",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "SFTP") {
+		t.Errorf("expected SFTP client error, got %s", resultText(result))
+	}
+}
+
+func TestCheckReadbackSum(t *testing.T) {
+	data := []byte(strings.Repeat("chunk of a large upload\n", 1000))
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	var result FilePutResult
+	if errResult := checkReadbackSum(bytes.NewReader(data), "/srv/large.bin", want, &result); errResult != nil {
+		t.Fatalf("unexpected mismatch: %s", resultText(errResult))
+	}
+	if result.Verified != verifiedSHA256 {
+		t.Errorf("Verified = %q, want sha256", result.Verified)
+	}
+
+	errResult := checkReadbackSum(bytes.NewReader(data[1:]), "/srv/large.bin", want, &result)
+	if errResult == nil || !strings.Contains(resultText(errResult), "readback_mismatch") {
+		t.Errorf("expected readback_mismatch, got %v", errResult)
+	}
+}
@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// sudoPutTimeoutMs bounds the sudo command that moves an upload into place.
+const sudoPutTimeoutMs = 30000
+
+// ownerPattern matches the owner argument: "user" or "user:group".
+var ownerPattern = regexp.MustCompile(`^[A-Za-z0-9._][A-Za-z0-9._-]*(:[A-Za-z0-9._][A-Za-z0-9._-]*)?$`)
+
+// validateSudoPut checks the options that only apply to sudo uploads.
+func validateSudoPut(opts FilePutOptions) *mcp.CallToolResult {
+	if opts.Owner != "" && !opts.Sudo {
+		return mcp.NewToolResultError("owner requires sudo=true")
+	}
+	if opts.Owner != "" && !ownerPattern.MatchString(opts.Owner) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid owner %q: use 'user' or 'user:group'", opts.Owner))
+	}
+	if opts.Sudo && opts.VerifyReadback {
+		return mcp.NewToolResultError("verify_readback cannot be combined with sudo: the written file may not be readable")
+	}
+	return nil
+}
+
+// handleFilePutSudo writes data to remotePath directly if the destination
+// allows it, and otherwise stages it in a temp file that sudo moves into
+// place. An owner always goes through sudo.
+func (s *Server) handleFilePutSudo(ctx context.Context, sessionID string, sess *session.Session, remotePath string, data []byte, opts FilePutOptions, sourceModTime time.Time) (*mcp.CallToolResult, error) {
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	result := newFilePutResult(remotePath, data, opts.Mode)
+	result.Normalized = opts.normalized
+	setPutChecksum(data, opts.Checksum, &result)
+	if _, err := ep.stat(remotePath); err == nil {
+		if !opts.Overwrite {
+			return mcp.NewToolResultError(fmt.Sprintf("file exists: %s (use overwrite=true to replace)", remotePath)), nil
+		}
+		result.Overwritten = true
+	}
+	dir := ep.dir(remotePath)
+
+	if opts.Owner == "" {
+		err := putEndpointFileAtomic(ep, remotePath, dir, data, opts)
+		if err == nil {
+			if opts.Preserve && !sourceModTime.IsZero() {
+				if err := ep.chtimes(remotePath, sourceModTime); err != nil {
+					slog.Warn(errPreserveTimestamp, slog.String("error", err.Error()))
+				}
+			}
+			result.DirsCreated = opts.CreateDirs
+			result.AtomicWrite = true
+			sudoUsed := false
+			result.SudoUsed = &sudoUsed
+			return jsonResult(result)
+		}
+		if !errors.Is(err, fs.ErrPermission) {
+			return mcp.NewToolResultError(fmt.Sprintf("upload file: %v", err)), nil
+		}
+		slog.Info("destination not writable, placing file with sudo",
+			slog.String("session_id", sessionID),
+			slog.String("remote_path", remotePath),
+		)
+	}
+
+	tempPath := path.Join(sudoTempDir(sess), ".claude-shell-mcp-put."+randomSuffix())
+	if err := writeEndpointFile(ep, tempPath, data, 0600); err != nil {
+		ep.remove(tempPath)
+		return mcp.NewToolResultError(fmt.Sprintf("write temp file: %v", err)), nil
+	}
+	defer ep.remove(tempPath)
+	preserve := opts.Preserve && !sourceModTime.IsZero() && ep.chtimes(tempPath, sourceModTime) == nil
+
+	command := sudoInstallCommand(tempPath, remotePath, dir, opts, preserve)
+	execResult, err := s.execInSession(ctx, sessionID, command, sudoPutTimeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("sudo install: %v", err)), nil
+	}
+	if execResult.Status == "awaiting_input" {
+		// No cached password answered the prompt: cancel it so the session
+		// is usable again.
+		if _, err := sess.Interrupt(); err != nil {
+			slog.Warn("interrupt sudo prompt", slog.String("error", err.Error()))
+		}
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"sudo needs a password to write %s: cache it with shell_provide_input(cache_for_sudo=true) or configure sudo_password_env, then retry; nothing was written", remotePath)), nil
+	}
+	if execResult.Status != "completed" || execResult.ExitCode == nil || *execResult.ExitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("sudo install failed (status %s): %s",
+			execResult.Status, strings.TrimSpace(execResult.Stdout))), nil
+	}
+
+	result.DirsCreated = opts.CreateDirs
+	result.AtomicWrite = true
+	sudoUsed := true
+	result.SudoUsed = &sudoUsed
+	result.Owner = opts.Owner
+	if result.Owner == "" {
+		result.Owner = "root"
+	}
+	return jsonResult(result)
+}
+
+// putEndpointFileAtomic writes data to a temp file next to remotePath and
+// renames it into place, creating dir first if opts.CreateDirs is set.
+func putEndpointFileAtomic(ep relayEndpoint, remotePath, dir string, data []byte, opts FilePutOptions) error {
+	if opts.CreateDirs {
+		if err := ep.mkdirAll(dir); err != nil {
+			return err
+		}
+	}
+	tempPath := fmt.Sprintf("%s/.%s.tmp.%s", dir, path.Base(remotePath), randomSuffix())
+	if err := writeEndpointFile(ep, tempPath, data, opts.Mode); err != nil {
+		ep.remove(tempPath)
+		return err
+	}
+	if err := ep.rename(tempPath, remotePath); err != nil {
+		ep.remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// sudoTempDir is where sudo uploads are staged before they are moved into
+// place: a directory the login user can always write to.
+func sudoTempDir(sess *session.Session) string {
+	if sess.IsSSH() {
+		return "/tmp"
+	}
+	return os.TempDir()
+}
+
+// sudoInstallCommand builds the shell command that copies tempPath to
+// remotePath as root with install(1), through a temp file in the
+// destination directory so the final rename is atomic.
+func sudoInstallCommand(tempPath, remotePath, dir string, opts FilePutOptions, preserve bool) string {
+	stage := fmt.Sprintf("%s/.%s.tmp.%s", dir, path.Base(remotePath), randomSuffix())
+	install := fmt.Sprintf("install -m %04o", opts.Mode)
+	if preserve {
+		install += " -p"
+	}
+	if opts.Owner != "" {
+		// Owner is checked against ownerPattern, so it needs no quoting.
+		user, group, hasGroup := strings.Cut(opts.Owner, ":")
+		install += " -o " + user
+		if hasGroup {
+			install += " -g " + group
+		}
+	}
+	script := fmt.Sprintf("%s -- %s %s && mv -f -- %s %s || { rm -f -- %s; exit 1; }",
+		install, shellQuote(tempPath), shellQuote(stage),
+		shellQuote(stage), shellQuote(remotePath), shellQuote(stage))
+	if opts.CreateDirs {
+		script = fmt.Sprintf("mkdir -p -- %s && %s", shellQuote(dir), script)
+	}
+	return "sudo sh -c " + shellQuote(script)
+}
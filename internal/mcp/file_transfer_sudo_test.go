@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newSudoPutServer returns a server with an initialized local session
+// "sess_sudo" whose filesystem has a read-only /etc.
+func newSudoPutServer(t *testing.T) (*Server, *fakefs.FS, *fakepty.PTY) {
+	t.Helper()
+	ffs := fakefs.New()
+	ffs.AddFile("/etc/motd", []byte("old"), 0644)
+	ffs.SetReadOnly("/etc")
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_sudo")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	return newTestServerWithFS(sm, ffs), ffs, pty
+}
+
+func sudoPutArgs(extra map[string]any) map[string]any {
+	args := map[string]any{
+		"session_id":  "sess_sudo",
+		"remote_path": "/etc/motd",
+		"content":     "welcome\n",
+		"overwrite":   true,
+		"sudo":        true,
+	}
+	for k, v := range extra {
+		args[k] = v
+	}
+	return args
+}
+
+// assertNoStagedFiles fails if a sudo upload left its temp file behind.
+func assertNoStagedFiles(t *testing.T, ffs *fakefs.FS) {
+	t.Helper()
+	for _, f := range ffs.Files() {
+		if strings.Contains(f, ".claude-shell-mcp-put.") {
+			t.Errorf("temp file %s was not removed", f)
+		}
+	}
+}
+
+func TestHandleShellFilePut_SudoNotNeeded(t *testing.T) {
+	srv, ffs, pty := newSudoPutServer(t)
+
+	result, err := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(map[string]any{
+		"remote_path": "/srv/app/motd",
+		"create_dirs": true,
+	})))
+	if err != nil || result.IsError {
+		t.Fatalf("put failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["sudo_used"] != false {
+		t.Errorf("sudo_used = %v, want false", m["sudo_used"])
+	}
+	if data, _ := ffs.ReadFile("/srv/app/motd"); string(data) != "welcome\n" {
+		t.Errorf("file = %q, want the uploaded content", data)
+	}
+	if pty.Written() != "" {
+		t.Errorf("written = %q, want no shell command", pty.Written())
+	}
+}
+
+func TestHandleShellFilePut_SudoFallback(t *testing.T) {
+	srv, ffs, pty := newSudoPutServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___0\n")
+
+	result, err := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(map[string]any{
+		"mode": "0640",
+	})))
+	if err != nil || result.IsError {
+		t.Fatalf("put failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["sudo_used"] != true || m["owner"] != "root" || m["overwritten"] != true {
+		t.Errorf("sudo_used=%v owner=%v overwritten=%v, want true/root/true", m["sudo_used"], m["owner"], m["overwritten"])
+	}
+	written := pty.Written()
+	for _, want := range []string{"sudo sh -c", "install -m 0640", "/etc/motd"} {
+		if !strings.Contains(written, want) {
+			t.Errorf("written = %q, want %q", written, want)
+		}
+	}
+	assertNoStagedFiles(t, ffs)
+}
+
+func TestHandleShellFilePut_SudoOwner(t *testing.T) {
+	srv, _, pty := newSudoPutServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___0\n")
+
+	// An owner is only possible with sudo, even where the login user can write.
+	result, _ := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(map[string]any{
+		"remote_path": "/srv/motd",
+		"owner":       "www-data:www-data",
+	})))
+	if result.IsError {
+		t.Fatalf("put failed: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["sudo_used"] != true || m["owner"] != "www-data:www-data" {
+		t.Errorf("sudo_used=%v owner=%v", m["sudo_used"], m["owner"])
+	}
+	if written := pty.Written(); !strings.Contains(written, "-o www-data -g www-data") {
+		t.Errorf("written = %q, want -o and -g", written)
+	}
+}
+
+func TestHandleShellFilePut_SudoCachedPassword(t *testing.T) {
+	srv, ffs, pty := newSudoPutServer(t)
+	srv.sudoCache.Set("sess_sudo", []byte("cachedpw"))
+	pty.AddResponse("___CMD_START_00010203___\n[sudo] password for user: ")
+	pty.AddResponse("\n___CMD_END_00010203___0\n")
+
+	result, _ := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(nil)))
+	if result.IsError {
+		t.Fatalf("put failed: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["sudo_used"] != true {
+		t.Errorf("sudo_used = %v, want true", m["sudo_used"])
+	}
+	if !strings.Contains(pty.Written(), "cachedpw") {
+		t.Error("cached sudo password was not injected")
+	}
+	assertNoStagedFiles(t, ffs)
+}
+
+func TestHandleShellFilePut_SudoNeedsPassword(t *testing.T) {
+	srv, ffs, pty := newSudoPutServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n[sudo] password for user: ")
+
+	result, _ := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(nil)))
+	if !result.IsError || !strings.Contains(resultText(result), "sudo needs a password") {
+		t.Fatalf("got %q, want a sudo password error", resultText(result))
+	}
+	if !pty.WasInterrupted() {
+		t.Error("the sudo prompt was not interrupted")
+	}
+	if data, _ := ffs.ReadFile("/etc/motd"); string(data) != "old" {
+		t.Errorf("file = %q, want it unchanged", data)
+	}
+	assertNoStagedFiles(t, ffs)
+}
+
+func TestHandleShellFilePut_SudoInstallFails(t *testing.T) {
+	srv, ffs, pty := newSudoPutServer(t)
+	pty.AddResponse("___CMD_START_00010203___\ninstall: invalid user 'nobody2'\n___CMD_END_00010203___1\n")
+
+	result, _ := srv.handleShellFilePut(context.Background(), makeRequest(sudoPutArgs(map[string]any{"owner": "nobody2"})))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid user") {
+		t.Errorf("got %q, want the install error", resultText(result))
+	}
+	assertNoStagedFiles(t, ffs)
+}
+
+func TestHandleShellFilePut_SudoValidation(t *testing.T) {
+	srv, _, _ := newSudoPutServer(t)
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{sudoPutArgs(map[string]any{"sudo": false, "owner": "root"}), "owner requires sudo"},
+		{sudoPutArgs(map[string]any{"owner": "root; rm -rf /"}), "invalid owner"},
+		{sudoPutArgs(map[string]any{"verify_readback": true}), "verify_readback cannot be combined with sudo"},
+		{sudoPutArgs(map[string]any{"overwrite": false}), "file exists"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellFilePut(context.Background(), makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
+
+func TestSudoInstallCommand(t *testing.T) {
+	cmd := sudoInstallCommand("/tmp/up", "/etc/it's.conf", "/etc", FilePutOptions{Mode: 0600, CreateDirs: true}, true)
+	for _, want := range []string{"sudo sh -c '", "mkdir -p -- ", "install -m 0600 -p -- ", "mv -f -- ", "rm -f -- "} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("command = %q, want %q", cmd, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package mcp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/acolita/claude-shell-mcp/internal/sftp"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Readback verification methods reported in FilePutResult.Verified.
+const (
+	verifiedSHA256 = "sha256"
+	verifiedSize   = "size"
+)
+
+// verifySSHReadback re-reads remotePath over SFTP and compares its SHA-256
+// with the bytes that were uploaded.
+func verifySSHReadback(client *sftp.Client, remotePath string, data []byte, result *FilePutResult) *mcp.CallToolResult {
+	want := sha256.Sum256(data)
+	return verifySSHReadbackSum(client, remotePath, hex.EncodeToString(want[:]), result)
+}
+
+// verifySSHReadbackSum is verifySSHReadback for uploads that were streamed,
+// where only the SHA-256 of the source is known.
+func verifySSHReadbackSum(client *sftp.Client, remotePath, want string, result *FilePutResult) *mcp.CallToolResult {
+	f, err := client.Open(remotePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("verify readback: open %s: %v", remotePath, err))
+	}
+	defer f.Close()
+	return checkReadbackSum(f, remotePath, want, result)
+}
+
+// checkReadback hashes r and compares it with the SHA-256 of data.
+func checkReadback(r io.Reader, path string, data []byte, result *FilePutResult) *mcp.CallToolResult {
+	want := sha256.Sum256(data)
+	return checkReadbackSum(r, path, hex.EncodeToString(want[:]), result)
+}
+
+// checkReadbackSum hashes r and compares it with the hex SHA-256 want.
+func checkReadbackSum(r io.Reader, path, want string, result *FilePutResult) *mcp.CallToolResult {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("verify readback: read %s: %v", path, err))
+	}
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if got != want {
+		return mcp.NewToolResultError(fmt.Sprintf("readback_mismatch: %s has sha256 %s after upload, source is %s",
+			path, got, want))
+	}
+	result.Verified = verifiedSHA256
+	return nil
+}
+
+// verifyLocalReadback checks a local write by comparing the file size with
+// the source. Local writes don't cross a network, so a full re-read is not
+// worth the cost.
+func (s *Server) verifyLocalReadback(path string, data []byte, result *FilePutResult) *mcp.CallToolResult {
+	info, err := s.fs.Stat(path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("verify readback: stat %s: %v", path, err))
+	}
+	if info.Size() != int64(len(data)) {
+		return mcp.NewToolResultError(fmt.Sprintf("readback_mismatch: %s is %d bytes after write, source is %d bytes",
+			path, info.Size(), len(data)))
+	}
+	result.Verified = verifiedSize
+	return nil
+}
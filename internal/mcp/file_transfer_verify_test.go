@@ -0,0 +1,75 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestCheckReadback(t *testing.T) {
+	data := []byte("release artifact v1.2.3\n")
+
+	var result FilePutResult
+	if errResult := checkReadback(bytes.NewReader(data), "/opt/app/bin", data, &result); errResult != nil {
+		t.Fatalf("unexpected mismatch: %s", resultText(errResult))
+	}
+	if result.Verified != verifiedSHA256 {
+		t.Errorf("Verified = %q, want %q", result.Verified, verifiedSHA256)
+	}
+
+	result = FilePutResult{}
+	errResult := checkReadback(strings.NewReader("release artifact v1.2.\n"), "/opt/app/bin", data, &result)
+	if errResult == nil || !strings.HasPrefix(resultText(errResult), "readback_mismatch:") {
+		t.Fatalf("expected readback_mismatch, got %v", errResult)
+	}
+	if result.Verified != "" {
+		t.Error("Verified set on mismatch")
+	}
+
+	errResult = checkReadback(iotest.ErrReader(errors.New("connection reset")), "/opt/app/bin", data, &result)
+	if errResult == nil || !strings.Contains(resultText(errResult), "connection reset") {
+		t.Errorf("expected read error, got %v", errResult)
+	}
+}
+
+func TestLocal_HandleLocalFilePut_VerifyReadback(t *testing.T) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_verify"))
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, err := srv.handleShellFilePut(context.Background(), makeRequest(map[string]any{
+		"session_id":      "sess_verify",
+		"remote_path":     "/deploy/app.conf",
+		"content":         "listen 8080\n",
+		"create_dirs":     true,
+		"verify_readback": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["verified"] != verifiedSize {
+		t.Errorf("verified = %v, want %q", m["verified"], verifiedSize)
+	}
+}
+
+func TestVerifyLocalReadback_SizeMismatch(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.AddFile("/deploy/app.conf", []byte("listen 80"), 0644)
+	srv := newTestServerWithFS(fakesessionmgr.New(), ffs)
+
+	var result FilePutResult
+	errResult := srv.verifyLocalReadback("/deploy/app.conf", []byte("listen 8080\n"), &result)
+	if errResult == nil || !strings.HasPrefix(resultText(errResult), "readback_mismatch:") {
+		t.Fatalf("expected readback_mismatch, got %v", errResult)
+	}
+}
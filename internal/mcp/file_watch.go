@@ -0,0 +1,296 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/acolita/claude-shell-mcp/internal/patch"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxFileWatches is the most files watched at once across all sessions.
+const maxFileWatches = 100
+
+func shellFileWatchTool() mcp.Tool {
+	return mcp.NewTool("shell_file_watch",
+		mcp.WithDescription(`Start watching a file for changes.
+
+Takes a snapshot of the file's content and checksum, kept by the server for
+this session and path. Call shell_file_watch_poll later to learn whether the
+file changed since and get a unified diff of the change, without fetching
+the whole file again. Useful to notice a config modified out from under you.
+
+Watching a path again replaces its snapshot. Watches end when the session
+is closed.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File to watch (relative paths use the session's cwd)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+func shellFileWatchPollTool() mcp.Tool {
+	return mcp.NewTool("shell_file_watch_poll",
+		mcp.WithDescription(`Check a file watched with shell_file_watch for changes.
+
+Re-reads the file and compares it with the snapshot: changed is true when
+the content differs, with a unified diff from the snapshot to the current
+content (omitted for binary files). deleted/created report the file
+disappearing or coming back. The current content becomes the new snapshot,
+so each poll reports the changes since the previous one.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Watched file (as given to shell_file_watch)"),
+		),
+		mcp.WithNumber("context_lines",
+			mcp.Description("Lines of context around each change in the diff (default 3)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// fileSnapshot is the state of a watched file at the last watch or poll.
+type fileSnapshot struct {
+	exists   bool
+	content  []byte
+	checksum string
+}
+
+// fileWatches holds the snapshots of watched files, keyed by session and
+// path.
+type fileWatches struct {
+	mu      sync.Mutex
+	entries map[string]fileSnapshot
+}
+
+func newFileWatches() *fileWatches {
+	return &fileWatches{entries: make(map[string]fileSnapshot)}
+}
+
+func fileWatchKey(sessionID, path string) string {
+	return sessionID + "\x00" + path
+}
+
+func (w *fileWatches) get(sessionID, path string) (fileSnapshot, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	snap, ok := w.entries[fileWatchKey(sessionID, path)]
+	return snap, ok
+}
+
+// set stores snap unless that would exceed maxFileWatches. Entries of
+// sessions for which alive returns false are dropped to make room.
+func (w *fileWatches) set(sessionID, path string, snap fileSnapshot, alive func(sessionID string) bool) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	key := fileWatchKey(sessionID, path)
+	if _, ok := w.entries[key]; !ok && len(w.entries) >= maxFileWatches {
+		for k := range w.entries {
+			if id, _, _ := strings.Cut(k, "\x00"); !alive(id) {
+				delete(w.entries, k)
+			}
+		}
+		if len(w.entries) >= maxFileWatches {
+			return false
+		}
+	}
+	w.entries[key] = snap
+	return true
+}
+
+// dropSession removes all watches of a session.
+func (w *fileWatches) dropSession(sessionID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	prefix := sessionID + "\x00"
+	for k := range w.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(w.entries, k)
+		}
+	}
+}
+
+// FileWatchResult is the result of shell_file_watch.
+type FileWatchResult struct {
+	Status   string `json:"status"`
+	Path     string `json:"path"`
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+}
+
+// FileWatchPollResult is the result of shell_file_watch_poll.
+type FileWatchPollResult struct {
+	Status           string `json:"status"`
+	Path             string `json:"path"`
+	Changed          bool   `json:"changed"`
+	Checksum         string `json:"checksum,omitempty"`
+	PreviousChecksum string `json:"previous_checksum,omitempty"`
+	Size             int64  `json:"size"`
+	Deleted          bool   `json:"deleted,omitempty"`
+	Created          bool   `json:"created,omitempty"`
+	Binary           bool   `json:"binary,omitempty"`
+	Diff             string `json:"diff,omitempty"`
+	DiffTruncated    bool   `json:"diff_truncated,omitempty"`
+	LinesAdded       int    `json:"lines_added,omitempty"`
+	LinesRemoved     int    `json:"lines_removed,omitempty"`
+}
+
+func (s *Server) handleShellFileWatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	filePath := mcp.ParseString(req, "path", "")
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if filePath == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	filePath = sess.ResolvePath(filePath)
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	snap, errResult := snapshotFile(ep, filePath)
+	if errResult != nil {
+		return errResult, nil
+	}
+	if !snap.exists {
+		return mcp.NewToolResultError(fmt.Sprintf("file not found: %s", filePath)), nil
+	}
+	if !s.fileWatches.set(sessionID, filePath, snap, s.sessionExists) {
+		return mcp.NewToolResultError(fmt.Sprintf("too many watched files (max %d): close sessions you no longer need", maxFileWatches)), nil
+	}
+
+	slog.Info("watching file",
+		slog.String("session_id", sessionID),
+		slog.String("path", filePath),
+	)
+	return jsonResult(FileWatchResult{
+		Status:   "watching",
+		Path:     filePath,
+		Checksum: snap.checksum,
+		Size:     int64(len(snap.content)),
+	})
+}
+
+func (s *Server) handleShellFileWatchPoll(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	filePath := mcp.ParseString(req, "path", "")
+	contextLines := mcp.ParseInt(req, "context_lines", 3)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if filePath == "" {
+		return mcp.NewToolResultError("path is required"), nil
+	}
+	if contextLines < 0 {
+		return mcp.NewToolResultError("context_lines must not be negative"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errResult := checkFileAccess(sess); errResult != nil {
+		return errResult, nil
+	}
+	filePath = sess.ResolvePath(filePath)
+	prev, ok := s.fileWatches.get(sessionID, filePath)
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("file is not watched: %s (start with shell_file_watch)", filePath)), nil
+	}
+	ep, err := s.relayEndpointFor(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	cur, errResult := snapshotFile(ep, filePath)
+	if errResult != nil {
+		return errResult, nil
+	}
+	s.fileWatches.set(sessionID, filePath, cur, s.sessionExists)
+
+	result := FileWatchPollResult{
+		Status:           "completed",
+		Path:             filePath,
+		Checksum:         cur.checksum,
+		PreviousChecksum: prev.checksum,
+		Size:             int64(len(cur.content)),
+		Changed:          cur.exists != prev.exists || cur.checksum != prev.checksum,
+		Deleted:          prev.exists && !cur.exists,
+		Created:          !prev.exists && cur.exists,
+	}
+	if !result.Changed || !cur.exists {
+		return jsonResult(result)
+	}
+
+	if !utf8.Valid(prev.content) || !utf8.Valid(cur.content) {
+		result.Binary = true
+		return jsonResult(result)
+	}
+	diff, stats := patch.Diff("a"+filePath, "b"+filePath, prev.content, cur.content, contextLines)
+	result.LinesAdded, result.LinesRemoved = stats.Added, stats.Removed
+	if len(diff) > maxContentSize {
+		diff = diff[:strings.LastIndexByte(diff[:maxContentSize], '\n')+1]
+		result.DiffTruncated = true
+	}
+	result.Diff = diff
+	return jsonResult(result)
+}
+
+// snapshotFile reads p for a watch. A missing file is a snapshot with
+// exists false; other failures are returned as a tool error.
+func snapshotFile(ep relayEndpoint, p string) (fileSnapshot, *mcp.CallToolResult) {
+	info, err := ep.stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fileSnapshot{}, nil
+	}
+	if err != nil {
+		return fileSnapshot{}, fileStatError(p, err)
+	}
+	if info.IsDir() {
+		return fileSnapshot{}, mcp.NewToolResultError(fmt.Sprintf("path is a directory: %s", p))
+	}
+	if info.Size() > maxPatchFileSize {
+		return fileSnapshot{}, mcp.NewToolResultError(fmt.Sprintf("file too large to watch: %d bytes (max %d)", info.Size(), maxPatchFileSize))
+	}
+	content, err := readEndpointFile(ep, p)
+	if err != nil {
+		return fileSnapshot{}, mcp.NewToolResultError(fmt.Sprintf("read file: %v", err))
+	}
+	sum := sha256.Sum256(content)
+	return fileSnapshot{exists: true, content: content, checksum: hex.EncodeToString(sum[:])}, nil
+}
+
+// sessionExists reports whether sessionID is still open.
+func (s *Server) sessionExists(sessionID string) bool {
+	_, err := s.sessionManager.Get(sessionID)
+	return err == nil
+}
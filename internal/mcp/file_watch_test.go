@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newWatchTestServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.AddFile("/etc/app.conf", []byte(patchTestConfig), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_watch"))
+	return newTestServerWithFS(sm, ffs), ffs
+}
+
+func watchFile(t *testing.T, srv *Server, path string) map[string]any {
+	t.Helper()
+	result, err := srv.handleShellFileWatch(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_watch", "path": path,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("watch failed: %v %s", err, resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func pollFile(t *testing.T, srv *Server, path string) map[string]any {
+	t.Helper()
+	result, err := srv.handleShellFileWatchPoll(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_watch", "path": path,
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("poll failed: %v %s", err, resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestHandleShellFileWatch_DetectsChange(t *testing.T) {
+	srv, ffs := newWatchTestServer()
+
+	m := watchFile(t, srv, "/etc/app.conf")
+	if m["status"] != "watching" || m["size"] != float64(len(patchTestConfig)) || m["checksum"] == "" {
+		t.Fatalf("watch result = %v", m)
+	}
+	initial := m["checksum"]
+
+	if m := pollFile(t, srv, "/etc/app.conf"); m["changed"] != false || m["diff"] != nil {
+		t.Errorf("unchanged poll = %v", m)
+	}
+
+	ffs.WriteFile("/etc/app.conf", []byte(strings.Replace(patchTestConfig, "listen 80;", "listen 8080;", 1)), 0644)
+	m = pollFile(t, srv, "/etc/app.conf")
+	if m["changed"] != true || m["previous_checksum"] != initial || m["checksum"] == initial {
+		t.Errorf("changed poll = %v", m)
+	}
+	diff, _ := m["diff"].(string)
+	if !strings.Contains(diff, "-listen 80;\n+listen 8080;\n") || !strings.HasPrefix(diff, "--- a/etc/app.conf\n+++ b/etc/app.conf\n") {
+		t.Errorf("diff = %q", diff)
+	}
+	if m["lines_added"] != float64(1) || m["lines_removed"] != float64(1) {
+		t.Errorf("lines_added=%v lines_removed=%v", m["lines_added"], m["lines_removed"])
+	}
+
+	// The poll moved the baseline forward.
+	if m := pollFile(t, srv, "/etc/app.conf"); m["changed"] != false {
+		t.Errorf("second poll = %v, want no change since the previous poll", m)
+	}
+}
+
+func TestHandleShellFileWatch_DeletedAndCreated(t *testing.T) {
+	srv, ffs := newWatchTestServer()
+	watchFile(t, srv, "/etc/app.conf")
+
+	ffs.Remove("/etc/app.conf")
+	m := pollFile(t, srv, "/etc/app.conf")
+	if m["changed"] != true || m["deleted"] != true || m["diff"] != nil {
+		t.Errorf("poll after delete = %v", m)
+	}
+
+	ffs.AddFile("/etc/app.conf", []byte("listen 443;\n"), 0644)
+	m = pollFile(t, srv, "/etc/app.conf")
+	if m["changed"] != true || m["created"] != true {
+		t.Errorf("poll after recreate = %v", m)
+	}
+	if diff, _ := m["diff"].(string); !strings.Contains(diff, "+listen 443;\n") {
+		t.Errorf("diff = %q", diff)
+	}
+}
+
+func TestHandleShellFileWatch_BinaryHasNoDiff(t *testing.T) {
+	srv, ffs := newWatchTestServer()
+	ffs.AddFile("/var/lib/app.db", []byte{0xff, 0x00, 0x01}, 0644)
+	watchFile(t, srv, "/var/lib/app.db")
+
+	ffs.WriteFile("/var/lib/app.db", []byte{0xff, 0x00, 0x02}, 0644)
+	m := pollFile(t, srv, "/var/lib/app.db")
+	if m["changed"] != true || m["binary"] != true || m["diff"] != nil {
+		t.Errorf("poll = %v", m)
+	}
+}
+
+func TestHandleShellFileWatch_Errors(t *testing.T) {
+	srv, _ := newWatchTestServer()
+	ctx := context.Background()
+
+	result, _ := srv.handleShellFileWatch(ctx, makeRequest(map[string]any{"session_id": "sess_watch", "path": "/etc/missing"}))
+	if !result.IsError || !strings.Contains(resultText(result), "file not found") {
+		t.Errorf("watch missing file = %s", resultText(result))
+	}
+
+	result, _ = srv.handleShellFileWatchPoll(ctx, makeRequest(map[string]any{"session_id": "sess_watch", "path": "/etc/app.conf"}))
+	if !result.IsError || !strings.Contains(resultText(result), "not watched") {
+		t.Errorf("poll unwatched file = %s", resultText(result))
+	}
+
+	result, _ = srv.handleShellFileWatch(ctx, makeRequest(map[string]any{"session_id": "sess_watch"}))
+	if !result.IsError || !strings.Contains(resultText(result), "path is required") {
+		t.Errorf("watch without path = %s", resultText(result))
+	}
+}
+
+func TestHandleShellFileWatch_DroppedOnSessionClose(t *testing.T) {
+	srv, _ := newWatchTestServer()
+	watchFile(t, srv, "/etc/app.conf")
+
+	result, err := srv.handleShellSessionClose(context.Background(), makeRequest(map[string]any{"session_id": "sess_watch"}))
+	if err != nil || result.IsError {
+		t.Fatalf("close failed: %v %s", err, resultText(result))
+	}
+	if _, ok := srv.fileWatches.get("sess_watch", "/etc/app.conf"); ok {
+		t.Error("watch still present after the session was closed")
+	}
+}
+
+func TestFileWatches_LimitPrunesClosedSessions(t *testing.T) {
+	w := newFileWatches()
+	alive := map[string]bool{"live": true}
+	isAlive := func(id string) bool { return alive[id] }
+
+	for i := 0; i < maxFileWatches; i++ {
+		if !w.set("live", fmt.Sprintf("/f%d", i), fileSnapshot{exists: true}, isAlive) {
+			t.Fatalf("set %d failed below the limit", i)
+		}
+	}
+	if w.set("live", "/one-more", fileSnapshot{exists: true}, isAlive) {
+		t.Error("set beyond the limit succeeded")
+	}
+	if !w.set("live", "/f0", fileSnapshot{exists: true}, isAlive) {
+		t.Error("replacing an existing watch at the limit failed")
+	}
+
+	alive["live"] = false
+	if !w.set("other", "/f", fileSnapshot{exists: true}, isAlive) {
+		t.Error("set failed although the closed session's watches could be dropped")
+	}
+	if _, ok := w.get("live", "/f1"); ok {
+		t.Error("closed session's watch was not pruned")
+	}
+}
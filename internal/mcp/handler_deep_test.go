@@ -133,10 +133,13 @@ func TestSaveOutputToFile(t *testing.T) {
 	sm := fakesessionmgr.New()
 	srv := newTestServerWithFS(sm, fs)
 
-	path, err := srv.saveOutputToFile("sess_1", "big output content")
+	path, strategy, err := srv.saveOutputToFile("sess_1", "", "big output content")
 	if err != nil {
 		t.Fatalf("saveOutputToFile error: %v", err)
 	}
+	if strategy != outputStrategySaveDir {
+		t.Errorf("strategy = %q, want %q", strategy, outputStrategySaveDir)
+	}
 	if !strings.HasPrefix(path, "/workdir/.claude-shell-mcp/") {
 		t.Errorf("path=%q, expected /workdir/.claude-shell-mcp/ prefix", path)
 	}
@@ -164,7 +167,7 @@ func TestApplyAutoTruncation_SmallOutput(t *testing.T) {
 	result := &session.ExecResult{
 		Stdout: "small output",
 	}
-	srv.applyAutoTruncation("sess_1", result)
+	srv.applyAutoTruncation("sess_1", "", result)
 
 	if result.Truncated {
 		t.Error("small output should not be truncated")
@@ -184,7 +187,7 @@ func TestApplyAutoTruncation_LargeOutput(t *testing.T) {
 	result := &session.ExecResult{
 		Stdout: largeOutput,
 	}
-	srv.applyAutoTruncation("sess_1", result)
+	srv.applyAutoTruncation("sess_1", "", result)
 
 	if !result.Truncated {
 		t.Error("large output should be truncated")
@@ -539,6 +542,41 @@ func TestHandleShellDebug_ControlExecNoCommand(t *testing.T) {
 	}
 }
 
+func TestHandleShellDebug_ExplainPrompt(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess := session.NewSession("sess_debug4", "local",
+		session.WithPTY(fakepty.New()),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellDebug(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_debug4",
+		"action":     "explain_prompt",
+		"sample":     "Do you want to continue? [Y/n] ",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	explanation, _ := resultJSON(t, result)["explanation"].(map[string]any)
+	match, _ := explanation["match"].(map[string]any)
+	if explanation["matched"] != true || match["prompt_type"] != "confirmation" || match["mask_input"] != false {
+		t.Errorf("explanation = %v, want an unmasked confirmation", explanation)
+	}
+
+	result, _ = srv.handleShellDebug(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_debug4",
+		"action":     "explain_prompt",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "sample is required") {
+		t.Errorf("got %q, want sample is required", resultText(result))
+	}
+}
+
 // ==================== handleShellSessionCreate — detailed scenarios ====================
 
 func TestHandleShellSessionCreate_SSHRateLimited(t *testing.T) {
@@ -2093,7 +2131,7 @@ func TestUpdateConfig(t *testing.T) {
 	srv.UpdateConfig(newCfg)
 
 	// Verify command filter was updated
-	allowed, _ := srv.commandFilter.IsAllowed("dangerous-cmd")
+	allowed, _ := srv.filter().IsAllowed("dangerous-cmd")
 	if allowed {
 		t.Error("expected command to be blocked after config update")
 	}
@@ -2502,6 +2540,55 @@ func TestResolveFileContent_TextContent(t *testing.T) {
 	}
 }
 
+func TestResolveFileContent_TextWithControlChars(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"nul byte", "abc\x00def", "NUL byte at byte 3"},
+		{"bell", "ding\a", "control character U+0007 at byte 4"},
+		{"invalid utf-8", "caf\xe9", "invalid UTF-8 at byte 3"},
+		{"replacement char", "caf\uFFFD", "U+FFFD replacement character at byte 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, errResult := srv.resolveFileContent(FilePutOptions{Content: tt.content, Encoding: "text"})
+			if errResult == nil {
+				t.Fatal("expected error for content that is not plain text")
+			}
+			text := resultText(errResult)
+			if !strings.Contains(text, tt.want) || !strings.Contains(text, "base64") {
+				t.Errorf("error = %q, want %q and a base64 hint", text, tt.want)
+			}
+		})
+	}
+
+	data, _, errResult := srv.resolveFileContent(FilePutOptions{Content: "a\tb\r\n\x1b[1mbold\x1b[0m\f\n", Encoding: "text"})
+	if errResult != nil {
+		t.Fatalf("plain text with tabs, CRLF and ANSI escapes rejected: %s", resultText(errResult))
+	}
+	if len(data) == 0 {
+		t.Error("no data returned")
+	}
+}
+
+func TestResolveFileContent_AllowControlChars(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Transfer.AllowControlChars = true
+	srv := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), cfg)
+
+	data, _, errResult := srv.resolveFileContent(FilePutOptions{Content: "abc\x00def", Encoding: "text"})
+	if errResult != nil {
+		t.Fatalf("unexpected error with allow_control_chars: %s", resultText(errResult))
+	}
+	if string(data) != "abc\x00def" {
+		t.Errorf("data = %q", data)
+	}
+}
+
 func TestResolveFileContent_FromLocalFile(t *testing.T) {
 	fs := fakefs.New()
 	fs.AddFile("/source/file.txt", []byte("from disk"), 0644)
@@ -3022,7 +3109,7 @@ func TestTryCachedSudoInjection_NotAwaitingInput(t *testing.T) {
 	)
 
 	input := &session.ExecResult{Status: "completed", Stdout: "done"}
-	result, err := srv.tryCachedSudoInjection("sess_sudo1", sess, input)
+	result, err := srv.tryCachedSudoInjection("sess_sudo1", sess, input, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -3043,7 +3130,7 @@ func TestTryCachedSudoInjection_NotPasswordPrompt(t *testing.T) {
 	)
 
 	input := &session.ExecResult{Status: "awaiting_input", PromptType: "confirmation"}
-	result, err := srv.tryCachedSudoInjection("sess_sudo2", sess, input)
+	result, err := srv.tryCachedSudoInjection("sess_sudo2", sess, input, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -3067,7 +3154,7 @@ func TestTryCachedSudoInjection_NoPasswordAvailable(t *testing.T) {
 	)
 
 	input := &session.ExecResult{Status: "awaiting_input", PromptType: "password"}
-	result, err := srv.tryCachedSudoInjection("sess_sudo3", sess, input)
+	result, err := srv.tryCachedSudoInjection("sess_sudo3", sess, input, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
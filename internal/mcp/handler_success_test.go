@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -235,6 +236,264 @@ func TestHandleShellExec_NonZeroExitCode(t *testing.T) {
 	}
 }
 
+func TestHandleShellExec_Quiet(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_quiet")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	cmdID := "00010203"
+	big := strings.Repeat("noise line\n", saveToFileThreshold/10)
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n" + big + "___CMD_END_" + cmdID + "___1\n")
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_quiet",
+		"command":    "test -f /etc/missing",
+		"quiet":      true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "completed" {
+		t.Errorf("status = %v, want completed", m["status"])
+	}
+	if exitCode, ok := m["exit_code"].(float64); !ok || exitCode != 1 {
+		t.Errorf("exit_code = %v, want 1", m["exit_code"])
+	}
+	if _, ok := m["stdout"]; ok {
+		t.Errorf("stdout = %q, want omitted", m["stdout"])
+	}
+	if _, ok := m["output_file"]; ok {
+		t.Error("quiet output should not be auto-saved to a file")
+	}
+}
+
+func TestHandleShellExec_QuietWithTailLines(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, _ := newFakeSessionWithRand("sess_quiet")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_quiet",
+		"command":    "ls",
+		"quiet":      true,
+		"tail_lines": float64(5),
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "quiet") {
+		t.Errorf("expected quiet/tail_lines conflict error, got %q", resultText(result))
+	}
+}
+
+func TestHandleShellExec_OkExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		okCodes  any
+		exitCode int
+		want     bool
+	}{
+		{"default success", nil, 0, true},
+		{"default failure", nil, 1, false},
+		{"grep no match", []any{float64(0), float64(1)}, 1, true},
+		{"grep error", []any{float64(0), float64(1)}, 2, false},
+		{"json string", "[0, 3]", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := fakesessionmgr.New()
+			sess, pty := newFakeSessionWithRand("sess_ok")
+			if err := sess.Initialize(); err != nil {
+				t.Fatalf("Initialize error: %v", err)
+			}
+			sm.AddSession(sess)
+			srv := newTestServer(sm)
+			pty.AddResponse(fmt.Sprintf("___CMD_START_00010203___\n___CMD_END_00010203___%d\n", tt.exitCode))
+
+			args := map[string]any{"session_id": "sess_ok", "command": "grep -q needle haystack"}
+			if tt.okCodes != nil {
+				args["ok_exit_codes"] = tt.okCodes
+			}
+			result, _ := srv.handleShellExec(context.Background(), makeRequest(args))
+			if result.IsError {
+				t.Fatalf("unexpected error result: %s", resultText(result))
+			}
+			if m := resultJSON(t, result); m["success"] != tt.want {
+				t.Errorf("success = %v, want %v", m["success"], tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleShellExec_InvalidOkExitCodes(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, _ := newFakeSessionWithRand("sess_ok")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	for _, codes := range []any{[]any{float64(256)}, []any{"zero"}, "nope"} {
+		result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+			"session_id":    "sess_ok",
+			"command":       "true",
+			"ok_exit_codes": codes,
+		}))
+		if !result.IsError || !strings.Contains(resultText(result), "ok_exit_codes") {
+			t.Errorf("ok_exit_codes=%v: got %q, want an ok_exit_codes error", codes, resultText(result))
+		}
+	}
+}
+
+func TestHandleShellExec_Shell(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_shell")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	cmdID := "00010203"
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n5.9\n___CMD_END_" + cmdID + "___0\n")
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_shell",
+		"command":    "echo $ZSH_VERSION",
+		"shell":      "zsh",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["stdout"] != "5.9" {
+		t.Errorf("stdout = %v, want 5.9", m["stdout"])
+	}
+	if !strings.Contains(pty.Written(), "zsh -c") {
+		t.Errorf("written = %q, want command run through zsh -c", pty.Written())
+	}
+}
+
+func TestHandleShellExec_CollapseProgress(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_progress")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	cmdID := "00010203"
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n 10%\r 60%\r100%\r\ndone\n___CMD_END_" + cmdID + "___0\n")
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id":        "sess_progress",
+		"command":           "pip install pkg",
+		"collapse_progress": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := resultJSON(t, result); m["stdout"] != "100%\ndone" {
+		t.Errorf("stdout = %q, want collapsed progress", m["stdout"])
+	}
+}
+
+func TestHandleShellExec_KillOnTimeout(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_hang")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	// The shell answers the check that it is back at its prompt.
+	pty.SetResponder(func(written string) string {
+		if marker, ok := strings.CutPrefix(strings.TrimSpace(written), "echo ___ALIVE_"); ok {
+			return "___ALIVE_" + marker + "\n$ "
+		}
+		return ""
+	})
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id":      "sess_hang",
+		"command":         "trap '' INT; sleep 1000",
+		"timeout_ms":      50,
+		"kill_on_timeout": true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m := resultJSON(t, result); m["status"] != "timeout_killed" {
+		t.Errorf("status = %v, want timeout_killed", m["status"])
+	}
+	if !pty.WasInterrupted() {
+		t.Error("expected the command to be interrupted")
+	}
+}
+
+// The shell option must not get a command past the filter or read-only
+// mode, whether as shell syntax or as another interpreter's code.
+func TestHandleShellExec_ShellStillFiltered(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		command  string
+		shell    string
+		want     string
+	}{
+		{"blocked command", false, "rm -rf /tmp/stuff", "bash", "command blocked"},
+		{"blocked shell", false, "ls", "/bin/zsh", "command blocked"},
+		{"interpreter", false, `import os; os.system("rm -rf /tmp/stuff")`, "/usr/bin/python3", "invalid shell"},
+		{"read-only", true, "touch /tmp/stuff", "sh", "read_only"},
+		{"read-only interpreter", true, `unlink "/tmp/stuff"`, "/usr/bin/perl", "invalid shell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sm := fakesessionmgr.New()
+			sess, pty := newFakeSessionWithRand("sess_shell")
+			sm.AddSession(sess)
+			cfg := config.DefaultConfig()
+			cfg.Security.CommandBlocklist = []string{`^rm\s`, `zsh -c`}
+			cfg.Security.ReadOnly = tt.readOnly
+			srv := newTestServerWithConfig(sm, fakefs.New(), cfg)
+
+			result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+				"session_id": "sess_shell",
+				"command":    tt.command,
+				"shell":      tt.shell,
+			}))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want an error containing %q", resultText(result), tt.want)
+			}
+			if pty.Written() != "" {
+				t.Errorf("written = %q, want nothing run", pty.Written())
+			}
+		})
+	}
+}
+
+func TestHandleShellExec_InvalidShell(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, _ := newFakeSessionWithRand("sess_shell")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_shell",
+		"command":    "ls",
+		"shell":      "bash; rm -rf /",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "invalid shell") {
+		t.Errorf("expected invalid shell error, got %q", resultText(result))
+	}
+}
+
 // ==================== handleShellProvideInput success paths ====================
 
 func TestHandleShellProvideInput_SuccessPath(t *testing.T) {
@@ -491,6 +750,40 @@ func TestHandleShellInterrupt_SuccessFromAwaitingInput(t *testing.T) {
 	}
 }
 
+func TestHandleShellInterrupt_ReturnsCleanupOutput(t *testing.T) {
+	sm := fakesessionmgr.New()
+	pty := fakepty.New().SetInterruptResponse("^C\r\nCaught SIGINT, removing lock file\r\n$ ")
+	sess := session.NewSession("sess_int_cleanup", "local",
+		session.WithPTY(pty),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+		session.WithConfig(config.DefaultConfig()),
+	)
+	sess.State = session.StateRunning
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellInterrupt(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_int_cleanup",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "interrupted" || m["prompt_returned"] != true {
+		t.Errorf("status = %v, prompt_returned = %v", m["status"], m["prompt_returned"])
+	}
+	if m["output"] != "^C\nCaught SIGINT, removing lock file" {
+		t.Errorf("output = %q, want the cleanup message", m["output"])
+	}
+	if m["grace_period_ms"] != float64(config.DefaultInterruptGracePeriod.Milliseconds()) {
+		t.Errorf("grace_period_ms = %v", m["grace_period_ms"])
+	}
+}
+
 // ==================== handleShellSessionStatus success paths ====================
 
 func TestHandleShellSessionStatus_SuccessPath(t *testing.T) {
@@ -700,7 +993,7 @@ func TestTryCachedSudoInjection_WithCachedPassword(t *testing.T) {
 		MaskInput:  true,
 	}
 
-	newResult, err := srv.tryCachedSudoInjection("sess_inject", sess, inputResult)
+	newResult, err := srv.tryCachedSudoInjection("sess_inject", sess, inputResult, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -742,7 +1035,7 @@ func TestTryCachedSudoInjection_WithConfigPassword(t *testing.T) {
 		MaskInput:  true,
 	}
 
-	newResult, err := srv.tryCachedSudoInjection("sess_inject_cfg", sess, inputResult)
+	newResult, err := srv.tryCachedSudoInjection("sess_inject_cfg", sess, inputResult, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -767,7 +1060,7 @@ func TestTryCachedSudoInjection_NonPasswordPromptPassthrough(t *testing.T) {
 		PromptText: "Do you want to continue? [Y/n]",
 	}
 
-	newResult, err := srv.tryCachedSudoInjection("sess_inject_nonpw", sess, inputResult)
+	newResult, err := srv.tryCachedSudoInjection("sess_inject_nonpw", sess, inputResult, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -796,7 +1089,7 @@ func TestTryCachedSudoInjection_CompletedStatusPassthrough(t *testing.T) {
 		Stdout:   "all done",
 	}
 
-	newResult, err := srv.tryCachedSudoInjection("sess_inject_done", sess, inputResult)
+	newResult, err := srv.tryCachedSudoInjection("sess_inject_done", sess, inputResult, sudoUnchecked)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -828,3 +1121,33 @@ func TestValidateSSHParams_RateLimitTriggered(t *testing.T) {
 		t.Errorf("error should mention authentication locked, got: %s", text)
 	}
 }
+
+func TestHandleShellExec_AutoReconnectOnLocalSession(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_auto")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	pty.AddResponse("___CMD_START_00010203___\nup\n___CMD_END_00010203___0\n")
+
+	result, err := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id":     "sess_auto",
+		"command":        "uptime",
+		"auto_reconnect": true,
+		"idempotent":     true,
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "completed" {
+		t.Errorf("status = %v, want completed", m["status"])
+	}
+	if _, ok := m["replayed"]; ok {
+		t.Error("replayed should be omitted when no reconnect happened")
+	}
+}
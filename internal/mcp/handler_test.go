@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
@@ -150,6 +151,287 @@ func TestHandleShellSessionCreate_Success(t *testing.T) {
 	}
 }
 
+func TestHandleShellSessionCreate_Captured(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess := newFakeSession("sess_captured")
+	sess.Cwd = "/srv/app"
+	sess.EnvVars = map[string]string{"LANG": "C.UTF-8", "HOME": "/root"}
+	sess.Captured = []string{"env", "cwd"}
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		return sess, nil
+	}
+	srv := newTestServer(sm)
+
+	m := resultJSON(t, mustCallCreate(t, srv, map[string]any{"mode": "local"}))
+	if got, _ := json.Marshal(m["captured"]); string(got) != `["env","cwd"]` {
+		t.Errorf("captured = %s, want [env cwd]", got)
+	}
+	if m["cwd"] != "/srv/app" || m["env_var_count"] != float64(2) {
+		t.Errorf("cwd=%v env_var_count=%v", m["cwd"], m["env_var_count"])
+	}
+	if _, ok := m["alias_count"]; ok {
+		t.Error("alias_count reported without an alias capture")
+	}
+
+	sess.Captured = nil
+	m = resultJSON(t, mustCallCreate(t, srv, map[string]any{"mode": "local"}))
+	if got, _ := json.Marshal(m["captured"]); string(got) != `[]` {
+		t.Errorf("captured = %s, want []", got)
+	}
+	if _, ok := m["cwd"]; ok {
+		t.Error("cwd reported without a cwd capture")
+	}
+}
+
+// mustCallCreate calls shell_session_create and fails on an error result.
+func mustCallCreate(t *testing.T, srv *Server, args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	result, err := srv.handleShellSessionCreate(context.Background(), makeRequest(args))
+	if err != nil || result.IsError {
+		t.Fatalf("create failed: %v %s", err, resultText(result))
+	}
+	return result
+}
+
+func TestHandleShellSessionCreate_ForwardX11(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_x11"), nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":        "ssh",
+		"host":        "gui.example.com",
+		"user":        "dev",
+		"forward_x11": true,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if !got.ForwardX11 {
+		t.Error("CreateOptions.ForwardX11 = false")
+	}
+
+	result, _ = srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":        "local",
+		"forward_x11": true,
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "only supported in ssh mode") {
+		t.Errorf("local forward_x11 = %s, want ssh-only error", resultText(result))
+	}
+}
+
+func TestHandleShellSessionCreate_DisablePagers(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_pagers"), nil
+	}
+	srv := newTestServer(sm)
+
+	srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{"mode": "local"}))
+	if got.KeepPagers {
+		t.Error("pagers kept by default")
+	}
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":           "local",
+		"disable_pagers": false,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if !got.KeepPagers {
+		t.Error("CreateOptions.KeepPagers = false with disable_pagers=false")
+	}
+}
+
+func TestHandleShellSessionCreate_Cwd(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		if opts.Cwd == "/nope" {
+			return nil, fmt.Errorf("initialize session: %w: cannot change to /nope", session.ErrInvalidStartDir)
+		}
+		return newFakeSession("sess_cwd"), nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "local",
+		"cwd":  "~/src",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got.Cwd != "~/src" {
+		t.Errorf("CreateOptions.Cwd = %q, want ~/src", got.Cwd)
+	}
+
+	result, _ = srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "local",
+		"cwd":  "/nope",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid_start_dir") {
+		t.Errorf("result = %s, want invalid_start_dir", resultText(result))
+	}
+}
+
+func TestHandleShellSessionCreate_CommandMode(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		sess := newFakeSession("sess_cmd")
+		sess.Mode = opts.Mode
+		sess.Command = opts.Command
+		sess.Shell = "/bin/bash"
+		return sess, nil
+	}
+	cfg := config.DefaultConfig()
+	cfg.Security.CommandBlocklist = []string{"nsenter"}
+	srv := newTestServerWithConfig(sm, fakefs.New(), cfg)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":    "command",
+		"command": "docker exec -it web bash",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got.Mode != "command" || got.Command != "docker exec -it web bash" {
+		t.Errorf("CreateOptions = mode %q, command %q", got.Mode, got.Command)
+	}
+	if m := resultJSON(t, result); m["command"] != "docker exec -it web bash" || m["shell"] != "/bin/bash" {
+		t.Errorf("result = %v", m)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing command", map[string]any{"mode": "command"}, "command is required"},
+		{"command outside command mode", map[string]any{"mode": "local", "command": "bash"}, "only supported in command mode"},
+		{"blocked command", map[string]any{"mode": "command", "command": "nsenter -t 1 -a bash"}, "command blocked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(tt.args))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRecoveredCommand(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Security.CommandBlocklist = []string{"nsenter"}
+	srv := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), cfg)
+
+	if err := srv.checkRecoveredCommand("docker exec -it web bash"); err != nil {
+		t.Errorf("allowed command: %v", err)
+	}
+	if err := srv.checkRecoveredCommand("nsenter -t 1 -a bash"); err == nil || !strings.Contains(err.Error(), "command blocked") {
+		t.Errorf("blocked command: error = %v, want command blocked", err)
+	}
+}
+
+func TestHandleShellSessionCreate_RawMode(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_raw"), nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":     "local",
+		"raw_mode": true,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if !got.RawMode {
+		t.Error("CreateOptions.RawMode = false, want true")
+	}
+	if m := resultJSON(t, result); m["raw_mode"] != true {
+		t.Errorf("raw_mode = %v, want true", m["raw_mode"])
+	}
+}
+
+func TestHandleShellSessionCreate_Container(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		sess := newFakeSession("sess_ctr")
+		sess.Cwd = "/srv/app"
+		return sess, nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":              "local",
+		"container":         "web-1",
+		"container_runtime": "podman",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if got.Container != "web-1" || got.ContainerRuntime != "podman" {
+		t.Errorf("CreateOptions container = %q/%q, want web-1/podman", got.Container, got.ContainerRuntime)
+	}
+	if m := resultJSON(t, result); m["container"] != "web-1" || m["cwd"] != "/srv/app" {
+		t.Errorf("container = %v, cwd = %v; want web-1 and the container cwd", m["container"], m["cwd"])
+	}
+}
+
+func TestHandleShellSessionCreate_Proxy(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		sess := newFakeSession("sess_proxy")
+		sess.Proxy = "socks5://proxy.corp:1080"
+		return sess, nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh",
+		"host": "db.internal",
+		"user": "dba",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["proxy"] != "socks5://proxy.corp:1080" {
+		t.Errorf("proxy = %v, want the proxy the session connected through", m["proxy"])
+	}
+}
+
+func TestFileTools_RejectCommandMode(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess := newFakeSession("sess_cmd")
+	sess.Mode = "command"
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_cmd",
+		"remote_path": "/etc/hostname",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "not supported for command mode") {
+		t.Errorf("result = %s, want command mode error", resultText(result))
+	}
+}
+
 // --- handleShellSessionList ---
 
 func TestHandleShellSessionList_Empty(t *testing.T) {
@@ -796,21 +1078,6 @@ func TestHandleShellFilePutChunked_MissingSessionID(t *testing.T) {
 	}
 }
 
-func TestHandleShellTransferStatus_MissingManifestPath(t *testing.T) {
-	sm := fakesessionmgr.New()
-	srv := newTestServer(sm)
-
-	req := makeRequest(map[string]any{})
-
-	result, err := srv.handleShellTransferStatus(context.Background(), req)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if !result.IsError {
-		t.Error("expected error for missing manifest_path")
-	}
-}
-
 func TestHandleShellTransferResume_MissingSessionID(t *testing.T) {
 	sm := fakesessionmgr.New()
 	srv := newTestServer(sm)
@@ -861,3 +1128,23 @@ func TestHandleShellDebug_SessionNotFound(t *testing.T) {
 		t.Error("expected error for nonexistent session")
 	}
 }
+
+func TestHandleShellSessionList_IncludesPoolStats(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.Pool = session.ConnectionPoolStats{Connections: 1, Sessions: 3, Reuses: 2}
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellSessionList(context.Background(), makeRequest(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := resultJSON(t, result)
+	pool, ok := m["pool"].(map[string]any)
+	if !ok {
+		t.Fatalf("pool missing from result: %v", m)
+	}
+	if pool["connections"] != float64(1) || pool["sessions"] != float64(3) || pool["reuses"] != float64(2) {
+		t.Errorf("pool = %v, want connections=1 sessions=3 reuses=2", pool)
+	}
+}
@@ -492,6 +492,47 @@ func TestHelperSetContentWithEncoding(t *testing.T) {
 			t.Errorf("Encoding = %q, want 'text' for non-compressed", result.Encoding)
 		}
 	})
+
+	t.Run("text falls back to base64 for binary data", func(t *testing.T) {
+		result := &FileGetResult{}
+		data := []byte("ELF\x00\x01header")
+		setContentWithEncoding(data, "a.out", FileGetOptions{Encoding: "text"}, result)
+		if result.Encoding != "base64" || !result.AutoBase64 {
+			t.Errorf("Encoding = %q, AutoBase64 = %v; want base64 fallback", result.Encoding, result.AutoBase64)
+		}
+		if result.Content != base64.StdEncoding.EncodeToString(data) {
+			t.Errorf("Content = %q, want base64 of the data", result.Content)
+		}
+	})
+
+	t.Run("invalid utf-8 above threshold falls back", func(t *testing.T) {
+		result := &FileGetResult{}
+		data := []byte("caf\xe9 cr\xe8me")
+		setContentWithEncoding(data, "menu.txt", FileGetOptions{Encoding: "text", TextInvalidThreshold: 0.1}, result)
+		if !result.AutoBase64 {
+			t.Errorf("AutoBase64 = false for %d invalid bytes in %d", 2, len(data))
+		}
+	})
+
+	t.Run("invalid utf-8 within threshold is replaced", func(t *testing.T) {
+		result := &FileGetResult{}
+		data := []byte("caf\xe9 cr\xe8me")
+		setContentWithEncoding(data, "menu.txt", FileGetOptions{Encoding: "text", TextInvalidThreshold: 0.2}, result)
+		if result.Encoding != "text" || result.AutoBase64 {
+			t.Fatalf("Encoding = %q, AutoBase64 = %v; want text", result.Encoding, result.AutoBase64)
+		}
+		if result.Content != "caf\uFFFD cr\uFFFDme" || result.InvalidBytes != 2 {
+			t.Errorf("Content = %q, InvalidBytes = %d", result.Content, result.InvalidBytes)
+		}
+	})
+
+	t.Run("valid utf-8 stays text", func(t *testing.T) {
+		result := &FileGetResult{}
+		setContentWithEncoding([]byte("naïve — ok"), "a.txt", FileGetOptions{Encoding: "text"}, result)
+		if result.Encoding != "text" || result.AutoBase64 || result.InvalidBytes != 0 {
+			t.Errorf("result = %+v, want plain text", result)
+		}
+	})
 }
 
 // --- newFilePutResult ---
@@ -900,10 +941,13 @@ func TestHelperSaveOutputToFile(t *testing.T) {
 	cfg := config.DefaultConfig()
 	srv := NewServer(cfg, WithFileSystem(fakeFS), WithClock(fc))
 
-	path, err := srv.saveOutputToFile("sess_abc", "big output content")
+	path, strategy, err := srv.saveOutputToFile("sess_abc", "", "big output content")
 	if err != nil {
 		t.Fatalf("saveOutputToFile: %v", err)
 	}
+	if strategy != outputStrategySaveDir {
+		t.Errorf("strategy = %q, want %q", strategy, outputStrategySaveDir)
+	}
 
 	// Verify path format
 	expectedPrefix := "/workspace/.claude-shell-mcp/sess_abc_"
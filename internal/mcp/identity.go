@@ -0,0 +1,33 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resolveIdentity checks the identity argument against the configured
+// server, named if srv is set, else found by host, and returns the user to
+// log in as: user, else the identity's.
+func (s *Server) resolveIdentity(mode, host, user, identity string, srv *config.ServerConfig) (string, *mcp.CallToolResult) {
+	if mode != "ssh" {
+		return "", mcp.NewToolResultError("identity is only supported in ssh mode")
+	}
+	if srv == nil {
+		srv = s.lookupServer(host)
+	}
+	if srv == nil {
+		return "", mcp.NewToolResultError(fmt.Sprintf("identity %q: host %q is not a configured server", identity, host))
+	}
+	id, ok := srv.Identity(identity)
+	if !ok {
+		_, err := srv.AuthFor(identity)
+		return "", mcp.NewToolResultError(err.Error())
+	}
+	if user == "" {
+		user = id.User
+	}
+	return user, nil
+}
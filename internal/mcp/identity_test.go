@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newIdentityServer returns a server with "web" configured with a personal
+// and a deploy identity. got receives the options of created sessions.
+func newIdentityServer() (*Server, *session.CreateOptions) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{
+		Name: "web", Host: "web.example.com", User: "alice",
+		Identities: []config.IdentityConfig{
+			{Name: "personal", AuthConfig: config.AuthConfig{Path: "~/.ssh/id_ed25519"}},
+			{Name: "deploy", User: "deploy", AuthConfig: config.AuthConfig{Path: "~/.ssh/deploy_key"}},
+		},
+	}}
+	return newTestServerWithConfig(sm, fakefs.New(), cfg), &got
+}
+
+func TestHandleShellSessionCreate_Identity(t *testing.T) {
+	srv, got := newIdentityServer()
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh", "host": "web", "identity": "deploy",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if got.Identity != "deploy" || got.User != "deploy" {
+		t.Errorf("Identity = %q, User = %q; want deploy with the identity's user", got.Identity, got.User)
+	}
+	if m := resultJSON(t, result); m["identity"] != "deploy" || m["user"] != "deploy" {
+		t.Errorf("result = %v, want identity and user", m)
+	}
+
+	// An explicit user wins over the identity's.
+	srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh", "host": "web.example.com", "user": "root", "identity": "deploy",
+	}))
+	if got.User != "root" {
+		t.Errorf("User = %q, want the explicit user", got.User)
+	}
+}
+
+func TestHandleShellSessionCreate_IdentityErrors(t *testing.T) {
+	srv, _ := newIdentityServer()
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"mode": "local", "identity": "deploy"}, "only supported in ssh mode"},
+		{map[string]any{"mode": "ssh", "host": "db.example.com", "user": "x", "identity": "deploy"}, "not a configured server"},
+		{map[string]any{"mode": "ssh", "host": "web", "user": "x", "identity": "ops"}, "available: personal, deploy"},
+		{map[string]any{"mode": "ssh", "host": "web", "identity": "personal"}, "user is required"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("args %v: result = %s, want an error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
+
+func TestHandleShellServerList_Identities(t *testing.T) {
+	srv, _ := newIdentityServer()
+
+	result, _ := srv.handleShellServerList(context.Background(), makeRequest(nil))
+	servers := resultJSON(t, result)["servers"].([]any)
+	ids := servers[0].(map[string]any)["identities"].([]any)
+	if len(ids) != 2 || ids[0] != "personal" || ids[1] != "deploy" {
+		t.Errorf("identities = %v, want [personal deploy]", ids)
+	}
+	if strings.Contains(resultText(result), "deploy_key") {
+		t.Errorf("server list shows identity credentials: %s", resultText(result))
+	}
+}
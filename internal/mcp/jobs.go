@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const jobsTimeoutMs = 5000
+
+// registerJobTools registers the shell job control tools.
+func (s *Server) registerJobTools() {
+	s.mcpServer.AddTool(shellJobsTool(), s.handleShellJobs)
+	s.mcpServer.AddTool(shellJobKillTool(), s.handleShellJobKill)
+}
+
+func shellJobsTool() mcp.Tool {
+	return mcp.NewTool("shell_jobs",
+		mcp.WithDescription(`List the background jobs of the session's shell.
+
+Runs "jobs -l" in the session's shell and returns one entry per job with
+job_id (the N in %N), pid, state ("running", "stopped", "done", "exit 1",
+...) and command. These are jobs of the shell itself, e.g. started with
+shell_send_raw "cmd &"; a shell_exec command runs in a child shell whose
+jobs end with it. Use shell_job_kill to signal a job.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		readOnlyTool(),
+	)
+}
+
+func shellJobKillTool() mcp.Tool {
+	return mcp.NewTool("shell_job_kill",
+		mcp.WithDescription(`Send a signal to a job of the session's shell with "kill %N".
+
+job_id is the number from shell_jobs. The signal defaults to TERM; use CONT
+to resume a stopped job or KILL for one that ignores TERM.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithNumber("job_id",
+			mcp.Required(),
+			mcp.Description("Job number as listed by shell_jobs"),
+		),
+		mcp.WithString("signal",
+			mcp.Description("Signal name or number, e.g. TERM, INT, KILL, HUP, CONT, 9 (default: TERM)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// JobEntry is one job in the shell's job table.
+type JobEntry struct {
+	JobID   int    `json:"job_id"`
+	PID     int    `json:"pid"`
+	State   string `json:"state"`
+	Command string `json:"command"`
+}
+
+// JobsResult is the result of shell_jobs.
+type JobsResult struct {
+	Status string     `json:"status"`
+	Count  int        `json:"count"`
+	Jobs   []JobEntry `json:"jobs"`
+}
+
+// JobKillResult is the result of shell_job_kill.
+type JobKillResult struct {
+	Status string `json:"status"`
+	JobID  int    `json:"job_id"`
+	Signal string `json:"signal"`
+}
+
+func (s *Server) handleShellJobs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	out, exitCode, err := execInShell(sess, "jobs -l")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("list jobs: %v", err)), nil
+	}
+	if exitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("list jobs: %s", strings.TrimSpace(out))), nil
+	}
+
+	jobs := parseJobs(out)
+	return jsonResult(JobsResult{Status: "completed", Count: len(jobs), Jobs: jobs})
+}
+
+// signalPattern matches signal names and numbers accepted by kill -s.
+var signalPattern = regexp.MustCompile(`^([A-Z][A-Z0-9+-]*|[0-9]+)$`)
+
+func (s *Server) handleShellJobKill(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_job_kill"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	jobID := mcp.ParseInt(req, "job_id", 0)
+	signal := strings.TrimPrefix(strings.ToUpper(mcp.ParseString(req, "signal", "TERM")), "SIG")
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if jobID <= 0 {
+		return mcp.NewToolResultError("job_id must be a positive job number from shell_jobs"), nil
+	}
+	if !signalPattern.MatchString(signal) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid signal %q", signal)), nil
+	}
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	slog.Info("signaling job",
+		slog.String("session_id", sessionID),
+		slog.Int("job_id", jobID),
+		slog.String("signal", signal),
+	)
+
+	out, exitCode, err := execInShell(sess, fmt.Sprintf("kill -s %s %%%d", signal, jobID))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("kill job: %v", err)), nil
+	}
+	if exitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("kill job %%%d: %s", jobID, strings.TrimSpace(out))), nil
+	}
+	return jsonResult(JobKillResult{Status: "sent", JobID: jobID, Signal: signal})
+}
+
+// execInShell runs a builtin in the session's shell itself (not a child
+// shell) and returns its output and exit code.
+func execInShell(sess *session.Session, command string) (string, int, error) {
+	res, err := sess.ExecWithOptions(command, jobsTimeoutMs, session.ExecOptions{Direct: true})
+	if err != nil {
+		return "", 0, err
+	}
+	if res.Status != "completed" || res.ExitCode == nil {
+		return "", 0, fmt.Errorf("command did not complete (status: %s)", res.Status)
+	}
+	return res.Stdout, *res.ExitCode, nil
+}
+
+var (
+	// jobLinePattern matches "[1]+ 4242 Running   sleep 100 &" (bash) and
+	// "[1]  + 4242 running    sleep 100" (zsh).
+	jobLinePattern = regexp.MustCompile(`^\[(\d+)\]\s*[+-]?\s+(\d+)\s+(.*)$`)
+	// jobPipePattern matches the following lines of a pipeline job in bash:
+	// "     4243                       | wc -l &".
+	jobPipePattern = regexp.MustCompile(`^\s+\d+\s+(\|.*)$`)
+	// jobStatePattern splits the padded state column from the command.
+	jobStatePattern = regexp.MustCompile(`^(\S.*?)\s{2,}(\S.*)$`)
+)
+
+// parseJobs parses the output of jobs -l. Unrecognized lines are skipped.
+func parseJobs(output string) []JobEntry {
+	jobs := make([]JobEntry, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := jobLinePattern.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.Atoi(m[1])
+			pid, _ := strconv.Atoi(m[2])
+			state, command := m[3], ""
+			if sm := jobStatePattern.FindStringSubmatch(m[3]); sm != nil {
+				state, command = sm[1], sm[2]
+			} else if before, after, ok := strings.Cut(m[3], " "); ok {
+				state, command = before, after
+			}
+			jobs = append(jobs, JobEntry{
+				JobID:   id,
+				PID:     pid,
+				State:   strings.ToLower(state),
+				Command: trimJobCommand(command),
+			})
+			continue
+		}
+		if m := jobPipePattern.FindStringSubmatch(line); m != nil && len(jobs) > 0 {
+			last := &jobs[len(jobs)-1]
+			last.Command = trimJobCommand(last.Command + " " + m[1])
+		}
+	}
+	return jobs
+}
+
+func trimJobCommand(command string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(command), "&"))
+}
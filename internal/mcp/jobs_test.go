@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestParseJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []JobEntry
+	}{
+		{
+			name: "bash",
+			output: "[1]  4242 Running                 sleep 100 &\n" +
+				"[2]- 4250 Stopped                 vim notes.txt\n" +
+				"[3]+ 4260 Exit 1                  false\n",
+			want: []JobEntry{
+				{JobID: 1, PID: 4242, State: "running", Command: "sleep 100"},
+				{JobID: 2, PID: 4250, State: "stopped", Command: "vim notes.txt"},
+				{JobID: 3, PID: 4260, State: "exit 1", Command: "false"},
+			},
+		},
+		{
+			name: "bash pipeline",
+			output: "[1]+ 4242 Running                 tail -f app.log\n" +
+				"     4243                       | grep ERROR &\n",
+			want: []JobEntry{
+				{JobID: 1, PID: 4242, State: "running", Command: "tail -f app.log | grep ERROR"},
+			},
+		},
+		{
+			name:   "zsh",
+			output: "[1]  + 4242 running    sleep 100\r\n[2]  - 4250 suspended  top\r\n",
+			want: []JobEntry{
+				{JobID: 1, PID: 4242, State: "running", Command: "sleep 100"},
+				{JobID: 2, PID: 4250, State: "suspended", Command: "top"},
+			},
+		},
+		{
+			name:   "no jobs",
+			output: "",
+			want:   []JobEntry{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseJobs(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJobs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// newJobsServer returns a server with one initialized session.
+func newJobsServer(t *testing.T) (*Server, *fakepty.PTY) {
+	t.Helper()
+	sess, pty := newFakeSessionWithRand("sess_jobs")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	return newTestServer(sm), pty
+}
+
+func TestHandleShellJobs(t *testing.T) {
+	srv, pty := newJobsServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n[1]+ 4242 Running                 sleep 100 &\n___CMD_END_00010203___0\n")
+
+	result, _ := srv.handleShellJobs(context.Background(), makeRequest(map[string]any{"session_id": "sess_jobs"}))
+	m := resultJSON(t, result)
+	if m["count"] != float64(1) {
+		t.Fatalf("count = %v, want 1 (result %v)", m["count"], m)
+	}
+	job := m["jobs"].([]any)[0].(map[string]any)
+	if job["job_id"] != float64(1) || job["pid"] != float64(4242) || job["state"] != "running" {
+		t.Errorf("job = %v", job)
+	}
+
+	written := pty.Written()
+	if !strings.Contains(written, "; jobs -l; ") {
+		t.Errorf("jobs -l not run in the session shell: %q", written)
+	}
+	if strings.Contains(written, "bash -c") {
+		t.Errorf("jobs -l run in a child shell: %q", written)
+	}
+}
+
+func TestHandleShellJobKill(t *testing.T) {
+	srv, pty := newJobsServer(t)
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___0\n")
+
+	result, _ := srv.handleShellJobKill(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_jobs",
+		"job_id":     2,
+		"signal":     "sigkill",
+	}))
+	m := resultJSON(t, result)
+	if m["status"] != "sent" || m["signal"] != "KILL" {
+		t.Errorf("result = %v", m)
+	}
+	if !strings.Contains(pty.Written(), "; kill -s KILL %2; ") {
+		t.Errorf("written = %q, want kill -s KILL %%2", pty.Written())
+	}
+}
+
+func TestHandleShellJobKill_NoSuchJob(t *testing.T) {
+	srv, pty := newJobsServer(t)
+	pty.AddResponse("___CMD_START_00010203___\nbash: kill: %7: no such job\n___CMD_END_00010203___1\n")
+
+	result, _ := srv.handleShellJobKill(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_jobs",
+		"job_id":     7,
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "no such job") {
+		t.Errorf("result = %s, want no such job error", resultText(result))
+	}
+}
+
+func TestHandleShellJobKill_Validation(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing session", map[string]any{"job_id": 1}, "session_id"},
+		{"bad job id", map[string]any{"session_id": "s", "job_id": 0}, "job_id"},
+		{"bad signal", map[string]any{"session_id": "s", "job_id": 1, "signal": "TERM; rm -rf /"}, "invalid signal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := srv.handleShellJobKill(context.Background(), makeRequest(tt.args))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+const cleanupManifestPath = "/local/out.bin.transfer"
+
+// newCleanupTestServer returns a server with cfg, a fake filesystem holding
+// an empty /local/out.bin and a session "sess_audit".
+func newCleanupTestServer(cfg *config.Config) (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.AddFile("/local/out.bin", make([]byte, 6), 0644)
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_audit"))
+	clk := fakeclock.New(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	return NewServer(cfg, WithSessionManager(sm), WithFileSystem(ffs), WithClock(clk)), ffs
+}
+
+// downloadChunks runs a two-chunk download of "abcabc" into /local/out.bin
+// with its manifest at cleanupManifestPath.
+func downloadChunks(t *testing.T, srv *Server, ffs *fakefs.FS) *mcpgo.CallToolResult {
+	t.Helper()
+	localFile, err := ffs.OpenFile("/local/out.bin", os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("open local file: %v", err)
+	}
+	defer localFile.Close()
+
+	manifest := &TransferManifest{
+		Version:     1,
+		Direction:   "get",
+		RemotePath:  "/srv/out.bin",
+		LocalPath:   "/local/out.bin",
+		TotalSize:   6,
+		ChunkSize:   3,
+		TotalChunks: 2,
+		SessionID:   "sess_audit",
+		Chunks: []ChunkInfo{
+			{Index: 0, Offset: 0, Size: 3},
+			{Index: 1, Offset: 3, Size: 3},
+		},
+	}
+	if err := srv.saveManifest(manifest, cleanupManifestPath); err != nil {
+		t.Fatalf("saveManifest: %v", err)
+	}
+	result, err := srv.transferChunksGet(localFile, bytes.NewReader([]byte("abcabc")), manifest, cleanupManifestPath, srv.clock.Now())
+	if err != nil || result.IsError {
+		t.Fatalf("transferChunksGet() = %s, %v", resultText(result), err)
+	}
+	return result
+}
+
+func TestFinalizeChunkedTransfer_RemovesManifest(t *testing.T) {
+	srv, ffs := newCleanupTestServer(config.DefaultConfig())
+
+	m := resultJSON(t, downloadChunks(t, srv, ffs))
+	if m["status"] != "completed" || m["manifest_retained"] != false {
+		t.Errorf("result = %v, want completed with manifest_retained false", m)
+	}
+	if _, err := ffs.Stat(cleanupManifestPath); err == nil {
+		t.Error("manifest of a completed transfer was kept")
+	}
+}
+
+func TestFinalizeChunkedTransfer_KeepsManifest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Transfer.CleanupManifestOnComplete = false
+	srv, ffs := newCleanupTestServer(cfg)
+
+	m := resultJSON(t, downloadChunks(t, srv, ffs))
+	if m["manifest_retained"] != true {
+		t.Errorf("manifest_retained = %v, want true", m["manifest_retained"])
+	}
+	saved, err := srv.loadManifest(cleanupManifestPath)
+	if err != nil {
+		t.Fatalf("loadManifest: %v", err)
+	}
+	if saved.CompletedAt == nil {
+		t.Error("kept manifest was not marked completed")
+	}
+}
+
+func TestInterruptedTransfer_KeepsManifest(t *testing.T) {
+	srv, ffs := newCleanupTestServer(config.DefaultConfig())
+	manifest := &TransferManifest{Version: 1, Direction: "get", TotalSize: 6, ChunkSize: 3, TotalChunks: 2,
+		Chunks: []ChunkInfo{{Index: 0, Size: 3, Completed: true}, {Index: 1, Offset: 3, Size: 3}}}
+
+	result, err := srv.interruptedTransfer(manifest, cleanupManifestPath)
+	if err != nil {
+		t.Fatalf("interruptedTransfer() error: %v", err)
+	}
+	if m := resultJSON(t, result); m["status"] != "interrupted" || m["manifest_retained"] != true {
+		t.Errorf("result = %v, want interrupted with manifest_retained true", m)
+	}
+	if _, err := ffs.Stat(cleanupManifestPath); err != nil {
+		t.Errorf("manifest of an incomplete transfer was removed: %v", err)
+	}
+}
+
+// With auditing on, the record is built from the manifest, which is only
+// removed afterwards.
+func TestFinalizeChunkedTransfer_RemovesManifestAfterAudit(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Transfer.AuditDir = "/var/audit"
+	srv, ffs := newCleanupTestServer(cfg)
+
+	handler := func(ctx context.Context, req mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error) {
+		return downloadChunks(t, srv, ffs), nil
+	}
+	result := callAudited(t, srv, "shell_file_get_chunked", handler, map[string]any{"session_id": "sess_audit"})
+	if m := resultJSON(t, result); m["manifest_retained"] != false {
+		t.Errorf("manifest_retained = %v, want false", m["manifest_retained"])
+	}
+	recs, _ := auditRecords(t, ffs)
+	if len(recs) != 1 || recs[0].Source != "sess_audit:/srv/out.bin" || recs[0].Size != 6 {
+		t.Fatalf("records = %+v, want one for the download", recs)
+	}
+	if _, err := ffs.Stat(cleanupManifestPath); err == nil {
+		t.Error("manifest was kept after the audit record was written")
+	}
+}
@@ -0,0 +1,259 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/sftp"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultNetProbeSamples = 5
+	maxNetProbeSamples     = 20
+	defaultNetProbeBytes   = 256 * 1024
+	minNetProbeBytes       = 4 * 1024
+	maxNetProbeBytes       = 4 * 1024 * 1024
+
+	// netProbeChunkTime is how long a recommended chunk takes to transfer at
+	// the estimated bandwidth.
+	netProbeChunkTime = 2 * time.Second
+	minTunedChunkSize = 64 * 1024
+)
+
+// registerNetProbeTools registers the link measurement tool.
+func (s *Server) registerNetProbeTools() {
+	s.mcpServer.AddTool(shellNetProbeTool(), s.handleShellNetProbe)
+}
+
+func shellNetProbeTool() mcp.Tool {
+	return mcp.NewTool("shell_net_probe",
+		mcp.WithDescription(`Measure latency and upload bandwidth to an SSH session's host.
+
+Times a few SFTP round-trips (rtt_ms is their median) and the upload of a
+fixed buffer to a temp file in the remote home directory, which is removed
+again, to estimate bandwidth (estimated_bps, in bits per second; it includes
+the file's open and close round-trips, so it errs low).
+
+Use it before choosing chunk sizes or parallelism. recommended_chunk_size is
+sized to take about 2s per chunk, and becomes the default chunk_size of
+shell_file_get_chunked and shell_file_put_chunked for this session. In
+read-only mode only latency is measured.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithNumber("samples",
+			mcp.Description(fmt.Sprintf("Round-trips to time (default %d, max %d)", defaultNetProbeSamples, maxNetProbeSamples)),
+		),
+		mcp.WithNumber("bytes",
+			mcp.Description(fmt.Sprintf("Size of the throughput test upload (default %d, %d-%d)", defaultNetProbeBytes, minNetProbeBytes, maxNetProbeBytes)),
+		),
+		additiveTool(),
+	)
+}
+
+// NetProbeResult is the result of shell_net_probe.
+type NetProbeResult struct {
+	Status               string  `json:"status"`
+	SessionID            string  `json:"session_id"`
+	Samples              int     `json:"samples"`
+	RTTMs                float64 `json:"rtt_ms"`
+	RTTMinMs             float64 `json:"rtt_min_ms"`
+	RTTMaxMs             float64 `json:"rtt_max_ms"`
+	BytesSent            int     `json:"bytes_sent,omitempty"`
+	UploadMs             float64 `json:"upload_ms,omitempty"`
+	EstimatedBps         int64   `json:"estimated_bps,omitempty"`
+	RecommendedChunkSize int     `json:"recommended_chunk_size,omitempty"`
+	BandwidthSkipped     string  `json:"bandwidth_skipped,omitempty"`
+}
+
+// netProbeTransport is what shell_net_probe times: an SSH session's SFTP
+// connection, or a fake in tests.
+type netProbeTransport interface {
+	roundTrip() error
+	upload(data []byte) error
+}
+
+// sftpProbeTransport probes over SFTP. Round-trips stat the home directory
+// and uploads go to a temp file there.
+type sftpProbeTransport struct {
+	client *sftp.Client
+	home   string
+}
+
+func (t sftpProbeTransport) roundTrip() error {
+	_, err := t.client.Stat(t.home)
+	return err
+}
+
+func (t sftpProbeTransport) upload(data []byte) error {
+	p := path.Join(t.home, ".claude-shell-mcp-probe-"+randomSuffix())
+	f, err := t.client.Create(p)
+	if err != nil {
+		return err
+	}
+	defer t.client.Remove(p)
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// sftpNetProbeTransport returns the probe transport of an SSH session.
+func sftpNetProbeTransport(sess *session.Session) (netProbeTransport, error) {
+	client, err := sess.SFTPClient()
+	if err != nil {
+		return nil, err
+	}
+	home, err := client.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("remote home directory: %w", err)
+	}
+	return sftpProbeTransport{client: client, home: home}, nil
+}
+
+// netProbes keeps the latest probe of each session, so chunked transfers
+// can default to its recommended chunk size.
+type netProbes struct {
+	mu      sync.Mutex
+	results map[string]NetProbeResult
+}
+
+func newNetProbes() *netProbes {
+	return &netProbes{results: make(map[string]NetProbeResult)}
+}
+
+func (p *netProbes) set(sessionID string, r NetProbeResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results[sessionID] = r
+}
+
+func (p *netProbes) get(sessionID string) (NetProbeResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.results[sessionID]
+	return r, ok
+}
+
+func (p *netProbes) dropSession(sessionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.results, sessionID)
+}
+
+// defaultChunkSize is the chunk size of a chunked transfer that does not
+// set one: the session's recommended_chunk_size from shell_net_probe, else
+// DefaultChunkSize.
+func (s *Server) defaultChunkSize(sessionID string) int {
+	if r, ok := s.netProbes.get(sessionID); ok && r.RecommendedChunkSize > 0 {
+		return r.RecommendedChunkSize
+	}
+	return DefaultChunkSize
+}
+
+func (s *Server) handleShellNetProbe(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	samples := mcp.ParseInt(req, "samples", defaultNetProbeSamples)
+	size := mcp.ParseInt(req, "bytes", defaultNetProbeBytes)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if samples < 1 || samples > maxNetProbeSamples {
+		return mcp.NewToolResultError(fmt.Sprintf("samples must be between 1 and %d", maxNetProbeSamples)), nil
+	}
+	if size < minNetProbeBytes || size > maxNetProbeBytes {
+		return mcp.NewToolResultError(fmt.Sprintf("bytes must be between %d and %d", minNetProbeBytes, maxNetProbeBytes)), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if !sess.IsSSH() {
+		return mcp.NewToolResultError("shell_net_probe needs an SSH session"), nil
+	}
+	transport, err := s.probeTransport(sess)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf(errGetSFTPClient, err)), nil
+	}
+
+	result, err := s.probeNetwork(transport, samples, size)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	result.SessionID = sessionID
+	s.netProbes.set(sessionID, *result)
+
+	slog.Info("network probe",
+		slog.String("session_id", sessionID),
+		slog.Float64("rtt_ms", result.RTTMs),
+		slog.Int64("estimated_bps", result.EstimatedBps),
+	)
+	return jsonResult(result)
+}
+
+// probeNetwork times samples round-trips and, unless in read-only mode, the
+// upload of size bytes.
+func (s *Server) probeNetwork(t netProbeTransport, samples, size int) (*NetProbeResult, error) {
+	rtts := make([]time.Duration, 0, samples)
+	for i := 0; i < samples; i++ {
+		start := s.clock.Now()
+		if err := t.roundTrip(); err != nil {
+			return nil, fmt.Errorf("round-trip: %w", err)
+		}
+		rtts = append(rtts, s.clock.Now().Sub(start))
+	}
+	slices.Sort(rtts)
+
+	result := &NetProbeResult{
+		Status:   "completed",
+		Samples:  samples,
+		RTTMs:    durationMs(rtts[len(rtts)/2]),
+		RTTMinMs: durationMs(rtts[0]),
+		RTTMaxMs: durationMs(rtts[len(rtts)-1]),
+	}
+	if s.readOnly() {
+		result.BandwidthSkipped = "read-only mode"
+		return result, nil
+	}
+
+	start := s.clock.Now()
+	if err := t.upload(make([]byte, size)); err != nil {
+		return nil, fmt.Errorf("throughput test: %w", err)
+	}
+	elapsed := s.clock.Now().Sub(start)
+	result.BytesSent = size
+	result.UploadMs = durationMs(elapsed)
+	if elapsed > 0 {
+		bytesPerSec := float64(size) / elapsed.Seconds()
+		result.EstimatedBps = int64(bytesPerSec * 8)
+		result.RecommendedChunkSize = tunedChunkSize(bytesPerSec)
+	}
+	return result, nil
+}
+
+// tunedChunkSize sizes chunks to take about netProbeChunkTime each, in
+// multiples of minTunedChunkSize up to MaxChunkSize.
+func tunedChunkSize(bytesPerSec float64) int {
+	size := bytesPerSec * netProbeChunkTime.Seconds()
+	if size >= MaxChunkSize {
+		return MaxChunkSize
+	}
+	return max(minTunedChunkSize, int(size)/minTunedChunkSize*minTunedChunkSize)
+}
+
+// durationMs returns d in milliseconds, rounded to 0.01ms.
+func durationMs(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// fakeProbeTransport simulates a link by advancing the clock: each
+// round-trip takes the next of rtts, and uploads move bytesPerSec.
+type fakeProbeTransport struct {
+	clock       *fakeclock.Clock
+	rtts        []time.Duration
+	bytesPerSec int
+	uploaded    int
+	err         error
+}
+
+func (f *fakeProbeTransport) roundTrip() error {
+	if f.err != nil {
+		return f.err
+	}
+	f.clock.Advance(f.rtts[0])
+	f.rtts = append(f.rtts[1:], f.rtts[0])
+	return nil
+}
+
+func (f *fakeProbeTransport) upload(data []byte) error {
+	f.uploaded += len(data)
+	f.clock.Advance(time.Duration(len(data)) * time.Second / time.Duration(f.bytesPerSec))
+	return nil
+}
+
+func newNetProbeTestServer(t *testing.T, transport *fakeProbeTransport) *Server {
+	t.Helper()
+	clk := fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	transport.clock = clk
+	sm := fakesessionmgr.New()
+	sm.AddSession(session.NewSession("sess_ssh", "ssh"))
+	sm.AddSession(newLocalSession("sess_local"))
+	srv := NewServer(config.DefaultConfig(), WithSessionManager(sm), WithClock(clk))
+	srv.probeTransport = func(*session.Session) (netProbeTransport, error) { return transport, nil }
+	return srv
+}
+
+func TestHandleShellNetProbe(t *testing.T) {
+	transport := &fakeProbeTransport{
+		rtts:        []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+		bytesPerSec: 1 << 20, // 8 Mbit/s
+	}
+	srv := newNetProbeTestServer(t, transport)
+
+	result, err := srv.handleShellNetProbe(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ssh",
+		"samples":    float64(3),
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("probe failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["rtt_ms"] != float64(20) || m["rtt_min_ms"] != float64(10) || m["rtt_max_ms"] != float64(30) {
+		t.Errorf("rtt = %v / %v / %v, want median 20, min 10, max 30", m["rtt_ms"], m["rtt_min_ms"], m["rtt_max_ms"])
+	}
+	if m["bytes_sent"] != float64(defaultNetProbeBytes) || transport.uploaded != defaultNetProbeBytes {
+		t.Errorf("bytes_sent = %v, uploaded %d", m["bytes_sent"], transport.uploaded)
+	}
+	if m["upload_ms"] != float64(250) || m["estimated_bps"] != float64(8<<20) {
+		t.Errorf("upload_ms = %v, estimated_bps = %v", m["upload_ms"], m["estimated_bps"])
+	}
+	if m["recommended_chunk_size"] != float64(2<<20) {
+		t.Errorf("recommended_chunk_size = %v, want 2MB", m["recommended_chunk_size"])
+	}
+
+	// Chunked transfers of the session now default to the recommendation.
+	if got := srv.defaultChunkSize("sess_ssh"); got != 2<<20 {
+		t.Errorf("defaultChunkSize(sess_ssh) = %d, want 2MB", got)
+	}
+	if got := srv.defaultChunkSize("sess_local"); got != DefaultChunkSize {
+		t.Errorf("defaultChunkSize(sess_local) = %d, want DefaultChunkSize", got)
+	}
+}
+
+func TestHandleShellNetProbe_ReadOnlySkipsUpload(t *testing.T) {
+	transport := &fakeProbeTransport{rtts: []time.Duration{5 * time.Millisecond}, bytesPerSec: 1 << 20}
+	srv := newNetProbeTestServer(t, transport)
+	srv.config.Security.ReadOnly = true
+
+	result, _ := srv.handleShellNetProbe(context.Background(), makeRequest(map[string]any{"session_id": "sess_ssh"}))
+	if result.IsError {
+		t.Fatalf("probe failed: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["bandwidth_skipped"] != "read-only mode" || m["estimated_bps"] != nil || transport.uploaded != 0 {
+		t.Errorf("result = %v, uploaded %d", m, transport.uploaded)
+	}
+	if m["samples"] != float64(defaultNetProbeSamples) || m["rtt_ms"] != float64(5) {
+		t.Errorf("samples = %v, rtt_ms = %v", m["samples"], m["rtt_ms"])
+	}
+}
+
+func TestHandleShellNetProbe_Errors(t *testing.T) {
+	transport := &fakeProbeTransport{rtts: []time.Duration{time.Millisecond}, bytesPerSec: 1}
+	srv := newNetProbeTestServer(t, transport)
+	ctx := context.Background()
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{}, errSessionIDRequired},
+		{map[string]any{"session_id": "sess_local"}, "needs an SSH session"},
+		{map[string]any{"session_id": "sess_ssh", "samples": float64(0)}, "samples must be between"},
+		{map[string]any{"session_id": "sess_ssh", "bytes": float64(maxNetProbeBytes + 1)}, "bytes must be between"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellNetProbe(ctx, makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("args %v: result = %s, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+
+	transport.err = errors.New("connection lost")
+	result, _ := srv.handleShellNetProbe(ctx, makeRequest(map[string]any{"session_id": "sess_ssh"}))
+	if !result.IsError || !strings.Contains(resultText(result), "round-trip: connection lost") {
+		t.Errorf("result = %s", resultText(result))
+	}
+	if _, ok := srv.netProbes.get("sess_ssh"); ok {
+		t.Error("a failed probe was kept")
+	}
+}
+
+func TestTunedChunkSize(t *testing.T) {
+	tests := []struct {
+		bytesPerSec float64
+		want        int
+	}{
+		{1000, minTunedChunkSize},
+		{100 * 1024, 192 * 1024},
+		{1 << 20, 2 << 20},
+		{100 << 20, MaxChunkSize},
+	}
+	for _, tt := range tests {
+		if got := tunedChunkSize(tt.bytesPerSec); got != tt.want {
+			t.Errorf("tunedChunkSize(%v) = %d, want %d", tt.bytesPerSec, got, tt.want)
+		}
+	}
+}
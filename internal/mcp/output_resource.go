@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	// outputResourcePrefix starts the URI of a saved large output:
+	// shell://output/<session_id>/<output_id>.
+	outputResourcePrefix = "shell://output/"
+	outputDirName        = ".claude-shell-mcp"
+)
+
+// outputIDPart matches a session or output id that is safe to use in a file
+// name under the output directory.
+var outputIDPart = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// registerOutputResources exposes outputs saved by applyAutoTruncation as
+// MCP resources, for clients that cannot read the server's filesystem.
+func (s *Server) registerOutputResources() {
+	s.mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(outputResourcePrefix+"{session_id}/{output_id}", "Command output",
+			mcp.WithTemplateDescription("Full output of a shell_exec command that was too large to return inline"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		s.handleOutputResource,
+	)
+}
+
+// Where applyAutoTruncation put a large output, reported as
+// ExecResult.OutputStrategy.
+const (
+	outputStrategySaveDir   = "save_dir"
+	outputStrategyTempDir   = "temp_dir"
+	outputStrategyTruncated = "inline_truncated"
+)
+
+// outputDir is a directory large outputs may be saved in.
+type outputDir struct {
+	path     string
+	strategy string
+}
+
+// outputDirs returns the directories large outputs are saved in, in order of
+// preference: output.save_dir (default <cwd>/.claude-shell-mcp), then
+// tempOutputDir.
+func (s *Server) outputDirs() []outputDir {
+	var dirs []outputDir
+	if s.config != nil && s.config.Output.SaveDir != "" {
+		dirs = append(dirs, outputDir{s.config.Output.SaveDir, outputStrategySaveDir})
+	} else if cwd, err := s.fs.Getwd(); err == nil {
+		dirs = append(dirs, outputDir{cwd + "/" + outputDirName, outputStrategySaveDir})
+	}
+	return append(dirs, outputDir{tempOutputDir(), outputStrategyTempDir})
+}
+
+// tempOutputDir is the fallback output directory: one per user in the temp
+// dir, which other users share.
+func tempOutputDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("claude-shell-mcp-%d", os.Getuid()))
+}
+
+// ensurePrivateDir creates dir, readable by this user only, and checks that
+// it is a directory of ours rather than a symlink or one another local user
+// created to read outputs or plant links in. Saved outputs hold command
+// output and the commands themselves.
+func (s *Server) ensurePrivateDir(dir string) error {
+	if err := s.fs.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := s.fs.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if !ownedByCurrentUser(info) {
+		return fmt.Errorf("%s is owned by another user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		// e.g. created 0755 by an older version
+		return s.fs.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// outputResourceURI returns the resource URI for a file written by
+// saveOutputToFile (<session_id>_<output_id>.txt).
+func outputResourceURI(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".txt")
+	i := strings.LastIndex(name, "_")
+	if i <= 0 || i == len(name)-1 {
+		return ""
+	}
+	return outputResourcePrefix + name[:i] + "/" + name[i+1:]
+}
+
+// parseOutputResourceURI splits shell://output/<session_id>/<output_id>.
+func parseOutputResourceURI(uri string) (sessionID, outputID string, err error) {
+	rest, ok := strings.CutPrefix(uri, outputResourcePrefix)
+	if !ok {
+		return "", "", fmt.Errorf("not an output resource: %s", uri)
+	}
+	sessionID, outputID, ok = strings.Cut(rest, "/")
+	if !ok || !validOutputID(sessionID) || !validOutputID(outputID) {
+		return "", "", fmt.Errorf("invalid output resource URI: %s", uri)
+	}
+	return sessionID, outputID, nil
+}
+
+func (s *Server) handleOutputResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	sessionID, outputID, err := parseOutputResourceURI(req.Params.URI)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := s.findOutputFile(sessionID, outputID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read output %s/%s: %w", sessionID, outputID, err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "text/plain",
+			Text:     string(data),
+		},
+	}, nil
+}
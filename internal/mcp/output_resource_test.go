@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func readResourceRequest(uri string) mcp.ReadResourceRequest {
+	var req mcp.ReadResourceRequest
+	req.Params.URI = uri
+	return req
+}
+
+func TestOutputResourceURI(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/work/.claude-shell-mcp/sess_ab12_1700000000000.txt", "shell://output/sess_ab12/1700000000000"},
+		{"/work/.claude-shell-mcp/local_1.txt", "shell://output/local/1"},
+		{"/work/.claude-shell-mcp/noseparator.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := outputResourceURI(tt.path); got != tt.want {
+			t.Errorf("outputResourceURI(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseOutputResourceURI_RejectsTraversal(t *testing.T) {
+	for _, uri := range []string{
+		"shell://output/../etc/passwd",
+		"shell://output/sess_1/../../x",
+		"shell://output/sess_1",
+		"shell://output/sess 1/1",
+		"file:///etc/passwd",
+	} {
+		if _, _, err := parseOutputResourceURI(uri); err == nil {
+			t.Errorf("parseOutputResourceURI(%q) should fail", uri)
+		}
+	}
+}
+
+func TestApplyAutoTruncation_ReadableAsResource(t *testing.T) {
+	fs := fakefs.New()
+	fs.SetCwd("/workdir")
+	srv := newTestServerWithFS(fakesessionmgr.New(), fs)
+
+	largeOutput := strings.Repeat("line of output\n", saveToFileThreshold/10)
+	result := &session.ExecResult{Stdout: largeOutput}
+	srv.applyAutoTruncation("sess_1", "", result)
+
+	if !strings.HasPrefix(result.OutputResource, "shell://output/sess_1/") {
+		t.Fatalf("OutputResource = %q, want shell://output/sess_1/...", result.OutputResource)
+	}
+	if !strings.Contains(result.Warning, result.OutputResource) {
+		t.Errorf("Warning should mention the resource: %q", result.Warning)
+	}
+
+	contents, err := srv.handleOutputResource(context.Background(), readResourceRequest(result.OutputResource))
+	if err != nil {
+		t.Fatalf("handleOutputResource error: %v", err)
+	}
+	if len(contents) != 1 {
+		t.Fatalf("got %d contents, want 1", len(contents))
+	}
+	text, ok := contents[0].(mcp.TextResourceContents)
+	if !ok {
+		t.Fatalf("contents[0] is %T, want TextResourceContents", contents[0])
+	}
+	if text.Text != largeOutput || text.MIMEType != "text/plain" || text.URI != result.OutputResource {
+		t.Errorf("resource = {URI %q, MIME %q, %d bytes}, want full output", text.URI, text.MIMEType, len(text.Text))
+	}
+}
+
+func TestOutputResource_TempDirFallback(t *testing.T) {
+	fs := fakefs.New()
+	fs.SetCwd("/readonly")
+	fs.SetReadOnly("/readonly/.claude-shell-mcp")
+	srv := newTestServerWithFS(fakesessionmgr.New(), fs)
+
+	largeOutput := strings.Repeat("line of output\n", saveToFileThreshold/10)
+	result := &session.ExecResult{Stdout: largeOutput}
+	srv.applyAutoTruncation("sess_1", "", result)
+	if result.OutputStrategy != outputStrategyTempDir {
+		t.Fatalf("OutputStrategy = %q, want temp_dir", result.OutputStrategy)
+	}
+
+	contents, err := srv.handleOutputResource(context.Background(), readResourceRequest(result.OutputResource))
+	if err != nil {
+		t.Fatalf("handleOutputResource error: %v", err)
+	}
+	if text := contents[0].(mcp.TextResourceContents).Text; text != largeOutput {
+		t.Errorf("resource text length = %d, want %d", len(text), len(largeOutput))
+	}
+}
+
+func TestHandleOutputResource_Missing(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+
+	if _, err := srv.handleOutputResource(context.Background(), readResourceRequest("shell://output/sess_1/42")); err == nil {
+		t.Error("expected error for an output that was never saved")
+	}
+}
+
+func TestOutputResourceTemplateRegistered(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+
+	resp := srv.mcpServer.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"resources/templates/list"}`))
+	msg, ok := resp.(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("response is %T, want JSONRPCResponse", resp)
+	}
+	list, ok := msg.Result.(mcp.ListResourceTemplatesResult)
+	if !ok {
+		t.Fatalf("result is %T, want ListResourceTemplatesResult", msg.Result)
+	}
+	if len(list.ResourceTemplates) != 1 || list.ResourceTemplates[0].URITemplate.Raw() != "shell://output/{session_id}/{output_id}" {
+		t.Errorf("templates = %+v", list.ResourceTemplates)
+	}
+}
+
+func TestSaveOutputToFile_PrivateFiles(t *testing.T) {
+	fs := fakefs.New()
+	fs.SetCwd("/project")
+	fs.MkdirAll("/project/.claude-shell-mcp", 0755)
+	srv := newTestServerWithFS(fakesessionmgr.New(), fs)
+
+	path, strategy, err := srv.saveOutputToFile("sess_1", "cat secrets", "output")
+	if err != nil || strategy != outputStrategySaveDir {
+		t.Fatalf("saveOutputToFile() = %q, %q, %v", path, strategy, err)
+	}
+	for _, name := range []string{path, outputMetaPath(path)} {
+		if info, err := fs.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode = %v, %v, want 0600", name, info.Mode(), err)
+		}
+	}
+	if info, _ := fs.Stat("/project/.claude-shell-mcp"); info.Mode().Perm() != 0700 {
+		t.Errorf("output dir mode = %v, want 0700", info.Mode().Perm())
+	}
+}
+
+func TestSaveOutputToFile_RejectsSymlinkedDir(t *testing.T) {
+	fs := fakefs.New()
+	fs.SetCwd("/project")
+	fs.MkdirAll("/shared", 0777)
+	fs.AddSymlink("/project/.claude-shell-mcp", "/shared")
+	srv := newTestServerWithFS(fakesessionmgr.New(), fs)
+
+	path, strategy, err := srv.saveOutputToFile("sess_1", "ls", "output")
+	if err != nil || strategy != outputStrategyTempDir || !strings.HasPrefix(path, tempOutputDir()+"/") {
+		t.Errorf("saveOutputToFile() = %q, %q, %v, want the temp dir instead of the symlink", path, strategy, err)
+	}
+}
@@ -0,0 +1,293 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultOutputsListLimit is how many saved outputs shell_outputs_list
+// returns by default, newest first.
+const defaultOutputsListLimit = 20
+
+// registerOutputTools registers the tools for outputs saved by
+// applyAutoTruncation.
+func (s *Server) registerOutputTools() {
+	s.mcpServer.AddTool(shellOutputsListTool(), s.handleShellOutputsList)
+	s.mcpServer.AddTool(shellOutputReadTool(), s.handleShellOutputRead)
+}
+
+func shellOutputsListTool() mcp.Tool {
+	return mcp.NewTool("shell_outputs_list",
+		mcp.WithDescription(`List the large outputs saved to files for a session, newest first.
+
+When a command's output is too large to return inline it is saved to a file
+and only the path is returned. This lists those files so an earlier output
+can be found again: output_id, path, size, the command that produced it and
+when. Read one with shell_output_read. Outputs stay listed after their
+session is closed.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of outputs to list (default: 20)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+func shellOutputReadTool() mcp.Tool {
+	return mcp.NewTool("shell_output_read",
+		mcp.WithDescription(`Read a large output saved for a session (see shell_outputs_list).
+
+Use head_lines or tail_lines to read only part of it; without them the whole
+output is returned if it is under output.max_inline_file_bytes.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("output_id",
+			mcp.Required(),
+			mcp.Description("Output ID from shell_outputs_list or the output_resource URI"),
+		),
+		mcp.WithNumber("head_lines",
+			mcp.Description("Return only the first N lines"),
+		),
+		mcp.WithNumber("tail_lines",
+			mcp.Description("Return only the last N lines"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// outputMeta is what saveOutputMeta records about a saved output, in a
+// .json file next to it.
+type outputMeta struct {
+	Command   string    `json:"command"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedOutput is a saved output in shell_outputs_list.
+type SavedOutput struct {
+	OutputID  string `json:"output_id"`
+	Path      string `json:"path"`
+	Resource  string `json:"resource"`
+	SizeBytes int64  `json:"size_bytes"`
+	Command   string `json:"command,omitempty"`
+	CreatedAt string `json:"created_at"`
+
+	createdAt time.Time
+}
+
+// OutputsListResult is the result of shell_outputs_list.
+type OutputsListResult struct {
+	SessionID string        `json:"session_id"`
+	Outputs   []SavedOutput `json:"outputs"`
+	Total     int           `json:"total"`
+}
+
+// OutputReadResult is the result of shell_output_read.
+type OutputReadResult struct {
+	SessionID  string `json:"session_id"`
+	OutputID   string `json:"output_id"`
+	Path       string `json:"path"`
+	Command    string `json:"command,omitempty"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Output     string `json:"output"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	TotalLines int    `json:"total_lines"`
+	ShownLines int    `json:"shown_lines"`
+}
+
+// outputMetaPath returns the metadata file of the output saved at path.
+func outputMetaPath(path string) string {
+	return strings.TrimSuffix(path, ".txt") + ".json"
+}
+
+// saveOutputMeta records command and the current time next to the output
+// saved at path. Failing to is logged: the output itself is saved.
+func (s *Server) saveOutputMeta(path, command string) {
+	data, err := json.Marshal(outputMeta{Command: command, CreatedAt: s.clock.Now().UTC()})
+	if err == nil {
+		err = s.fs.WriteFile(outputMetaPath(path), data, 0600)
+	}
+	if err != nil {
+		slog.Warn("failed to save output metadata",
+			slog.String("path", path),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// readOutputMeta reads the metadata of the output saved at path. Outputs
+// saved without it have none.
+func (s *Server) readOutputMeta(path string) outputMeta {
+	var meta outputMeta
+	if data, err := s.fs.ReadFile(outputMetaPath(path)); err == nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+// findOutputFile returns the path of a saved output, looking in each of
+// outputDirs.
+func (s *Server) findOutputFile(sessionID, outputID string) (string, fs.FileInfo, error) {
+	var err error
+	for _, dir := range s.outputDirs() {
+		path := fmt.Sprintf("%s/%s_%s.txt", dir.path, sessionID, outputID)
+		var info fs.FileInfo
+		if info, err = s.fs.Stat(path); err == nil {
+			return path, info, nil
+		}
+	}
+	return "", nil, fmt.Errorf("read output %s/%s: %w", sessionID, outputID, err)
+}
+
+// validOutputID checks a session or output id before it is used in a file
+// name, as parseOutputResourceURI does.
+func validOutputID(id string) bool {
+	return outputIDPart.MatchString(id) && !strings.Contains(id, "..")
+}
+
+// listSavedOutputs returns the outputs saved for sessionID in outputDirs.
+func (s *Server) listSavedOutputs(sessionID string) []SavedOutput {
+	prefix := sessionID + "_"
+	var outputs []SavedOutput
+	for _, dir := range s.outputDirs() {
+		entries, err := s.fs.ReadDir(dir.path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				slog.Warn("list output dir", slog.String("dir", dir.path), slog.String("error", err.Error()))
+			}
+			continue
+		}
+		for _, entry := range entries {
+			outputID, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			outputID, ok = strings.CutSuffix(outputID, ".txt")
+			// Another session's id can start with this one's plus "_".
+			if !ok || strings.Contains(outputID, "_") || !validOutputID(outputID) {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			path := dir.path + "/" + entry.Name()
+			meta := s.readOutputMeta(path)
+			if meta.CreatedAt.IsZero() {
+				meta.CreatedAt = info.ModTime()
+			}
+			outputs = append(outputs, SavedOutput{
+				OutputID:  outputID,
+				Path:      path,
+				Resource:  outputResourcePrefix + sessionID + "/" + outputID,
+				SizeBytes: info.Size(),
+				Command:   meta.Command,
+				CreatedAt: meta.CreatedAt.UTC().Format(time.RFC3339),
+				createdAt: meta.CreatedAt,
+			})
+		}
+	}
+	sort.SliceStable(outputs, func(i, j int) bool {
+		return outputs[i].createdAt.After(outputs[j].createdAt)
+	})
+	return outputs
+}
+
+func (s *Server) handleShellOutputsList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	limit := mcp.ParseInt(req, "limit", defaultOutputsListLimit)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if !validOutputID(sessionID) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid session_id %q", sessionID)), nil
+	}
+	if limit <= 0 {
+		return mcp.NewToolResultError("limit must be positive"), nil
+	}
+
+	outputs := s.listSavedOutputs(sessionID)
+	result := OutputsListResult{
+		SessionID: sessionID,
+		Outputs:   outputs,
+		Total:     len(outputs),
+	}
+	if len(outputs) > limit {
+		result.Outputs = outputs[:limit]
+	}
+	if result.Outputs == nil {
+		result.Outputs = []SavedOutput{}
+	}
+	return jsonResult(result)
+}
+
+func (s *Server) handleShellOutputRead(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	outputID := mcp.ParseString(req, "output_id", "")
+	headLines := mcp.ParseInt(req, "head_lines", 0)
+	tailLines := mcp.ParseInt(req, "tail_lines", 0)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if outputID == "" {
+		return mcp.NewToolResultError("output_id is required"), nil
+	}
+	if strings.HasPrefix(outputID, outputResourcePrefix) {
+		uriSession, id, err := parseOutputResourceURI(outputID)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if uriSession != sessionID {
+			return mcp.NewToolResultError(fmt.Sprintf("output %s belongs to session %s, not %s", outputID, uriSession, sessionID)), nil
+		}
+		outputID = id
+	}
+	if !validOutputID(sessionID) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid session_id %q", sessionID)), nil
+	}
+	if !validOutputID(outputID) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid output_id %q", outputID)), nil
+	}
+	if tailLines > 0 && headLines > 0 {
+		return mcp.NewToolResultError("cannot use both tail_lines and head_lines"), nil
+	}
+
+	path, info, err := s.findOutputFile(sessionID, outputID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if limit := s.maxInlineFileBytes(); headLines <= 0 && tailLines <= 0 && info.Size() > limit {
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"output_too_large: %s is %d bytes, exceeds limit of %d bytes for inline content; read part of it with head_lines or tail_lines",
+			path, info.Size(), limit)), nil
+	}
+	data, err := s.fs.ReadFile(path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("read output: %v", err)), nil
+	}
+
+	result := OutputReadResult{
+		SessionID: sessionID,
+		OutputID:  outputID,
+		Path:      path,
+		Command:   s.readOutputMeta(path).Command,
+		SizeBytes: info.Size(),
+	}
+	result.Output, result.Truncated, result.TotalLines, result.ShownLines = truncateOutput(string(data), tailLines, headLines)
+	return jsonResult(result)
+}
@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func newOutputsServer(cfg *config.Config) (*Server, *fakefs.FS, *fakeclock.Clock) {
+	ffs := fakefs.New()
+	ffs.SetCwd("/work")
+	clock := fakeclock.New(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	srv := NewServer(cfg,
+		WithSessionManager(fakesessionmgr.New()),
+		WithFileSystem(ffs),
+		WithClock(clock),
+	)
+	return srv, ffs, clock
+}
+
+// saveLargeOutput runs output through applyAutoTruncation as a command's
+// result would be and returns the output ID it was saved under.
+func saveLargeOutput(t *testing.T, srv *Server, sessionID, command, output string) string {
+	t.Helper()
+	result := &session.ExecResult{Stdout: output}
+	srv.applyAutoTruncation(sessionID, command, result)
+	if result.OutputResource == "" {
+		t.Fatalf("output was not saved: %+v", result)
+	}
+	return result.OutputResource[strings.LastIndex(result.OutputResource, "/")+1:]
+}
+
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %05d of a large output\n", i)
+	}
+	return b.String()
+}
+
+func callOutputTool(t *testing.T, handler func(context.Context, mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error), args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	result, err := handler(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	return result
+}
+
+func TestOutputsList_NewestFirst(t *testing.T) {
+	srv, _, clock := newOutputsServer(config.DefaultConfig())
+	big := numberedLines(2000)
+
+	first := saveLargeOutput(t, srv, "sess_a", "journalctl -u app", big)
+	clock.Advance(time.Minute)
+	second := saveLargeOutput(t, srv, "sess_a", "find / -name '*.log'", big+big)
+	clock.Advance(time.Minute)
+	saveLargeOutput(t, srv, "sess_a_b", "other session", big)
+
+	result := callOutputTool(t, srv.handleShellOutputsList, map[string]any{"session_id": "sess_a"})
+	if result.IsError {
+		t.Fatalf("list failed: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	outputs, _ := m["outputs"].([]any)
+	if len(outputs) != 2 || m["total"] != float64(2) {
+		t.Fatalf("outputs = %v, want the two of sess_a", m["outputs"])
+	}
+
+	newest := outputs[0].(map[string]any)
+	if newest["output_id"] != second || newest["command"] != "find / -name '*.log'" {
+		t.Errorf("outputs[0] = %v, want the newer output %s", newest, second)
+	}
+	if newest["size_bytes"] != float64(2*len(big)) || newest["created_at"] != "2024-03-01T12:01:00Z" {
+		t.Errorf("size_bytes = %v, created_at = %v", newest["size_bytes"], newest["created_at"])
+	}
+	if newest["path"] != "/work/.claude-shell-mcp/sess_a_"+second+".txt" || newest["resource"] != "shell://output/sess_a/"+second {
+		t.Errorf("path = %v, resource = %v", newest["path"], newest["resource"])
+	}
+	if oldest := outputs[1].(map[string]any); oldest["output_id"] != first || oldest["command"] != "journalctl -u app" {
+		t.Errorf("outputs[1] = %v, want %s", oldest, first)
+	}
+}
+
+func TestOutputsList_Limit(t *testing.T) {
+	srv, _, clock := newOutputsServer(config.DefaultConfig())
+	for i := 0; i < 3; i++ {
+		saveLargeOutput(t, srv, "sess_a", "cmd", numberedLines(2000))
+		clock.Advance(time.Second)
+	}
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputsList, map[string]any{"session_id": "sess_a", "limit": 2}))
+	if outputs, _ := m["outputs"].([]any); len(outputs) != 2 || m["total"] != float64(3) {
+		t.Errorf("got %d outputs of total %v, want 2 of 3", len(outputs), m["total"])
+	}
+}
+
+func TestOutputsList_Empty(t *testing.T) {
+	srv, _, _ := newOutputsServer(config.DefaultConfig())
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputsList, map[string]any{"session_id": "sess_none"}))
+	if outputs, ok := m["outputs"].([]any); !ok || len(outputs) != 0 || m["total"] != float64(0) {
+		t.Errorf("outputs = %v, total = %v, want an empty list", m["outputs"], m["total"])
+	}
+}
+
+func TestOutputsList_WithoutMetadata(t *testing.T) {
+	srv, ffs, _ := newOutputsServer(config.DefaultConfig())
+	// Saved before outputs had a metadata file.
+	ffs.AddFile("/work/.claude-shell-mcp/sess_a_1700000000000.txt", []byte("old output\n"), 0644)
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputsList, map[string]any{"session_id": "sess_a"}))
+	outputs, _ := m["outputs"].([]any)
+	if len(outputs) != 1 {
+		t.Fatalf("outputs = %v, want one", m["outputs"])
+	}
+	out := outputs[0].(map[string]any)
+	if out["output_id"] != "1700000000000" || out["command"] != nil || out["size_bytes"] != float64(11) {
+		t.Errorf("output = %v", out)
+	}
+}
+
+func TestOutputRead_HeadAndTail(t *testing.T) {
+	srv, _, _ := newOutputsServer(config.DefaultConfig())
+	id := saveLargeOutput(t, srv, "sess_a", "seq", numberedLines(2000))
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputRead, map[string]any{
+		"session_id": "sess_a", "output_id": id, "head_lines": 2,
+	}))
+	if m["output"] != "line 00001 of a large output\nline 00002 of a large output" {
+		t.Errorf("head output = %q", m["output"])
+	}
+	if m["truncated"] != true || m["total_lines"] != float64(2000) || m["shown_lines"] != float64(2) || m["command"] != "seq" {
+		t.Errorf("result = %v", m)
+	}
+
+	m = resultJSON(t, callOutputTool(t, srv.handleShellOutputRead, map[string]any{
+		"session_id": "sess_a", "output_id": id, "tail_lines": 1,
+	}))
+	if m["output"] != "line 02000 of a large output" {
+		t.Errorf("tail output = %q", m["output"])
+	}
+}
+
+func TestOutputRead_Whole(t *testing.T) {
+	srv, _, _ := newOutputsServer(config.DefaultConfig())
+	big := numberedLines(2000)
+	id := saveLargeOutput(t, srv, "sess_a", "seq", big)
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputRead, map[string]any{"session_id": "sess_a", "output_id": id}))
+	if m["output"] != big || m["truncated"] != nil {
+		t.Errorf("got %d bytes, truncated = %v; want the whole output", len(m["output"].(string)), m["truncated"])
+	}
+}
+
+func TestOutputRead_ResourceURI(t *testing.T) {
+	srv, _, _ := newOutputsServer(config.DefaultConfig())
+	id := saveLargeOutput(t, srv, "sess_a", "seq", numberedLines(2000))
+
+	m := resultJSON(t, callOutputTool(t, srv.handleShellOutputRead, map[string]any{
+		"session_id": "sess_a", "output_id": outputResourcePrefix + "sess_a/" + id, "head_lines": 1,
+	}))
+	if m["output"] != "line 00001 of a large output" {
+		t.Errorf("output = %q, want the output the URI names", m["output"])
+	}
+}
+
+func TestOutputRead_TooLargeForInline(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.MaxInlineFileBytes = 1024
+	srv, _, _ := newOutputsServer(cfg)
+	id := saveLargeOutput(t, srv, "sess_a", "seq", numberedLines(2000))
+
+	result := callOutputTool(t, srv.handleShellOutputRead, map[string]any{"session_id": "sess_a", "output_id": id})
+	if !result.IsError || !strings.Contains(resultText(result), "output_too_large") {
+		t.Errorf("result = %s, want output_too_large", resultText(result))
+	}
+
+	result = callOutputTool(t, srv.handleShellOutputRead, map[string]any{"session_id": "sess_a", "output_id": id, "tail_lines": 5})
+	if result.IsError {
+		t.Errorf("tail_lines should be allowed past the limit: %s", resultText(result))
+	}
+}
+
+func TestOutputRead_Invalid(t *testing.T) {
+	srv, _, _ := newOutputsServer(config.DefaultConfig())
+	id := saveLargeOutput(t, srv, "sess_a", "seq", numberedLines(2000))
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing output_id", map[string]any{"session_id": "sess_a"}, "output_id is required"},
+		{"traversal", map[string]any{"session_id": "sess_a", "output_id": "../../etc/passwd"}, "invalid output_id"},
+		{"bad session", map[string]any{"session_id": "../x", "output_id": id}, "invalid session_id"},
+		{"unknown output", map[string]any{"session_id": "sess_a", "output_id": "42"}, "read output sess_a/42"},
+		{"head and tail", map[string]any{"session_id": "sess_a", "output_id": id, "head_lines": 1, "tail_lines": 1}, "cannot use both"},
+		{"bad URI", map[string]any{"session_id": "sess_a", "output_id": outputResourcePrefix + "sess_a/../x"}, "invalid output resource URI"},
+		{"URI of another session", map[string]any{"session_id": "sess_a", "output_id": outputResourcePrefix + "sess_b/" + id}, "belongs to session sess_b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := callOutputTool(t, srv.handleShellOutputRead, tt.args)
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package mcp
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser reports whether info is of a file the server's user
+// owns. Files without ownership information (e.g. in tests) count as owned.
+func ownedByCurrentUser(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return !ok || int(st.Uid) == os.Getuid()
+}
@@ -22,6 +22,7 @@ func (s *Server) registerPeakTTYTools() {
 	s.mcpServer.AddTool(peakTTYStartTool(), s.handlePeakTTYStart)
 	s.mcpServer.AddTool(peakTTYStopTool(), s.handlePeakTTYStop)
 	s.mcpServer.AddTool(peakTTYDeployTool(), s.handlePeakTTYDeploy)
+	s.mcpServer.AddTool(peakTTYScreenTool(), s.handlePeakTTYScreen)
 }
 
 func peakTTYStatusTool() mcp.Tool {
@@ -42,6 +43,7 @@ Returns:
 		mcp.WithString("binary_path",
 			mcp.Description("Path to check for peak-tty binary (default: /tmp/peak-tty)"),
 		),
+		readOnlyTool(),
 	)
 }
 
@@ -64,6 +66,7 @@ as a signal that can be detected by claude-shell-mcp.`),
 		mcp.WithString("binary_path",
 			mcp.Description("Path to peak-tty binary (default: /tmp/peak-tty)"),
 		),
+		additiveTool(),
 	)
 }
 
@@ -76,6 +79,7 @@ Sends SIGTERM to the peak-tty process. Requires sudo.`),
 			mcp.Required(),
 			mcp.Description(descSessionID),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -97,6 +101,7 @@ After deployment, use peak_tty_start to run the daemon (requires sudo).`),
 		mcp.WithBoolean("overwrite",
 			mcp.Description("Overwrite existing binary (default: false)"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -265,6 +270,10 @@ func (s *Server) handlePeakTTYStop(ctx context.Context, req mcp.CallToolRequest)
 }
 
 func (s *Server) handlePeakTTYDeploy(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("peak_tty_deploy"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	binaryPath := mcp.ParseString(req, "binary_path", peakTTYDefaultPath)
 	overwrite := mcp.ParseBoolean(req, "overwrite", false)
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultScreenScrollback is how many scrollback lines shell_peak_tty_screen
+// returns by default.
+const defaultScreenScrollback = 200
+
+func peakTTYScreenTool() mcp.Tool {
+	return mcp.NewTool("shell_peak_tty_screen",
+		mcp.WithDescription(`Show a session's terminal as rendered from its output.
+
+Command output is fed through a terminal screen model (cursor movement,
+erasing, scrolling, the alternate screen; colors dropped), so TUIs and
+progress displays read as a user would have seen them rather than as raw
+escape sequences. Use it after an interactive program (detected by peak-tty,
+or driven with shell_send_raw) to read what it displayed.
+
+Returns:
+- screen: the current screen, one string per row
+- in_alternate_screen: whether a full-screen program (less, htop, vim) is up
+- alternate_screen: the last frame a full-screen program showed before it
+  exited, which the terminal no longer displays
+- scrollback: the newest lines scrolled off the top, oldest first
+
+Command markers are left out. Nothing runs in the session.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithNumber("scrollback_lines",
+			mcp.Description("Maximum scrollback lines to return (default: 200, 0 for none)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+func (s *Server) handlePeakTTYScreen(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	scrollback := mcp.ParseInt(req, "scrollback_lines", defaultScreenScrollback)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if scrollback < 0 {
+		return mcp.NewToolResultError("scrollback_lines must not be negative"), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonResult(sess.Screen(scrollback))
+}
@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandlePeakTTYScreen(t *testing.T) {
+	sess, pty := newInitializedFakeSession("sess_screen")
+	pty.AddResponse(makeExecResponse(fixedCmdID, "\x1b[?1049h\x1b[H\x1b[2J  PID USER\x1b[2;1H  412 deploy\x1b[?1049l", 0))
+	pty.AddResponse(makePwdResponse("/home/test"))
+	if _, err := sess.Exec("htop", 5000); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, err := srv.handlePeakTTYScreen(context.Background(), makeRequest(map[string]any{"session_id": "sess_screen"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	alt, _ := m["alternate_screen"].([]any)
+	if len(alt) != 2 || alt[0] != "  PID USER" || alt[1] != "  412 deploy" {
+		t.Errorf("alternate_screen = %v, want htop's last frame", m["alternate_screen"])
+	}
+	if m["in_alternate_screen"] != false || m["rows"] != float64(24) {
+		t.Errorf("result = %v", m)
+	}
+	if strings.Contains(resultText(result), "___CMD_") {
+		t.Errorf("markers in the screen: %s", resultText(result))
+	}
+}
+
+func TestHandlePeakTTYScreen_Invalid(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newFakeSession("sess_screen"))
+	srv := newTestServer(sm)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing session", map[string]any{}, "session_id"},
+		{"unknown session", map[string]any{"session_id": "sess_nope"}, "sess_nope"},
+		{"negative scrollback", map[string]any{"session_id": "sess_screen", "scrollback_lines": -1}, "scrollback_lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := srv.handlePeakTTYScreen(context.Background(), makeRequest(tt.args))
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
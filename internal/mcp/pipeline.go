@@ -0,0 +1,98 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerPipelineTools registers the single round-trip multi-command tool.
+func (s *Server) registerPipelineTools() {
+	s.mcpServer.AddTool(shellExecPipelineTool(), s.handleShellExecPipeline)
+}
+
+func shellExecPipelineTool() mcp.Tool {
+	return mcp.NewTool("shell_exec_pipeline",
+		mcp.WithDescription(fmt.Sprintf(`Run several independent quick commands in one terminal round-trip.
+
+The commands are sent together as one command line, each between its own
+markers, and the combined output is split back into one result per command
+(status, exit_code, stdout). For a handful of short commands such as
+"uname -r", "df -h /" and "systemctl is-active nginx" this is much faster than
+one shell_exec each, where every command waits for its own round-trip.
+
+Commands run in order regardless of exit codes, each in its own shell, so a
+cd or export does not carry over to the next one. They must be single-line
+and must not read input. If the pipeline times out or the connection drops,
+the commands that finished are still returned: the one that was running has
+status "incomplete" with its output so far and the rest "not_run". Up to %d
+commands.`, session.MaxPipelineCommands)),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithArray("commands",
+			mcp.Required(),
+			mcp.Description("Commands to run, in order"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Timeout for the whole pipeline in milliseconds (default: 30000)"),
+		),
+		destructiveTool(),
+	)
+}
+
+func (s *Server) handleShellExecPipeline(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	commands, err := parseStringArray(req, "commands")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(commands) == 0 {
+		return mcp.NewToolResultError("commands is required"), nil
+	}
+	for i, command := range commands {
+		if heredocPattern.MatchString(command) {
+			return mcp.NewToolResultError(fmt.Sprintf("command %d: heredocs are not supported; use shell_file_put", i+1)), nil
+		}
+		if allowed, reason := s.filter().IsAllowed(command); !allowed {
+			slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+			return mcp.NewToolResultError(fmt.Sprintf("command %d blocked: %s", i+1, reason)), nil
+		}
+		if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+			return errResult, nil
+		}
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	slog.Info("executing pipeline",
+		slog.String("session_id", sessionID),
+		slog.Int("commands", len(commands)),
+	)
+	s.recordingManager.RecordInput(sessionID, strings.Join(commands, "\n")+"\n", false)
+
+	result, err := sess.ExecPipeline(commands, timeoutMs)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for _, step := range result.Steps {
+		if step.Stdout != "" {
+			s.recordingManager.RecordOutput(sessionID, step.Stdout+"\n")
+		}
+	}
+	return jsonResult(result)
+}
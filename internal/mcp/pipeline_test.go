@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+var pipelineMarkerID = regexp.MustCompile(`echo '___CMD_START_([0-9a-f]+)___'`)
+
+// newPipelineServer returns a server with one session whose shell prints
+// outputs[i] and exits with 0 for the i-th command of a pipeline.
+func newPipelineServer(outputs ...string) (*Server, *fakepty.PTY) {
+	pty := fakepty.New().SetResponder(func(written string) string {
+		if written == "pwd\n" {
+			return "pwd\r\n/root\r\n$ "
+		}
+		ids := pipelineMarkerID.FindAllStringSubmatch(written, -1)
+		if ids == nil {
+			return ""
+		}
+		var b strings.Builder
+		b.WriteString(strings.TrimSuffix(written, "\n") + "\r\n")
+		for i, m := range ids {
+			b.WriteString("___CMD_START_" + m[1] + "___\r\n" + outputs[i] + "\r\n")
+			b.WriteString("___CMD_END_" + m[1] + "___0\r\n")
+		}
+		return b.String() + "$ "
+	})
+	sess := session.NewSession("sess_pipe", "local",
+		session.WithPTY(pty),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+		session.WithSessionRandom(fakerand.NewSequential()),
+		session.WithConfig(config.DefaultConfig()),
+	)
+	sess.State = session.StateIdle
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	return newTestServer(sm), pty
+}
+
+func TestHandleShellExecPipeline(t *testing.T) {
+	srv, pty := newPipelineServer("5.15.0-91-generic", "/dev/sda1  40G  12G  28G  30% /", "active")
+
+	result, err := srv.handleShellExecPipeline(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_pipe",
+		"commands":   []any{"uname -r", "df -h / | tail -1", "systemctl is-active nginx"},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "completed" || m["completed"] != float64(3) || m["failed"] != float64(0) {
+		t.Errorf("result = %v", m)
+	}
+	steps, _ := m["steps"].([]any)
+	if len(steps) != 3 {
+		t.Fatalf("steps = %v, want 3", m["steps"])
+	}
+	if step := steps[2].(map[string]any); step["command"] != "systemctl is-active nginx" || step["stdout"] != "active" || step["exit_code"] != float64(0) {
+		t.Errorf("steps[2] = %v", step)
+	}
+	if n := strings.Count(pty.Written(), "___CMD_START_"); n != 3 || strings.Count(strings.TrimSuffix(pty.Written(), "pwd\n"), "\n") != 1 {
+		t.Errorf("written = %q, want the three commands on one line", pty.Written())
+	}
+}
+
+func TestHandleShellExecPipeline_Rejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands any
+		mutate   func(*Server)
+		want     string
+	}{
+		{"no commands", []any{}, nil, "commands is required"},
+		{"not strings", []any{"uname", 42}, nil, "array of strings"},
+		{"heredoc", []any{"cat <<EOF"}, nil, "command 1: heredocs are not supported"},
+		{"blocked", []any{"uname", "shutdown now"}, func(s *Server) {
+			s.replaceCommandFilter([]string{`^shutdown\b`}, nil, true)
+		}, "command 2 blocked"},
+		{"read only", []any{"uname", "rm -rf /tmp/x"}, func(s *Server) { s.config.Security.ReadOnly = true }, "read_only:"},
+		{"multi-line", []any{"for i in 1 2\ndo echo $i; done"}, nil, "single-line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, pty := newPipelineServer()
+			if tt.mutate != nil {
+				tt.mutate(srv)
+			}
+			result, err := srv.handleShellExecPipeline(context.Background(), makeRequest(map[string]any{
+				"session_id": "sess_pipe",
+				"commands":   tt.commands,
+			}))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %s, want error containing %q", resultText(result), tt.want)
+			}
+			if pty.Written() != "" {
+				t.Errorf("wrote %q for a rejected pipeline", pty.Written())
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/acolita/claude-shell-mcp/internal/prompt"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerPromptPatternTools registers the prompt pattern inspection tool.
+func (s *Server) registerPromptPatternTools() {
+	s.mcpServer.AddTool(shellPromptPatternsListTool(), s.handleShellPromptPatternsList)
+}
+
+func shellPromptPatternsListTool() mcp.Tool {
+	return mcp.NewTool("shell_prompt_patterns_list",
+		mcp.WithDescription(`List the prompt detection patterns, in the order they are tried.
+
+Each pattern has its name, regex, prompt type ("password", "confirmation",
+"text", "editor", "pager"), whether input to it is masked, its priority and
+whether it is built in or from prompt_detection.custom_patterns.
+
+With session_id, lists the patterns of that session's detector, fixed when it
+was created. Without, lists what a new session would get from the current
+config, so custom patterns can be checked after editing it; patterns whose
+regex does not compile are reported in invalid. Use shell_debug
+action="explain_prompt" to see which of them match a given output.`),
+		mcp.WithString("session_id",
+			mcp.Description("Session whose detector to list (default: the patterns from the current config)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// PromptPatternInfo is one pattern listed by shell_prompt_patterns_list.
+type PromptPatternInfo struct {
+	Name              string `json:"name"`
+	Regex             string `json:"regex"`
+	Type              string `json:"type"`
+	MaskInput         bool   `json:"mask_input"`
+	Priority          int    `json:"priority,omitempty"`
+	Source            string `json:"source"` // "builtin" or "custom"
+	SuggestedResponse string `json:"suggested_response,omitempty"`
+}
+
+// InvalidPromptPattern is a custom pattern from the config that could not
+// be compiled.
+type InvalidPromptPattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+	Error string `json:"error"`
+}
+
+// PromptPatternsResult is the result of shell_prompt_patterns_list.
+type PromptPatternsResult struct {
+	SessionID string                 `json:"session_id,omitempty"`
+	Builtin   int                    `json:"builtin"`
+	Custom    int                    `json:"custom"`
+	Patterns  []PromptPatternInfo    `json:"patterns"`
+	Invalid   []InvalidPromptPattern `json:"invalid,omitempty"`
+}
+
+func (s *Server) handleShellPromptPatternsList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	if sessionID == "" {
+		return jsonResult(s.configPromptPatterns())
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	entries, err := sess.PromptPatterns()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	result := newPromptPatternsResult(entries)
+	result.SessionID = sessionID
+	return jsonResult(result)
+}
+
+// configPromptPatterns builds the detector a new session would get from the
+// current config, the way session.Initialize does, but reports custom
+// patterns that fail to compile instead of failing.
+func (s *Server) configPromptPatterns() *PromptPatternsResult {
+	detector := prompt.NewDetector()
+	var invalid []InvalidPromptPattern
+	for _, p := range s.config.PromptDetection.CustomPatterns {
+		if err := detector.AddPatternFromConfig(p.Name, p.Regex, p.Type, p.MaskInput, p.Priority); err != nil {
+			invalid = append(invalid, InvalidPromptPattern{Name: p.Name, Regex: p.Regex, Error: err.Error()})
+		}
+	}
+	result := newPromptPatternsResult(detector.Patterns())
+	result.Invalid = invalid
+	return result
+}
+
+func newPromptPatternsResult(entries []prompt.PatternEntry) *PromptPatternsResult {
+	result := &PromptPatternsResult{Patterns: make([]PromptPatternInfo, 0, len(entries))}
+	for _, e := range entries {
+		info := PromptPatternInfo{
+			Name:              e.Name,
+			Type:              string(e.Type),
+			MaskInput:         e.MaskInput,
+			Priority:          e.Priority,
+			Source:            "builtin",
+			SuggestedResponse: e.SuggestedResponse,
+		}
+		if e.Regex != nil {
+			info.Regex = e.Regex.String()
+		}
+		if e.Custom {
+			info.Source = "custom"
+			result.Custom++
+		} else {
+			result.Builtin++
+		}
+		result.Patterns = append(result.Patterns, info)
+	}
+	return result
+}
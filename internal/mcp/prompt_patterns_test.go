@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func callPromptPatternsList(t *testing.T, srv *Server, args map[string]any) map[string]any {
+	t.Helper()
+	result, err := srv.handleShellPromptPatternsList(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestPromptPatternsList_Config(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.CustomPatterns = []config.PatternConfig{
+		{Name: "vault_token", Regex: `Vault token:\s*$`, Type: "password", MaskInput: true, Priority: 5},
+		{Name: "broken", Regex: `([`, Type: "text"},
+	}
+	srv := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), cfg)
+
+	m := callPromptPatternsList(t, srv, map[string]any{})
+	if m["custom"] != float64(1) || m["builtin"] == float64(0) || m["session_id"] != nil {
+		t.Errorf("result = %v", m)
+	}
+	patterns, _ := m["patterns"].([]any)
+	first, _ := patterns[0].(map[string]any)
+	if first["name"] != "vault_token" || first["source"] != "custom" || first["type"] != "password" ||
+		first["mask_input"] != true || first["priority"] != float64(5) || first["regex"] != `Vault token:\s*$` {
+		t.Errorf("patterns[0] = %v, want the custom pattern first", first)
+	}
+	second, _ := patterns[1].(map[string]any)
+	if second["source"] != "builtin" || second["regex"] == "" {
+		t.Errorf("patterns[1] = %v, want a built-in", second)
+	}
+
+	invalid, _ := m["invalid"].([]any)
+	if len(invalid) != 1 {
+		t.Fatalf("invalid = %v", m["invalid"])
+	}
+	if bad, _ := invalid[0].(map[string]any); bad["name"] != "broken" || !strings.Contains(bad["error"].(string), "missing closing ]") {
+		t.Errorf("invalid[0] = %v", bad)
+	}
+}
+
+func TestPromptPatternsList_FollowsConfigReload(t *testing.T) {
+	srv := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), config.DefaultConfig())
+	if m := callPromptPatternsList(t, srv, map[string]any{}); m["custom"] != float64(0) {
+		t.Fatalf("custom = %v before the reload", m["custom"])
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.CustomPatterns = []config.PatternConfig{{Name: "otp", Regex: `OTP:\s*$`}}
+	srv.UpdateConfig(cfg)
+
+	m := callPromptPatternsList(t, srv, map[string]any{})
+	patterns, _ := m["patterns"].([]any)
+	if first, _ := patterns[0].(map[string]any); m["custom"] != float64(1) || first["name"] != "otp" || first["type"] != "text" {
+		t.Errorf("result after the reload = %v", m)
+	}
+}
+
+func TestPromptPatternsList_Session(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.CustomPatterns = []config.PatternConfig{{Name: "vault_token", Regex: `Vault token:\s*$`, Type: "password", MaskInput: true}}
+	sess := session.NewSession("sess_patterns", "local",
+		session.WithPTY(fakepty.New()),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+		session.WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+
+	// The server's config has no custom patterns; the session keeps the
+	// ones it was created with.
+	srv := newTestServerWithFS(sm, fakefs.New())
+	m := callPromptPatternsList(t, srv, map[string]any{"session_id": "sess_patterns"})
+	patterns, _ := m["patterns"].([]any)
+	if first, _ := patterns[0].(map[string]any); m["session_id"] != "sess_patterns" || m["custom"] != float64(1) || first["name"] != "vault_token" {
+		t.Errorf("result = %v", m)
+	}
+}
+
+func TestPromptPatternsList_UnknownSession(t *testing.T) {
+	srv := newTestServerWithFS(fakesessionmgr.New(), fakefs.New())
+	result, err := srv.handleShellPromptPatternsList(context.Background(), makeRequest(map[string]any{"session_id": "nope"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Errorf("result = %s, want an error for an unknown session", resultText(result))
+	}
+}
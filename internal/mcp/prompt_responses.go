@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// promptResponseParam is one element of shell_session_create's
+// prompt_responses array.
+type promptResponseParam struct {
+	Pattern     string `json:"pattern"`
+	Response    string `json:"response"`
+	ResponseEnv string `json:"response_env"`
+	Mask        *bool  `json:"mask"`
+	TimeoutMs   int    `json:"timeout_ms"`
+}
+
+func promptResponsesParam() mcp.ToolOption {
+	return mcp.WithArray("prompt_responses",
+		mcp.Description(`SSH only: prompts to answer in order right after connecting, for multi-step logins (e.g. a bastion asking for a password, then a TOTP code). Each item: {"pattern": regex, "response": text or "response_env": env var holding it, "mask": hide from logs (default true), "timeout_ms": wait for the pattern (default 15000)}`),
+		mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"pattern":      map[string]any{"type": "string"},
+				"response":     map[string]any{"type": "string"},
+				"response_env": map[string]any{"type": "string"},
+				"mask":         map[string]any{"type": "boolean"},
+				"timeout_ms":   map[string]any{"type": "number"},
+			},
+			"required": []string{"pattern"},
+		}),
+	)
+}
+
+// parsePromptResponses reads the prompt_responses argument. Responses may come
+// from an environment variable (response_env) so secrets such as TOTP codes
+// produced by a helper never pass through the tool call.
+func (s *Server) parsePromptResponses(req mcp.CallToolRequest) ([]session.PromptResponse, error) {
+	raw, ok := req.GetArguments()["prompt_responses"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	// Some clients send arrays as a JSON-encoded string.
+	data, isString := raw.(string)
+	var encoded []byte
+	if isString {
+		encoded = []byte(data)
+	} else {
+		var err error
+		if encoded, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("prompt_responses: %w", err)
+		}
+	}
+	var params []promptResponseParam
+	if err := json.Unmarshal(encoded, &params); err != nil {
+		return nil, fmt.Errorf("prompt_responses must be an array of {pattern, response} objects: %w", err)
+	}
+
+	steps := make([]session.PromptResponse, 0, len(params))
+	for i, p := range params {
+		response := p.Response
+		if p.ResponseEnv != "" {
+			if p.Response != "" {
+				return nil, fmt.Errorf("prompt_responses[%d]: set response or response_env, not both", i)
+			}
+			response = s.fs.Getenv(p.ResponseEnv)
+			if response == "" {
+				return nil, fmt.Errorf("prompt_responses[%d]: environment variable %s is empty", i, p.ResponseEnv)
+			}
+		}
+		mask := true
+		if p.Mask != nil {
+			mask = *p.Mask
+		}
+		steps = append(steps, session.PromptResponse{
+			Pattern:  p.Pattern,
+			Response: response,
+			Mask:     mask,
+			Timeout:  time.Duration(p.TimeoutMs) * time.Millisecond,
+		})
+	}
+	if err := session.ValidatePromptResponses(steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
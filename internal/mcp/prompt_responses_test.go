@@ -0,0 +1,194 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/security"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellSessionCreate_PromptResponses(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.SetEnv("BASTION_TOTP", "654321")
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, err := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh",
+		"host": "bastion",
+		"user": "ops",
+		"prompt_responses": []any{
+			map[string]any{"pattern": "Password:", "response": "pw"},
+			map[string]any{"pattern": "Verification code:", "response_env": "BASTION_TOTP", "mask": false, "timeout_ms": float64(30000)},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+
+	want := []session.PromptResponse{
+		{Pattern: "Password:", Response: "pw", Mask: true},
+		{Pattern: "Verification code:", Response: "654321", Mask: false, Timeout: 30 * time.Second},
+	}
+	if len(got.PromptResponses) != len(want) {
+		t.Fatalf("PromptResponses = %+v, want %+v", got.PromptResponses, want)
+	}
+	for i := range want {
+		if got.PromptResponses[i] != want[i] {
+			t.Errorf("PromptResponses[%d] = %+v, want %+v", i, got.PromptResponses[i], want[i])
+		}
+	}
+}
+
+func TestHandleShellSessionCreate_PromptResponsesJSONString(t *testing.T) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode":             "ssh",
+		"host":             "bastion",
+		"user":             "ops",
+		"prompt_responses": `[{"pattern": "Password:", "response": "pw"}]`,
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if len(got.PromptResponses) != 1 || got.PromptResponses[0].Response != "pw" {
+		t.Errorf("PromptResponses = %+v", got.PromptResponses)
+	}
+}
+
+func TestHandleShellSessionCreate_PromptResponsesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{
+			name: "local mode",
+			args: map[string]any{"mode": "local", "prompt_responses": []any{map[string]any{"pattern": "x", "response": "y"}}},
+			want: "only supported in ssh mode",
+		},
+		{
+			name: "bad regex",
+			args: map[string]any{"mode": "ssh", "host": "h", "user": "u", "prompt_responses": []any{map[string]any{"pattern": "(", "response": "y"}}},
+			want: "invalid pattern",
+		},
+		{
+			name: "empty env",
+			args: map[string]any{"mode": "ssh", "host": "h", "user": "u", "prompt_responses": []any{map[string]any{"pattern": "x", "response_env": "UNSET"}}},
+			want: "UNSET is empty",
+		},
+		{
+			name: "not an array",
+			args: map[string]any{"mode": "ssh", "host": "h", "user": "u", "prompt_responses": float64(3)},
+			want: "must be an array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(fakesessionmgr.New())
+			result, err := srv.handleShellSessionCreate(context.Background(), makeRequest(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleShellSessionCreate_NewPasswordEnv(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.SetEnv("DB1_NEW_PASSWORD", "n3w-secret")
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	srv := newTestServerWithFS(sm, ffs)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh", "host": "db1", "user": "deploy", "new_password_env": "DB1_NEW_PASSWORD",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if got.NewPassword != "n3w-secret" {
+		t.Errorf("NewPassword = %q, want the env var's value", got.NewPassword)
+	}
+
+	for _, args := range []map[string]any{
+		{"mode": "local", "new_password_env": "DB1_NEW_PASSWORD"},
+		{"mode": "ssh", "host": "db1", "user": "deploy", "new_password_env": "UNSET_VAR"},
+	} {
+		result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(args))
+		if !result.IsError {
+			t.Errorf("args %v: expected a tool error, got %s", args, resultText(result))
+		}
+	}
+}
+
+func TestHandleShellSessionCreate_PasswordChangeRequired(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		return nil, fmt.Errorf("initialize session: %w: deploy@db1 must change the password", session.ErrPasswordChangeRequired)
+	}
+	srv := newTestServer(sm)
+	srv.authRateLimiter = security.NewAuthRateLimiter(1, time.Minute)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh", "host": "db1", "user": "deploy",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["status"] != "password_change_required" || m["host"] != "db1" || m["user"] != "deploy" {
+		t.Errorf("result = %v", m)
+	}
+	if locked, _ := srv.authRateLimiter.IsLocked("db1", "deploy"); locked {
+		t.Error("a forced password change counted as an auth failure")
+	}
+}
+
+func TestHandleShellSessionCreate_CertExpired(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		return nil, fmt.Errorf("initialize session: build auth methods: certificate auth: %w: certificate /keys/fleet-cert.pub expired", ssh.ErrCertExpired)
+	}
+	srv := newTestServer(sm)
+	srv.authRateLimiter = security.NewAuthRateLimiter(1, time.Minute)
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"mode": "ssh", "host": "db1", "user": "deploy",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "cert_expired") {
+		t.Fatalf("result = %s, want a cert_expired error", resultText(result))
+	}
+	if locked, _ := srv.authRateLimiter.IsLocked("db1", "deploy"); locked {
+		t.Error("an expired certificate counted as an auth failure")
+	}
+}
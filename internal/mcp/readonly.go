@@ -0,0 +1,56 @@
+package mcp
+
+import (
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/prompt"
+	"github.com/acolita/claude-shell-mcp/internal/security"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// readOnly reports whether the server runs in read-only (safe) mode.
+func (s *Server) readOnly() bool {
+	return s.config != nil && s.config.Security.ReadOnly
+}
+
+// checkReadOnly rejects a mutating tool when read-only mode is enabled.
+func (s *Server) checkReadOnly(tool string) *mcp.CallToolResult {
+	if !s.readOnly() {
+		return nil
+	}
+	slog.Warn("tool rejected in read-only mode", slog.String("tool", tool))
+	return mcp.NewToolResultError("read_only: " + tool + " is disabled while the server is in read-only mode")
+}
+
+// checkReadOnlyCommand rejects a command that looks mutating when read-only
+// mode is enabled.
+func (s *Server) checkReadOnlyCommand(command string) *mcp.CallToolResult {
+	if !s.readOnly() {
+		return nil
+	}
+	mutating, reason := security.IsMutatingCommand(command)
+	if !mutating {
+		return nil
+	}
+	slog.Warn("command rejected in read-only mode", slog.String("command", command), slog.String("reason", reason))
+	return mcp.NewToolResultError("read_only: command rejected in read-only mode (" + reason + ")")
+}
+
+// checkReadOnlyInput guards input typed into a waiting session: in read-only
+// mode only answers to a detected prompt are let through, and those that
+// look like a mutating command are rejected too. Passwords are not checked,
+// so they never reach the log.
+func (s *Server) checkReadOnlyInput(tool string, sess *session.Session, input string) *mcp.CallToolResult {
+	if !s.readOnly() {
+		return nil
+	}
+	switch sess.AwaitingPromptType() {
+	case "":
+		slog.Warn("input rejected in read-only mode", slog.String("tool", tool))
+		return mcp.NewToolResultError("read_only: " + tool + " can only answer a detected prompt while the server is in read-only mode")
+	case string(prompt.PromptTypePassword):
+		return nil
+	}
+	return s.checkReadOnlyCommand(input)
+}
@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+func newReadOnlyTestServer() (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	ffs.AddFile("/etc/app.conf", []byte("key=value\n"), 0644)
+
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_ro"))
+
+	cfg := config.DefaultConfig()
+	cfg.Security.ReadOnly = true
+	return newTestServerWithConfig(sm, ffs, cfg), ffs
+}
+
+func TestReadOnly_RejectsMutatingTools(t *testing.T) {
+	srv, ffs := newReadOnlyTestServer()
+
+	tests := []struct {
+		name    string
+		handler func(context.Context, mcpgo.CallToolRequest) (*mcpgo.CallToolResult, error)
+		args    map[string]any
+	}{
+		{"file_put", srv.handleShellFilePut, map[string]any{"remote_path": "/tmp/new.txt", "content": "x"}},
+		{"file_mv", srv.handleShellFileMv, map[string]any{"source": "/etc/app.conf", "destination": "/tmp/app.conf"}},
+		{"file_relay", srv.handleShellFileRelay, map[string]any{"source_session_id": "sess_ro", "source_path": "/etc/app.conf", "dest_session_id": "sess_ro", "dest_path": "/tmp/app.conf"}},
+		{"file_patch", srv.handleShellFilePatch, map[string]any{"path": "/etc/app.conf", "edits": `[{"search": "a", "replace": "b"}]`}},
+		{"job_kill", srv.handleShellJobKill, map[string]any{"job_id": 1}},
+		{"dir_chmod", srv.handleShellDirChmod, map[string]any{"path": "/etc", "file_mode": "0600"}},
+		{"sudo_shell", srv.handleShellSudoShell, map[string]any{}},
+		{"dir_put", srv.handleShellDirPut, map[string]any{"local_path": "/src", "remote_path": "/dst"}},
+		{"file_put_chunked", srv.handleShellFilePutChunked, map[string]any{"local_path": "/src.bin", "remote_path": "/dst.bin"}},
+		{"peak_tty_deploy", srv.handlePeakTTYDeploy, map[string]any{}},
+		{"exec rm", srv.handleShellExec, map[string]any{"command": "rm /etc/app.conf"}},
+		{"exec redirect", srv.handleShellExec, map[string]any{"command": "echo x > /etc/app.conf"}},
+		{"exec_stdin tee", srv.handleShellExecStdin, map[string]any{"command": "tee /etc/app.conf", "stdin": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["session_id"] = "sess_ro"
+			result, err := tt.handler(context.Background(), makeRequest(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.HasPrefix(resultText(result), "read_only:") {
+				t.Errorf("expected read_only error, got %q", resultText(result))
+			}
+		})
+	}
+
+	if _, err := ffs.Stat("/tmp/new.txt"); err == nil {
+		t.Error("file_put wrote a file in read-only mode")
+	}
+	if _, err := ffs.Stat("/etc/app.conf"); err != nil {
+		t.Error("source file was moved in read-only mode")
+	}
+}
+
+func TestReadOnly_AllowsReads(t *testing.T) {
+	srv, _ := newReadOnlyTestServer()
+
+	result, err := srv.handleShellFileGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_ro",
+		"remote_path": "/etc/app.conf",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("file_get rejected in read-only mode: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["content"] != "key=value\n" {
+		t.Errorf("content = %q", m["content"])
+	}
+
+	result, err = srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_ro",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("session_status rejected in read-only mode: %v %s", err, resultText(result))
+	}
+}
+
+func TestReadOnly_ReadOnlyExecPassesGuard(t *testing.T) {
+	srv, _ := newReadOnlyTestServer()
+	if errResult := srv.checkReadOnlyCommand("cat /etc/app.conf | grep key"); errResult != nil {
+		t.Errorf("read-only command rejected: %s", resultText(errResult))
+	}
+}
+
+func TestReadOnly_DisabledByDefault(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+	if errResult := srv.checkReadOnly("shell_file_put"); errResult != nil {
+		t.Errorf("read-only guard active without config: %s", resultText(errResult))
+	}
+	if errResult := srv.checkReadOnlyCommand("rm -rf /tmp/x"); errResult != nil {
+		t.Errorf("command guard active without config: %s", resultText(errResult))
+	}
+}
+
+// newReadOnlyShellServer returns a read-only server with an initialized
+// session id on a fake PTY.
+func newReadOnlyShellServer(t *testing.T, id string) (*Server, *session.Session, *fakepty.PTY) {
+	t.Helper()
+	sess, pty := newFakeSessionWithRand(id)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+	cfg := config.DefaultConfig()
+	cfg.Security.ReadOnly = true
+	return newTestServerWithConfig(sm, fakefs.New(), cfg), sess, pty
+}
+
+func TestReadOnly_RejectsRawInput(t *testing.T) {
+	srv, sess, pty := newReadOnlyShellServer(t, "sess_raw")
+	sess.RawMode = true
+	written := pty.Written()
+
+	result, err := srv.handleShellSendRaw(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_raw",
+		"input":      "rm -rf /srv/data\n",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.HasPrefix(resultText(result), "read_only:") {
+		t.Errorf("expected read_only error, got %q", resultText(result))
+	}
+	if pty.Written() != written {
+		t.Errorf("raw input reached the shell: %q", strings.TrimPrefix(pty.Written(), written))
+	}
+}
+
+func TestReadOnly_ProvideInputOnlyAnswersPrompts(t *testing.T) {
+	srv, sess, pty := newReadOnlyShellServer(t, "sess_prompt")
+
+	provide := func(input string) *mcpgo.CallToolResult {
+		t.Helper()
+		result, err := srv.handleShellProvideInput(context.Background(), makeRequest(map[string]any{
+			"session_id": "sess_prompt",
+			"input":      input,
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	// A quiet command is waiting, but not at a detected prompt: whatever is
+	// typed could be a command for a nested shell.
+	sess.State = session.StateAwaitingInput
+	if result := provide("rm -rf /srv/data"); !result.IsError || !strings.HasPrefix(resultText(result), "read_only:") {
+		t.Errorf("input without a prompt: got %q, want read_only error", resultText(result))
+	}
+	sess.State = session.StateIdle
+
+	pty.AddResponse("Do you want to continue? [y/N] ")
+	if result, err := sess.Exec("apt-get -s upgrade", 1000); err != nil || result.Status != "awaiting_input" {
+		t.Fatalf("Exec() = %+v, %v, want awaiting_input", result, err)
+	}
+	if result := provide("rm -rf /srv/data"); !result.IsError || !strings.HasPrefix(resultText(result), "read_only:") {
+		t.Errorf("mutating answer: got %q, want read_only error", resultText(result))
+	}
+	pty.AddResponse("n\n___CMD_END_MARKER___1\n")
+	if result := provide("n"); result.IsError {
+		t.Errorf("answer to a prompt rejected: %s", resultText(result))
+	}
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Default terminal size written to the header of on-demand recordings.
+const (
+	defaultRecordingWidth  = 120
+	defaultRecordingHeight = 24
+)
+
+// registerRecordingTools registers the on-demand session recording tools.
+func (s *Server) registerRecordingTools() {
+	s.mcpServer.AddTool(shellSessionRecordStartTool(), s.handleShellSessionRecordStart)
+	s.mcpServer.AddTool(shellSessionRecordStopTool(), s.handleShellSessionRecordStop)
+}
+
+func shellSessionRecordStartTool() mcp.Tool {
+	return mcp.NewTool("shell_session_record_start",
+		mcp.WithDescription(`Start recording a session's terminal I/O to an asciinema (asciicast v2) file.
+
+Useful for debugging interactive sessions: every command, its output and
+each shell_provide_input is written with timestamps. Input to password
+prompts is redacted. Stop with shell_session_record_stop and replay the
+file with "asciinema play <recording_path>".
+
+Works whether or not recording.enabled is set in the config.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("The session ID to record"),
+		),
+		mcp.WithNumber("width",
+			mcp.Description("Terminal width in the recording header (default: 120)"),
+		),
+		mcp.WithNumber("height",
+			mcp.Description("Terminal height in the recording header (default: 24)"),
+		),
+		additiveTool(),
+	)
+}
+
+func shellSessionRecordStopTool() mcp.Tool {
+	return mcp.NewTool("shell_session_record_stop",
+		mcp.WithDescription(`Stop recording a session and return the path of the recording file.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description("The session ID to stop recording"),
+		),
+		additiveTool(),
+	)
+}
+
+// RecordingResult is the result of shell_session_record_start and
+// shell_session_record_stop.
+type RecordingResult struct {
+	SessionID     string `json:"session_id"`
+	RecordingPath string `json:"recording_path"`
+	Status        string `json:"status"`
+	Hint          string `json:"hint,omitempty"`
+}
+
+func (s *Server) handleShellSessionRecordStart(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if _, err := s.sessionManager.Get(sessionID); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if s.recordingManager.IsRecording(sessionID) {
+		return mcp.NewToolResultError(fmt.Sprintf("session %s is already being recorded to %s",
+			sessionID, s.recordingManager.GetRecordingPath(sessionID))), nil
+	}
+
+	width := mcp.ParseInt(req, "width", defaultRecordingWidth)
+	height := mcp.ParseInt(req, "height", defaultRecordingHeight)
+	if width <= 0 || height <= 0 {
+		return mcp.NewToolResultError("width and height must be positive"), nil
+	}
+
+	path, err := s.recordingManager.Start(sessionID, width, height)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("start recording: %v", err)), nil
+	}
+	slog.Info("session recording started",
+		slog.String("session_id", sessionID),
+		slog.String("recording_path", path),
+	)
+
+	return jsonResult(RecordingResult{
+		SessionID:     sessionID,
+		RecordingPath: path,
+		Status:        "recording",
+	})
+}
+
+func (s *Server) handleShellSessionRecordStop(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if !s.recordingManager.IsRecording(sessionID) {
+		return mcp.NewToolResultError(fmt.Sprintf("session %s is not being recorded", sessionID)), nil
+	}
+
+	path := s.recordingManager.GetRecordingPath(sessionID)
+	if err := s.recordingManager.StopRecording(sessionID); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("stop recording: %v", err)), nil
+	}
+	slog.Info("session recording stopped",
+		slog.String("session_id", sessionID),
+		slog.String("recording_path", path),
+	)
+
+	return jsonResult(RecordingResult{
+		SessionID:     sessionID,
+		RecordingPath: path,
+		Status:        "stopped",
+		Hint:          "replay with: asciinema play " + path,
+	})
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newRecordingTestServer(t *testing.T, sm *fakesessionmgr.Manager) (*Server, *fakefs.FS) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Recording.Path = "/recordings"
+	ffs := fakefs.New()
+	return newTestServerWithConfig(sm, ffs, cfg), ffs
+}
+
+// castLines returns the header and events of the recording at path.
+func castLines(t *testing.T, ffs *fakefs.FS, path string) (map[string]any, [][]any) {
+	t.Helper()
+	data, err := ffs.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read recording: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var header map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("header %q: %v", lines[0], err)
+	}
+	var events [][]any
+	for _, line := range lines[1:] {
+		var ev []any
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("event %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return header, events
+}
+
+func TestSessionRecord_StartExecStop(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_rec")
+	sm.AddSession(sess)
+	srv, ffs := newRecordingTestServer(t, sm)
+
+	result, err := srv.handleShellSessionRecordStart(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_rec",
+		"width":      float64(80),
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("record_start failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	path, _ := m["recording_path"].(string)
+	if m["status"] != "recording" || !strings.HasPrefix(path, "/recordings/sess_rec_") || !strings.HasSuffix(path, ".cast") {
+		t.Fatalf("record_start = %v", m)
+	}
+
+	pty.AddResponse("___CMD_START_00010203___\nhello\n___CMD_END_00010203___0\n")
+	result, _ = srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_rec",
+		"command":    "echo hello",
+	}))
+	if result.IsError {
+		t.Fatalf("exec failed: %s", resultText(result))
+	}
+
+	result, err = srv.handleShellSessionRecordStop(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_rec",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("record_stop failed: %v %s", err, resultText(result))
+	}
+	m = resultJSON(t, result)
+	if m["status"] != "stopped" || m["recording_path"] != path {
+		t.Errorf("record_stop = %v", m)
+	}
+	if hint, _ := m["hint"].(string); !strings.Contains(hint, "asciinema play "+path) {
+		t.Errorf("hint = %q", hint)
+	}
+
+	header, events := castLines(t, ffs, path)
+	if header["version"] != float64(2) || header["width"] != float64(80) || header["height"] != float64(24) {
+		t.Errorf("header = %v", header)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %v, want input and output", events)
+	}
+	if events[0][1] != "i" || events[0][2] != "echo hello\n" {
+		t.Errorf("input event = %v", events[0])
+	}
+	if events[1][1] != "o" || !strings.Contains(events[1][2].(string), "hello") {
+		t.Errorf("output event = %v", events[1])
+	}
+	if srv.recordingManager.IsRecording("sess_rec") {
+		t.Error("session still recording after stop")
+	}
+}
+
+func TestSessionRecord_MaskedPromptInputRedacted(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_pw")
+	if err := sess.Initialize(); err != nil { // loads the prompt detector
+		t.Fatalf("Initialize: %v", err)
+	}
+	sm.AddSession(sess)
+	srv, ffs := newRecordingTestServer(t, sm)
+
+	pty.AddResponse("___CMD_START_00010203___\n[sudo] password for user: ")
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_pw",
+		"command":    "sudo true",
+	}))
+	if m := resultJSON(t, result); m["status"] != "awaiting_input" {
+		t.Fatalf("exec status = %v, want awaiting_input", m["status"])
+	}
+
+	result, _ = srv.handleShellSessionRecordStart(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_pw",
+	}))
+	path := resultJSON(t, result)["recording_path"].(string)
+
+	pty.AddResponse("\n___CMD_END_00010203___0\n")
+	srv.handleShellProvideInput(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_pw",
+		"input":      "hunter2",
+	}))
+	srv.handleShellSessionRecordStop(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_pw",
+	}))
+
+	data, _ := ffs.ReadFile(path)
+	if strings.Contains(string(data), "hunter2") {
+		t.Fatalf("recording contains the password:\n%s", data)
+	}
+	_, events := castLines(t, ffs, path)
+	if len(events) == 0 || events[0][1] != "i" || !strings.HasPrefix(events[0][2].(string), "***") {
+		t.Errorf("events = %v, want masked input first", events)
+	}
+}
+
+func TestSessionRecord_Errors(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newFakeSessionWithClock("sess_err"))
+	srv, _ := newRecordingTestServer(t, sm)
+	ctx := context.Background()
+	req := makeRequest(map[string]any{"session_id": "sess_err"})
+
+	result, _ := srv.handleShellSessionRecordStop(ctx, req)
+	if !result.IsError || !strings.Contains(resultText(result), "not being recorded") {
+		t.Errorf("stop without start = %q", resultText(result))
+	}
+
+	if result, _ = srv.handleShellSessionRecordStart(ctx, req); result.IsError {
+		t.Fatalf("start failed: %s", resultText(result))
+	}
+	result, _ = srv.handleShellSessionRecordStart(ctx, req)
+	if !result.IsError || !strings.Contains(resultText(result), "already being recorded") {
+		t.Errorf("second start = %q", resultText(result))
+	}
+
+	result, _ = srv.handleShellSessionRecordStart(ctx, makeRequest(map[string]any{"session_id": "sess_missing"}))
+	if !result.IsError {
+		t.Error("expected error for unknown session")
+	}
+
+	result, _ = srv.handleShellSessionRecordStart(ctx, makeRequest(map[string]any{}))
+	if !result.IsError || resultText(result) != errSessionIDRequired {
+		t.Errorf("missing session_id = %q", resultText(result))
+	}
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/security"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerSecurityTools registers the command filter inspection tools.
+func (s *Server) registerSecurityTools() {
+	s.mcpServer.AddTool(shellSecurityStatusTool(), s.handleShellSecurityStatus)
+	s.mcpServer.AddTool(shellSecuritySetTool(), s.handleShellSecuritySet)
+}
+
+func shellSecurityStatusTool() mcp.Tool {
+	return mcp.NewTool("shell_security_status",
+		mcp.WithDescription(`Show the command filter the server is enforcing.
+
+Returns:
+- blocklist / allowlist: regex patterns currently applied to commands
+- filter_mode: "allowlist" (only matching commands run), "blocklist" (matching
+  commands are rejected) or "permissive" (no patterns)
+- read_only: whether security.read_only is on
+- runtime_changes_allowed: whether shell_security_set is enabled
+- overridden: the filter was changed with shell_security_set and differs from
+  the config file`),
+		readOnlyTool(),
+	)
+}
+
+func shellSecuritySetTool() mcp.Tool {
+	return mcp.NewTool("shell_security_set",
+		mcp.WithDescription(`Replace the command blocklist and/or allowlist of the running server.
+
+Only available when the server was started with
+--allow-runtime-security-changes. Changes are in memory only: they are not
+written to the config file and are lost on restart or config reload.
+
+Omitted lists are left as they are; pass an empty array to clear one. If any
+pattern is not a valid regex the call fails and the filter is unchanged.
+Set reset=true to go back to the patterns from the config file.`),
+		mcp.WithArray("blocklist",
+			mcp.Description("Regex patterns of commands to reject"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("allowlist",
+			mcp.Description("Regex patterns of commands to allow; when non-empty, every other command is rejected"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("reset",
+			mcp.Description("Restore the blocklist and allowlist from the config file"),
+		),
+		// It can clear the blocklist, which removes a guardrail.
+		destructiveTool(),
+	)
+}
+
+// SecurityStatusResult is the result of shell_security_status and
+// shell_security_set.
+type SecurityStatusResult struct {
+	Blocklist             []string `json:"blocklist"`
+	Allowlist             []string `json:"allowlist"`
+	FilterMode            string   `json:"filter_mode"`
+	ReadOnly              bool     `json:"read_only"`
+	RuntimeChangesAllowed bool     `json:"runtime_changes_allowed"`
+	Overridden            bool     `json:"overridden"`
+}
+
+func (s *Server) handleShellSecurityStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return jsonResult(s.securityStatus())
+}
+
+func (s *Server) handleShellSecuritySet(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !s.allowRuntimeSecurity {
+		return mcp.NewToolResultError("runtime security changes are disabled; restart the server with --allow-runtime-security-changes to enable shell_security_set"), nil
+	}
+
+	if mcp.ParseBoolean(req, "reset", false) {
+		if err := s.replaceCommandFilter(s.config.Security.CommandBlocklist, s.config.Security.CommandAllowlist, false); err != nil {
+			return mcp.NewToolResultError("reset command filter: " + err.Error()), nil
+		}
+		slog.Warn("command filter reset to config at runtime")
+		return jsonResult(s.securityStatus())
+	}
+
+	args := req.GetArguments()
+	_, hasBlocklist := args["blocklist"]
+	_, hasAllowlist := args["allowlist"]
+	if !hasBlocklist && !hasAllowlist {
+		return mcp.NewToolResultError("blocklist, allowlist or reset is required"), nil
+	}
+
+	blocklist := s.filter().Blocklist()
+	allowlist := s.filter().Allowlist()
+	var err error
+	if hasBlocklist {
+		if blocklist, err = parseStringArray(req, "blocklist"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	if hasAllowlist {
+		if allowlist, err = parseStringArray(req, "allowlist"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if err := s.replaceCommandFilter(blocklist, allowlist, true); err != nil {
+		return mcp.NewToolResultError("command filter unchanged: " + err.Error()), nil
+	}
+	slog.Warn("command filter changed at runtime",
+		slog.Any("blocklist", blocklist),
+		slog.Any("allowlist", allowlist),
+	)
+	return jsonResult(s.securityStatus())
+}
+
+// filter returns the current command filter.
+func (s *Server) filter() *security.CommandFilter {
+	s.filterMu.RLock()
+	defer s.filterMu.RUnlock()
+	return s.commandFilter
+}
+
+// replaceCommandFilter swaps in a filter built from the given patterns,
+// recording whether it overrides the config's. If a pattern does not
+// compile, the previous filter is kept and the error returned.
+func (s *Server) replaceCommandFilter(blocklist, allowlist []string, overridden bool) error {
+	newFilter, err := security.NewCommandFilter(blocklist, allowlist)
+	if err != nil {
+		slog.Warn("failed to update command filter, keeping previous",
+			slog.String("error", err.Error()),
+		)
+		return err
+	}
+	s.filterMu.Lock()
+	s.commandFilter = newFilter
+	s.filterOverridden = overridden
+	s.filterMu.Unlock()
+	slog.Debug("command filter updated")
+	return nil
+}
+
+func (s *Server) securityStatus() SecurityStatusResult {
+	s.filterMu.RLock()
+	result := SecurityStatusResult{
+		Blocklist:             s.commandFilter.Blocklist(),
+		Allowlist:             s.commandFilter.Allowlist(),
+		FilterMode:            "permissive",
+		ReadOnly:              s.config.Security.ReadOnly,
+		RuntimeChangesAllowed: s.allowRuntimeSecurity,
+		Overridden:            s.filterOverridden,
+	}
+	s.filterMu.RUnlock()
+	switch {
+	case len(result.Allowlist) > 0:
+		result.FilterMode = "allowlist"
+	case len(result.Blocklist) > 0:
+		result.FilterMode = "blocklist"
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newSecurityTestServer(allow bool) *Server {
+	cfg := config.DefaultConfig()
+	cfg.Security.CommandBlocklist = []string{`^shutdown`}
+	return NewServer(cfg,
+		WithSessionManager(fakesessionmgr.New()),
+		WithFileSystem(fakefs.New()),
+		WithClock(fakeclock.New(time.Now())),
+		WithRuntimeSecurityChanges(allow),
+	)
+}
+
+func TestHandleShellSecurityStatus(t *testing.T) {
+	srv := newSecurityTestServer(false)
+
+	result, err := srv.handleShellSecurityStatus(context.Background(), makeRequest(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := resultJSON(t, result)
+	if m["filter_mode"] != "blocklist" {
+		t.Errorf("filter_mode = %v, want blocklist", m["filter_mode"])
+	}
+	if bl := m["blocklist"].([]any); len(bl) != 1 || bl[0] != "^shutdown" {
+		t.Errorf("blocklist = %v", m["blocklist"])
+	}
+	if m["runtime_changes_allowed"] != false || m["overridden"] != false {
+		t.Errorf("status = %v", m)
+	}
+}
+
+func TestHandleShellSecuritySet_DisabledByDefault(t *testing.T) {
+	srv := newSecurityTestServer(false)
+
+	result, _ := srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{
+		"blocklist": []any{},
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "--allow-runtime-security-changes") {
+		t.Fatalf("expected disabled error, got %q", resultText(result))
+	}
+	if allowed, _ := srv.filter().IsAllowed("shutdown -h now"); allowed {
+		t.Error("blocklist was cleared although runtime changes are disabled")
+	}
+}
+
+func TestHandleShellSecuritySet_ReplacesFilter(t *testing.T) {
+	srv := newSecurityTestServer(true)
+
+	result, _ := srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{
+		"allowlist": []any{"^ls", "^cat"},
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["filter_mode"] != "allowlist" || m["overridden"] != true {
+		t.Errorf("status = %v, want allowlist mode, overridden", m)
+	}
+	if allowed, _ := srv.filter().IsAllowed("ls -la"); !allowed {
+		t.Error("ls should be allowed")
+	}
+	if allowed, _ := srv.filter().IsAllowed("whoami"); allowed {
+		t.Error("whoami should not be in the allowlist")
+	}
+	if allowed, _ := srv.filter().IsAllowed("shutdown"); allowed {
+		t.Error("omitted blocklist should be kept")
+	}
+	if len(srv.config.Security.CommandAllowlist) != 0 {
+		t.Error("runtime change must not modify the config")
+	}
+}
+
+func TestHandleShellSecuritySet_InvalidRegexKeepsFilter(t *testing.T) {
+	srv := newSecurityTestServer(true)
+
+	result, _ := srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{
+		"blocklist": []any{"[unclosed"},
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "command filter unchanged") {
+		t.Fatalf("expected invalid pattern error, got %q", resultText(result))
+	}
+	if allowed, _ := srv.filter().IsAllowed("shutdown"); allowed {
+		t.Error("previous blocklist should be kept after an invalid pattern")
+	}
+}
+
+func TestHandleShellSecuritySet_Reset(t *testing.T) {
+	srv := newSecurityTestServer(true)
+
+	srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{"blocklist": []any{}}))
+	if allowed, _ := srv.filter().IsAllowed("shutdown"); !allowed {
+		t.Fatal("empty blocklist should allow shutdown")
+	}
+
+	result, _ := srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{"reset": true}))
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	if m := resultJSON(t, result); m["overridden"] != false {
+		t.Errorf("overridden = %v after reset", m["overridden"])
+	}
+	if allowed, _ := srv.filter().IsAllowed("shutdown"); allowed {
+		t.Error("reset should restore the config blocklist")
+	}
+}
+
+func TestHandleShellSecuritySet_RequiresChange(t *testing.T) {
+	srv := newSecurityTestServer(true)
+
+	result, _ := srv.handleShellSecuritySet(context.Background(), makeRequest(nil))
+	if !result.IsError {
+		t.Error("expected error without blocklist, allowlist or reset")
+	}
+}
+
+func TestUpdateConfig_ClearsRuntimeOverride(t *testing.T) {
+	srv := newSecurityTestServer(true)
+	srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{"blocklist": []any{}}))
+
+	srv.UpdateConfig(srv.config)
+	if srv.filterOverridden {
+		t.Error("config reload should replace the runtime filter")
+	}
+	if allowed, _ := srv.filter().IsAllowed("shutdown"); allowed {
+		t.Error("config reload should restore the blocklist")
+	}
+}
+
+// Run with -race: handlers read the filter while shell_security_set swaps it.
+func TestSecuritySet_ConcurrentWithFilterReads(t *testing.T) {
+	srv := newSecurityTestServer(true)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			srv.handleShellSecuritySet(context.Background(), makeRequest(map[string]any{"blocklist": []any{`^reboot\b`}}))
+		}()
+		go func() {
+			defer wg.Done()
+			srv.filter().IsAllowed("reboot")
+			srv.securityStatus()
+		}()
+	}
+	wg.Wait()
+	if allowed, _ := srv.filter().IsAllowed("reboot"); allowed {
+		t.Error("runtime blocklist not applied")
+	}
+}
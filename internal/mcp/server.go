@@ -2,7 +2,11 @@
 package mcp
 
 import (
+	"context"
 	"log/slog"
+	"os"
+	"regexp"
+	"sync"
 
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realclock"
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realdialog"
@@ -21,6 +25,7 @@ type Server struct {
 	sessionManager   sessionManager
 	sudoCache        *security.SudoCache
 	commandFilter    *security.CommandFilter
+	autoSudoPatterns []*regexp.Regexp
 	authRateLimiter  *security.AuthRateLimiter
 	recordingManager *recording.Manager
 	config           *config.Config
@@ -28,6 +33,27 @@ type Server struct {
 	dialogProvider   ports.DialogProvider
 	fs               ports.FileSystem
 	clock            ports.Clock
+	transferLimiter  *transferLimiter
+	fileWatches      *fileWatches
+	netProbes        *netProbes
+	dirListCache     *dirListCache
+
+	// probeTransport opens what shell_net_probe times (tests replace it)
+	probeTransport func(*session.Session) (netProbeTransport, error)
+
+	// Runtime command filter changes (see shell_security_set). filterMu
+	// guards commandFilter and filterOverridden, which tool handlers change
+	// while others read them.
+	allowRuntimeSecurity bool
+	filterMu             sync.RWMutex
+	filterOverridden     bool
+
+	// Graceful shutdown state (see Shutdown).
+	shutdownMu     sync.Mutex
+	shuttingDown   bool
+	inFlight       sync.WaitGroup
+	abortTransfers chan struct{} // closed when the grace period expires
+	stopped        chan struct{} // closed when Shutdown finishes
 }
 
 // ServerOption configures a Server.
@@ -61,6 +87,14 @@ func WithConfigPath(path string) ServerOption {
 	}
 }
 
+// WithRuntimeSecurityChanges enables shell_security_set, which lets a
+// client change the command filter of the running server.
+func WithRuntimeSecurityChanges(allow bool) ServerOption {
+	return func(s *Server) {
+		s.allowRuntimeSecurity = allow
+	}
+}
+
 // WithDialogProvider sets the dialog provider used for interactive user prompts.
 func WithDialogProvider(dp ports.DialogProvider) ServerOption {
 	return func(s *Server) {
@@ -70,13 +104,6 @@ func WithDialogProvider(dp ports.DialogProvider) ServerOption {
 
 // NewServer creates a new MCP server with the given configuration.
 func NewServer(cfg *config.Config, opts ...ServerOption) *Server {
-	mcpServer := server.NewMCPServer(
-		"claude-shell-mcp",
-		"1.5.1",
-		server.WithToolCapabilities(false),
-		server.WithLogging(),
-	)
-
 	// Use sudo cache TTL from config, or default
 	sudoTTL := cfg.Security.SudoCacheTTL
 	if sudoTTL == 0 {
@@ -112,32 +139,53 @@ func NewServer(cfg *config.Config, opts ...ServerOption) *Server {
 	}
 
 	s := &Server{
-		mcpServer:        mcpServer,
-		sessionManager:   session.NewManager(cfg),
 		sudoCache:        security.NewSudoCache(sudoTTL),
 		commandFilter:    commandFilter,
+		autoSudoPatterns: compileAutoSudoPatterns(cfg),
 		authRateLimiter:  security.NewAuthRateLimiter(maxAuthFailures, authLockoutDuration),
-		recordingManager: recording.NewManager(recordingPath, cfg.Recording.Enabled),
 		config:           cfg,
 		dialogProvider:   realdialog.New(),
 		fs:               realfs.New(),
 		clock:            realclock.New(),
+		abortTransfers:   make(chan struct{}),
+		stopped:          make(chan struct{}),
 	}
+	s.sessionManager = session.NewManager(cfg, session.WithCommandCheck(s.checkRecoveredCommand))
+	s.mcpServer = server.NewMCPServer(
+		"claude-shell-mcp",
+		"1.5.1",
+		server.WithToolCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+		server.WithLogging(),
+		server.WithToolHandlerMiddleware(s.trackInFlight),
+		server.WithToolHandlerMiddleware(s.auditTransfers),
+		server.WithRecovery(),
+	)
 
 	// Apply options
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.transferLimiter = newTransferLimiter(cfg.Transfer, s.clock)
+	s.fileWatches = newFileWatches()
+	s.netProbes = newNetProbes()
+	s.dirListCache = newDirListCache()
+	s.probeTransport = sftpNetProbeTransport
+	s.recordingManager = recording.NewManager(recordingPath, cfg.Recording.Enabled,
+		recording.WithFileSystem(s.fs),
+		recording.WithClock(s.clock),
+	)
 
 	s.registerTools()
 
 	return s
 }
 
-// Run starts the MCP server on stdio transport.
+// Run starts the MCP server on stdio transport. It returns once the client
+// disconnects and the server has shut down (see serveStdio).
 func (s *Server) Run() error {
 	slog.Info("starting MCP server on stdio transport")
-	return server.ServeStdio(s.mcpServer)
+	return s.serveStdio(context.Background(), os.Stdin, os.Stdout)
 }
 
 // UpdateConfig applies a new configuration at runtime.
@@ -146,18 +194,8 @@ func (s *Server) UpdateConfig(cfg *config.Config) {
 	slog.Debug("applying config update")
 
 	// Update command filter
-	newFilter, err := security.NewCommandFilter(
-		cfg.Security.CommandBlocklist,
-		cfg.Security.CommandAllowlist,
-	)
-	if err != nil {
-		slog.Warn("failed to update command filter, keeping previous",
-			slog.String("error", err.Error()),
-		)
-	} else {
-		s.commandFilter = newFilter
-		slog.Debug("command filter updated")
-	}
+	_ = s.replaceCommandFilter(cfg.Security.CommandBlocklist, cfg.Security.CommandAllowlist, false)
+	s.autoSudoPatterns = compileAutoSudoPatterns(cfg)
 
 	// Update rate limiter settings
 	maxAuthFailures := cfg.Security.MaxAuthFailures
@@ -176,9 +214,12 @@ func (s *Server) UpdateConfig(cfg *config.Config) {
 	if recordingPath == "" {
 		recordingPath = "/tmp/claude-shell-mcp/recordings"
 	}
-	s.recordingManager = recording.NewManager(recordingPath, cfg.Recording.Enabled)
+	s.recordingManager.Reconfigure(recordingPath, cfg.Recording.Enabled)
 	slog.Debug("recording manager updated")
 
+	// Update transfer limits; running transfers keep their slots
+	s.transferLimiter.configure(cfg.Transfer)
+
 	// Update config reference
 	s.config = cfg
 
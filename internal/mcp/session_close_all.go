@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func shellSessionCloseAllTool() mcp.Tool {
+	return mcp.NewTool("shell_session_close_all",
+		mcp.WithDescription(`Close every session matching the filters, for teardown at the end of a task.
+
+Filters combine: a session is closed only if it matches all of those given.
+Without filters every session is closed. Sessions close concurrently; the
+result lists the closed session IDs, and failures under "errors".
+
+Sessions with a chunked, directory or relay transfer running or queued (see
+shell_transfer_status) are skipped and listed under "skipped" unless force
+is true.`),
+		mcp.WithString("tag",
+			mcp.Description("Only close sessions with this tag"),
+		),
+		mcp.WithString("host",
+			mcp.Description("Only close SSH sessions to this host"),
+		),
+		mcp.WithString("idle_longer_than",
+			mcp.Description("Only close sessions unused for longer than this duration, e.g. \"30m\""),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Also close sessions with a transfer in progress (default: false)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// SessionCloseAllResult is the result of shell_session_close_all.
+type SessionCloseAllResult struct {
+	Closed         []string          `json:"closed"`
+	RecordingPaths map[string]string `json:"recording_paths,omitempty"`
+	Skipped        map[string]string `json:"skipped,omitempty"`
+	Errors         map[string]string `json:"errors,omitempty"`
+}
+
+// sessionFilter selects the sessions shell_session_close_all closes.
+type sessionFilter struct {
+	tag       string
+	host      string
+	idleOver  time.Duration
+	idleSince time.Time
+}
+
+func (f sessionFilter) matches(info session.SessionInfo) bool {
+	if f.tag != "" && !slices.Contains(info.Tags, f.tag) {
+		return false
+	}
+	if f.host != "" && info.Host != f.host {
+		return false
+	}
+	if f.idleOver > 0 {
+		lastUsed, err := time.Parse(time.RFC3339, info.LastUsed)
+		if err != nil || f.idleSince.Sub(lastUsed) <= f.idleOver {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) handleShellSessionCloseAll(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter := sessionFilter{
+		tag:       mcp.ParseString(req, "tag", ""),
+		host:      mcp.ParseString(req, "host", ""),
+		idleSince: s.clock.Now(),
+	}
+	force := mcp.ParseBoolean(req, "force", false)
+	if idle := mcp.ParseString(req, "idle_longer_than", ""); idle != "" {
+		d, err := time.ParseDuration(idle)
+		if err != nil || d < 0 {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid idle_longer_than %q: want a duration like \"30m\"", idle)), nil
+		}
+		filter.idleOver = d
+	}
+
+	result := SessionCloseAllResult{Closed: []string{}}
+	transferring := s.sessionsTransferring()
+	var ids []string
+	for _, info := range s.sessionManager.ListDetailed() {
+		if !filter.matches(info) {
+			continue
+		}
+		if transferring[info.ID] && !force {
+			if result.Skipped == nil {
+				result.Skipped = make(map[string]string)
+			}
+			result.Skipped[info.ID] = "transfer in progress (use force=true to close anyway)"
+			continue
+		}
+		ids = append(ids, info.ID)
+	}
+
+	slog.Info("closing sessions",
+		slog.String("tag", filter.tag),
+		slog.String("host", filter.host),
+		slog.Duration("idle_longer_than", filter.idleOver),
+		slog.Int("sessions", len(ids)),
+		slog.Int("skipped", len(result.Skipped)),
+	)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordingPath, err := s.closeSession(id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if result.Errors == nil {
+					result.Errors = make(map[string]string)
+				}
+				result.Errors[id] = err.Error()
+				return
+			}
+			result.Closed = append(result.Closed, id)
+			if recordingPath != "" {
+				if result.RecordingPaths == nil {
+					result.RecordingPaths = make(map[string]string)
+				}
+				result.RecordingPaths[id] = recordingPath
+			}
+		}()
+	}
+	wg.Wait()
+	slices.Sort(result.Closed)
+
+	return jsonResult(result)
+}
+
+// sessionsTransferring returns the IDs of sessions with a transfer running
+// or waiting for a slot.
+func (s *Server) sessionsTransferring() map[string]bool {
+	running, queued := s.transferLimiter.snapshot()
+	ids := make(map[string]bool, len(running)+len(queued))
+	for _, slot := range append(running, queued...) {
+		ids[slot.SessionID] = true
+	}
+	return ids
+}
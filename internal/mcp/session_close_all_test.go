@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+)
+
+var closeAllNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newCloseAllServer returns a server with four sessions: two tagged "ci" on
+// web1 and web2, an idle one on web1 and an untagged local one.
+func newCloseAllServer() (*Server, *fakesessionmgr.Manager) {
+	sm := fakesessionmgr.New()
+	add := func(id, host string, idle time.Duration, tags ...string) {
+		sess := newFakeSession(id)
+		sess.Host = host
+		sess.Tags = tags
+		sess.LastUsed = closeAllNow.Add(-idle)
+		sm.AddSession(sess)
+	}
+	add("sess_ci1", "web1", time.Minute, "ci")
+	add("sess_ci2", "web2", 2*time.Minute, "ci")
+	add("sess_old", "web1", 2*time.Hour)
+	add("sess_local", "", 0)
+
+	srv := NewServer(config.DefaultConfig(),
+		WithSessionManager(sm),
+		WithFileSystem(fakefs.New()),
+		WithClock(fakeclock.New(closeAllNow)),
+	)
+	return srv, sm
+}
+
+func callCloseAll(t *testing.T, srv *Server, args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	result, err := srv.handleShellSessionCloseAll(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return result
+}
+
+func closedIDs(t *testing.T, result *mcpgo.CallToolResult) string {
+	t.Helper()
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", resultText(result))
+	}
+	var ids []string
+	for _, id := range resultJSON(t, result)["closed"].([]any) {
+		ids = append(ids, id.(string))
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSessionCloseAll_Filters(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"all", map[string]any{}, "sess_ci1,sess_ci2,sess_local,sess_old"},
+		{"tag", map[string]any{"tag": "ci"}, "sess_ci1,sess_ci2"},
+		{"host", map[string]any{"host": "web1"}, "sess_ci1,sess_old"},
+		{"idle", map[string]any{"idle_longer_than": "30m"}, "sess_old"},
+		{"combined", map[string]any{"tag": "ci", "host": "web2", "idle_longer_than": "90s"}, "sess_ci2"},
+		{"no match", map[string]any{"tag": "prod"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, sm := newCloseAllServer()
+			got := closedIDs(t, callCloseAll(t, srv, tt.args))
+			if got != tt.want {
+				t.Errorf("closed = %q, want %q", got, tt.want)
+			}
+			for _, id := range strings.Split(tt.want, ",") {
+				if id == "" {
+					continue
+				}
+				if _, err := sm.Get(id); err == nil {
+					t.Errorf("%s is still open", id)
+				}
+			}
+			if len(sm.ListDetailed())+strings.Count(tt.want, "sess_") != 4 {
+				t.Errorf("%d sessions left, want the unmatched ones kept", len(sm.ListDetailed()))
+			}
+		})
+	}
+}
+
+func TestSessionCloseAll_SkipsTransfers(t *testing.T) {
+	srv, sm := newCloseAllServer()
+	release, err := srv.transferLimiter.acquire(context.Background(), nil, TransferSlot{Tool: "shell_dir_put", SessionID: "sess_ci1"})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+
+	result := callCloseAll(t, srv, map[string]any{"tag": "ci"})
+	if got := closedIDs(t, result); got != "sess_ci2" {
+		t.Errorf("closed = %q, want sess_ci2", got)
+	}
+	skipped, _ := resultJSON(t, result)["skipped"].(map[string]any)
+	if reason, _ := skipped["sess_ci1"].(string); !strings.Contains(reason, "transfer in progress") {
+		t.Errorf("skipped = %v, want sess_ci1 for its transfer", skipped)
+	}
+	if _, err := sm.Get("sess_ci1"); err != nil {
+		t.Error("session closed mid-transfer")
+	}
+
+	if got := closedIDs(t, callCloseAll(t, srv, map[string]any{"tag": "ci", "force": true})); got != "sess_ci1" {
+		t.Errorf("closed with force = %q, want sess_ci1", got)
+	}
+}
+
+func TestSessionCloseAll_AggregatesErrors(t *testing.T) {
+	srv, sm := newCloseAllServer()
+	sm.CloseFunc = func(id string) error {
+		if id == "sess_ci2" {
+			return errors.New("connection reset by peer")
+		}
+		return nil
+	}
+
+	result := callCloseAll(t, srv, map[string]any{"tag": "ci"})
+	if got := closedIDs(t, result); got != "sess_ci1" {
+		t.Errorf("closed = %q, want sess_ci1", got)
+	}
+	errs, _ := resultJSON(t, result)["errors"].(map[string]any)
+	if errs["sess_ci2"] != "connection reset by peer" {
+		t.Errorf("errors = %v, want sess_ci2's close error", errs)
+	}
+}
+
+func TestSessionCloseAll_InvalidIdle(t *testing.T) {
+	srv, sm := newCloseAllServer()
+
+	result := callCloseAll(t, srv, map[string]any{"idle_longer_than": "a while"})
+	if !result.IsError || !strings.Contains(resultText(result), "invalid idle_longer_than") {
+		t.Errorf("result = %s, want invalid idle_longer_than", resultText(result))
+	}
+	if len(sm.ListDetailed()) != 4 {
+		t.Error("sessions closed despite the invalid filter")
+	}
+}
@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+)
+
+// duplicateSessionKey identifies the target of an SSH session for
+// on_duplicate_session.
+func duplicateSessionKey(user, host string, port int) string {
+	return fmt.Sprintf("%s@%s:%d", user, host, port)
+}
+
+// duplicateSessions returns the open SSH sessions to the same
+// user@host:port (and container and identity) as a new session would be,
+// most recently used first.
+func (s *Server) duplicateSessions(opts session.CreateOptions) []session.SessionStatus {
+	var dups []session.SessionStatus
+	for _, info := range s.sessionManager.ListDetailed() {
+		sess, err := s.sessionManager.Get(info.ID)
+		if err != nil || sess.Mode != "ssh" {
+			continue
+		}
+		if sess.Host != opts.Host || sess.User != opts.User || sess.Port != opts.Port || sess.Container != opts.Container || sess.Identity != opts.Identity {
+			continue
+		}
+		if status := sess.Status(); status.State != session.StateClosed {
+			dups = append(dups, status)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].IdleSeconds != dups[j].IdleSeconds {
+			return dups[i].IdleSeconds < dups[j].IdleSeconds
+		}
+		return dups[i].ID < dups[j].ID
+	})
+	return dups
+}
+
+// reusableSession returns the first of dups that was created with the same
+// options as opts, so that reusing it gives the client the session it asked
+// for: the same key and server config, raw mode, pagers, start directory and
+// tags.
+func (s *Server) reusableSession(dups []session.SessionStatus, opts session.CreateOptions) (session.SessionStatus, bool) {
+	tags, _ := session.NormalizeTags(opts.Tags)
+	for _, d := range dups {
+		sess, err := s.sessionManager.Get(d.ID)
+		if err != nil {
+			continue
+		}
+		if sess.KeyPath == opts.KeyPath && sess.Server == opts.Server && sess.RawMode == opts.RawMode &&
+			sess.KeepPagers == opts.KeepPagers && sess.StartDir == opts.Cwd && slices.Equal(sess.Tags, tags) {
+			return d, true
+		}
+	}
+	return session.SessionStatus{}, false
+}
+
+// checkDuplicateSession applies on_duplicate_session to a new SSH session.
+// It returns the result to send instead of creating a session (reuse), or
+// fields to add to the new session's result (warn); both are nil when there
+// is nothing to do.
+func (s *Server) checkDuplicateSession(opts session.CreateOptions) (reused map[string]any, extra map[string]any) {
+	strategy := s.config.OnDuplicateSession
+	if opts.Mode != "ssh" || strategy == "" || strategy == config.DuplicateSessionAllow {
+		return nil, nil
+	}
+	dups := s.duplicateSessions(opts)
+	if len(dups) == 0 {
+		return nil, nil
+	}
+
+	key := duplicateSessionKey(opts.User, opts.Host, opts.Port)
+	ids := make([]string, len(dups))
+	for i, d := range dups {
+		ids[i] = d.ID
+	}
+
+	if strategy == config.DuplicateSessionReuse {
+		existing, ok := s.reusableSession(dups, opts)
+		if !ok {
+			return nil, map[string]any{
+				"duplicate_key":    key,
+				"duplicate_action": config.DuplicateSessionWarn,
+				"duplicate_of":     ids,
+				"warning": fmt.Sprintf("already connected to %s in %s with different session options; opened a new session",
+					key, strings.Join(ids, ", ")),
+			}
+		}
+		slog.Info("reusing existing session",
+			slog.String("session_id", existing.ID),
+			slog.String("target", key),
+		)
+		return map[string]any{
+			"session_id":       existing.ID,
+			"status":           "reused",
+			"mode":             existing.Mode,
+			"shell":            existing.Shell,
+			"cwd":              existing.Cwd,
+			"reused":           true,
+			"duplicate_key":    key,
+			"duplicate_action": config.DuplicateSessionReuse,
+		}, nil
+	}
+
+	return nil, map[string]any{
+		"duplicate_key":    key,
+		"duplicate_action": config.DuplicateSessionWarn,
+		"duplicate_of":     ids,
+		"warning": fmt.Sprintf("already connected to %s in %s; close unused sessions with shell_session_close",
+			key, strings.Join(ids, ", ")),
+	}
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newDuplicateServer returns a server with on_duplicate_session set to
+// strategy and an open SSH session "sess_prod" to deploy@prod:22. created
+// counts the sessions the handler creates.
+func newDuplicateServer(t *testing.T, strategy string) (*Server, *int) {
+	t.Helper()
+	existing := session.NewSession("sess_prod", "ssh",
+		session.WithPTY(fakepty.New()),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+	)
+	existing.Host, existing.User, existing.Port = "prod", "deploy", 22
+	if err := existing.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	existing.Cwd = "/srv/app"
+
+	sm := fakesessionmgr.New()
+	sm.AddSession(existing)
+	created := 0
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		created++
+		return newFakeSession("sess_new"), nil
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.OnDuplicateSession = strategy
+	return newTestServerWithConfig(sm, fakefs.New(), cfg), &created
+}
+
+func createSSHSession(t *testing.T, srv *Server, args map[string]any) map[string]any {
+	t.Helper()
+	req := map[string]any{"mode": "ssh", "host": "prod", "user": "deploy"}
+	for k, v := range args {
+		req[k] = v
+	}
+	result, err := srv.handleShellSessionCreate(context.Background(), makeRequest(req))
+	if err != nil || result.IsError {
+		t.Fatalf("create failed: %v %s", err, resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestHandleShellSessionCreate_DuplicateReuse(t *testing.T) {
+	srv, created := newDuplicateServer(t, config.DuplicateSessionReuse)
+
+	m := createSSHSession(t, srv, nil)
+	if *created != 0 {
+		t.Errorf("created %d sessions, want the existing one reused", *created)
+	}
+	if m["session_id"] != "sess_prod" || m["reused"] != true || m["status"] != "reused" {
+		t.Errorf("session_id=%v reused=%v status=%v, want sess_prod reused", m["session_id"], m["reused"], m["status"])
+	}
+	if m["duplicate_key"] != "deploy@prod:22" || m["duplicate_action"] != "reuse" || m["cwd"] != "/srv/app" {
+		t.Errorf("duplicate_key=%v duplicate_action=%v cwd=%v", m["duplicate_key"], m["duplicate_action"], m["cwd"])
+	}
+
+	// A different port is a different target.
+	m = createSSHSession(t, srv, map[string]any{"port": 2222})
+	if *created != 1 || m["session_id"] != "sess_new" || m["reused"] != nil {
+		t.Errorf("created=%d session_id=%v reused=%v, want a new session", *created, m["session_id"], m["reused"])
+	}
+}
+
+func TestHandleShellSessionCreate_DuplicateReuseNeedsSameOptions(t *testing.T) {
+	for _, args := range []map[string]any{
+		{"raw_mode": true},
+		{"tags": []any{"db"}},
+		{"cwd": "/srv/app"},
+		{"key_path": "~/.ssh/other_key"},
+	} {
+		srv, created := newDuplicateServer(t, config.DuplicateSessionReuse)
+
+		m := createSSHSession(t, srv, args)
+		if *created != 1 || m["session_id"] != "sess_new" || m["reused"] != nil {
+			t.Errorf("%v: created=%d session_id=%v reused=%v, want a new session", args, *created, m["session_id"], m["reused"])
+		}
+		if w, _ := m["warning"].(string); m["duplicate_action"] != "warn" || !strings.Contains(w, "different session options") {
+			t.Errorf("%v: duplicate_action=%v warning=%q, want a warning about the options", args, m["duplicate_action"], w)
+		}
+	}
+}
+
+func TestHandleShellSessionCreate_DuplicateWarn(t *testing.T) {
+	srv, created := newDuplicateServer(t, config.DuplicateSessionWarn)
+
+	m := createSSHSession(t, srv, nil)
+	if *created != 1 || m["session_id"] != "sess_new" {
+		t.Fatalf("created=%d session_id=%v, want a new session", *created, m["session_id"])
+	}
+	if m["duplicate_action"] != "warn" || m["duplicate_key"] != "deploy@prod:22" {
+		t.Errorf("duplicate_action=%v duplicate_key=%v", m["duplicate_action"], m["duplicate_key"])
+	}
+	if dups, _ := m["duplicate_of"].([]any); len(dups) != 1 || dups[0] != "sess_prod" {
+		t.Errorf("duplicate_of = %v, want [sess_prod]", m["duplicate_of"])
+	}
+	if w, _ := m["warning"].(string); !strings.Contains(w, "sess_prod") {
+		t.Errorf("warning = %q, want it to name sess_prod", w)
+	}
+}
+
+func TestHandleShellSessionCreate_DuplicateAllow(t *testing.T) {
+	srv, created := newDuplicateServer(t, config.DuplicateSessionAllow)
+
+	m := createSSHSession(t, srv, nil)
+	if *created != 1 || m["duplicate_key"] != nil {
+		t.Errorf("created=%d duplicate_key=%v, want a new session and no duplicate report", *created, m["duplicate_key"])
+	}
+}
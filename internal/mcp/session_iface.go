@@ -15,15 +15,20 @@ type sessionManager interface {
 	Get(id string) (*session.Session, error)
 	Close(id string) error
 	ListDetailed() []session.SessionInfo
+	PoolStats() session.ConnectionPoolStats
+	Connections() []session.PooledConnection
+	CloseConnection(key string) ([]string, error)
+	CloseAll() error
 }
 
 // managedSession abstracts the operations MCP handlers call on a session.
 type managedSession interface {
 	// Command execution
 	Exec(command string, timeoutMs int) (*session.ExecResult, error)
+	ExecPipeline(commands []string, timeoutMs int) (*session.PipelineResult, error)
 	ProvideInput(input string) (*session.ExecResult, error)
 	SendRaw(input string) (*session.ExecResult, error)
-	Interrupt() error
+	Interrupt() (*session.InterruptResult, error)
 
 	// Session info
 	Status() session.SessionStatus
@@ -43,6 +48,7 @@ type managedSession interface {
 	ControlExec(ctx context.Context, command string) (string, error)
 
 	// Lifecycle
+	Reconnect(force bool) ([]session.TunnelConfig, error)
 	Close() error
 }
 
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerReconnectTools registers the in-place session reconnect tool.
+func (s *Server) registerReconnectTools() {
+	s.mcpServer.AddTool(shellSessionReconnectTool(), s.handleShellSessionReconnect)
+}
+
+func shellSessionReconnectTool() mcp.Tool {
+	return mcp.NewTool("shell_session_reconnect",
+		mcp.WithDescription(`Reconnect a broken SSH session in place, keeping its session_id.
+
+Re-dials the host with the session's original credentials, restores the
+working directory and exported environment variables, and re-creates the
+tunnels that were active (or saved from before an MCP restart). Labels, tags
+and anything else keyed by the session_id carry over, unlike closing the
+session and creating a new one.
+
+Refuses a session whose connection is healthy unless force=true. Any command
+that was running or waiting for input when the connection broke is gone; the
+session is idle afterwards.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSSHSessionID),
+		),
+		mcp.WithBoolean("force",
+			mcp.Description("Reconnect even if the connection looks healthy (default: false)"),
+		),
+		destructiveTool(),
+	)
+}
+
+// SessionReconnectResult is the result of shell_session_reconnect.
+type SessionReconnectResult struct {
+	Status          string               `json:"status"`
+	SessionID       string               `json:"session_id"`
+	Cwd             string               `json:"cwd"`
+	EnvVarsRestored int                  `json:"env_vars_restored"`
+	Tunnels         []TunnelCreateResult `json:"tunnels,omitempty"`
+	TunnelErrors    []string             `json:"tunnel_errors,omitempty"`
+}
+
+func (s *Server) handleShellSessionReconnect(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	force := mcp.ParseBoolean(req, "force", false)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tunnels, err := sess.Reconnect(force)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	status := sess.Status()
+	result := SessionReconnectResult{
+		Status:          "reconnected",
+		SessionID:       sessionID,
+		Cwd:             status.Cwd,
+		EnvVarsRestored: len(status.EnvVars),
+	}
+
+	if len(tunnels) > 0 {
+		tunnelManager, err := sess.TunnelManager()
+		if err != nil {
+			result.TunnelErrors = append(result.TunnelErrors, fmt.Sprintf("restore tunnels: %v", err))
+		} else {
+			restored := restoreTunnels(sessionID, tunnelManager, missingTunnels(tunnelManager.ListTunnels(), tunnels))
+			result.Tunnels = restored.Restored
+			result.TunnelErrors = restored.Errors
+		}
+		sess.ClearSavedTunnels()
+	}
+
+	slog.Info("session reconnected",
+		slog.String("session_id", sessionID),
+		slog.Bool("force", force),
+		slog.Int("tunnels_restored", len(result.Tunnels)),
+	)
+
+	return jsonResult(result)
+}
+
+// missingTunnels returns the configs not already served by an active tunnel.
+// A pooled SSH client that survived the reconnect keeps its tunnels, and
+// re-creating them would fail on the bound port.
+func missingTunnels(active []*ssh.Tunnel, configs []session.TunnelConfig) []session.TunnelConfig {
+	var missing []session.TunnelConfig
+	for _, tc := range configs {
+		found := false
+		for _, t := range active {
+			if string(t.Type) == tc.Type && t.LocalHost == tc.LocalHost && t.LocalPort == tc.LocalPort &&
+				t.RemoteHost == tc.RemoteHost && t.RemotePort == tc.RemotePort {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, tc)
+		}
+	}
+	return missing
+}
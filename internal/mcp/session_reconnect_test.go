@@ -0,0 +1,59 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellSessionReconnect_Errors(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newFakeSession("sess_local"))
+	// An SSH session without a host cannot be re-dialed.
+	sm.AddSession(session.NewSession("sess_ssh", "ssh",
+		session.WithPTY(fakepty.New()),
+		session.WithSessionClock(fakeclock.New(time.Now())),
+	))
+	srv := newTestServer(sm)
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{}, errSessionIDRequired},
+		{map[string]any{"session_id": "nope"}, "not found"},
+		{map[string]any{"session_id": "sess_local"}, "only available for SSH"},
+		{map[string]any{"session_id": "sess_ssh"}, "reconnect failed"},
+	}
+	for _, tt := range tests {
+		result, err := srv.handleShellSessionReconnect(context.Background(), makeRequest(tt.args))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+}
+
+func TestMissingTunnels(t *testing.T) {
+	active := []*ssh.Tunnel{
+		{Type: ssh.TunnelTypeLocal, LocalHost: "127.0.0.1", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432},
+	}
+	configs := []session.TunnelConfig{
+		{Type: "local", LocalHost: "127.0.0.1", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432},
+		{Type: "reverse", LocalHost: "127.0.0.1", LocalPort: 3000, RemoteHost: "0.0.0.0", RemotePort: 8080},
+	}
+
+	missing := missingTunnels(active, configs)
+	if len(missing) != 1 || missing[0].Type != "reverse" {
+		t.Errorf("missing = %+v, want only the reverse tunnel", missing)
+	}
+}
@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// resolveSessionServer returns the configured server named by
+// shell_session_create's server argument, or nil if it is not set. A named
+// server implies ssh mode.
+func (s *Server) resolveSessionServer(req mcp.CallToolRequest) (*config.ServerConfig, *mcp.CallToolResult) {
+	name := mcp.ParseString(req, "server", "")
+	if name == "" {
+		return nil, nil
+	}
+	if mode := mcp.ParseString(req, "mode", "ssh"); mode != "ssh" {
+		return nil, mcp.NewToolResultError("server is only supported in ssh mode")
+	}
+	srv := s.lookupServer(name)
+	if srv == nil {
+		return nil, mcp.NewToolResultError(s.serverNotFoundMessage(name))
+	}
+	return srv, nil
+}
+
+// serverNotFoundMessage says that name is not a configured server and lists
+// the ones that are.
+func (s *Server) serverNotFoundMessage(name string) string {
+	var names []string
+	if s.config != nil {
+		for _, srv := range s.config.Servers {
+			names = append(names, srv.Name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("server %q not found in config (no servers are configured)", name)
+	}
+	return fmt.Sprintf("server %q not found in config (configured: %s)", name, strings.Join(names, ", "))
+}
+
+// serverName returns the name of srv, or "" if it is nil.
+func serverName(srv *config.ServerConfig) string {
+	if srv == nil {
+		return ""
+	}
+	return srv.Name
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newNamedServerServer returns a server with "prod-db" and "web" configured.
+// got receives the options of created sessions.
+func newNamedServerServer() (*Server, *session.CreateOptions) {
+	sm := fakesessionmgr.New()
+	var got session.CreateOptions
+	sm.CreateFunc = func(opts session.CreateOptions) (*session.Session, error) {
+		got = opts
+		return newFakeSession("sess_1"), nil
+	}
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "prod-db", Host: "db1.example.com", Port: 2222, User: "postgres", KeyPath: "~/.ssh/db_key"},
+		{
+			Name: "web", Host: "web.example.com", User: "alice",
+			Identities: []config.IdentityConfig{{Name: "deploy", User: "deploy", AuthConfig: config.AuthConfig{Path: "~/.ssh/deploy_key"}}},
+		},
+	}
+	return newTestServerWithConfig(sm, fakefs.New(), cfg), &got
+}
+
+func TestHandleShellSessionCreate_Server(t *testing.T) {
+	srv, got := newNamedServerServer()
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{"server": "prod-db"}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if got.Mode != "ssh" || got.Host != "db1.example.com" || got.Port != 2222 || got.User != "postgres" {
+		t.Errorf("options = %+v, want the server's host, port and user in ssh mode", *got)
+	}
+	// The key and auth come from the server config, which the session looks
+	// up by name.
+	if got.KeyPath != "" || got.Server != "prod-db" {
+		t.Errorf("KeyPath = %q, Server = %q, want auth left to the named server", got.KeyPath, got.Server)
+	}
+	m := resultJSON(t, result)
+	if m["server"] != "prod-db" || m["host"] != "db1.example.com" || m["user"] != "postgres" || m["mode"] != "ssh" {
+		t.Errorf("result = %v", m)
+	}
+}
+
+func TestHandleShellSessionCreate_ServerOverrides(t *testing.T) {
+	srv, got := newNamedServerServer()
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{
+		"server": "prod-db", "user": "root", "port": 22, "key_path": "~/.ssh/root_key",
+	}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if got.Host != "db1.example.com" || got.User != "root" || got.Port != 22 || got.KeyPath != "~/.ssh/root_key" {
+		t.Errorf("options = %+v, want the explicit user, port and key", *got)
+	}
+}
+
+func TestHandleShellSessionCreate_ServerWithIdentity(t *testing.T) {
+	srv, got := newNamedServerServer()
+
+	result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{"server": "web", "identity": "deploy"}))
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	if got.Host != "web.example.com" || got.Port != 22 || got.Identity != "deploy" || got.User != "deploy" {
+		t.Errorf("options = %+v, want the identity's user over the server's", *got)
+	}
+}
+
+func TestHandleShellSessionCreate_ServerErrors(t *testing.T) {
+	srv, _ := newNamedServerServer()
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{"server": "prod-dbx"}, `server "prod-dbx" not found in config (configured: prod-db, web)`},
+		{map[string]any{"server": "prod-db", "mode": "local"}, "server is only supported in ssh mode"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("args %v: result = %s, want an error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+
+	empty := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), config.DefaultConfig())
+	result, _ := empty.handleShellSessionCreate(context.Background(), makeRequest(map[string]any{"server": "prod-db"}))
+	if !result.IsError || !strings.Contains(resultText(result), "no servers are configured") {
+		t.Errorf("result = %s, want a not found error", resultText(result))
+	}
+}
+
+func TestHandleShellSessionCreate_ServerSharedHost(t *testing.T) {
+	srv, got := newNamedServerServer()
+	// A second entry for the same host, listed first, with its own identity.
+	srv.config.Servers = append([]config.ServerConfig{{
+		Name: "web-admin", Host: "web.example.com", User: "admin",
+		Identities: []config.IdentityConfig{{Name: "deploy", User: "admin-deploy"}},
+	}}, srv.config.Servers...)
+
+	for _, args := range []map[string]any{
+		{"server": "web", "identity": "deploy"},
+		{"server": "web", "identity": "deploy", "host": "10.0.0.5"},
+	} {
+		result, _ := srv.handleShellSessionCreate(context.Background(), makeRequest(args))
+		if result.IsError {
+			t.Fatalf("%v: unexpected tool error: %s", args, resultText(result))
+		}
+		if got.Server != "web" || got.User != "deploy" {
+			t.Errorf("%v: Server = %q, User = %q, want web's deploy identity", args, got.Server, got.User)
+		}
+	}
+}
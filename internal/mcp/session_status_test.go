@@ -0,0 +1,114 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellSessionStatus_BasicDoesNotTouchShell(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_basic")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	result, err := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_basic",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("status failed: %v %s", err, resultText(result))
+	}
+	if w := pty.Written(); w != "" {
+		t.Errorf("basic status wrote %q to the session", w)
+	}
+	m := resultJSON(t, result)
+	if m["state"] != "idle" {
+		t.Errorf("state = %v, want idle", m["state"])
+	}
+	for _, key := range []string{"env_var_count", "alias_count", "connection_health", "tunnels"} {
+		if _, ok := m[key]; ok {
+			t.Errorf("basic status includes %q", key)
+		}
+	}
+}
+
+func TestHandleShellSessionStatus_Full(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_full")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+	srv.sudoCache.Set("sess_full", []byte("pw"))
+
+	pty.AddResponse("HOME=/home/test\nPATH=/usr/bin\n")
+	pty.AddResponse("alias ll='ls -la'\n")
+
+	result, err := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_full",
+		"detail":     "full",
+	}))
+	if err != nil || result.IsError {
+		t.Fatalf("status failed: %v %s", err, resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["env_var_count"] != float64(2) || m["alias_count"] != float64(1) {
+		t.Errorf("counts = %v env, %v aliases; want 2 and 1", m["env_var_count"], m["alias_count"])
+	}
+	if m["sudo_cache_valid"] != true {
+		t.Errorf("sudo_cache_valid = %v, want true", m["sudo_cache_valid"])
+	}
+	if m["connection_health"] != "healthy" {
+		t.Errorf("connection_health = %v, want healthy", m["connection_health"])
+	}
+	if tunnels, ok := m["tunnels"].([]any); !ok || len(tunnels) != 0 {
+		t.Errorf("tunnels = %v, want []", m["tunnels"])
+	}
+	if _, ok := m["running_command"]; ok {
+		t.Errorf("running_command = %v for an idle session", m["running_command"])
+	}
+	if m["session_id"] != "sess_full" {
+		t.Errorf("session_id = %v, basic fields missing", m["session_id"])
+	}
+}
+
+func TestHandleShellSessionStatus_FullAwaitingInput(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_wait")
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	pty.AddResponse("___CMD_START_00010203___\nok\n___CMD_END_00010203___0\n")
+	if _, err := sess.Exec("apt-get upgrade", 1000); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	sess.State = session.StateAwaitingInput
+	written := len(pty.Written())
+
+	result, _ := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_wait",
+		"detail":     "full",
+	}))
+	m := resultJSON(t, result)
+	if m["running_command"] != "apt-get upgrade" {
+		t.Errorf("running_command = %v, want apt-get upgrade", m["running_command"])
+	}
+	if extra := pty.Written()[written:]; strings.Contains(extra, "env") {
+		t.Errorf("full status typed %q into a session waiting for input", extra)
+	}
+}
+
+func TestHandleShellSessionStatus_InvalidDetail(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newFakeSessionWithClock("sess_x"))
+	srv := newTestServer(sm)
+
+	result, _ := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_x",
+		"detail":     "verbose",
+	}))
+	if !result.IsError || !strings.Contains(resultText(result), "invalid detail") {
+		t.Errorf("expected invalid detail error, got %q", resultText(result))
+	}
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// shutdownAbortWait bounds each step after the grace period: interrupted
+// transfers saving their manifests, then sessions closing.
+const shutdownAbortWait = 5 * time.Second
+
+const errShuttingDown = "server is shutting down"
+
+var errShutdownInProgress = errors.New("shutdown already in progress")
+
+// trackInFlight is tool middleware that rejects calls once shutdown has
+// begun and counts the calls in progress so Shutdown can wait for them.
+func (s *Server) trackInFlight(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		s.shutdownMu.Lock()
+		if s.shuttingDown {
+			s.shutdownMu.Unlock()
+			return mcp.NewToolResultError(errShuttingDown), nil
+		}
+		s.inFlight.Add(1)
+		s.shutdownMu.Unlock()
+
+		defer s.inFlight.Done()
+		return next(ctx, req)
+	}
+}
+
+// ShutdownGracePeriod returns how long running tool calls may take to finish
+// on shutdown (shutdown.grace_period).
+func (s *Server) ShutdownGracePeriod() time.Duration {
+	return s.config.Shutdown.GracePeriod
+}
+
+// Shutdown stops the server gracefully. New tool calls are rejected and calls
+// in progress get until ctx is done to finish. After that, chunked transfers
+// stop at the next chunk boundary with their manifest saved so they can be
+// resumed. Finally all sessions are closed; their persisted metadata is kept
+// so they can be recovered after a restart.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownMu.Lock()
+	if s.shuttingDown {
+		s.shutdownMu.Unlock()
+		return errShutdownInProgress
+	}
+	s.shuttingDown = true
+	s.shutdownMu.Unlock()
+	defer close(s.stopped)
+
+	drained := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		slog.Warn("shutdown grace period expired, interrupting transfers")
+		close(s.abortTransfers)
+		select {
+		case <-drained:
+		case <-s.clock.After(shutdownAbortWait):
+			err = errors.New("tool calls still running after grace period")
+		}
+	}
+
+	closed := make(chan error, 1)
+	go func() { closed <- s.sessionManager.CloseAll() }()
+	select {
+	case closeErr := <-closed:
+		if closeErr != nil && err == nil {
+			err = fmt.Errorf("close sessions: %w", closeErr)
+		}
+	case <-s.clock.After(shutdownAbortWait):
+		if err == nil {
+			err = errors.New("timed out closing sessions")
+		}
+	}
+
+	s.recordingManager.CloseAll()
+	if err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	slog.Info("shutdown complete")
+	return nil
+}
+
+// transfersAborted reports whether running transfers must stop because the
+// shutdown grace period expired.
+func (s *Server) transfersAborted() bool {
+	select {
+	case <-s.abortTransfers:
+		return true
+	default:
+		return false
+	}
+}
+
+// interruptedTransfer saves the manifest of a transfer stopped by shutdown
+// and reports how far it got.
+func (s *Server) interruptedTransfer(manifest *TransferManifest, manifestPath string) (*mcp.CallToolResult, error) {
+	manifest.LastUpdatedAt = s.clock.Now()
+	if err := s.saveManifest(manifest, manifestPath); err != nil {
+		slog.Error("failed to save manifest of interrupted transfer",
+			slog.String("manifest", manifestPath),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	completed := 0
+	for _, chunk := range manifest.Chunks {
+		if chunk.Completed {
+			completed++
+		}
+	}
+	result := ChunkedTransferResult{
+		Status:           "interrupted",
+		ManifestPath:     manifestPath,
+		ManifestRetained: mcp.ToBoolPtr(true),
+		ChunksCompleted:  completed,
+		TotalChunks:      manifest.TotalChunks,
+		BytesTransferred: manifest.BytesSent,
+		TotalBytes:       manifest.TotalSize,
+		Error:            errShuttingDown + "; resume with shell_transfer_resume",
+	}
+	if manifest.TotalSize > 0 {
+		result.Progress = float64(manifest.BytesSent) / float64(manifest.TotalSize) * 100
+	}
+	return jsonResult(result)
+}
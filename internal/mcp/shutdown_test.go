@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func waitForShutdownStart(t *testing.T, srv *Server) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		srv.shutdownMu.Lock()
+		started := srv.shuttingDown
+		srv.shutdownMu.Unlock()
+		if started {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("shutdown did not start")
+}
+
+func TestShutdown_WaitsForInFlightCallsThenClosesSessions(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_drain"))
+	srv := newTestServer(sm)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := srv.trackInFlight(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-release
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	callDone := make(chan *mcp.CallToolResult)
+	go func() {
+		result, _ := handler(context.Background(), makeRequest(nil))
+		callDone <- result
+	}()
+	<-started
+
+	shutdownDone := make(chan error)
+	go func() { shutdownDone <- srv.Shutdown(context.Background()) }()
+	waitForShutdownStart(t, srv)
+
+	// New calls are rejected while draining.
+	rejected, _ := srv.trackInFlight(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		t.Error("handler must not run during shutdown")
+		return nil, nil
+	})(context.Background(), makeRequest(nil))
+	if !rejected.IsError || !strings.Contains(resultText(rejected), errShuttingDown) {
+		t.Errorf("result = %q, want %q error", resultText(rejected), errShuttingDown)
+	}
+
+	select {
+	case <-shutdownDone:
+		t.Fatal("Shutdown returned while a call was in flight")
+	default:
+	}
+
+	close(release)
+	if result := <-callDone; resultText(result) != "done" {
+		t.Errorf("in-flight call result = %q, want done", resultText(result))
+	}
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	if _, err := sm.Get("sess_drain"); err == nil {
+		t.Error("sessions should be closed after shutdown")
+	}
+}
+
+func TestShutdown_GraceExpiryAbortsTransfers(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	started := make(chan struct{})
+	handler := srv.trackInFlight(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		for !srv.transfersAborted() {
+			time.Sleep(time.Millisecond)
+		}
+		return mcp.NewToolResultText("interrupted"), nil
+	})
+	go handler(context.Background(), makeRequest(nil))
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	if !srv.transfersAborted() {
+		t.Error("transfers should be aborted after the grace period")
+	}
+}
+
+func TestShutdown_Twice(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+	if err := srv.Shutdown(context.Background()); err == nil {
+		t.Error("second Shutdown should fail")
+	}
+}
+
+func TestTransferChunks_InterruptedByShutdownSavesManifest(t *testing.T) {
+	ffs := fakefs.New()
+	srv := newTestServerWithFS(fakesessionmgr.New(), ffs)
+	close(srv.abortTransfers)
+
+	manifest := &TransferManifest{
+		Version:     1,
+		Direction:   "put",
+		TotalSize:   300,
+		ChunkSize:   100,
+		TotalChunks: 3,
+		BytesSent:   100,
+		Chunks: []ChunkInfo{
+			{Index: 0, Offset: 0, Size: 100, Completed: true},
+			{Index: 1, Offset: 100, Size: 100},
+			{Index: 2, Offset: 200, Size: 100},
+		},
+	}
+	manifestPath := "/tmp/big.bin.transfer"
+
+	result, err := srv.transferChunksPut(nil, nil, manifest, manifestPath, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := resultJSON(t, result)
+	if m["status"] != "interrupted" || m["chunks_completed"] != float64(1) {
+		t.Errorf("result = %v, want interrupted with 1 chunk completed", m)
+	}
+	if !strings.Contains(m["error"].(string), "shell_transfer_resume") {
+		t.Errorf("error = %v, want resume hint", m["error"])
+	}
+
+	data, err := ffs.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("manifest not saved: %v", err)
+	}
+	var saved TransferManifest
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unmarshal manifest: %v", err)
+	}
+	if saved.BytesSent != 100 || !saved.Chunks[0].Completed || saved.Chunks[1].Completed {
+		t.Errorf("saved manifest = %+v, want progress preserved", saved)
+	}
+}
@@ -0,0 +1,192 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/mark3labs/mcp-go/mcp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sshPreflightCommand reports the login shell of the authenticated user.
+const sshPreflightCommand = `echo "$SHELL"`
+
+// registerSSHPreflightTools registers the SSH credential check tool.
+func (s *Server) registerSSHPreflightTools() {
+	s.mcpServer.AddTool(shellSSHTestTool(), s.handleShellSSHTest)
+}
+
+func shellSSHTestTool() mcp.Tool {
+	return mcp.NewTool("shell_ssh_test",
+		mcp.WithDescription(`Check that SSH credentials work without creating a session.
+
+Connects, authenticates, runs a trivial command and disconnects. Unlike
+shell_server_test it accepts any host (not only configured servers) and also
+verifies that commands can run. Failed attempts count towards the auth rate
+limit like shell_session_create; no session slot is used.
+
+Returns:
+- success: authentication succeeded and the command ran
+- stage: where it failed ("auth", "connect" or "exec")
+- shell: the user's login shell ($SHELL)
+- server_version: the SSH server version string
+- banner: the server's pre-login banner, if any
+- latency_ms: time to connect and authenticate`),
+		mcp.WithString("name",
+			mcp.Description("Server name from config; its host, user, port and auth settings are used"),
+		),
+		mcp.WithString("host",
+			mcp.Description("Remote host (when name is not given)"),
+		),
+		mcp.WithNumber("port",
+			mcp.Description("SSH port (default: 22)"),
+		),
+		mcp.WithString("user",
+			mcp.Description("Remote user (when name is not given)"),
+		),
+		mcp.WithString("key_path",
+			mcp.Description("Path to the private key (default: SSH agent and ~/.ssh keys)"),
+		),
+		mcp.WithNumber("timeout_ms",
+			mcp.Description("Connection timeout in milliseconds (default: 10000)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// SSHTestResult is the result of shell_ssh_test.
+type SSHTestResult struct {
+	Success       bool   `json:"success"`
+	Host          string `json:"host"`
+	Port          int    `json:"port"`
+	User          string `json:"user"`
+	Stage         string `json:"stage,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Shell         string `json:"shell,omitempty"`
+	ServerVersion string `json:"server_version,omitempty"`
+	Banner        string `json:"banner,omitempty"`
+	LatencyMs     int64  `json:"latency_ms"`
+}
+
+func (s *Server) handleShellSSHTest(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name := mcp.ParseString(req, "name", "")
+	host := mcp.ParseString(req, "host", "")
+	port := mcp.ParseInt(req, "port", 0)
+	user := mcp.ParseString(req, "user", "")
+	keyPath := mcp.ParseString(req, "key_path", "")
+	timeoutMs := mcp.ParseInt(req, "timeout_ms", 10000)
+
+	authCfg := ssh.AuthConfig{UseAgent: true, KeyPath: keyPath, Host: host}
+	if name != "" {
+		srv := s.lookupServer(name)
+		if srv == nil {
+			return mcp.NewToolResultError(fmt.Sprintf("server %q not found in config", name)), nil
+		}
+		host, user = srv.Host, srv.User
+		if port == 0 {
+			port = srv.Port
+		}
+		authCfg = s.serverAuthConfig(srv)
+		if keyPath != "" {
+			// The server's certificate belongs to its own key.
+			authCfg.KeyPath = keyPath
+			authCfg.CertPath = ""
+		}
+	}
+	if port == 0 {
+		port = 22
+	}
+	if errResult := s.validateSSHParams(host, user); errResult != nil {
+		return errResult, nil
+	}
+	authCfg.FS = s.fs
+
+	result := SSHTestResult{Host: host, Port: port, User: user}
+
+	authMethods, err := ssh.BuildAuthMethods(authCfg)
+	if err != nil {
+		result.Stage = "auth"
+		result.Error = fmt.Sprintf("build auth methods: %v", err)
+		return jsonResult(result)
+	}
+
+	hostKeyCallback, err := ssh.BuildHostKeyCallback("", s.fs)
+	if err != nil {
+		hostKeyCallback = ssh.InsecureHostKeyCallback()
+	}
+
+	var banner strings.Builder
+	client, err := ssh.NewClient(ssh.ClientOptions{
+		Host:            host,
+		Port:            port,
+		User:            user,
+		AuthMethods:     authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         time.Duration(timeoutMs) * time.Millisecond,
+		Clock:           s.clock,
+		BannerCallback: func(message string) error {
+			banner.WriteString(message)
+			return nil
+		},
+	})
+	if err != nil {
+		result.Stage = "connect"
+		result.Error = fmt.Sprintf("create client: %v", err)
+		return jsonResult(result)
+	}
+
+	start := s.clock.Now()
+	err = client.Connect()
+	result.LatencyMs = s.clock.Now().Sub(start).Milliseconds()
+	result.Banner = strings.TrimSpace(banner.String())
+	if err != nil {
+		s.authRateLimiter.RecordFailure(host, user)
+		result.Stage = "connect"
+		result.Error = err.Error()
+		return jsonResult(result)
+	}
+	defer client.Close()
+	s.authRateLimiter.RecordSuccess(host, user)
+	result.ServerVersion = client.ServerVersion()
+
+	shell, err := runPreflightCommand(client)
+	if err != nil {
+		result.Stage = "exec"
+		result.Error = err.Error()
+		return jsonResult(result)
+	}
+	result.Success = true
+	result.Shell = shell
+
+	slog.Info("ssh preflight succeeded",
+		slog.String("host", host),
+		slog.String("user", user),
+		slog.String("shell", shell),
+	)
+	return jsonResult(result)
+}
+
+// runPreflightCommand runs sshPreflightCommand on a new channel and returns
+// its trimmed output.
+func runPreflightCommand(client *ssh.Client) (string, error) {
+	sess, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer sess.Close()
+
+	out, err := sess.Output(sshPreflightCommand)
+	if err != nil {
+		var exitErr *gossh.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("command exited with status %d", exitErr.ExitStatus())
+		}
+		return "", fmt.Errorf("run command: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
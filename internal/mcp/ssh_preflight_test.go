@@ -0,0 +1,119 @@
+package mcp
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	"github.com/acolita/claude-shell-mcp/internal/testing/mockssh"
+)
+
+// newPreflightServer starts a mock SSH server and returns an MCP server with
+// it configured as "mock", authenticating with the given password.
+func newPreflightServer(t *testing.T, password string) (*Server, *fakesessionmgr.Manager, *mockssh.Server) {
+	t.Helper()
+	mock, err := mockssh.New(mockssh.WithUser("test", "test"))
+	if err != nil {
+		t.Fatalf("start mock ssh: %v", err)
+	}
+	t.Cleanup(func() { mock.Close() })
+
+	port, err := strconv.Atoi(mock.Port())
+	if err != nil {
+		t.Fatalf("parse port: %v", err)
+	}
+
+	ffs := fakefs.New()
+	ffs.SetEnv("MOCK_SSH_PASSWORD", password)
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{
+		Name: "mock",
+		Host: mock.Host(),
+		Port: port,
+		User: "test",
+		Auth: config.AuthConfig{Type: "password", PasswordEnv: "MOCK_SSH_PASSWORD"},
+	}}
+	sm := fakesessionmgr.New()
+	return newTestServerWithConfig(sm, ffs, cfg), sm, mock
+}
+
+func TestHandleShellSSHTest_Success(t *testing.T) {
+	srv, sm, _ := newPreflightServer(t, "test")
+
+	result, err := srv.handleShellSSHTest(context.Background(), makeRequest(map[string]any{"name": "mock"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected tool error: %s", resultText(result))
+	}
+	m := resultJSON(t, result)
+	if m["success"] != true {
+		t.Fatalf("success = %v, want true (result: %v)", m["success"], m)
+	}
+	if m["user"] != "test" {
+		t.Errorf("user = %v, want test", m["user"])
+	}
+	if v, _ := m["server_version"].(string); v == "" {
+		t.Error("expected server_version to be set")
+	}
+	if n := len(sm.ListDetailed()); n != 0 {
+		t.Errorf("preflight created %d sessions, want 0", n)
+	}
+	if locked, _ := srv.authRateLimiter.IsLocked(m["host"].(string), "test"); locked {
+		t.Error("successful preflight should not lock the host")
+	}
+}
+
+func TestHandleShellSSHTest_AuthFailureRecorded(t *testing.T) {
+	srv, _, mock := newPreflightServer(t, "wrong")
+
+	for i := 0; i < 10; i++ {
+		result, err := srv.handleShellSSHTest(context.Background(), makeRequest(map[string]any{"name": "mock"}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.IsError {
+			// Locked out by the rate limiter after repeated failures.
+			if locked, _ := srv.authRateLimiter.IsLocked(mock.Host(), "test"); !locked {
+				t.Fatalf("tool error without lockout: %s", resultText(result))
+			}
+			return
+		}
+		m := resultJSON(t, result)
+		if m["success"] != false {
+			t.Fatalf("success = %v, want false", m["success"])
+		}
+		if m["stage"] != "connect" {
+			t.Errorf("stage = %v, want connect", m["stage"])
+		}
+	}
+	t.Fatal("expected repeated auth failures to trigger a lockout")
+}
+
+func TestHandleShellSSHTest_UnknownServer(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	result, err := srv.handleShellSSHTest(context.Background(), makeRequest(map[string]any{"name": "nope"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error for unknown server")
+	}
+}
+
+func TestHandleShellSSHTest_MissingHost(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	result, err := srv.handleShellSSHTest(context.Background(), makeRequest(map[string]any{"user": "test"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error when host is missing")
+	}
+}
@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"log/slog"
+	"regexp"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+)
+
+// sudoCheckCommand asks sudo whether its own per-tty credentials are still
+// cached, without ever prompting.
+const (
+	sudoCheckCommand   = "sudo -n true 2>/dev/null"
+	sudoCheckTimeoutMs = 5000
+)
+
+// sudoCommandPattern matches commands that invoke sudo.
+var sudoCommandPattern = regexp.MustCompile(`(^|[\s;&|(])sudo\s`)
+
+// sudoCheck is what sudo reported about its credential cache.
+type sudoCheck int
+
+const (
+	sudoUnchecked         sudoCheck = iota // not asked, or the check failed
+	sudoNeedsPassword                      // sudo would prompt for a password
+	sudoCredentialsCached                  // sudo would run without prompting
+)
+
+// sudoScopeTTY reports whether security.sudo_cache_scope is "tty".
+func (s *Server) sudoScopeTTY() bool {
+	return s.config != nil && s.config.Security.SudoCacheScope == config.SudoScopeTTY
+}
+
+// sudoCheckBefore asks sudo about its credential cache before command runs,
+// when sudo_cache_scope is "tty" and command invokes sudo.
+func (s *Server) sudoCheckBefore(sessionID string, sess *session.Session, command string) sudoCheck {
+	if !s.sudoScopeTTY() || !sudoCommandPattern.MatchString(command) {
+		return sudoUnchecked
+	}
+	return s.checkSudoCredentials(sessionID, sess)
+}
+
+// checkSudoCredentials runs sudoCheckCommand in the session, outside its
+// quotas. The session must be idle.
+func (s *Server) checkSudoCredentials(sessionID string, sess *session.Session) sudoCheck {
+	result, err := sess.ExecWithOptions(sudoCheckCommand, sudoCheckTimeoutMs, session.ExecOptions{Uncharged: true})
+	if err != nil {
+		slog.Debug("sudo credential check failed",
+			slog.String("session_id", sessionID),
+			slog.String("error", err.Error()),
+		)
+		return sudoUnchecked
+	}
+	if result.Status != "completed" || result.ExitCode == nil {
+		slog.Debug("sudo credential check did not complete",
+			slog.String("session_id", sessionID),
+			slog.String("status", result.Status),
+		)
+		return sudoUnchecked
+	}
+
+	check := sudoNeedsPassword
+	if *result.ExitCode == 0 {
+		check = sudoCredentialsCached
+	}
+	slog.Debug("sudo credential check",
+		slog.String("session_id", sessionID),
+		slog.Bool("cached", check == sudoCredentialsCached),
+	)
+	return check
+}
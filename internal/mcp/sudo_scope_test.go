@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+// newSudoScopeServer returns a server with the given sudo_cache_scope, a
+// cached sudo password, and an initialized session (prompt detection on).
+func newSudoScopeServer(t *testing.T, scope string) (*Server, *session.Session, *fakepty.PTY) {
+	t.Helper()
+	sess, pty := newFakeSessionWithRand("sess_sudo")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	sm := fakesessionmgr.New()
+	sm.AddSession(sess)
+
+	cfg := config.DefaultConfig()
+	cfg.Security.SudoCacheScope = scope
+	srv := newTestServerWithConfig(sm, fakefs.New(), cfg)
+	srv.sudoCache.Set("sess_sudo", []byte("cachedpw"))
+	return srv, sess, pty
+}
+
+func TestSudoScopeTTY_CachedCredentialsSkipInjection(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeTTY)
+
+	// sudo -n true succeeds, then the command asks for some other password.
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___0\n")
+	pty.AddResponse("/home/user\n") // pwd after the check
+	pty.AddResponse("___CMD_START_04050607___\nEnter password: ")
+
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"command":    "sudo ./install.sh",
+	}))
+	m := resultJSON(t, result)
+	if m["status"] != "awaiting_input" {
+		t.Fatalf("status = %v, want awaiting_input", m["status"])
+	}
+	written := pty.Written()
+	if !strings.Contains(written, "sudo -n true") {
+		t.Errorf("sudo credentials were not checked; wrote %q", written)
+	}
+	if strings.Contains(written, "cachedpw") {
+		t.Error("cached sudo password was injected although sudo's credentials were cached")
+	}
+}
+
+func TestSudoScopeTTY_ExpiredCredentialsInject(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeTTY)
+
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___1\n")
+	pty.AddResponse("/home/user\n")
+	pty.AddResponse("___CMD_START_04050607___\n[sudo] password for user: ")
+	pty.AddResponse("\nok\n___CMD_END_04050607___0\n")
+
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"command":    "sudo systemctl restart nginx",
+	}))
+	m := resultJSON(t, result)
+	if m["sudo_authenticated"] != true {
+		t.Errorf("sudo_authenticated = %v, want true (result %v)", m["sudo_authenticated"], m)
+	}
+	if !strings.Contains(pty.Written(), "cachedpw") {
+		t.Error("cached sudo password was not injected")
+	}
+}
+
+func TestSudoScopeTTY_NonSudoCommandNotChecked(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeTTY)
+
+	pty.AddResponse("___CMD_START_00010203___\nfile\n___CMD_END_00010203___0\n")
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"command":    "ls /etc/sudoers.d",
+	}))
+	if m := resultJSON(t, result); m["status"] != "completed" {
+		t.Fatalf("status = %v, want completed", m["status"])
+	}
+	if strings.Contains(pty.Written(), "sudo -n") {
+		t.Errorf("sudo credentials checked for a command without sudo: %q", pty.Written())
+	}
+}
+
+func TestSudoScopeSession_NoCheck(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeSession)
+
+	pty.AddResponse("___CMD_START_00010203___\n[sudo] password for user: ")
+	pty.AddResponse("\nok\n___CMD_END_00010203___0\n")
+
+	result, _ := srv.handleShellExec(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"command":    "sudo true",
+	}))
+	if m := resultJSON(t, result); m["sudo_authenticated"] != true {
+		t.Errorf("sudo_authenticated = %v, want true", m["sudo_authenticated"])
+	}
+	if strings.Contains(pty.Written(), "sudo -n") {
+		t.Errorf("sudo credentials checked in session scope: %q", pty.Written())
+	}
+}
+
+func TestSudoCommandPattern(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"sudo apt update", true},
+		{"cd /app && sudo make install", true},
+		{"echo hi | sudo tee /etc/motd", true},
+		{"(sudo true)", true},
+		{"ls /etc/sudoers.d", false},
+		{"visudo -c", false},
+		{"echo pseudo code", false},
+	}
+	for _, tt := range tests {
+		if got := sudoCommandPattern.MatchString(tt.command); got != tt.want {
+			t.Errorf("sudoCommandPattern.MatchString(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestHandleShellSessionStatus_FullSudoTTY(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeTTY)
+
+	pty.AddResponse("HOME=/home/test\n")
+	pty.AddResponse("alias ll='ls -la'\n")
+	pty.AddResponse("___CMD_START_00010203___\n___CMD_END_00010203___1\n")
+
+	result, _ := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"detail":     "full",
+	}))
+	m := resultJSON(t, result)
+	if m["sudo_tty_valid"] != false {
+		t.Errorf("sudo_tty_valid = %v, want false", m["sudo_tty_valid"])
+	}
+	if m["sudo_cache_valid"] != true {
+		t.Errorf("sudo_cache_valid = %v, want true", m["sudo_cache_valid"])
+	}
+}
+
+func TestHandleShellSessionStatus_FullSessionScopeOmitsSudoTTY(t *testing.T) {
+	srv, _, pty := newSudoScopeServer(t, config.SudoScopeSession)
+
+	pty.AddResponse("HOME=/home/test\n")
+	pty.AddResponse("alias ll='ls -la'\n")
+
+	result, _ := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sudo",
+		"detail":     "full",
+	}))
+	if v, ok := resultJSON(t, result)["sudo_tty_valid"]; ok {
+		t.Errorf("sudo_tty_valid = %v in session scope", v)
+	}
+	if strings.Contains(pty.Written(), "sudo -n") {
+		t.Errorf("status checked sudo in session scope: %q", pty.Written())
+	}
+}
@@ -0,0 +1,171 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// sudoValidateCommand refreshes the sudo credentials of the session's user,
+// answering the password prompt like any sudo command, so the root shell
+// then starts without one.
+const sudoValidateCommand = "sudo -v"
+
+// sudoValidateTimeoutMs bounds sudoValidateCommand.
+const sudoValidateTimeoutMs = 30000
+
+// registerSudoShellTools registers the tools that move a session into a
+// root shell and back.
+func (s *Server) registerSudoShellTools() {
+	s.mcpServer.AddTool(shellSudoShellTool(), s.handleShellSudoShell)
+	s.mcpServer.AddTool(shellSudoExitTool(), s.handleShellSudoExit)
+}
+
+func shellSudoShellTool() mcp.Tool {
+	return mcp.NewTool("shell_sudo_shell",
+		mcp.WithDescription(`Elevate a session to a root login shell, for a series of commands that all need root.
+
+Runs "sudo -i" (or "sudo su -") in the session's shell. The sudo password is
+answered from the session's sudo cache or the server's sudo_password_env, never
+passing through the LLM; without either, cache it first with
+shell_provide_input(cache_for_sudo=true) on any sudo command.
+
+Afterwards shell_exec and the other tools run as root, in root's home directory
+and environment, with exit codes and prompt detection as usual. The session's
+status shows privileged: true. Call shell_sudo_exit to return to the normal user.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithString("method",
+			mcp.Description(`Command that starts the root shell: "sudo -i" (default) or "sudo su -"`),
+			mcp.Enum(session.SudoShellMethods...),
+		),
+		destructiveTool(),
+	)
+}
+
+func shellSudoExitTool() mcp.Tool {
+	return mcp.NewTool("shell_sudo_exit",
+		mcp.WithDescription(`Leave the root shell started by shell_sudo_shell and return to the normal user.
+
+The session gets back the shell, working directory and environment it had
+before shell_sudo_shell.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		additiveTool(),
+	)
+}
+
+// SudoShellResult is the result of shell_sudo_shell and shell_sudo_exit:
+// the session's context after the switch.
+type SudoShellResult struct {
+	Status     string            `json:"status"` // "elevated" or "exited"
+	SessionID  string            `json:"session_id"`
+	Privileged bool              `json:"privileged"`
+	Method     string            `json:"method,omitempty"`
+	Shell      string            `json:"shell"`
+	Cwd        string            `json:"cwd"`
+	EnvVars    map[string]string `json:"env_vars,omitempty"`
+}
+
+func newSudoShellResult(status string, sess *session.Session) SudoShellResult {
+	st := sess.Status()
+	return SudoShellResult{
+		Status:     status,
+		SessionID:  st.ID,
+		Privileged: st.Privileged,
+		Shell:      st.Shell,
+		Cwd:        st.Cwd,
+		EnvVars:    st.EnvVars,
+	}
+}
+
+func (s *Server) handleShellSudoShell(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_sudo_shell"); errResult != nil {
+		return errResult, nil
+	}
+
+	sessionID := mcp.ParseString(req, "session_id", "")
+	method := mcp.ParseString(req, "method", session.SudoShellMethods[0])
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+	if !slices.Contains(session.SudoShellMethods, method) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid method %q: use %s", method, strings.Join(session.SudoShellMethods, " or "))), nil
+	}
+
+	for _, command := range []string{sudoValidateCommand, method} {
+		if allowed, reason := s.filter().IsAllowed(command); !allowed {
+			slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+			return mcp.NewToolResultError("command blocked: " + reason), nil
+		}
+		if errResult := s.checkReadOnlyCommand(command); errResult != nil {
+			return errResult, nil
+		}
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := sess.CheckSudoShell(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("cannot start a root shell: %v", err)), nil
+	}
+
+	// The check is the server's own, so it is not counted against quotas.
+	execResult, err := s.execInSessionWith(ctx, sessionID, sudoValidateCommand, sudoValidateTimeoutMs, session.ExecOptions{Uncharged: true})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("%s: %v", sudoValidateCommand, err)), nil
+	}
+	if execResult.Status == "awaiting_input" {
+		// No cached password answered the prompt: cancel it so the session
+		// is usable again.
+		if _, err := sess.Interrupt(); err != nil {
+			slog.Warn("interrupt sudo prompt", slog.String("error", err.Error()))
+		}
+		return mcp.NewToolResultError(errSudoShellPassword), nil
+	}
+	if execResult.Status != "completed" || execResult.ExitCode == nil || *execResult.ExitCode != 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("%s failed (status %s): %s",
+			sudoValidateCommand, execResult.Status, strings.TrimSpace(execResult.Stdout))), nil
+	}
+
+	if err := sess.SudoShell(method); err != nil {
+		if errors.Is(err, session.ErrSudoPassword) {
+			return mcp.NewToolResultError(errSudoShellPassword), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("start root shell: %v", err)), nil
+	}
+
+	result := newSudoShellResult("elevated", sess)
+	result.Method = method
+	return jsonResult(result)
+}
+
+// errSudoShellPassword is returned when sudo still asks for a password.
+const errSudoShellPassword = "sudo needs a password to start a root shell: cache it with shell_provide_input(cache_for_sudo=true) or configure sudo_password_env, then retry"
+
+func (s *Server) handleShellSudoExit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if err := sess.SudoExit(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("leave root shell: %v", err)), nil
+	}
+	return jsonResult(newSudoShellResult("exited", sess))
+}
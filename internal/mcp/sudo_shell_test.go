@@ -0,0 +1,226 @@
+package mcp
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+var (
+	sudoShellStartMarker = regexp.MustCompile(`echo '___CMD_START_([0-9a-f]+)___'`)
+	sudoShellUIDProbe    = regexp.MustCompile(`^printf '___UID_%s_%s___\\n' ([0-9a-f]+) "\$\(id -u\)"\n$`)
+)
+
+// fakeRootShell answers like a user's shell where sudo -v wants password
+// (unless it is empty) and sudo -i then starts a root shell.
+type fakeRootShell struct {
+	password  string
+	validated bool
+	root      bool
+	cmdID     string // of the command waiting for the password
+}
+
+func (f *fakeRootShell) respond(written string) string {
+	if m := sudoShellUIDProbe.FindStringSubmatch(written); m != nil {
+		uid := "1000"
+		if f.root {
+			uid = "0"
+		}
+		return "___UID_" + m[1] + "_" + uid + "___\r\n$ "
+	}
+	if m := sudoShellStartMarker.FindStringSubmatch(written); m != nil {
+		start := "___CMD_START_" + m[1] + "___\r\n"
+		if strings.Contains(written, "sudo -v") && f.password != "" && !f.validated {
+			f.cmdID = m[1]
+			return start + "[sudo] password for user: "
+		}
+		return start + "___CMD_END_" + m[1] + "___0\r\n$ "
+	}
+	switch written {
+	case f.password + "\n":
+		f.validated = true
+		return "\r\n___CMD_END_" + f.cmdID + "___0\r\n$ "
+	case "sudo -i\n", "sudo su -\n":
+		if f.password != "" && !f.validated {
+			return "[sudo] password for user: "
+		}
+		f.root = true
+	case "exit\n":
+		f.root = false
+		return "logout\r\n$ "
+	case "echo \"$0\"\n":
+		return "-bash\r\n$ "
+	case "pwd\n":
+		if f.root {
+			return "/root\r\n# "
+		}
+		return "/home/user\r\n$ "
+	case "env\n":
+		if f.root {
+			return "HOME=/root\r\nUSER=root\r\n# "
+		}
+		return "HOME=/home/user\r\nUSER=user\r\n$ "
+	}
+	return ""
+}
+
+func newSudoShellServer(t *testing.T, shell *fakeRootShell) (*Server, *fakepty.PTY) {
+	t.Helper()
+	return newSudoShellServerWithConfig(t, shell, config.DefaultConfig())
+}
+
+func newSudoShellServerWithConfig(t *testing.T, shell *fakeRootShell, cfg *config.Config) (*Server, *fakepty.PTY) {
+	t.Helper()
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_root")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.Cwd = "/home/user"
+	pty.SetResponder(shell.respond)
+	sm.AddSession(sess)
+	return newTestServerWithConfig(sm, fakefs.New(), cfg), pty
+}
+
+func callSudoShell(t *testing.T, srv *Server, args map[string]any) map[string]any {
+	t.Helper()
+	args["session_id"] = "sess_root"
+	result, err := srv.handleShellSudoShell(context.Background(), makeRequest(args))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+	return resultJSON(t, result)
+}
+
+func TestHandleShellSudoShell_CachedPassword(t *testing.T) {
+	srv, pty := newSudoShellServer(t, &fakeRootShell{password: "cachedpw"})
+	srv.sudoCache.Set("sess_root", []byte("cachedpw"))
+
+	m := callSudoShell(t, srv, map[string]any{})
+	if m["status"] != "elevated" || m["privileged"] != true || m["method"] != "sudo -i" || m["cwd"] != "/root" {
+		t.Errorf("result = %v, want an elevated session in /root", m)
+	}
+	if env, _ := m["env_vars"].(map[string]any); env["USER"] != "root" {
+		t.Errorf("env_vars = %v, want root's", m["env_vars"])
+	}
+	written := pty.Written()
+	if !strings.Contains(written, "cachedpw\n") || strings.Index(written, "sudo -v") > strings.Index(written, "sudo -i\n") {
+		t.Errorf("written = %q, want sudo -v answered from the cache before sudo -i", written)
+	}
+
+	status, err := srv.handleShellSessionStatus(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+	if err != nil || status.IsError {
+		t.Fatalf("status failed: %v %s", err, resultText(status))
+	}
+	if st := resultJSON(t, status); st["privileged"] != true {
+		t.Errorf("status privileged = %v, want true", st["privileged"])
+	}
+
+	result, err := srv.handleShellSudoExit(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+	if err != nil || result.IsError {
+		t.Fatalf("sudo exit failed: %v %s", err, resultText(result))
+	}
+	m = resultJSON(t, result)
+	if m["status"] != "exited" || m["privileged"] != false || m["cwd"] != "/home/user" {
+		t.Errorf("result = %v, want the user's session back", m)
+	}
+}
+
+func TestHandleShellSudoShell_SuMethod(t *testing.T) {
+	srv, pty := newSudoShellServer(t, &fakeRootShell{})
+
+	m := callSudoShell(t, srv, map[string]any{"method": "sudo su -"})
+	if m["method"] != "sudo su -" || m["privileged"] != true {
+		t.Errorf("result = %v", m)
+	}
+	if !strings.Contains(pty.Written(), "sudo su -\n") {
+		t.Errorf("written = %q, want sudo su -", pty.Written())
+	}
+}
+
+func TestHandleShellSudoShell_NeedsPassword(t *testing.T) {
+	srv, pty := newSudoShellServer(t, &fakeRootShell{password: "secret"})
+
+	result, _ := srv.handleShellSudoShell(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+	if !result.IsError || !strings.Contains(resultText(result), "sudo needs a password") {
+		t.Fatalf("got %q, want a sudo password error", resultText(result))
+	}
+	if !pty.WasInterrupted() {
+		t.Error("the sudo prompt was not interrupted")
+	}
+	if strings.Contains(pty.Written(), "sudo -i") {
+		t.Errorf("written = %q, want no root shell started", pty.Written())
+	}
+}
+
+func TestHandleShellSudoShell_Validation(t *testing.T) {
+	srv, pty := newSudoShellServer(t, &fakeRootShell{})
+
+	tests := []struct {
+		args map[string]any
+		want string
+	}{
+		{map[string]any{}, "session_id is required"},
+		{map[string]any{"session_id": "sess_root", "method": "sudo bash"}, "invalid method"},
+		{map[string]any{"session_id": "nope"}, "not found"},
+	}
+	for _, tt := range tests {
+		result, _ := srv.handleShellSudoShell(context.Background(), makeRequest(tt.args))
+		if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+			t.Errorf("%v: got %q, want error containing %q", tt.args, resultText(result), tt.want)
+		}
+	}
+	if pty.Written() != "" {
+		t.Errorf("written = %q, want nothing run", pty.Written())
+	}
+}
+
+func TestHandleShellSudoShell_AlreadyPrivileged(t *testing.T) {
+	srv, pty := newSudoShellServer(t, &fakeRootShell{})
+	callSudoShell(t, srv, map[string]any{})
+	written := pty.Written()
+
+	result, _ := srv.handleShellSudoShell(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+	if !result.IsError || !strings.Contains(resultText(result), "already in a root shell") {
+		t.Errorf("got %q, want an already privileged error", resultText(result))
+	}
+	if pty.Written() != written {
+		t.Errorf("a second elevation ran %q", strings.TrimPrefix(pty.Written(), written))
+	}
+}
+
+func TestHandleShellSudoExit_NotPrivileged(t *testing.T) {
+	srv, _ := newSudoShellServer(t, &fakeRootShell{})
+
+	result, _ := srv.handleShellSudoExit(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+	if !result.IsError || !strings.Contains(resultText(result), "not in a root shell") {
+		t.Errorf("got %q, want a not privileged error", resultText(result))
+	}
+}
+
+func TestHandleShellSudoShell_CommandFilter(t *testing.T) {
+	for _, blocked := range []string{`^sudo -i$`, `^sudo -v$`} {
+		t.Run(blocked, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Security.CommandBlocklist = []string{blocked}
+			srv, pty := newSudoShellServerWithConfig(t, &fakeRootShell{}, cfg)
+
+			result, _ := srv.handleShellSudoShell(context.Background(), makeRequest(map[string]any{"session_id": "sess_root"}))
+			if !result.IsError || !strings.Contains(resultText(result), "command blocked") {
+				t.Errorf("got %q, want a command blocked error", resultText(result))
+			}
+			if pty.Written() != "" {
+				t.Errorf("written = %q, want nothing run", pty.Written())
+			}
+		})
+	}
+}
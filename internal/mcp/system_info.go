@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/session"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// registerSystemInfoTools registers the host detection tool.
+func (s *Server) registerSystemInfoTools() {
+	s.mcpServer.AddTool(shellSystemInfoTool(), s.handleShellSystemInfo)
+}
+
+func shellSystemInfoTool() mcp.Tool {
+	return mcp.NewTool("shell_system_info",
+		mcp.WithDescription(`Describe the host a session runs on: OS, distro, version, arch, kernel and shell.
+
+Runs uname, /etc/os-release (or sw_vers on macOS) and a shell version probe in
+one round-trip. Use it to pick the right commands, e.g. apt vs dnf (distro and
+family: "debian", "rhel", "fedora", ...) or amd64 vs arm64 binaries (arch).
+
+The result is cached for the session; set refresh to probe again.`),
+		mcp.WithString("session_id",
+			mcp.Required(),
+			mcp.Description(descSessionID),
+		),
+		mcp.WithBoolean("refresh",
+			mcp.Description("Probe again instead of returning the cached result (default: false)"),
+		),
+		readOnlyTool(),
+	)
+}
+
+// SystemInfoResult is the result of shell_system_info.
+type SystemInfoResult struct {
+	*session.SystemInfo
+	Cached bool `json:"cached"`
+}
+
+func (s *Server) handleShellSystemInfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	refresh := mcp.ParseBoolean(req, "refresh", false)
+
+	if sessionID == "" {
+		return mcp.NewToolResultError(errSessionIDRequired), nil
+	}
+
+	sess, err := s.sessionManager.Get(sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	info, cached, err := sess.SystemInfo(refresh, 10000)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	slog.Debug("system info",
+		slog.String("session_id", sessionID),
+		slog.String("os", info.OS),
+		slog.String("distro", info.Distro),
+		slog.String("arch", info.Arch),
+		slog.Bool("cached", cached),
+	)
+	return jsonResult(SystemInfoResult{SystemInfo: info, Cached: cached})
+}
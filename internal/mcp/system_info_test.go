@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func TestHandleShellSystemInfo(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sess, pty := newFakeSessionWithRand("sess_sysinfo")
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sm.AddSession(sess)
+	srv := newTestServer(sm)
+
+	cmdID := "00010203"
+	pty.AddResponse("___CMD_START_" + cmdID + "___\n")
+	pty.AddResponse("Linux\r\naarch64\r\n6.5.0\r\n5.2.15(1)-release\r\n___SYSINFO_RELEASE___\r\n" +
+		"ID=fedora\r\nVERSION_ID=39\r\n___CMD_END_" + cmdID + "___0\n")
+
+	result, err := srv.handleShellSystemInfo(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sysinfo",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %s", resultText(result))
+	}
+
+	m := resultJSON(t, result)
+	if m["os"] != "linux" || m["distro"] != "fedora" || m["version"] != "39" || m["arch"] != "aarch64" || m["kernel"] != "6.5.0" {
+		t.Errorf("result = %v", m)
+	}
+	if m["cached"] != false {
+		t.Errorf("cached = %v, want false", m["cached"])
+	}
+	if shell, _ := m["shell"].(map[string]any); shell == nil || shell["version"] != "5.2.15(1)-release" {
+		t.Errorf("shell = %v", m["shell"])
+	}
+
+	// Second call is served from the session cache.
+	result, _ = srv.handleShellSystemInfo(context.Background(), makeRequest(map[string]any{
+		"session_id": "sess_sysinfo",
+	}))
+	if m := resultJSON(t, result); m["cached"] != true || m["distro"] != "fedora" {
+		t.Errorf("second call = %v, want cached fedora", m)
+	}
+}
+
+func TestHandleShellSystemInfo_Errors(t *testing.T) {
+	srv := newTestServer(fakesessionmgr.New())
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"missing session", map[string]any{}, "session_id is required"},
+		{"unknown session", map[string]any{"session_id": "nope"}, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := srv.handleShellSystemInfo(context.Background(), makeRequest(tt.args))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), tt.want) {
+				t.Errorf("result = %q, want error containing %q", resultText(result), tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,19 +32,38 @@ func (s *Server) registerTools() {
 	s.mcpServer.AddTool(shellSessionCreateTool(), s.handleShellSessionCreate)
 	s.mcpServer.AddTool(shellSessionListTool(), s.handleShellSessionList)
 	s.mcpServer.AddTool(shellExecTool(), s.handleShellExec)
+	s.registerExecStdinTools()
 	s.mcpServer.AddTool(shellProvideInputTool(), s.handleShellProvideInput)
 	s.mcpServer.AddTool(shellSendRawTool(), s.handleShellSendRaw)
 	s.mcpServer.AddTool(shellInterruptTool(), s.handleShellInterrupt)
 	s.mcpServer.AddTool(shellSessionStatusTool(), s.handleShellSessionStatus)
 	s.mcpServer.AddTool(shellSessionCloseTool(), s.handleShellSessionClose)
+	s.mcpServer.AddTool(shellSessionCloseAllTool(), s.handleShellSessionCloseAll)
 	s.mcpServer.AddTool(shellSudoAuthTool(), s.handleShellSudoAuth)
 	s.mcpServer.AddTool(shellServerListTool(), s.handleShellServerList)
 	s.mcpServer.AddTool(shellServerTestTool(), s.handleShellServerTest)
+	s.registerSSHPreflightTools()
+	s.registerBroadcastTools()
+	s.registerExecIfTools()
+	s.registerPipelineTools()
+	s.registerAssertTools()
+	s.registerSystemInfoTools()
+	s.registerNetProbeTools()
+	s.registerConnectionTools()
+	s.registerReconnectTools()
+	s.registerSecurityTools()
+	s.registerRecordingTools()
+	s.registerJobTools()
 
 	// Register file transfer tools
 	s.registerFileTransferTools()
 	s.registerRecursiveTransferTools()
 	s.registerChunkedTransferTools()
+	s.registerDirListTools()
+	s.registerDirChmodTools()
+	s.registerPromptPatternTools()
+	s.registerSudoShellTools()
+	s.registerAuthorizeKeyTools()
 
 	// Register SSH tunnel tools
 	s.registerTunnelTools()
@@ -55,35 +76,76 @@ func (s *Server) registerTools() {
 
 	// Register debug tool
 	s.mcpServer.AddTool(shellDebugTool(), s.handleShellDebug)
+
+	s.registerOutputTools()
+	s.registerOutputResources()
 }
 
 // Tool definitions
 
 func shellSessionCreateTool() mcp.Tool {
 	return mcp.NewTool("shell_session_create",
-		mcp.WithDescription(`Initialize a persistent shell session (local PTY or SSH).
+		mcp.WithDescription(`Initialize a persistent shell session (local PTY, SSH or a shell command).
 
 The session maintains state (working directory, environment variables, shell history) across multiple shell_exec calls. Use 'local' mode for commands on the local machine, or 'ssh' mode for remote servers.
 
 For SSH mode, authentication uses SSH keys (agent or key_path). The session auto-reconnects if the connection drops.
 
+Use 'command' mode for shells reached through a local command, e.g. command="docker exec -it web bash", "kubectl exec -it mypod -- sh" or "sudo nsenter -t 1234 -a bash". The command's terminal is used as the session's PTY, so shell_exec and the other command tools work as usual; file transfer tools do not.
+
 Returns a session_id to use with other shell_* tools.`),
 		mcp.WithString("mode",
-			mcp.Description("Session mode: 'local' for local PTY or 'ssh' for remote SSH"),
+			mcp.Description("Session mode: 'local' for local PTY, 'ssh' for remote SSH, or 'command' for a shell provided by command"),
 			mcp.DefaultString("local"),
 		),
+		mcp.WithString("server",
+			mcp.Description("Name of a configured server (listed by shell_server_list) to connect to in ssh mode; its host, port, user and auth are used, and host, port, user and key_path given here override them, e.g. \"prod-db\""),
+		),
 		mcp.WithString("host",
-			mcp.Description("SSH host (required for ssh mode)"),
+			mcp.Description("SSH host (required for ssh mode unless server is given)"),
 		),
 		mcp.WithNumber("port",
 			mcp.Description("SSH port (default: 22)"),
 		),
 		mcp.WithString("user",
-			mcp.Description("SSH username (required for ssh mode)"),
+			mcp.Description("SSH username (required for ssh mode unless server is given)"),
 		),
 		mcp.WithString("key_path",
 			mcp.Description("Path to SSH private key file (e.g., ~/.ssh/id_ed25519)"),
 		),
+		mcp.WithString("identity",
+			mcp.Description("SSH only: name of one of the configured server's identities (listed by shell_server_list) to authenticate with instead of its auth block, e.g. \"deploy\". user defaults to the identity's user"),
+		),
+		mcp.WithString("command",
+			mcp.Description("Command that starts an interactive shell on its stdio (required for command mode), e.g. \"docker exec -it web bash\""),
+		),
+		mcp.WithBoolean("forward_x11",
+			mcp.Description("Forward X11 from the remote host to the local display, like ssh -X (ssh mode; needs a local X server and DISPLAY set)"),
+		),
+		mcp.WithString("cwd",
+			mcp.Description("Directory the session starts in (local and ssh modes; absolute or ~/...). Creation fails with invalid_start_dir if it does not exist. Local sessions default to local_session.default_cwd, else the server's working directory"),
+		),
+		mcp.WithBoolean("disable_pagers",
+			mcp.Description("Export the session.disable_pagers variables (PAGER=cat, GIT_PAGER=cat, SYSTEMD_PAGER= ...) when the shell starts, so git log, man or systemctl status print instead of opening a pager; set false to keep the shell's pagers (default: true)"),
+		),
+		mcp.WithBoolean("raw_mode",
+			mcp.Description("Escape hatch for programs the normal command handling breaks: shell_exec sends the command verbatim and returns everything the PTY emits (echo, prompts, escape sequences) once it is quiet for 500ms. No exit codes, cwd tracking or prompt detection; shell_send_raw works at any time (default: false)"),
+		),
+		mcp.WithString("container",
+			mcp.Description("Run every command inside this docker/podman container (name or ID) on the local or SSH host, as `<runtime> exec -it <container> sh -c '...'`. Cwd and env are the container's. File tools still act on the host."),
+		),
+		mcp.WithString("container_runtime",
+			mcp.Description("Container runtime for container: 'docker' (default) or 'podman'"),
+		),
+		promptResponsesParam(),
+		mcp.WithString("new_password_env",
+			mcp.Description("SSH only: env var holding the password to set if the server forces a password change at login. Without it (or the server's auth.new_password_env) such a login returns status password_change_required"),
+		),
+		mcp.WithArray("tags",
+			mcp.Description("Labels for grouping sessions, e.g. [\"web\", \"prod\"]. Filter shell_session_list by tag or run a command in every tagged session with shell_exec_broadcast."),
+			mcp.WithStringItems(),
+		),
+		additiveTool(),
 	)
 }
 
@@ -93,7 +155,7 @@ func shellSessionListTool() mcp.Tool {
 
 Returns a list of all open sessions with their details including:
 - session_id: The ID to use with other shell_* tools
-- mode: "local" or "ssh"
+- mode: "local", "ssh" or "command"
 - host/user: Connection info for SSH sessions
 - state: Current state (idle, running, awaiting_input)
 - cwd: Current working directory
@@ -101,7 +163,14 @@ Returns a list of all open sessions with their details including:
 - last_used: When the session was last used
 - idle_for: How long the session has been idle
 
+Also returns "pool" with SSH connection pool stats: SSH sessions to the same
+user@host:port share one authenticated connection (each with its own PTY).
+
 Use this to recover session IDs after context compaction, or to find and close orphaned sessions.`),
+		mcp.WithString("tag",
+			mcp.Description("Only list sessions with this tag"),
+		),
+		readOnlyTool(),
 	)
 }
 
@@ -119,8 +188,10 @@ Each server includes:
 - user: SSH username
 - key_path: Path to SSH key (if configured)
 - has_sudo_password: Whether sudo password is configured (never reveals the password)
+- identities: Names of the server's alternative credentials, for shell_session_create's identity parameter
 
 Returns an empty list if no config file is loaded or no servers are defined.`),
+		readOnlyTool(),
 	)
 }
 
@@ -144,6 +215,7 @@ Returns:
 		mcp.WithNumber("timeout_ms",
 			mcp.Description("Connection timeout in milliseconds (default: 10000)"),
 		),
+		readOnlyTool(),
 	)
 }
 
@@ -151,10 +223,18 @@ func shellExecTool() mcp.Tool {
 	return mcp.NewTool("shell_exec",
 		mcp.WithDescription(`Execute a command in a shell session with interactive prompt detection.
 
-Returns one of three statuses:
-- "completed": Command finished. Check exit_code and stdout.
-- "awaiting_input": Command is waiting for input (password, confirmation, or interactive app like vim). Use shell_provide_input to send input, or shell_interrupt to cancel.
+Returns one of these statuses:
+- "completed": Command finished. Check success (exit_code is one of ok_exit_codes, default 0) and stdout.
+- "awaiting_input": Command is waiting for input (password, confirmation, or interactive app like vim). Use shell_provide_input to send input, or shell_interrupt to cancel. If input_timeout_seconds is set, the command is auto-interrupted when no input arrives within that time.
 - "timeout": Command exceeded timeout_ms. The command was interrupted and the session is ready for new commands.
+- "timeout_killed": (kill_on_timeout only) Command exceeded timeout_ms and was confirmed stopped; stdout holds the partial output. If it could not be stopped, status stays "timeout" with a hint.
+- "connection_lost": (auto_reconnect only) The SSH connection dropped mid-command. stdout holds the output captured before the drop; reconnected tells whether the session is usable again. error_code says why: "remote_closed", "network_timeout" or "auth_revoked".
+- "runaway_output": Command flooded the terminal (e.g. an accidental "yes") and was interrupted. stdout holds a sample; filter or redirect the output and retry.
+- "indeterminate": (prompt_detection.on_ambiguous: return_partial) Output stalled without a recognizable prompt. stdout holds the output so far; decide whether to send input with shell_provide_input or cancel with shell_interrupt.
+
+Connection failures returned as errors are prefixed with the same codes, or "local_pty_died" for local sessions.
+
+Commands matching security.auto_sudo_patterns are run with sudo when a sudo password is cached or configured; auto_sudo is set on the result.
 
 Interactive prompts are auto-detected:
 - Password prompts (sudo, ssh) - prompt_type: "password", mask_input: true
@@ -200,7 +280,7 @@ For multi-line file content, use these alternatives:
 			mcp.Description("The command to execute"),
 		),
 		mcp.WithNumber("timeout_ms",
-			mcp.Description("Command timeout in milliseconds (default: 30000)"),
+			mcp.Description("Command timeout in milliseconds (default: 30000). With session.adaptive_timeout configured, it counts from the command's last output."),
 		),
 		mcp.WithNumber("tail_lines",
 			mcp.Description("Return only the last N lines of output (built-in tail). Use for logs, long output. Cannot be combined with head_lines."),
@@ -208,6 +288,36 @@ For multi-line file content, use these alternatives:
 		mcp.WithNumber("head_lines",
 			mcp.Description("Return only the first N lines of output (built-in head). Use for previewing large files. Cannot be combined with tail_lines."),
 		),
+		mcp.WithBoolean("auto_reconnect",
+			mcp.Description("SSH only: if the connection drops mid-command, reconnect (restoring cwd/env) instead of waiting for the timeout. Returns status \"connection_lost\" with the partial output unless idempotent is set (default: false)"),
+		),
+		mcp.WithBoolean("idempotent",
+			mcp.Description("With auto_reconnect: the command is safe to run twice, so re-run it once after reconnecting (default: false)"),
+		),
+		mcp.WithBoolean("quiet",
+			mcp.Description("Discard stdout and return only status, exit_code, success and duration_ms. For checks like 'test -f /path' where only the exit code matters. Prompts are still reported (default: false)"),
+		),
+		mcp.WithBoolean("collapse_progress",
+			mcp.Description("Collapse progress bars redrawn with carriage returns (apt, pip, docker, curl) to their final state instead of returning every redraw. Also applies to output read by shell_provide_input for this command (default: false)"),
+		),
+		mcp.WithBoolean("kill_on_timeout",
+			mcp.Description("On timeout, make sure the command is stopped: after interrupting it, check that the shell answers and escalate the kill until it does, reporting status \"timeout_killed\". Use for commands that may ignore Ctrl+C and wedge the session (default: false)"),
+		),
+		mcp.WithBoolean("stream",
+			mcp.Description("Send the output as it arrives, in complete lines at most every 500ms, as progress notifications (message is the new output, progress the bytes sent so far). Needs a progressToken in the request's _meta; otherwise ignored. The result still holds all of the output. Not for raw_mode or no_pty (default: false)"),
+		),
+		mcp.WithBoolean("no_pty",
+			mcp.Description("SSH only: run the command on its own exec channel instead of the session's terminal, for batch commands. stdout and stderr are returned separately and exit_code is the channel's real exit status. The command starts in the session's cwd with its environment but cannot change them, and cannot prompt for input. Ignored for local and container sessions (default: false)"),
+		),
+		mcp.WithString("shell",
+			mcp.Description("Run the command as `<shell> -c '<command>'` (a shell name such as \"bash\" or \"zsh\", or an absolute path to one such as \"/bin/sh\"; other programs are rejected) for that shell's syntax regardless of the session shell. The session's cwd and env still apply"),
+		),
+		mcp.WithArray("ok_exit_codes",
+			mcp.Description("Exit codes that count as success in the result's success field, e.g. [0, 1] for grep, where 1 means no match (default: [0])"),
+			mcp.Items(map[string]any{"type": "integer"}),
+		),
+		execLimitsParam(),
+		destructiveTool(),
 	)
 }
 
@@ -236,6 +346,7 @@ For interactive apps (prompt_type: "interactive"), provide the appropriate comma
 		mcp.WithBoolean("cache_for_sudo",
 			mcp.Description("Cache this input for subsequent sudo prompts (default: false)"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -254,6 +365,7 @@ environment variable.`),
 			mcp.Required(),
 			mcp.Description(descSessionID),
 		),
+		additiveTool(),
 	)
 }
 
@@ -308,6 +420,7 @@ DO NOT use shell_send_raw for:
 			mcp.Required(),
 			mcp.Description("Raw input with escape sequences (e.g., '\\x04' for EOF, '\\n' for newline)"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -317,11 +430,14 @@ func shellInterruptTool() mcp.Tool {
 
 Use this to cancel long-running commands or exit interactive prompts. Note: Some programs like vim ignore SIGINT - for those, use shell_provide_input with the appropriate exit command (e.g., ":q!" for vim).
 
-After interrupt, the session returns to idle state and is ready for new commands.`),
+After interrupt, the session returns to idle state and is ready for new commands.
+
+The result's output is what the command printed after Ctrl-C (e.g. cleanup messages from a SIGINT trap), read until the shell prompt returns or session.interrupt_grace_period (default 1s) passes. prompt_returned=false means the prompt did not come back in time and the command may still be running.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
 			mcp.Description(descSessionID),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -329,13 +445,26 @@ func shellSessionStatusTool() mcp.Tool {
 	return mcp.NewTool("shell_session_status",
 		mcp.WithDescription(`Check session health, current directory, and environment.
 
-Returns session state (idle, running, awaiting_input), current working directory, environment variables, shell aliases, connection status, and sudo cache status.
+Returns session state (idle, running, awaiting_input), current working directory, connection status, and sudo cache status.
+
+detail="basic" (default) is cheap enough for frequent polling: it never runs
+anything in the session and returns env vars and aliases only if already
+captured. detail="full" also captures env vars and aliases (when idle) and adds
+env_var_count, alias_count, sudo_cache_valid, tunnels, running_command and
+connection_health (healthy, disconnected, dropped or closed). With
+security.sudo_cache_scope "tty" it also reports sudo_tty_valid: whether sudo's
+own credential cache is valid, from "sudo -n true".
 
 Useful for debugging or verifying session state before executing commands.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
 			mcp.Description(descSessionID),
 		),
+		mcp.WithString("detail",
+			mcp.Description("How much to report: 'basic' (default) or 'full'"),
+			mcp.Enum(statusDetailBasic, statusDetailFull),
+		),
+		readOnlyTool(),
 	)
 }
 
@@ -350,6 +479,7 @@ If session recording was enabled, returns the path to the recording file.`),
 			mcp.Required(),
 			mcp.Description(descSessionID),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -377,6 +507,53 @@ func (s *Server) validateSSHParams(host, user string) *mcp.CallToolResult {
 	return nil
 }
 
+// lookupNewPassword reads the new_password_env argument: the env var must be
+// set, and only SSH sessions can use it.
+func (s *Server) lookupNewPassword(req mcp.CallToolRequest, mode string) (string, *mcp.CallToolResult) {
+	env := mcp.ParseString(req, "new_password_env", "")
+	if env == "" {
+		return "", nil
+	}
+	if mode != "ssh" {
+		return "", mcp.NewToolResultError("new_password_env is only supported in ssh mode")
+	}
+	pw := s.fs.Getenv(env)
+	if pw == "" {
+		return "", mcp.NewToolResultError(fmt.Sprintf("new_password_env: environment variable %s is not set", env))
+	}
+	return pw, nil
+}
+
+// validateSessionCommand checks the command of a command mode session
+// against the command filter, like any command run with shell_exec.
+func (s *Server) validateSessionCommand(mode, command string) *mcp.CallToolResult {
+	if mode != "command" {
+		if command != "" {
+			return mcp.NewToolResultError("command is only supported in command mode")
+		}
+		return nil
+	}
+	if strings.TrimSpace(command) == "" {
+		return mcp.NewToolResultError("command is required for command mode")
+	}
+	if allowed, reason := s.filter().IsAllowed(command); !allowed {
+		slog.Warn("session command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+		return mcp.NewToolResultError("command blocked: " + reason)
+	}
+	return s.checkReadOnlyCommand(command)
+}
+
+// checkRecoveredCommand checks the command of a command mode session against
+// the current command filter before the session manager runs it again to
+// recover the session.
+func (s *Server) checkRecoveredCommand(command string) error {
+	if allowed, reason := s.filter().IsAllowed(command); !allowed {
+		slog.Warn("recovered session command blocked by filter", slog.String("command", command), slog.String("reason", reason))
+		return fmt.Errorf("command blocked: %s", reason)
+	}
+	return nil
+}
+
 func (s *Server) handleShellSessionCreate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	mode := mcp.ParseString(req, "mode", "local")
 
@@ -384,25 +561,118 @@ func (s *Server) handleShellSessionCreate(ctx context.Context, req mcp.CallToolR
 	port := mcp.ParseInt(req, "port", 22)
 	user := mcp.ParseString(req, "user", "")
 	keyPath := mcp.ParseString(req, "key_path", "")
-
+	forwardX11 := mcp.ParseBoolean(req, "forward_x11", false)
+	disablePagers := mcp.ParseBoolean(req, "disable_pagers", true)
+	cwd := mcp.ParseString(req, "cwd", "")
+	command := mcp.ParseString(req, "command", "")
+	rawMode := mcp.ParseBoolean(req, "raw_mode", false)
+	container := mcp.ParseString(req, "container", "")
+	containerRuntime := mcp.ParseString(req, "container_runtime", "")
+	identity := mcp.ParseString(req, "identity", "")
+
+	// Connection settings left out are taken from the named server, and
+	// auth and identities always are, even with host overridden.
+	srv, errResult := s.resolveSessionServer(req)
+	if errResult != nil {
+		return errResult, nil
+	}
+	if srv != nil {
+		mode = "ssh"
+		if host == "" {
+			host = srv.Host
+		}
+		if _, ok := req.GetArguments()["port"]; !ok && srv.Port != 0 {
+			port = srv.Port
+		}
+	}
+	if identity != "" {
+		if user, errResult = s.resolveIdentity(mode, host, user, identity, srv); errResult != nil {
+			return errResult, nil
+		}
+	}
+	if srv != nil && user == "" {
+		user = srv.User
+	}
 	if mode == "ssh" {
 		if errResult := s.validateSSHParams(host, user); errResult != nil {
 			return errResult, nil
 		}
 	}
+	if errResult := s.validateSessionCommand(mode, command); errResult != nil {
+		return errResult, nil
+	}
+
+	promptResponses, err := s.parsePromptResponses(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(promptResponses) > 0 && mode != "ssh" {
+		return mcp.NewToolResultError("prompt_responses is only supported in ssh mode"), nil
+	}
+	if forwardX11 && mode != "ssh" {
+		return mcp.NewToolResultError("forward_x11 is only supported in ssh mode"), nil
+	}
+	newPassword, errResult := s.lookupNewPassword(req, mode)
+	if errResult != nil {
+		return errResult, nil
+	}
+	tags, err := parseStringArray(req, "tags")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if tags, err = session.NormalizeTags(tags); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	opts := session.CreateOptions{
+		Mode:             mode,
+		Host:             host,
+		Port:             port,
+		User:             user,
+		KeyPath:          keyPath,
+		Server:           serverName(srv),
+		Identity:         identity,
+		Command:          command,
+		RawMode:          rawMode,
+		Container:        container,
+		ContainerRuntime: containerRuntime,
+		ForwardX11:       forwardX11,
+		KeepPagers:       !disablePagers,
+		Cwd:              cwd,
+		PromptResponses:  promptResponses,
+		NewPassword:      newPassword,
+		Tags:             tags,
+	}
+	reused, duplicateInfo := s.checkDuplicateSession(opts)
+	if reused != nil {
+		return jsonResult(reused)
+	}
 
 	slog.Info("creating shell session",
 		slog.String("mode", mode),
 		slog.String("host", host),
 	)
 
-	sess, err := s.sessionManager.Create(session.CreateOptions{
-		Mode:    mode,
-		Host:    host,
-		Port:    port,
-		User:    user,
-		KeyPath: keyPath,
-	})
+	sess, err := s.sessionManager.Create(opts)
+	if errors.Is(err, session.ErrPasswordChangeRequired) {
+		// The credentials worked; the client has to supply a new password.
+		return jsonResult(map[string]any{
+			"status":  "password_change_required",
+			"mode":    mode,
+			"host":    host,
+			"user":    user,
+			"message": err.Error(),
+		})
+	}
+	if errors.Is(err, ssh.ErrCertExpired) {
+		// The certificate needs re-signing; the server never saw a login
+		// attempt, so this does not count towards the auth lockout.
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if errors.Is(err, session.ErrInvalidStartDir) {
+		// The login worked; only the directory was wrong.
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 	if err != nil {
 		// Record auth failure for SSH
 		if mode == "ssh" {
@@ -433,19 +703,78 @@ func (s *Server) handleShellSessionCreate(ctx context.Context, req mcp.CallToolR
 		"shell":      "/bin/bash",
 	}
 
+	if command != "" {
+		result["command"] = command
+		result["shell"] = sess.Shell
+	}
+
+	if rawMode {
+		result["raw_mode"] = true
+	}
+
+	if container != "" {
+		result["container"] = container
+		result["cwd"] = sess.Cwd
+	}
+
+	// What the new shell was asked for up front (session.auto_capture_on_connect).
+	captured := sess.Captured
+	if captured == nil {
+		captured = []string{}
+	}
+	result["captured"] = captured
+	if slices.Contains(captured, "cwd") {
+		result["cwd"] = sess.Cwd
+	}
+	if slices.Contains(captured, "env") {
+		result["env_var_count"] = len(sess.EnvVars)
+	}
+	if slices.Contains(captured, "aliases") {
+		result["alias_count"] = len(sess.Aliases)
+	}
+
+	if sess.Proxy != "" {
+		result["proxy"] = sess.Proxy
+	}
+
+	if srv != nil {
+		result["server"] = srv.Name
+		result["host"] = host
+		result["user"] = user
+	}
+
+	if identity != "" {
+		result["identity"] = identity
+		result["user"] = user
+	}
+
+	if len(tags) > 0 {
+		result["tags"] = tags
+	}
+
 	if path := s.recordingManager.GetRecordingPath(sess.ID); path != "" {
 		result["recording_path"] = path
 	}
 
+	for k, v := range duplicateInfo {
+		result[k] = v
+	}
+
 	return jsonResult(result)
 }
 
 func (s *Server) handleShellSessionList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	sessions := s.sessionManager.ListDetailed()
+	if tag := mcp.ParseString(req, "tag", ""); tag != "" {
+		sessions = slices.DeleteFunc(sessions, func(info session.SessionInfo) bool {
+			return !slices.Contains(info.Tags, tag)
+		})
+	}
 
 	result := map[string]any{
 		"count":    len(sessions),
 		"sessions": sessions,
+		"pool":     s.sessionManager.PoolStats(),
 	}
 
 	return jsonResult(result)
@@ -479,6 +808,7 @@ func (s *Server) handleShellServerList(ctx context.Context, req mcp.CallToolRequ
 				"user":              srv.User,
 				"key_path":          srv.KeyPath,
 				"has_sudo_password": srv.SudoPasswordEnv != "",
+				"identities":        srv.IdentityNames(),
 				"active_sessions":   len(sessionIDs),
 				"session_ids":       sessionIDs,
 			}
@@ -517,24 +847,15 @@ func (s *Server) handleShellServerTest(ctx context.Context, req mcp.CallToolRequ
 		"port": port,
 		"user": srv.User,
 	}
-
-	// Build auth methods
-	authCfg := ssh.AuthConfig{
-		UseAgent: true,
-		KeyPath:  srv.KeyPath,
-		Host:     srv.Host,
-	}
-	if srv.Auth.Type == "password" && srv.Auth.PasswordEnv != "" {
-		authCfg.Password = s.fs.Getenv(srv.Auth.PasswordEnv)
-	}
-	if srv.Auth.PassphraseEnv != "" {
-		authCfg.KeyPassphrase = s.fs.Getenv(srv.Auth.PassphraseEnv)
+	proxy, err := s.serverProxyOptions(srv)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-	if srv.Auth.Path != "" {
-		authCfg.KeyPath = srv.Auth.Path
+	if proxy != nil {
+		serverInfo["proxy"] = proxy.String()
 	}
 
-	authMethods, err := ssh.BuildAuthMethods(authCfg)
+	authMethods, err := ssh.BuildAuthMethods(s.serverAuthConfig(srv))
 	if err != nil {
 		result := map[string]any{
 			"reachable": false,
@@ -557,6 +878,7 @@ func (s *Server) handleShellServerTest(ctx context.Context, req mcp.CallToolRequ
 		AuthMethods:     authMethods,
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         timeout,
+		Proxy:           proxy,
 	})
 	if err != nil {
 		result := map[string]any{
@@ -592,18 +914,26 @@ func (s *Server) handleShellServerTest(ctx context.Context, req mcp.CallToolRequ
 
 // tryCachedSudoInjection attempts to auto-inject a sudo password.
 // It checks (in order): the sudo cache, then the server's sudo_password_env config.
+// check is what sudo reported before the command ran: if its credentials
+// were cached, the prompt is not sudo's and nothing is injected.
 // Returns updated result and any error that occurred.
-func (s *Server) tryCachedSudoInjection(sessionID string, sess *session.Session, result *session.ExecResult) (*session.ExecResult, error) {
+func (s *Server) tryCachedSudoInjection(sessionID string, sess *session.Session, result *session.ExecResult, check sudoCheck) (*session.ExecResult, error) {
 	if result.Status != "awaiting_input" || result.PromptType != "password" {
 		return result, nil
 	}
+	if check == sudoCredentialsCached {
+		slog.Info("not injecting sudo password: sudo credentials are cached, so the prompt is not sudo's",
+			slog.String("session_id", sessionID),
+		)
+		return result, nil
+	}
 
 	// 1. Check the in-memory sudo cache first
 	cachedPwd := s.sudoCache.Get(sessionID)
 
 	// 2. Fall back to server config's sudo_password_env
 	if cachedPwd == nil {
-		cachedPwd = s.lookupSudoPasswordFromConfig(sess.Host)
+		cachedPwd = s.lookupSudoPasswordFromConfig(sess.ConfigServer())
 	}
 
 	if cachedPwd == nil {
@@ -613,7 +943,7 @@ func (s *Server) tryCachedSudoInjection(sessionID string, sess *session.Session,
 	slog.Info("auto-injecting sudo password", slog.String("session_id", sessionID))
 	s.recordingManager.RecordInput(sessionID, "***", true)
 
-	newResult, err := sess.ProvideInput(string(cachedPwd))
+	newResult, err := sess.ProvideInputUncharged(string(cachedPwd))
 	if err != nil {
 		return nil, err
 	}
@@ -633,14 +963,57 @@ func (s *Server) lookupServer(name string) *config.ServerConfig {
 		return nil
 	}
 	for i, srv := range s.config.Servers {
-		if srv.Name == name || srv.Host == name {
+		if srv.Name == name {
+			return &s.config.Servers[i]
+		}
+	}
+	for i, srv := range s.config.Servers {
+		if srv.Host == name {
 			return &s.config.Servers[i]
 		}
 	}
 	return nil
 }
 
-// lookupSudoPasswordFromConfig reads the sudo password from a server's configured env var.
+// serverAuthConfig builds SSH authentication settings for a configured server.
+func (s *Server) serverAuthConfig(srv *config.ServerConfig) ssh.AuthConfig {
+	authCfg := ssh.AuthConfig{
+		UseAgent: true,
+		KeyPath:  srv.KeyPath,
+		Host:     srv.Host,
+	}
+	if srv.Auth.Type == "password" && srv.Auth.PasswordEnv != "" {
+		authCfg.Password = s.fs.Getenv(srv.Auth.PasswordEnv)
+	}
+	if srv.Auth.PassphraseEnv != "" {
+		authCfg.KeyPassphrase = s.fs.Getenv(srv.Auth.PassphraseEnv)
+	}
+	if srv.Auth.Path != "" {
+		authCfg.KeyPath = srv.Auth.Path
+	}
+	authCfg.CertPath = srv.Auth.CertPath
+	return authCfg
+}
+
+// serverProxyOptions returns the proxy configured for srv, or nil to
+// connect directly.
+func (s *Server) serverProxyOptions(srv *config.ServerConfig) (*ssh.ProxyOptions, error) {
+	if srv.Proxy.URL == "" {
+		return nil, nil
+	}
+	scheme, address, err := srv.Proxy.Parse()
+	if err != nil {
+		return nil, err
+	}
+	proxy := &ssh.ProxyOptions{Scheme: scheme, Address: address, User: srv.Proxy.User}
+	if srv.Proxy.PasswordEnv != "" {
+		proxy.Password = s.fs.Getenv(srv.Proxy.PasswordEnv)
+	}
+	return proxy, nil
+}
+
+// lookupSudoPasswordFromConfig reads the sudo password from the configured
+// env var of the server with name or host (see Session.ConfigServer).
 func (s *Server) lookupSudoPasswordFromConfig(host string) []byte {
 	srv := s.lookupServer(host)
 	if srv == nil || srv.SudoPasswordEnv == "" {
@@ -658,8 +1031,9 @@ func (s *Server) lookupSudoPasswordFromConfig(host string) []byte {
 }
 
 // applyAutoTruncation saves large outputs to a file and clears stdout.
-// The LLM must explicitly read the file to get the content.
-func (s *Server) applyAutoTruncation(sessionID string, result *session.ExecResult) {
+// The LLM must explicitly read the file to get the content. When no output
+// directory is writable, the tail of the output is returned inline instead.
+func (s *Server) applyAutoTruncation(sessionID, command string, result *session.ExecResult) {
 	outputLen := len(result.Stdout)
 	if outputLen <= saveToFileThreshold {
 		return
@@ -668,57 +1042,75 @@ func (s *Server) applyAutoTruncation(sessionID string, result *session.ExecResul
 	result.TotalBytes = outputLen
 	result.Truncated = true
 
-	outputFile, err := s.saveOutputToFile(sessionID, result.Stdout)
+	outputFile, strategy, err := s.saveOutputToFile(sessionID, command, result.Stdout)
 	if err != nil {
-		slog.Warn("failed to save output to file",
+		slog.Warn("failed to save output to file, returning it truncated",
 			slog.String("session_id", sessionID),
 			slog.String("error", err.Error()),
 		)
-		// Clear stdout completely - we can't return it inline
-		result.Stdout = ""
+		result.Stdout = tailBytes(result.Stdout, saveToFileThreshold)
+		result.TruncatedBytes = len(result.Stdout)
+		result.OutputStrategy = outputStrategyTruncated
 		result.Warning = fmt.Sprintf(
-			"Output too large (%d bytes) and failed to save to file: %v. Use shell_file_get for large outputs.",
-			outputLen, err,
+			"Output too large (%d bytes) and failed to save to file: %v. Showing the last %d bytes; redirect the command's output to a file and use shell_file_get for the rest.",
+			outputLen, err, result.TruncatedBytes,
 		)
 		return
 	}
 
-	// Clear stdout - only return the file path
+	// Clear stdout - only return the file path and resource URI
 	result.Stdout = ""
 	result.OutputFile = outputFile
+	result.OutputResource = outputResourceURI(outputFile)
+	result.OutputStrategy = strategy
 	result.Warning = fmt.Sprintf(
-		"Output too large (%d bytes). Full output saved to: %s. Read the file to analyze the content.",
-		outputLen, outputFile,
+		"Output too large (%d bytes). Full output saved to: %s (MCP resource %s). Read the file or the resource to analyze the content.",
+		outputLen, outputFile, result.OutputResource,
 	)
 	slog.Info("large output saved to file",
 		slog.String("session_id", sessionID),
 		slog.Int("total_bytes", outputLen),
 		slog.String("output_file", outputFile),
+		slog.String("strategy", strategy),
 	)
 }
 
-// saveOutputToFile saves command output to a file in the working directory and returns the path.
-func (s *Server) saveOutputToFile(sessionID, output string) (string, error) {
-	cwd, err := s.fs.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("get working dir: %w", err)
+// tailBytes returns at most n bytes from the end of s, starting at a line
+// boundary when there is one.
+func tailBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
-
-	outputDir := cwd + "/.claude-shell-mcp"
-	if err := s.fs.MkdirAll(outputDir, 0755); err != nil {
-		return "", fmt.Errorf("create output dir: %w", err)
+	tail := s[len(s)-n:]
+	if i := strings.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		return tail[i+1:]
 	}
+	return tail
+}
 
+// saveOutputToFile saves command output to the first writable directory of
+// outputDirs and returns the path and the directory's strategy. The command
+// and time go in a metadata file next to it for shell_outputs_list.
+func (s *Server) saveOutputToFile(sessionID, command, output string) (string, string, error) {
 	// Generate unique filename: session_timestamp.txt
 	timestamp := fmt.Sprintf("%d", s.clock.Now().UnixMilli())
 	filename := fmt.Sprintf("%s_%s.txt", sessionID, timestamp)
-	filepath := outputDir + "/" + filename
 
-	if err := s.fs.WriteFile(filepath, []byte(output), 0644); err != nil {
-		return "", fmt.Errorf("write output file: %w", err)
+	var errs []error
+	for _, dir := range s.outputDirs() {
+		if err := s.ensurePrivateDir(dir.path); err != nil {
+			errs = append(errs, fmt.Errorf("create output dir %s: %w", dir.path, err))
+			continue
+		}
+		filepath := dir.path + "/" + filename
+		if err := s.fs.WriteFile(filepath, []byte(output), 0600); err != nil {
+			errs = append(errs, fmt.Errorf("write output file: %w", err))
+			continue
+		}
+		s.saveOutputMeta(filepath, command)
+		return filepath, dir.strategy, nil
 	}
-
-	return filepath, nil
+	return "", "", errors.Join(errs...)
 }
 
 // validateExecParams validates parameters for shell_exec.
@@ -750,14 +1142,50 @@ func (s *Server) handleShellExec(ctx context.Context, req mcp.CallToolRequest) (
 	timeoutMs := mcp.ParseInt(req, "timeout_ms", 30000)
 	tailLines := mcp.ParseInt(req, "tail_lines", 0)
 	headLines := mcp.ParseInt(req, "head_lines", 0)
+	quiet := mcp.ParseBoolean(req, "quiet", false)
+	execOpts := session.ExecOptions{
+		AutoReconnect:    mcp.ParseBoolean(req, "auto_reconnect", false),
+		Idempotent:       mcp.ParseBoolean(req, "idempotent", false),
+		Shell:            mcp.ParseString(req, "shell", ""),
+		CollapseProgress: mcp.ParseBoolean(req, "collapse_progress", false),
+		KillOnTimeout:    mcp.ParseBoolean(req, "kill_on_timeout", false),
+		NoPTY:            mcp.ParseBoolean(req, "no_pty", false),
+	}
+	if mcp.ParseBoolean(req, "stream", false) {
+		execOpts.OnOutput = s.outputProgress(ctx, req)
+	}
 
 	if errResult := validateExecParams(sessionID, command, tailLines, headLines); errResult != nil {
 		return errResult, nil
 	}
+	if quiet && (tailLines > 0 || headLines > 0) {
+		return mcp.NewToolResultError("quiet discards output and cannot be combined with tail_lines or head_lines"), nil
+	}
+	okExitCodes, err := parseOkExitCodes(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if execOpts.Limits, err = parseExecLimits(req); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
 
-	if allowed, reason := s.commandFilter.IsAllowed(command); !allowed {
-		slog.Warn("command blocked by filter", slog.String("command", command), slog.String("reason", reason))
-		return mcp.NewToolResultError("command blocked: " + reason), nil
+	checked := []string{command}
+	if execOpts.Shell != "" {
+		wrapped, err := session.WrapInShell(execOpts.Shell, command)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// The shell is part of what runs; the filter sees it too.
+		checked = append(checked, wrapped)
+	}
+	for _, c := range checked {
+		if allowed, reason := s.filter().IsAllowed(c); !allowed {
+			slog.Warn("command blocked by filter", slog.String("command", c), slog.String("reason", reason))
+			return mcp.NewToolResultError("command blocked: " + reason), nil
+		}
+		if errResult := s.checkReadOnlyCommand(c); errResult != nil {
+			return errResult, nil
+		}
 	}
 
 	sess, err := s.sessionManager.Get(sessionID)
@@ -765,26 +1193,45 @@ func (s *Server) handleShellExec(ctx context.Context, req mcp.CallToolRequest) (
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
+	command, autoSudo, errResult := s.applyAutoSudo(sessionID, sess, command)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	sudoCheck := s.sudoCheckBefore(sessionID, sess, command)
+
 	slog.Info("executing command", slog.String("session_id", sessionID), slog.String("command", command))
 	s.recordingManager.RecordInput(sessionID, command+"\n", false)
 
-	result, err := sess.Exec(command, timeoutMs)
+	started := s.clock.Now()
+	result, err := sess.ExecWithOptions(command, timeoutMs, execOpts)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
 	s.recordingManager.RecordOutput(sessionID, result.Stdout)
 
-	result, err = s.tryCachedSudoInjection(sessionID, sess, result)
+	result, err = s.tryCachedSudoInjection(sessionID, sess, result, sudoCheck)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	result.DurationMs = s.clock.Now().Sub(started).Milliseconds()
+	result.AutoSudo = autoSudo
+	setExecSuccess(result, okExitCodes)
+
+	if quiet {
+		// Prompt fields are kept so an awaiting_input result can be answered.
+		result.Stdout = ""
+		result.Stderr = ""
+		result.AsyncOutput = ""
+		return jsonResult(result)
+	}
 
 	if result.Stdout != "" && (tailLines > 0 || headLines > 0) {
 		result.Stdout, result.Truncated, result.TotalLines, result.ShownLines = truncateOutput(result.Stdout, tailLines, headLines)
 	}
 
-	s.applyAutoTruncation(sessionID, result)
+	s.applyAutoTruncation(sessionID, command, result)
 
 	return jsonResult(result)
 }
@@ -802,6 +1249,9 @@ func (s *Server) handleShellProvideInput(ctx context.Context, req mcp.CallToolRe
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if errResult := s.checkReadOnlyInput("shell_provide_input", sess, input); errResult != nil {
+		return errResult, nil
+	}
 
 	slog.Info("providing input to session",
 		slog.String("session_id", sessionID),
@@ -809,7 +1259,7 @@ func (s *Server) handleShellProvideInput(ctx context.Context, req mcp.CallToolRe
 	)
 
 	// Record input (masked if it's a password)
-	s.recordingManager.RecordInput(sessionID, input+"\n", cacheForSudo)
+	s.recordingManager.RecordInput(sessionID, input+"\n", cacheForSudo || sess.AwaitingMaskedInput())
 
 	// Cache the password if requested (for sudo prompts)
 	if cacheForSudo && input != "" {
@@ -835,7 +1285,7 @@ func (s *Server) handleShellProvideInput(ctx context.Context, req mcp.CallToolRe
 		result.SudoExpiresInSeconds = int(expiresIn.Seconds())
 	}
 
-	s.applyAutoTruncation(sessionID, result)
+	s.applyAutoTruncation(sessionID, "", result)
 
 	return jsonResult(result)
 }
@@ -853,7 +1303,7 @@ func (s *Server) handleShellSudoAuth(ctx context.Context, req mcp.CallToolReques
 	}
 
 	// Look up password from config
-	pwd := s.lookupSudoPasswordFromConfig(sess.Host)
+	pwd := s.lookupSudoPasswordFromConfig(sess.ConfigServer())
 	if pwd == nil {
 		return mcp.NewToolResultError(
 			"No sudo password configured for this server. " +
@@ -877,12 +1327,16 @@ func (s *Server) handleShellSudoAuth(ctx context.Context, req mcp.CallToolReques
 	result.SudoAuthenticated = true
 	result.SudoExpiresInSeconds = int(s.sudoCache.ExpiresIn(sessionID).Seconds())
 
-	s.applyAutoTruncation(sessionID, result)
+	s.applyAutoTruncation(sessionID, "", result)
 
 	return jsonResult(result)
 }
 
 func (s *Server) handleShellSendRaw(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if errResult := s.checkReadOnly("shell_send_raw"); errResult != nil {
+		return errResult, nil
+	}
+
 	sessionID := mcp.ParseString(req, "session_id", "")
 	input := mcp.ParseString(req, "input", "")
 
@@ -914,7 +1368,7 @@ func (s *Server) handleShellSendRaw(ctx context.Context, req mcp.CallToolRequest
 	// Record output
 	s.recordingManager.RecordOutput(sessionID, result.Stdout)
 
-	s.applyAutoTruncation(sessionID, result)
+	s.applyAutoTruncation(sessionID, "", result)
 
 	return jsonResult(result)
 }
@@ -935,11 +1389,26 @@ func (s *Server) handleShellInterrupt(ctx context.Context, req mcp.CallToolReque
 		slog.String("session_id", sessionID),
 	)
 
-	if err := sess.Interrupt(); err != nil {
+	interrupted, err := sess.Interrupt()
+	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
+	if interrupted.Output != "" {
+		s.recordingManager.RecordOutput(sessionID, interrupted.Output)
+	}
+
+	return jsonResult(InterruptResult{
+		Status:          "interrupted",
+		Message:         "Interrupt signal sent",
+		InterruptResult: interrupted,
+	})
+}
 
-	return mcp.NewToolResultText("Interrupt signal sent"), nil
+// InterruptResult is the result of shell_interrupt.
+type InterruptResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	*session.InterruptResult
 }
 
 func (s *Server) handleShellSessionStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -949,6 +1418,11 @@ func (s *Server) handleShellSessionStatus(ctx context.Context, req mcp.CallToolR
 		return mcp.NewToolResultError(errSessionIDRequired), nil
 	}
 
+	detail := mcp.ParseString(req, "detail", statusDetailBasic)
+	if detail != statusDetailBasic && detail != statusDetailFull {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid detail %q: must be '%s' or '%s'", detail, statusDetailBasic, statusDetailFull)), nil
+	}
+
 	sess, err := s.sessionManager.Get(sessionID)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
@@ -962,17 +1436,77 @@ func (s *Server) handleShellSessionStatus(ctx context.Context, req mcp.CallToolR
 		status.SudoExpiresIn = int(s.sudoCache.ExpiresIn(sessionID).Seconds())
 	}
 
-	// Capture current env vars if not already populated
-	if status.EnvVars == nil || len(status.EnvVars) == 0 {
-		status.EnvVars = sess.CaptureEnv()
+	result := SessionStatusResult{SessionStatus: status}
+	if detail == statusDetailFull {
+		result.SessionStatusFull = s.fullSessionStatus(sess, &result.SessionStatus)
 	}
+	return jsonResult(result)
+}
+
+const (
+	statusDetailBasic = "basic"
+	statusDetailFull  = "full"
+)
+
+// SessionStatusResult is the result of shell_session_status. The embedded
+// SessionStatusFull is only set for detail="full".
+type SessionStatusResult struct {
+	session.SessionStatus
+	*SessionStatusFull
+}
 
-	// Capture aliases if not already populated
-	if status.Aliases == nil || len(status.Aliases) == 0 {
-		status.Aliases = sess.CaptureAliases()
+// SessionStatusFull holds the fields shell_session_status adds for
+// detail="full".
+type SessionStatusFull struct {
+	EnvVarCount      int                    `json:"env_var_count"`
+	AliasCount       int                    `json:"alias_count"`
+	SudoCacheValid   bool                   `json:"sudo_cache_valid"`
+	SudoTTYValid     *bool                  `json:"sudo_tty_valid,omitempty"`
+	Tunnels          []session.TunnelConfig `json:"tunnels"`
+	RunningCommand   string                 `json:"running_command,omitempty"`
+	ConnectionHealth string                 `json:"connection_health"`
+}
+
+// fullSessionStatus gathers the detail="full" fields, capturing env vars and
+// aliases into status if they haven't been yet, and asking sudo about its
+// credential cache when sudo_cache_scope is "tty". Both type commands into
+// the shell, so they only happen while the session is idle.
+func (s *Server) fullSessionStatus(sess *session.Session, status *session.SessionStatus) *SessionStatusFull {
+	if status.State == session.StateIdle {
+		if len(status.EnvVars) == 0 {
+			status.EnvVars = sess.CaptureEnv()
+		}
+		if len(status.Aliases) == 0 {
+			status.Aliases = sess.CaptureAliases()
+		}
 	}
 
-	return jsonResult(status)
+	full := &SessionStatusFull{
+		EnvVarCount:      len(status.EnvVars),
+		AliasCount:       len(status.Aliases),
+		SudoCacheValid:   status.SudoCached,
+		Tunnels:          sess.GetTunnelConfigs(),
+		RunningCommand:   sess.RunningCommand(),
+		ConnectionHealth: "healthy",
+	}
+	if full.Tunnels == nil {
+		full.Tunnels = []session.TunnelConfig{}
+	}
+	if status.State == session.StateIdle && s.sudoScopeTTY() {
+		if check := s.checkSudoCredentials(sess.ID, sess); check != sudoUnchecked {
+			valid := check == sudoCredentialsCached
+			full.SudoTTYValid = &valid
+		}
+	}
+	switch {
+	case status.State == session.StateClosed:
+		full.ConnectionHealth = "closed"
+	case status.ConnectionError != "":
+		full.ConnectionHealth = "dropped"
+	case !status.Connected:
+		full.ConnectionHealth = "disconnected"
+	}
+	return full
 }
 
 func (s *Server) handleShellSessionClose(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -986,13 +1520,8 @@ func (s *Server) handleShellSessionClose(ctx context.Context, req mcp.CallToolRe
 		slog.String("session_id", sessionID),
 	)
 
-	// Get recording path before closing
-	recordingPath := s.recordingManager.GetRecordingPath(sessionID)
-
-	// Stop recording
-	s.recordingManager.StopRecording(sessionID)
-
-	if err := s.sessionManager.Close(sessionID); err != nil {
+	recordingPath, err := s.closeSession(sessionID)
+	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
 
@@ -1006,6 +1535,24 @@ func (s *Server) handleShellSessionClose(ctx context.Context, req mcp.CallToolRe
 	return jsonResult(result)
 }
 
+// closeSession closes a session and drops the server's state for it. It
+// returns the path of the session's recording, if it was recorded.
+func (s *Server) closeSession(sessionID string) (string, error) {
+	// Get recording path before closing
+	recordingPath := s.recordingManager.GetRecordingPath(sessionID)
+
+	// Stop recording
+	s.recordingManager.StopRecording(sessionID)
+
+	if err := s.sessionManager.Close(sessionID); err != nil {
+		return "", err
+	}
+	s.fileWatches.dropSession(sessionID)
+	s.netProbes.dropSession(sessionID)
+	s.dirListCache.dropSession(sessionID)
+	return recordingPath, nil
+}
+
 // jsonResult converts a value to a JSON tool result.
 func jsonResult(v any) (*mcp.CallToolResult, error) {
 	data, err := json.MarshalIndent(v, "", "  ")
@@ -1058,17 +1605,26 @@ Returns detailed internal information about a session including:
 - PTY name and control session availability
 - Internal state for debugging issues
 
+action='explain_prompt' runs sample through the session's prompt detector
+(built-in and custom patterns) and returns which pattern would match, its
+prompt_type and whether input would be masked, plus any lower-priority
+patterns that match too. Use it to tune prompt_detection.custom_patterns.
+
 This tool is for debugging only and may change without notice.`),
 		mcp.WithString("session_id",
 			mcp.Required(),
 			mcp.Description("The session ID to inspect"),
 		),
 		mcp.WithString("action",
-			mcp.Description("Debug action: 'status' (default), 'foreground', 'control_exec'"),
+			mcp.Description("Debug action: 'status' (default), 'foreground', 'control_exec', 'explain_prompt'"),
 		),
 		mcp.WithString("command",
 			mcp.Description("Command to run via control session (only for action='control_exec')"),
 		),
+		mcp.WithString("sample",
+			mcp.Description("Output to classify, e.g. \"[sudo] password for deploy: \" (only for action='explain_prompt')"),
+		),
+		destructiveTool(),
 	)
 }
 
@@ -1117,10 +1673,14 @@ func (s *Server) handleShellDebug(ctx context.Context, req mcp.CallToolRequest)
 	sessionID := mcp.ParseString(req, "session_id", "")
 	action := mcp.ParseString(req, "action", "status")
 	command := mcp.ParseString(req, "command", "")
+	sample := mcp.ParseString(req, "sample", "")
 
 	if sessionID == "" {
 		return mcp.NewToolResultError(errSessionIDRequired), nil
 	}
+	if action == "explain_prompt" && sample == "" {
+		return mcp.NewToolResultError("sample is required for action='explain_prompt'"), nil
+	}
 
 	sess, err := s.sessionManager.Get(sessionID)
 	if err != nil {
@@ -1147,6 +1707,12 @@ func (s *Server) handleShellDebug(ctx context.Context, req mcp.CallToolRequest)
 		if errResult := handleDebugControlExecAction(ctx, sess, status, command, result); errResult != nil {
 			return errResult, nil
 		}
+	case "explain_prompt":
+		explanation, err := sess.ExplainPrompt(sample)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		result["explanation"] = explanation
 	}
 
 	return jsonResult(result)
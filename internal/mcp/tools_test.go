@@ -188,7 +188,7 @@ func TestApplyAutoTruncation(t *testing.T) {
 				Stdout: tt.stdout,
 			}
 
-			s.applyAutoTruncation("sess_123", result)
+			s.applyAutoTruncation("sess_123", "", result)
 
 			if result.Truncated != tt.wantTruncated {
 				t.Errorf("Truncated = %v, want %v", result.Truncated, tt.wantTruncated)
@@ -233,6 +233,98 @@ func TestApplyAutoTruncation(t *testing.T) {
 	}
 }
 
+func TestApplyAutoTruncation_Fallbacks(t *testing.T) {
+	fc := fakeclock.New(time.Unix(1704067200, 0))
+	tempDir := tempOutputDir()
+	large := strings.Repeat("line of output\n", 5000) + "last line\n"
+
+	tests := []struct {
+		name         string
+		saveDir      string
+		readOnly     []string
+		wantStrategy string
+		wantPrefix   string
+	}{
+		{
+			name:         "configured save_dir",
+			saveDir:      "/var/log/mcp-output",
+			wantStrategy: outputStrategySaveDir,
+			wantPrefix:   "/var/log/mcp-output/sess_123_",
+		},
+		{
+			name:         "unwritable cwd falls back to temp dir",
+			readOnly:     []string{"/ro/project/.claude-shell-mcp"},
+			wantStrategy: outputStrategyTempDir,
+			wantPrefix:   tempDir + "/sess_123_",
+		},
+		{
+			name:         "nothing writable returns the tail inline",
+			saveDir:      "/var/log/mcp-output",
+			readOnly:     []string{"/var/log/mcp-output", tempDir},
+			wantStrategy: outputStrategyTruncated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := fakefs.New()
+			fs.SetCwd("/ro/project")
+			for _, dir := range tt.readOnly {
+				fs.SetReadOnly(dir)
+			}
+			cfg := config.DefaultConfig()
+			cfg.Output.SaveDir = tt.saveDir
+			s := &Server{fs: fs, clock: fc, config: cfg}
+
+			result := &session.ExecResult{Stdout: large}
+			s.applyAutoTruncation("sess_123", "", result)
+
+			if result.OutputStrategy != tt.wantStrategy {
+				t.Fatalf("OutputStrategy = %q, want %q (warning: %s)", result.OutputStrategy, tt.wantStrategy, result.Warning)
+			}
+			if !result.Truncated || result.TotalBytes != len(large) {
+				t.Errorf("Truncated = %v, TotalBytes = %d", result.Truncated, result.TotalBytes)
+			}
+			if tt.wantStrategy != outputStrategyTruncated {
+				if !strings.HasPrefix(result.OutputFile, tt.wantPrefix) || result.Stdout != "" {
+					t.Errorf("OutputFile = %q, Stdout len %d", result.OutputFile, len(result.Stdout))
+				}
+				if data, err := fs.ReadFile(result.OutputFile); err != nil || string(data) != large {
+					t.Errorf("saved output: err=%v len=%d", err, len(data))
+				}
+				return
+			}
+
+			if result.OutputFile != "" || result.TruncatedBytes != len(result.Stdout) {
+				t.Errorf("OutputFile = %q, TruncatedBytes = %d, Stdout len %d", result.OutputFile, result.TruncatedBytes, len(result.Stdout))
+			}
+			if len(result.Stdout) > saveToFileThreshold || !strings.HasPrefix(result.Stdout, "line of output\n") || !strings.HasSuffix(result.Stdout, "last line\n") {
+				t.Errorf("Stdout is not the tail of the output cut at a line: %q...", truncStr(result.Stdout, 40))
+			}
+			if !strings.Contains(result.Warning, "failed to save to file") || !strings.Contains(result.Warning, "permission denied") {
+				t.Errorf("Warning = %q", result.Warning)
+			}
+		})
+	}
+}
+
+func TestTailBytes(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"one\ntwo\nthree\n", 9, "three\n"},
+		{"abcdefgh", 3, "fgh"},
+		{"abc\n", 2, "c\n"},
+	}
+	for _, tt := range tests {
+		if got := tailBytes(tt.s, tt.n); got != tt.want {
+			t.Errorf("tailBytes(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
 func TestLookupSudoPasswordFromConfig(t *testing.T) {
 	tests := []struct {
 		name     string
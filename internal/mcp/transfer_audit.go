@@ -0,0 +1,316 @@
+package mcp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// transferAuditVersion is the TransferAuditRecord format version.
+const transferAuditVersion = 1
+
+// Transfer directions in TransferAuditRecord.Direction.
+const (
+	auditDownload = "download"
+	auditUpload   = "upload"
+	auditRelay    = "relay"
+)
+
+// auditedTransferTools are the tools that get an audit record when
+// transfer.audit_dir is set.
+var auditedTransferTools = map[string]bool{
+	"shell_file_get":         true,
+	"shell_file_put":         true,
+	"shell_file_get_chunked": true,
+	"shell_file_put_chunked": true,
+	"shell_transfer_resume":  true,
+	"shell_dir_get":          true,
+	"shell_dir_put":          true,
+	"shell_file_relay":       true,
+}
+
+// TransferAuditRecord is the audit record of one completed transfer.
+// Endpoints are "<session_id>:<path>" for paths on a session's host,
+// "local:<path>" for paths on the server and "inline" for content passed in
+// the tool call or its result. It never holds file content or credentials.
+type TransferAuditRecord struct {
+	Version     int                    `json:"version"`
+	Tool        string                 `json:"tool"`
+	Status      string                 `json:"status"`
+	Direction   string                 `json:"direction"`
+	Source      string                 `json:"source"`
+	Destination string                 `json:"destination"`
+	Size        int64                  `json:"size"`
+	SHA256      string                 `json:"sha256,omitempty"`
+	Files       int                    `json:"files,omitempty"`  // directory transfers
+	Errors      int                    `json:"errors,omitempty"` // files that failed in a directory transfer
+	StartedAt   time.Time              `json:"started_at"`
+	EndedAt     time.Time              `json:"ended_at"`
+	Sessions    []TransferAuditSession `json:"sessions"`
+
+	// Signature is the hex HMAC-SHA256, keyed with
+	// transfer.audit_signing_key_env, of the record's compact JSON encoding
+	// with Signature empty.
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+	Signature          string `json:"signature,omitempty"`
+}
+
+// TransferAuditSession identifies a session that took part in a transfer.
+type TransferAuditSession struct {
+	ID     string `json:"id"`
+	Mode   string `json:"mode,omitempty"`
+	Target string `json:"target,omitempty"` // user@host:port for SSH sessions
+}
+
+// transferAuditFields are the result fields of the audited tools that an
+// audit record is built from.
+type transferAuditFields struct {
+	Status           string          `json:"status"`
+	RemotePath       string          `json:"remote_path"`
+	LocalPath        string          `json:"local_path"`
+	Size             int64           `json:"size"`
+	Checksum         string          `json:"checksum"`
+	ManifestPath     string          `json:"manifest_path"`
+	ManifestRetained *bool           `json:"manifest_retained"`
+	FilesTransferred int             `json:"files_transferred"`
+	TotalBytes       int64           `json:"total_bytes"`
+	Errors           []TransferError `json:"errors"`
+	ErrorsOmitted    int             `json:"errors_omitted"`
+	SourceSession    string          `json:"source_session_id"`
+	SourcePath       string          `json:"source_path"`
+	DestSession      string          `json:"dest_session_id"`
+	DestPath         string          `json:"dest_path"`
+}
+
+// auditTransfers is tool middleware that writes a TransferAuditRecord to
+// transfer.audit_dir after each completed transfer. Checksums are forced on
+// for shell_file_get and shell_file_put so every record has one.
+func (s *Server) auditTransfers(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := req.Params.Name
+		if !auditedTransferTools[tool] || s.config.Transfer.AuditDir == "" {
+			return next(ctx, req)
+		}
+		if tool == "shell_file_get" || tool == "shell_file_put" {
+			args := maps.Clone(req.GetArguments())
+			if args == nil {
+				args = map[string]any{}
+			}
+			args["checksum"] = true
+			req.Params.Arguments = args
+		}
+
+		started := s.clock.Now()
+		result, err := next(ctx, req)
+		if err != nil || result == nil || result.IsError {
+			return result, err
+		}
+		if path, auditErr := s.writeTransferAudit(tool, req, result, started); auditErr != nil {
+			slog.Warn("failed to write transfer audit record",
+				slog.String("tool", tool),
+				slog.String("error", auditErr.Error()),
+			)
+		} else if path != "" {
+			slog.Info("transfer audit record written",
+				slog.String("tool", tool),
+				slog.String("path", path),
+			)
+		}
+		return result, nil
+	}
+}
+
+// writeTransferAudit builds the audit record of a successful tool call and
+// writes it to the audit directory. It returns "" when the call did not
+// complete a transfer, e.g. a chunked transfer that is only queued.
+func (s *Server) writeTransferAudit(tool string, req mcp.CallToolRequest, result *mcp.CallToolResult, started time.Time) (string, error) {
+	text := resultContentText(result)
+	var f transferAuditFields
+	if err := json.Unmarshal([]byte(text), &f); err != nil {
+		return "", fmt.Errorf("parse %s result: %w", tool, err)
+	}
+	if f.ManifestRetained != nil && !*f.ManifestRetained {
+		// finalizeChunkedTransfer left the manifest for this record.
+		defer s.removeManifest(f.ManifestPath)
+	}
+
+	rec, err := s.transferAuditRecord(tool, req, f)
+	if err != nil || rec == nil {
+		return "", err
+	}
+	rec.Version = transferAuditVersion
+	rec.Tool = tool
+	rec.Status = f.Status
+	if rec.StartedAt.IsZero() {
+		rec.StartedAt = started
+	}
+	rec.EndedAt = s.clock.Now()
+
+	if err := s.signTransferAudit(rec); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	dir := s.config.Transfer.AuditDir
+	if err := s.fs.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("create audit dir: %w", err)
+	}
+	name := fmt.Sprintf("transfer-%s-%s.json", rec.EndedAt.UTC().Format("20060102T150405Z"), randomSuffix())
+	path := filepath.Join(dir, name)
+	if err := s.fs.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("write audit record: %w", err)
+	}
+	return path, nil
+}
+
+// transferAuditRecord fills in what was transferred from the request and
+// the tool's result.
+func (s *Server) transferAuditRecord(tool string, req mcp.CallToolRequest, f transferAuditFields) (*TransferAuditRecord, error) {
+	sessionID := mcp.ParseString(req, "session_id", "")
+	rec := &TransferAuditRecord{Size: f.Size, SHA256: f.Checksum}
+
+	switch tool {
+	case "shell_file_get":
+		rec.Direction = auditDownload
+		rec.Source = sessionID + ":" + f.RemotePath
+		rec.Destination = localEndpoint(f.LocalPath)
+
+	case "shell_file_put":
+		rec.Direction = auditUpload
+		rec.Source = localEndpoint(mcp.ParseString(req, "local_path", ""))
+		rec.Destination = sessionID + ":" + f.RemotePath
+
+	case "shell_file_get_chunked", "shell_file_put_chunked", "shell_transfer_resume":
+		if f.Status != "completed" {
+			return nil, nil
+		}
+		manifest, err := s.loadManifest(f.ManifestPath)
+		if err != nil {
+			return nil, err
+		}
+		// The local file is the same as the remote one once the transfer
+		// completed, and hashing it here costs no network round trip.
+		if rec.SHA256, err = s.hashLocalFile(manifest.LocalPath); err != nil {
+			return nil, fmt.Errorf("hash %s: %w", manifest.LocalPath, err)
+		}
+		rec.Size = manifest.TotalSize
+		rec.StartedAt = manifest.StartedAt
+		remote := manifest.SessionID + ":" + manifest.RemotePath
+		if manifest.Direction == "get" {
+			rec.Direction, rec.Source, rec.Destination = auditDownload, remote, localEndpoint(manifest.LocalPath)
+		} else {
+			rec.Direction, rec.Source, rec.Destination = auditUpload, localEndpoint(manifest.LocalPath), remote
+		}
+		sessionID = manifest.SessionID
+
+	case "shell_dir_get", "shell_dir_put":
+		rec.Size, rec.SHA256 = f.TotalBytes, ""
+		rec.Files = f.FilesTransferred
+		rec.Errors = len(f.Errors) + f.ErrorsOmitted
+		remote := sessionID + ":" + mcp.ParseString(req, "remote_path", "")
+		local := localEndpoint(mcp.ParseString(req, "local_path", ""))
+		if tool == "shell_dir_get" {
+			rec.Direction, rec.Source, rec.Destination = auditDownload, remote, local
+		} else {
+			rec.Direction, rec.Source, rec.Destination = auditUpload, local, remote
+		}
+
+	case "shell_file_relay":
+		rec.Direction = auditRelay
+		rec.Source = f.SourceSession + ":" + f.SourcePath
+		rec.Destination = f.DestSession + ":" + f.DestPath
+		rec.Sessions = []TransferAuditSession{s.auditSession(f.SourceSession), s.auditSession(f.DestSession)}
+		return rec, nil
+	}
+
+	rec.Sessions = []TransferAuditSession{s.auditSession(sessionID)}
+	return rec, nil
+}
+
+// localEndpoint names a path on the server, or inline content when p is
+// empty.
+func localEndpoint(p string) string {
+	if p == "" {
+		return "inline"
+	}
+	return "local:" + p
+}
+
+// auditSession describes a session for an audit record. A session that is
+// gone by now is recorded by ID only.
+func (s *Server) auditSession(id string) TransferAuditSession {
+	as := TransferAuditSession{ID: id}
+	sess, err := s.sessionManager.Get(id)
+	if err != nil {
+		return as
+	}
+	as.Mode = sess.Mode
+	if sess.IsSSH() {
+		as.Target = duplicateSessionKey(sess.User, sess.Host, sess.Port)
+	}
+	return as
+}
+
+// hashLocalFile returns the hex SHA-256 of a file on the server.
+func (s *Server) hashLocalFile(p string) (string, error) {
+	f, err := s.fs.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signTransferAudit signs rec when transfer.audit_signing_key_env is set.
+// A configured but empty key leaves the record unsigned with a warning.
+func (s *Server) signTransferAudit(rec *TransferAuditRecord) error {
+	env := s.config.Transfer.AuditSigningKeyEnv
+	if env == "" {
+		return nil
+	}
+	key := s.fs.Getenv(env)
+	if key == "" {
+		slog.Warn("audit signing key is not set; writing unsigned record",
+			slog.String("env", env),
+		)
+		return nil
+	}
+	rec.SignatureAlgorithm = "hmac-sha256"
+	rec.Signature = ""
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	rec.Signature = hex.EncodeToString(mac.Sum(nil))
+	return nil
+}
+
+// resultContentText returns the text of a tool result's first text content.
+func resultContentText(result *mcp.CallToolResult) string {
+	for _, c := range result.Content {
+		if tc, ok := mcp.AsTextContent(c); ok {
+			return tc.Text
+		}
+	}
+	return ""
+}
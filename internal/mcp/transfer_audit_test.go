@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+	mcpgo "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func newAuditTestServer(signingKey string) (*Server, *fakefs.FS) {
+	ffs := fakefs.New()
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_audit"))
+	cfg := config.DefaultConfig()
+	cfg.Transfer.AuditDir = "/var/audit"
+	if signingKey != "" {
+		cfg.Transfer.AuditSigningKeyEnv = "AUDIT_KEY"
+		ffs.SetEnv("AUDIT_KEY", signingKey)
+	}
+	return newTestServerWithConfig(sm, ffs, cfg), ffs
+}
+
+// callAudited calls handler for tool through the audit middleware.
+func callAudited(t *testing.T, srv *Server, tool string, handler server.ToolHandlerFunc, args map[string]any) *mcpgo.CallToolResult {
+	t.Helper()
+	req := makeRequest(args)
+	req.Params.Name = tool
+	result, err := srv.auditTransfers(handler)(context.Background(), req)
+	if err != nil {
+		t.Fatalf("%s error: %v", tool, err)
+	}
+	return result
+}
+
+// auditRecords returns the audit records written to /var/audit.
+func auditRecords(t *testing.T, ffs *fakefs.FS) ([]TransferAuditRecord, []string) {
+	t.Helper()
+	var recs []TransferAuditRecord
+	var raw []string
+	for _, name := range ffs.Files() {
+		if !strings.HasPrefix(name, "/var/audit/transfer-") {
+			continue
+		}
+		data, _ := ffs.ReadFile(name)
+		var rec TransferAuditRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			t.Fatalf("record %s: %v", name, err)
+		}
+		if info, _ := ffs.Stat(name); info.Mode().Perm() != 0600 {
+			t.Errorf("record mode = %v, want 0600", info.Mode().Perm())
+		}
+		recs = append(recs, rec)
+		raw = append(raw, string(data))
+	}
+	return recs, raw
+}
+
+func TestAuditTransfers_FilePutSigned(t *testing.T) {
+	srv, ffs := newAuditTestServer("k3y")
+
+	result := callAudited(t, srv, "shell_file_put", srv.handleShellFilePut, map[string]any{
+		"session_id":  "sess_audit",
+		"remote_path": "/srv/app/token.txt",
+		"content":     "top-secret-token",
+		"checksum":    false,
+	})
+	if result.IsError {
+		t.Fatalf("put failed: %s", resultText(result))
+	}
+
+	recs, raw := auditRecords(t, ffs)
+	if len(recs) != 1 {
+		t.Fatalf("wrote %d audit records, want 1", len(recs))
+	}
+	rec := recs[0]
+	sum := sha256.Sum256([]byte("top-secret-token"))
+	if rec.Tool != "shell_file_put" || rec.Direction != "upload" || rec.Status != "completed" {
+		t.Errorf("tool=%q direction=%q status=%q", rec.Tool, rec.Direction, rec.Status)
+	}
+	if rec.Source != "inline" || rec.Destination != "sess_audit:/srv/app/token.txt" {
+		t.Errorf("source=%q destination=%q", rec.Source, rec.Destination)
+	}
+	if rec.Size != 16 || rec.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("size=%d sha256=%q, want the checksum even with checksum=false", rec.Size, rec.SHA256)
+	}
+	if len(rec.Sessions) != 1 || rec.Sessions[0].ID != "sess_audit" || rec.Sessions[0].Mode != "local" {
+		t.Errorf("sessions = %+v", rec.Sessions)
+	}
+	if rec.StartedAt.IsZero() || rec.EndedAt.Before(rec.StartedAt) {
+		t.Errorf("started_at=%v ended_at=%v", rec.StartedAt, rec.EndedAt)
+	}
+	if strings.Contains(raw[0], "top-secret-token") || strings.Contains(raw[0], "k3y") {
+		t.Errorf("record leaks content or the signing key:\n%s", raw[0])
+	}
+
+	// The signature covers the compact record without the signature.
+	if rec.SignatureAlgorithm != "hmac-sha256" {
+		t.Fatalf("signature_algorithm = %q", rec.SignatureAlgorithm)
+	}
+	sig := rec.Signature
+	rec.Signature = ""
+	data, _ := json.Marshal(rec)
+	mac := hmac.New(sha256.New, []byte("k3y"))
+	mac.Write(data)
+	if want := hex.EncodeToString(mac.Sum(nil)); sig != want {
+		t.Errorf("signature = %q, want %q", sig, want)
+	}
+}
+
+func TestAuditTransfers_FileGetUnsigned(t *testing.T) {
+	srv, ffs := newAuditTestServer("")
+	ffs.AddFile("/etc/app.conf", []byte("listen 80;\n"), 0644)
+
+	result := callAudited(t, srv, "shell_file_get", srv.handleShellFileGet, map[string]any{
+		"session_id":  "sess_audit",
+		"remote_path": "/etc/app.conf",
+	})
+	if result.IsError {
+		t.Fatalf("get failed: %s", resultText(result))
+	}
+
+	recs, _ := auditRecords(t, ffs)
+	if len(recs) != 1 {
+		t.Fatalf("wrote %d audit records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if rec.Direction != "download" || rec.Source != "sess_audit:/etc/app.conf" || rec.Destination != "inline" {
+		t.Errorf("direction=%q source=%q destination=%q", rec.Direction, rec.Source, rec.Destination)
+	}
+	if rec.Signature != "" || rec.SignatureAlgorithm != "" {
+		t.Errorf("record signed without a signing key: %+v", rec)
+	}
+}
+
+func TestAuditTransfers_SkipsFailuresAndOtherTools(t *testing.T) {
+	srv, ffs := newAuditTestServer("")
+	ffs.AddFile("/etc/app.conf", []byte("listen 80;\n"), 0644)
+
+	result := callAudited(t, srv, "shell_file_get", srv.handleShellFileGet, map[string]any{
+		"session_id":  "sess_audit",
+		"remote_path": "/etc/missing.conf",
+	})
+	if !result.IsError {
+		t.Fatalf("get of a missing file succeeded: %s", resultText(result))
+	}
+	callAudited(t, srv, "shell_file_checksum", srv.handleShellFileChecksum, map[string]any{
+		"session_id": "sess_audit",
+		"path":       "/etc/app.conf",
+	})
+
+	if recs, _ := auditRecords(t, ffs); len(recs) != 0 {
+		t.Errorf("wrote %d audit records, want none", len(recs))
+	}
+}
+
+func TestAuditTransfers_Disabled(t *testing.T) {
+	srv, ffs := newAuditTestServer("")
+	srv.config.Transfer.AuditDir = ""
+
+	callAudited(t, srv, "shell_file_put", srv.handleShellFilePut, map[string]any{
+		"session_id":  "sess_audit",
+		"remote_path": "/srv/app/token.txt",
+		"content":     "x",
+	})
+	for _, name := range ffs.Files() {
+		if strings.Contains(name, "transfer-") {
+			t.Errorf("audit record %s written with audit_dir unset", name)
+		}
+	}
+}
+
+func TestAuditTransfers_Relay(t *testing.T) {
+	srv, ffs := newAuditTestServer("")
+	srv.sessionManager.(*fakesessionmgr.Manager).AddSession(newLocalSession("sess_dest"))
+	ffs.AddFile("/srv/release.tar", []byte("release"), 0644)
+
+	result := callAudited(t, srv, "shell_file_relay", srv.handleShellFileRelay, map[string]any{
+		"source_session_id": "sess_audit",
+		"source_path":       "/srv/release.tar",
+		"dest_session_id":   "sess_dest",
+		"dest_path":         "/opt/release.tar",
+	})
+	if result.IsError {
+		t.Fatalf("relay failed: %s", resultText(result))
+	}
+
+	recs, _ := auditRecords(t, ffs)
+	if len(recs) != 1 {
+		t.Fatalf("wrote %d audit records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if rec.Direction != "relay" || rec.Source != "sess_audit:/srv/release.tar" || rec.Destination != "sess_dest:/opt/release.tar" {
+		t.Errorf("direction=%q source=%q destination=%q", rec.Direction, rec.Source, rec.Destination)
+	}
+	if len(rec.Sessions) != 2 || rec.Sessions[1].ID != "sess_dest" || rec.SHA256 == "" {
+		t.Errorf("sessions=%+v sha256=%q", rec.Sessions, rec.SHA256)
+	}
+}
@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// TransferSlot describes a chunked or directory transfer holding or waiting
+// for one of the transfer.max_concurrent_transfers slots.
+type TransferSlot struct {
+	Tool          string     `json:"tool"`
+	SessionID     string     `json:"session_id"`
+	Source        string     `json:"source"`
+	Destination   string     `json:"destination"`
+	ManifestPath  string     `json:"manifest_path,omitempty"`
+	QueuedAt      time.Time  `json:"queued_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	QueuePosition int        `json:"queue_position,omitempty"`
+}
+
+// transferWaiter is a queued transfer; ready is closed when it gets a slot.
+type transferWaiter struct {
+	slot  *TransferSlot
+	ready chan struct{}
+}
+
+// transferLimiter bounds the number of transfers running at once. Transfers
+// past the limit wait in FIFO order or are rejected, depending on onLimit.
+type transferLimiter struct {
+	mu      sync.Mutex
+	max     int // 0 = unlimited
+	onLimit string
+	clock   ports.Clock
+	running []*TransferSlot
+	queue   []*transferWaiter
+}
+
+func newTransferLimiter(cfg config.TransferConfig, clock ports.Clock) *transferLimiter {
+	l := &transferLimiter{clock: clock}
+	l.configure(cfg)
+	return l
+}
+
+// configure applies new limits. Running transfers are never interrupted; a
+// raised limit lets queued transfers start right away.
+func (l *transferLimiter) configure(cfg config.TransferConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.max = cfg.MaxConcurrentTransfers
+	l.onLimit = cfg.OnLimit
+	if l.onLimit == "" {
+		l.onLimit = config.TransferLimitQueue
+	}
+	l.promoteLocked()
+}
+
+var errTransferLimit = errors.New("too many concurrent transfers")
+
+// acquire takes a transfer slot, waiting in the queue if the limit is reached
+// and onLimit is "queue". The returned release func must be called when the
+// transfer finishes. abort stops waiting (e.g. on shutdown).
+func (l *transferLimiter) acquire(ctx context.Context, abort <-chan struct{}, slot TransferSlot) (func(), error) {
+	l.mu.Lock()
+	slot.QueuedAt = l.clock.Now()
+	s := &slot
+	if l.max == 0 || len(l.running) < l.max {
+		s.StartedAt = &slot.QueuedAt
+		l.running = append(l.running, s)
+		l.mu.Unlock()
+		return func() { l.release(s) }, nil
+	}
+	if l.onLimit == config.TransferLimitReject {
+		running, max := len(l.running), l.max
+		l.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d running, limit is %d", errTransferLimit, running, max)
+	}
+	w := &transferWaiter{slot: s, ready: make(chan struct{})}
+	l.queue = append(l.queue, w)
+	position := len(l.queue)
+	l.mu.Unlock()
+
+	slog.Info("transfer queued",
+		slog.String("tool", s.Tool),
+		slog.String("session_id", s.SessionID),
+		slog.Int("position", position),
+	)
+
+	select {
+	case <-w.ready:
+		return func() { l.release(s) }, nil
+	case <-ctx.Done():
+		return nil, l.abandon(w, ctx.Err())
+	case <-abort:
+		return nil, l.abandon(w, errors.New(errShuttingDown))
+	}
+}
+
+// abandon removes a waiter that gave up. If it was handed a slot in the
+// meantime, the slot is released again.
+func (l *transferLimiter) abandon(w *transferWaiter, err error) error {
+	l.mu.Lock()
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.mu.Unlock()
+	l.release(w.slot)
+	return err
+}
+
+func (l *transferLimiter) release(s *TransferSlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, r := range l.running {
+		if r == s {
+			l.running = append(l.running[:i], l.running[i+1:]...)
+			break
+		}
+	}
+	l.promoteLocked()
+}
+
+// promoteLocked starts queued transfers while slots are free.
+func (l *transferLimiter) promoteLocked() {
+	for len(l.queue) > 0 && (l.max == 0 || len(l.running) < l.max) {
+		w := l.queue[0]
+		l.queue = l.queue[1:]
+		now := l.clock.Now()
+		w.slot.StartedAt = &now
+		l.running = append(l.running, w.slot)
+		close(w.ready)
+	}
+}
+
+// snapshot returns copies of the running and queued transfers.
+func (l *transferLimiter) snapshot() (running, queued []TransferSlot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	running = make([]TransferSlot, 0, len(l.running))
+	for _, r := range l.running {
+		running = append(running, *r)
+	}
+	queued = make([]TransferSlot, 0, len(l.queue))
+	for i, w := range l.queue {
+		q := *w.slot
+		q.QueuePosition = i + 1
+		queued = append(queued, q)
+	}
+	return running, queued
+}
+
+// queuedByManifest returns the queued transfer using manifestPath, if any.
+func (l *transferLimiter) queuedByManifest(manifestPath string) (TransferSlot, bool) {
+	_, queued := l.snapshot()
+	for _, q := range queued {
+		if q.ManifestPath == manifestPath {
+			return q, true
+		}
+	}
+	return TransferSlot{}, false
+}
+
+// acquireTransferSlot wraps transferLimiter.acquire for tool handlers: a
+// rejected or abandoned wait becomes a tool error result.
+func (s *Server) acquireTransferSlot(ctx context.Context, slot TransferSlot) (func(), *mcp.CallToolResult) {
+	release, err := s.transferLimiter.acquire(ctx, s.abortTransfers, slot)
+	if err != nil {
+		if errors.Is(err, errTransferLimit) {
+			return nil, mcp.NewToolResultError(fmt.Sprintf(
+				"%v; retry when one finishes (see shell_transfer_status) or raise transfer.max_concurrent_transfers", err))
+		}
+		return nil, mcp.NewToolResultError(fmt.Sprintf("waiting for a transfer slot: %v", err))
+	}
+	return release, nil
+}
+
+// TransferQueueStatus is returned by shell_transfer_status without a
+// manifest_path.
+type TransferQueueStatus struct {
+	MaxConcurrent int            `json:"max_concurrent_transfers"`
+	Running       []TransferSlot `json:"running"`
+	Queued        []TransferSlot `json:"queued"`
+}
+
+func (s *Server) transferQueueStatus() (*mcp.CallToolResult, error) {
+	running, queued := s.transferLimiter.snapshot()
+	return jsonResult(TransferQueueStatus{
+		MaxConcurrent: s.config.Transfer.MaxConcurrentTransfers,
+		Running:       running,
+		Queued:        queued,
+	})
+}
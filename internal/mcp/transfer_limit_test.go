@@ -0,0 +1,215 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakesessionmgr"
+)
+
+func newTestLimiter(max int, onLimit string) *transferLimiter {
+	return newTransferLimiter(config.TransferConfig{
+		MaxConcurrentTransfers: max,
+		OnLimit:                onLimit,
+	}, fakeclock.New(time.Now()))
+}
+
+func TestTransferLimiter_Unlimited(t *testing.T) {
+	l := newTestLimiter(0, config.TransferLimitReject)
+	for i := 0; i < 10; i++ {
+		if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "t"}); err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+	}
+	running, _ := l.snapshot()
+	if len(running) != 10 {
+		t.Errorf("running = %d, want 10", len(running))
+	}
+}
+
+func TestTransferLimiter_Reject(t *testing.T) {
+	l := newTestLimiter(1, config.TransferLimitReject)
+	release, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "first"})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "second"}); err == nil {
+		t.Fatal("expected second transfer to be rejected")
+	}
+	release()
+	if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "third"}); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+}
+
+func TestTransferLimiter_QueueFIFO(t *testing.T) {
+	l := newTestLimiter(1, config.TransferLimitQueue)
+	release, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "first"})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	started := make(chan string, 2)
+	for _, name := range []string{"second", "third"} {
+		go func(name string) {
+			rel, err := l.acquire(context.Background(), nil, TransferSlot{Tool: name, ManifestPath: "/tmp/" + name})
+			if err != nil {
+				t.Errorf("acquire %s: %v", name, err)
+				return
+			}
+			started <- name
+			rel()
+		}(name)
+		waitForQueued(t, l, name)
+	}
+
+	slot, ok := l.queuedByManifest("/tmp/third")
+	if !ok || slot.QueuePosition != 2 {
+		t.Errorf("queuedByManifest(third) = %+v, %v; want position 2", slot, ok)
+	}
+
+	release()
+	for _, want := range []string{"second", "third"} {
+		select {
+		case got := <-started:
+			if got != want {
+				t.Errorf("started %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s never started", want)
+		}
+	}
+}
+
+func TestTransferLimiter_QueueCancelled(t *testing.T) {
+	l := newTestLimiter(1, config.TransferLimitQueue)
+	if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "first"}); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	abort := make(chan struct{})
+	errc := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(context.Background(), abort, TransferSlot{Tool: "waiting"})
+		errc <- err
+	}()
+	waitForQueued(t, l, "waiting")
+	close(abort)
+
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), errShuttingDown) {
+			t.Errorf("err = %v, want %q", err, errShuttingDown)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued acquire did not return on abort")
+	}
+	if _, queued := l.snapshot(); len(queued) != 0 {
+		t.Errorf("queued = %d, want 0 after abort", len(queued))
+	}
+}
+
+func TestTransferLimiter_RaisedLimitStartsQueued(t *testing.T) {
+	l := newTestLimiter(1, config.TransferLimitQueue)
+	if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "first"}); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		if _, err := l.acquire(context.Background(), nil, TransferSlot{Tool: "second"}); err != nil {
+			t.Errorf("acquire: %v", err)
+		}
+		close(done)
+	}()
+	waitForQueued(t, l, "second")
+
+	l.configure(config.TransferConfig{MaxConcurrentTransfers: 2})
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued transfer did not start after the limit was raised")
+	}
+}
+
+// waitForQueued waits until a transfer with the given tool name is queued.
+func waitForQueued(t *testing.T, l *transferLimiter, tool string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		_, queued := l.snapshot()
+		for _, q := range queued {
+			if q.Tool == tool {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("%s was never queued", tool)
+}
+
+func TestHandleDirGet_RejectedAtTransferLimit(t *testing.T) {
+	sm := fakesessionmgr.New()
+	sm.AddSession(newLocalSession("sess_1"))
+	cfg := config.DefaultConfig()
+	cfg.Transfer = config.TransferConfig{MaxConcurrentTransfers: 1, OnLimit: config.TransferLimitReject}
+	srv := newTestServerWithConfig(sm, fakefs.New(), cfg)
+
+	release, errResult := srv.acquireTransferSlot(context.Background(), TransferSlot{Tool: "shell_file_get_chunked"})
+	if errResult != nil {
+		t.Fatalf("acquire: %s", resultText(errResult))
+	}
+	defer release()
+
+	result, err := srv.handleShellDirGet(context.Background(), makeRequest(map[string]any{
+		"session_id":  "sess_1",
+		"remote_path": "/src",
+		"local_path":  "/dst",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(resultText(result), "too many concurrent transfers") {
+		t.Fatalf("expected transfer limit error, got: %s", resultText(result))
+	}
+
+	status, _ := srv.handleShellTransferStatus(context.Background(), makeRequest(map[string]any{}))
+	m := resultJSON(t, status)
+	if running, _ := m["running"].([]any); len(running) != 1 {
+		t.Errorf("running = %v, want 1 entry", m["running"])
+	}
+}
+
+func TestHandleTransferStatus_Queued(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Transfer.MaxConcurrentTransfers = 1
+	srv := newTestServerWithConfig(fakesessionmgr.New(), fakefs.New(), cfg)
+
+	release, errResult := srv.acquireTransferSlot(context.Background(), TransferSlot{Tool: "first"})
+	if errResult != nil {
+		t.Fatalf("acquire: %s", resultText(errResult))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.acquireTransferSlot(ctx, TransferSlot{Tool: "second", ManifestPath: "/tmp/big.iso.transfer"})
+	waitForQueued(t, srv.transferLimiter, "second")
+
+	result, err := srv.handleShellTransferStatus(context.Background(), makeRequest(map[string]any{
+		"manifest_path": "/tmp/big.iso.transfer",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := resultJSON(t, result)
+	if m["status"] != "queued" {
+		t.Errorf("status = %v, want queued", m["status"])
+	}
+	if m["queue_position"] != float64(1) {
+		t.Errorf("queue_position = %v, want 1", m["queue_position"])
+	}
+	release()
+}
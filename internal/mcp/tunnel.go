@@ -59,6 +59,7 @@ Note: Tunnels are only available for SSH sessions, not local sessions.`),
 		mcp.WithString("remote_host",
 			mcp.Description("Remote host (default: '127.0.0.1' for local, '0.0.0.0' for reverse)"),
 		),
+		additiveTool(),
 	)
 }
 
@@ -76,6 +77,7 @@ Use this to monitor tunnel status and find tunnel IDs for closing.`),
 			mcp.Required(),
 			mcp.Description(descSSHSessionID),
 		),
+		readOnlyTool(),
 	)
 }
 
@@ -93,6 +95,7 @@ The tunnel ID can be found using shell_tunnel_list.`),
 			mcp.Required(),
 			mcp.Description("The tunnel ID to close"),
 		),
+		destructiveTool(),
 	)
 }
 
@@ -300,6 +303,7 @@ Use tunnel_index to restore a specific tunnel, or omit it to restore all saved t
 		mcp.WithNumber("tunnel_index",
 			mcp.Description("Index of specific tunnel to restore (0-based). Omit to restore all."),
 		),
+		additiveTool(),
 	)
 }
 
@@ -343,11 +347,22 @@ func (s *Server) handleShellTunnelRestore(ctx context.Context, req mcp.CallToolR
 		tunnelsToRestore = status.SavedTunnels
 	}
 
+	result := restoreTunnels(sessionID, tunnelManager, tunnelsToRestore)
+
+	// Clear saved tunnels after restore attempt
+	sess.ClearSavedTunnels()
+
+	return jsonResult(result)
+}
+
+// restoreTunnels re-creates the given tunnels on tunnelManager, collecting
+// failures in the result's Errors instead of stopping at the first one.
+func restoreTunnels(sessionID string, tunnelManager *ssh.TunnelManager, configs []session.TunnelConfig) TunnelRestoreResult {
 	result := TunnelRestoreResult{
 		Status: "ok",
 	}
 
-	for _, tc := range tunnelsToRestore {
+	for _, tc := range configs {
 		var tunnel *ssh.Tunnel
 		var err error
 
@@ -379,9 +394,5 @@ func (s *Server) handleShellTunnelRestore(ctx context.Context, req mcp.CallToolR
 			slog.String("type", tc.Type),
 		)
 	}
-
-	// Clear saved tunnels after restore attempt
-	sess.ClearSavedTunnels()
-
-	return jsonResult(result)
+	return result
 }
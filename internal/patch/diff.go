@@ -0,0 +1,189 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the LCS table Diff builds for the changed middle of
+// two files. Larger changes are reported as one hunk replacing the whole
+// region, which is still a correct (if coarse) diff.
+const maxDiffCells = 4 << 20
+
+// diffOp is one line of an edit script: ' ' keeps a[i] (== b[j]), '-'
+// removes a[i] and '+' inserts b[j].
+type diffOp struct {
+	op   byte
+	i, j int
+}
+
+// Diff returns a unified diff turning old into new with context lines of
+// context around each change, and the number of hunks and changed lines.
+// The diff is empty when the contents are equal. oldName and newName are
+// used for the "---" and "+++" headers. The result applies cleanly with
+// ApplyUnified.
+func Diff(oldName, newName string, old, new []byte, context int) (string, Stats) {
+	a, b := diffLines(string(old)), diffLines(string(new))
+	ops := editScript(a, b)
+	if context < 0 {
+		context = 0
+	}
+
+	var sb strings.Builder
+	var stats Stats
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are at
+		// most 2*context lines apart.
+		first := start
+		for first < len(ops) && ops[first].op == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].op != ' ' {
+				last = k
+			} else if k-last > 2*context {
+				break
+			}
+		}
+		lo := max(first-context, start)
+		hi := min(last+context+1, len(ops))
+
+		if stats.Hunks == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(&sb, a, b, ops[lo:hi], &stats)
+		start = hi
+	}
+	return sb.String(), stats
+}
+
+// diffLines splits s into lines that keep their "\n", so a missing final
+// newline makes the last line differ.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// editScript returns the edit script from a to b: the common prefix and
+// suffix are kept and the middle is diffed with an LCS table.
+func editScript(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b)-pre-suf)
+	for k := 0; k < pre; k++ {
+		ops = append(ops, diffOp{' ', k, k})
+	}
+	ops = append(ops, diffMiddle(a[pre:len(a)-suf], b[pre:len(b)-suf], pre)...)
+	for k := suf; k > 0; k-- {
+		ops = append(ops, diffOp{' ', len(a) - k, len(b) - k})
+	}
+	return ops
+}
+
+// diffMiddle diffs a and b, which start at line off of both files.
+func diffMiddle(a, b []string, off int) []diffOp {
+	n, m := len(a), len(b)
+	var ops []diffOp
+	if n*m > maxDiffCells {
+		for i := range a {
+			ops = append(ops, diffOp{'-', off + i, off})
+		}
+		for j := range b {
+			ops = append(ops, diffOp{'+', off + n, off + j})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', off + i, off + j})
+			i++
+			j++
+		case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', off + i, off + j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', off + i, off + j})
+			j++
+		}
+	}
+	return ops
+}
+
+// writeHunk writes the @@ header and lines of one hunk.
+func writeHunk(sb *strings.Builder, a, b []string, ops []diffOp, stats *Stats) {
+	oldCount, newCount := 0, 0
+	for _, o := range ops {
+		if o.op != '+' {
+			oldCount++
+		}
+		if o.op != '-' {
+			newCount++
+		}
+	}
+	// An empty side starts at the line before the hunk ("-5,0" inserts
+	// after line 5).
+	oldStart, newStart := ops[0].i+1, ops[0].j+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+
+	for _, o := range ops {
+		var line string
+		if o.op == '+' {
+			line = b[o.j]
+		} else {
+			line = a[o.i]
+		}
+		sb.WriteByte(o.op)
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+		switch o.op {
+		case '-':
+			stats.Removed++
+		case '+':
+			stats.Added++
+		}
+	}
+	stats.Hunks++
+}
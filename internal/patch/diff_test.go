@@ -0,0 +1,115 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		old   string
+		new   string
+		want  string
+		stats Stats
+	}{
+		{
+			name:  "equal",
+			old:   "a\nb\n",
+			new:   "a\nb\n",
+			want:  "",
+			stats: Stats{},
+		},
+		{
+			name:  "replace line",
+			old:   "a\nb\nc\n",
+			new:   "a\nB\nc\n",
+			want:  "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			stats: Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:  "insert at start",
+			old:   "b\n",
+			new:   "a\nb\n",
+			want:  "--- a/f\n+++ b/f\n@@ -1,1 +1,2 @@\n+a\n b\n",
+			stats: Stats{Hunks: 1, Added: 1},
+		},
+		{
+			name:  "delete everything",
+			old:   "a\nb\n",
+			new:   "",
+			want:  "--- a/f\n+++ b/f\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+			stats: Stats{Hunks: 1, Removed: 2},
+		},
+		{
+			name:  "final newline added",
+			old:   "a\nb",
+			new:   "a\nb\n",
+			want:  "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			stats: Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:  "distant changes make two hunks",
+			old:   "1\n2\n3\n4\n5\n6\n7\n8\n",
+			new:   "X\n2\n3\n4\n5\n6\n7\nY\n",
+			want:  "--- a/f\n+++ b/f\n@@ -1,2 +1,2 @@\n-1\n+X\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+Y\n",
+			stats: Stats{Hunks: 2, Added: 2, Removed: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := Diff("a/f", "b/f", []byte(tt.old), []byte(tt.new), 1)
+			if got != tt.want {
+				t.Errorf("Diff() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("stats = %+v, want %+v", stats, tt.stats)
+			}
+		})
+	}
+}
+
+func TestDiff_RoundTrip(t *testing.T) {
+	pairs := [][2]string{
+		{"a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n", "a\nB\nc\nd\ne\nf\ng\nh\nI\nj\nk\n"},
+		{"", "new file\n"},
+		{"x\ny\nz", "y\nz\nw"},
+		{"one\r\ntwo\r\nthree\r\n", "one\r\n2\r\nthree\r\n"},
+		{"k: 1\nk: 2\nk: 1\nk: 2\n", "k: 2\nk: 1\nk: 2\nk: 1\n"},
+	}
+	for _, p := range pairs {
+		for _, context := range []int{0, 1, 3} {
+			diff, _ := Diff("a", "b", []byte(p[0]), []byte(p[1]), context)
+			hunks, err := ParseUnified(diff)
+			if err != nil {
+				t.Fatalf("ParseUnified(%q) error = %v", diff, err)
+			}
+			out, _, err := ApplyUnified([]byte(p[0]), hunks)
+			if err != nil {
+				t.Fatalf("ApplyUnified() error = %v for diff\n%s", err, diff)
+			}
+			if string(out) != p[1] {
+				t.Errorf("context %d: applying\n%s\nto %q gave %q, want %q", context, diff, p[0], out, p[1])
+			}
+		}
+	}
+}
+
+func TestDiff_LargeChangeIsOneHunk(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 3000; i++ {
+		a.WriteString("old line\n")
+		b.WriteString("new line\n")
+	}
+	diff, stats := Diff("a", "b", []byte(a.String()), []byte(b.String()), 3)
+	if stats != (Stats{Hunks: 1, Added: 3000, Removed: 3000}) {
+		t.Errorf("stats = %+v", stats)
+	}
+	hunks, err := ParseUnified(diff)
+	if err != nil {
+		t.Fatalf("ParseUnified() error = %v", err)
+	}
+	if out, _, err := ApplyUnified([]byte(a.String()), hunks); err != nil || string(out) != b.String() {
+		t.Errorf("ApplyUnified() error = %v, round trip mismatch", err)
+	}
+}
@@ -0,0 +1,305 @@
+// Package patch applies unified diffs and search/replace edits to file
+// contents in memory.
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ConflictError reports that a patch does not match the content it was
+// applied to. Nothing is changed when it is returned.
+type ConflictError struct {
+	Kind   string // "hunk" or "edit"
+	Index  int    // 1-based hunk or edit number
+	Header string // hunk header, e.g. "@@ -10,3 +10,4 @@"
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Header != "" {
+		return fmt.Sprintf("%s %d (%s): %s", e.Kind, e.Index, e.Header, e.Reason)
+	}
+	return fmt.Sprintf("%s %d: %s", e.Kind, e.Index, e.Reason)
+}
+
+// Hunk is one @@ section of a unified diff.
+type Hunk struct {
+	Header   string
+	OldStart int
+	NewStart int
+
+	lines    []hunkLine
+	oldNoEOL bool // "\ No newline at end of file" after the old side
+	newNoEOL bool // ... after the new side
+}
+
+type hunkLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// old returns the lines the hunk expects to find: context and removals.
+func (h Hunk) old() []string {
+	var lines []string
+	for _, l := range h.lines {
+		if l.op != '+' {
+			lines = append(lines, l.text)
+		}
+	}
+	return lines
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseUnified parses a unified diff of a single file. File headers ("---",
+// "+++", "diff --git", "index") are optional and ignored. The line counts in
+// hunk headers are not checked; the start line is used as a hint for where
+// the hunk applies.
+func ParseUnified(diff string) ([]Hunk, error) {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+
+	var hunks []Hunk
+	var cur *Hunk
+	files := 0
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			newStart, _ := strconv.Atoi(m[3])
+			hunks = append(hunks, Hunk{Header: m[0], OldStart: oldStart, NewStart: newStart})
+			cur = &hunks[len(hunks)-1]
+
+		case isFileHeader(lines, i):
+			if files++; files > 1 {
+				return nil, fmt.Errorf("line %d: patch changes more than one file", i+1)
+			}
+			cur = nil
+
+		case cur == nil:
+			// Preamble before the first hunk: "diff --git", "index", "+++" etc.
+			if strings.HasPrefix(line, "+++ ") && files == 0 {
+				files++
+			}
+
+		case line == "":
+			// Editors and models often strip the leading space of empty
+			// context lines.
+			cur.lines = append(cur.lines, hunkLine{op: ' '})
+
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			cur.lines = append(cur.lines, hunkLine{op: line[0], text: line[1:]})
+
+		case line[0] == '\\':
+			if len(cur.lines) == 0 {
+				return nil, fmt.Errorf("line %d: %q before any hunk line", i+1, line)
+			}
+			switch cur.lines[len(cur.lines)-1].op {
+			case '-':
+				cur.oldNoEOL = true
+			case '+':
+				cur.newNoEOL = true
+			default:
+				cur.oldNoEOL, cur.newNoEOL = true, true
+			}
+
+		default:
+			return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", i+1, line)
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("no hunks (@@ ... @@) found in patch")
+	}
+	return hunks, nil
+}
+
+// isFileHeader reports whether lines[i] starts a "--- a/x" / "+++ b/x" file
+// header pair. A lone "--- " line is a removed line starting with "--".
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") &&
+		i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// Stats summarizes an applied patch.
+type Stats struct {
+	Hunks   int
+	Added   int
+	Removed int
+}
+
+// ApplyUnified applies hunks to content. Each hunk's context and removed
+// lines must match exactly (a trailing "\r" is ignored, so CRLF files can be
+// patched with LF diffs). When they do not match at the hunk's start line,
+// the closest match after the previous hunk is used, like patch(1) with
+// --fuzz=0. A hunk that matches nowhere is a *ConflictError.
+func ApplyUnified(content []byte, hunks []Hunk) ([]byte, Stats, error) {
+	lines, finalNewline := splitLines(string(content))
+	crlf := len(lines) > 0 && strings.HasSuffix(lines[0], "\r")
+
+	var stats Stats
+	out := make([]string, 0, len(lines))
+	pos, offset := 0, 0
+	for i, h := range hunks {
+		old := h.old()
+		want := h.OldStart - 1
+		if len(old) == 0 {
+			want = h.OldStart // "-5,0" inserts after line 5
+		}
+		at, ok := findBlock(lines, old, pos, want+offset)
+		if !ok {
+			return nil, Stats{}, &ConflictError{
+				Kind:   "hunk",
+				Index:  i + 1,
+				Header: h.Header,
+				Reason: mismatchReason(lines, old, max(want+offset, pos)),
+			}
+		}
+		offset = at - want
+
+		out = append(out, lines[pos:at]...)
+		k := at
+		for _, l := range h.lines {
+			switch l.op {
+			case ' ':
+				out = append(out, lines[k])
+				k++
+			case '-':
+				k++
+				stats.Removed++
+			case '+':
+				text := l.text
+				if crlf && !strings.HasSuffix(text, "\r") {
+					text += "\r"
+				}
+				out = append(out, text)
+				stats.Added++
+			}
+		}
+		pos = k
+		if pos == len(lines) && (h.oldNoEOL || h.newNoEOL) {
+			finalNewline = !h.newNoEOL
+		}
+		stats.Hunks++
+	}
+	out = append(out, lines[pos:]...)
+
+	result := strings.Join(out, "\n")
+	if finalNewline && len(out) > 0 {
+		result += "\n"
+	}
+	return []byte(result), stats, nil
+}
+
+// splitLines splits content into lines without their "\n" and reports
+// whether the last line was terminated.
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, true
+	}
+	finalNewline := strings.HasSuffix(content, "\n")
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n"), finalNewline
+}
+
+func lineEqual(a, b string) bool {
+	return strings.TrimSuffix(a, "\r") == strings.TrimSuffix(b, "\r")
+}
+
+func matchesAt(lines, block []string, at int) bool {
+	if at < 0 || at+len(block) > len(lines) {
+		return false
+	}
+	for j, b := range block {
+		if !lineEqual(lines[at+j], b) {
+			return false
+		}
+	}
+	return true
+}
+
+// findBlock returns the index at or after from where block matches lines,
+// preferring the match closest to want.
+func findBlock(lines, block []string, from, want int) (int, bool) {
+	if len(block) == 0 {
+		return min(max(want, from), len(lines)), true
+	}
+	best, found := 0, false
+	for at := from; at+len(block) <= len(lines); at++ {
+		if !matchesAt(lines, block, at) {
+			continue
+		}
+		if !found || abs(at-want) < abs(best-want) {
+			best, found = at, true
+		}
+	}
+	return best, found
+}
+
+// mismatchReason describes the first line where block differs from lines
+// at index at.
+func mismatchReason(lines, block []string, at int) string {
+	for j, b := range block {
+		if at+j >= len(lines) {
+			return fmt.Sprintf("context not found: line %d is past the end of the file (%d lines), expected %q",
+				at+j+1, len(lines), b)
+		}
+		if !lineEqual(lines[at+j], b) {
+			return fmt.Sprintf("context not found: line %d is %q, expected %q", at+j+1, lines[at+j], b)
+		}
+	}
+	return "context not found after the previous hunk"
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Edit replaces Search with Replace. Search must occur exactly once unless
+// All is set.
+type Edit struct {
+	Search  string `json:"search"`
+	Replace string `json:"replace"`
+	All     bool   `json:"all"`
+}
+
+// ApplyEdits applies edits in order and returns the new content and the
+// number of replacements made. An edit whose search text is missing, or
+// ambiguous without All, is a *ConflictError.
+func ApplyEdits(content []byte, edits []Edit) ([]byte, int, error) {
+	text := string(content)
+	replaced := 0
+	for i, e := range edits {
+		if e.Search == "" {
+			return nil, 0, fmt.Errorf("edit %d: search is empty", i+1)
+		}
+		n := strings.Count(text, e.Search)
+		switch {
+		case n == 0:
+			return nil, 0, &ConflictError{Kind: "edit", Index: i + 1, Reason: "search text not found"}
+		case n > 1 && !e.All:
+			return nil, 0, &ConflictError{
+				Kind:   "edit",
+				Index:  i + 1,
+				Reason: fmt.Sprintf("search text matches %d times; include more surrounding text or set all=true", n),
+			}
+		}
+		if e.All {
+			text = strings.ReplaceAll(text, e.Search, e.Replace)
+			replaced += n
+		} else {
+			text = strings.Replace(text, e.Search, e.Replace, 1)
+			replaced++
+		}
+	}
+	return []byte(text), replaced, nil
+}
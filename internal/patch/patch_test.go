@@ -0,0 +1,219 @@
+package patch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func applyDiff(t *testing.T, content, diff string) (string, Stats, error) {
+	t.Helper()
+	hunks, err := ParseUnified(diff)
+	if err != nil {
+		t.Fatalf("ParseUnified() error = %v", err)
+	}
+	out, stats, err := ApplyUnified([]byte(content), hunks)
+	return string(out), stats, err
+}
+
+func TestApplyUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		diff    string
+		want    string
+		stats   Stats
+	}{
+		{
+			name:    "replace line with file headers",
+			content: "a\nb\nc\n",
+			diff:    "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			want:    "a\nB\nc\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "two hunks",
+			content: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			diff:    "@@ -1,2 +1,3 @@\n 1\n+1.5\n 2\n@@ -7,2 +8,1 @@\n 7\n-8\n",
+			want:    "1\n1.5\n2\n3\n4\n5\n6\n7\n",
+			stats:   Stats{Hunks: 2, Added: 1, Removed: 1},
+		},
+		{
+			name:    "wrong start line uses closest match",
+			content: "x\ny\nfoo\nbar\nz\n",
+			diff:    "@@ -1,2 +1,2 @@\n foo\n-bar\n+baz\n",
+			want:    "x\ny\nfoo\nbaz\nz\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "closest of several matches",
+			content: "k\nv\nk\nv\nk\nv\n",
+			diff:    "@@ -5,2 +5,2 @@\n k\n-v\n+w\n",
+			want:    "k\nv\nk\nv\nk\nw\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "pure insertion after line",
+			content: "a\nb\n",
+			diff:    "@@ -1,0 +2,1 @@\n+inserted\n",
+			want:    "a\ninserted\nb\n",
+			stats:   Stats{Hunks: 1, Added: 1},
+		},
+		{
+			name:    "empty context line without leading space",
+			content: "a\n\nb\n",
+			diff:    "@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n",
+			want:    "a\n\nc\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "crlf file with lf diff",
+			content: "a\r\nb\r\n",
+			diff:    "@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+			want:    "a\r\nc\r\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "add newline at end of file",
+			content: "a\nb",
+			diff:    "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+			want:    "a\nb\n",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "remove newline at end of file",
+			content: "a\nb\n",
+			diff:    "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+			want:    "a\nb",
+			stats:   Stats{Hunks: 1, Added: 1, Removed: 1},
+		},
+		{
+			name:    "patch empty file",
+			content: "",
+			diff:    "@@ -0,0 +1,2 @@\n+one\n+two\n",
+			want:    "one\ntwo\n",
+			stats:   Stats{Hunks: 1, Added: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats, err := applyDiff(t, tt.content, tt.diff)
+			if err != nil {
+				t.Fatalf("ApplyUnified() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyUnified() = %q, want %q", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("stats = %+v, want %+v", stats, tt.stats)
+			}
+		})
+	}
+}
+
+func TestApplyUnified_Conflict(t *testing.T) {
+	_, _, err := applyDiff(t, "a\nb\nc\n", "@@ -1,3 +1,3 @@\n a\n-x\n+y\n c\n")
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("error = %v, want *ConflictError", err)
+	}
+	if conflict.Index != 1 || conflict.Header != "@@ -1,3 +1,3 @@" {
+		t.Errorf("conflict = %+v", conflict)
+	}
+	if !strings.Contains(err.Error(), `line 2 is "b", expected "x"`) {
+		t.Errorf("error = %q, want the mismatching line", err)
+	}
+}
+
+func TestApplyUnified_HunksOutOfOrderConflict(t *testing.T) {
+	// The second hunk's context only exists before the first hunk.
+	_, _, err := applyDiff(t, "a\nb\nc\nd\n", "@@ -3,1 +3,1 @@\n-c\n+C\n@@ -1,1 +1,1 @@\n-a\n+A\n")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Index != 2 {
+		t.Errorf("error = %v, want conflict in hunk 2", err)
+	}
+}
+
+func TestApplyUnified_PastEndOfFile(t *testing.T) {
+	_, _, err := applyDiff(t, "a\n", "@@ -1,2 +1,2 @@\n a\n-b\n+c\n")
+	if err == nil || !strings.Contains(err.Error(), "past the end of the file") {
+		t.Errorf("error = %v, want past end of file", err)
+	}
+}
+
+func TestParseUnified_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{"no hunks", "--- a/f\n+++ b/f\n", "no hunks"},
+		{"malformed header", "@@ -x +1 @@\n a\n", "malformed hunk header"},
+		{"two files", "--- a/f\n+++ b/f\n@@ -1 +1 @@\n-a\n+b\n--- a/g\n+++ b/g\n@@ -1 +1 @@\n-a\n+b\n", "more than one file"},
+		{"garbage in hunk", "@@ -1 +1 @@\n-a\n*b\n", "unexpected line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseUnified(tt.diff)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseUnified() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnified_RemovedLineLikeHeader(t *testing.T) {
+	// "--- x" inside a hunk is a removed "-- x" line unless "+++" follows.
+	got, _, err := applyDiff(t, "-- x\ny\n", "@@ -1,2 +1,1 @@\n--- x\n y\n")
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if got != "y\n" {
+		t.Errorf("got %q, want %q", got, "y\n")
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	content := []byte("port = 80\nhost = a\nhost = b\n")
+
+	got, n, err := ApplyEdits(content, []Edit{
+		{Search: "port = 80", Replace: "port = 8080"},
+		{Search: "host = ", Replace: "server = ", All: true},
+	})
+	if err != nil {
+		t.Fatalf("ApplyEdits() error = %v", err)
+	}
+	if want := "port = 8080\nserver = a\nserver = b\n"; string(got) != want {
+		t.Errorf("ApplyEdits() = %q, want %q", got, want)
+	}
+	if n != 3 {
+		t.Errorf("replacements = %d, want 3", n)
+	}
+}
+
+func TestApplyEdits_Conflicts(t *testing.T) {
+	content := []byte("a\na\n")
+	tests := []struct {
+		name  string
+		edits []Edit
+		want  string
+	}{
+		{"not found", []Edit{{Search: "b", Replace: "c"}}, "edit 1: search text not found"},
+		{"ambiguous", []Edit{{Search: "a", Replace: "c"}}, "matches 2 times"},
+		{"later edit", []Edit{{Search: "a\na", Replace: "x"}, {Search: "a", Replace: "y"}}, "edit 2: search text not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ApplyEdits(content, tt.edits)
+			var conflict *ConflictError
+			if !errors.As(err, &conflict) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ApplyEdits() error = %v, want conflict %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := ApplyEdits(content, []Edit{{Search: ""}}); err == nil {
+		t.Error("expected error for empty search")
+	}
+}
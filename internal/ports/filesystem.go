@@ -44,6 +44,9 @@ type FileSystem interface {
 	// Chtimes changes the access and modification times of the named file.
 	Chtimes(name string, atime, mtime time.Time) error
 
+	// Chmod changes the mode of the named file or directory.
+	Chmod(name string, mode fs.FileMode) error
+
 	// UserHomeDir returns the current user's home directory.
 	UserHomeDir() (string, error)
 
@@ -68,6 +71,9 @@ type FileSystem interface {
 	// Readlink returns the destination of the named symbolic link.
 	Readlink(name string) (string, error)
 
+	// ReadDir reads the named directory and returns its entries sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+
 	// Executable returns the path of the current executable.
 	Executable() (string, error)
 }
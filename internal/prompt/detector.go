@@ -2,6 +2,7 @@ package prompt
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -18,14 +19,17 @@ type Detection struct {
 type Detector struct {
 	patterns       []Pattern
 	customPatterns []Pattern
+	ordered        []Pattern // custom + default patterns in evaluation order
 	mu             sync.RWMutex
 }
 
 // NewDetector creates a new prompt detector with default patterns.
 func NewDetector() *Detector {
-	return &Detector{
+	d := &Detector{
 		patterns: DefaultPatterns(),
 	}
+	d.reorder()
+	return d
 }
 
 // AddPattern adds a custom pattern to the detector.
@@ -33,10 +37,53 @@ func (d *Detector) AddPattern(p Pattern) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.customPatterns = append(d.customPatterns, p)
+	d.reorder()
+}
+
+// reorder rebuilds the evaluation order.
+// Must be called with d.mu held.
+func (d *Detector) reorder() {
+	entries := d.entriesLocked()
+	ordered := make([]Pattern, len(entries))
+	for i, e := range entries {
+		ordered[i] = e.Pattern
+	}
+	d.ordered = ordered
+}
+
+// PatternEntry is a pattern of a Detector, with whether it was added as a
+// custom pattern rather than being built in.
+type PatternEntry struct {
+	Pattern
+	Custom bool
+}
+
+// Patterns returns the detector's patterns in evaluation order.
+func (d *Detector) Patterns() []PatternEntry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.entriesLocked()
+}
+
+// entriesLocked returns the patterns in evaluation order: highest priority
+// first, custom patterns before built-ins on a tie, otherwise in insertion
+// order. Must be called with d.mu held.
+func (d *Detector) entriesLocked() []PatternEntry {
+	entries := make([]PatternEntry, 0, len(d.customPatterns)+len(d.patterns))
+	for _, p := range d.customPatterns {
+		entries = append(entries, PatternEntry{Pattern: p, Custom: true})
+	}
+	for _, p := range d.patterns {
+		entries = append(entries, PatternEntry{Pattern: p})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Priority > entries[j].Priority
+	})
+	return entries
 }
 
 // AddPatternFromConfig adds a pattern from configuration.
-func (d *Detector) AddPatternFromConfig(name, regex, promptType string, maskInput bool) error {
+func (d *Detector) AddPatternFromConfig(name, regex, promptType string, maskInput bool, priority int) error {
 	re, err := regexp.Compile(regex)
 	if err != nil {
 		return err
@@ -61,26 +108,19 @@ func (d *Detector) AddPatternFromConfig(name, regex, promptType string, maskInpu
 		Regex:     re,
 		Type:      pt,
 		MaskInput: maskInput,
+		Priority:  priority,
 	})
 
 	return nil
 }
 
 // Detect checks if the buffer contains an interactive prompt.
-// Returns the detection if found, nil otherwise.
+// Patterns are tried in priority order and the first match is returned.
 func (d *Detector) Detect(buffer string) *Detection {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	// Check custom patterns first (higher priority)
-	for _, p := range d.customPatterns {
-		if match := d.matchPattern(buffer, p); match != nil {
-			return match
-		}
-	}
-
-	// Check default patterns
-	for _, p := range d.patterns {
+	for _, p := range d.ordered {
 		if match := d.matchPattern(buffer, p); match != nil {
 			return match
 		}
@@ -119,15 +159,14 @@ func (d *Detector) matchPattern(buffer string, p Pattern) *Detection {
 	return nil
 }
 
-// DetectAll returns all matching prompts in the buffer.
+// DetectAll returns all matching prompts in the buffer, in priority order.
 func (d *Detector) DetectAll(buffer string) []Detection {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
 	var detections []Detection
 
-	allPatterns := append(d.customPatterns, d.patterns...)
-	for _, p := range allPatterns {
+	for _, p := range d.ordered {
 		if match := d.matchPattern(buffer, p); match != nil {
 			detections = append(detections, *match)
 		}
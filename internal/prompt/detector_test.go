@@ -62,7 +62,7 @@ func TestAddPattern(t *testing.T) {
 func TestAddPatternFromConfig_ValidRegex(t *testing.T) {
 	d := NewDetector()
 
-	err := d.AddPatternFromConfig("vault_pw", `Vault password:\s*$`, "password", true)
+	err := d.AddPatternFromConfig("vault_pw", `Vault password:\s*$`, "password", true, 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -85,7 +85,7 @@ func TestAddPatternFromConfig_ValidRegex(t *testing.T) {
 func TestAddPatternFromConfig_InvalidRegex(t *testing.T) {
 	d := NewDetector()
 
-	err := d.AddPatternFromConfig("bad", `[invalid(`, "text", false)
+	err := d.AddPatternFromConfig("bad", `[invalid(`, "text", false, 0)
 	if err == nil {
 		t.Fatal("expected error for invalid regex, got nil")
 	}
@@ -107,7 +107,7 @@ func TestAddPatternFromConfig_PromptTypes(t *testing.T) {
 	for _, tt := range tests {
 		t.Run("type_"+tt.inputType, func(t *testing.T) {
 			d := NewDetector()
-			err := d.AddPatternFromConfig("test", `test_prompt`, tt.inputType, false)
+			err := d.AddPatternFromConfig("test", `test_prompt`, tt.inputType, false, 0)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -619,3 +619,86 @@ func TestDetect_BufferElevenLinesPromptOnFirstLine(t *testing.T) {
 		t.Errorf("prompt on line 1 of 11 should not be detected, got %q", det.Pattern.Name)
 	}
 }
+
+func TestDetect_PriorityOrder(t *testing.T) {
+	d := NewDetector()
+
+	d.AddPattern(Pattern{
+		Name:  "generic_continue",
+		Regex: regexp.MustCompile(`(?i)continue\?\s*\[Y/n\]\s*$`),
+		Type:  PromptTypeConfirmation,
+	})
+	d.AddPattern(Pattern{
+		Name:      "app_token",
+		Regex:     regexp.MustCompile(`(?i)do you want to continue\?\s*\[Y/n\]\s*$`),
+		Type:      PromptTypePassword,
+		MaskInput: true,
+		Priority:  10,
+	})
+
+	det := d.Detect("Do you want to continue? [Y/n] ")
+	if det == nil {
+		t.Fatal("expected detection, got nil")
+	}
+	if det.Pattern.Name != "app_token" || !det.Pattern.MaskInput {
+		t.Errorf("matched %q, want highest-priority app_token", det.Pattern.Name)
+	}
+
+	all := d.DetectAll("Do you want to continue? [Y/n] ")
+	var names []string
+	for _, a := range all {
+		names = append(names, a.Pattern.Name)
+	}
+	if got := strings.Join(names, ","); !strings.HasPrefix(got, "app_token,generic_continue,apt_confirmation") {
+		t.Errorf("DetectAll order = %s, want app_token, generic_continue, then built-ins", got)
+	}
+}
+
+func TestDetect_NegativePriorityYieldsToBuiltins(t *testing.T) {
+	d := NewDetector()
+	if err := d.AddPatternFromConfig("fallback", `(?i)continue\?\s*(\[Y/n\])?\s*$`, "text", false, -1); err != nil {
+		t.Fatalf("AddPatternFromConfig error: %v", err)
+	}
+
+	det := d.Detect("Do you want to continue? [Y/n] ")
+	if det == nil || det.Pattern.Name != "apt_confirmation" {
+		t.Errorf("matched %v, want built-in apt_confirmation before negative-priority custom", det)
+	}
+
+	det = d.Detect("Shall we continue? ")
+	if det == nil || det.Pattern.Name != "fallback" {
+		t.Errorf("matched %v, want fallback when no built-in matches", det)
+	}
+}
+
+func TestPatterns_EvaluationOrder(t *testing.T) {
+	d := NewDetector()
+	if err := d.AddPatternFromConfig("vault_token", `Vault token:\s*$`, "password", true, 5); err != nil {
+		t.Fatalf("AddPatternFromConfig error: %v", err)
+	}
+	if err := d.AddPatternFromConfig("fallback", `\?\s*$`, "text", false, -1); err != nil {
+		t.Fatalf("AddPatternFromConfig error: %v", err)
+	}
+
+	entries := d.Patterns()
+	if len(entries) != len(DefaultPatterns())+2 {
+		t.Fatalf("got %d patterns, want the built-ins and 2 custom", len(entries))
+	}
+	first, last := entries[0], entries[len(entries)-1]
+	if first.Name != "vault_token" || !first.Custom || !first.MaskInput || first.Type != PromptTypePassword {
+		t.Errorf("first = %+v, want the high-priority custom pattern", first)
+	}
+	if last.Name != "fallback" || !last.Custom {
+		t.Errorf("last = %+v, want the negative-priority custom pattern", last)
+	}
+	if entries[1].Name != DefaultPatterns()[0].Name || entries[1].Custom {
+		t.Errorf("entries[1] = %+v, want the first built-in", entries[1])
+	}
+
+	// The detector tries them in the listed order.
+	for i, e := range entries {
+		if d.ordered[i].Name != e.Name {
+			t.Fatalf("entry %d = %s, detector tries %s", i, e.Name, d.ordered[i].Name)
+		}
+	}
+}
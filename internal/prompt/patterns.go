@@ -21,6 +21,9 @@ type Pattern struct {
 	Type              PromptType
 	MaskInput         bool
 	SuggestedResponse string
+	// Priority orders evaluation: higher values are tried first. Built-in
+	// patterns use 0; on a tie custom patterns are tried before built-ins.
+	Priority int
 }
 
 // DefaultPatterns returns the built-in prompt patterns.
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -38,6 +39,14 @@ type PTYOptions struct {
 	Dir   string   // Initial working directory
 	Env   []string // Additional environment variables
 	NoRC  bool     // Don't source rc files (--norc for bash, --no-rcs for zsh)
+
+	// Fallbacks are shells (paths or names looked up in PATH) tried in
+	// order when Shell fails to start, e.g. because $SHELL is invalid.
+	Fallbacks []string
+
+	// Command, if set, is run with /bin/sh -c instead of Shell; its stdio is
+	// the PTY (e.g. "docker exec -it web bash").
+	Command string
 }
 
 // DefaultOptions returns default PTY options.
@@ -112,10 +121,29 @@ func NewLocalPTY(opts PTYOptions) (*LocalPTY, error) {
 		opts.Cols = 80
 	}
 
-	// Build shell command with appropriate flags
-	args := noRCFlags(opts.Shell, opts.NoRC)
-	cmd := exec.Command(opts.Shell, args...)
+	if opts.Command != "" {
+		return startPTY(opts, exec.Command("/bin/sh", "-c", opts.Command))
+	}
+
+	// Try the shell, then each fallback, until one starts.
+	var errs []string
+	tried := make(map[string]bool)
+	for _, shell := range append([]string{opts.Shell}, opts.Fallbacks...) {
+		if shell == "" || tried[shell] {
+			continue
+		}
+		tried[shell] = true
+		p, err := startPTY(opts, exec.Command(shell, noRCFlags(shell, opts.NoRC)...))
+		if err == nil {
+			return p, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", shell, err))
+	}
+	return nil, fmt.Errorf("no shell could be started: %s", strings.Join(errs, "; "))
+}
 
+// startPTY starts cmd on a new PTY.
+func startPTY(opts PTYOptions, cmd *exec.Cmd) (*LocalPTY, error) {
 	// Set working directory if specified
 	if opts.Dir != "" {
 		cmd.Dir = opts.Dir
@@ -140,7 +168,7 @@ func NewLocalPTY(opts PTYOptions) (*LocalPTY, error) {
 	return &LocalPTY{
 		cmd:   cmd,
 		pty:   ptmx,
-		shell: opts.Shell,
+		shell: cmd.Path,
 	}, nil
 }
 
@@ -217,6 +217,37 @@ func TestLocalPTY_NewAndClose(t *testing.T) {
 	}
 }
 
+func TestLocalPTY_ShellFallback(t *testing.T) {
+	p, err := NewLocalPTY(PTYOptions{
+		Shell:     "/nonexistent/shell",
+		Fallbacks: []string{"/nonexistent/other", "sh"},
+		NoRC:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewLocalPTY: %v", err)
+	}
+	defer p.Close() //nolint:errcheck
+
+	if !strings.HasSuffix(p.Shell(), "/sh") {
+		t.Errorf("Shell() = %q, want the resolved path of the sh fallback", p.Shell())
+	}
+}
+
+func TestLocalPTY_NoShellStarts(t *testing.T) {
+	_, err := NewLocalPTY(PTYOptions{
+		Shell:     "/nonexistent/shell",
+		Fallbacks: []string{"/nonexistent/other"},
+	})
+	if err == nil {
+		t.Fatal("NewLocalPTY succeeded without a working shell")
+	}
+	for _, want := range []string{"/nonexistent/shell", "/nonexistent/other"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to name %s", err, want)
+		}
+	}
+}
+
 func TestLocalPTY_DefaultsApplied(t *testing.T) {
 	// Pass zero-value options so all defaults kick in.
 	p, err := NewLocalPTY(PTYOptions{NoRC: true})
@@ -51,12 +51,29 @@ func NewManager(basePath string, enabled bool, opts ...ManagerOption) *Manager {
 	return m
 }
 
-// StartRecording starts recording for a session.
+// Reconfigure changes the recording directory and whether new sessions are
+// recorded automatically. Recordings in progress continue.
+func (m *Manager) Reconfigure(basePath string, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.basePath = basePath
+	m.enabled = enabled
+}
+
+// StartRecording starts recording for a session if automatic recording is
+// enabled.
 func (m *Manager) StartRecording(sessionID string, width, height int) error {
-	if !m.enabled {
+	if !m.IsEnabled() {
 		return nil
 	}
+	_, err := m.Start(sessionID, width, height)
+	return err
+}
 
+// Start records a session whether or not automatic recording is enabled,
+// replacing any recording already running for it. It returns the path of
+// the new recording.
+func (m *Manager) Start(sessionID string, width, height int) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -67,19 +84,23 @@ func (m *Manager) StartRecording(sessionID string, width, height int) error {
 
 	recorder, err := NewRecorder(m.basePath, sessionID, width, height, m.fs, m.clock)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	m.recorders[sessionID] = recorder
-	return nil
+	return recorder.Path(), nil
+}
+
+// IsRecording reports whether a session is being recorded.
+func (m *Manager) IsRecording(sessionID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.recorders[sessionID]
+	return ok
 }
 
 // RecordOutput records output for a session.
 func (m *Manager) RecordOutput(sessionID, data string) {
-	if !m.enabled {
-		return
-	}
-
 	m.mu.RLock()
 	recorder, ok := m.recorders[sessionID]
 	m.mu.RUnlock()
@@ -91,10 +112,6 @@ func (m *Manager) RecordOutput(sessionID, data string) {
 
 // RecordInput records input for a session.
 func (m *Manager) RecordInput(sessionID, data string, masked bool) {
-	if !m.enabled {
-		return
-	}
-
 	m.mu.RLock()
 	recorder, ok := m.recorders[sessionID]
 	m.mu.RUnlock()
@@ -110,10 +127,6 @@ func (m *Manager) RecordInput(sessionID, data string, masked bool) {
 
 // StopRecording stops recording for a session.
 func (m *Manager) StopRecording(sessionID string) error {
-	if !m.enabled {
-		return nil
-	}
-
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -147,7 +160,9 @@ func (m *Manager) CloseAll() {
 	}
 }
 
-// IsEnabled returns whether recording is enabled.
+// IsEnabled returns whether new sessions are recorded automatically.
 func (m *Manager) IsEnabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.enabled
 }
@@ -666,6 +666,57 @@ func TestManagerStartRecording_ReplacesExisting(t *testing.T) {
 	}
 }
 
+func TestManagerStart_Disabled(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := NewManager(tmpDir, false)
+
+	path, err := m.Start("sess_manual", 80, 24)
+	if err != nil {
+		t.Fatalf("Start() on disabled manager error = %v", err)
+	}
+	if !m.IsRecording("sess_manual") {
+		t.Error("IsRecording() = false after Start()")
+	}
+	if got := m.GetRecordingPath("sess_manual"); got != path {
+		t.Errorf("GetRecordingPath() = %q, want %q", got, path)
+	}
+
+	m.RecordInput("sess_manual", "ls\n", false)
+	if err := m.StopRecording("sess_manual"); err != nil {
+		t.Fatalf("StopRecording() error = %v", err)
+	}
+	if m.IsRecording("sess_manual") {
+		t.Error("IsRecording() = true after StopRecording()")
+	}
+	if events := readEvents(t, path); len(events) != 1 {
+		t.Errorf("got %d events, want 1", len(events))
+	}
+}
+
+func TestManagerReconfigure(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	m := NewManager(first, false)
+
+	if _, err := m.Start("sess_a", 80, 24); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	m.Reconfigure(second, true)
+	if !m.IsEnabled() {
+		t.Error("IsEnabled() = false after Reconfigure(..., true)")
+	}
+	if !m.IsRecording("sess_a") {
+		t.Error("Reconfigure() stopped a running recording")
+	}
+
+	if err := m.StartRecording("sess_b", 80, 24); err != nil {
+		t.Fatalf("StartRecording() error = %v", err)
+	}
+	if dir := filepath.Dir(m.GetRecordingPath("sess_b")); dir != second {
+		t.Errorf("new recording in %q, want %q", dir, second)
+	}
+	m.CloseAll()
+}
+
 // ---------- Manager RecordOutput tests ----------
 
 func TestManagerRecordOutput(t *testing.T) {
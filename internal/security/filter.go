@@ -77,6 +77,28 @@ func (cf *CommandFilter) HasAllowlist() bool {
 	return len(cf.allowlist) > 0
 }
 
+// Blocklist returns the blocklist patterns as written.
+func (cf *CommandFilter) Blocklist() []string {
+	cf.mu.RLock()
+	defer cf.mu.RUnlock()
+	return patternStrings(cf.blocklist)
+}
+
+// Allowlist returns the allowlist patterns as written.
+func (cf *CommandFilter) Allowlist() []string {
+	cf.mu.RLock()
+	defer cf.mu.RUnlock()
+	return patternStrings(cf.allowlist)
+}
+
+func patternStrings(res []*regexp.Regexp) []string {
+	out := make([]string, len(res))
+	for i, re := range res {
+		out[i] = re.String()
+	}
+	return out
+}
+
 // DefaultBlocklist returns a set of commonly dangerous patterns.
 func DefaultBlocklist() []string {
 	return []string{
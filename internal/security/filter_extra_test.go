@@ -1,6 +1,7 @@
 package security
 
 import (
+	"slices"
 	"testing"
 )
 
@@ -138,3 +139,17 @@ func TestCommandFilter_AllowlistNotInList(t *testing.T) {
 		t.Error("expected non-empty reason when not in allowlist")
 	}
 }
+
+func TestCommandFilter_Patterns(t *testing.T) {
+	cf, err := NewCommandFilter([]string{`rm\s+-rf`}, []string{`^ls`, `^cat`})
+	if err != nil {
+		t.Fatalf("NewCommandFilter() error = %v", err)
+	}
+
+	if got := cf.Blocklist(); !slices.Equal(got, []string{`rm\s+-rf`}) {
+		t.Errorf("Blocklist() = %q", got)
+	}
+	if got := cf.Allowlist(); !slices.Equal(got, []string{`^ls`, `^cat`}) {
+		t.Errorf("Allowlist() = %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// mutatingCommands are programs that modify files, packages, services or
+// processes whatever their arguments.
+var mutatingCommands = map[string]bool{
+	"rm": true, "rmdir": true, "mv": true, "cp": true, "touch": true,
+	"mkdir": true, "ln": true, "chmod": true, "chown": true, "chgrp": true,
+	"dd": true, "truncate": true, "tee": true, "install": true, "shred": true,
+	"unlink": true, "rsync": true, "scp": true, "patch": true,
+	"kill": true, "pkill": true, "killall": true,
+	"reboot": true, "shutdown": true, "halt": true, "poweroff": true,
+	"mount": true, "umount": true, "fdisk": true, "parted": true,
+	"useradd": true, "userdel": true, "usermod": true, "groupadd": true,
+	"groupdel": true, "passwd": true, "chpasswd": true, "crontab": true,
+	"dpkg": true, "rpm": true, "iptables": true, "sysctl": true,
+	"wget": true,
+}
+
+// mutatingSubcommands are programs that only mutate with certain subcommands.
+var mutatingSubcommands = map[string][]string{
+	"systemctl": {"start", "stop", "restart", "reload", "enable", "disable", "mask", "unmask", "kill", "daemon-reload"},
+	"service":   {"start", "stop", "restart", "reload"},
+	"apt":       {"install", "remove", "purge", "upgrade", "dist-upgrade", "autoremove", "update"},
+	"apt-get":   {"install", "remove", "purge", "upgrade", "dist-upgrade", "autoremove", "update"},
+	"yum":       {"install", "remove", "erase", "update", "upgrade"},
+	"dnf":       {"install", "remove", "erase", "update", "upgrade"},
+	"apk":       {"add", "del", "upgrade", "update"},
+	"brew":      {"install", "uninstall", "upgrade", "update"},
+	"pip":       {"install", "uninstall"},
+	"pip3":      {"install", "uninstall"},
+	"npm":       {"install", "i", "uninstall", "update", "publish", "ci"},
+	"git":       {"commit", "push", "reset", "checkout", "switch", "merge", "rebase", "clean", "pull", "stash", "rm", "mv", "tag", "branch", "apply", "am", "cherry-pick", "revert", "init", "clone"},
+	"docker":    {"run", "rm", "rmi", "stop", "kill", "start", "restart", "exec", "build", "pull", "push", "create", "prune", "compose"},
+	"kubectl":   {"apply", "create", "delete", "patch", "edit", "replace", "scale", "rollout", "label", "annotate", "cordon", "drain", "taint", "exec", "cp"},
+}
+
+// mutatingFlags mark otherwise read-only tools that edit files in place.
+var mutatingFlags = map[string][]string{
+	"sed":  {"-i", "--in-place"},
+	"perl": {"-i", "-pi"},
+	"curl": {"-o", "-O", "--output", "--remote-name"},
+}
+
+// writeRedirect matches output redirections to files. Redirects to
+// /dev/null and file-descriptor duplication (2>&1) are not writes.
+var writeRedirect = regexp.MustCompile(`[0-9]?>>?\s*([^&\s>][^\s;|&]*)`)
+
+// commandSeparators split a command line into simple commands.
+var commandSeparators = regexp.MustCompile(`&&|\|\||[;|&\n]|\$\(|` + "`")
+
+// commandPrefixes are wrappers that run the following word as the command,
+// mapped to their options that take a separate value.
+var commandPrefixes = map[string][]string{
+	"sudo":    {"-u", "-g", "-C", "-p"},
+	"env":     {"-u"},
+	"nice":    {"-n"},
+	"timeout": {"-s", "-k"},
+	"xargs":   {"-n", "-I", "-P", "-d"},
+	"nohup":   nil,
+	"time":    nil,
+	"exec":    nil,
+	"command": nil,
+}
+
+// IsMutatingCommand reports whether command looks like it modifies the
+// system, with a short reason. It is a heuristic for read-only mode, not a
+// sandbox: it errs on the side of flagging common write operations.
+func IsMutatingCommand(command string) (bool, string) {
+	for _, m := range writeRedirect.FindAllStringSubmatch(command, -1) {
+		if target := strings.Trim(m[1], `"'`); target != "/dev/null" && !strings.HasPrefix(target, "/dev/std") {
+			return true, fmt.Sprintf("output redirection to %s", target)
+		}
+	}
+
+	for _, segment := range commandSeparators.Split(command, -1) {
+		if mutating, reason := isMutatingSimpleCommand(strings.Fields(segment)); mutating {
+			return true, reason
+		}
+	}
+	return false, ""
+}
+
+// isMutatingSimpleCommand checks one command's words.
+func isMutatingSimpleCommand(words []string) (bool, string) {
+	// Skip leading variable assignments and wrapper commands.
+	for len(words) > 0 {
+		w := words[0]
+		if strings.Contains(w, "=") && !strings.HasPrefix(w, "=") {
+			words = words[1:]
+			continue
+		}
+		if valueOpts, ok := commandPrefixes[path.Base(w)]; ok {
+			words = skipPrefixArgs(words[1:], valueOpts)
+			if path.Base(w) == "timeout" && len(words) > 0 {
+				words = words[1:] // duration
+			}
+			continue
+		}
+		break
+	}
+	if len(words) == 0 {
+		return false, ""
+	}
+
+	name := path.Base(strings.Trim(words[0], `"'()`))
+	args := words[1:]
+
+	if mutatingCommands[name] {
+		return true, fmt.Sprintf("%s modifies the system", name)
+	}
+	if subs, ok := mutatingSubcommands[name]; ok {
+		if sub := firstNonFlag(args); sub != "" && contains(subs, sub) {
+			return true, fmt.Sprintf("%s %s modifies the system", name, sub)
+		}
+	}
+	if flags, ok := mutatingFlags[name]; ok {
+		for _, a := range args {
+			for _, f := range flags {
+				// -i takes an optional attached suffix (sed -i.bak, perl -pi -e).
+				if a == f || strings.HasPrefix(f, "-i") && strings.HasPrefix(a, f) {
+					return true, fmt.Sprintf("%s %s writes files", name, a)
+				}
+			}
+		}
+	}
+	return false, ""
+}
+
+// skipPrefixArgs drops a wrapper's own options (e.g. "sudo -u root").
+func skipPrefixArgs(words, valueOpts []string) []string {
+	for len(words) > 0 && strings.HasPrefix(words[0], "-") {
+		opt := words[0]
+		words = words[1:]
+		if contains(valueOpts, opt) && len(words) > 0 {
+			words = words[1:]
+		}
+	}
+	return words
+}
+
+func firstNonFlag(args []string) string {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
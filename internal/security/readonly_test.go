@@ -0,0 +1,48 @@
+package security
+
+import "testing"
+
+func TestIsMutatingCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		mutating bool
+	}{
+		{"ls -la /etc", false},
+		{"cat /etc/hosts | grep localhost", false},
+		{"grep -r TODO . 2>/dev/null", false},
+		{"find / -name '*.log' 2>&1 | head", false},
+		{"systemctl status nginx", false},
+		{"git log --oneline -5", false},
+		{"docker ps", false},
+		{"sed -n '1,10p' file.txt", false},
+		{"FOO=bar env", false},
+		{"rm -rf /tmp/x", true},
+		{"/bin/rm file", true},
+		{"sudo -u root mv a b", true},
+		{"LANG=C timeout 5 touch x", true},
+		{"ls && mkdir out", true},
+		{"echo $(chmod 600 key)", true},
+		{"echo hi > out.txt", true},
+		{"echo hi >> /var/log/x", true},
+		{"sed -i 's/a/b/' file", true},
+		{"sed -i.bak 's/a/b/' file", true},
+		{"perl -pi -e 's/a/b/' file", true},
+		{"systemctl restart nginx", true},
+		{"apt-get install -y curl", true},
+		{"git push origin main", true},
+		{"kubectl delete pod x", true},
+		{"curl -o out.bin https://example.com", true},
+		{"find . -name x | xargs rm", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, reason := IsMutatingCommand(tt.command)
+			if got != tt.mutating {
+				t.Errorf("IsMutatingCommand(%q) = %v (%q), want %v", tt.command, got, reason, tt.mutating)
+			}
+			if got && reason == "" {
+				t.Error("expected a reason for a mutating command")
+			}
+		})
+	}
+}
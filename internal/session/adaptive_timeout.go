@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// adaptiveTimeout is the timeout of a command run with
+// session.adaptive_timeout: it expires idle after the last output, but never
+// later than max after the command started. Time comes from the session
+// clock, so tests drive it deterministically.
+type adaptiveTimeout struct {
+	idle     time.Duration
+	max      time.Duration
+	deadline time.Time
+	limit    time.Time
+}
+
+// newAdaptiveTimeout returns the adaptive timeout for a command with the
+// given timeout, or nil when adaptive timeouts are disabled.
+func (s *Session) newAdaptiveTimeout(timeout time.Duration) *adaptiveTimeout {
+	if s.config == nil || !s.config.Session.AdaptiveTimeout {
+		return nil
+	}
+	maxTimeout := max(s.config.Session.AdaptiveTimeoutMax, timeout)
+	now := s.clock.Now()
+	return &adaptiveTimeout{
+		idle:     timeout,
+		max:      maxTimeout,
+		deadline: now.Add(timeout),
+		limit:    now.Add(maxTimeout),
+	}
+}
+
+// outputArrived starts the idle timeout over from now.
+func (a *adaptiveTimeout) outputArrived(now time.Time) {
+	if a == nil {
+		return
+	}
+	a.deadline = now.Add(a.idle)
+	if a.deadline.After(a.limit) {
+		a.deadline = a.limit
+	}
+}
+
+// expired reports whether the timeout has run out at now.
+func (a *adaptiveTimeout) expired(now time.Time) bool {
+	return a != nil && !now.Before(a.deadline)
+}
+
+// hint explains why the command timed out.
+func (a *adaptiveTimeout) hint() string {
+	if a.deadline.Equal(a.limit) {
+		return fmt.Sprintf("the command was still printing output when it reached session.adaptive_timeout_max (%s)", a.max)
+	}
+	return fmt.Sprintf("the command printed nothing for %s (timeout_ms)", a.idle)
+}
+
+// commandContext arms the timeout of the command about to be read. With
+// adaptive timeouts the returned context only enforces the overall limit;
+// the idle timeout is checked against the session clock in the read loop.
+// Must be called with s.mu held.
+func (s *Session) commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	s.adaptive = s.newAdaptiveTimeout(timeout)
+	if s.adaptive == nil {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithTimeout(context.Background(), s.adaptive.max)
+}
+
+// commandTimedOut reports whether the command being read has run out of
+// time, by its context or its adaptive timeout. Must be called with s.mu
+// held.
+func (s *Session) commandTimedOut(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return s.adaptive.expired(s.clock.Now())
+	}
+}
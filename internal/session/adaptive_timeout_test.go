@@ -0,0 +1,159 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestAdaptiveTimeout_OutputExtends(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := config.DefaultConfig()
+	cfg.Session.AdaptiveTimeout = true
+	cfg.Session.AdaptiveTimeoutMax = 10 * time.Second
+	sess := NewSession("sess_adaptive", "local", WithConfig(cfg), WithSessionClock(fakeclock.New(start)))
+
+	a := sess.newAdaptiveTimeout(3 * time.Second)
+	if a.expired(start.Add(2 * time.Second)) {
+		t.Fatal("expired before the timeout")
+	}
+	if !a.expired(start.Add(3 * time.Second)) {
+		t.Fatal("not expired after the timeout without output")
+	}
+
+	a.outputArrived(start.Add(2 * time.Second))
+	if a.expired(start.Add(4 * time.Second)) {
+		t.Error("output did not restart the timeout")
+	}
+	if !a.expired(start.Add(5 * time.Second)) {
+		t.Error("not expired 3s after the last output")
+	}
+
+	a.outputArrived(start.Add(9 * time.Second))
+	if !a.expired(start.Add(10 * time.Second)) {
+		t.Error("output extended the timeout past the max")
+	}
+	if !strings.Contains(a.hint(), "adaptive_timeout_max (10s)") {
+		t.Errorf("hint = %q", a.hint())
+	}
+}
+
+func TestAdaptiveTimeout_MaxNotBelowTimeout(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Session.AdaptiveTimeout = true
+	cfg.Session.AdaptiveTimeoutMax = time.Minute
+	sess := NewSession("sess_adaptive", "local", WithConfig(cfg), WithSessionClock(fakeclock.New(time.Now())))
+
+	if a := sess.newAdaptiveTimeout(5 * time.Minute); a.max != 5*time.Minute {
+		t.Errorf("max = %v, want the command's own longer timeout", a.max)
+	}
+}
+
+func TestAdaptiveTimeout_Disabled(t *testing.T) {
+	sess := NewSession("sess_adaptive", "local", WithConfig(config.DefaultConfig()))
+	a := sess.newAdaptiveTimeout(time.Second)
+	if a != nil {
+		t.Fatal("adaptive timeout enabled by default")
+	}
+	a.outputArrived(time.Now())
+	if a.expired(time.Now().Add(time.Hour)) {
+		t.Error("nil adaptive timeout expired")
+	}
+}
+
+// newAdaptiveSession returns an initialized session with adaptive timeouts
+// up to maxTimeout, whose clock moves 100ms each time it is read.
+func newAdaptiveSession(t *testing.T, maxTimeout time.Duration) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	cfg := config.DefaultConfig()
+	cfg.Session.AdaptiveTimeout = true
+	cfg.Session.AdaptiveTimeoutMax = maxTimeout
+
+	sess := NewSession("sess_adaptive", "local",
+		WithPTY(pty),
+		WithSessionClock(&steppingClock{Clock: fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)), step: 100 * time.Millisecond}),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestExec_AdaptiveTimeoutSteadyOutput(t *testing.T) {
+	sess, pty := newAdaptiveSession(t, time.Hour)
+	clk := sess.clock.(*steppingClock)
+	start := clk.Clock.Now()
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\n")
+	for i := 0; i < 60; i++ {
+		pty.AddResponse(fmt.Sprintf("compiling %d\n", i))
+	}
+	pty.AddResponse(endMarkerPrefix + "01020304" + markerSuffix + "0\n")
+
+	result, err := sess.Exec("make", 2000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "completed" || !strings.Contains(result.Stdout, "compiling 59") {
+		t.Fatalf("result = %+v, want the build to complete", result)
+	}
+	if elapsed := clk.Clock.Now().Sub(start); elapsed <= 2*time.Second {
+		t.Errorf("command took %v, want longer than its 2s timeout for the test to mean anything", elapsed)
+	}
+	if sess.adaptive != nil {
+		t.Error("adaptive timeout not cleared after Exec")
+	}
+}
+
+func TestExec_AdaptiveTimeoutSilence(t *testing.T) {
+	sess, pty := newAdaptiveSession(t, time.Hour)
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\n")
+	pty.AddResponse("waiting for lock\n")
+
+	result, err := sess.Exec("./hang", 2000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "timeout" || !strings.Contains(result.Stdout, "waiting for lock") {
+		t.Fatalf("result = %+v, want a timeout with the output so far", result)
+	}
+	if !strings.Contains(result.Hint, "printed nothing for 2s") {
+		t.Errorf("Hint = %q", result.Hint)
+	}
+	if !pty.WasInterrupted() {
+		t.Error("expected the command to be interrupted")
+	}
+}
+
+func TestExec_AdaptiveTimeoutMax(t *testing.T) {
+	sess, pty := newAdaptiveSession(t, 5*time.Second)
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\n")
+	for i := 0; i < 500; i++ {
+		pty.AddResponse(fmt.Sprintf("tick %d\n", i))
+	}
+
+	result, err := sess.Exec("tail -f app.log", 2000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "timeout" || !strings.Contains(result.Stdout, "tick 0") {
+		t.Fatalf("result = %+v, want a timeout at the max", result)
+	}
+	if strings.Contains(result.Stdout, "tick 499") {
+		t.Error("all output was read; the max did not stop the command")
+	}
+	if !strings.Contains(result.Hint, "adaptive_timeout_max (5s)") {
+		t.Errorf("Hint = %q", result.Hint)
+	}
+}
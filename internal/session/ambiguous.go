@@ -0,0 +1,69 @@
+package session
+
+import (
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+)
+
+const (
+	hintAssumedPrompt = "No output for a while and no known prompt matched; assuming the command is waiting for input. " +
+		"Send input with shell_provide_input or cancel with shell_interrupt."
+	hintIndeterminate = "No output for a while and no known prompt matched; the command may still be running or waiting for input. " +
+		"Send input with shell_provide_input, or shell_interrupt to cancel."
+)
+
+// onAmbiguous returns how a stalled command with no recognized prompt is
+// reported (prompt_detection.on_ambiguous).
+func (s *Session) onAmbiguous() string {
+	if s.config == nil || s.config.PromptDetection.OnAmbiguous == "" {
+		return config.AmbiguousAssumeRunning
+	}
+	return s.config.PromptDetection.OnAmbiguous
+}
+
+// resolveAmbiguousStall applies on_ambiguous to a command whose output has
+// stalled without completing or matching a prompt. It returns nil to keep
+// waiting; otherwise the session is left awaiting input and result (built
+// from the output so far) is returned with the matching status.
+func (s *Session) resolveAmbiguousStall(result *ExecResult, output string) *ExecResult {
+	var status, hint string
+	switch s.onAmbiguous() {
+	case config.AmbiguousAssumePrompt:
+		status, hint = "awaiting_input", hintAssumedPrompt
+	case config.AmbiguousReturnPartial:
+		status, hint = "indeterminate", hintIndeterminate
+	default:
+		return nil
+	}
+
+	slog.Debug("ambiguous output stall",
+		slog.String("session_id", s.ID),
+		slog.String("status", status),
+	)
+
+	s.State = StateAwaitingInput
+	s.pendingPrompt = nil
+	result.Status = status
+	result.PromptType = "interactive"
+	result.ContextBuffer = StripANSI(output)
+	result.Hint = hint
+	return result
+}
+
+// checkAmbiguousStall builds the on_ambiguous result for marker-based reads.
+func (s *Session) checkAmbiguousStall(ctx *execContext) (*ExecResult, bool) {
+	output := s.outputBuffer.String()
+	asyncOutput, stdout := s.parseMarkedOutput(output, ctx.startMarker, ctx.endMarker, ctx.command)
+	result := s.resolveAmbiguousStall(&ExecResult{
+		Stdout:      stdout,
+		AsyncOutput: asyncOutput,
+		CommandID:   ctx.commandID,
+	}, output)
+	return result, result != nil
+}
+
+// checkLegacyAmbiguousStall builds the on_ambiguous result for legacy reads.
+func (s *Session) checkLegacyAmbiguousStall(output, command string) *ExecResult {
+	return s.resolveAmbiguousStall(&ExecResult{Stdout: s.cleanOutput(output, command)}, output)
+}
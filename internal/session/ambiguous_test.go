@@ -0,0 +1,125 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func newAmbiguousTestSession(t *testing.T, mode string) *Session {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.OnAmbiguous = mode
+
+	sess := NewSession("test_ambiguous", "local",
+		WithPTY(fakepty.New()),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.State = StateRunning
+	return sess
+}
+
+func stalledExecContext(sess *Session) *execContext {
+	cmdID := "aabb1122"
+	startM := startMarkerPrefix + cmdID + markerSuffix
+	endM := endMarkerPrefix + cmdID + markerSuffix
+	sess.outputBuffer.WriteString(startM + "\nConfigure widget now? ")
+	return newExecContext(cmdID, startM, endM, "./setup.sh")
+}
+
+func TestHandleReadError_AmbiguousModes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantStatus string // "" = keep waiting
+	}{
+		{config.AmbiguousAssumeRunning, ""},
+		{"", ""},
+		{config.AmbiguousAssumePrompt, "awaiting_input"},
+		{config.AmbiguousReturnPartial, "indeterminate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			sess := newAmbiguousTestSession(t, tt.mode)
+			execCtx := stalledExecContext(sess)
+
+			result, stall, cont := sess.handleReadError(&timeoutError{}, execCtx, 14, 15)
+
+			if tt.wantStatus == "" {
+				if result != nil || !cont {
+					t.Fatalf("result = %+v, cont = %v; want to keep waiting", result, cont)
+				}
+				if stall != 7 {
+					t.Errorf("stallCount = %d, want 7 (partially reset)", stall)
+				}
+				if sess.State != StateRunning {
+					t.Errorf("State = %q, want running", sess.State)
+				}
+				return
+			}
+
+			if result == nil {
+				t.Fatal("expected a result at the stall threshold")
+			}
+			if result.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", result.Status, tt.wantStatus)
+			}
+			if result.Stdout != "Configure widget now?" {
+				t.Errorf("Stdout = %q, want the partial output", result.Stdout)
+			}
+			if result.CommandID != "aabb1122" || result.PromptType != "interactive" || result.Hint == "" {
+				t.Errorf("result = %+v, want command ID, interactive prompt type and a hint", result)
+			}
+			if sess.State != StateAwaitingInput {
+				t.Errorf("State = %q, want awaiting_input so input can be provided", sess.State)
+			}
+		})
+	}
+}
+
+func TestHandleReadError_AmbiguousBeforeThresholdKeepsWaiting(t *testing.T) {
+	sess := newAmbiguousTestSession(t, config.AmbiguousReturnPartial)
+	execCtx := stalledExecContext(sess)
+
+	result, stall, cont := sess.handleReadError(&timeoutError{}, execCtx, 3, 15)
+	if result != nil || !cont || stall != 4 {
+		t.Errorf("result = %+v, stall = %d, cont = %v; want to keep waiting", result, stall, cont)
+	}
+}
+
+func TestHandleLegacyReadError_AmbiguousReturnPartial(t *testing.T) {
+	sess := newAmbiguousTestSession(t, config.AmbiguousReturnPartial)
+	sess.outputBuffer.WriteString("Continue? ")
+
+	result, _, cont := sess.handleLegacyReadError(&timeoutError{}, "", 14, 15)
+	if result == nil || cont {
+		t.Fatalf("result = %+v, cont = %v; want indeterminate result", result, cont)
+	}
+	if result.Status != "indeterminate" {
+		t.Errorf("Status = %q, want indeterminate", result.Status)
+	}
+	if sess.State != StateAwaitingInput {
+		t.Errorf("State = %q, want awaiting_input", sess.State)
+	}
+}
+
+func TestArmPromptTimeout_Indeterminate(t *testing.T) {
+	sess := newAmbiguousTestSession(t, config.AmbiguousReturnPartial)
+	sess.State = StateAwaitingInput
+
+	result := &ExecResult{Status: "indeterminate"}
+	sess.mu.Lock()
+	sess.armPromptTimeout(result)
+	sess.disarmPromptTimeout()
+	sess.mu.Unlock()
+
+	if result.InputTimeoutSeconds != 600 {
+		t.Errorf("InputTimeoutSeconds = %d, want 600", result.InputTimeoutSeconds)
+	}
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+)
+
+// autoCapture returns what a new session captures from its shell (see
+// config.SessionConfig.AutoCaptureOnConnect).
+func (s *Session) autoCapture() config.AutoCaptureConfig {
+	if s.config == nil {
+		return config.DefaultConfig().Session.AutoCaptureOnConnect
+	}
+	return s.config.Session.AutoCaptureOnConnect
+}
+
+// captureOnConnect runs the captures a new shell is configured for, once
+// its prompt is set. It runs before the readiness probe, which drains any
+// output they leave behind. A container's env and cwd are captured by
+// initializeContainer instead. SSH sessions have already captured env
+// together with the PTY name. Caller must hold s.mu.
+func (s *Session) captureOnConnect() {
+	ac := s.autoCapture()
+	if s.Container == "" && (s.Mode == "ssh" && ac.Env || s.Mode != "ssh" && ac.LocalEnv) {
+		if s.Mode != "ssh" {
+			s.captureEnvLocked()
+		}
+		if len(s.EnvVars) > 0 {
+			s.noteCaptured("env")
+		}
+	}
+	if ac.Aliases {
+		s.captureAliasesLocked()
+		s.noteCaptured("aliases")
+	}
+	if ac.Cwd && s.Container == "" {
+		s.updateCwd()
+		if strings.HasPrefix(s.Cwd, "/") {
+			s.noteCaptured("cwd")
+		}
+	}
+	slog.Debug("captured on connect",
+		slog.String("session_id", s.ID),
+		slog.Any("captured", s.Captured),
+	)
+}
+
+// noteCaptured records that Initialize captured item.
+func (s *Session) noteCaptured(item string) {
+	if !slices.Contains(s.Captured, item) {
+		s.Captured = append(s.Captured, item)
+	}
+}
+
+// capturePTYName reads the PTY name from SSH_TTY when env is not captured,
+// since the control plane needs it either way.
+func (s *Session) capturePTYName() {
+	s.pty.WriteString("echo $SSH_TTY\n")
+	s.clock.Sleep(100 * time.Millisecond)
+
+	buf := make([]byte, 1024)
+	n, _ := s.readWithTimeout(buf, 200*time.Millisecond)
+	for _, line := range strings.Split(string(buf[:n]), "\n") {
+		if ptyNum := extractPTYNumber(line); ptyNum != "" {
+			s.PTYName = ptyNum
+			return
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+// answerCaptures answers the readiness probe and the env, alias and pwd
+// captures of a new shell.
+func answerCaptures(written string) string {
+	switch written {
+	case "env\n":
+		return "env\nLANG=C.UTF-8\nHOME=/home/deploy\n$ "
+	case "alias\n":
+		return "alias\nalias ll='ls -l'\n$ "
+	case "pwd\n":
+		return "pwd\n/srv/app\n$ "
+	}
+	return answerReadyProbe(written)
+}
+
+func newAutoCaptureSession(t *testing.T, ac config.AutoCaptureConfig) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New().SetResponder(answerCaptures)
+	cfg := config.DefaultConfig()
+	cfg.Session.AutoCaptureOnConnect = ac
+	sess := newReadinessSession(pty, cfg)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return sess, pty
+}
+
+func TestAutoCapture_DefaultCapturesNothingLocally(t *testing.T) {
+	pty := fakepty.New().SetResponder(answerCaptures)
+	sess := newReadinessSession(pty, config.DefaultConfig())
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	// Only SSH and container sessions capture env by default.
+	if len(sess.Captured) != 0 || len(sess.EnvVars) != 0 {
+		t.Errorf("Captured = %v, EnvVars = %v, want nothing", sess.Captured, sess.EnvVars)
+	}
+	for _, cmd := range []string{"env\n", "alias\n", "pwd\n"} {
+		if strings.Contains(pty.Written(), cmd) {
+			t.Errorf("written = %q, want no %q", pty.Written(), cmd)
+		}
+	}
+	if sess.Cwd != "/project" {
+		t.Errorf("Cwd = %q, want the server's working directory", sess.Cwd)
+	}
+}
+
+func TestAutoCapture_All(t *testing.T) {
+	sess, _ := newAutoCaptureSession(t, config.AutoCaptureConfig{LocalEnv: true, Aliases: true, Cwd: true})
+
+	if !slices.Equal(sess.Captured, []string{"env", "aliases", "cwd"}) {
+		t.Errorf("Captured = %v, want [env aliases cwd]", sess.Captured)
+	}
+	if sess.EnvVars["LANG"] != "C.UTF-8" {
+		t.Errorf("EnvVars = %v", sess.EnvVars)
+	}
+	if sess.Aliases["ll"] != "ls -l" {
+		t.Errorf("Aliases = %v", sess.Aliases)
+	}
+	if sess.Cwd != "/srv/app" {
+		t.Errorf("Cwd = %q, want /srv/app from pwd", sess.Cwd)
+	}
+}
+
+func TestAutoCapture_None(t *testing.T) {
+	sess, pty := newAutoCaptureSession(t, config.AutoCaptureConfig{})
+
+	if len(sess.Captured) != 0 || len(sess.EnvVars) != 0 {
+		t.Errorf("Captured = %v, EnvVars = %v, want nothing", sess.Captured, sess.EnvVars)
+	}
+	for _, cmd := range []string{"env\n", "alias\n", "pwd\n"} {
+		if strings.Contains(pty.Written(), cmd) {
+			t.Errorf("written = %q, want no %q", pty.Written(), cmd)
+		}
+	}
+}
+
+func TestAutoCapture_SSHWithoutEnvStillReadsPTYName(t *testing.T) {
+	pty := fakepty.New().SetResponder(func(written string) string {
+		switch written {
+		case "echo $SHELL\n":
+			return "/bin/bash\n"
+		case "echo $SSH_TTY\n":
+			return "echo $SSH_TTY\r\n/dev/pts/4\r\n$ "
+		}
+		return ""
+	}).SetReadError(io.EOF)
+	cfg := config.DefaultConfig()
+	cfg.Session.AutoCaptureOnConnect.Env = false
+	sess := &Session{ID: "sess_ssh", Mode: "ssh", pty: pty, clock: fakeclock.New(time.Now()), config: cfg}
+
+	sess.initializeSSHShell()
+	if sess.PTYName != "4" {
+		t.Errorf("PTYName = %q, want 4", sess.PTYName)
+	}
+	if strings.Contains(pty.Written(), "env\n") {
+		t.Errorf("written = %q, want no env capture", pty.Written())
+	}
+	if len(sess.Captured) != 0 {
+		t.Errorf("Captured = %v, want nothing", sess.Captured)
+	}
+}
@@ -0,0 +1,82 @@
+package session
+
+import "strings"
+
+// stripCommandEcho removes the shell's echo of a marked command (see
+// buildWrappedCommand) from raw PTY output. The echo is found by its
+// structure, not by the command text: it starts with `echo '<startMarker>'`
+// and ends with `echo '<endMarker>'` and the shell's exit status variable,
+// $? or $status (see exitStatusVar). A terminal soft-wraps a long echo
+// with line breaks or readline's " \r" at the right margin, which can put
+// part of the command, or even the start marker, at the start of a line
+// where it would pass for output. An echo that has not fully arrived yet is
+// dropped as far as it goes.
+func stripCommandEcho(output, startMarker, endMarker string) string {
+	echoStart, prefixEnd := findWrapped(output, 0, "echo '"+startMarker+"'")
+	if echoStart < 0 {
+		return output
+	}
+	_, echoEnd := findWrapped(output, prefixEnd, "echo '"+endMarker+"'")
+	if echoEnd < 0 {
+		return output[:echoStart]
+	}
+	for _, v := range []string{"$?", "$status"} {
+		if end := matchWrappedAt(output, echoEnd, v); end >= 0 {
+			return output[:echoStart] + output[end:]
+		}
+	}
+	return output[:echoStart]
+}
+
+// findWrapped finds the first occurrence of literal in output at or after
+// from, allowing soft-wrap noise between its characters. It returns the
+// start and end of the match, or -1, -1.
+func findWrapped(output string, from int, literal string) (int, int) {
+	for i := from; i < len(output); {
+		j := strings.IndexByte(output[i:], literal[0])
+		if j < 0 {
+			break
+		}
+		i += j
+		if end := matchWrappedAt(output, i, literal); end >= 0 {
+			return i, end
+		}
+		i++
+	}
+	return -1, -1
+}
+
+// matchWrappedAt matches literal at output[i:], skipping soft-wrap noise,
+// and returns the index just past the match or -1.
+func matchWrappedAt(output string, i int, literal string) int {
+	for j := 0; j < len(literal); {
+		if i >= len(output) {
+			return -1
+		}
+		if output[i] == literal[j] {
+			i++
+			j++
+			continue
+		}
+		n := softWrapLen(output, i)
+		if n == 0 {
+			return -1
+		}
+		i += n
+	}
+	return i
+}
+
+// softWrapLen returns the length of the soft-wrap noise at output[i]: a CR
+// or LF, or a space followed by one (readline's right-margin " \r").
+func softWrapLen(output string, i int) int {
+	switch output[i] {
+	case '\r', '\n':
+		return 1
+	case ' ':
+		if i+1 < len(output) && (output[i+1] == '\r' || output[i+1] == '\n') {
+			return 2
+		}
+	}
+	return 0
+}
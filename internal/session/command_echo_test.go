@@ -0,0 +1,119 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+)
+
+// softWrap breaks s every width bytes with sep, like a terminal echoing a
+// line longer than its width.
+func softWrap(s string, width int, sep string) string {
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString(sep)
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// markedEcho returns what the PTY shows for a marked command: the prompt
+// and the command's echo soft-wrapped at width, then its output.
+func markedEcho(prompt, cmdID, command, output string, width int, sep string) string {
+	s := &Session{}
+	full := strings.TrimSuffix(s.buildWrappedCommand(command, cmdID), "\n")
+	return softWrap(prompt+full, width, sep) + "\r\n" +
+		buildCommandOutput(cmdID, output, 0) + prompt
+}
+
+func TestParseMarkedOutput_SoftWrappedEcho(t *testing.T) {
+	const cmdID = "0a1b2c3d"
+	startMarker := startMarkerPrefix + cmdID + markerSuffix
+	endMarker := endMarkerPrefix + cmdID + markerSuffix
+	command := "find /var/log -name '*.gz' -mtime +30 -print | xargs ls -la"
+	sess := &Session{}
+
+	for _, sep := range []string{"\r\n", " \r", "\n"} {
+		for _, width := range []int{8, 20, 24, 33, 80} {
+			output := markedEcho("$ ", cmdID, command, "total 0", width, sep)
+			async, stdout := sess.parseMarkedOutput(output, startMarker, endMarker, command)
+			if stdout != "total 0" || async != "" {
+				t.Errorf("width %d sep %q: async = %q, stdout = %q; want no echo left", width, sep, async, stdout)
+			}
+		}
+	}
+}
+
+func TestParseMarkedOutput_WrapPutsMarkerAtLineStart(t *testing.T) {
+	// The prompt and "echo '" take 30 bytes: at width 30 the echoed start
+	// marker begins a line, where a plain search takes it for the real one.
+	const cmdID = "0a1b2c3d"
+	const prompt = "deploy@web-01:/srv/app$ "
+	startMarker := startMarkerPrefix + cmdID + markerSuffix
+	endMarker := endMarkerPrefix + cmdID + markerSuffix
+	output := markedEcho(prompt, cmdID, "uptime", "up 3 days", len(prompt+"echo '"), "\r\n")
+	if !strings.Contains(output, "\r\n"+startMarker+"'") {
+		t.Fatalf("test output does not wrap before the echoed marker:\n%q", output)
+	}
+
+	async, stdout := (&Session{}).parseMarkedOutput(output, startMarker, endMarker, "uptime")
+	if stdout != "up 3 days" {
+		t.Errorf("stdout = %q, want %q", stdout, "up 3 days")
+	}
+	if strings.Contains(async, "CMD_") || strings.Contains(async, "uptime") {
+		t.Errorf("async output holds the echoed command: %q", async)
+	}
+}
+
+func TestParseMarkedOutput_PartialWrappedEcho(t *testing.T) {
+	const cmdID = "0a1b2c3d"
+	startMarker := startMarkerPrefix + cmdID + markerSuffix
+	endMarker := endMarkerPrefix + cmdID + markerSuffix
+	output := markedEcho("$ ", cmdID, "uptime", "up 3 days", 8, "\r\n")
+	partial := output[:strings.Index(output, "TTOU")]
+
+	async, stdout := (&Session{}).parseMarkedOutput("backup done\r\n"+partial, startMarker, endMarker, "uptime")
+	if stdout != "" || async != "backup done" {
+		t.Errorf("async = %q, stdout = %q; want only the async line", async, stdout)
+	}
+}
+
+func TestStripCommandEcho_NoEcho(t *testing.T) {
+	// With echo off (stty -echo) there is nothing to strip.
+	output := "___CMD_START_ab___\nhi\n___CMD_END_ab___0\n"
+	if got := stripCommandEcho(output, "___CMD_START_ab___", "___CMD_END_ab___"); got != output {
+		t.Errorf("stripCommandEcho() = %q, want output unchanged", got)
+	}
+}
+
+var wrapTestMarker = regexp.MustCompile(`___CMD_START_([0-9a-f]+)___`)
+
+func TestExec_NarrowTerminalWrapsEcho(t *testing.T) {
+	const output = "line one\nline two"
+	pty := newReadyPTY()
+	pty.SetResponder(func(written string) string {
+		if m := wrapTestMarker.FindStringSubmatch(written); m != nil {
+			return markedEcho("$ ", m[1], "cat /etc/motd", output, 20, "\r\n")
+		}
+		return answerReadyProbe(written)
+	})
+	sess := newReadinessSession(pty, config.DefaultConfig())
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	result, err := sess.Exec("cat /etc/motd", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Stdout != output {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, output)
+	}
+	if result.AsyncOutput != "" {
+		t.Errorf("AsyncOutput = %q, want no command text", result.AsyncOutput)
+	}
+}
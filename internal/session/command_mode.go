@@ -0,0 +1,89 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+)
+
+// initializeCommand sets up a "command" mode session: a local command that
+// provides a shell over its stdio, such as "docker exec -it web bash",
+// "kubectl exec -it pod -- sh" or "nsenter -t 1 -a bash". The command runs
+// in a local PTY and the shell on the other end is driven like any other.
+func (s *Session) initializeCommand() error {
+	if strings.TrimSpace(s.Command) == "" {
+		return fmt.Errorf("command is required for command mode")
+	}
+
+	opts := localpty.DefaultOptions()
+	opts.Command = s.Command
+
+	factory := s.localPTYFactory
+	if factory == nil {
+		factory = defaultLocalPTYFactory
+	}
+	pty, _, err := factory(opts)
+	if err != nil {
+		return fmt.Errorf("start command: %w", err)
+	}
+
+	// The local PTY belongs to the command (docker, kubectl, ...), not the
+	// shell, so PTYName stays empty and the control plane leaves it alone.
+	s.pty = &commandPTY{PTY: pty}
+	s.Shell = commandShell(s.Command)
+	s.Cwd = "~"
+	s.State = StateIdle
+	s.CreatedAt = s.clock.Now()
+	s.LastUsed = s.clock.Now()
+
+	s.clock.Sleep(500 * time.Millisecond)
+	s.drainStartupOutput()
+
+	if s.normalizePrompt() {
+		s.pty.WriteString(s.shellPromptCommand())
+		s.clock.Sleep(200 * time.Millisecond)
+		buf := make([]byte, 8192)
+		s.pty.SetReadDeadline(s.clock.Now().Add(300 * time.Millisecond))
+		s.pty.Read(buf) // Drain the output
+	}
+	s.disablePagers()
+	return nil
+}
+
+// commandPTY interrupts by typing Ctrl+C instead of signaling the local
+// process, which is the command relaying the shell: SIGINT would end the
+// whole session rather than the command running inside it.
+type commandPTY struct {
+	PTY
+}
+
+func (p *commandPTY) Interrupt() error {
+	_, err := p.PTY.WriteString("\x03")
+	return err
+}
+
+// knownShells are the shells commandShell recognizes at the end of a
+// command, and detectShellChange in a session.
+var knownShells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "ash": true, "dash": true, "ksh": true, "fish": true,
+	"mksh": true, "csh": true, "tcsh": true,
+}
+
+// commandShell guesses the shell a command starts from its last word, e.g.
+// "bash" for "docker exec -it web /bin/bash". It defaults to /bin/sh.
+func commandShell(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) > 0 {
+		last := strings.Trim(fields[len(fields)-1], `'"`)
+		if knownShells[path.Base(last)] {
+			if strings.HasPrefix(last, "/") {
+				return last
+			}
+			return "/bin/" + last
+		}
+	}
+	return "/bin/sh"
+}
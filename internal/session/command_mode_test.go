@@ -0,0 +1,142 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestManager_Create_CommandMode(t *testing.T) {
+	var started localpty.PTYOptions
+	fake := newReadyPTY()
+	mgr := NewManager(config.DefaultConfig(),
+		WithManagerClock(fakeclock.New(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC))),
+		WithManagerRandom(fakerand.NewSequential()),
+		WithManagerStore(NewSessionStore(WithFileSystem(fakefs.New()), WithStorePath("/tmp/command-test.json"))),
+		WithLocalPTYFactory(func(opts localpty.PTYOptions) (PTY, string, error) {
+			started = opts
+			return fake, "/bin/zsh", nil
+		}),
+	)
+
+	sess, err := mgr.Create(CreateOptions{Mode: "command", Command: "docker exec -it web /bin/bash"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if started.Command != "docker exec -it web /bin/bash" {
+		t.Errorf("PTY command = %q", started.Command)
+	}
+	if sess.Shell != "/bin/bash" {
+		t.Errorf("Shell = %q, want the shell the command starts", sess.Shell)
+	}
+	if sess.controlSession != nil || sess.PTYName != "" {
+		t.Error("command session should not use the local control plane")
+	}
+	if !strings.Contains(fake.Written(), "PS1='$ '") {
+		t.Errorf("prompt not normalized, wrote %q", fake.Written())
+	}
+
+	status := sess.Status()
+	if status.Mode != "command" || status.Command != "docker exec -it web /bin/bash" {
+		t.Errorf("Status() = mode %q, command %q", status.Mode, status.Command)
+	}
+	if meta, ok := mgr.store.Get(sess.ID); !ok || meta.Command != sess.Command {
+		t.Errorf("stored metadata = %+v, want the command for recovery", meta)
+	}
+}
+
+func TestInitialize_CommandModeRequiresCommand(t *testing.T) {
+	sess := NewSession("sess_cmd", "command",
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionFileSystem(fakefs.New()),
+		WithSessionRandom(fakerand.NewSequential()),
+	)
+	sess.localPTYFactory = fakePTYFactory
+
+	err := sess.Initialize()
+	if err == nil || !strings.Contains(err.Error(), "command is required") {
+		t.Errorf("Initialize() error = %v, want command is required", err)
+	}
+}
+
+func TestCommandPTY_InterruptTypesCtrlC(t *testing.T) {
+	fake := fakepty.New()
+	p := &commandPTY{PTY: fake}
+
+	if err := p.Interrupt(); err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if fake.WasInterrupted() {
+		t.Error("Interrupt() signaled the local command")
+	}
+	if fake.Written() != "\x03" {
+		t.Errorf("wrote %q, want Ctrl+C", fake.Written())
+	}
+}
+
+func TestCommandShell(t *testing.T) {
+	tests := map[string]string{
+		"docker exec -it web bash":         "/bin/bash",
+		"kubectl exec -it pod -- /bin/ash": "/bin/ash",
+		"nsenter -t 1 -a '/usr/bin/zsh'":   "/usr/bin/zsh",
+		"ssh-like-tool host":               "/bin/sh",
+		"":                                 "/bin/sh",
+	}
+	for command, want := range tests {
+		if got := commandShell(command); got != want {
+			t.Errorf("commandShell(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestBuildWrappedCommand_CommandModeShell(t *testing.T) {
+	tests := []struct {
+		mode, shell, want string
+	}{
+		{"command", "/bin/ash", "/bin/ash -c 'trap \"\" TTOU; ls'"},
+		{"command", "/usr/bin/fish", "/bin/sh -c 'trap \"\" TTOU; ls'"},
+		{"local", "/bin/zsh", "bash -c 'trap \"\" TTOU; ls'"},
+	}
+	for _, tt := range tests {
+		sess := &Session{Mode: tt.mode, Shell: tt.shell}
+		if got := sess.buildWrappedCommand("ls", "abc12345"); !strings.Contains(got, tt.want) {
+			t.Errorf("%s session with %s: command = %q, want it to contain %q", tt.mode, tt.shell, got, tt.want)
+		}
+	}
+}
+
+func TestManager_Recover_CommandModeChecksCommand(t *testing.T) {
+	store := NewSessionStore(WithFileSystem(fakefs.New()), WithStorePath("/tmp/command-recover.json"))
+	started := false
+	mgr := NewManager(config.DefaultConfig(),
+		WithManagerClock(fakeclock.New(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC))),
+		WithManagerRandom(fakerand.NewSequential()),
+		WithManagerStore(store),
+		WithLocalPTYFactory(func(opts localpty.PTYOptions) (PTY, string, error) {
+			started = true
+			return newReadyPTY(), "/bin/sh", nil
+		}),
+		WithCommandCheck(func(command string) error {
+			return errors.New("command blocked: docker is not allowed")
+		}),
+	)
+	store.mu.Lock()
+	store.sessions["sess_cmd"] = SessionMetadata{ID: "sess_cmd", Mode: "command", Command: "docker exec -it web sh"}
+	store.mu.Unlock()
+
+	_, err := mgr.Get("sess_cmd")
+	if err == nil || !strings.Contains(err.Error(), "command blocked") {
+		t.Errorf("Get() error = %v, want the command check's error", err)
+	}
+	if started {
+		t.Error("a blocked command was run to recover the session")
+	}
+}
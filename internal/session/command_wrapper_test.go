@@ -0,0 +1,101 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+)
+
+func newWrapperSession(host, wrapper string) *Session {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "batch", Host: "batch.example.com", CommandWrapper: wrapper},
+		{Name: "web", Host: "web.example.com"},
+	}
+	sess := NewSession("sess_wrap", "ssh", WithConfig(cfg))
+	sess.Host = host
+	return sess
+}
+
+func TestCommandWrapper_MatchesServer(t *testing.T) {
+	tests := []struct {
+		name string
+		sess *Session
+		want string
+	}{
+		{"by host", newWrapperSession("batch.example.com", "nice -n 19 {{cmd}}"), "nice -n 19 {{cmd}}"},
+		{"by name", newWrapperSession("batch", "nice -n 19 {{cmd}}"), "nice -n 19 {{cmd}}"},
+		{"other server", newWrapperSession("web.example.com", "nice -n 19 {{cmd}}"), ""},
+		{"unknown host", newWrapperSession("db.example.com", "nice -n 19 {{cmd}}"), ""},
+		{"local session", NewSession("sess_local", "local", WithConfig(config.DefaultConfig())), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sess.commandWrapper(); got != tt.want {
+				t.Errorf("commandWrapper() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandWrapper_NamedServer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "db-batch", Host: "db.example.com", CommandWrapper: "nice -n 19 {{cmd}}"},
+		{Name: "prod-db", Host: "db.example.com", CommandWrapper: "timeout 600 {{cmd}}"},
+		{Name: "db-plain", Host: "db.example.com"},
+	}
+	for server, want := range map[string]string{
+		"prod-db":  "timeout 600 {{cmd}}",
+		"db-plain": "",
+		"":         "nice -n 19 {{cmd}}",
+	} {
+		sess := NewSession("sess_wrap", "ssh", WithConfig(cfg))
+		sess.Host, sess.Server = "db.example.com", server
+		if got := sess.commandWrapper(); got != want {
+			t.Errorf("server %q: commandWrapper() = %q, want %q", server, got, want)
+		}
+	}
+}
+
+func TestBuildWrappedCommand_AppliesWrapper(t *testing.T) {
+	sess := newWrapperSession("batch.example.com", "timeout 600 {{cmd}}")
+	got := sess.buildWrappedCommand("ls | wc -l", "abc12345")
+
+	if !strings.Contains(got, `timeout 600 bash -c '\''ls | wc -l'\''`) {
+		t.Errorf("command not nested in wrapper: %q", got)
+	}
+	if !strings.HasPrefix(got, "echo '___CMD_START_abc12345___'; ") {
+		t.Errorf("start marker should come before the wrapper: %q", got)
+	}
+	if !strings.HasSuffix(got, "echo '___CMD_END_abc12345___'$?\n") {
+		t.Errorf("end marker should come after the wrapper: %q", got)
+	}
+
+	plain := newWrapperSession("web.example.com", "timeout 600 {{cmd}}").buildWrappedCommand("ls | wc -l", "abc12345")
+	if strings.Contains(plain, "timeout") {
+		t.Errorf("wrapper applied to a server without one: %q", plain)
+	}
+}
+
+// TestBuildWrappedCommand_WrapperRoundTrip runs a wrapped command through a
+// real shell to check quoting and exit code propagation.
+func TestBuildWrappedCommand_WrapperRoundTrip(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	sess := newWrapperSession("batch.example.com", "nice -n 5 {{cmd}}")
+	full := sess.buildWrappedCommand(`v='a b'; echo "$v" | tr a-z A-Z; exit 3`, "abc12345")
+
+	out, err := exec.Command("bash", "-c", full).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running wrapped command: %v\n%s", err, out)
+	}
+	want := "___CMD_START_abc12345___\nA B\n___CMD_END_abc12345___3\n"
+	if string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,231 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+)
+
+// ConnectionPoolStats reports the state of the shared SSH connection pool.
+type ConnectionPoolStats struct {
+	Connections int `json:"connections"` // distinct authenticated SSH connections
+	Sessions    int `json:"sessions"`    // logical sessions holding a pooled connection
+	Reuses      int `json:"reuses"`      // times an existing connection was handed out
+}
+
+// PooledConnection describes one SSH connection held by the pool.
+type PooledConnection struct {
+	Key       string   `json:"key"`                // user@host:port, then server, auth and proxy (see connPoolKey)
+	Refs      int      `json:"refs"`               // sessions holding the connection
+	Connected bool     `json:"connected"`          // underlying transport is up
+	Closed    bool     `json:"closed,omitempty"`   // force-closed, waiting for holders to release it
+	Sessions  []string `json:"sessions,omitempty"` // IDs of sessions using the connection
+}
+
+// pooledClient is an SSH client shared by one or more sessions.
+type pooledClient struct {
+	key    string
+	client *ssh.Client
+	refs   int
+	closed bool // force-closed; Release only drops the reference
+}
+
+// poolDial is a dial in progress for a key. done is closed once err is set
+// and, on success, the client is pooled.
+type poolDial struct {
+	done chan struct{}
+	err  error
+}
+
+// ConnectionPool shares authenticated SSH clients between sessions that
+// target the same user@host:port. Each session opens its own channel/PTY on
+// the shared client; the underlying connection is closed when the last
+// session releases it.
+type ConnectionPool struct {
+	mu       sync.Mutex
+	byKey    map[string]*pooledClient
+	byClient map[*ssh.Client]*pooledClient
+	dialing  map[string]*poolDial
+	reuses   int
+
+	// alive reports whether a pooled client can be handed out again.
+	alive func(*ssh.Client) bool
+}
+
+// NewConnectionPool creates an empty connection pool.
+func NewConnectionPool() *ConnectionPool {
+	return &ConnectionPool{
+		byKey:    make(map[string]*pooledClient),
+		byClient: make(map[*ssh.Client]*pooledClient),
+		dialing:  make(map[string]*poolDial),
+		alive:    func(c *ssh.Client) bool { return c.IsConnected() },
+	}
+}
+
+// poolKey returns the pool key for a user, host and port.
+func poolKey(user, host string, port int) string {
+	return fmt.Sprintf("%s@%s:%d", user, host, port)
+}
+
+// Acquire returns a connected client for key, calling dial to create one if
+// no live client is pooled. dial runs without the pool lock; concurrent
+// Acquires for the same key wait for it instead of dialing again. Every
+// successful Acquire must be paired with a Release.
+func (p *ConnectionPool) Acquire(key string, dial func() (*ssh.Client, error)) (*ssh.Client, error) {
+	for {
+		p.mu.Lock()
+		if pc, ok := p.byKey[key]; ok {
+			if p.alive(pc.client) {
+				pc.refs++
+				p.reuses++
+				refs := pc.refs
+				p.mu.Unlock()
+				slog.Debug("reusing pooled SSH connection",
+					slog.String("key", key),
+					slog.Int("refs", refs),
+				)
+				return pc.client, nil
+			}
+			// Dead connection: stop handing it out. Sessions still holding it
+			// release it through byClient.
+			delete(p.byKey, key)
+		}
+
+		if d, ok := p.dialing[key]; ok {
+			p.mu.Unlock()
+			<-d.done
+			if d.err != nil {
+				return nil, d.err
+			}
+			// Take a reference to the new client like any other reuse.
+			continue
+		}
+
+		d := &poolDial{done: make(chan struct{})}
+		p.dialing[key] = d
+		p.mu.Unlock()
+
+		client, err := dial()
+
+		p.mu.Lock()
+		delete(p.dialing, key)
+		if err == nil {
+			pc := &pooledClient{key: key, client: client, refs: 1}
+			p.byKey[key] = pc
+			p.byClient[client] = pc
+		}
+		p.mu.Unlock()
+
+		d.err = err
+		close(d.done)
+		return client, err
+	}
+}
+
+// Release drops one reference to client and closes it when no session uses
+// it anymore. Clients not obtained from the pool are closed directly.
+func (p *ConnectionPool) Release(client *ssh.Client) error {
+	if client == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	pc, ok := p.byClient[client]
+	if !ok {
+		p.mu.Unlock()
+		return client.Close()
+	}
+
+	pc.refs--
+	if pc.refs > 0 {
+		p.mu.Unlock()
+		return nil
+	}
+
+	delete(p.byClient, client)
+	if p.byKey[pc.key] == pc {
+		delete(p.byKey, pc.key)
+	}
+	p.mu.Unlock()
+
+	if pc.closed {
+		return nil
+	}
+
+	slog.Debug("closing pooled SSH connection", slog.String("key", pc.key))
+	return client.Close()
+}
+
+// Stats returns a snapshot of pool usage.
+func (p *ConnectionPool) Stats() ConnectionPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := ConnectionPoolStats{
+		Connections: len(p.byClient),
+		Reuses:      p.reuses,
+	}
+	for _, pc := range p.byClient {
+		stats.Sessions += pc.refs
+	}
+	return stats
+}
+
+// list returns the pooled connections sorted by key. holders maps clients to
+// the IDs of the sessions using them.
+func (p *ConnectionPool) list(holders map[*ssh.Client][]string) []PooledConnection {
+	p.mu.Lock()
+	conns := make([]PooledConnection, 0, len(p.byClient))
+	for client, pc := range p.byClient {
+		conns = append(conns, PooledConnection{
+			Key:       pc.key,
+			Refs:      pc.refs,
+			Connected: !pc.closed && p.alive(client),
+			Closed:    pc.closed,
+			Sessions:  holders[client],
+		})
+	}
+	p.mu.Unlock()
+
+	sort.SliceStable(conns, func(i, j int) bool {
+		if conns[i].Key != conns[j].Key {
+			return conns[i].Key < conns[j].Key
+		}
+		return conns[i].Refs > conns[j].Refs
+	})
+	return conns
+}
+
+// ForceClose closes every pooled connection for key even if sessions still
+// reference it. The clients stay tracked until their holders release them, so
+// those releases do not close them twice. It returns the closed clients.
+func (p *ConnectionPool) ForceClose(key string) ([]*ssh.Client, error) {
+	p.mu.Lock()
+	var clients []*ssh.Client
+	for client, pc := range p.byClient {
+		if pc.key == key && !pc.closed {
+			pc.closed = true
+			clients = append(clients, client)
+		}
+	}
+	delete(p.byKey, key)
+	p.mu.Unlock()
+
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("no pooled connection for %s", key)
+	}
+
+	for _, client := range clients {
+		slog.Warn("force-closing pooled SSH connection", slog.String("key", key))
+		if err := client.Close(); err != nil {
+			slog.Debug("error closing pooled SSH connection",
+				slog.String("key", key),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	return clients, nil
+}
@@ -0,0 +1,445 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func newUnconnectedClient(t *testing.T) *ssh.Client {
+	t.Helper()
+	c, err := ssh.NewClient(ssh.ClientOptions{
+		Host:        "example.com",
+		User:        "deploy",
+		AuthMethods: []gossh.AuthMethod{gossh.Password("x")},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return c
+}
+
+func TestConnectionPool_ReusesClientForSameKey(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+
+	dials := 0
+	dial := func() (*ssh.Client, error) {
+		dials++
+		return newUnconnectedClient(t), nil
+	}
+
+	key := poolKey("deploy", "example.com", 22)
+	c1, err := p.Acquire(key, dial)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	c2, err := p.Acquire(key, dial)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	if c1 != c2 {
+		t.Error("expected the same client for the same key")
+	}
+	if dials != 1 {
+		t.Errorf("dials = %d, want 1", dials)
+	}
+
+	stats := p.Stats()
+	if stats.Connections != 1 || stats.Sessions != 2 || stats.Reuses != 1 {
+		t.Errorf("stats = %+v, want 1 connection, 2 sessions, 1 reuse", stats)
+	}
+}
+
+func TestConnectionPool_DistinctKeysGetDistinctClients(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	c1, _ := p.Acquire(poolKey("deploy", "a.example.com", 22), dial)
+	c2, _ := p.Acquire(poolKey("deploy", "a.example.com", 2222), dial)
+	c3, _ := p.Acquire(poolKey("root", "a.example.com", 22), dial)
+
+	if c1 == c2 || c1 == c3 || c2 == c3 {
+		t.Error("different user/host/port should not share a client")
+	}
+	if got := p.Stats().Connections; got != 3 {
+		t.Errorf("Connections = %d, want 3", got)
+	}
+}
+
+func TestConnectionPool_ConcurrentAcquireDialsOnce(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+
+	var dials atomic.Int32
+	release := make(chan struct{})
+	dial := func() (*ssh.Client, error) {
+		dials.Add(1)
+		<-release
+		return newUnconnectedClient(t), nil
+	}
+
+	key := poolKey("deploy", "example.com", 22)
+	clients := make(chan *ssh.Client, 3)
+	for range 3 {
+		go func() {
+			c, _ := p.Acquire(key, dial)
+			clients <- c
+		}()
+	}
+	for dials.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The pool lock is not held while dialing.
+	stats := make(chan ConnectionPoolStats)
+	go func() { stats <- p.Stats() }()
+	select {
+	case <-stats:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stats blocked on a dial in progress")
+	}
+
+	close(release)
+	first := <-clients
+	for range 2 {
+		if c := <-clients; c != first {
+			t.Error("concurrent Acquires for one key got different clients")
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dials = %d, want 1", n)
+	}
+	if got := p.Stats(); got.Connections != 1 || got.Sessions != 3 {
+		t.Errorf("stats = %+v, want 1 connection held by 3 sessions", got)
+	}
+}
+
+func TestConnectionPool_DialErrorReachesWaiters(t *testing.T) {
+	p := NewConnectionPool()
+	release := make(chan struct{})
+	dialErr := errors.New("connection refused")
+	dial := func() (*ssh.Client, error) {
+		<-release
+		return nil, dialErr
+	}
+
+	key := poolKey("deploy", "example.com", 22)
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := p.Acquire(key, dial)
+			errs <- err
+		}()
+	}
+	close(release)
+	for range 2 {
+		if err := <-errs; !errors.Is(err, dialErr) {
+			t.Errorf("Acquire error = %v, want %v", err, dialErr)
+		}
+	}
+	if got := p.Stats().Connections; got != 0 {
+		t.Errorf("Connections = %d after a failed dial, want 0", got)
+	}
+}
+
+func TestSession_ConnPoolKey(t *testing.T) {
+	agent := ssh.AuthConfig{UseAgent: true}
+	tests := []struct {
+		identity string
+		auth     ssh.AuthConfig
+		proxy    *ssh.ProxyOptions
+		want     string
+	}{
+		{"", agent, nil, "deploy@example.com:22"},
+		{"admin", agent, nil, "deploy@example.com:22#admin"},
+		{"", ssh.AuthConfig{KeyPath: "~/.ssh/other_key", UseAgent: true}, nil, "deploy@example.com:22#key:~/.ssh/other_key"},
+		{"", ssh.AuthConfig{Password: "hunter2"}, nil, "deploy@example.com:22#password:" + secretDigest("hunter2") + "#noagent"},
+		{"", agent, &ssh.ProxyOptions{Scheme: "socks5", Address: "bastion:1080", User: "me"}, "deploy@example.com:22#proxy:socks5://bastion:1080#proxyuser:me"},
+	}
+	for _, tt := range tests {
+		sess := NewSession("sess_1", "ssh")
+		sess.User, sess.Host, sess.Port = "deploy", "example.com", 22
+		sess.Identity = tt.identity
+		if got := sess.connPoolKey(tt.auth, tt.proxy); got != tt.want {
+			t.Errorf("identity %q, auth %+v, proxy %v: connPoolKey() = %q, want %q", tt.identity, tt.auth, tt.proxy, got, tt.want)
+		}
+	}
+	if strings.Contains(secretDigest("hunter2"), "hunter2") {
+		t.Error("secretDigest leaks the secret")
+	}
+}
+
+// Sessions to one user@host:port that would connect differently each get
+// their own client.
+func TestConnectionPool_SessionsWithDifferentRoutesDoNotShare(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "db-east", Host: "db.internal", Proxy: config.ProxyConfig{URL: "socks5://bastion-east:1080"}},
+		{Name: "db-west", Host: "db.internal", Proxy: config.ProxyConfig{URL: "socks5://bastion-west:1080"}},
+	}
+	newSess := func(server string) *Session {
+		sess := NewSession("sess_"+server, "ssh", WithConfig(cfg))
+		sess.User, sess.Host, sess.Port, sess.Server = "deploy", "db.internal", 22, server
+		return sess
+	}
+	agent := ssh.AuthConfig{UseAgent: true}
+	east, west := newSess("db-east"), newSess("db-west")
+
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+	dials := 0
+	dial := func() (*ssh.Client, error) { dials++; return newUnconnectedClient(t), nil }
+
+	eastProxy := &ssh.ProxyOptions{Scheme: "socks5", Address: "bastion-east:1080"}
+	westProxy := &ssh.ProxyOptions{Scheme: "socks5", Address: "bastion-west:1080"}
+	p.Acquire(east.connPoolKey(agent, eastProxy), dial)
+	p.Acquire(west.connPoolKey(agent, westProxy), dial)
+	p.Acquire(east.connPoolKey(ssh.AuthConfig{Password: "pw1"}, eastProxy), dial)
+	p.Acquire(east.connPoolKey(ssh.AuthConfig{Password: "pw2"}, eastProxy), dial)
+	if dials != 4 {
+		t.Errorf("dials = %d, want a client per server, proxy and password", dials)
+	}
+
+	p.Acquire(east.connPoolKey(agent, eastProxy), dial)
+	if dials != 4 {
+		t.Errorf("dials = %d, want the same route to share its client", dials)
+	}
+}
+
+func TestConnectionPool_ReleaseClosesOnLastReference(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	key := poolKey("deploy", "example.com", 22)
+	c, _ := p.Acquire(key, dial)
+	p.Acquire(key, dial)
+
+	if err := p.Release(c); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if stats := p.Stats(); stats.Connections != 1 || stats.Sessions != 1 {
+		t.Errorf("after first release stats = %+v, want 1 connection, 1 session", stats)
+	}
+
+	if err := p.Release(c); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if stats := p.Stats(); stats.Connections != 0 || stats.Sessions != 0 {
+		t.Errorf("after last release stats = %+v, want empty pool", stats)
+	}
+
+	// A new Acquire must dial again.
+	dials := 0
+	p.Acquire(key, func() (*ssh.Client, error) {
+		dials++
+		return newUnconnectedClient(t), nil
+	})
+	if dials != 1 {
+		t.Errorf("dials = %d, want 1 after pool drained", dials)
+	}
+}
+
+func TestConnectionPool_DeadClientIsReplaced(t *testing.T) {
+	p := NewConnectionPool()
+	dead := map[*ssh.Client]bool{}
+	p.alive = func(c *ssh.Client) bool { return !dead[c] }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	key := poolKey("deploy", "example.com", 22)
+	old, _ := p.Acquire(key, dial)
+	dead[old] = true
+
+	fresh, _ := p.Acquire(key, dial)
+	if fresh == old {
+		t.Fatal("expected a fresh client when the pooled one is dead")
+	}
+	if got := p.Stats().Connections; got != 2 {
+		t.Errorf("Connections = %d, want 2 (old still referenced)", got)
+	}
+
+	// Releasing the old client must not affect the fresh one.
+	p.Release(old)
+	again, _ := p.Acquire(key, dial)
+	if again != fresh {
+		t.Error("expected the fresh client to be reused")
+	}
+}
+
+func TestConnectionPool_DialError(t *testing.T) {
+	p := NewConnectionPool()
+	wantErr := errors.New("dial failed")
+
+	_, err := p.Acquire("k", func() (*ssh.Client, error) { return nil, wantErr })
+	if !errors.Is(err, wantErr) {
+		t.Errorf("err = %v, want %v", err, wantErr)
+	}
+	if got := p.Stats().Connections; got != 0 {
+		t.Errorf("Connections = %d, want 0", got)
+	}
+}
+
+func TestConnectionPool_ReleaseUnpooledClient(t *testing.T) {
+	p := NewConnectionPool()
+	if err := p.Release(newUnconnectedClient(t)); err != nil {
+		t.Errorf("Release of unpooled client: %v", err)
+	}
+	if err := p.Release(nil); err != nil {
+		t.Errorf("Release(nil): %v", err)
+	}
+}
+
+func TestManager_PoolStatsEmpty(t *testing.T) {
+	mgr := NewManager(config.DefaultConfig())
+	if stats := mgr.PoolStats(); stats != (ConnectionPoolStats{}) {
+		t.Errorf("PoolStats() = %+v, want zero", stats)
+	}
+}
+
+func TestConnectionPool_ForceCloseKeepsReferencesUntilReleased(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	key := poolKey("deploy", "example.com", 22)
+	c, _ := p.Acquire(key, dial)
+	p.Acquire(key, dial)
+
+	clients, err := p.ForceClose(key)
+	if err != nil {
+		t.Fatalf("ForceClose: %v", err)
+	}
+	if len(clients) != 1 || clients[0] != c {
+		t.Fatalf("ForceClose returned %v, want the pooled client", clients)
+	}
+
+	conns := p.list(nil)
+	if len(conns) != 1 || !conns[0].Closed || conns[0].Connected || conns[0].Refs != 2 {
+		t.Errorf("list after ForceClose = %+v, want one closed entry with 2 refs", conns)
+	}
+
+	// New sessions must get a fresh connection.
+	fresh, _ := p.Acquire(key, dial)
+	if fresh == c {
+		t.Error("force-closed client was handed out again")
+	}
+
+	// Holders release the closed client without error; it then disappears.
+	p.Release(c)
+	if err := p.Release(c); err != nil {
+		t.Errorf("Release of force-closed client: %v", err)
+	}
+	if got := p.Stats().Connections; got != 1 {
+		t.Errorf("Connections = %d, want 1 (the fresh client)", got)
+	}
+}
+
+func TestConnectionPool_ForceCloseUnknownKey(t *testing.T) {
+	p := NewConnectionPool()
+	if _, err := p.ForceClose("nobody@nowhere:22"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestConnectionPool_ListSortedWithHolders(t *testing.T) {
+	p := NewConnectionPool()
+	p.alive = func(*ssh.Client) bool { return true }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	b, _ := p.Acquire(poolKey("deploy", "b.example.com", 22), dial)
+	a, _ := p.Acquire(poolKey("deploy", "a.example.com", 22), dial)
+
+	conns := p.list(map[*ssh.Client][]string{a: {"sess_a"}, b: {"sess_b"}})
+	if len(conns) != 2 {
+		t.Fatalf("list = %+v, want 2 entries", conns)
+	}
+	if conns[0].Key != "deploy@a.example.com:22" || conns[0].Sessions[0] != "sess_a" {
+		t.Errorf("first entry = %+v, want a.example.com with sess_a", conns[0])
+	}
+	if !conns[1].Connected || conns[1].Refs != 1 {
+		t.Errorf("second entry = %+v, want connected with 1 ref", conns[1])
+	}
+}
+
+func TestManager_CloseConnectionMarksSessionsDropped(t *testing.T) {
+	mgr := NewManager(config.DefaultConfig())
+	mgr.connPool.alive = func(*ssh.Client) bool { return true }
+	dial := func() (*ssh.Client, error) { return newUnconnectedClient(t), nil }
+
+	key := poolKey("deploy", "example.com", 22)
+	for _, id := range []string{"sess_2", "sess_1"} {
+		c, _ := mgr.connPool.Acquire(key, dial)
+		sess := NewSession(id, "ssh", WithSessionClock(fakeclock.New(time.Now())))
+		sess.sshClient = c
+		sess.connPool = mgr.connPool
+		mgr.sessions[id] = sess
+	}
+	other := NewSession("sess_other", "ssh", WithSessionClock(fakeclock.New(time.Now())))
+	other.sshClient, _ = mgr.connPool.Acquire(poolKey("root", "example.com", 22), dial)
+	mgr.sessions[other.ID] = other
+
+	conns := mgr.Connections()
+	if len(conns) != 2 || len(conns[0].Sessions) != 2 {
+		t.Fatalf("Connections() = %+v, want 2 connections, first shared by 2 sessions", conns)
+	}
+
+	dropped, err := mgr.CloseConnection(key)
+	if err != nil {
+		t.Fatalf("CloseConnection: %v", err)
+	}
+	if len(dropped) != 2 || dropped[0] != "sess_1" || dropped[1] != "sess_2" {
+		t.Errorf("dropped = %v, want [sess_1 sess_2]", dropped)
+	}
+	if got := mgr.sessions["sess_1"].Status().ConnectionError; !strings.Contains(got, "force-closed") {
+		t.Errorf("ConnectionError = %q, want force-closed reason", got)
+	}
+	if got := other.Status().ConnectionError; got != "" {
+		t.Errorf("unrelated session ConnectionError = %q, want empty", got)
+	}
+
+	if _, err := mgr.CloseConnection(key); err == nil {
+		t.Error("closing an already force-closed key should fail")
+	}
+}
+
+func TestManager_CloseConnectionWhileSessionBusy(t *testing.T) {
+	mgr := NewManager(config.DefaultConfig())
+	mgr.connPool.alive = func(*ssh.Client) bool { return true }
+	key := poolKey("deploy", "example.com", 22)
+	c, _ := mgr.connPool.Acquire(key, func() (*ssh.Client, error) { return newUnconnectedClient(t), nil })
+	sess := NewSession("sess_busy", "ssh", WithSessionClock(fakeclock.New(time.Now())))
+	sess.sshClient = c
+	sess.connPool = mgr.connPool
+	mgr.sessions[sess.ID] = sess
+
+	// A hung command holds the session lock; closing its connection is how
+	// it gets unstuck, so that must not wait for the lock.
+	sess.mu.Lock()
+	done := make(chan []string)
+	go func() {
+		dropped, _ := mgr.CloseConnection(key)
+		done <- dropped
+	}()
+	select {
+	case dropped := <-done:
+		if len(dropped) != 1 || dropped[0] != "sess_busy" {
+			t.Errorf("dropped = %v, want [sess_busy]", dropped)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("CloseConnection blocked on a busy session")
+	}
+	sess.mu.Unlock()
+	if got := sess.Status().ConnectionError; !strings.Contains(got, "force-closed") {
+		t.Errorf("ConnectionError = %q, want force-closed reason", got)
+	}
+}
@@ -0,0 +1,147 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ConnectionErrorCode says why a session lost its connection or PTY.
+type ConnectionErrorCode string
+
+const (
+	// ConnRemoteClosed: the remote end closed the connection (reboot, sshd
+	// restart, the shell exited).
+	ConnRemoteClosed ConnectionErrorCode = "remote_closed"
+	// ConnNetworkTimeout: the network stopped answering (dial or I/O
+	// timeout, unreachable host).
+	ConnNetworkTimeout ConnectionErrorCode = "network_timeout"
+	// ConnAuthRevoked: reconnecting failed because the credentials were
+	// rejected.
+	ConnAuthRevoked ConnectionErrorCode = "auth_revoked"
+	// ConnLocalPTYDied: the local shell or its PTY went away.
+	ConnLocalPTYDied ConnectionErrorCode = "local_pty_died"
+)
+
+// ConnectionError is a session error caused by a lost connection or PTY.
+// Code lets callers tell "the box rebooted" from "my credential expired".
+type ConnectionError struct {
+	Code ConnectionErrorCode
+	Err  error
+}
+
+func (e *ConnectionError) Error() string {
+	return string(e.Code) + ": " + e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error { return e.Err }
+
+// ConnectionErrorCodeOf returns the code of the ConnectionError in err's
+// chain, or "" if there is none.
+func ConnectionErrorCodeOf(err error) ConnectionErrorCode {
+	var connErr *ConnectionError
+	if errors.As(err, &connErr) {
+		return connErr.Code
+	}
+	return ""
+}
+
+// classifyConnectionError returns why err broke the session's connection,
+// or "" if it does not look like a connection failure. Typed errors from the
+// SSH and PTY layers are checked first; message matching covers errors that
+// lost their type crossing the SSH channel.
+func (s *Session) classifyConnectionError(err error) ConnectionErrorCode {
+	if err == nil {
+		return ""
+	}
+	if code := ConnectionErrorCodeOf(err); code != "" {
+		return code
+	}
+	if isAuthFailure(err) {
+		return ConnAuthRevoked
+	}
+	if isNetworkTimeout(err) {
+		return ConnNetworkTimeout
+	}
+	if !isConnectionBroken(err) {
+		return ""
+	}
+	if s.Mode != "ssh" {
+		return ConnLocalPTYDied
+	}
+	return ConnRemoteClosed
+}
+
+// lostConnectionCode classifies a connection that broke with cause and
+// could not be re-established because of reconnErr. An auth or network
+// failure while reconnecting says more than the original EOF.
+func (s *Session) lostConnectionCode(cause, reconnErr error) ConnectionErrorCode {
+	if code := s.classifyConnectionError(reconnErr); code == ConnAuthRevoked || code == ConnNetworkTimeout {
+		return code
+	}
+	if code := s.classifyConnectionError(cause); code != "" {
+		return code
+	}
+	return ConnRemoteClosed
+}
+
+// connectionError wraps err as a ConnectionError if it is a connection
+// failure, and returns it unchanged otherwise.
+func (s *Session) connectionError(err error) error {
+	if ConnectionErrorCodeOf(err) != "" {
+		return err
+	}
+	if code := s.classifyConnectionError(err); code != "" {
+		return &ConnectionError{Code: code, Err: err}
+	}
+	return err
+}
+
+// isAuthFailure reports whether err is the SSH handshake, or the proxy in
+// front of it, rejecting our credentials.
+func isAuthFailure(err error) bool {
+	var passErr *gossh.PassphraseMissingError
+	if errors.As(err, &passErr) || errors.Is(err, ssh.ErrProxyAuth) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "unable to authenticate") ||
+		strings.Contains(msg, "no supported methods remain") ||
+		strings.Contains(msg, "permission denied (publickey")
+}
+
+// isNetworkTimeout reports whether err is a network-level timeout or an
+// unreachable network, as opposed to the short read deadlines the read loop
+// sets itself.
+func isNetworkTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		strings.Contains(err.Error(), "connection timed out") ||
+		strings.Contains(err.Error(), "no route to host")
+}
+
+// isClosedError reports whether err is a typed "the other end is gone" error
+// from the SSH or PTY layer.
+func isClosedError(err error) bool {
+	var exitMissing *gossh.ExitMissingError
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, os.ErrClosed) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.As(err, &exitMissing)
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestClassifyConnectionError(t *testing.T) {
+	ssh := &Session{Mode: "ssh"}
+	local := &Session{Mode: "local"}
+
+	tests := []struct {
+		name string
+		sess *Session
+		err  error
+		want ConnectionErrorCode
+	}{
+		{"nil", ssh, nil, ""},
+		{"unrelated", ssh, errors.New("permission denied"), ""},
+		{"ssh EOF", ssh, fmt.Errorf("read: %w", io.EOF), ConnRemoteClosed},
+		{"ssh reset", ssh, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, ConnRemoteClosed},
+		{"ssh broken pipe", ssh, &net.OpError{Op: "write", Net: "tcp", Err: os.NewSyscallError("write", syscall.EPIPE)}, ConnRemoteClosed},
+		{"ssh exit missing", ssh, &gossh.ExitMissingError{}, ConnRemoteClosed},
+		{"ssh channel closed", ssh, errors.New("ssh: channel closed"), ConnRemoteClosed},
+		{"io timeout", ssh, &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, ConnNetworkTimeout},
+		{"host unreachable", ssh, &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, ConnNetworkTimeout},
+		{"auth rejected", ssh, errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"), ConnAuthRevoked},
+		{"passphrase missing", ssh, &gossh.PassphraseMissingError{}, ConnAuthRevoked},
+		{"local EIO", local, &os.PathError{Op: "read", Path: "/dev/ptmx", Err: syscall.EIO}, ConnLocalPTYDied},
+		{"local closed", local, fmt.Errorf("write: %w", os.ErrClosed), ConnLocalPTYDied},
+		{"already classified", local, &ConnectionError{Code: ConnAuthRevoked, Err: io.EOF}, ConnAuthRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sess.classifyConnectionError(tt.err); got != tt.want {
+				t.Errorf("classifyConnectionError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLostConnectionCode_PrefersReconnectCause(t *testing.T) {
+	sess := &Session{Mode: "ssh"}
+	authErr := errors.New("ssh: handshake failed: ssh: unable to authenticate")
+	timeoutErr := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+
+	tests := []struct {
+		name      string
+		cause     error
+		reconnErr error
+		want      ConnectionErrorCode
+	}{
+		{"no reconnect", io.EOF, nil, ConnRemoteClosed},
+		{"credentials rejected", io.EOF, authErr, ConnAuthRevoked},
+		{"dial timed out", io.EOF, timeoutErr, ConnNetworkTimeout},
+		{"refused keeps cause", io.EOF, refused, ConnRemoteClosed},
+		{"unknown cause", nil, errors.New("boom"), ConnRemoteClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sess.lostConnectionCode(tt.cause, tt.reconnErr); got != tt.want {
+				t.Errorf("lostConnectionCode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionError_WrapsAndPrefixesCode(t *testing.T) {
+	err := &ConnectionError{Code: ConnRemoteClosed, Err: fmt.Errorf("read output: %w", io.EOF)}
+	if !errors.Is(err, io.EOF) {
+		t.Error("ConnectionError should unwrap to the underlying error")
+	}
+	if !strings.HasPrefix(err.Error(), "remote_closed: ") {
+		t.Errorf("Error() = %q, want remote_closed prefix", err.Error())
+	}
+	if got := ConnectionErrorCodeOf(fmt.Errorf("exec: %w", err)); got != ConnRemoteClosed {
+		t.Errorf("ConnectionErrorCodeOf = %q, want remote_closed", got)
+	}
+	if got := ConnectionErrorCodeOf(io.EOF); got != "" {
+		t.Errorf("ConnectionErrorCodeOf(plain) = %q, want empty", got)
+	}
+}
+
+func TestExec_LocalPTYDied(t *testing.T) {
+	sess, pty := newStdinSession(t)
+	pty.SetReadError(&os.PathError{Op: "read", Path: "/dev/ptmx", Err: syscall.EIO})
+
+	_, err := sess.Exec("echo hi", 5000)
+	if err == nil {
+		t.Fatal("expected error when the PTY dies")
+	}
+	if got := ConnectionErrorCodeOf(err); got != ConnLocalPTYDied {
+		t.Errorf("code = %q, want local_pty_died (err: %v)", got, err)
+	}
+}
+
+func TestExecWithOptions_ConnectionLostReportsRemoteClosed(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.reconnect = func() error { return nil }
+
+	result, err := sess.ExecWithOptions("./migrate.sh", 5000, ExecOptions{AutoReconnect: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.ErrorCode != ConnRemoteClosed {
+		t.Errorf("ErrorCode = %q, want remote_closed", result.ErrorCode)
+	}
+}
+
+func TestExecWithOptions_ReconnectAuthRevoked(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.reconnect = func() error {
+		return errors.New("reconnect failed after 3 attempts: ssh: handshake failed: ssh: unable to authenticate, attempted methods [none password], no supported methods remain")
+	}
+
+	result, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{AutoReconnect: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "connection_lost" {
+		t.Fatalf("Status = %q, want connection_lost", result.Status)
+	}
+	if result.ErrorCode != ConnAuthRevoked {
+		t.Errorf("ErrorCode = %q, want auth_revoked", result.ErrorCode)
+	}
+}
+
+func TestExecWithOptions_ReconnectNetworkTimeout(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.reconnect = func() error {
+		return fmt.Errorf("reconnect failed after 3 attempts: %w", &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded})
+	}
+
+	result, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{AutoReconnect: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.ErrorCode != ConnNetworkTimeout {
+		t.Errorf("ErrorCode = %q, want network_timeout", result.ErrorCode)
+	}
+}
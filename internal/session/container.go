@@ -0,0 +1,127 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultContainerRuntime is the runtime used when a session names a
+// container without one.
+const DefaultContainerRuntime = "docker"
+
+// containerNamePattern matches docker and podman container names and IDs.
+// It is strict enough to put the name on a command line unquoted.
+var containerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateContainer checks the container options of a new session.
+func validateContainer(opts CreateOptions) error {
+	if opts.Container == "" {
+		if opts.ContainerRuntime != "" {
+			return fmt.Errorf("container_runtime requires container")
+		}
+		return nil
+	}
+	if opts.Mode == "command" {
+		return fmt.Errorf("container is not supported for command mode sessions; use command: \"docker exec -it <container> bash\" instead")
+	}
+	if opts.RawMode {
+		return fmt.Errorf("container cannot be combined with raw_mode")
+	}
+	if !containerNamePattern.MatchString(opts.Container) {
+		return fmt.Errorf("invalid container %q: must be a container name or ID", opts.Container)
+	}
+	switch opts.ContainerRuntime {
+	case "", "docker", "podman":
+		return nil
+	}
+	return fmt.Errorf("invalid container_runtime %q: must be docker or podman", opts.ContainerRuntime)
+}
+
+// containerRuntime returns the session's container runtime.
+func (s *Session) containerRuntime() string {
+	if s.ContainerRuntime == "" {
+		return DefaultContainerRuntime
+	}
+	return s.ContainerRuntime
+}
+
+// applyContainer runs command inside the session's container with
+// `<runtime> exec -it -w <cwd> <container> sh -c '<command>'`, or returns it
+// unchanged for sessions without one. sh is used because slim images often
+// lack bash.
+func (s *Session) applyContainer(command string) string {
+	if s.Container == "" {
+		return command
+	}
+	inner, _ := WrapInShell("sh", command)
+	workdir := ""
+	if strings.HasPrefix(s.Cwd, "/") {
+		workdir = "-w " + shellQuote(s.Cwd) + " "
+	}
+	return fmt.Sprintf("%s exec -it %s%s%s %s", s.containerRuntime(), workdir, s.containerEnv(), s.Container, inner)
+}
+
+// containerCommand returns the helper command (pwd, env) to type into the
+// session's shell so it reports on the container rather than the host.
+func (s *Session) containerCommand(command string) string {
+	if s.Container == "" {
+		return command
+	}
+	return fmt.Sprintf("%s exec %s%s %s", s.containerRuntime(), s.containerEnv(), s.Container, command)
+}
+
+// containerEnv returns the -e flags that pass the pager-disabling variables
+// into the container, where the host shell's exports do not reach.
+func (s *Session) containerEnv() string {
+	var flags strings.Builder
+	for _, assignment := range s.pagerEnv() {
+		flags.WriteString("-e " + shellQuote(assignment) + " ")
+	}
+	return flags.String()
+}
+
+// initializeContainer replaces the host's cwd and environment with the
+// container's: its working directory becomes the session cwd that later
+// commands run in. Caller must hold s.mu.
+func (s *Session) initializeContainer() {
+	if s.Container == "" {
+		return
+	}
+
+	pwd := s.containerCommand("pwd")
+	if output := s.probeContainer(pwd, 4096); output != "" {
+		// Drop the echoed command so a prompt showing the host cwd in
+		// front of it isn't taken for pwd's output.
+		if cwd := parsePwdOutput(strings.ReplaceAll(output, pwd, "")); cwd != "" {
+			s.Cwd = cwd
+			s.noteCaptured("cwd")
+		}
+	}
+	if !s.autoCapture().Env {
+		// The host's variables don't apply; CaptureEnv asks the container.
+		s.EnvVars = nil
+		return
+	}
+	if env := parseEnvOutput(s.probeContainer(s.containerCommand("env"), 32768)); len(env) > 0 {
+		s.EnvVars = s.filterEnv(env)
+		s.noteCaptured("env")
+	}
+}
+
+// probeContainer types command into the session's shell and returns what
+// one read of up to size bytes yields, like updateCwd and CaptureEnv.
+func (s *Session) probeContainer(command string, size int) string {
+	s.pty.WriteString(command + "\n")
+	s.clock.Sleep(200 * time.Millisecond)
+	buf := make([]byte, size)
+	s.pty.SetReadDeadline(s.clock.Now().Add(500 * time.Millisecond))
+	n, _ := s.pty.Read(buf)
+	return string(buf[:n])
+}
+
+// shellQuote quotes v for safe use as a single POSIX shell word.
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newContainerSession(t *testing.T, runtime string) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_ctr", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionRandom(fakerand.NewSequential()),
+	)
+	sess.Container = "web-1"
+	sess.ContainerRuntime = runtime
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return sess, pty
+}
+
+func TestValidateContainer(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CreateOptions
+		wantErr string
+	}{
+		{"none", CreateOptions{Mode: "local"}, ""},
+		{"docker default", CreateOptions{Mode: "ssh", Container: "web-1"}, ""},
+		{"podman", CreateOptions{Mode: "local", Container: "3f4e2a1b", ContainerRuntime: "podman"}, ""},
+		{"runtime without container", CreateOptions{Mode: "local", ContainerRuntime: "podman"}, "requires container"},
+		{"command mode", CreateOptions{Mode: "command", Command: "bash", Container: "web"}, "not supported for command mode"},
+		{"raw mode", CreateOptions{Mode: "local", Container: "web", RawMode: true}, "raw_mode"},
+		{"bad name", CreateOptions{Mode: "local", Container: "web; rm -rf /"}, "invalid container"},
+		{"bad runtime", CreateOptions{Mode: "local", Container: "web", ContainerRuntime: "lxc"}, "invalid container_runtime"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContainer(tt.opts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateContainer() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateContainer() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyContainer(t *testing.T) {
+	sess, _ := newContainerSession(t, "")
+	sess.Cwd = "/srv/app"
+
+	got := sess.applyContainer("echo 'hi'")
+	want := `docker exec -it -w '/srv/app' web-1 sh -c 'echo '\''hi'\'''`
+	if got != want {
+		t.Errorf("applyContainer() = %q, want %q", got, want)
+	}
+
+	sess.Cwd = "~"
+	if got := sess.applyContainer("ls"); got != "docker exec -it web-1 sh -c 'ls'" {
+		t.Errorf("applyContainer() with no cwd = %q", got)
+	}
+
+	sess.Container = ""
+	if got := sess.applyContainer("ls"); got != "ls" {
+		t.Errorf("applyContainer() without container = %q, want the command unchanged", got)
+	}
+}
+
+func TestInitializeContainer(t *testing.T) {
+	sess, pty := newContainerSession(t, "podman")
+	// A prompt showing the host cwd precedes the echoed command.
+	pty.AddResponse("/home/user $ podman exec web-1 pwd\r\n/srv/app\r\n/home/user $ ")
+	pty.AddResponse("podman exec web-1 env\r\nAPP_ENV=production\r\nHOME=/root\r\n$ ")
+
+	sess.initializeContainer()
+
+	if sess.Cwd != "/srv/app" {
+		t.Errorf("Cwd = %q, want the container's /srv/app", sess.Cwd)
+	}
+	if sess.EnvVars["APP_ENV"] != "production" || sess.EnvVars["HOME"] != "/root" {
+		t.Errorf("EnvVars = %v, want the container's environment", sess.EnvVars)
+	}
+	if written := pty.Written(); written != "podman exec web-1 pwd\npodman exec web-1 env\n" {
+		t.Errorf("written = %q, want the probes run through podman", written)
+	}
+}
+
+func TestExec_Container(t *testing.T) {
+	sess, pty := newContainerSession(t, "")
+	sess.Cwd = "/srv/app"
+	pty.AddResponse(startMarkerPrefix + "00010203" + markerSuffix + "\nrails 7.1\n" + endMarkerPrefix + "00010203" + markerSuffix + "0\n")
+
+	result, err := sess.Exec("rails -v", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Stdout != "rails 7.1" || result.Cwd != "/srv/app" {
+		t.Errorf("Stdout = %q, Cwd = %q; want container output and cwd", result.Stdout, result.Cwd)
+	}
+	written := pty.Written()
+	if !strings.Contains(written, "docker exec -it -w ") || !strings.Contains(written, "web-1 sh -c") {
+		t.Errorf("written = %q, want the command run through docker exec", written)
+	}
+	if strings.Contains(written, "\npwd\n") {
+		t.Errorf("written = %q, want no host pwd probe", written)
+	}
+}
+
+func TestCaptureEnv_Container(t *testing.T) {
+	sess, pty := newContainerSession(t, "")
+	pty.AddResponse("docker exec web-1 env\r\nRAILS_ENV=test\r\n")
+
+	env := sess.CaptureEnv()
+	if env["RAILS_ENV"] != "test" {
+		t.Errorf("CaptureEnv() = %v, want the container's environment", env)
+	}
+	if pty.Written() != "docker exec web-1 env\n" {
+		t.Errorf("written = %q, want env run in the container", pty.Written())
+	}
+}
+
+func TestManagerCreate_InvalidContainer(t *testing.T) {
+	mgr, _, _ := newTestManager(config.DefaultConfig())
+
+	_, err := mgr.Create(CreateOptions{Mode: "local", Container: "$(reboot)"})
+	if err == nil || !strings.Contains(err.Error(), "invalid container") {
+		t.Errorf("Create() error = %v, want invalid container", err)
+	}
+}
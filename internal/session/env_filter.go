@@ -0,0 +1,54 @@
+package session
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// filterEnv applies session.capture_env_allowlist and capture_env_denylist
+// to a captured environment. Without either, env is returned as is.
+func (s *Session) filterEnv(env map[string]string) map[string]string {
+	if s.config == nil {
+		return env
+	}
+	allow := compileEnvPatterns(s.config.Session.CaptureEnvAllowlist)
+	deny := compileEnvPatterns(s.config.Session.CaptureEnvDenylist)
+	if len(allow) == 0 && len(deny) == 0 {
+		return env
+	}
+
+	filtered := make(map[string]string, len(env))
+	for name, value := range env {
+		if len(allow) > 0 && !matchesAny(allow, name) {
+			continue
+		}
+		if matchesAny(deny, name) {
+			continue
+		}
+		filtered[name] = value
+	}
+	return filtered
+}
+
+// compileEnvPatterns compiles capture_env patterns, skipping invalid ones.
+func compileEnvPatterns(exprs []string) []*regexp.Regexp {
+	patterns := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			slog.Warn("ignoring invalid capture_env pattern", slog.String("pattern", expr), slog.String("error", err.Error()))
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
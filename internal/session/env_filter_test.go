@@ -0,0 +1,90 @@
+package session
+
+import (
+	"io"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func TestFilterEnv(t *testing.T) {
+	env := map[string]string{
+		"HOME":                  "/home/deploy",
+		"PATH":                  "/usr/bin",
+		"AWS_REGION":            "eu-west-1",
+		"AWS_SECRET_ACCESS_KEY": "s3cr3t",
+		"GITHUB_TOKEN":          "ghp_x",
+	}
+
+	tests := []struct {
+		name        string
+		allow, deny []string
+		want        []string
+	}{
+		{"default keeps everything", nil, nil, []string{"HOME", "PATH", "AWS_REGION", "AWS_SECRET_ACCESS_KEY", "GITHUB_TOKEN"}},
+		{"denylist", nil, []string{`(?i)secret|token`}, []string{"HOME", "PATH", "AWS_REGION"}},
+		{"allowlist", []string{`^AWS_`, `^HOME$`}, nil, []string{"HOME", "AWS_REGION", "AWS_SECRET_ACCESS_KEY"}},
+		{"denylist wins", []string{`^AWS_`}, []string{`SECRET`}, []string{"AWS_REGION"}},
+		{"invalid pattern skipped", nil, []string{`[`, `TOKEN`}, []string{"HOME", "PATH", "AWS_REGION", "AWS_SECRET_ACCESS_KEY"}},
+	}
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.Session.CaptureEnvAllowlist = tt.allow
+		cfg.Session.CaptureEnvDenylist = tt.deny
+		sess := &Session{config: cfg}
+
+		got := sess.filterEnv(maps.Clone(env))
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: filterEnv() = %v, want keys %v", tt.name, got, tt.want)
+			continue
+		}
+		for _, name := range tt.want {
+			if got[name] != env[name] {
+				t.Errorf("%s: %s = %q, want %q", tt.name, name, got[name], env[name])
+			}
+		}
+	}
+}
+
+func TestCaptureEnv_Denylist(t *testing.T) {
+	pty := fakepty.New()
+	cfg := config.DefaultConfig()
+	cfg.Session.CaptureEnvDenylist = []string{`(?i)password|token`}
+	sess := NewSession("sess_env", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	pty.AddResponse("HOME=/home/deploy\nDB_PASSWORD=hunter2\nVAULT_TOKEN=s.abc\n")
+	env := sess.CaptureEnv()
+	if env["HOME"] != "/home/deploy" || len(env) != 1 {
+		t.Errorf("CaptureEnv() = %v, want HOME only", env)
+	}
+	if _, ok := sess.Status().EnvVars["DB_PASSWORD"]; ok {
+		t.Error("Status() shows a denylisted variable")
+	}
+}
+
+func TestCaptureEnvAndPTY_AllowlistKeepsPTYName(t *testing.T) {
+	pty := fakepty.New()
+	cfg := config.DefaultConfig()
+	cfg.Session.CaptureEnvAllowlist = []string{`^LANG$`}
+	sess := &Session{ID: "sess_env", pty: pty, clock: fakeclock.New(time.Now()), config: cfg}
+
+	pty.AddResponse("LANG=C.UTF-8\nSSH_TTY=/dev/pts/7\nAPI_KEY=k\n").SetReadError(io.EOF)
+	sess.captureEnvAndPTY()
+	if len(sess.EnvVars) != 1 || sess.EnvVars["LANG"] != "C.UTF-8" {
+		t.Errorf("EnvVars = %v, want LANG only", sess.EnvVars)
+	}
+	if sess.PTYName != "7" {
+		t.Errorf("PTYName = %q, want 7 from the unfiltered SSH_TTY", sess.PTYName)
+	}
+}
@@ -0,0 +1,168 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// envNameRe matches the environment variable names that can be exported
+// into a no-PTY command.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// noPTYSkipEnv are variables of the session's interactive shell that do not
+// belong in a command on another channel.
+var noPTYSkipEnv = map[string]bool{
+	"SSH_TTY": true, "SSH_CLIENT": true, "SSH_CONNECTION": true,
+	"TERM": true, "PWD": true, "OLDPWD": true, "SHLVL": true, "_": true,
+	"COLUMNS": true, "LINES": true,
+}
+
+// execNoPTYLocked runs command on its own SSH exec channel (see
+// ExecOptions.NoPTY). It starts in the session's cwd with its environment
+// variables, but cannot change either. Caller must hold s.mu.
+func (s *Session) execNoPTYLocked(command string, timeout time.Duration) (*ExecResult, error) {
+	run := s.execChannel
+	if run == nil {
+		if s.sshClient == nil {
+			return nil, fmt.Errorf("SSH client not initialized")
+		}
+		run = s.sshClient.Exec
+	}
+
+	s.State = StateRunning
+	s.LastUsed = s.clock.Now()
+	defer func() { s.State = StateIdle }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out := s.newNoPTYOutput(cancel)
+	code, err := run(ctx, s.noPTYCommand(command), out.writer(&out.stdout), out.writer(&out.stderr))
+	result := &ExecResult{
+		Stdout: strings.TrimRight(out.stdout.String(), "\n"),
+		Stderr: strings.TrimRight(out.stderr.String(), "\n"),
+		Cwd:    s.Cwd,
+	}
+	if out.runaway {
+		slog.Warn("runaway no_pty output, command stopped",
+			slog.String("session_id", s.ID),
+			slog.Int64("total_bytes", out.total),
+			slog.Float64("bytes_per_sec", out.rate),
+		)
+		result.Status = "runaway_output"
+		result.Stdout = truncateSample(result.Stdout, s.runawaySampleBytes())
+		result.Stderr = truncateSample(result.Stderr, s.runawaySampleBytes())
+		result.TotalBytes = int(out.total)
+		result.Warning = s.runawayWarning(out.rate)
+		return result, nil
+	}
+	if out.dropped > 0 {
+		result.Truncated = true
+		result.TotalBytes = int(out.total)
+		result.Warning = fmt.Sprintf("output beyond security.max_output_bytes_per_session was dropped (%d bytes)", out.dropped)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("no_pty command timed out",
+			slog.String("session_id", s.ID),
+			slog.Duration("timeout", timeout),
+		)
+		result.Status = "timeout"
+		result.Hint = "The command's channel was closed; stdout and stderr hold its output so far."
+		return result, nil
+	}
+	if err != nil {
+		return nil, s.connectionError(fmt.Errorf("no_pty exec: %w", err))
+	}
+	result.Status = "completed"
+	result.ExitCode = &code
+	return result, nil
+}
+
+// noPTYOutput collects the stdout and stderr of a no-PTY command, which the
+// channel writes from separate goroutines. Like PTY output it is watched by
+// the runaway guard, which stops the command, and it keeps no more than the
+// session's output quota has left.
+type noPTYOutput struct {
+	mu     sync.Mutex
+	guard  *runawayGuard
+	now    func() time.Time
+	cancel context.CancelFunc
+	room   int64 // bytes still kept; -1 = no quota
+
+	stdout, stderr bytes.Buffer
+	total          int64 // bytes the command wrote
+	dropped        int64 // bytes not kept for the quota
+	runaway        bool
+	rate           float64
+}
+
+func (s *Session) newNoPTYOutput(cancel context.CancelFunc) *noPTYOutput {
+	out := &noPTYOutput{guard: s.newRunawayGuard(), now: s.clock.Now, cancel: cancel, room: -1}
+	if _, maxBytes := s.quotaLimits(); maxBytes > 0 {
+		out.room = max(maxBytes-s.outputBytes, 0)
+	}
+	return out
+}
+
+// writer returns an io.Writer that adds to buf.
+func (o *noPTYOutput) writer(buf *bytes.Buffer) io.Writer {
+	return writerFunc(func(p []byte) (int, error) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		o.total += int64(len(p))
+		if o.runaway {
+			return len(p), nil
+		}
+		if rate, runaway := o.guard.observe(o.now(), len(p)); runaway {
+			o.runaway, o.rate = true, rate
+			o.cancel()
+		}
+		keep := p
+		if o.room >= 0 {
+			if int64(len(keep)) > o.room {
+				keep = p[:o.room]
+			}
+			o.room -= int64(len(keep))
+			o.dropped += int64(len(p) - len(keep))
+		}
+		buf.Write(keep)
+		return len(p), nil
+	})
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+// noPTYCommand builds the remote command line of a no-PTY command: bash
+// running it in the session's cwd with its exported variables and the
+// server's command_wrapper.
+func (s *Session) noPTYCommand(command string) string {
+	var script strings.Builder
+	names := make([]string, 0, len(s.EnvVars))
+	for name := range s.EnvVars {
+		if envNameRe.MatchString(name) && !noPTYSkipEnv[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Fprintf(&script, "export %s=%s; ", name, shellQuote(s.EnvVars[name]))
+	}
+	if strings.HasPrefix(s.Cwd, "/") {
+		fmt.Fprintf(&script, "cd %s && ", shellQuote(s.Cwd))
+	}
+	script.WriteString(s.applyCommandWrapper(command))
+	return "bash -c " + shellQuote(script.String())
+}
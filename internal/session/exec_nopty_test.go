@@ -0,0 +1,182 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+// newNoPTYSession returns an initialized SSH session whose exec channel
+// calls run. pty records what reaches the session's PTY.
+func newNoPTYSession(t *testing.T, run func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error)) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_nopty", "ssh", WithPTY(pty), WithSessionClock(fakeclock.New(time.Now())))
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.Host, sess.User = "web", "deploy"
+	sess.execChannel = run
+	return sess, pty
+}
+
+func TestExecNoPTY_SeparatesStreams(t *testing.T) {
+	var remote string
+	sess, pty := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		remote = command
+		io.WriteString(stdout, "built 3 targets\n")
+		io.WriteString(stderr, "warning: deprecated flag\n")
+		return 2, nil
+	})
+	sess.Cwd = "/srv/app's"
+	sess.EnvVars = map[string]string{"GOFLAGS": "-mod=vendor", "SSH_TTY": "/dev/pts/3", "BASH_FUNC_x%%": "() { :; }"}
+	pty.Reset()
+
+	result, err := sess.ExecWithOptions("make build", 5000, ExecOptions{NoPTY: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "completed" || result.ExitCode == nil || *result.ExitCode != 2 {
+		t.Errorf("status = %q, exit code = %v; want completed with 2", result.Status, result.ExitCode)
+	}
+	if result.Stdout != "built 3 targets" || result.Stderr != "warning: deprecated flag" {
+		t.Errorf("stdout = %q, stderr = %q", result.Stdout, result.Stderr)
+	}
+	if pty.Written() != "" {
+		t.Errorf("no_pty command reached the PTY: %q", pty.Written())
+	}
+
+	want := `bash -c 'export GOFLAGS='\''-mod=vendor'\''; cd '\''/srv/app'\''\'\'''\''s'\'' && make build'`
+	if remote != want {
+		t.Errorf("remote command =\n%s\nwant\n%s", remote, want)
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestExecNoPTY_Timeout(t *testing.T) {
+	sess, _ := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		io.WriteString(stdout, "partial\n")
+		<-ctx.Done()
+		return -1, ctx.Err()
+	})
+
+	result, err := sess.ExecWithOptions("sleep 60", 10, ExecOptions{NoPTY: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "timeout" || result.Stdout != "partial" || result.ExitCode != nil {
+		t.Errorf("result = %+v, want a timeout with the partial output", result)
+	}
+}
+
+func TestExecNoPTY_ChannelError(t *testing.T) {
+	sess, _ := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		return -1, errors.New("open failed: administratively prohibited")
+	})
+
+	_, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{NoPTY: true})
+	if err == nil || !strings.Contains(err.Error(), "administratively prohibited") {
+		t.Errorf("error = %v, want the channel error", err)
+	}
+}
+
+func TestExecNoPTY_CommandWrapper(t *testing.T) {
+	var remote string
+	sess, _ := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		remote = command
+		return 0, nil
+	})
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{Name: "web", CommandWrapper: "nice -n 19 {{cmd}}"}}
+	sess.config = cfg
+
+	if _, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{NoPTY: true}); err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if !strings.Contains(remote, "nice -n 19 bash -c") {
+		t.Errorf("remote command %q does not apply command_wrapper", remote)
+	}
+}
+
+func TestExecNoPTY_IgnoredForLocalSessions(t *testing.T) {
+	called := false
+	pty := fakepty.New()
+	sess := NewSession("sess_local", "local", WithPTY(pty), WithSessionClock(fakeclock.New(time.Now())))
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.execChannel = func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		called = true
+		return 0, nil
+	}
+	pty.SetResponder(func(written string) string {
+		if m := wrapTestMarker.FindStringSubmatch(written); m != nil {
+			return buildCommandOutput(m[1], "ok", 0)
+		}
+		return ""
+	})
+
+	result, err := sess.ExecWithOptions("echo ok", 5000, ExecOptions{NoPTY: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if called || result.Stdout != "ok" {
+		t.Errorf("exec channel called = %v, stdout = %q; want the PTY path", called, result.Stdout)
+	}
+}
+
+func TestExecNoPTY_RunawayOutput(t *testing.T) {
+	var clk *fakeclock.Clock
+	sess, _ := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		for ctx.Err() == nil {
+			stdout.Write([]byte(strings.Repeat("y\n", 512)))
+			clk.Advance(100 * time.Millisecond)
+		}
+		return -1, ctx.Err()
+	})
+	clk = sess.clock.(*fakeclock.Clock)
+	cfg := config.DefaultConfig()
+	cfg.Output.RunawayBytesPerSec = 1024
+	cfg.Output.RunawayWindow = time.Second
+	cfg.Output.RunawaySampleBytes = 16
+	sess.config = cfg
+
+	result, err := sess.ExecWithOptions("yes", 5000, ExecOptions{NoPTY: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "runaway_output" || len(result.Stdout) > 16 || result.TotalBytes < 1024 {
+		t.Errorf("status = %q, stdout = %d bytes, total = %d; want runaway_output with a sample", result.Status, len(result.Stdout), result.TotalBytes)
+	}
+}
+
+func TestExecNoPTY_OutputCappedByQuota(t *testing.T) {
+	sess, _ := newNoPTYSession(t, func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+		io.WriteString(stdout, "0123456789")
+		io.WriteString(stderr, "abcdefghij")
+		return 0, nil
+	})
+	cfg := config.DefaultConfig()
+	cfg.Security.MaxOutputBytesPerSession = 14
+	sess.config = cfg
+
+	result, err := sess.ExecWithOptions("cat big", 5000, ExecOptions{NoPTY: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Stdout != "0123456789" || result.Stderr != "abcd" {
+		t.Errorf("stdout = %q, stderr = %q; want output cut at the quota", result.Stdout, result.Stderr)
+	}
+	if result.Status != "completed" || !result.Truncated || result.TotalBytes != 20 {
+		t.Errorf("result = %+v, want completed and truncated from 20 bytes", result)
+	}
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// errConnectionLost is returned by the read loop when auto-reconnect is
+// enabled and the SSH connection drops while a command is running.
+var errConnectionLost = errors.New("connection lost during command")
+
+// ExecOptions are per-call options for ExecWithOptions.
+type ExecOptions struct {
+	// AutoReconnect reconnects an SSH session whose connection drops while
+	// the command runs, restoring cwd and environment variables.
+	AutoReconnect bool
+	// Idempotent marks the command as safe to run again: after an
+	// auto-reconnect it is re-run once instead of returning connection_lost.
+	Idempotent bool
+	// Shell runs the command as `<Shell> -c '<command>'` (e.g. "zsh")
+	// instead of directly. Empty uses the default wrapping.
+	Shell string
+	// CollapseProgress keeps only the final state of lines redrawn with \r
+	// (progress bars) instead of concatenating every redraw.
+	CollapseProgress bool
+	// Direct runs the command in the session's shell itself instead of a
+	// `bash -c` child, so it sees and changes shell state such as the job
+	// table. Meant for builtins like jobs and kill %N; command_wrapper and
+	// Shell are not applied.
+	Direct bool
+	// KillOnTimeout makes sure a command that exceeds its timeout is
+	// stopped: after interrupting it the shell is checked, and the kill
+	// escalated, until it answers again. The result status is then
+	// "timeout_killed" instead of "timeout".
+	KillOnTimeout bool
+	// NoPTY runs the command of an SSH session on its own exec channel
+	// instead of the session's PTY, so stdout and stderr are captured
+	// separately and the exit code is the channel's exit status. Local and
+	// container sessions ignore it.
+	NoPTY bool
+	// OnOutput, if set, receives the command's output while it runs, in
+	// complete lines at most every streamInterval. The result still holds
+	// all of it. It is called with the session locked and must not call
+	// back into the session. Raw mode and NoPTY commands do not stream.
+	OnOutput func(chunk string)
+	// Limits runs the command in a subshell that applies them with ulimit
+	// first. A command stopped by one reports status "limit_exceeded".
+	// They cannot be combined with Direct, which exists to run in the
+	// session's shell itself.
+	Limits *ResourceLimits
+	// Uncharged keeps the command out of the session's quotas. It is for
+	// probes the server runs on its own, not for commands a client asked
+	// for.
+	Uncharged bool
+
+	// stdin, set by ExecWithStdin, is typed into the terminal once the
+	// command has started.
+	stdin *string
+}
+
+// recoverLostCommand handles a connection that dropped mid-command. It
+// reconnects and either replays an idempotent command once or returns a
+// connection_lost result with the output captured before the drop.
+// Must be called with s.mu held.
+func (s *Session) recoverLostCommand(command, cmdID string, timeout time.Duration, opts ExecOptions, cause error) (*ExecResult, error) {
+	startMarker := startMarkerPrefix + cmdID + markerSuffix
+	endMarker := endMarkerPrefix + cmdID + markerSuffix
+	asyncOutput, partial := s.parseMarkedOutput(s.outputBuffer.String(), startMarker, endMarker, command)
+
+	slog.Warn("SSH connection lost during command, reconnecting",
+		slog.String("session_id", s.ID),
+		slog.String("command_id", cmdID),
+		slog.Bool("idempotent", opts.Idempotent),
+		slog.String("error", cause.Error()),
+	)
+
+	s.State = StateIdle
+	lost := &ExecResult{
+		Status:      "connection_lost",
+		Stdout:      partial,
+		AsyncOutput: asyncOutput,
+		CommandID:   cmdID,
+		Cwd:         s.Cwd,
+		ErrorCode:   s.lostConnectionCode(cause, nil),
+	}
+
+	if err := s.reconnectSession(); err != nil {
+		lost.ErrorCode = s.lostConnectionCode(cause, err)
+		lost.Warning = "connection lost mid-command and reconnect failed: " + err.Error()
+		return lost, nil
+	}
+	lost.Reconnected = true
+
+	if !opts.Idempotent {
+		lost.Warning = "connection lost mid-command; session reconnected but the command may have partially run. Stdout holds the output captured before the drop."
+		return lost, nil
+	}
+
+	result, err := s.replayCommand(command, timeout)
+	if errors.Is(err, errConnectionLost) {
+		lost.Warning = "connection lost again while replaying the command"
+		s.State = StateIdle
+		return lost, nil
+	}
+	if result != nil {
+		result.Reconnected = true
+		result.Replayed = true
+	}
+	return result, err
+}
+
+// reconnectSession re-establishes the SSH connection, restoring cwd and
+// environment variables.
+func (s *Session) reconnectSession() error {
+	if s.reconnect != nil {
+		return s.reconnect()
+	}
+	return s.reconnectSSH()
+}
+
+// replayCommand runs command once more on the reconnected session. A second
+// drop is reported as errConnectionLost rather than retried.
+func (s *Session) replayCommand(command string, timeout time.Duration) (*ExecResult, error) {
+	s.State = StateRunning
+	s.outputBuffer.Reset()
+
+	cmdID := s.generateCommandID()
+	if err := s.writeCommandWithReconnect(s.buildWrappedCommand(command, cmdID)); err != nil {
+		return nil, err
+	}
+	s.applyMultilineDelay(command)
+
+	ctx, cancel := s.commandContext(timeout)
+	defer cancel()
+
+	slog.Info("replaying idempotent command after reconnect",
+		slog.String("session_id", s.ID),
+		slog.String("command_id", cmdID),
+	)
+	return s.readOutputWithMarkers(ctx, command, cmdID)
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+// newDroppingSSHSession returns an ssh-mode session whose PTY emits partial
+// output for command 01020304 and then reports EOF, as a dropped connection does.
+func newDroppingSSHSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_drop", "ssh",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.Cwd = "/srv/app"
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\nstep 1 done\n")
+	pty.SetReadError(io.EOF)
+	return sess, pty
+}
+
+func TestExecWithOptions_ConnectionLostNonIdempotent(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+
+	reconnects := 0
+	sess.reconnect = func() error {
+		reconnects++
+		sess.pty = fakepty.New()
+		return nil
+	}
+
+	result, err := sess.ExecWithOptions("./migrate.sh", 5000, ExecOptions{AutoReconnect: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "connection_lost" {
+		t.Fatalf("Status = %q, want connection_lost", result.Status)
+	}
+	if result.Stdout != "step 1 done" {
+		t.Errorf("Stdout = %q, want partial output", result.Stdout)
+	}
+	if !result.Reconnected || result.Replayed {
+		t.Errorf("Reconnected = %v, Replayed = %v; want reconnected without replay", result.Reconnected, result.Replayed)
+	}
+	if reconnects != 1 {
+		t.Errorf("reconnects = %d, want 1", reconnects)
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestExecWithOptions_ReplaysIdempotentCommand(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+
+	replayPTY := fakepty.New()
+	replayPTY.AddResponse(fmt.Sprintf("%s\nok\n%s0\n",
+		startMarkerPrefix+"05060708"+markerSuffix, endMarkerPrefix+"05060708"+markerSuffix))
+	replayPTY.AddResponse("/srv/app\n")
+	sess.reconnect = func() error {
+		sess.pty = replayPTY
+		return nil
+	}
+
+	result, err := sess.ExecWithOptions("kubectl get pods", 5000, ExecOptions{AutoReconnect: true, Idempotent: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q, want completed", result.Status)
+	}
+	if result.Stdout != "ok" {
+		t.Errorf("Stdout = %q, want replayed output", result.Stdout)
+	}
+	if !result.Reconnected || !result.Replayed {
+		t.Errorf("Reconnected = %v, Replayed = %v; want both", result.Reconnected, result.Replayed)
+	}
+	if !strings.Contains(replayPTY.Written(), "kubectl get pods") {
+		t.Error("expected the command to be re-sent after reconnect")
+	}
+}
+
+func TestExecWithOptions_ReplayDropsAgain(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+
+	replayPTY := fakepty.New().SetReadError(io.EOF)
+	sess.reconnect = func() error {
+		sess.pty = replayPTY
+		return nil
+	}
+
+	result, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{AutoReconnect: true, Idempotent: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "connection_lost" || result.Replayed {
+		t.Errorf("Status = %q, Replayed = %v; want connection_lost without a second replay", result.Status, result.Replayed)
+	}
+}
+
+func TestExecWithOptions_ReconnectFails(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.reconnect = func() error { return fmt.Errorf("host unreachable") }
+
+	result, err := sess.ExecWithOptions("uptime", 5000, ExecOptions{AutoReconnect: true, Idempotent: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "connection_lost" || result.Reconnected {
+		t.Errorf("Status = %q, Reconnected = %v; want connection_lost, not reconnected", result.Status, result.Reconnected)
+	}
+	if !strings.Contains(result.Warning, "host unreachable") {
+		t.Errorf("Warning = %q, want reconnect error", result.Warning)
+	}
+}
+
+func TestExecWithOptions_LocalSessionIgnoresAutoReconnect(t *testing.T) {
+	sess, pty := newStdinSession(t)
+	pty.AddResponse(buildCommandOutput("01020304", "hi", 0))
+
+	result, err := sess.ExecWithOptions("echo hi", 5000, ExecOptions{AutoReconnect: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Errorf("Status = %q, want completed", result.Status)
+	}
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"strings"
+	"time"
 
 	"github.com/acolita/claude-shell-mcp/internal/prompt"
 )
@@ -12,6 +13,11 @@ type execContext struct {
 	startMarker string
 	endMarker   string
 	command     string
+
+	// streamed is how much of the output was passed to onOutput, and
+	// streamedAt when it last was.
+	streamed   int
+	streamedAt time.Time
 }
 
 // newExecContext creates a new execution context.
@@ -59,7 +65,7 @@ func (s *Session) buildPeakTTYResult(ctx *execContext, output string) *ExecResul
 		CommandID:     ctx.commandID,
 		PromptType:    "interactive",
 		PromptText:    "",
-		ContextBuffer: stripANSI(strings.ReplaceAll(output, "\x00", "")),
+		ContextBuffer: StripANSI(strings.ReplaceAll(output, "\x00", "")),
 		Hint:          hintPeakTTYWaiting,
 	}
 }
@@ -0,0 +1,108 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestWrapInShell(t *testing.T) {
+	got, err := WrapInShell("zsh", `echo 'hi' "$HOME"`)
+	if err != nil {
+		t.Fatalf("WrapInShell error: %v", err)
+	}
+	want := `zsh -c 'echo '\''hi'\'' "$HOME"'`
+	if got != want {
+		t.Errorf("WrapInShell = %q, want %q", got, want)
+	}
+}
+
+func TestWrapInShell_RejectsUnsafeNames(t *testing.T) {
+	for _, shell := range []string{"bash -x", "sh;rm", "$(id)", "zsh'", "-i", "python3", "bin/sh", "/bin/../tmp/sh", "/bin/", "/usr/bin/python3", "/usr/bin/perl", "/bin/busybox", "/usr/local/bin/bash-5.2"} {
+		if _, err := WrapInShell(shell, "true"); err == nil {
+			t.Errorf("WrapInShell(%q) should fail", shell)
+		}
+	}
+	for _, shell := range []string{"dash", "/bin/sh", "/usr/local/bin/bash", "/home/dev/.nix-profile/bin/zsh"} {
+		if _, err := WrapInShell(shell, "true"); err != nil {
+			t.Errorf("WrapInShell(%q) error: %v", shell, err)
+		}
+	}
+}
+
+// TestWrapInShell_ComposesWithMarkerQuoting runs the fully wrapped command
+// through a real shell to check both quoting layers round-trip.
+func TestWrapInShell_ComposesWithMarkerQuoting(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+
+	command := `v='a b'; printf '%s|%s\n' "$v" "it's \"quoted\""`
+	wrapped, err := WrapInShell("sh", command)
+	if err != nil {
+		t.Fatalf("WrapInShell error: %v", err)
+	}
+	full := (&Session{}).buildWrappedCommand(wrapped, "abc12345")
+
+	out, err := exec.Command("bash", "-c", full).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running wrapped command: %v\n%s", err, out)
+	}
+	want := "___CMD_START_abc12345___\na b|it's \"quoted\"\n___CMD_END_abc12345___0\n"
+	if string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestExecWithOptions_Shell(t *testing.T) {
+	pty := fakepty.New()
+	sess := NewSession("sess_shell", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	pty.AddResponse(buildCommandOutput("01020304", "5.9", 0))
+
+	result, err := sess.ExecWithOptions("echo $ZSH_VERSION", 5000, ExecOptions{Shell: "zsh"})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Errorf("Status = %q, want completed", result.Status)
+	}
+	if !strings.Contains(pty.Written(), `zsh -c '\''echo $ZSH_VERSION'\''`) {
+		t.Errorf("written = %q, want command wrapped in zsh -c", pty.Written())
+	}
+}
+
+func TestExecWithOptions_InvalidShell(t *testing.T) {
+	pty := fakepty.New()
+	sess := NewSession("sess_shell", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	if _, err := sess.ExecWithOptions("true", 5000, ExecOptions{Shell: "sh; reboot"}); err == nil {
+		t.Fatal("expected error for invalid shell")
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+	if pty.Written() != "" {
+		t.Errorf("nothing should be written, got %q", pty.Written())
+	}
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxStdinLineBytes is the longest line the terminal line discipline accepts
+// in canonical mode; longer lines would be silently truncated.
+const maxStdinLineBytes = 4095
+
+// eofChar is the terminal EOF character (Ctrl-D).
+const eofChar = "\x04"
+
+// ValidateStdin checks that content can be typed into a terminal unchanged:
+// no control characters other than tab and newline, and no line longer than
+// the canonical-mode line limit.
+func ValidateStdin(content string) error {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	for i, line := range strings.Split(content, "\n") {
+		if len(line) > maxStdinLineBytes {
+			return fmt.Errorf("stdin line %d is %d bytes, exceeds terminal limit of %d bytes (use shell_file_put for large lines)", i+1, len(line), maxStdinLineBytes)
+		}
+		for _, c := range line {
+			if (c < 0x20 && c != '\t') || c == 0x7f {
+				return fmt.Errorf("stdin line %d contains control character 0x%02x that the terminal would interpret", i+1, c)
+			}
+		}
+	}
+	return nil
+}
+
+// ExecWithStdin runs command and types stdin into the terminal once the
+// command has started, followed by Ctrl-D so the command sees end of input.
+// The terminal echo of stdin is removed from the returned output.
+//
+// Content is only sent after the start marker appears; if the command
+// finishes before that, stdin is never written. Otherwise it runs like Exec.
+func (s *Session) ExecWithStdin(command, stdin string, timeoutMs int) (*ExecResult, error) {
+	if err := ValidateStdin(stdin); err != nil {
+		return nil, err
+	}
+	return s.ExecWithOptions(command, timeoutMs, ExecOptions{stdin: &stdin})
+}
+
+// readCommandOutput reads the output of the command cmdID, first typing
+// opts.stdin once it has started. Must be called with s.mu held.
+func (s *Session) readCommandOutput(ctx context.Context, command, cmdID string, opts ExecOptions) (*ExecResult, error) {
+	if opts.stdin == nil {
+		return s.readOutputWithMarkers(ctx, command, cmdID)
+	}
+
+	execCtx := newExecContext(cmdID, startMarkerPrefix+cmdID+markerSuffix, endMarkerPrefix+cmdID+markerSuffix, command)
+	if result, err := s.waitForStartMarker(ctx, execCtx); result != nil || err != nil {
+		return result, err
+	}
+	if _, err := s.pty.WriteString(terminalStdin(*opts.stdin) + eofChar); err != nil {
+		s.State = StateIdle
+		return nil, fmt.Errorf("write stdin: %w", err)
+	}
+
+	result, err := s.readOutputWithMarkers(ctx, command, cmdID)
+	if result != nil {
+		result.Stdout = stripStdinEcho(result.Stdout, *opts.stdin)
+	}
+	return result, err
+}
+
+// waitForStartMarker reads output until the command's start marker is on its
+// own line. A non-nil result means the command already finished, prompted or
+// timed out before it could be fed input.
+func (s *Session) waitForStartMarker(ctx context.Context, execCtx *execContext) (*ExecResult, error) {
+	buf := make([]byte, s.readBufferSize())
+	stallCount := 0
+	stallThreshold := s.stallPolls()
+
+	for {
+		output := strings.ReplaceAll(stripCommandEcho(s.outputBuffer.String(), execCtx.startMarker, execCtx.endMarker), "\r\n", "\n")
+		if findMarkerOnOwnLine(output, execCtx.startMarker) != -1 {
+			return nil, nil
+		}
+
+		result, newStall, err := s.processMarkedRead(ctx, buf, execCtx, stallCount, stallThreshold)
+		stallCount = newStall
+		if result != nil || err != nil {
+			return result, err
+		}
+	}
+}
+
+// terminalStdin normalizes content for typing into the terminal. A trailing
+// newline is required so the following Ctrl-D is read as end of input rather
+// than flushing a partial line.
+func terminalStdin(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
+// stripStdinEcho removes the terminal echo of stdin from the start of output.
+func stripStdinEcho(output, stdin string) string {
+	echo := strings.TrimSuffix(terminalStdin(stdin), "\n")
+	if echo == "" {
+		return output
+	}
+	if output == echo {
+		return ""
+	}
+	return strings.TrimPrefix(output, echo+"\n")
+}
@@ -0,0 +1,142 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newStdinSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_stdin", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestExecWithStdin_WritesContentAfterStartAndStripsEcho(t *testing.T) {
+	sess, pty := newStdinSession(t)
+
+	startMarker := startMarkerPrefix + "01020304" + markerSuffix
+	endMarker := endMarkerPrefix + "01020304" + markerSuffix
+	pty.AddResponse(startMarker + "\n")
+	pty.AddResponse("b\r\na\r\na\r\nb\r\n" + endMarker + "0\n")
+
+	result, err := sess.ExecWithStdin("sort", "b\na", 5000)
+	if err != nil {
+		t.Fatalf("ExecWithStdin error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q, want completed", result.Status)
+	}
+	if result.Stdout != "a\nb" {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, "a\nb")
+	}
+
+	written := pty.Written()
+	cmdIdx := strings.Index(written, "sort")
+	stdinIdx := strings.Index(written, "b\na\n\x04")
+	if cmdIdx == -1 || stdinIdx == -1 || stdinIdx < cmdIdx {
+		t.Errorf("expected command then stdin with Ctrl-D, got %q", written)
+	}
+}
+
+func TestExecWithStdin_CommandFinishesBeforeStart(t *testing.T) {
+	sess, pty := newStdinSession(t)
+
+	pty.AddResponse(buildCommandOutput("01020304", "nope: command not found", 127))
+
+	result, err := sess.ExecWithStdin("nope", "data", 5000)
+	if err != nil {
+		t.Fatalf("ExecWithStdin error: %v", err)
+	}
+	if result.ExitCode == nil || *result.ExitCode != 127 {
+		t.Errorf("ExitCode = %v, want 127", result.ExitCode)
+	}
+	if strings.Contains(pty.Written(), "data") {
+		t.Error("stdin must not be written when the command already finished")
+	}
+}
+
+func TestExecWithStdin_StartupOutputBecomesAsyncOutput(t *testing.T) {
+	sess, pty := newStdinSession(t)
+	sess.startupOutput = "Welcome to slowhost\n"
+
+	startMarker := startMarkerPrefix + "01020304" + markerSuffix
+	endMarker := endMarkerPrefix + "01020304" + markerSuffix
+	pty.AddResponse(startMarker + "\n")
+	pty.AddResponse("data\r\ndata\r\n" + endMarker + "0\n")
+
+	result, err := sess.ExecWithStdin("cat", "data", 5000)
+	if err != nil {
+		t.Fatalf("ExecWithStdin error: %v", err)
+	}
+	if result.Stdout != "data" {
+		t.Errorf("Stdout = %q, want data", result.Stdout)
+	}
+	if !strings.Contains(result.AsyncOutput, "Welcome to slowhost") {
+		t.Errorf("AsyncOutput = %q, want the login output", result.AsyncOutput)
+	}
+}
+
+func TestExecWithStdin_RawMode(t *testing.T) {
+	sess, pty := newStdinSession(t)
+	sess.RawMode = true
+	before := pty.Written()
+
+	if _, err := sess.ExecWithStdin("cat", "data", 5000); err == nil || !strings.Contains(err.Error(), "raw mode") {
+		t.Errorf("ExecWithStdin error = %v, want a raw mode error", err)
+	}
+	if pty.Written() != before {
+		t.Errorf("written = %q, want nothing sent", strings.TrimPrefix(pty.Written(), before))
+	}
+}
+
+func TestValidateStdin(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"plain text", "hello\nworld\n", false},
+		{"tabs and CRLF", "a\tb\r\nc", false},
+		{"empty", "", false},
+		{"ctrl-c", "abc\x03", true},
+		{"ctrl-d", "abc\x04def", true},
+		{"bare CR", "abc\rdef", true},
+		{"line too long", strings.Repeat("x", maxStdinLineBytes+1), true},
+		{"line at limit", strings.Repeat("x", maxStdinLineBytes), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStdin(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateStdin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStripStdinEcho(t *testing.T) {
+	if got := stripStdinEcho("x\ny\nout", "x\ny"); got != "out" {
+		t.Errorf("got %q, want %q", got, "out")
+	}
+	if got := stripStdinEcho("x", "x\n"); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+	if got := stripStdinEcho("other\nx", "x"); got != "other\nx" {
+		t.Errorf("got %q, output without leading echo must be unchanged", got)
+	}
+}
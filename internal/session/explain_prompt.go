@@ -0,0 +1,68 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/prompt"
+)
+
+// PromptMatch is one prompt pattern that matches a sample.
+type PromptMatch struct {
+	Pattern     string `json:"pattern"`
+	Regex       string `json:"regex"`
+	PromptType  string `json:"prompt_type"`
+	MaskInput   bool   `json:"mask_input"`
+	Priority    int    `json:"priority,omitempty"`
+	MatchedText string `json:"matched_text"`
+}
+
+// PromptExplanation describes how the session's prompt detector classifies
+// an output sample. Match is the pattern Exec would act on; Others are
+// lower-priority patterns that match as well.
+type PromptExplanation struct {
+	Matched bool          `json:"matched"`
+	Match   *PromptMatch  `json:"match,omitempty"`
+	Others  []PromptMatch `json:"also_matched,omitempty"`
+}
+
+// ExplainPrompt runs sample through the session's prompt detector, with the
+// same ANSI stripping Exec applies, without changing session state. It does
+// not wait for a running command.
+func (s *Session) ExplainPrompt(sample string) (*PromptExplanation, error) {
+	detector := s.promptDetector
+	if detector == nil {
+		return nil, fmt.Errorf("session %s has no prompt detector (not initialized)", s.ID)
+	}
+
+	detections := detector.DetectAll(StripANSI(strings.ReplaceAll(sample, "\x00", "")))
+	explanation := &PromptExplanation{Matched: len(detections) > 0}
+	for i, d := range detections {
+		match := PromptMatch{
+			Pattern:     d.Pattern.Name,
+			PromptType:  string(d.Pattern.Type),
+			MaskInput:   d.Pattern.MaskInput,
+			Priority:    d.Pattern.Priority,
+			MatchedText: d.MatchedText,
+		}
+		if d.Pattern.Regex != nil {
+			match.Regex = d.Pattern.Regex.String()
+		}
+		if i == 0 {
+			explanation.Match = &match
+			continue
+		}
+		explanation.Others = append(explanation.Others, match)
+	}
+	return explanation, nil
+}
+
+// PromptPatterns returns the patterns of the session's prompt detector in
+// the order Exec tries them.
+func (s *Session) PromptPatterns() ([]prompt.PatternEntry, error) {
+	detector := s.promptDetector
+	if detector == nil {
+		return nil, fmt.Errorf("session %s has no prompt detector (not initialized)", s.ID)
+	}
+	return detector.Patterns(), nil
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func newExplainSession(t *testing.T, patterns ...config.PatternConfig) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.CustomPatterns = patterns
+	sess := NewSession("sess_explain", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestExplainPrompt_BuiltinPassword(t *testing.T) {
+	sess, pty := newExplainSession(t)
+
+	got, err := sess.ExplainPrompt("\x1b[1m[sudo] password for deploy: \x1b[0m")
+	if err != nil {
+		t.Fatalf("ExplainPrompt() error = %v", err)
+	}
+	if !got.Matched || got.Match == nil {
+		t.Fatalf("ExplainPrompt() = %+v, want a match", got)
+	}
+	if got.Match.PromptType != "password" || !got.Match.MaskInput {
+		t.Errorf("Match = %+v, want a masked password prompt", got.Match)
+	}
+	if strings.Contains(got.Match.MatchedText, "\x1b") {
+		t.Errorf("MatchedText = %q, want ANSI stripped", got.Match.MatchedText)
+	}
+	if pty.Written() != "" || sess.State != StateIdle {
+		t.Error("ExplainPrompt() changed the session")
+	}
+}
+
+func TestExplainPrompt_CustomPatternWinsOverBuiltin(t *testing.T) {
+	sess, _ := newExplainSession(t, config.PatternConfig{
+		Name: "vault_password", Regex: `Vault password:\s*$`, Type: "password", MaskInput: true, Priority: 10,
+	})
+
+	got, err := sess.ExplainPrompt("Vault password: ")
+	if err != nil {
+		t.Fatalf("ExplainPrompt() error = %v", err)
+	}
+	if got.Match == nil || got.Match.Pattern != "vault_password" || got.Match.Priority != 10 {
+		t.Fatalf("Match = %+v, want vault_password", got.Match)
+	}
+	if len(got.Others) == 0 {
+		t.Error("Others is empty, want the built-in password pattern that also matches")
+	}
+}
+
+func TestExplainPrompt_NoMatch(t *testing.T) {
+	sess, _ := newExplainSession(t)
+
+	got, err := sess.ExplainPrompt("build finished in 3.2s\n")
+	if err != nil {
+		t.Fatalf("ExplainPrompt() error = %v", err)
+	}
+	if got.Matched || got.Match != nil {
+		t.Errorf("ExplainPrompt() = %+v, want no match", got)
+	}
+}
+
+func TestExplainPrompt_NotInitialized(t *testing.T) {
+	if _, err := NewSession("sess_new", "local").ExplainPrompt("Password:"); err == nil {
+		t.Error("expected error before Initialize")
+	}
+}
+
+func TestPromptPatterns(t *testing.T) {
+	sess, _ := newExplainSession(t, config.PatternConfig{Name: "vault_token", Regex: `Vault token:\s*$`, Type: "password", MaskInput: true, Priority: 5})
+
+	patterns, err := sess.PromptPatterns()
+	if err != nil {
+		t.Fatalf("PromptPatterns() error = %v", err)
+	}
+	if len(patterns) < 2 || patterns[0].Name != "vault_token" || !patterns[0].Custom {
+		t.Fatalf("patterns = %+v, want vault_token first", patterns)
+	}
+
+	if _, err := NewSession("sess_new", "local").PromptPatterns(); err == nil {
+		t.Error("PromptPatterns() succeeded on an uninitialized session")
+	}
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// defaultInteractiveExitPatterns match lines pagers and editors leave on the
+// terminal when they exit: less's ":" prompt and "(END)" marker, its and
+// man's status lines, more's "--More--" and vim's "~" filler and
+// hit-enter prompt.
+var defaultInteractiveExitPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^:$`),
+	regexp.MustCompile(`^\(END\)$`),
+	regexp.MustCompile(`^~+$`),
+	regexp.MustCompile(`^--More--`),
+	regexp.MustCompile(`(?i)^lines \d+-\d+(/\d+)?`),
+	regexp.MustCompile(`^Manual page .* line \d+`),
+	regexp.MustCompile(`^Press ENTER or type command to continue`),
+	regexp.MustCompile(`^HIT RETURN`),
+}
+
+// terminalControlPattern matches the escape sequences full-screen programs
+// send on exit (leave the alternate screen, reset keypad and cursor modes,
+// clear the line) and bare carriage returns.
+var terminalControlPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]|\x1b\][^\x07]*\x07|\x1b[()][0-9A-Za-z]|\x1b[=>78]|\r`)
+
+// interactivePromptTypes are the prompt types of full-screen programs whose
+// exit can leave artifacts behind.
+var interactivePromptTypes = map[string]bool{
+	"pager":       true,
+	"editor":      true,
+	"interactive": true,
+}
+
+// trimInteractiveExit strips pager/editor exit artifacts from the start of
+// result's output when an interactive program was open before it, and
+// tracks whether one is open now. Artifacts are trimmed until a command
+// completes, since a pager may be quit with shell_send_raw or shell_interrupt
+// and leave its residue for the next shell_exec.
+func (s *Session) trimInteractiveExit(result *ExecResult) {
+	if result == nil {
+		return
+	}
+	if s.interactiveOpen {
+		patterns := s.interactiveExitPatterns()
+		result.AsyncOutput = trimLeadingArtifacts(result.AsyncOutput, patterns)
+		if result.AsyncOutput == "" {
+			result.Stdout = trimLeadingArtifacts(result.Stdout, patterns)
+		}
+	}
+	switch {
+	case result.Status == "awaiting_input" && interactivePromptTypes[result.PromptType]:
+		s.interactiveOpen = true
+	case result.Status == "completed":
+		s.interactiveOpen = false
+	}
+}
+
+// interactiveExitPatterns returns the built-in artifact patterns plus those
+// from config.Output.InteractiveExitPatterns.
+func (s *Session) interactiveExitPatterns() []*regexp.Regexp {
+	if s.config == nil || len(s.config.Output.InteractiveExitPatterns) == 0 {
+		return defaultInteractiveExitPatterns
+	}
+	patterns := append([]*regexp.Regexp{}, defaultInteractiveExitPatterns...)
+	for _, expr := range s.config.Output.InteractiveExitPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			slog.Warn("ignoring invalid interactive exit pattern", slog.String("pattern", expr), slog.String("error", err.Error()))
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// trimLeadingArtifacts drops leading lines of output that are blank or match
+// one of patterns once terminal control sequences are removed. The first
+// other line and everything after it are kept unchanged.
+func trimLeadingArtifacts(output string, patterns []*regexp.Regexp) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if !isInteractiveArtifact(line, patterns) {
+			return strings.Join(lines[i:], "\n")
+		}
+	}
+	return ""
+}
+
+func isInteractiveArtifact(line string, patterns []*regexp.Regexp) bool {
+	text := strings.TrimSpace(terminalControlPattern.ReplaceAllString(line, ""))
+	if text == "" {
+		return true
+	}
+	for _, re := range patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestTrimLeadingArtifacts(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"less quit", "\x1b[?1049l\r\x1b[K:\r\n\x1b[?1l\x1b>\nfile.txt", "file.txt"},
+		{"end marker", "(END)\nlines 1-24/80 (END)\nnext output", "next output"},
+		{"vim filler", "~\n~\n~\nPress ENTER or type command to continue\nok", "ok"},
+		{"keeps later matches", "real line\n:\n~", "real line\n:\n~"},
+		{"only artifacts", "\x1b[?1049l\n(END)", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimLeadingArtifacts(tt.output, defaultInteractiveExitPatterns); got != tt.want {
+				t.Errorf("trimLeadingArtifacts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimInteractiveExit_OnlyAfterInteractivePrompt(t *testing.T) {
+	sess := NewSession("sess_pager", "local")
+
+	plain := &ExecResult{Status: "completed", Stdout: ":\nreal output"}
+	sess.trimInteractiveExit(plain)
+	if plain.Stdout != ":\nreal output" {
+		t.Errorf("Stdout = %q, want untouched without a pager before it", plain.Stdout)
+	}
+
+	sess.trimInteractiveExit(&ExecResult{Status: "awaiting_input", PromptType: "pager"})
+	if !sess.interactiveOpen {
+		t.Fatal("interactiveOpen = false after a pager prompt")
+	}
+
+	// Quit with shell_send_raw "q": nothing completed yet, keep trimming.
+	quit := &ExecResult{Status: "timeout", Stdout: "\x1b[?1049l(END)"}
+	sess.trimInteractiveExit(quit)
+	if quit.Stdout != "" || !sess.interactiveOpen {
+		t.Errorf("Stdout = %q, interactiveOpen = %v", quit.Stdout, sess.interactiveOpen)
+	}
+
+	next := &ExecResult{Status: "completed", AsyncOutput: "\x1b[?1l\x1b>\n:", Stdout: "abc123 fix bug"}
+	sess.trimInteractiveExit(next)
+	if next.AsyncOutput != "" || next.Stdout != "abc123 fix bug" {
+		t.Errorf("result = %q/%q, want artifacts trimmed", next.AsyncOutput, next.Stdout)
+	}
+	if sess.interactiveOpen {
+		t.Error("interactiveOpen still set after a completed command")
+	}
+}
+
+func TestTrimInteractiveExit_ConfigPatterns(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.InteractiveExitPatterns = []string{`^\[Press q to quit\]$`}
+	sess := NewSession("sess_pager", "local", WithConfig(cfg))
+	sess.interactiveOpen = true
+
+	result := &ExecResult{Status: "completed", Stdout: "[Press q to quit]\n(END)\ndone"}
+	sess.trimInteractiveExit(result)
+	if result.Stdout != "done" {
+		t.Errorf("Stdout = %q, want custom and built-in artifacts trimmed", result.Stdout)
+	}
+}
+
+func TestSession_Exec_TrimsPagerResidueFromAsyncOutput(t *testing.T) {
+	pty := fakepty.New()
+	sess := NewSession("sess_pager", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.interactiveOpen = true // git log was quit with shell_interrupt
+
+	pty.AddResponse("\x1b[?1049l\r\x1b[K:\r\n\x1b[?1l\x1b>\n" + buildCommandOutput("01020304", "main.go", 0))
+	result, err := sess.Exec("ls", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.AsyncOutput != "" || result.Stdout != "main.go" {
+		t.Errorf("result = %q/%q, want pager residue trimmed", result.AsyncOutput, result.Stdout)
+	}
+}
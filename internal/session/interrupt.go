@@ -0,0 +1,74 @@
+package session
+
+import (
+	"strings"
+	"time"
+)
+
+// InterruptResult is what Interrupt read from the session after Ctrl-C.
+type InterruptResult struct {
+	// Output is what the command printed while dying (e.g. a trap's
+	// cleanup messages), without the shell prompt that followed it.
+	Output string `json:"output"`
+	// PromptReturned tells whether the shell prompt came back within the
+	// grace period. When false the command may still be running.
+	PromptReturned bool `json:"prompt_returned"`
+	// GracePeriodMs is how long Interrupt was willing to wait.
+	GracePeriodMs int64 `json:"grace_period_ms"`
+}
+
+// interruptGracePeriod returns how long Interrupt reads after Ctrl-C (see
+// config.SessionConfig.InterruptGracePeriod).
+func (s *Session) interruptGracePeriod() time.Duration {
+	if s.config == nil {
+		return 0
+	}
+	return s.config.Session.InterruptGracePeriod
+}
+
+// readInterruptOutput reads the interrupted command's final output until
+// the grace period ends, the PTY goes quiet or the shell prompt is back.
+// Caller must hold s.mu.
+func (s *Session) readInterruptOutput(grace time.Duration) *InterruptResult {
+	result := &InterruptResult{GracePeriodMs: grace.Milliseconds()}
+	if grace <= 0 {
+		return result
+	}
+
+	buf := make([]byte, s.readBufferSize())
+	deadline := s.clock.Now().Add(grace)
+	var out strings.Builder
+
+	for {
+		s.pty.SetReadDeadline(deadline)
+		n, err := s.pty.Read(buf)
+		out.Write(buf[:n])
+		s.feedScreen(buf[:n])
+		if output, ok := s.trimInterruptPrompt(out.String()); ok {
+			result.Output = output
+			result.PromptReturned = true
+			return result
+		}
+		if err != nil || n == 0 || !s.clock.Now().Before(deadline) {
+			break
+		}
+	}
+
+	result.Output = strings.TrimSpace(StripANSI(s.normalizeLineEndings(out.String())))
+	return result
+}
+
+// trimInterruptPrompt reports whether output ends with the session's
+// normalized prompt and returns it cleaned and without that prompt. With
+// shell.normalize_prompt off the prompt is unknown and never matched.
+func (s *Session) trimInterruptPrompt(output string) (string, bool) {
+	if !s.normalizePrompt() {
+		return "", false
+	}
+	cleaned := StripANSI(s.normalizeLineEndings(output))
+	lines := strings.Split(cleaned, "\n")
+	if strings.TrimSpace(lines[len(lines)-1]) != "$" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.Join(lines[:len(lines)-1], "\n")), true
+}
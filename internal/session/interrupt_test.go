@@ -0,0 +1,125 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+// newRunningSession returns a session with a command running, interrupted
+// with the given grace period.
+func newRunningSession(pty *fakepty.PTY, grace time.Duration) *Session {
+	cfg := config.DefaultConfig()
+	cfg.Session.InterruptGracePeriod = grace
+	sess := NewSession("sess_int", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithConfig(cfg),
+	)
+	sess.State = StateRunning
+	return sess
+}
+
+func TestInterrupt_CapturesCleanupOutput(t *testing.T) {
+	// A command trapping SIGINT prints its cleanup messages, then the
+	// shell prompt returns.
+	pty := fakepty.New().SetInterruptResponse("^C\r\nremoving /tmp/build.lock\r\n\x1b[1mcleanup done\x1b[0m\r\n$ ")
+	sess := newRunningSession(pty, time.Second)
+
+	result, err := sess.Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if !pty.WasInterrupted() {
+		t.Error("Ctrl-C was not sent")
+	}
+	if want := "^C\nremoving /tmp/build.lock\ncleanup done"; result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+	if !result.PromptReturned || result.GracePeriodMs != 1000 {
+		t.Errorf("PromptReturned = %v, GracePeriodMs = %d, want true, 1000", result.PromptReturned, result.GracePeriodMs)
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestInterrupt_OutputAcrossReads(t *testing.T) {
+	pty := fakepty.New().AddResponses("^C\r\n", "flushing cache...\r\n", "done\r\n", "$ ")
+	sess := newRunningSession(pty, time.Second)
+
+	result, err := sess.Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if want := "^C\nflushing cache...\ndone"; result.Output != want || !result.PromptReturned {
+		t.Errorf("Output = %q, PromptReturned = %v, want %q, true", result.Output, result.PromptReturned, want)
+	}
+}
+
+func TestInterrupt_PromptDoesNotReturn(t *testing.T) {
+	pty := fakepty.New().SetInterruptResponse("^C\r\nstill shutting down\r\n")
+	sess := newRunningSession(pty, time.Second)
+
+	result, err := sess.Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if result.Output != "^C\nstill shutting down" || result.PromptReturned {
+		t.Errorf("Output = %q, PromptReturned = %v, want the output and false", result.Output, result.PromptReturned)
+	}
+}
+
+func TestInterrupt_NoGracePeriod(t *testing.T) {
+	pty := fakepty.New().SetInterruptResponse("^C\r\ncleanup done\r\n$ ")
+	sess := newRunningSession(pty, 0)
+
+	result, err := sess.Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if result.Output != "" || result.PromptReturned {
+		t.Errorf("Output = %q, PromptReturned = %v, want nothing read", result.Output, result.PromptReturned)
+	}
+	if len(pty.ReadSizes()) != 0 {
+		t.Errorf("PTY read %d times with no grace period", len(pty.ReadSizes()))
+	}
+}
+
+func TestInterrupt_GracePeriodUsesClock(t *testing.T) {
+	pty := fakepty.New()
+	sess := newRunningSession(pty, 500*time.Millisecond)
+	clock := sess.clock.(*fakeclock.Clock)
+	// Every read takes 200ms of session time, so the window closes after
+	// three reads although data keeps coming.
+	for i := 0; i < 10; i++ {
+		pty.AddResponse("tick\n")
+	}
+	var reads int
+	sess.pty = &advancingPTY{PTY: pty, clock: clock, step: 200 * time.Millisecond, reads: &reads}
+
+	result, err := sess.Interrupt()
+	if err != nil {
+		t.Fatalf("Interrupt() error = %v", err)
+	}
+	if reads != 3 || result.PromptReturned {
+		t.Errorf("reads = %d, PromptReturned = %v, want 3, false", reads, result.PromptReturned)
+	}
+}
+
+// advancingPTY advances a fake clock on every read.
+type advancingPTY struct {
+	*fakepty.PTY
+	clock *fakeclock.Clock
+	step  time.Duration
+	reads *int
+}
+
+func (p *advancingPTY) Read(b []byte) (int, error) {
+	*p.reads++
+	p.clock.Advance(p.step)
+	return p.PTY.Read(b)
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// killOnTimeoutAttempts is how many rounds of forceKillCommand
+	// ExecOptions.KillOnTimeout tries before giving up.
+	killOnTimeoutAttempts = 3
+
+	// aliveMarkerPrefix marks the echo that checks the shell is back at
+	// its prompt after a kill.
+	aliveMarkerPrefix = "___ALIVE_"
+
+	// hintKillFailed is set on a timeout result when kill_on_timeout could
+	// not get the shell to answer again.
+	hintKillFailed = "the timed-out command could not be stopped and may still be running; try shell_interrupt, or reconnect or close the session"
+)
+
+// killTimedOutCommand stops the command of an Exec that timed out with
+// ExecOptions.KillOnTimeout set. Unlike the default, which interrupts once
+// and assumes the shell is back, it checks that the shell answers and
+// escalates until it does, returning a "timeout_killed" result with the
+// partial output. The session goes idle only once the kill is confirmed; a
+// command that could not be stopped leaves it running, so shell_interrupt
+// still applies. Caller must hold s.mu.
+func (s *Session) killTimedOutCommand(execCtx *execContext) *ExecResult {
+	result := s.buildTimeoutResult(execCtx)
+
+	for attempt := 1; attempt <= killOnTimeoutAttempts; attempt++ {
+		s.forceKillCommand()
+		if s.shellResponds() {
+			s.State = StateIdle
+			slog.Info("killed timed-out command",
+				slog.String("session_id", s.ID),
+				slog.String("command_id", execCtx.commandID),
+				slog.Int("attempts", attempt),
+			)
+			result.Status = "timeout_killed"
+			return result
+		}
+	}
+
+	slog.Warn("timed-out command did not stop",
+		slog.String("session_id", s.ID),
+		slog.String("command_id", execCtx.commandID),
+	)
+	result.Hint = hintKillFailed
+	return result
+}
+
+// shellResponds echoes a marker and reports whether the shell prints it,
+// i.e. whether it is back at its prompt rather than still running a
+// command. The echoed input line starts with "echo", so only the shell's
+// output has the marker at the start of a line.
+func (s *Session) shellResponds() bool {
+	marker := aliveMarkerPrefix + s.generateCommandID() + markerSuffix
+	if _, err := s.pty.WriteString("echo " + marker + "\n"); err != nil {
+		return false
+	}
+	s.clock.Sleep(100 * time.Millisecond)
+
+	var output strings.Builder
+	buf := make([]byte, 4096)
+	for i := 0; i < 5; i++ {
+		s.pty.SetReadDeadline(s.clock.Now().Add(300 * time.Millisecond))
+		n, err := s.pty.Read(buf)
+		output.Write(buf[:n])
+		if findMarkerOnOwnLine(s.normalizeLineEndings(output.String()), marker) != -1 {
+			s.drainOutput()
+			return true
+		}
+		if err != nil && !isTimeoutError(err) {
+			return false
+		}
+	}
+	return false
+}
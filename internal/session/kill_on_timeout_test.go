@@ -0,0 +1,113 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newKillTestSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_kill", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionRandom(fakerand.NewSequential()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return sess, pty
+}
+
+// answerAliveProbe makes pty print the marker of each alive check once the
+// shell is back, i.e. after the given number of probes went unanswered.
+func answerAliveProbe(pty *fakepty.PTY, ignore int) {
+	pty.SetResponder(func(written string) string {
+		marker, ok := strings.CutPrefix(strings.TrimSpace(written), "echo ")
+		if !ok || !strings.HasPrefix(marker, aliveMarkerPrefix) {
+			return ""
+		}
+		if ignore > 0 {
+			ignore--
+			return ""
+		}
+		return written + marker + "\r\n$ "
+	})
+}
+
+func TestKillTimedOutCommand_Confirmed(t *testing.T) {
+	sess, pty := newKillTestSession(t)
+	answerAliveProbe(pty, 1)
+	sess.State = StateRunning
+	sess.killOnTimeout = true
+	startMarker := startMarkerPrefix + "abc" + markerSuffix
+	sess.outputBuffer.WriteString(startMarker + "\nline 1\nline 2\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := sess.handleContextTimeout(ctx, newExecContext("abc", startMarker, endMarkerPrefix+"abc"+markerSuffix, "cmd"))
+
+	if result.Status != "timeout_killed" || result.Hint != "" {
+		t.Errorf("Status = %q, Hint = %q; want timeout_killed", result.Status, result.Hint)
+	}
+	if result.Stdout != "line 1\nline 2" {
+		t.Errorf("Stdout = %q, want the partial output", result.Stdout)
+	}
+	if sess.State != StateIdle || !pty.WasInterrupted() {
+		t.Errorf("State = %q, interrupted = %v; want idle after an interrupt", sess.State, pty.WasInterrupted())
+	}
+	if probes := strings.Count(pty.Written(), "echo "+aliveMarkerPrefix); probes != 2 {
+		t.Errorf("alive probes = %d, want 2 (escalated once)", probes)
+	}
+}
+
+func TestKillTimedOutCommand_NotStopped(t *testing.T) {
+	sess, pty := newKillTestSession(t)
+	sess.State = StateRunning
+	sess.killOnTimeout = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := sess.handleContextTimeout(ctx, newExecContext("abc", "s", "e", "cmd"))
+
+	if result.Status != "timeout" || result.Hint != hintKillFailed {
+		t.Errorf("Status = %q, Hint = %q; want timeout with the kill-failed hint", result.Status, result.Hint)
+	}
+	if probes := strings.Count(pty.Written(), "echo "+aliveMarkerPrefix); probes != killOnTimeoutAttempts {
+		t.Errorf("alive probes = %d, want %d", probes, killOnTimeoutAttempts)
+	}
+	if sess.State != StateRunning {
+		t.Errorf("State = %q, want running while the command may still run", sess.State)
+	}
+}
+
+func TestExecWithOptions_KillOnTimeout(t *testing.T) {
+	sess, pty := newKillTestSession(t)
+	answerAliveProbe(pty, 0)
+
+	result, err := sess.ExecWithOptions("sleep 100", 50, ExecOptions{KillOnTimeout: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions() error = %v", err)
+	}
+	if result.Status != "timeout_killed" || sess.State != StateIdle {
+		t.Errorf("Status = %q, State = %q; want timeout_killed and idle", result.Status, sess.State)
+	}
+	if sess.killOnTimeout {
+		t.Error("killOnTimeout still set after Exec")
+	}
+
+	// Without the option the default timeout is unchanged.
+	result, err = sess.Exec("sleep 100", 50)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Status != "timeout" {
+		t.Errorf("Status = %q, want timeout", result.Status)
+	}
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ResourceLimits are ulimit settings for a single command (see
+// ExecOptions.Limits). Zero fields leave that resource unlimited.
+type ResourceLimits struct {
+	CPUSeconds    int `json:"cpu_seconds,omitempty"`      // ulimit -t
+	MaxMemoryMB   int `json:"max_memory_mb,omitempty"`    // ulimit -v (virtual memory)
+	MaxFileSizeMB int `json:"max_file_size_mb,omitempty"` // ulimit -f (largest file written)
+}
+
+// Names of ResourceLimits fields, reported in ExecResult.LimitExceeded.
+const (
+	limitCPUSeconds    = "cpu_seconds"
+	limitMaxMemoryMB   = "max_memory_mb"
+	limitMaxFileSizeMB = "max_file_size_mb"
+)
+
+// Exit statuses of a process killed by SIGXCPU and SIGXFSZ, which the kernel
+// sends when the CPU time and file size limits are reached.
+const (
+	exitSIGXCPU = 128 + 24
+	exitSIGXFSZ = 128 + 25
+)
+
+// Validate checks that at least one limit is set and none is negative.
+func (l *ResourceLimits) Validate() error {
+	if l.CPUSeconds < 0 || l.MaxMemoryMB < 0 || l.MaxFileSizeMB < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if l.CPUSeconds == 0 && l.MaxMemoryMB == 0 && l.MaxFileSizeMB == 0 {
+		return fmt.Errorf("limits must set at least one of %s, %s or %s", limitCPUSeconds, limitMaxMemoryMB, limitMaxFileSizeMB)
+	}
+	return nil
+}
+
+// wrap runs command in a subshell that applies the limits first, so they
+// end with the command and never reach the session's shell. If a limit
+// cannot be set the subshell exits with ulimit's status instead of running
+// the command unconfined. Sizes are in KiB, the unit bash uses for both -v
+// and -f.
+func (l *ResourceLimits) wrap(command string) string {
+	var set []string
+	if l.CPUSeconds > 0 {
+		// The soft limit sends SIGXCPU, which kills the command with a
+		// recognizable status; the hard limit a second later sends SIGKILL
+		// to a command that ignores it. With both equal Linux sends SIGKILL
+		// straight away. The soft limit goes first: the hard one cannot be
+		// set below it.
+		set = append(set, fmt.Sprintf("ulimit -S -t %d", l.CPUSeconds), fmt.Sprintf("ulimit -H -t %d", l.CPUSeconds+1))
+	}
+	if l.MaxMemoryMB > 0 {
+		set = append(set, fmt.Sprintf("ulimit -v %d", l.MaxMemoryMB*1024))
+	}
+	if l.MaxFileSizeMB > 0 {
+		set = append(set, fmt.Sprintf("ulimit -f %d", l.MaxFileSizeMB*1024))
+	}
+	return "(" + strings.Join(set, " && ") + " || exit; " + command + ")"
+}
+
+// outOfMemoryPattern matches what common runtimes print when an allocation
+// fails, which is how a command reaching the memory limit usually ends.
+var outOfMemoryPattern = regexp.MustCompile(`(?i)cannot allocate memory|out of memory|memoryerror|bad_alloc|memory exhausted|failed to allocate|allocation failed`)
+
+// fileTooLargePattern matches the EFBIG error of a command that ignores
+// SIGXFSZ and gets a failed write instead.
+var fileTooLargePattern = regexp.MustCompile(`(?i)file too large|file size limit exceeded`)
+
+// exceededLimit returns the limit a command that ended with exitCode and
+// output most likely ran into, or "" if it does not look like one did.
+func (l *ResourceLimits) exceededLimit(exitCode int, output string) string {
+	switch {
+	case exitCode == 0:
+		return ""
+	case l.CPUSeconds > 0 && exitCode == exitSIGXCPU:
+		return limitCPUSeconds
+	case l.MaxFileSizeMB > 0 && (exitCode == exitSIGXFSZ || fileTooLargePattern.MatchString(output)):
+		return limitMaxFileSizeMB
+	case l.MaxMemoryMB > 0 && outOfMemoryPattern.MatchString(output):
+		return limitMaxMemoryMB
+	}
+	return ""
+}
+
+// value returns the configured value of the named limit.
+func (l *ResourceLimits) value(name string) int {
+	switch name {
+	case limitCPUSeconds:
+		return l.CPUSeconds
+	case limitMaxMemoryMB:
+		return l.MaxMemoryMB
+	case limitMaxFileSizeMB:
+		return l.MaxFileSizeMB
+	}
+	return 0
+}
+
+// markLimitExceeded turns a completed result of a command run with limits
+// into a "limit_exceeded" one when its exit code or output shows it was
+// stopped by one of them. The exit code and output are kept.
+func (s *Session) markLimitExceeded(result *ExecResult) {
+	if s.limits == nil || result == nil || result.Status != "completed" || result.ExitCode == nil {
+		return
+	}
+	name := s.limits.exceededLimit(*result.ExitCode, result.Stdout+"\n"+result.Stderr)
+	if name == "" {
+		return
+	}
+	result.Status = "limit_exceeded"
+	result.LimitExceeded = name
+	if result.Hint == "" {
+		result.Hint = fmt.Sprintf("the command was stopped by its %s limit of %d; raise it in limits or run the command without it", name, s.limits.value(name))
+	}
+}
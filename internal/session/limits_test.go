@@ -0,0 +1,190 @@
+package session
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestResourceLimits_Validate(t *testing.T) {
+	tests := []struct {
+		limits  ResourceLimits
+		wantErr string
+	}{
+		{ResourceLimits{CPUSeconds: 10}, ""},
+		{ResourceLimits{MaxMemoryMB: 512, MaxFileSizeMB: 100}, ""},
+		{ResourceLimits{}, "at least one"},
+		{ResourceLimits{CPUSeconds: -1}, "negative"},
+	}
+	for _, tt := range tests {
+		err := tt.limits.Validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%+v: Validate() = %v, want %q", tt.limits, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResourceLimits_Wrap(t *testing.T) {
+	limits := &ResourceLimits{CPUSeconds: 10, MaxMemoryMB: 512, MaxFileSizeMB: 100}
+	got := limits.wrap("make test")
+	want := "(ulimit -S -t 10 && ulimit -H -t 11 && ulimit -v 524288 && ulimit -f 102400 || exit; make test)"
+	if got != want {
+		t.Errorf("wrap = %q, want %q", got, want)
+	}
+	if got := (&ResourceLimits{MaxFileSizeMB: 1}).wrap("true"); got != "(ulimit -f 1024 || exit; true)" {
+		t.Errorf("wrap = %q", got)
+	}
+}
+
+// TestResourceLimits_RealShell runs wrapped commands through bash inside the
+// marker wrapper, checking that the limits apply to the command only and
+// that a file size kill is recognized.
+func TestResourceLimits_RealShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	sess := &Session{}
+
+	limits := &ResourceLimits{CPUSeconds: 30, MaxFileSizeMB: 1}
+	full := sess.buildWrappedCommand(limits.wrap("ulimit -S -t; ulimit -f")+"; ulimit -f", "abc12345")
+	out, err := exec.Command("bash", "-c", full).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running wrapped command: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "\n30\n1024\nunlimited\n") {
+		t.Errorf("output = %q, want the limits inside the subshell only", out)
+	}
+
+	big := filepath.Join(t.TempDir(), "big")
+	full = sess.buildWrappedCommand(limits.wrap("head -c 2097152 /dev/zero > "+big), "abc12345")
+	out, _ = exec.Command("bash", "-c", full).CombinedOutput()
+	exitCode, found := sess.extractExitCodeWithMarker(string(out), endMarkerPrefix+"abc12345"+markerSuffix)
+	if !found {
+		t.Fatalf("no end marker in %q", out)
+	}
+	if got := limits.exceededLimit(exitCode, string(out)); got != limitMaxFileSizeMB {
+		t.Errorf("exceededLimit(%d, %q) = %q, want %s", exitCode, out, got, limitMaxFileSizeMB)
+	}
+}
+
+func TestResourceLimits_ExceededLimit(t *testing.T) {
+	all := &ResourceLimits{CPUSeconds: 5, MaxMemoryMB: 256, MaxFileSizeMB: 10}
+	tests := []struct {
+		name     string
+		limits   *ResourceLimits
+		exitCode int
+		output   string
+		want     string
+	}{
+		{"success", all, 0, "Cannot allocate memory", ""},
+		{"SIGXCPU", all, 152, "CPU time limit exceeded (core dumped)", limitCPUSeconds},
+		{"SIGXCPU without cpu limit", &ResourceLimits{MaxMemoryMB: 256}, 152, "", ""},
+		{"SIGXFSZ", all, 153, "File size limit exceeded", limitMaxFileSizeMB},
+		{"EFBIG", all, 1, "cp: error writing 'out.img': File too large", limitMaxFileSizeMB},
+		{"malloc", all, 1, "bash: fork: Cannot allocate memory", limitMaxMemoryMB},
+		{"python", all, 1, "Traceback (most recent call last):\nMemoryError", limitMaxMemoryMB},
+		{"c++", all, 134, "terminate called after throwing an instance of 'std::bad_alloc'", limitMaxMemoryMB},
+		{"memory message without memory limit", &ResourceLimits{CPUSeconds: 5}, 1, "out of memory", ""},
+		{"ordinary failure", all, 2, "ls: cannot access '/nope'", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limits.exceededLimit(tt.exitCode, tt.output); got != tt.want {
+				t.Errorf("exceededLimit(%d, %q) = %q, want %q", tt.exitCode, tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func newLimitsSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_limits", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestExecWithOptions_LimitExceeded(t *testing.T) {
+	sess, pty := newLimitsSession(t)
+	pty.AddResponse(buildCommandOutput("01020304", "CPU time limit exceeded (core dumped)", 152))
+
+	result, err := sess.ExecWithOptions("./spin", 5000, ExecOptions{Limits: &ResourceLimits{CPUSeconds: 2}})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if !strings.Contains(pty.Written(), "(ulimit -S -t 2 && ulimit -H -t 3 || exit; ./spin)") {
+		t.Errorf("written = %q, want the command in a limited subshell", pty.Written())
+	}
+	if result.Status != "limit_exceeded" || result.LimitExceeded != limitCPUSeconds {
+		t.Errorf("Status = %q, LimitExceeded = %q, want limit_exceeded, cpu_seconds", result.Status, result.LimitExceeded)
+	}
+	if result.ExitCode == nil || *result.ExitCode != 152 || !strings.Contains(result.Stdout, "CPU time limit") {
+		t.Errorf("exit code and output not kept: %v %q", result.ExitCode, result.Stdout)
+	}
+	if !strings.Contains(result.Hint, "cpu_seconds limit of 2") {
+		t.Errorf("Hint = %q", result.Hint)
+	}
+}
+
+func TestExecWithOptions_LimitsNotHit(t *testing.T) {
+	sess, pty := newLimitsSession(t)
+	pty.AddResponse(buildCommandOutput("01020304", "ok", 0))
+
+	result, err := sess.ExecWithOptions("make", 5000, ExecOptions{Limits: &ResourceLimits{MaxMemoryMB: 512}})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if result.Status != "completed" || result.LimitExceeded != "" || result.Stdout != "ok" {
+		t.Errorf("result = %+v, want a plain completed result", result)
+	}
+	if sess.limits == nil {
+		t.Error("limits not kept for ProvideInput")
+	}
+
+	pty.AddResponse(buildCommandOutput("01020304", "ok", 0))
+	if _, err := sess.Exec("true", 5000); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if sess.limits != nil {
+		t.Error("limits carried over to the next command")
+	}
+}
+
+func TestExecWithOptions_InvalidLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ExecOptions
+		want string
+	}{
+		{"direct", ExecOptions{Direct: true, Limits: &ResourceLimits{CPUSeconds: 1}}, "cannot be combined with direct"},
+		{"empty", ExecOptions{Limits: &ResourceLimits{}}, "at least one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sess, pty := newLimitsSession(t)
+			if _, err := sess.ExecWithOptions("jobs", 5000, tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ExecWithOptions error = %v, want %q", err, tt.want)
+			}
+			if pty.Written() != "" {
+				t.Errorf("wrote %q for invalid limits", pty.Written())
+			}
+			if sess.commandsRun != 0 {
+				t.Error("invalid limits were charged as a command")
+			}
+		})
+	}
+}
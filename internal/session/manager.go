@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/acolita/claude-shell-mcp/internal/config"
 	"github.com/acolita/claude-shell-mcp/internal/ports"
 	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
 )
 
 // LocalPTYFactory creates a local PTY and returns (pty, shell name, error).
@@ -25,11 +27,13 @@ type Manager struct {
 	sessions        map[string]*Session
 	controlSessions map[string]*ControlSession // key: "local" or hostname
 	store           *SessionStore              // persists session metadata for recovery
+	connPool        *ConnectionPool            // shared SSH clients keyed by user@host:port
 	mu              sync.RWMutex
 	config          *config.Config
 	clock           ports.Clock
 	random          ports.Random
 	localPTYFactory LocalPTYFactory
+	commandCheck    func(command string) error
 }
 
 // ManagerOption configures a Manager.
@@ -64,11 +68,21 @@ func WithLocalPTYFactory(factory LocalPTYFactory) ManagerOption {
 	}
 }
 
+// WithCommandCheck sets the check a command mode session's command must pass
+// before recovery runs it again, since the command filter may have changed
+// since the session was created.
+func WithCommandCheck(check func(command string) error) ManagerOption {
+	return func(m *Manager) {
+		m.commandCheck = check
+	}
+}
+
 // NewManager creates a new session manager.
 func NewManager(cfg *config.Config, opts ...ManagerOption) *Manager {
 	m := &Manager{
 		sessions:        make(map[string]*Session),
 		controlSessions: make(map[string]*ControlSession),
+		connPool:        NewConnectionPool(),
 		config:          cfg,
 		clock:           realclock.New(),
 		random:          realrand.New(),
@@ -101,20 +115,44 @@ func (m *Manager) Create(opts CreateOptions) (*Session, error) {
 		return nil, fmt.Errorf("max sessions reached (%d)", m.config.Security.MaxSessionsPerUser)
 	}
 
+	tags, err := NormalizeTags(opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateContainer(opts); err != nil {
+		return nil, err
+	}
+	if err := validateStartDir(opts); err != nil {
+		return nil, err
+	}
+
 	id := m.generateSessionID()
 	sess := &Session{
-		ID:              id,
-		State:           StateIdle,
-		Mode:            opts.Mode,
-		Host:            opts.Host,
-		Port:            opts.Port,
-		User:            opts.User,
-		Password:        opts.Password,
-		KeyPath:         opts.KeyPath,
-		config:          m.config,
-		clock:           m.clock,
-		random:          m.random,
-		localPTYFactory: m.localPTYFactory,
+		ID:               id,
+		State:            StateIdle,
+		Mode:             opts.Mode,
+		Host:             opts.Host,
+		Port:             opts.Port,
+		User:             opts.User,
+		Password:         opts.Password,
+		NewPassword:      opts.NewPassword,
+		KeyPath:          opts.KeyPath,
+		Server:           opts.Server,
+		Identity:         opts.Identity,
+		Command:          opts.Command,
+		RawMode:          opts.RawMode,
+		Container:        opts.Container,
+		ContainerRuntime: opts.ContainerRuntime,
+		ForwardX11:       opts.ForwardX11,
+		KeepPagers:       opts.KeepPagers,
+		StartDir:         m.startDir(opts),
+		PromptResponses:  opts.PromptResponses,
+		Tags:             tags,
+		config:           m.config,
+		clock:            m.clock,
+		random:           m.random,
+		localPTYFactory:  m.localPTYFactory,
+		connPool:         m.connPool,
 	}
 
 	// Initialize the session (creates PTY/SSH connection)
@@ -122,13 +160,16 @@ func (m *Manager) Create(opts CreateOptions) (*Session, error) {
 		return nil, fmt.Errorf("initialize session: %w", err)
 	}
 
-	// Get or create control session for this host (without locking again)
-	cs, err := m.getOrCreateControlSessionLocked(opts)
-	if err != nil {
-		// Non-fatal: control session is optional for enhanced process management
-		// The session can still work with fallback interrupt handling
-	} else {
-		sess.controlSession = cs
+	// Get or create control session for this host (without locking again).
+	// Command sessions have none: their processes are out of its reach.
+	if opts.Mode != "command" {
+		cs, err := m.getOrCreateControlSessionLocked(opts)
+		if err != nil {
+			// Non-fatal: control session is optional for enhanced process management
+			// The session can still work with fallback interrupt handling
+		} else {
+			sess.controlSession = cs
+		}
 	}
 
 	m.sessions[id] = sess
@@ -170,21 +211,37 @@ func (m *Manager) recover(id string) (*Session, error) {
 		return sess, nil
 	}
 
+	if meta.Mode == "command" && m.commandCheck != nil {
+		if err := m.commandCheck(meta.Command); err != nil {
+			return nil, fmt.Errorf("failed to recover session %s: %w", id, err)
+		}
+	}
+
 	// Recreate the session with stored metadata
 	sess := &Session{
-		ID:              id, // Use the same ID!
-		State:           StateIdle,
-		Mode:            meta.Mode,
-		Host:            meta.Host,
-		Port:            meta.Port,
-		User:            meta.User,
-		KeyPath:         meta.KeyPath,
-		Cwd:             meta.Cwd,
-		SavedTunnels:    meta.Tunnels, // Saved tunnels for user to restore
-		config:          m.config,
-		clock:           m.clock,
-		random:          m.random,
-		localPTYFactory: m.localPTYFactory,
+		ID:               id, // Use the same ID!
+		State:            StateIdle,
+		Mode:             meta.Mode,
+		Host:             meta.Host,
+		Port:             meta.Port,
+		User:             meta.User,
+		KeyPath:          meta.KeyPath,
+		Server:           meta.Server,
+		Identity:         meta.Identity,
+		Command:          meta.Command,
+		RawMode:          meta.RawMode,
+		Container:        meta.Container,
+		ContainerRuntime: meta.ContainerRuntime,
+		ForwardX11:       meta.ForwardX11,
+		KeepPagers:       meta.KeepPagers,
+		Cwd:              meta.Cwd,
+		SavedTunnels:     meta.Tunnels, // Saved tunnels for user to restore
+		Tags:             meta.Tags,
+		config:           m.config,
+		clock:            m.clock,
+		random:           m.random,
+		localPTYFactory:  m.localPTYFactory,
+		connPool:         m.connPool,
 	}
 
 	// Initialize the session (creates PTY/SSH connection)
@@ -202,8 +259,10 @@ func (m *Manager) recover(id string) (*Session, error) {
 		User:    meta.User,
 		KeyPath: meta.KeyPath,
 	}
-	if cs, err := m.getOrCreateControlSessionLocked(opts); err == nil {
-		sess.controlSession = cs
+	if meta.Mode != "command" {
+		if cs, err := m.getOrCreateControlSessionLocked(opts); err == nil {
+			sess.controlSession = cs
+		}
 	}
 
 	m.sessions[id] = sess
@@ -252,15 +311,16 @@ func (m *Manager) List() []string {
 
 // SessionInfo contains summary information about a session.
 type SessionInfo struct {
-	ID        string `json:"session_id"`
-	Mode      string `json:"mode"`
-	Host      string `json:"host,omitempty"`
-	User      string `json:"user,omitempty"`
-	State     string `json:"state"`
-	Cwd       string `json:"cwd,omitempty"`
-	CreatedAt string `json:"created_at"`
-	LastUsed  string `json:"last_used"`
-	IdleFor   string `json:"idle_for"`
+	ID        string   `json:"session_id"`
+	Mode      string   `json:"mode"`
+	Host      string   `json:"host,omitempty"`
+	User      string   `json:"user,omitempty"`
+	State     string   `json:"state"`
+	Cwd       string   `json:"cwd,omitempty"`
+	CreatedAt string   `json:"created_at"`
+	LastUsed  string   `json:"last_used"`
+	IdleFor   string   `json:"idle_for"`
+	Tags      []string `json:"tags,omitempty"`
 }
 
 // ListDetailed returns detailed information about all active sessions.
@@ -282,12 +342,64 @@ func (m *Manager) ListDetailed() []SessionInfo {
 			CreatedAt: sess.CreatedAt.Format(time.RFC3339),
 			LastUsed:  sess.LastUsed.Format(time.RFC3339),
 			IdleFor:   now.Sub(sess.LastUsed).Round(time.Second).String(),
+			Tags:      sess.Tags,
 		}
 		infos = append(infos, info)
 	}
 	return infos
 }
 
+// PoolStats returns usage statistics for the shared SSH connection pool.
+func (m *Manager) PoolStats() ConnectionPoolStats {
+	return m.connPool.Stats()
+}
+
+// Connections lists the pooled SSH connections with the sessions using each.
+func (m *Manager) Connections() []PooledConnection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.connPool.list(m.sshClientHoldersLocked())
+}
+
+// CloseConnection force-closes the pooled SSH connection for key even if
+// sessions still use it. Those sessions are marked as disconnected and
+// reconnect on their next command. It returns their IDs.
+func (m *Manager) CloseConnection(key string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	holders := m.sshClientHoldersLocked()
+	clients, err := m.connPool.ForceClose(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, client := range clients {
+		for _, id := range holders[client] {
+			m.sessions[id].setConnectionDropped(fmt.Sprintf("connection %s was force-closed", key))
+			dropped = append(dropped, id)
+		}
+	}
+	sort.Strings(dropped)
+	return dropped, nil
+}
+
+// sshClientHoldersLocked maps each SSH client to the IDs of the sessions
+// using it. Caller must hold m.mu.
+func (m *Manager) sshClientHoldersLocked() map[*ssh.Client][]string {
+	holders := make(map[*ssh.Client][]string)
+	for id, sess := range m.sessions {
+		if client := sess.currentSSHClient(); client != nil {
+			holders[client] = append(holders[client], id)
+		}
+	}
+	for _, ids := range holders {
+		sort.Strings(ids)
+	}
+	return holders
+}
+
 // SessionCount returns the number of active sessions.
 func (m *Manager) SessionCount() int {
 	m.mu.RLock()
@@ -328,12 +440,53 @@ func localPTYFile(p PTY) *os.File {
 
 // CreateOptions defines options for creating a session.
 type CreateOptions struct {
-	Mode     string // "local" or "ssh"
+	Mode     string // "local", "ssh" or "command"
 	Host     string
 	Port     int
 	User     string
 	Password string // For password-based SSH authentication
 	KeyPath  string // Path to SSH private key file
+
+	// Server names the configured server to take auth and identities from
+	// (ssh mode), whatever Host is; see Session.Server.
+	Server string
+
+	// Identity selects one of the configured server's identities (ssh
+	// mode) instead of its auth block.
+	Identity string
+
+	// NewPassword answers a password change the server forces at login
+	// (ssh mode). Without it such a login fails with
+	// ErrPasswordChangeRequired.
+	NewPassword string
+
+	// Command provides the shell over its stdio in command mode, e.g.
+	// "kubectl exec -it pod -- bash".
+	Command string
+
+	// RawMode disables marker wrapping and echo handling: see Session.RawMode.
+	RawMode bool
+
+	// Container runs every command inside this docker or podman container
+	// (see Session.Container). ContainerRuntime defaults to "docker".
+	Container        string
+	ContainerRuntime string
+
+	// ForwardX11 requests X11 forwarding to the local DISPLAY (ssh mode).
+	ForwardX11 bool
+
+	// KeepPagers leaves PAGER, GIT_PAGER and the rest of
+	// session.disable_pagers as the shell sets them.
+	KeepPagers bool
+
+	// Cwd is the directory the session starts in (local and ssh modes).
+	// Local sessions default to local_session.default_cwd.
+	Cwd string
+
+	// PromptResponses script a multi-step login (e.g. password then TOTP).
+	PromptResponses []PromptResponse
+
+	Tags []string // labels for grouping sessions (see NormalizeTags)
 }
 
 // GetControlSession returns the control session for a host, creating it if needed.
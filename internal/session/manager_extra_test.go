@@ -19,7 +19,7 @@ import (
 // fakePTYFactory returns a LocalPTYFactory that creates fakepty instances
 // instead of spawning real shells. This prevents tests from hanging on macOS.
 func fakePTYFactory(opts localpty.PTYOptions) (PTY, string, error) {
-	return fakepty.New(), "/bin/sh", nil
+	return newReadyPTY(), "/bin/sh", nil
 }
 
 // newTestManager creates a Manager configured with fakes for testing.
@@ -553,7 +553,7 @@ func TestSession_Interrupt_FromRunning(t *testing.T) {
 	// Set state to running to allow interrupt
 	sess.State = StateRunning
 
-	err := sess.Interrupt()
+	_, err := sess.Interrupt()
 	if err != nil {
 		t.Fatalf("Interrupt error: %v", err)
 	}
@@ -582,7 +582,7 @@ func TestSession_Interrupt_FromAwaitingInput(t *testing.T) {
 
 	sess.State = StateAwaitingInput
 
-	err := sess.Interrupt()
+	_, err := sess.Interrupt()
 	if err != nil {
 		t.Fatalf("Interrupt error: %v", err)
 	}
@@ -606,7 +606,7 @@ func TestSession_Interrupt_FromIdle_ReturnsError(t *testing.T) {
 		t.Fatalf("Initialize error: %v", err)
 	}
 
-	err := sess.Interrupt()
+	_, err := sess.Interrupt()
 	if err == nil {
 		t.Fatal("expected error when interrupting idle session")
 	}
@@ -619,7 +619,7 @@ func TestSession_Interrupt_NilPTY_ReturnsError(t *testing.T) {
 	sess := NewSession("sess_no_pty", "local")
 	sess.State = StateRunning
 
-	err := sess.Interrupt()
+	_, err := sess.Interrupt()
 	if err == nil {
 		t.Fatal("expected error when PTY is nil")
 	}
@@ -965,7 +965,7 @@ func TestSession_StateTransitions_RunningToIdleViaInterrupt(t *testing.T) {
 
 	sess.State = StateRunning
 
-	if err := sess.Interrupt(); err != nil {
+	if _, err := sess.Interrupt(); err != nil {
 		t.Fatalf("Interrupt error: %v", err)
 	}
 
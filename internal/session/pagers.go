@@ -0,0 +1,58 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// pagerEnv returns the NAME=value assignments from
+// config.Session.DisablePagers that keep pagers out of command output, or
+// nil when the session keeps its pagers.
+func (s *Session) pagerEnv() []string {
+	if s.KeepPagers || s.config == nil {
+		return nil
+	}
+	return s.config.Session.DisablePagers
+}
+
+// pagerExportCommand returns the command that exports env in the session's
+// shell.
+func (s *Session) pagerExportCommand(env []string) string {
+	var set string
+	switch path.Base(s.Shell) {
+	case "fish":
+		set = "set -gx %s %s"
+	case "csh", "tcsh":
+		set = "setenv %s %s"
+	}
+	if set != "" {
+		sets := make([]string, len(env))
+		for i, assignment := range env {
+			name, value, _ := strings.Cut(assignment, "=")
+			sets[i] = fmt.Sprintf(set, name, shellQuote(value))
+		}
+		return strings.Join(sets, "; ") + "\n"
+	}
+	exports := make([]string, len(env))
+	for i, assignment := range env {
+		name, value, _ := strings.Cut(assignment, "=")
+		exports[i] = name + "=" + shellQuote(value)
+	}
+	return "export " + strings.Join(exports, " ") + "\n"
+}
+
+// disablePagers exports pagerEnv in the session's shell, so commands like
+// git log or systemctl status print and return instead of waiting in a
+// pager, which would come back as awaiting_input. Caller must hold s.mu.
+func (s *Session) disablePagers() {
+	env := s.pagerEnv()
+	if len(env) == 0 {
+		return
+	}
+	s.pty.WriteString(s.pagerExportCommand(env))
+	s.clock.Sleep(100 * time.Millisecond)
+	buf := make([]byte, 4096)
+	s.readWithTimeout(buf, 200*time.Millisecond) // Drain the echo and prompt
+}
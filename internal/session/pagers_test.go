@@ -0,0 +1,105 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func initLocalPagers(t *testing.T, cfg *config.Config, keepPagers bool) *fakepty.PTY {
+	t.Helper()
+	pty := newReadyPTY()
+	sess := NewSession("sess_pagers", "local",
+		WithConfig(cfg),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionFileSystem(fakefs.New()),
+	)
+	sess.KeepPagers = keepPagers
+	sess.localPTYFactory = func(localpty.PTYOptions) (PTY, string, error) {
+		return pty, "/bin/bash", nil
+	}
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	return pty
+}
+
+func TestInitialize_DisablesPagers(t *testing.T) {
+	pty := initLocalPagers(t, config.DefaultConfig(), false)
+
+	want := "export PAGER='cat' GIT_PAGER='cat' MANPAGER='cat' SYSTEMD_PAGER='' AWS_PAGER='' GH_PAGER='cat' PSQL_PAGER='cat'\n"
+	written := pty.Written()
+	if !strings.Contains(written, want) {
+		t.Errorf("written = %q, want %q", written, want)
+	}
+	if strings.Index(written, want) < strings.Index(written, "PS1=") {
+		t.Error("pagers were disabled before the prompt was normalized")
+	}
+}
+
+func TestInitialize_KeepPagers(t *testing.T) {
+	pty := initLocalPagers(t, config.DefaultConfig(), true)
+	if strings.Contains(pty.Written(), "PAGER") {
+		t.Errorf("written = %q, want no pager exports", pty.Written())
+	}
+}
+
+func TestInitialize_DisablePagersConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Session.DisablePagers = []string{"GIT_PAGER=cat", "LESS=-FRX"}
+	if pty := initLocalPagers(t, cfg, false); !strings.Contains(pty.Written(), "export GIT_PAGER='cat' LESS='-FRX'\n") {
+		t.Errorf("written = %q, want only the configured variables", pty.Written())
+	}
+
+	cfg.Session.DisablePagers = nil
+	if pty := initLocalPagers(t, cfg, false); strings.Contains(pty.Written(), "export") {
+		t.Errorf("written = %q, want nothing exported for an empty list", pty.Written())
+	}
+}
+
+func TestPagerExportCommand_Fish(t *testing.T) {
+	sess := NewSession("sess_fish", "local", WithConfig(config.DefaultConfig()))
+	sess.Shell = "/usr/bin/fish"
+
+	got := sess.pagerExportCommand([]string{"GIT_PAGER=cat", "SYSTEMD_PAGER="})
+	if want := "set -gx GIT_PAGER 'cat'; set -gx SYSTEMD_PAGER ''\n"; got != want {
+		t.Errorf("pagerExportCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestPagerExportCommand_Csh(t *testing.T) {
+	for _, shell := range []string{"/bin/csh", "tcsh"} {
+		sess := NewSession("sess_csh", "local", WithConfig(config.DefaultConfig()))
+		sess.Shell = shell
+
+		got := sess.pagerExportCommand([]string{"GIT_PAGER=cat", "SYSTEMD_PAGER="})
+		if want := "setenv GIT_PAGER 'cat'; setenv SYSTEMD_PAGER ''\n"; got != want {
+			t.Errorf("%s: pagerExportCommand() = %q, want %q", shell, got, want)
+		}
+	}
+}
+
+func TestContainerEnv_Pagers(t *testing.T) {
+	sess := NewSession("sess_ctr", "local", WithConfig(config.DefaultConfig()))
+	sess.Container = "web-1"
+	sess.Cwd = "~"
+	sess.config.Session.DisablePagers = []string{"GIT_PAGER=cat", "SYSTEMD_PAGER="}
+
+	if got, want := sess.applyContainer("git log"), "docker exec -it -e 'GIT_PAGER=cat' -e 'SYSTEMD_PAGER=' web-1 sh -c 'git log'"; got != want {
+		t.Errorf("applyContainer() = %q, want %q", got, want)
+	}
+	if got, want := sess.containerCommand("pwd"), "docker exec -e 'GIT_PAGER=cat' -e 'SYSTEMD_PAGER=' web-1 pwd"; got != want {
+		t.Errorf("containerCommand() = %q, want %q", got, want)
+	}
+
+	sess.KeepPagers = true
+	if got := sess.containerCommand("pwd"); got != "docker exec web-1 pwd" {
+		t.Errorf("containerCommand() with KeepPagers = %q", got)
+	}
+}
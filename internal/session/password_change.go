@@ -0,0 +1,157 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrPasswordChangeRequired is returned when the server forces a password
+// change at login and no new password was supplied.
+var ErrPasswordChangeRequired = errors.New("password_change_required")
+
+// maxPasswordChangeAnswers bounds the prompts answered in one password
+// change, so a passwd that keeps asking cannot loop forever.
+const maxPasswordChangeAnswers = 6
+
+var (
+	// passwordChangeRequiredRe spots the login banner of an expired password.
+	passwordChangeRequiredRe = regexp.MustCompile(`(?i)(required to change your password|must change your password|password has expired|password expired|password change required)`)
+
+	// The passwd prompts, matched against the end of the output. The confirm
+	// prompt is tried first since it usually mentions "new" too.
+	confirmPasswordPromptRe = regexp.MustCompile(`(?i)(retype|re-enter|reenter|confirm|again)[^\n]*password[^\n]*:\s*$`)
+	newPasswordPromptRe     = regexp.MustCompile(`(?i)new[^\n]*password[^\n]*:\s*$`)
+	currentPasswordPromptRe = regexp.MustCompile(`(?i)(current|old)[^\n]*password[^\n]*:\s*$`)
+
+	passwordChangedRe      = regexp.MustCompile(`(?i)(updated successfully|password changed|password updated)`)
+	passwordChangeFailedRe = regexp.MustCompile(`(?i)(bad password|password unchanged|manipulation error|do not match|have exhausted|authentication failure)[^\n]*`)
+)
+
+// passwordChangeRequired reports whether startup output asks for a password
+// change before the shell starts.
+func passwordChangeRequired(output string) bool {
+	return passwordChangeRequiredRe.MatchString(output)
+}
+
+// newPasswordFor returns the password to set when the server forces a
+// change: the session's NewPassword, else the new_password_env of the
+// server's auth block or selected identity.
+func (s *Session) newPasswordFor() string {
+	if s.NewPassword != "" {
+		return s.NewPassword
+	}
+	srv := s.serverConfig()
+	if srv == nil {
+		return ""
+	}
+	auth, err := srv.AuthFor(s.Identity)
+	if err != nil || auth.NewPasswordEnv == "" {
+		return ""
+	}
+	return s.fs.Getenv(auth.NewPasswordEnv)
+}
+
+// changeExpiredPassword answers the current/new/confirm prompts of a forced
+// password change, given the startup output that announced it. On success
+// s.Password holds the new password. closed reports that the server ended
+// the connection afterwards, as sshd does once passwd returns, so the caller
+// must log in again.
+func (s *Session) changeExpiredPassword(output, currentPassword string) (closed bool, err error) {
+	newPassword := s.newPasswordFor()
+	if newPassword == "" {
+		return false, fmt.Errorf("%w: %s@%s must change the password before logging in; pass new_password_env or set auth.new_password_env for the server",
+			ErrPasswordChangeRequired, s.User, s.Host)
+	}
+
+	slog.Info("server requires a password change",
+		slog.String("session_id", s.ID),
+		slog.String("host", s.Host),
+		slog.String("user", s.User),
+	)
+
+	buf := make([]byte, s.readBufferSize())
+	pending := output
+	answers := 0
+	changed := false
+	deadline := s.clock.Now().Add(defaultPromptResponseTimeout)
+
+	for {
+		if m := passwordChangeFailedRe.FindString(pending); m != "" {
+			return false, fmt.Errorf("password_change_failed: %s", strings.TrimSpace(m))
+		}
+		if !changed && passwordChangedRe.MatchString(pending) {
+			changed = true
+			s.Password = newPassword
+			slog.Info("password changed",
+				slog.String("session_id", s.ID),
+				slog.String("host", s.Host),
+			)
+			// The shell may follow on the same connection; give the server
+			// until the end of the startup drain to hang up instead.
+			deadline = s.clock.Now().Add(s.startupDrain())
+		}
+
+		if !changed {
+			if answer, prompt := passwordChangeAnswer(pending, currentPassword, newPassword); prompt != "" {
+				if answer == "" {
+					return false, fmt.Errorf("password_change_failed: server asked for the current password, which is unknown")
+				}
+				if answers++; answers > maxPasswordChangeAnswers {
+					return false, fmt.Errorf("password_change_failed: server kept asking for passwords")
+				}
+				slog.Debug("answering password change prompt",
+					slog.String("session_id", s.ID),
+					slog.String("prompt", prompt),
+				)
+				if _, err := s.pty.WriteString(answer + "\n"); err != nil {
+					return false, fmt.Errorf("password_change_failed: write: %w", err)
+				}
+				pending = ""
+				deadline = s.clock.Now().Add(defaultPromptResponseTimeout)
+				continue
+			}
+		}
+
+		if !s.clock.Now().Before(deadline) {
+			if changed {
+				return false, nil
+			}
+			return false, fmt.Errorf("password_change_failed: no passwd prompt within %v", defaultPromptResponseTimeout)
+		}
+		s.pty.SetReadDeadline(s.clock.Now().Add(100 * time.Millisecond))
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			pending += string(buf[:n])
+			continue
+		}
+		if err != nil && !isTimeoutError(err) {
+			if changed && isConnectionBroken(err) {
+				return true, nil
+			}
+			return false, fmt.Errorf("password_change_failed: connection closed before the password was changed: %w", err)
+		}
+		if changed && n == 0 && err == nil {
+			// Nothing more to read: the shell is up on this connection.
+			return false, nil
+		}
+		s.clock.Sleep(20 * time.Millisecond)
+	}
+}
+
+// passwordChangeAnswer returns what to type at the passwd prompt that output
+// ends with, and which prompt it is ("" when output ends with none).
+func passwordChangeAnswer(output, currentPassword, newPassword string) (answer, prompt string) {
+	switch {
+	case confirmPasswordPromptRe.MatchString(output):
+		return newPassword, "confirm"
+	case newPasswordPromptRe.MatchString(output):
+		return newPassword, "new"
+	case currentPasswordPromptRe.MatchString(output):
+		return currentPassword, "current"
+	}
+	return "", ""
+}
@@ -0,0 +1,203 @@
+package session
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+const expiredBanner = "WARNING: Your password has expired.\r\n" +
+	"You must change your password now and login again!\r\n" +
+	"Changing password for deploy.\r\n" +
+	"Current password: "
+
+// passwdResponder plays passwd: it answers each password written with the
+// next prompt, then with done.
+func passwdResponder(done string) func(string) string {
+	replies := []string{"\r\nNew password: ", "\r\nRetype new password: ", "\r\n" + done}
+	return func(string) string {
+		if len(replies) == 0 {
+			return ""
+		}
+		r := replies[0]
+		replies = replies[1:]
+		return r
+	}
+}
+
+func newPasswordChangeSession(pty *fakepty.PTY) *Session {
+	sess := NewSession("s1", "ssh",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionFileSystem(fakefs.New()),
+	)
+	sess.Host, sess.User = "db1", "deploy"
+	return sess
+}
+
+func TestPasswordChangeRequired(t *testing.T) {
+	tests := []struct {
+		output string
+		want   bool
+	}{
+		{"You are required to change your password immediately (administrator enforced)", true},
+		{"WARNING: Your password has expired.", true},
+		{"Password change required but no TTY available.", true},
+		{"Last login: Mon Jan  1 12:00:00 2024\r\n$ ", false},
+		{"Your password will expire in 5 days", false},
+	}
+	for _, tt := range tests {
+		if got := passwordChangeRequired(tt.output); got != tt.want {
+			t.Errorf("passwordChangeRequired(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordChangeAnswer(t *testing.T) {
+	tests := []struct {
+		output string
+		answer string
+		prompt string
+	}{
+		{"Changing password for deploy.\r\nCurrent password: ", "old", "current"},
+		{"(current) UNIX password: ", "old", "current"},
+		{"New password: ", "new", "new"},
+		{"Enter new UNIX password: ", "new", "new"},
+		{"Retype new password: ", "new", "confirm"},
+		{"Re-enter new password:", "new", "confirm"},
+		{"New password: \r\nsome output", "", ""},
+		{"Changing password for deploy.\r\n", "", ""},
+	}
+	for _, tt := range tests {
+		answer, prompt := passwordChangeAnswer(tt.output, "old", "new")
+		if answer != tt.answer || prompt != tt.prompt {
+			t.Errorf("passwordChangeAnswer(%q) = %q, %q; want %q, %q", tt.output, answer, prompt, tt.answer, tt.prompt)
+		}
+	}
+}
+
+func TestChangeExpiredPassword_ServerHangsUp(t *testing.T) {
+	pty := fakepty.New().
+		SetResponder(passwdResponder("passwd: all authentication tokens updated successfully.\r\n")).
+		SetReadError(io.EOF)
+	sess := newPasswordChangeSession(pty)
+	sess.NewPassword = "n3w-secret"
+
+	closed, err := sess.changeExpiredPassword(expiredBanner, "old-secret")
+	if err != nil {
+		t.Fatalf("changeExpiredPassword() error = %v", err)
+	}
+	if !closed {
+		t.Error("closed = false, want true after the server hung up")
+	}
+	if got, want := pty.Written(), "old-secret\nn3w-secret\nn3w-secret\n"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+	if sess.Password != "n3w-secret" {
+		t.Errorf("Password = %q, want the new password", sess.Password)
+	}
+}
+
+func TestChangeExpiredPassword_ShellOnSameConnection(t *testing.T) {
+	pty := fakepty.New().SetResponder(passwdResponder("passwd: password updated successfully\r\n$ "))
+	sess := newPasswordChangeSession(pty)
+	sess.NewPassword = "n3w-secret"
+
+	closed, err := sess.changeExpiredPassword("You are required to change your password immediately (administrator enforced)\r\nCurrent password: ", "old-secret")
+	if err != nil || closed {
+		t.Fatalf("changeExpiredPassword() = %v, %v; want false, nil", closed, err)
+	}
+	if sess.Password != "n3w-secret" {
+		t.Errorf("Password = %q, want the new password", sess.Password)
+	}
+}
+
+func TestChangeExpiredPassword_NoNewPassword(t *testing.T) {
+	pty := fakepty.New()
+	sess := newPasswordChangeSession(pty)
+
+	_, err := sess.changeExpiredPassword(expiredBanner, "old-secret")
+	if !errors.Is(err, ErrPasswordChangeRequired) {
+		t.Fatalf("error = %v, want ErrPasswordChangeRequired", err)
+	}
+	if !strings.HasPrefix(err.Error(), "password_change_required:") || !strings.Contains(err.Error(), "deploy@db1") {
+		t.Errorf("error = %q", err)
+	}
+	if pty.Written() != "" {
+		t.Errorf("written = %q, want nothing", pty.Written())
+	}
+}
+
+func TestChangeExpiredPassword_Rejected(t *testing.T) {
+	replies := []string{"\r\nNew password: ", "\r\nBAD PASSWORD: The password is shorter than 8 characters\r\nNew password: "}
+	pty := fakepty.New().SetResponder(func(string) string {
+		r := replies[0]
+		replies = replies[1:]
+		return r
+	})
+	sess := newPasswordChangeSession(pty)
+	sess.NewPassword = "short"
+
+	_, err := sess.changeExpiredPassword(expiredBanner, "old-secret")
+	if err == nil || err.Error() != "password_change_failed: BAD PASSWORD: The password is shorter than 8 characters" {
+		t.Fatalf("error = %v, want password_change_failed with the passwd message", err)
+	}
+	if sess.Password != "" {
+		t.Errorf("Password = %q, want it unchanged", sess.Password)
+	}
+}
+
+func TestChangeExpiredPassword_ConnectionClosedEarly(t *testing.T) {
+	pty := fakepty.New().SetReadError(io.EOF)
+	sess := newPasswordChangeSession(pty)
+	sess.NewPassword = "n3w-secret"
+
+	_, err := sess.changeExpiredPassword("WARNING: Your password has expired.\r\n", "old-secret")
+	if err == nil || !strings.Contains(err.Error(), "password_change_failed: connection closed") {
+		t.Fatalf("error = %v, want password_change_failed", err)
+	}
+}
+
+func TestNewPasswordFor_ServerConfig(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.SetEnv("DB1_NEW_PASSWORD", "from-env")
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{Name: "db1", Host: "db1.example.com", Auth: config.AuthConfig{NewPasswordEnv: "DB1_NEW_PASSWORD"}}}
+	sess := NewSession("s1", "ssh", WithConfig(cfg), WithSessionFileSystem(ffs))
+	sess.Host = "db1"
+
+	if got := sess.newPasswordFor(); got != "from-env" {
+		t.Errorf("newPasswordFor() = %q, want the server's new_password_env", got)
+	}
+	sess.NewPassword = "explicit"
+	if got := sess.newPasswordFor(); got != "explicit" {
+		t.Errorf("newPasswordFor() = %q, want NewPassword to win", got)
+	}
+}
+
+func TestNewPasswordFor_Identity(t *testing.T) {
+	ffs := fakefs.New()
+	ffs.SetEnv("SERVER_NEW_PASSWORD", "server")
+	ffs.SetEnv("DEPLOY_NEW_PASSWORD", "deploy")
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{
+		Name: "db1", Host: "db1.example.com",
+		Auth: config.AuthConfig{NewPasswordEnv: "SERVER_NEW_PASSWORD"},
+		Identities: []config.IdentityConfig{
+			{Name: "deploy", AuthConfig: config.AuthConfig{NewPasswordEnv: "DEPLOY_NEW_PASSWORD"}},
+		},
+	}}
+	sess := NewSession("s1", "ssh", WithConfig(cfg), WithSessionFileSystem(ffs))
+	sess.Host, sess.Identity = "db1", "deploy"
+
+	if got := sess.newPasswordFor(); got != "deploy" {
+		t.Errorf("newPasswordFor() = %q, want the identity's new_password_env", got)
+	}
+}
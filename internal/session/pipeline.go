@@ -0,0 +1,210 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MaxPipelineCommands bounds the commands of one ExecPipeline call, which
+// all go out on a single command line.
+const MaxPipelineCommands = 50
+
+// Statuses of a PipelineStep besides "completed".
+const (
+	pipelineStepIncomplete = "incomplete" // was running when the pipeline stopped
+	pipelineStepNotRun     = "not_run"
+)
+
+// PipelineStep is the result of one command of ExecPipeline.
+type PipelineStep struct {
+	Command   string `json:"command"`
+	Status    string `json:"status"`
+	ExitCode  *int   `json:"exit_code,omitempty"`
+	Stdout    string `json:"stdout"`
+	CommandID string `json:"command_id"`
+
+	startMarker string
+	endMarker   string
+}
+
+// PipelineResult is the result of ExecPipeline.
+type PipelineResult struct {
+	// Status is "completed" when every command ran, "timeout" when the
+	// pipeline was stopped before that and "error" when the connection
+	// failed; steps that finished before are parsed either way.
+	Status      string          `json:"status"`
+	Steps       []*PipelineStep `json:"steps"`
+	Completed   int             `json:"completed"`
+	Failed      int             `json:"failed"`
+	AsyncOutput string          `json:"async_output,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Cwd         string          `json:"cwd,omitempty"`
+}
+
+// ExecPipeline runs independent commands with a single write and reads
+// their output back in one pass: each command gets its own markers, the
+// wrapped commands are joined into one command line, and the combined
+// output is split per command. This saves a round-trip per command over
+// running them one by one with Exec. Commands run in order whatever the
+// exit codes; a command that waits for input stalls the pipeline until the
+// timeout, which then interrupts it and skips the rest.
+func (s *Session) ExecPipeline(commands []string, timeoutMs int) (*PipelineResult, error) {
+	if len(commands) == 0 {
+		return nil, fmt.Errorf("at least one command is required")
+	}
+	if len(commands) > MaxPipelineCommands {
+		return nil, fmt.Errorf("too many commands: %d (max %d)", len(commands), MaxPipelineCommands)
+	}
+	for i, command := range commands {
+		if strings.TrimSpace(command) == "" {
+			return nil, fmt.Errorf("command %d is empty", i+1)
+		}
+		if strings.ContainsAny(command, "\r\n") {
+			return nil, fmt.Errorf("command %d spans several lines; pipeline commands must be single-line", i+1)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.validateExecPreconditions(); err != nil {
+		return nil, err
+	}
+	if s.RawMode {
+		return nil, fmt.Errorf("pipelines need command markers and are not supported in raw mode")
+	}
+	// Refuse the whole pipeline rather than run only the commands that fit.
+	if maxCommands, _ := s.quotaLimits(); maxCommands > 0 && s.commandsRun+len(commands) > maxCommands {
+		return nil, &QuotaError{Quota: "max_commands_per_session", Used: int64(s.commandsRun), Limit: int64(maxCommands)}
+	}
+	for range commands {
+		if err := s.chargeCommand(); err != nil {
+			return nil, err
+		}
+	}
+	s.command = strings.Join(commands, "; ")
+	s.disarmPromptTimeout()
+
+	if err := s.ensureConnectionHealthy(); err != nil {
+		return nil, err
+	}
+
+	s.State = StateRunning
+	s.LastUsed = s.clock.Now()
+	s.outputBuffer.Reset()
+	s.outputBuffer.WriteString(s.startupOutput)
+	s.startupOutput = ""
+	s.collapseProgress = false
+	s.limits = nil
+
+	s.detectShellChange()
+	for _, command := range commands {
+		if mayChangeShell(command) {
+			s.shellCheckPending = true
+		}
+	}
+	steps, commandLine := s.buildPipeline(commands)
+	if err := s.writeCommandWithReconnect(commandLine); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.getTimeout(timeoutMs))
+	defer cancel()
+
+	result := &PipelineResult{Status: "completed", Steps: steps}
+	if err := s.readPipelineOutput(ctx, steps[len(steps)-1]); err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+	} else if ctx.Err() != nil {
+		s.forceKillCommand()
+		result.Status = "timeout"
+	}
+	s.State = StateIdle
+
+	s.parsePipelineOutput(result)
+	if result.Status == "completed" {
+		s.updateCwd()
+		result.Cwd = s.Cwd
+	}
+	s.outputBytes += int64(len(result.AsyncOutput))
+	for _, step := range steps {
+		s.outputBytes += int64(len(step.Stdout))
+	}
+	return result, nil
+}
+
+// buildPipeline gives each command its markers and joins the wrapped
+// commands into a single command line.
+func (s *Session) buildPipeline(commands []string) ([]*PipelineStep, string) {
+	steps := make([]*PipelineStep, len(commands))
+	wrapped := make([]string, len(commands))
+	for i, command := range commands {
+		cmdID := s.generateCommandID()
+		steps[i] = &PipelineStep{
+			Command:     command,
+			CommandID:   cmdID,
+			startMarker: startMarkerPrefix + cmdID + markerSuffix,
+			endMarker:   endMarkerPrefix + cmdID + markerSuffix,
+		}
+		wrapped[i] = strings.TrimSuffix(s.buildWrappedCommand(command, cmdID), "\n")
+	}
+	return steps, strings.Join(wrapped, "; ") + "\n"
+}
+
+// readPipelineOutput reads into outputBuffer until the last command's end
+// marker arrives or ctx is done. It returns an error only if reading fails.
+func (s *Session) readPipelineOutput(ctx context.Context, last *PipelineStep) error {
+	buf := make([]byte, s.readBufferSize())
+	for ctx.Err() == nil {
+		s.pty.SetReadDeadline(s.clock.Now().Add(s.pollInterval()))
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			s.outputBuffer.Write(buf[:n])
+			if _, found := s.extractExitCodeWithMarker(s.outputBuffer.String(), last.endMarker); found {
+				return nil
+			}
+		}
+		if err != nil && !os.IsTimeout(err) && !isTimeoutError(err) && err != io.EOF {
+			return s.connectionError(fmt.Errorf("read output: %w", err))
+		}
+	}
+	return nil
+}
+
+// parsePipelineOutput splits outputBuffer into the steps of result by their
+// markers. A step is completed once its end marker is there; the step whose
+// start marker is the last one seen was running when the pipeline stopped,
+// and any after it never ran.
+func (s *Session) parsePipelineOutput(result *PipelineResult) {
+	steps := result.Steps
+	// The command line is echoed as a whole; drop it so only the markers
+	// printed by echo are left.
+	output := stripCommandEcho(s.outputBuffer.String(), steps[0].startMarker, steps[len(steps)-1].endMarker)
+	normalized := s.normalizeLineEndings(output)
+
+	for i, step := range steps {
+		asyncOutput, stdout := s.parseMarkedOutput(output, step.startMarker, step.endMarker, step.Command)
+		if i == 0 {
+			result.AsyncOutput = asyncOutput
+		}
+		if exitCode, found := s.extractExitCodeWithMarker(output, step.endMarker); found {
+			step.Status = "completed"
+			step.ExitCode = &exitCode
+			step.Stdout = stdout
+			result.Completed++
+			if exitCode != 0 {
+				result.Failed++
+			}
+			continue
+		}
+		if findMarkerOnOwnLine(normalized, step.startMarker) != -1 {
+			step.Status = pipelineStepIncomplete
+			step.Stdout = stdout
+			continue
+		}
+		step.Status = pipelineStepNotRun
+	}
+}
@@ -0,0 +1,241 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+var pipelineStartPattern = regexp.MustCompile(`echo '` + startMarkerPrefix + `([0-9a-f]+)` + markerSuffix + `'`)
+
+// fakeStep is what the fake shell prints for one pipeline command.
+type fakeStep struct {
+	output string
+	exit   int
+	hang   bool // print output, then never finish
+}
+
+// pipelineShell answers a pipeline command line like a shell: it echoes the
+// line, then runs each command in turn, and answers pwd.
+func pipelineShell(steps ...fakeStep) func(string) string {
+	return func(written string) string {
+		if written == "pwd\n" {
+			return "pwd\r\n/home/user\r\n$ "
+		}
+		ids := pipelineStartPattern.FindAllStringSubmatch(written, -1)
+		if ids == nil {
+			return ""
+		}
+		var b strings.Builder
+		b.WriteString(strings.TrimSuffix(written, "\n") + "\r\n")
+		for i, m := range ids {
+			step := steps[i]
+			b.WriteString(startMarkerPrefix + m[1] + markerSuffix + "\r\n")
+			if step.output != "" {
+				b.WriteString(strings.ReplaceAll(step.output, "\n", "\r\n") + "\r\n")
+			}
+			if step.hang {
+				return b.String()
+			}
+			fmt.Fprintf(&b, "%s%s%s%d\r\n", endMarkerPrefix, m[1], markerSuffix, step.exit)
+		}
+		b.WriteString("$ ")
+		return b.String()
+	}
+}
+
+func newPipelineSession(pty *fakepty.PTY) *Session {
+	sess := NewSession("sess_pipe", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(config.DefaultConfig()),
+	)
+	sess.State = StateIdle
+	return sess
+}
+
+func TestExecPipeline_OneWrite(t *testing.T) {
+	pty := fakepty.New().SetResponder(pipelineShell(
+		fakeStep{output: "Linux"},
+		fakeStep{output: "ls: cannot access '/nope': No such file or directory", exit: 2},
+		fakeStep{output: "a b\nc d"},
+	))
+	sess := newPipelineSession(pty)
+
+	result, err := sess.ExecPipeline([]string{"uname", "ls /nope", "printf 'a b\\nc d\\n'"}, 5000)
+	if err != nil {
+		t.Fatalf("ExecPipeline() error = %v", err)
+	}
+
+	written := strings.TrimSuffix(pty.Written(), "pwd\n")
+	if strings.Count(written, "\n") != 1 || !strings.HasSuffix(written, "\n") {
+		t.Errorf("commands were not sent as one line: %q", written)
+	}
+	if result.Status != "completed" || result.Completed != 3 || result.Failed != 1 || result.Cwd != "/home/user" {
+		t.Errorf("result = %+v", result)
+	}
+
+	want := []struct {
+		stdout string
+		exit   int
+	}{
+		{"Linux", 0},
+		{"ls: cannot access '/nope': No such file or directory", 2},
+		{"a b\nc d", 0},
+	}
+	for i, w := range want {
+		step := result.Steps[i]
+		if step.Status != "completed" || step.ExitCode == nil || *step.ExitCode != w.exit || step.Stdout != w.stdout {
+			t.Errorf("step %d = {%s exit %v %q}, want {completed exit %d %q}", i, step.Status, step.ExitCode, step.Stdout, w.exit, w.stdout)
+		}
+	}
+	if result.Steps[0].CommandID == result.Steps[1].CommandID {
+		t.Error("steps share a command ID")
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestExecPipeline_MidPipelineTimeout(t *testing.T) {
+	pty := fakepty.New().SetResponder(pipelineShell(
+		fakeStep{output: "first"},
+		fakeStep{output: "waiting for lock...", hang: true},
+		fakeStep{output: "never"},
+	))
+	sess := newPipelineSession(pty)
+
+	result, err := sess.ExecPipeline([]string{"echo first", "flock /var/lock/x true", "echo never"}, 200)
+	if err != nil {
+		t.Fatalf("ExecPipeline() error = %v", err)
+	}
+	if result.Status != "timeout" || result.Completed != 1 {
+		t.Errorf("Status = %q, Completed = %d, want timeout, 1", result.Status, result.Completed)
+	}
+	if s := result.Steps[0]; s.Status != "completed" || s.Stdout != "first" {
+		t.Errorf("step 0 = %+v, want completed", s)
+	}
+	if s := result.Steps[1]; s.Status != "incomplete" || s.Stdout != "waiting for lock..." || s.ExitCode != nil {
+		t.Errorf("step 1 = %+v, want incomplete with its partial output", s)
+	}
+	if s := result.Steps[2]; s.Status != "not_run" || s.Stdout != "" {
+		t.Errorf("step 2 = %+v, want not_run", s)
+	}
+	if !pty.WasInterrupted() {
+		t.Error("hung pipeline was not interrupted")
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestExecPipeline_ReadError(t *testing.T) {
+	pty := fakepty.New().SetResponder(pipelineShell(
+		fakeStep{output: "done"},
+		fakeStep{output: "partial", hang: true},
+	))
+	pty.SetReadError(errors.New("connection reset by peer"))
+	sess := newPipelineSession(pty)
+
+	result, err := sess.ExecPipeline([]string{"echo done", "sleep 60"}, 5000)
+	if err != nil {
+		t.Fatalf("ExecPipeline() error = %v", err)
+	}
+	if result.Status != "error" || !strings.Contains(result.Error, "connection reset") {
+		t.Errorf("Status = %q, Error = %q, want a read error", result.Status, result.Error)
+	}
+	if result.Steps[0].Status != "completed" || result.Steps[0].Stdout != "done" {
+		t.Errorf("step 0 = %+v, want it parsed", result.Steps[0])
+	}
+	if result.Steps[1].Status != "incomplete" {
+		t.Errorf("step 1 status = %q, want incomplete", result.Steps[1].Status)
+	}
+}
+
+func TestExecPipeline_Invalid(t *testing.T) {
+	many := make([]string, MaxPipelineCommands+1)
+	for i := range many {
+		many[i] = "true"
+	}
+	tests := []struct {
+		name     string
+		commands []string
+		want     string
+	}{
+		{"none", nil, "at least one command"},
+		{"empty", []string{"true", " "}, "command 2 is empty"},
+		{"multi-line", []string{"for i in 1 2\ndo echo $i; done"}, "single-line"},
+		{"too many", many, "too many commands"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pty := fakepty.New()
+			sess := newPipelineSession(pty)
+			if _, err := sess.ExecPipeline(tt.commands, 1000); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ExecPipeline() error = %v, want %q", err, tt.want)
+			}
+			if pty.Written() != "" {
+				t.Errorf("wrote %q for an invalid pipeline", pty.Written())
+			}
+		})
+	}
+}
+
+func TestExecPipeline_ChargesEachCommand(t *testing.T) {
+	pty := fakepty.New().SetResponder(pipelineShell(fakeStep{output: "a"}, fakeStep{output: "b"}))
+	sess := newPipelineSession(pty)
+	sess.config.Security.MaxCommandsPerSession = 3
+
+	if _, err := sess.ExecPipeline([]string{"echo a", "echo b"}, 5000); err != nil {
+		t.Fatalf("ExecPipeline() error = %v", err)
+	}
+	if sess.commandsRun != 2 {
+		t.Errorf("commandsRun = %d, want 2", sess.commandsRun)
+	}
+	var quotaErr *QuotaError
+	if _, err := sess.ExecPipeline([]string{"echo a", "echo b"}, 5000); !errors.As(err, &quotaErr) {
+		t.Errorf("ExecPipeline() error = %v, want a quota error", err)
+	}
+	if sess.commandsRun != 2 {
+		t.Errorf("commandsRun = %d after a refused pipeline, want 2", sess.commandsRun)
+	}
+}
+
+// TestBuildPipeline_RealShell runs a pipeline command line through bash and
+// parses its output, checking quoting and per-command exit codes.
+func TestBuildPipeline_RealShell(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	sess := newPipelineSession(fakepty.New())
+
+	steps, line := sess.buildPipeline([]string{`printf '%s\n' "it's"`, "false", "echo a; echo b >&2"})
+	out, _ := exec.Command("bash", "-c", line).CombinedOutput()
+	sess.outputBuffer.WriteString(string(out))
+	result := &PipelineResult{Steps: steps}
+	sess.parsePipelineOutput(result)
+
+	want := []struct {
+		stdout string
+		exit   int
+	}{{"it's", 0}, {"", 1}, {"a\nb", 0}}
+	for i, w := range want {
+		step := result.Steps[i]
+		if step.Status != "completed" || step.ExitCode == nil || *step.ExitCode != w.exit || step.Stdout != w.stdout {
+			t.Errorf("step %d = {%s exit %v %q}, want exit %d %q", i, step.Status, step.ExitCode, step.Stdout, w.exit, w.stdout)
+		}
+	}
+	if result.Completed != 3 || result.Failed != 1 {
+		t.Errorf("Completed = %d, Failed = %d, want 3, 1", result.Completed, result.Failed)
+	}
+}
@@ -0,0 +1,28 @@
+package session
+
+import "time"
+
+const (
+	// defaultPollInterval is how long each read of a command's output
+	// waits for data before the read loop checks its timeout again.
+	defaultPollInterval = 100 * time.Millisecond
+
+	// stallWindow is how long a running command's output must be quiet
+	// before it is checked for an input prompt. It is fixed in time, so a
+	// longer poll interval means fewer, not slower, checks.
+	stallWindow = 1500 * time.Millisecond
+)
+
+// pollInterval returns the read loop's poll interval, from
+// config.PTY.PollIntervalMs or the default.
+func (s *Session) pollInterval() time.Duration {
+	if s.config != nil && s.config.PTY.PollIntervalMs > 0 {
+		return time.Duration(s.config.PTY.PollIntervalMs) * time.Millisecond
+	}
+	return defaultPollInterval
+}
+
+// stallPolls returns how many quiet polls add up to stallWindow.
+func (s *Session) stallPolls() int {
+	return max(1, int(stallWindow/s.pollInterval()))
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+// deadlinePTY records the read deadlines set on a fake PTY, relative to the
+// clock at the time they were set.
+type deadlinePTY struct {
+	*fakepty.PTY
+	clock     *fakeclock.Clock
+	deadlines []time.Duration
+}
+
+func (p *deadlinePTY) SetReadDeadline(t time.Time) error {
+	p.deadlines = append(p.deadlines, t.Sub(p.clock.Now()))
+	return p.PTY.SetReadDeadline(t)
+}
+
+func newPollSession(pollIntervalMs int) (*Session, *deadlinePTY) {
+	cfg := config.DefaultConfig()
+	cfg.PTY.PollIntervalMs = pollIntervalMs
+	clock := fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	pty := &deadlinePTY{PTY: fakepty.New(), clock: clock}
+	sess := NewSession("sess_poll", "local",
+		WithPTY(pty),
+		WithSessionClock(clock),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(cfg),
+	)
+	return sess, pty
+}
+
+func TestPollInterval(t *testing.T) {
+	tests := []struct {
+		ms         int
+		want       time.Duration
+		stallPolls int
+	}{
+		{0, 100 * time.Millisecond, 15},
+		{25, 25 * time.Millisecond, 60},
+		{500, 500 * time.Millisecond, 3},
+		{5000, 5 * time.Second, 1},
+	}
+	for _, tt := range tests {
+		sess, _ := newPollSession(tt.ms)
+		if got := sess.pollInterval(); got != tt.want {
+			t.Errorf("PollIntervalMs %d: pollInterval() = %v, want %v", tt.ms, got, tt.want)
+		}
+		if got := sess.stallPolls(); got != tt.stallPolls {
+			t.Errorf("PollIntervalMs %d: stallPolls() = %d, want %d", tt.ms, got, tt.stallPolls)
+		}
+	}
+
+	if got := (&Session{}).pollInterval(); got != defaultPollInterval {
+		t.Errorf("pollInterval() without config = %v, want %v", got, defaultPollInterval)
+	}
+}
+
+func TestReadWithTimeout_EndsWithFakeClock(t *testing.T) {
+	// The fake clock never moves and the fake PTY returns (0, nil) when it
+	// has nothing, so only counting the empty reads ends the loop.
+	sess, pty := newPollSession(100)
+	pty.AddResponse("some output")
+
+	buf := make([]byte, 64)
+	n, _ := sess.readWithTimeout(buf, 300*time.Millisecond)
+	if string(buf[:n]) != "some output" {
+		t.Errorf("read %q", buf[:n])
+	}
+	// One read with data, then 300ms of 50ms polls.
+	if len(pty.deadlines) != 7 {
+		t.Errorf("%d reads, want 7", len(pty.deadlines))
+	}
+	for _, d := range pty.deadlines {
+		if d != 50*time.Millisecond {
+			t.Errorf("read deadline %v, want half the poll interval", d)
+			break
+		}
+	}
+}
+
+func TestRestoreState_WithFakeClock(t *testing.T) {
+	sess, pty := newPollSession(0)
+
+	sess.restoreState("/srv/app", map[string]string{"APP_ENV": "staging", "PATH": "/usr/bin"})
+
+	written := pty.Written()
+	if !strings.Contains(written, `cd "/srv/app"`) || !strings.Contains(written, `export APP_ENV="staging"`) {
+		t.Errorf("written = %q", written)
+	}
+	if strings.Contains(written, "PATH") {
+		t.Errorf("restored PATH: %q", written)
+	}
+	if sess.Cwd != "/srv/app" {
+		t.Errorf("Cwd = %q", sess.Cwd)
+	}
+}
+
+func TestExec_ReadsWithPollInterval(t *testing.T) {
+	sess, pty := newPollSession(250)
+	sess.State = StateIdle
+	pty.SetResponder(func(written string) string {
+		if written == "pwd\n" {
+			return "/home/user\r\n$ "
+		}
+		if m := pipelineStartPattern.FindStringSubmatch(written); m != nil {
+			return startMarkerPrefix + m[1] + markerSuffix + "\r\nok\r\n" + endMarkerPrefix + m[1] + markerSuffix + "0\r\n$ "
+		}
+		return ""
+	})
+
+	result, err := sess.Exec("true", 1000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q", result.Status)
+	}
+	if !containsDuration(pty.deadlines, 250*time.Millisecond) {
+		t.Errorf("read deadlines %v, want reads polling every 250ms", pty.deadlines)
+	}
+}
+
+func containsDuration(ds []time.Duration, want time.Duration) bool {
+	for _, d := range ds {
+		if d == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package session
+
+import "strings"
+
+// normalizeLineEndings converts CRLF to LF and resolves lone carriage
+// returns. By default they are dropped; with collapse_progress each line is
+// rendered the way a terminal shows it, so a progress bar redrawn a thousand
+// times with \r leaves only its final state.
+func (s *Session) normalizeLineEndings(output string) string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	if !s.collapseProgress {
+		return strings.ReplaceAll(output, "\r", "")
+	}
+	return collapseCarriageReturns(output)
+}
+
+// collapseCarriageReturns renders \r-overwritten lines: text after a \r is
+// written over the start of the line, like a terminal cursor returning to
+// column 0.
+func collapseCarriageReturns(output string) string {
+	if !strings.Contains(output, "\r") {
+		return output
+	}
+
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		if !strings.Contains(line, "\r") {
+			continue
+		}
+		var rendered []rune
+		for _, segment := range strings.Split(line, "\r") {
+			seg := []rune(segment)
+			if len(seg) >= len(rendered) {
+				rendered = seg
+				continue
+			}
+			copy(rendered, seg)
+		}
+		lines[i] = string(rendered)
+	}
+	return strings.Join(lines, "\n")
+}
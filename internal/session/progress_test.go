@@ -0,0 +1,104 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestCollapseCarriageReturns(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"no carriage returns", "a\nb", "a\nb"},
+		{"progress bar", "Downloading\n 10%\r 50%\r100%\nDone", "Downloading\n100%\nDone"},
+		{"shorter redraw keeps tail", "Progress: 100%\rDone", "Doneress: 100%"},
+		{"trailing carriage return", "50%\r", "50%"},
+		{"multibyte", "█░░ 33%\r███ 100%", "███ 100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := collapseCarriageReturns(tt.input); got != tt.want {
+				t.Errorf("collapseCarriageReturns(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	sess := &Session{}
+	input := "line\r\n 1%\r 2%\r 3%\r\n"
+	if got := sess.normalizeLineEndings(input); got != "line\n 1% 2% 3%\n" {
+		t.Errorf("default = %q, want carriage returns dropped", got)
+	}
+
+	sess.collapseProgress = true
+	if got := sess.normalizeLineEndings(input); got != "line\n 3%\n" {
+		t.Errorf("collapse = %q, want final state only", got)
+	}
+}
+
+func newProgressSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_progress", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestExecWithOptions_CollapseProgress(t *testing.T) {
+	var bar strings.Builder
+	for i := 0; i <= 100; i++ {
+		bar.WriteString("\rFetching ")
+		bar.WriteString(strings.Repeat("#", i/10))
+	}
+	output := "Reading lists\r\n" + bar.String() + "\r\nFetched 3 MB\r\n"
+
+	sess, pty := newProgressSession(t)
+	pty.AddResponse(buildCommandOutput("01020304", output, 0))
+
+	result, err := sess.ExecWithOptions("apt-get download pkg", 5000, ExecOptions{CollapseProgress: true})
+	if err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	want := "Reading lists\nFetching ##########\nFetched 3 MB"
+	if result.Stdout != want {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, want)
+	}
+}
+
+func TestExecWithOptions_CollapseProgressIsPerCommand(t *testing.T) {
+	sess, pty := newProgressSession(t)
+	pty.AddResponse(buildCommandOutput("01020304", "1%\r2%", 0))
+
+	if _, err := sess.ExecWithOptions("first", 5000, ExecOptions{CollapseProgress: true}); err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	if !sess.collapseProgress {
+		t.Fatal("collapseProgress should stay set for ProvideInput on the same command")
+	}
+
+	sess.random = fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})
+	pty.AddResponse(buildCommandOutput("01020304", "1%\r2%", 0))
+	result, err := sess.Exec("second", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Stdout != "1%2%" {
+		t.Errorf("Stdout = %q, want default carriage return stripping", result.Stdout)
+	}
+}
@@ -0,0 +1,87 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func initLocalWithConfig(t *testing.T, cfg *config.Config) *fakepty.PTY {
+	t.Helper()
+	pty := newReadyPTY()
+	sess := NewSession("sess_prompt", "local",
+		WithConfig(cfg),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionFileSystem(fakefs.New()),
+	)
+	sess.localPTYFactory = func(localpty.PTYOptions) (PTY, string, error) {
+		return pty, "/bin/bash", nil
+	}
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	return pty
+}
+
+func TestInitialize_NormalizesPrompt(t *testing.T) {
+	pty := initLocalWithConfig(t, config.DefaultConfig())
+	if !strings.Contains(pty.Written(), "PS1='$ '") {
+		t.Errorf("written = %q, want PS1 reset", pty.Written())
+	}
+}
+
+func TestInitialize_KeepsPromptWhenDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Shell.NormalizePrompt = false
+	pty := initLocalWithConfig(t, cfg)
+	if strings.Contains(pty.Written(), "PS1=") {
+		t.Errorf("written = %q, want the user's prompt left alone", pty.Written())
+	}
+}
+
+func TestParsePwdOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"bare", "/home/user\n", "/home/user"},
+		{"echo", "pwd\r\n/home/user\r\n$ ", "/home/user"},
+		{"prompt with fake path", "/fake/prompt/path$ pwd\r\n/home/user\r\n/fake/prompt/path$ ", "/home/user"},
+		{"root prompt", "/root # pwd\n/srv/app\n/root # ", "/srv/app"},
+		{"bracketed prompt", "[me@box /etc]$ pwd\n/var/log\n", "/var/log"},
+		{"dir named pwd", "pwd\n/opt/pwd\n", "/opt/pwd"},
+		{"only prompt", "/fake/prompt/path$ ", ""},
+		{"only echo", "/fake/prompt/path$ pwd\r\n", ""},
+		{"dir ending in dollar", "$ pwd\r\n/srv/cost$\r\n$ ", "/srv/cost$"},
+		{"dir ending in hash", "/root # pwd\n/tmp/build #\n/tmp/build # ", "/tmp/build #"},
+		{"dir ending in gt", "pwd\n/data/a>\n$ ", "/data/a>"},
+		{"nothing", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePwdOutput(tt.output); got != tt.want {
+				t.Errorf("parsePwdOutput(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession_UpdateCwd_IgnoresPathInPrompt(t *testing.T) {
+	pty := fakepty.New()
+	clock := fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sess := &Session{pty: pty, clock: clock, Cwd: "/old"}
+
+	pty.AddResponse("/fake/prompt/path$ pwd\r\n/home/user/project\r\n/fake/prompt/path$ ")
+	sess.updateCwd()
+
+	if sess.Cwd != "/home/user/project" {
+		t.Errorf("Cwd = %q, want /home/user/project", sess.Cwd)
+	}
+}
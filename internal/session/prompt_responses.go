@@ -0,0 +1,92 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// defaultPromptResponseTimeout bounds the wait for each login prompt.
+const defaultPromptResponseTimeout = 15 * time.Second
+
+// PromptResponse is one step of a scripted login sequence, e.g. a bastion
+// asking for a password and then a TOTP code. Steps are answered in order
+// while the session connects, before the shell is set up.
+type PromptResponse struct {
+	Pattern  string        // regex matched against the output since the previous step
+	Response string        // sent followed by a newline
+	Mask     bool          // keep the response out of logs
+	Timeout  time.Duration // wait for the pattern this long (0 = 15s)
+}
+
+// ValidatePromptResponses checks that every step has a valid pattern.
+func ValidatePromptResponses(steps []PromptResponse) error {
+	for i, step := range steps {
+		if step.Pattern == "" {
+			return fmt.Errorf("prompt_responses[%d]: pattern is required", i)
+		}
+		if _, err := regexp.Compile(step.Pattern); err != nil {
+			return fmt.Errorf("prompt_responses[%d]: invalid pattern %q: %w", i, step.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// answerPromptResponses plays the session's PromptResponses against the
+// freshly opened PTY. Each step waits for its pattern to appear in the output
+// received since the previous match, then writes its response.
+func (s *Session) answerPromptResponses() error {
+	if len(s.PromptResponses) == 0 {
+		return nil
+	}
+	if err := ValidatePromptResponses(s.PromptResponses); err != nil {
+		return err
+	}
+
+	buf := make([]byte, s.readBufferSize())
+	var pending []byte
+	for i, step := range s.PromptResponses {
+		re := regexp.MustCompile(step.Pattern)
+		timeout := step.Timeout
+		if timeout <= 0 {
+			timeout = defaultPromptResponseTimeout
+		}
+		deadline := s.clock.Now().Add(timeout)
+
+		for {
+			if loc := re.FindIndex(pending); loc != nil {
+				pending = pending[loc[1]:]
+				break
+			}
+			if !s.clock.Now().Before(deadline) {
+				return fmt.Errorf("prompt_responses[%d]: no output matching %q within %v", i, step.Pattern, timeout)
+			}
+			s.pty.SetReadDeadline(s.clock.Now().Add(100 * time.Millisecond))
+			n, err := s.pty.Read(buf)
+			if n > 0 {
+				pending = append(pending, buf[:n]...)
+				continue
+			}
+			if err != nil && !isTimeoutError(err) {
+				return fmt.Errorf("prompt_responses[%d]: read: %w", i, err)
+			}
+			s.clock.Sleep(20 * time.Millisecond)
+		}
+
+		logged := step.Response
+		if step.Mask {
+			logged = "********"
+		}
+		slog.Debug("answering login prompt",
+			slog.String("session_id", s.ID),
+			slog.Int("step", i),
+			slog.String("pattern", step.Pattern),
+			slog.String("response", logged),
+		)
+		if _, err := s.pty.WriteString(step.Response + "\n"); err != nil {
+			return fmt.Errorf("prompt_responses[%d]: write: %w", i, err)
+		}
+	}
+	return nil
+}
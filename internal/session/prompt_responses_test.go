@@ -0,0 +1,89 @@
+package session
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func TestAnswerPromptResponses_Sequence(t *testing.T) {
+	pty := fakepty.New().AddResponses(
+		"Welcome to bastion\r\nPassword: ",
+		"\r\nVerification ",
+		"code: ",
+	)
+	sess := NewSession("s1", "ssh", WithPTY(pty), WithSessionClock(fakeclock.New(time.Now())))
+	sess.PromptResponses = []PromptResponse{
+		{Pattern: `Password:\s*$`, Response: "hunter2", Mask: true},
+		{Pattern: `Verification code:`, Response: "123456", Mask: true},
+	}
+
+	if err := sess.answerPromptResponses(); err != nil {
+		t.Fatalf("answerPromptResponses() error: %v", err)
+	}
+	if got, want := pty.Written(), "hunter2\n123456\n"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+}
+
+func TestAnswerPromptResponses_MatchesOnlyNewOutput(t *testing.T) {
+	// Both prompts arrive in one read; the second step must match the output
+	// after the first match, not the first prompt again.
+	pty := fakepty.New().AddResponse("Password: Password: ")
+	sess := NewSession("s1", "ssh", WithPTY(pty), WithSessionClock(fakeclock.New(time.Now())))
+	sess.PromptResponses = []PromptResponse{
+		{Pattern: `Password: `, Response: "first"},
+		{Pattern: `Password: `, Response: "second"},
+	}
+
+	if err := sess.answerPromptResponses(); err != nil {
+		t.Fatalf("answerPromptResponses() error: %v", err)
+	}
+	if got, want := pty.Written(), "first\nsecond\n"; got != want {
+		t.Errorf("written = %q, want %q", got, want)
+	}
+}
+
+func TestAnswerPromptResponses_Timeout(t *testing.T) {
+	pty := fakepty.New().AddResponse("Password: ")
+	sess := NewSession("s1", "ssh", WithPTY(pty), WithSessionClock(realclock.New()))
+	sess.PromptResponses = []PromptResponse{
+		{Pattern: `Password:`, Response: "pw"},
+		{Pattern: `Verification code:`, Response: "123456", Timeout: 50 * time.Millisecond},
+	}
+
+	err := sess.answerPromptResponses()
+	if err == nil || !strings.Contains(err.Error(), "prompt_responses[1]") {
+		t.Fatalf("err = %v, want timeout on step 1", err)
+	}
+	if got := pty.Written(); got != "pw\n" {
+		t.Errorf("written = %q, want only the first response", got)
+	}
+}
+
+func TestAnswerPromptResponses_ConnectionClosed(t *testing.T) {
+	pty := fakepty.New().SetReadError(io.EOF)
+	sess := NewSession("s1", "ssh", WithPTY(pty), WithSessionClock(fakeclock.New(time.Now())))
+	sess.PromptResponses = []PromptResponse{{Pattern: `Password:`, Response: "pw"}}
+
+	if err := sess.answerPromptResponses(); err == nil {
+		t.Fatal("expected error when the connection closes before the prompt")
+	}
+}
+
+func TestValidatePromptResponses(t *testing.T) {
+	if err := ValidatePromptResponses([]PromptResponse{{Pattern: `ok:`}}); err != nil {
+		t.Errorf("valid pattern: %v", err)
+	}
+	if err := ValidatePromptResponses([]PromptResponse{{Pattern: ""}}); err == nil {
+		t.Error("expected error for empty pattern")
+	}
+	if err := ValidatePromptResponses([]PromptResponse{{Pattern: `(`}}); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"log/slog"
+	"time"
+)
+
+// promptTimeout returns how long an awaiting_input prompt may stay
+// unanswered before the pending command is interrupted. Zero disables it.
+func (s *Session) promptTimeout() time.Duration {
+	if s.config == nil {
+		return 0
+	}
+	return s.config.PromptDetection.InputTimeout
+}
+
+// armPromptTimeout starts the prompt timer when result left the session
+// awaiting input, and reports the deadline in the result.
+// Must be called with s.mu held.
+func (s *Session) armPromptTimeout(result *ExecResult) {
+	s.disarmPromptTimeout()
+
+	if result == nil || s.State != StateAwaitingInput {
+		return
+	}
+	if result.Status != "awaiting_input" && result.Status != "indeterminate" {
+		return
+	}
+	timeout := s.promptTimeout()
+	if timeout <= 0 {
+		return
+	}
+
+	result.InputTimeoutSeconds = int(timeout.Seconds())
+
+	cancel := make(chan struct{})
+	s.promptCancel = cancel
+	expired := s.clock.After(timeout)
+
+	go func() {
+		select {
+		case <-expired:
+			s.expirePrompt(cancel, timeout)
+		case <-cancel:
+		}
+	}()
+}
+
+// disarmPromptTimeout stops a pending prompt timer, if any.
+// Must be called with s.mu held.
+func (s *Session) disarmPromptTimeout() {
+	if s.promptCancel != nil {
+		close(s.promptCancel)
+		s.promptCancel = nil
+	}
+}
+
+// expirePrompt interrupts the pending command once the prompt timer identified
+// by cancel fires, returning the session to idle.
+func (s *Session) expirePrompt(cancel chan struct{}, timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The timer was disarmed or replaced while we were waiting for the lock.
+	if s.promptCancel != cancel {
+		return
+	}
+	s.promptCancel = nil
+
+	if s.State != StateAwaitingInput || s.pty == nil {
+		return
+	}
+
+	slog.Warn("prompt_timeout",
+		slog.String("session_id", s.ID),
+		slog.Duration("timeout", timeout),
+	)
+
+	if err := s.pty.Interrupt(); err != nil {
+		slog.Warn("failed to interrupt timed out prompt",
+			slog.String("session_id", s.ID),
+			slog.String("error", err.Error()),
+		)
+	}
+	s.State = StateIdle
+	s.pendingPrompt = nil
+	s.outputBuffer.Reset()
+}
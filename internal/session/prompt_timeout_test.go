@@ -0,0 +1,134 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+// newPromptingSession returns a session whose next Exec stops at a password prompt.
+func newPromptingSession(t *testing.T, timeout time.Duration) (*Session, *fakepty.PTY, *fakeclock.Clock) {
+	t.Helper()
+	pty := fakepty.New()
+	clock := fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.InputTimeout = timeout
+
+	sess := NewSession("sess_prompt", "local",
+		WithPTY(pty),
+		WithSessionClock(clock),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	startMarker := startMarkerPrefix + "01020304" + markerSuffix
+	pty.AddResponse(fmt.Sprintf("%s\n[sudo] password for user: ", startMarker))
+	for i := 0; i < 20; i++ {
+		pty.AddResponse("")
+	}
+	return sess, pty, clock
+}
+
+// waitForState polls until the session reaches want or the deadline passes.
+func waitForState(t *testing.T, sess *Session, want State) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		sess.mu.Lock()
+		state := sess.State
+		sess.mu.Unlock()
+		if state == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("session did not reach state %q", want)
+}
+
+func TestPromptTimeout_InterruptsUnansweredPrompt(t *testing.T) {
+	sess, pty, clock := newPromptingSession(t, time.Minute)
+
+	result, err := sess.Exec("sudo ls", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "awaiting_input" {
+		t.Fatalf("Status = %q, want awaiting_input", result.Status)
+	}
+	if result.InputTimeoutSeconds != 60 {
+		t.Errorf("InputTimeoutSeconds = %d, want 60", result.InputTimeoutSeconds)
+	}
+
+	clock.Advance(59 * time.Second)
+	if pty.WasInterrupted() {
+		t.Fatal("interrupted before the timeout elapsed")
+	}
+
+	clock.Advance(2 * time.Second)
+	waitForState(t, sess, StateIdle)
+
+	if !pty.WasInterrupted() {
+		t.Error("expected the pending command to be interrupted")
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.pendingPrompt != nil {
+		t.Error("pendingPrompt should be cleared after timeout")
+	}
+}
+
+func TestPromptTimeout_DisarmedByInterrupt(t *testing.T) {
+	sess, _, clock := newPromptingSession(t, time.Minute)
+
+	if _, err := sess.Exec("sudo ls", 5000); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if _, err := sess.Interrupt(); err != nil {
+		t.Fatalf("Interrupt error: %v", err)
+	}
+
+	sess.mu.Lock()
+	armed := sess.promptCancel != nil
+	sess.mu.Unlock()
+	if armed {
+		t.Error("prompt timer should be disarmed after Interrupt")
+	}
+
+	// Put the session back into awaiting_input by hand; the stale timer must not fire.
+	sess.mu.Lock()
+	sess.State = StateAwaitingInput
+	sess.mu.Unlock()
+	clock.Advance(2 * time.Minute)
+	time.Sleep(20 * time.Millisecond)
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.State != StateAwaitingInput {
+		t.Errorf("State = %q, stale timer should not have fired", sess.State)
+	}
+}
+
+func TestPromptTimeout_Disabled(t *testing.T) {
+	sess, _, _ := newPromptingSession(t, 0)
+
+	result, err := sess.Exec("sudo ls", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.InputTimeoutSeconds != 0 {
+		t.Errorf("InputTimeoutSeconds = %d, want 0 when disabled", result.InputTimeoutSeconds)
+	}
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.promptCancel != nil {
+		t.Error("no timer should be armed when the timeout is disabled")
+	}
+}
@@ -0,0 +1,25 @@
+package session
+
+import (
+	"fmt"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+)
+
+// proxyOptions returns the proxy configured for the session's server, or
+// nil to connect directly.
+func (s *Session) proxyOptions() (*ssh.ProxyOptions, error) {
+	srv := s.serverConfig()
+	if srv == nil || srv.Proxy.URL == "" {
+		return nil, nil
+	}
+	scheme, address, err := srv.Proxy.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("server %s: %w", srv.Name, err)
+	}
+	proxy := &ssh.ProxyOptions{Scheme: scheme, Address: address, User: srv.Proxy.User}
+	if srv.Proxy.PasswordEnv != "" {
+		proxy.Password = s.fs.Getenv(srv.Proxy.PasswordEnv)
+	}
+	return proxy, nil
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+)
+
+func newProxySession(fs *fakefs.FS, cfg *config.Config) *Session {
+	s := NewSession("sess_proxy", "ssh",
+		WithSessionFileSystem(fs),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithConfig(cfg),
+	)
+	s.Host = "db.internal"
+	s.User = "dba"
+	return s
+}
+
+func TestProxyOptions_ServerConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{
+		Name: "db",
+		Host: "db.internal",
+		Proxy: config.ProxyConfig{
+			URL:         "socks5://proxy.corp:1080",
+			User:        "svc",
+			PasswordEnv: "PROXY_PASSWORD",
+		},
+	}}
+	fs := fakefs.New()
+	fs.SetEnv("PROXY_PASSWORD", "hunter2")
+
+	proxy, err := newProxySession(fs, cfg).proxyOptions()
+	if err != nil {
+		t.Fatalf("proxyOptions() error = %v", err)
+	}
+	want := ssh.ProxyOptions{Scheme: ssh.ProxySOCKS5, Address: "proxy.corp:1080", User: "svc", Password: "hunter2"}
+	if proxy == nil || *proxy != want {
+		t.Errorf("proxyOptions() = %+v, want %+v", proxy, want)
+	}
+}
+
+func TestProxyOptions_NamedServer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "db-east", Host: "db.internal", Proxy: config.ProxyConfig{URL: "socks5://bastion-east:1080"}},
+		{Name: "db-west", Host: "db.internal", Proxy: config.ProxyConfig{URL: "socks5://bastion-west:1080"}},
+	}
+	sess := newProxySession(fakefs.New(), cfg)
+	sess.Server = "db-west"
+
+	proxy, err := sess.proxyOptions()
+	if err != nil || proxy == nil || proxy.Address != "bastion-west:1080" {
+		t.Errorf("proxyOptions() = %+v, %v; want the named server's proxy", proxy, err)
+	}
+}
+
+func TestProxyOptions_Direct(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{Name: "web", Host: "web.internal", Proxy: config.ProxyConfig{URL: "http://proxy.corp:3128"}}}
+
+	proxy, err := newProxySession(fakefs.New(), cfg).proxyOptions()
+	if err != nil || proxy != nil {
+		t.Errorf("proxyOptions() = %+v, %v; want a direct connection for an unconfigured host", proxy, err)
+	}
+	if proxy, err := newProxySession(fakefs.New(), nil).proxyOptions(); err != nil || proxy != nil {
+		t.Errorf("proxyOptions() without config = %+v, %v", proxy, err)
+	}
+}
+
+func TestClassifyConnectionError_ProxyAuth(t *testing.T) {
+	sess := newProxySession(fakefs.New(), nil)
+	err := fmt.Errorf("connect: ssh dial db.internal:22: proxy http://proxy.corp:3128: %w: credentials rejected", ssh.ErrProxyAuth)
+	if code := sess.classifyConnectionError(err); code != ConnAuthRevoked {
+		t.Errorf("classifyConnectionError() = %q, want %q", code, ConnAuthRevoked)
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import "fmt"
+
+// QuotaError is returned by Exec, ExecWithStdin, ProvideInput and SendRaw
+// once a session has used up security.max_commands_per_session or
+// max_output_bytes_per_session.
+type QuotaError struct {
+	Quota string // config key of the exhausted quota
+	Used  int64
+	Limit int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota_exceeded: security.%s reached (%d of %d); quotas reset only when a new session is created",
+		e.Quota, e.Used, e.Limit)
+}
+
+// QuotaUsage is a session's usage against its quotas. A zero maximum means
+// unlimited.
+type QuotaUsage struct {
+	Commands       int   `json:"commands"`
+	MaxCommands    int   `json:"max_commands,omitempty"`
+	OutputBytes    int64 `json:"output_bytes"`
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+}
+
+// quotaLimits returns the configured quotas (0 = unlimited).
+func (s *Session) quotaLimits() (int, int64) {
+	if s.config == nil {
+		return 0, 0
+	}
+	return s.config.Security.MaxCommandsPerSession, s.config.Security.MaxOutputBytesPerSession
+}
+
+// chargeCommand counts a new command against the quotas, or returns a
+// *QuotaError if either is used up. Caller must hold s.mu.
+func (s *Session) chargeCommand() error {
+	maxCommands, maxBytes := s.quotaLimits()
+	if maxCommands > 0 && s.commandsRun >= maxCommands {
+		return &QuotaError{Quota: "max_commands_per_session", Used: int64(s.commandsRun), Limit: int64(maxCommands)}
+	}
+	if maxBytes > 0 && s.outputBytes >= maxBytes {
+		return &QuotaError{Quota: "max_output_bytes_per_session", Used: s.outputBytes, Limit: maxBytes}
+	}
+	s.commandsRun++
+	return nil
+}
+
+// chargeOutput counts the output returned in result. Output that puts the
+// session over its byte quota is still returned; the next command is
+// rejected. Caller must hold s.mu.
+func (s *Session) chargeOutput(result *ExecResult) {
+	if result != nil {
+		s.outputBytes += int64(len(result.Stdout) + len(result.Stderr) + len(result.AsyncOutput))
+	}
+}
+
+// quotaUsage reports usage against the quotas. Caller must hold s.mu.
+func (s *Session) quotaUsage() QuotaUsage {
+	maxCommands, maxBytes := s.quotaLimits()
+	return QuotaUsage{
+		Commands:       s.commandsRun,
+		MaxCommands:    maxCommands,
+		OutputBytes:    s.outputBytes,
+		MaxOutputBytes: maxBytes,
+	}
+}
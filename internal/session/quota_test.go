@@ -0,0 +1,148 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newQuotaSession(t *testing.T, maxCommands int, maxBytes int64) (*Session, *fakepty.PTY) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Security.MaxCommandsPerSession = maxCommands
+	cfg.Security.MaxOutputBytesPerSession = maxBytes
+	pty := fakepty.New()
+	sess := NewSession("sess_quota", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestQuota_MaxCommands(t *testing.T) {
+	sess, pty := newQuotaSession(t, 2, 0)
+
+	for i, id := range []string{"00010203", "04050607"} {
+		pty.AddResponse(buildCommandOutput(id, "ok", 0))
+		if _, err := sess.Exec("true", 1000); err != nil {
+			t.Fatalf("command %d: %v", i+1, err)
+		}
+	}
+
+	written := len(pty.Written())
+	_, err := sess.Exec("true", 1000)
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("third command error = %v, want *QuotaError", err)
+	}
+	if quotaErr.Quota != "max_commands_per_session" || quotaErr.Used != 2 || quotaErr.Limit != 2 {
+		t.Errorf("QuotaError = %+v", quotaErr)
+	}
+	if !strings.HasPrefix(err.Error(), "quota_exceeded:") {
+		t.Errorf("error = %q, want quota_exceeded prefix", err)
+	}
+	if len(pty.Written()) != written {
+		t.Error("rejected command was written to the terminal")
+	}
+	if _, err := sess.ExecWithStdin("cat", "x", 1000); !errors.As(err, &quotaErr) {
+		t.Errorf("ExecWithStdin error = %v, want *QuotaError", err)
+	}
+}
+
+func TestQuota_MaxOutputBytes(t *testing.T) {
+	sess, pty := newQuotaSession(t, 0, 10)
+
+	pty.AddResponse(buildCommandOutput("00010203", "0123456789abcdef", 0))
+	result, err := sess.Exec("cat big", 1000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Stdout != "0123456789abcdef" {
+		t.Errorf("output over the quota was not returned: %q", result.Stdout)
+	}
+
+	_, err = sess.Exec("true", 1000)
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || quotaErr.Quota != "max_output_bytes_per_session" {
+		t.Fatalf("error = %v, want max_output_bytes_per_session QuotaError", err)
+	}
+	if quotaErr.Used != 16 || quotaErr.Limit != 10 {
+		t.Errorf("QuotaError = %+v, want 16 of 10", quotaErr)
+	}
+}
+
+func TestQuota_StatusReportsUsage(t *testing.T) {
+	sess, pty := newQuotaSession(t, 5, 1000)
+
+	pty.AddResponse(buildCommandOutput("00010203", "hello", 0))
+	if _, err := sess.Exec("echo hello", 1000); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+
+	quota := sess.Status().Quota
+	if quota == nil {
+		t.Fatal("Status().Quota = nil with quotas configured")
+	}
+	want := QuotaUsage{Commands: 1, MaxCommands: 5, OutputBytes: 5, MaxOutputBytes: 1000}
+	if *quota != want {
+		t.Errorf("Quota = %+v, want %+v", *quota, want)
+	}
+}
+
+func TestQuota_UnlimitedByDefault(t *testing.T) {
+	sess, pty := newQuotaSession(t, 0, 0)
+
+	pty.AddResponse(buildCommandOutput("00010203", "ok", 0))
+	if _, err := sess.Exec("true", 1000); err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if quota := sess.Status().Quota; quota != nil {
+		t.Errorf("Status().Quota = %+v without quotas configured", quota)
+	}
+}
+
+func TestQuota_RawInputIsCharged(t *testing.T) {
+	sess, pty := newQuotaSession(t, 1, 0)
+	sess.commandsRun = 1
+	sess.State = StateAwaitingInput
+
+	written := len(pty.Written())
+	var quotaErr *QuotaError
+	if _, err := sess.ProvideInput("y"); !errors.As(err, &quotaErr) {
+		t.Errorf("ProvideInput error = %v, want *QuotaError", err)
+	}
+	if _, err := sess.SendRaw(`\x04`); !errors.As(err, &quotaErr) {
+		t.Errorf("SendRaw error = %v, want *QuotaError", err)
+	}
+	if len(pty.Written()) != written {
+		t.Error("rejected input was written to the terminal")
+	}
+}
+
+func TestQuota_UnchargedExec(t *testing.T) {
+	sess, pty := newQuotaSession(t, 1, 1000)
+
+	pty.AddResponse(buildCommandOutput("00010203", "probe", 0))
+	if _, err := sess.ExecWithOptions("sudo -n true", 1000, ExecOptions{Uncharged: true}); err != nil {
+		t.Fatalf("uncharged Exec error: %v", err)
+	}
+	if quota := sess.Status().Quota; quota.Commands != 0 || quota.OutputBytes != 0 {
+		t.Errorf("Quota = %+v after an uncharged command, want no usage", *quota)
+	}
+
+	pty.AddResponse(buildCommandOutput("04050607", "ok", 0))
+	if _, err := sess.Exec("true", 1000); err != nil {
+		t.Errorf("Exec error: %v, want the quota left for the client", err)
+	}
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	// rawReadInterval is the read deadline of each raw mode read.
+	rawReadInterval = 100 * time.Millisecond
+	// rawQuietReads is how many empty reads in a row (500ms of silence)
+	// end a raw mode command's output.
+	rawQuietReads = 5
+	// rawModeHint explains the missing exit code on raw mode results.
+	rawModeHint = "raw mode: output is returned as the PTY emitted it once it goes quiet; the exit code is not available"
+)
+
+// execRawLocked writes command to the PTY verbatim, without markers, and
+// returns everything the PTY emits until it has been quiet for
+// rawQuietReads reads or timeout passes. The output keeps the echo, prompts
+// and escape sequences. Caller must hold s.mu.
+func (s *Session) execRawLocked(command string, timeout time.Duration) (*ExecResult, error) {
+	if err := s.writeCommandWithReconnect(command + "\n"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.readRawOutput(ctx)
+}
+
+// readRawOutput appends PTY output to outputBuffer until it goes quiet.
+// Caller must hold s.mu.
+func (s *Session) readRawOutput(ctx context.Context) (*ExecResult, error) {
+	buf := make([]byte, s.readBufferSize())
+	quiet := 0
+	for quiet < rawQuietReads {
+		select {
+		case <-ctx.Done():
+			s.State = StateIdle
+			return &ExecResult{Status: "timeout", Stdout: s.outputBuffer.String(), Hint: rawModeHint}, nil
+		default:
+		}
+
+		s.pty.SetReadDeadline(s.clock.Now().Add(rawReadInterval))
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			s.outputBuffer.Write(buf[:n])
+			s.feedScreen(buf[:n])
+			quiet = 0
+			continue
+		}
+		if err != nil && (err == io.EOF || !(os.IsTimeout(err) || isTimeoutError(err))) {
+			s.State = StateIdle
+			return nil, s.connectionError(fmt.Errorf("read output: %w", err))
+		}
+		quiet++
+	}
+
+	s.State = StateIdle
+	return &ExecResult{Status: "completed", Stdout: s.outputBuffer.String(), Hint: rawModeHint}, nil
+}
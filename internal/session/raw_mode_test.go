@@ -0,0 +1,102 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newRawSession(t *testing.T) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	sess := NewSession("sess_raw", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Now())),
+		WithSessionRandom(fakerand.NewSequential()),
+	)
+	sess.RawMode = true
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	return sess, pty
+}
+
+func TestExec_RawMode(t *testing.T) {
+	sess, pty := newRawSession(t)
+	pty.AddResponse("mysql> SELECT 1;\r\n")
+	pty.AddResponse("+---+\r\n| 1 |\r\n+---+\r\nmysql> ")
+
+	result, err := sess.Exec("SELECT 1;", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if pty.Written() != "SELECT 1;\n" {
+		t.Errorf("written = %q, want the command verbatim", pty.Written())
+	}
+	if want := "mysql> SELECT 1;\r\n+---+\r\n| 1 |\r\n+---+\r\nmysql> "; result.Stdout != want {
+		t.Errorf("Stdout = %q, want %q", result.Stdout, want)
+	}
+	if result.Status != "completed" || result.ExitCode != nil {
+		t.Errorf("status = %q, exit code = %v; want completed without exit code", result.Status, result.ExitCode)
+	}
+	if !strings.Contains(result.Hint, "raw mode") {
+		t.Errorf("Hint = %q, want a raw mode note", result.Hint)
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %s, want idle", sess.State)
+	}
+}
+
+func TestExec_RawModeKeepsPrompts(t *testing.T) {
+	sess, pty := newRawSession(t)
+	pty.AddResponse("[sudo] password for user: ")
+
+	result, err := sess.Exec("sudo -v", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	// Raw mode does no prompt detection: the prompt is plain output.
+	if result.Status != "completed" || result.Stdout != "[sudo] password for user: " {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestSendRaw_RawModeWhileIdle(t *testing.T) {
+	sess, pty := newRawSession(t)
+	pty.AddResponse("\x1b[2J\x1b[Hmenu")
+
+	result, err := sess.SendRaw("\\x1b[A")
+	if err != nil {
+		t.Fatalf("SendRaw() error = %v", err)
+	}
+	if result.Stdout != "\x1b[2J\x1b[Hmenu" {
+		t.Errorf("Stdout = %q, want escape sequences kept", result.Stdout)
+	}
+	if pty.Written() != "\x1b[A" {
+		t.Errorf("written = %q", pty.Written())
+	}
+}
+
+func TestExec_RawModeReadError(t *testing.T) {
+	sess, pty := newRawSession(t)
+	pty.SetReadError(errors.New("broken pipe"))
+
+	if _, err := sess.Exec("ls", 5000); err == nil {
+		t.Fatal("Exec() succeeded, want a read error")
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %s, want idle", sess.State)
+	}
+}
+
+func TestStatus_RawMode(t *testing.T) {
+	sess, _ := newRawSession(t)
+	if !sess.Status().RawMode {
+		t.Error("Status().RawMode = false for a raw mode session")
+	}
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// readyMarkerPrefix starts the line a new shell prints to show it is reading
+// input. The probe prints it with printf so the echoed command line, which
+// has "%s" in place of the ID, never matches.
+const readyMarkerPrefix = "___SHELL_READY_"
+
+const (
+	defaultReadyTimeout = 10 * time.Second
+	readyPollInterval   = 100 * time.Millisecond
+)
+
+// readyTimeout returns how long to wait for the readiness probe, from
+// config.PTY.ReadyTimeoutMs or the default.
+func (s *Session) readyTimeout() time.Duration {
+	if s.config != nil && s.config.PTY.ReadyTimeoutMs > 0 {
+		return time.Duration(s.config.PTY.ReadyTimeoutMs) * time.Millisecond
+	}
+	return defaultReadyTimeout
+}
+
+// waitReady sends a sentinel through the shell and waits for it to come
+// back, so the first Exec cannot race a slow login. Output read before the
+// sentinel (a late banner or MOTD) is kept for the first command's
+// async_output, as if it had been read there.
+func (s *Session) waitReady() error {
+	timeout := s.readyTimeout()
+	id := s.generateCommandID()
+	sentinel := readyMarkerPrefix + id + markerSuffix
+
+	probe := fmt.Sprintf("printf '%s%%s%s\\n' %s\n", readyMarkerPrefix, markerSuffix, id)
+	if _, err := s.pty.WriteString(probe); err != nil {
+		return fmt.Errorf("shell_not_ready: write readiness probe: %w", err)
+	}
+
+	var output strings.Builder
+	buf := make([]byte, s.readBufferSize())
+	deadline := s.clock.Now().Add(timeout)
+	for waited := time.Duration(0); waited < timeout && s.clock.Now().Before(deadline); {
+		s.pty.SetReadDeadline(s.clock.Now().Add(readyPollInterval))
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			output.Write(buf[:n])
+			if before, _, found := strings.Cut(output.String(), sentinel); found {
+				s.startupOutput = stripReadyProbe(before)
+				s.drainReadyPrompt()
+				return nil
+			}
+			continue
+		}
+		if isConnectionBroken(err) {
+			return fmt.Errorf("shell_not_ready: shell exited before it was ready: %w", err)
+		}
+		s.clock.Sleep(readyPollInterval)
+		waited += readyPollInterval
+	}
+	return fmt.Errorf("shell_not_ready: shell did not respond within %v (raise pty.ready_timeout_ms for slow logins)", timeout)
+}
+
+// stripReadyProbe removes the echoed probe command from output.
+func stripReadyProbe(output string) string {
+	lines := strings.Split(output, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.Contains(line, readyMarkerPrefix) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// drainReadyPrompt discards the prompt the shell prints after the probe.
+func (s *Session) drainReadyPrompt() {
+	buf := make([]byte, 1024)
+	s.pty.SetReadDeadline(s.clock.Now().Add(readyPollInterval))
+	s.pty.Read(buf)
+}
+
+// abortInitialize releases what Initialize set up when the shell never
+// became ready.
+func (s *Session) abortInitialize() {
+	if s.pty != nil {
+		s.pty.Close()
+		s.pty = nil
+	}
+	s.releaseSSHClient()
+	s.State = StateClosed
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+var readyProbePattern = regexp.MustCompile(`printf '` + readyMarkerPrefix + `%s` + markerSuffix + `\\n' ([0-9a-f]+)`)
+
+// answerReadyProbe answers the readiness probe like a shell would.
+func answerReadyProbe(written string) string {
+	if m := readyProbePattern.FindStringSubmatch(written); m != nil {
+		return readyMarkerPrefix + m[1] + markerSuffix + "\n$ "
+	}
+	return ""
+}
+
+// newReadyPTY returns a fake PTY whose shell answers the readiness probe.
+func newReadyPTY() *fakepty.PTY {
+	return fakepty.New().SetResponder(answerReadyProbe)
+}
+
+func newReadinessSession(pty *fakepty.PTY, cfg *config.Config) *Session {
+	sess := NewSession("sess_ready", "local",
+		WithConfig(cfg),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionFileSystem(fakefs.New()),
+		WithSessionRandom(fakerand.New([]byte{0xaa, 0xbb, 0xcc, 0xdd})),
+	)
+	sess.localPTYFactory = func(localpty.PTYOptions) (PTY, string, error) {
+		return pty, "/bin/bash", nil
+	}
+	return sess
+}
+
+func TestInitialize_WaitsForReadinessProbe(t *testing.T) {
+	pty := newReadyPTY()
+	sess := newReadinessSession(pty, config.DefaultConfig())
+
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if !strings.Contains(pty.Written(), "printf '"+readyMarkerPrefix+"%s"+markerSuffix+`\n' aabbccdd`) {
+		t.Errorf("written = %q, want the readiness probe", pty.Written())
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %q, want idle", sess.State)
+	}
+}
+
+func TestInitialize_ShellNotReady(t *testing.T) {
+	pty := fakepty.New() // never answers
+	cfg := config.DefaultConfig()
+	cfg.PTY.ReadyTimeoutMs = 500
+	sess := newReadinessSession(pty, cfg)
+
+	err := sess.Initialize()
+	if err == nil || !strings.HasPrefix(err.Error(), "shell_not_ready:") {
+		t.Fatalf("Initialize() error = %v, want shell_not_ready", err)
+	}
+	if !strings.Contains(err.Error(), "500ms") {
+		t.Errorf("error = %q, want the configured timeout", err)
+	}
+	if !pty.IsClosed() || sess.State != StateClosed {
+		t.Error("PTY of a shell that never became ready should be closed")
+	}
+}
+
+func TestInitialize_ShellExitsBeforeReady(t *testing.T) {
+	pty := fakepty.New().SetReadError(errors.New("EOF"))
+	sess := newReadinessSession(pty, config.DefaultConfig())
+
+	err := sess.Initialize()
+	if err == nil || !strings.Contains(err.Error(), "shell_not_ready: shell exited") {
+		t.Fatalf("Initialize() error = %v, want shell_not_ready", err)
+	}
+}
+
+func TestInitialize_SlowLoginOutputBecomesAsyncOutput(t *testing.T) {
+	pty := fakepty.New()
+	// Nothing during the startup drain, then the login finishes: a late
+	// MOTD, the echoed probe and its answer arrive together.
+	pty.SetResponder(func(written string) string {
+		if m := readyProbePattern.FindStringSubmatch(written); m != nil {
+			return "Welcome to slowhost\n$ " + strings.TrimSuffix(written, "\n") + "\n" +
+				readyMarkerPrefix + m[1] + markerSuffix + "\n$ "
+		}
+		return ""
+	})
+	sess := newReadinessSession(pty, config.DefaultConfig())
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	pty.AddResponse(buildCommandOutput("aabbccdd", "hello", 0))
+	result, err := sess.Exec("echo hello", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Stdout != "hello" {
+		t.Errorf("Stdout = %q, want hello", result.Stdout)
+	}
+	if !strings.Contains(result.AsyncOutput, "Welcome to slowhost") || strings.Contains(result.AsyncOutput, readyMarkerPrefix) {
+		t.Errorf("AsyncOutput = %q, want the MOTD without the probe", result.AsyncOutput)
+	}
+}
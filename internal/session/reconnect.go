@@ -0,0 +1,80 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+)
+
+// Reconnect re-dials a broken SSH session in place with its original
+// credentials, restoring cwd and environment variables and keeping the
+// session ID. A healthy session is refused unless force is set.
+//
+// It returns the tunnels that were active (or saved from before a restart)
+// when the connection broke, for the caller to re-create on the new client.
+func (s *Session) Reconnect(force bool) ([]TunnelConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Mode != "ssh" {
+		return nil, fmt.Errorf("reconnect is only available for SSH sessions")
+	}
+	if s.State == StateClosed {
+		return nil, fmt.Errorf("session is closed")
+	}
+	if s.sshHealthy() && !force {
+		return nil, fmt.Errorf("session is healthy; use force to reconnect anyway")
+	}
+
+	tunnels := append(s.GetTunnelConfigs(), s.SavedTunnels...)
+
+	slog.Info("reconnecting session",
+		slog.String("session_id", s.ID),
+		slog.Bool("force", force),
+		slog.Int("tunnels", len(tunnels)),
+	)
+
+	s.disarmPromptTimeout()
+	if err := s.reconnectSession(); err != nil {
+		return nil, &ConnectionError{
+			Code: s.lostConnectionCode(nil, err),
+			Err:  fmt.Errorf("reconnect failed: %w", err),
+		}
+	}
+
+	s.State = StateIdle
+	s.pendingPrompt = nil
+	s.command = ""
+	s.outputBuffer.Reset()
+	s.LastUsed = s.clock.Now()
+	return tunnels, nil
+}
+
+// sshHealthy reports whether the SSH connection is up and was not
+// force-closed. Caller must hold s.mu.
+func (s *Session) sshHealthy() bool {
+	return s.sshClient != nil && s.sshClient.IsConnected() && s.connectionDroppedReason() == ""
+}
+
+// setConnectionDropped records why the session's SSH connection was closed
+// underneath it, or clears it with "". It does not take s.mu, so the manager
+// can call it while the session runs a command.
+func (s *Session) setConnectionDropped(reason string) {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	s.connectionDropped = reason
+}
+
+func (s *Session) connectionDroppedReason() string {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return s.connectionDropped
+}
+
+// currentSSHClient returns the session's SSH client without s.mu.
+func (s *Session) currentSSHClient() *ssh.Client {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return s.sshClient
+}
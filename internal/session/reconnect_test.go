@@ -0,0 +1,67 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+func TestReconnect_BrokenSession(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.State = StateAwaitingInput
+	sess.connectionDropped = "connection user@host:22 was force-closed"
+	sess.SavedTunnels = []TunnelConfig{{Type: "local", LocalHost: "127.0.0.1", LocalPort: 5432, RemoteHost: "db", RemotePort: 5432}}
+
+	reconnects := 0
+	sess.reconnect = func() error {
+		reconnects++
+		sess.pty = fakepty.New()
+		return nil
+	}
+
+	tunnels, err := sess.Reconnect(false)
+	if err != nil {
+		t.Fatalf("Reconnect error: %v", err)
+	}
+	if reconnects != 1 {
+		t.Errorf("reconnects = %d, want 1", reconnects)
+	}
+	if len(tunnels) != 1 || tunnels[0].LocalPort != 5432 {
+		t.Errorf("tunnels = %+v, want the saved tunnel", tunnels)
+	}
+	if sess.State != StateIdle || sess.pendingPrompt != nil {
+		t.Errorf("State = %q, pendingPrompt = %v; want idle with no prompt", sess.State, sess.pendingPrompt)
+	}
+	if sess.ID != "sess_drop" || sess.Cwd != "/srv/app" {
+		t.Errorf("ID = %q, Cwd = %q; want the session identity kept", sess.ID, sess.Cwd)
+	}
+}
+
+func TestReconnect_Failure(t *testing.T) {
+	sess, _ := newDroppingSSHSession(t)
+	sess.reconnect = func() error { return errors.New("dial tcp: connection refused") }
+
+	_, err := sess.Reconnect(true)
+	if err == nil || !strings.Contains(err.Error(), "reconnect failed") {
+		t.Fatalf("err = %v, want reconnect failed", err)
+	}
+	if ConnectionErrorCodeOf(err) == "" {
+		t.Errorf("err = %v, want a connection error code", err)
+	}
+}
+
+func TestReconnect_Refused(t *testing.T) {
+	local := NewSession("sess_local", "local", WithPTY(fakepty.New()))
+	if _, err := local.Reconnect(true); err == nil || !strings.Contains(err.Error(), "only available for SSH") {
+		t.Errorf("local: err = %v, want SSH-only error", err)
+	}
+
+	closed, _ := newDroppingSSHSession(t)
+	closed.reconnect = func() error { return nil }
+	closed.State = StateClosed
+	if _, err := closed.Reconnect(true); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("closed: err = %v, want closed error", err)
+	}
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+)
+
+// runawayGuard detects commands that flood the terminal (e.g. an accidental
+// `yes`): output arriving faster than limit bytes/sec for a whole window.
+// Time comes from the session clock, so tests drive it deterministically.
+type runawayGuard struct {
+	limit  float64
+	window time.Duration
+	start  time.Time
+	bytes  int64
+}
+
+// newRunawayGuard returns a guard from config, or nil when disabled.
+func (s *Session) newRunawayGuard() *runawayGuard {
+	if s.config == nil || s.config.Output.RunawayBytesPerSec <= 0 || s.config.Output.RunawayWindow <= 0 {
+		return nil
+	}
+	return &runawayGuard{
+		limit:  float64(s.config.Output.RunawayBytesPerSec),
+		window: s.config.Output.RunawayWindow,
+	}
+}
+
+// observe records n bytes read at now. It returns the measured rate and
+// whether the rate stayed above the limit for a full window.
+func (g *runawayGuard) observe(now time.Time, n int) (float64, bool) {
+	if g == nil {
+		return 0, false
+	}
+	if g.start.IsZero() {
+		g.start = now
+	}
+	g.bytes += int64(n)
+
+	elapsed := now.Sub(g.start)
+	if elapsed < g.window {
+		return 0, false
+	}
+	rate := float64(g.bytes) / elapsed.Seconds()
+	if rate > g.limit {
+		return rate, true
+	}
+	g.start = now
+	g.bytes = 0
+	return rate, false
+}
+
+// checkRunaway feeds a read into the active guard. Must be called with s.mu held.
+func (s *Session) checkRunaway(n int) (float64, bool) {
+	return s.runaway.observe(s.clock.Now(), n)
+}
+
+// stopRunawayOutput interrupts a flooding command and returns a
+// runaway_output result holding the start of its output.
+// Must be called with s.mu held.
+func (s *Session) stopRunawayOutput(stdout, asyncOutput, cmdID string, rate float64) *ExecResult {
+	slog.Warn("runaway output detected, interrupting command",
+		slog.String("session_id", s.ID),
+		slog.String("command_id", cmdID),
+		slog.Int("buffered_bytes", s.outputBuffer.Len()),
+		slog.Float64("bytes_per_sec", rate),
+	)
+
+	total := s.outputBuffer.Len()
+	s.forceKillCommand()
+	s.outputBuffer.Reset()
+	s.State = StateIdle
+
+	return &ExecResult{
+		Status:      "runaway_output",
+		Stdout:      truncateSample(stdout, s.runawaySampleBytes()),
+		AsyncOutput: asyncOutput,
+		CommandID:   cmdID,
+		Cwd:         s.Cwd,
+		TotalBytes:  total,
+		Warning:     s.runawayWarning(rate),
+	}
+}
+
+// runawayWarning explains a runaway_output result.
+func (s *Session) runawayWarning(rate float64) string {
+	return fmt.Sprintf("command produced output at %.0f bytes/sec for over %s and was interrupted; stdout holds a sample. Redirect or filter the output (e.g. | head) if it is expected.",
+		rate, s.config.Output.RunawayWindow)
+}
+
+// runawaySampleBytes returns how much output a runaway_output result keeps.
+func (s *Session) runawaySampleBytes() int {
+	if s.config != nil && s.config.Output.RunawaySampleBytes > 0 {
+		return s.config.Output.RunawaySampleBytes
+	}
+	return 4096
+}
+
+// truncateSample cuts out to at most limit bytes on a rune boundary.
+func truncateSample(out string, limit int) string {
+	if len(out) <= limit {
+		return out
+	}
+	for limit > 0 && !utf8.RuneStart(out[limit]) {
+		limit--
+	}
+	return out[:limit]
+}
@@ -0,0 +1,148 @@
+package session
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+// steppingClock advances by step every time Now is called, so each PTY
+// read appears to take a fixed amount of time.
+type steppingClock struct {
+	*fakeclock.Clock
+	mu   sync.Mutex
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Clock.Advance(c.step)
+	return c.Clock.Now()
+}
+
+func newRunawaySession(t *testing.T, step time.Duration) (*Session, *fakepty.PTY) {
+	t.Helper()
+	pty := fakepty.New()
+	cfg := config.DefaultConfig()
+	cfg.Output.RunawayBytesPerSec = 1000
+	cfg.Output.RunawayWindow = time.Second
+	cfg.Output.RunawaySampleBytes = 16
+
+	sess := NewSession("sess_runaway", "local",
+		WithPTY(pty),
+		WithSessionClock(&steppingClock{Clock: fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)), step: step}),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	return sess, pty
+}
+
+func TestRunawayGuard_Observe(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := &runawayGuard{limit: 1000, window: time.Second}
+
+	if _, tripped := g.observe(start, 5000); tripped {
+		t.Fatal("tripped before a full window elapsed")
+	}
+	if _, tripped := g.observe(start.Add(500*time.Millisecond), 5000); tripped {
+		t.Fatal("tripped before a full window elapsed")
+	}
+	rate, tripped := g.observe(start.Add(time.Second), 5000)
+	if !tripped {
+		t.Fatalf("expected trip at %.0f bytes/sec", rate)
+	}
+}
+
+func TestRunawayGuard_SlowOutputResetsWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g := &runawayGuard{limit: 1000, window: time.Second}
+
+	for i := 0; i < 10; i++ {
+		if _, tripped := g.observe(start.Add(time.Duration(i)*time.Second), 500); tripped {
+			t.Fatalf("tripped on read %d at 500 bytes/sec", i)
+		}
+	}
+}
+
+func TestRunawayGuard_NilIsDisabled(t *testing.T) {
+	var g *runawayGuard
+	if _, tripped := g.observe(time.Now(), 1<<30); tripped {
+		t.Error("nil guard tripped")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Output.RunawayBytesPerSec = 0
+	sess := NewSession("sess_off", "local", WithConfig(cfg))
+	if sess.newRunawayGuard() != nil {
+		t.Error("guard enabled with runaway_bytes_per_sec = 0")
+	}
+}
+
+func TestSession_Exec_RunawayOutputInterrupted(t *testing.T) {
+	sess, pty := newRunawaySession(t, 100*time.Millisecond)
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\n")
+	for i := 0; i < 50; i++ {
+		pty.AddResponse(strings.Repeat("y\n", 500))
+	}
+
+	result, err := sess.Exec("yes", 60000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "runaway_output" {
+		t.Fatalf("Status = %q, want runaway_output", result.Status)
+	}
+	if len(result.Stdout) > 16 || !strings.HasPrefix(result.Stdout, "y\ny") {
+		t.Errorf("Stdout = %q, want a sample of at most 16 bytes", result.Stdout)
+	}
+	if result.TotalBytes == 0 || result.Warning == "" {
+		t.Errorf("TotalBytes = %d, Warning = %q", result.TotalBytes, result.Warning)
+	}
+	if !pty.WasInterrupted() {
+		t.Error("expected the command to be interrupted")
+	}
+	if sess.State != StateIdle {
+		t.Errorf("State = %s, want idle", sess.State)
+	}
+	if sess.runaway != nil {
+		t.Error("guard not cleared after the read loop")
+	}
+}
+
+func TestSession_Exec_SlowOutputCompletes(t *testing.T) {
+	sess, pty := newRunawaySession(t, time.Second)
+
+	pty.AddResponse(startMarkerPrefix + "01020304" + markerSuffix + "\n")
+	for i := 0; i < 10; i++ {
+		pty.AddResponse(strings.Repeat("y\n", 200))
+	}
+	pty.AddResponse(endMarkerPrefix + "01020304" + markerSuffix + "0\n")
+
+	result, err := sess.Exec("slow", 60000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q, want completed", result.Status)
+	}
+}
+
+func TestTruncateSample(t *testing.T) {
+	if got := truncateSample("héllo", 2); got != "h" {
+		t.Errorf("truncateSample split a rune: %q", got)
+	}
+	if got := truncateSample("short", 16); got != "short" {
+		t.Errorf("truncateSample(short) = %q", got)
+	}
+}
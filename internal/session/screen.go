@@ -0,0 +1,80 @@
+package session
+
+import (
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/vt"
+)
+
+// Size of the screen model that command output is rendered into; it
+// matches the size local and SSH PTYs are opened with.
+const (
+	screenRows       = 24
+	screenCols       = 120
+	screenScrollback = 1000
+)
+
+// ScreenCapture is the session's terminal as rendered from its output:
+// what a user looking at the terminal would see.
+type ScreenCapture struct {
+	Rows int `json:"rows"`
+	Cols int `json:"cols"`
+	// Screen is the current screen, blank rows at the bottom dropped.
+	Screen []string `json:"screen"`
+	// InAlternateScreen is true while a full-screen program (less, htop,
+	// vim) has the alternate screen up; Screen is then its display.
+	InAlternateScreen bool `json:"in_alternate_screen"`
+	// AlternateScreen is the last frame a full-screen program showed before
+	// it exited, which the terminal no longer displays.
+	AlternateScreen []string `json:"alternate_screen,omitempty"`
+	// Scrollback holds the newest lines scrolled off the top of the screen,
+	// oldest first.
+	Scrollback          []string `json:"scrollback,omitempty"`
+	ScrollbackTruncated bool     `json:"scrollback_truncated,omitempty"`
+}
+
+// feedScreen renders PTY output into the session's screen model. Caller
+// must hold s.mu.
+func (s *Session) feedScreen(data []byte) {
+	if s.screen == nil {
+		s.screen = vt.New(screenRows, screenCols, screenScrollback)
+	}
+	s.screen.Write(data)
+}
+
+// Screen returns the session's terminal as rendered from the output of its
+// commands, with up to scrollbackLines lines of scrollback. The command
+// markers the server wraps commands in are left out.
+func (s *Session) Screen(scrollbackLines int) *ScreenCapture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capture := &ScreenCapture{Rows: screenRows, Cols: screenCols, Screen: []string{}}
+	if s.screen == nil {
+		return capture
+	}
+	capture.Screen = withoutMarkerLines(s.screen.Lines())
+	capture.InAlternateScreen = s.screen.InAlternate()
+	capture.AlternateScreen = s.screen.LastAlternate()
+
+	back := withoutMarkerLines(s.screen.Scrollback())
+	if scrollbackLines < len(back) {
+		back = back[len(back)-max(scrollbackLines, 0):]
+		capture.ScrollbackTruncated = true
+	}
+	capture.Scrollback = back
+	return capture
+}
+
+// withoutMarkerLines drops the lines showing command markers or the echoed
+// wrapped commands that print them.
+func withoutMarkerLines(lines []string) []string {
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.Contains(line, startMarkerPrefix) || strings.Contains(line, endMarkerPrefix) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
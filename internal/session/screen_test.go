@@ -0,0 +1,58 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScreen_AfterFullScreenProgram(t *testing.T) {
+	sess, _ := newStreamSession(
+		"\x1b[?1049h\x1b[H\x1b[2J\x1b[1mSelect a target\x1b[0m\r\n> deploy\r\n  rollback",
+		"\x1b[2;1H  deploy\r\n> rollback\x1b[?1049l",
+		"rolled back to v1.2\r\n",
+	)
+	if _, err := sess.Exec("./menu.sh", 5000); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	capture := sess.Screen(100)
+	if capture.InAlternateScreen {
+		t.Error("InAlternateScreen = true after the program left it")
+	}
+	if got := strings.Join(capture.AlternateScreen, "|"); got != "Select a target|  deploy|> rollback" {
+		t.Errorf("AlternateScreen = %q, want the menu's final frame", capture.AlternateScreen)
+	}
+	if got := strings.Join(capture.Screen, "|"); got != "rolled back to v1.2|$" {
+		t.Errorf("Screen = %q, want the output without markers", capture.Screen)
+	}
+	if capture.Rows != screenRows || capture.Cols != screenCols {
+		t.Errorf("size = %dx%d", capture.Rows, capture.Cols)
+	}
+}
+
+func TestScreen_ScrollbackLimit(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i%5))
+	}
+	sess, _ := newStreamSession(strings.Join(lines, "\r\n") + "\r\n")
+	if _, err := sess.Exec("seq", 5000); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	capture := sess.Screen(5)
+	if len(capture.Scrollback) != 5 || !capture.ScrollbackTruncated {
+		t.Errorf("Scrollback = %d lines, truncated = %v; want 5, true", len(capture.Scrollback), capture.ScrollbackTruncated)
+	}
+	if capture = sess.Screen(0); len(capture.Scrollback) != 0 {
+		t.Errorf("Scrollback = %q with no lines asked for", capture.Scrollback)
+	}
+}
+
+func TestScreen_NothingRendered(t *testing.T) {
+	sess, _ := newStreamSession()
+	capture := sess.Screen(100)
+	if capture.Screen == nil || len(capture.Screen) != 0 || capture.AlternateScreen != nil {
+		t.Errorf("capture = %+v, want an empty screen", capture)
+	}
+}
@@ -4,12 +4,17 @@ package session
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +28,7 @@ import (
 	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
 	"github.com/acolita/claude-shell-mcp/internal/sftp"
 	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/vt"
 	gossh "golang.org/x/crypto/ssh"
 )
 
@@ -51,7 +57,7 @@ const endMarker = "___CMD_END_MARKER___"
 type Session struct {
 	ID        string
 	State     State
-	Mode      string // "local" or "ssh"
+	Mode      string // "local", "ssh" or "command"
 	Shell     string
 	Cwd       string
 	EnvVars   map[string]string
@@ -66,9 +72,68 @@ type Session struct {
 	Password string // For password-based auth (not persisted)
 	KeyPath  string // Path to SSH private key file
 
+	// Server names the configured server the session was created for. Its
+	// auth and identities are used even if Host was overridden or another
+	// server has the same host; without it the server is found by Host.
+	Server string
+
+	// Identity names the configured server identity (see
+	// config.ServerConfig.Identities) whose credentials are used instead of
+	// the server's auth block.
+	Identity string
+
+	// NewPassword is set when the server forces a password change at login
+	// (not persisted).
+	NewPassword string
+
+	// Command provides the shell over its stdio (for command mode), e.g.
+	// "docker exec -it web bash".
+	Command string
+
+	// Container runs every command inside this container with
+	// "<ContainerRuntime> exec", on top of a local or SSH session. Cwd and
+	// EnvVars are the container's.
+	Container        string
+	ContainerRuntime string // "docker" (default) or "podman"
+
+	// RawMode sends commands verbatim, without markers, and returns the raw
+	// PTY output once it goes quiet. Exit codes are not available.
+	RawMode bool
+
+	// Privileged is set while the PTY runs the root shell started by
+	// SudoShell; Shell, Cwd and EnvVars are then the root shell's.
+	Privileged bool
+
+	// ForwardX11 forwards X11 connections from the server to the local
+	// display (also enabled by the server's forward_x11 config).
+	ForwardX11 bool
+
+	// KeepPagers skips exporting config.Session.DisablePagers at startup,
+	// for sessions that want git or man to page as usual.
+	KeepPagers bool
+
+	// StartDir is the directory Initialize changes to once the shell is
+	// ready (see CreateOptions.Cwd).
+	StartDir string
+
+	// Proxy is the proxy the SSH connection went through, as a URL without
+	// credentials (from the server's proxy config); empty when direct.
+	Proxy string
+
+	// Tags group sessions for filtering and shell_exec_broadcast.
+	Tags []string
+
+	// PromptResponses are answered in order right after connecting, for
+	// multi-step logins (not persisted).
+	PromptResponses []PromptResponse
+
 	// PTY info for control plane
 	PTYName string // e.g., "3" for /dev/pts/3
 
+	// Captured lists what Initialize captured from the new shell ("env",
+	// "aliases", "cwd"), per config.Session.AutoCaptureOnConnect.
+	Captured []string
+
 	// Saved tunnel configs from before MCP restart (for user to restore)
 	SavedTunnels []TunnelConfig
 
@@ -85,12 +150,84 @@ type Session struct {
 	// Pending prompt info when awaiting input
 	pendingPrompt *prompt.Detection
 	outputBuffer  bytes.Buffer
+	promptCancel  chan struct{} // stops the awaiting_input timeout timer
 
 	// Control session reference for process management
 	controlSession *ControlSession
 
 	// localPTYFactory creates local PTYs (injectable for testing)
 	localPTYFactory LocalPTYFactory
+
+	// connPool shares SSH clients between sessions (nil = dedicated client)
+	connPool *ConnectionPool
+
+	// autoReconnect reports broken connections during the current Exec
+	// instead of waiting for the timeout.
+	autoReconnect bool
+	// killOnTimeout verifies the kill of a command that times out during
+	// the current Exec (see ExecOptions.KillOnTimeout).
+	killOnTimeout bool
+	// onOutput receives the output of the current Exec as it arrives (see
+	// ExecOptions.OnOutput).
+	onOutput func(chunk string)
+	// screen renders command output as a terminal would show it (see
+	// Screen).
+	screen *vt.Screen
+	// shellCheckPending is set after input that may have changed the shell
+	// in the PTY; the next command checks it first (see detectShellChange).
+	shellCheckPending bool
+	// reconnect re-establishes the SSH connection (injectable for testing;
+	// nil uses reconnectSSH)
+	reconnect func() error
+	// execChannel runs an ExecOptions.NoPTY command (injectable for testing;
+	// nil uses the SSH client's exec channel)
+	execChannel func(ctx context.Context, command string, stdout, stderr io.Writer) (int, error)
+
+	// runaway watches the output rate of the command being read (nil = off).
+	runaway *runawayGuard
+
+	// systemInfo caches the result of SystemInfo for the session's lifetime.
+	systemInfo *SystemInfo
+
+	// collapseProgress renders \r-overwritten progress lines to their final
+	// state when cleaning output. Set per Exec and kept for ProvideInput,
+	// which continues the same command.
+	collapseProgress bool
+	// limits are the resource limits of the current command (see
+	// ExecOptions.Limits), kept for ProvideInput like collapseProgress.
+	limits *ResourceLimits
+	// adaptive is the adaptive timeout of the current Exec (nil = off; see
+	// config.SessionConfig.AdaptiveTimeout).
+	adaptive *adaptiveTimeout
+
+	// unprivileged is what the session had before SudoShell (nil unless
+	// Privileged).
+	unprivileged *unprivilegedState
+
+	// connectionDropped explains why the session's SSH connection was closed
+	// underneath it; cleared by a successful reconnect.
+	connectionDropped string
+
+	// connMu guards connectionDropped, and sshClient, which is written with
+	// both mu and connMu held, so the manager can reach them while a command
+	// holds mu.
+	connMu sync.Mutex
+
+	// command is the last command started with Exec or ExecStdin.
+	command string
+
+	// interactiveOpen is set while a pager or editor may be on screen; its
+	// exit artifacts are trimmed from the next output (see trimInteractiveExit).
+	interactiveOpen bool
+
+	// startupOutput is shell output that arrived before the readiness probe
+	// answered; it becomes part of the first command's async_output.
+	startupOutput string
+
+	// Usage counted against security.max_commands_per_session and
+	// max_output_bytes_per_session (see quota.go).
+	commandsRun int
+	outputBytes int64
 }
 
 // SessionOption configures a Session.
@@ -167,7 +304,7 @@ func (s *Session) Initialize() error {
 	// Add custom patterns from config
 	if s.config != nil {
 		for _, p := range s.config.PromptDetection.CustomPatterns {
-			if err := s.promptDetector.AddPatternFromConfig(p.Name, p.Regex, p.Type, p.MaskInput); err != nil {
+			if err := s.promptDetector.AddPatternFromConfig(p.Name, p.Regex, p.Type, p.MaskInput, p.Priority); err != nil {
 				return fmt.Errorf("add custom pattern %s: %w", p.Name, err)
 			}
 		}
@@ -181,11 +318,29 @@ func (s *Session) Initialize() error {
 		return nil
 	}
 
-	if s.Mode == "ssh" {
-		return s.initializeSSH()
+	var err error
+	switch s.Mode {
+	case "ssh":
+		err = s.initializeSSH()
+	case "command":
+		err = s.initializeCommand()
+	default:
+		err = s.initializeLocal()
+	}
+	if err != nil {
+		return err
 	}
 
-	return s.initializeLocal()
+	if err := s.waitReady(); err != nil {
+		s.abortInitialize()
+		return err
+	}
+	if err := s.enterStartDir(); err != nil {
+		s.abortInitialize()
+		return err
+	}
+	s.initializeContainer()
+	return nil
 }
 
 // initializeLocal sets up a local PTY session.
@@ -198,6 +353,7 @@ func (s *Session) initializeLocal() error {
 			opts.Shell = s.config.Shell.Path
 		}
 		opts.NoRC = !s.config.Shell.SourceRC
+		opts.Fallbacks = s.config.PTY.ShellFallbacks
 	}
 
 	// Use injected factory if available, otherwise use default
@@ -210,6 +366,13 @@ func (s *Session) initializeLocal() error {
 	if err != nil {
 		return fmt.Errorf("create local pty: %w", err)
 	}
+	if shell != opts.Shell {
+		slog.Warn("shell failed to start, using fallback",
+			slog.String("session_id", s.ID),
+			slog.String("shell", opts.Shell),
+			slog.String("fallback", shell),
+		)
+	}
 
 	s.pty = pty
 	s.Shell = shell
@@ -235,19 +398,29 @@ func (s *Session) initializeLocal() error {
 	s.clock.Sleep(200 * time.Millisecond)
 
 	// Drain initial output (shell prompt, etc.)
+	s.drainStartupOutput()
 	buf := make([]byte, 8192)
-	s.pty.SetReadDeadline(s.clock.Now().Add(300 * time.Millisecond))
-	s.pty.Read(buf) // Ignore output
 
 	// Set simple prompt based on shell type
-	s.pty.WriteString(s.shellPromptCommand())
-	s.clock.Sleep(100 * time.Millisecond)
-	s.pty.SetReadDeadline(s.clock.Now().Add(200 * time.Millisecond))
-	s.pty.Read(buf) // Drain the output
+	if s.normalizePrompt() {
+		s.pty.WriteString(s.shellPromptCommand())
+		s.clock.Sleep(100 * time.Millisecond)
+		s.pty.SetReadDeadline(s.clock.Now().Add(200 * time.Millisecond))
+		s.pty.Read(buf) // Drain the output
+	}
+	s.disablePagers()
+	s.captureOnConnect()
 
 	return nil
 }
 
+// normalizePrompt reports whether Initialize replaces the user's prompt with
+// a minimal one, so prompts that print paths or markers can't confuse output
+// and cwd parsing. shell.normalize_prompt turns it off.
+func (s *Session) normalizePrompt() bool {
+	return s.config == nil || s.config.Shell.NormalizePrompt
+}
+
 // shellPromptCommand returns the command to set a simple prompt for the current shell.
 func (s *Session) shellPromptCommand() string {
 	shellName := s.Shell
@@ -275,6 +448,10 @@ func (s *Session) initializeSSH() error {
 	if err := s.validateSSHConfig(); err != nil {
 		return err
 	}
+	x11, err := s.x11Options()
+	if err != nil {
+		return err
+	}
 
 	authCfg := s.buildSSHAuthConfig()
 	authMethods, err := ssh.BuildAuthMethods(authCfg)
@@ -282,20 +459,71 @@ func (s *Session) initializeSSH() error {
 		return fmt.Errorf("build auth methods: %w", err)
 	}
 
-	client, err := s.createSSHClient(authMethods)
+	client, err := s.createSSHClient(authCfg, authMethods)
 	if err != nil {
 		return err
 	}
 
-	if err := s.setupSSHPTY(client); err != nil {
-		client.Close()
+	if err := s.setupSSHPTY(client, x11); err != nil {
+		s.releaseSSHClient()
+		return err
+	}
+
+	if err := s.answerPromptResponses(); err != nil {
+		s.closeSSHConnection()
 		return err
 	}
 
+	s.clock.Sleep(500 * time.Millisecond)
+	output := s.drainStartupOutput()
+	if passwordChangeRequired(output) {
+		if err := s.handlePasswordChange(output, authCfg, x11); err != nil {
+			s.closeSSHConnection()
+			return err
+		}
+	}
+
 	s.initializeSSHShell()
 	return nil
 }
 
+// handlePasswordChange runs a forced password change and, when the server
+// hangs up afterwards, logs in again with the new password.
+func (s *Session) handlePasswordChange(output string, authCfg ssh.AuthConfig, x11 *ssh.X11Options) error {
+	closed, err := s.changeExpiredPassword(output, authCfg.Password)
+	if err != nil || !closed {
+		return err
+	}
+
+	s.closeSSHConnection()
+	authCfg.Password = s.Password
+	authMethods, err := ssh.BuildAuthMethods(authCfg)
+	if err != nil {
+		return fmt.Errorf("build auth methods: %w", err)
+	}
+	client, err := s.createSSHClient(authCfg, authMethods)
+	if err != nil {
+		return fmt.Errorf("reconnect after password change: %w", err)
+	}
+	if err := s.setupSSHPTY(client, x11); err != nil {
+		s.releaseSSHClient()
+		return fmt.Errorf("reconnect after password change: %w", err)
+	}
+	s.clock.Sleep(500 * time.Millisecond)
+	s.drainStartupOutput()
+	return nil
+}
+
+// closeSSHConnection closes the PTY and releases the SSH client of a
+// session whose setup failed.
+func (s *Session) closeSSHConnection() {
+	if s.pty != nil {
+		s.pty.Close()
+		s.pty = nil
+	}
+	s.releaseSSHClient()
+}
+
 // validateSSHConfig validates SSH configuration.
 func (s *Session) validateSSHConfig() error {
 	if s.Host == "" {
@@ -307,6 +535,15 @@ func (s *Session) validateSSHConfig() error {
 	if s.Port == 0 {
 		s.Port = 22
 	}
+	if s.Identity != "" {
+		srv := s.serverConfig()
+		if srv == nil {
+			return fmt.Errorf("identity %q: %s is not a configured server", s.Identity, s.Host)
+		}
+		if _, err := srv.AuthFor(s.Identity); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -317,40 +554,91 @@ func (s *Session) buildSSHAuthConfig() ssh.AuthConfig {
 		Password: s.Password,
 		KeyPath:  s.KeyPath,
 		Host:     s.Host,
+		Clock:    s.clock,
 	}
 
-	if authCfg.KeyPath == "" && s.config != nil {
+	if authCfg.KeyPath == "" || s.Identity != "" {
 		s.applyServerAuthConfig(&authCfg)
 	}
 	return authCfg
 }
 
-// applyServerAuthConfig applies authentication settings from server config.
+// applyServerAuthConfig applies authentication settings from server config:
+// the selected identity's, else the server's auth block. An identity's key
+// is the only one offered, so the agent is not consulted for it.
+// validateSSHConfig has checked that the identity exists.
 func (s *Session) applyServerAuthConfig(authCfg *ssh.AuthConfig) {
-	for _, srv := range s.config.Servers {
-		if srv.Host != s.Host && srv.Name != s.Host {
-			continue
-		}
-		if srv.KeyPath != "" {
-			authCfg.KeyPath = srv.KeyPath
+	srv := s.serverConfig()
+	if srv == nil {
+		return
+	}
+	auth, err := srv.AuthFor(s.Identity)
+	if err != nil {
+		return
+	}
+	if authCfg.KeyPath == "" {
+		authCfg.KeyPath = srv.KeyPath
+	}
+	if auth.Path != "" {
+		authCfg.KeyPath = auth.Path
+		if s.Identity != "" {
+			authCfg.UseAgent = false
 		}
-		if srv.Auth.PassphraseEnv != "" {
-			authCfg.KeyPassphrase = s.fs.Getenv(srv.Auth.PassphraseEnv)
+	}
+	if auth.CertPath != "" {
+		authCfg.CertPath = auth.CertPath
+	}
+	if auth.PassphraseEnv != "" {
+		authCfg.KeyPassphrase = s.fs.Getenv(auth.PassphraseEnv)
+	}
+	if auth.PasswordEnv != "" {
+		authCfg.Password = s.fs.Getenv(auth.PasswordEnv)
+	}
+}
+
+// serverConfig returns the configured server the session connects to: the
+// one named by Server, else the first whose host or name is Host, or nil.
+func (s *Session) serverConfig() *config.ServerConfig {
+	if s.config == nil {
+		return nil
+	}
+	if s.Server != "" {
+		for i, srv := range s.config.Servers {
+			if srv.Name == s.Server {
+				return &s.config.Servers[i]
+			}
 		}
-		if srv.Auth.PasswordEnv != "" {
-			authCfg.Password = s.fs.Getenv(srv.Auth.PasswordEnv)
+	}
+	for i, srv := range s.config.Servers {
+		if srv.Host == s.Host || srv.Name == s.Host {
+			return &s.config.Servers[i]
 		}
-		break
 	}
+	return nil
 }
 
-// createSSHClient creates and connects an SSH client.
-func (s *Session) createSSHClient(authMethods []gossh.AuthMethod) (*ssh.Client, error) {
+// ConfigServer returns what identifies the session's configured server:
+// the name it was created with, else its host.
+func (s *Session) ConfigServer() string {
+	if s.Server != "" {
+		return s.Server
+	}
+	return s.Host
+}
+
+// createSSHClient creates and connects an SSH client. authCfg is what
+// authMethods were built from.
+func (s *Session) createSSHClient(authCfg ssh.AuthConfig, authMethods []gossh.AuthMethod) (*ssh.Client, error) {
 	hostKeyCallback, err := ssh.BuildHostKeyCallback("")
 	if err != nil {
 		hostKeyCallback = ssh.InsecureHostKeyCallback()
 	}
 
+	proxy, err := s.proxyOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	clientOpts := ssh.ClientOptions{
 		Host:            s.Host,
 		Port:            s.Port,
@@ -358,24 +646,107 @@ func (s *Session) createSSHClient(authMethods []gossh.AuthMethod) (*ssh.Client,
 		AuthMethods:     authMethods,
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         30 * time.Second,
+		Proxy:           proxy,
 	}
 
-	client, err := ssh.NewClient(clientOpts)
-	if err != nil {
-		return nil, fmt.Errorf("create ssh client: %w", err)
+	dial := func() (*ssh.Client, error) {
+		client, err := ssh.NewClient(clientOpts)
+		if err != nil {
+			return nil, fmt.Errorf("create ssh client: %w", err)
+		}
+		if err := client.Connect(); err != nil {
+			return nil, fmt.Errorf("connect: %w", err)
+		}
+		return client, nil
 	}
 
-	if err := client.Connect(); err != nil {
-		return nil, fmt.Errorf("connect: %w", err)
+	var client *ssh.Client
+	if s.connPool != nil {
+		client, err = s.connPool.Acquire(s.connPoolKey(authCfg, proxy), dial)
+	} else {
+		client, err = dial()
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	s.connMu.Lock()
 	s.sshClient = client
+	s.connMu.Unlock()
+	s.Proxy = ""
+	if proxy != nil {
+		s.Proxy = proxy.String()
+	}
 	return client, nil
 }
 
+// connPoolKey returns the session's connection pool key. A connection is
+// only shared by sessions that would have opened the same one: to the same
+// configured server, through the same proxy, with the same credentials.
+// Passwords and passphrases go in as digests.
+func (s *Session) connPoolKey(auth ssh.AuthConfig, proxy *ssh.ProxyOptions) string {
+	key := poolKey(s.User, s.Host, s.Port)
+	if srv := s.serverConfig(); srv != nil {
+		key += "#server:" + srv.Name
+	}
+	if s.Identity != "" {
+		key += "#" + s.Identity
+	}
+	if auth.KeyPath != "" {
+		key += "#key:" + auth.KeyPath
+	}
+	if auth.CertPath != "" {
+		key += "#cert:" + auth.CertPath
+	}
+	if auth.KeyPassphrase != "" {
+		key += "#passphrase:" + secretDigest(auth.KeyPassphrase)
+	}
+	if auth.Password != "" {
+		key += "#password:" + secretDigest(auth.Password)
+	}
+	if !auth.UseAgent {
+		key += "#noagent"
+	}
+	if proxy != nil {
+		key += "#proxy:" + proxy.String()
+		if proxy.User != "" {
+			key += "#proxyuser:" + proxy.User
+		}
+		if proxy.Password != "" {
+			key += "#proxypassword:" + secretDigest(proxy.Password)
+		}
+	}
+	return key
+}
+
+// secretDigest returns a short digest of a secret for use in a pool key,
+// which is listed by shell_connection_list.
+func secretDigest(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// releaseSSHClient closes the session's SSH client, or drops the session's
+// reference to it when the client is shared through the connection pool.
+func (s *Session) releaseSSHClient() error {
+	s.connMu.Lock()
+	client := s.sshClient
+	s.sshClient = nil
+	s.connMu.Unlock()
+	if client == nil {
+		return nil
+	}
+	if s.connPool != nil {
+		return s.connPool.Release(client)
+	}
+	return client.Close()
+}
+
 // setupSSHPTY creates and configures the SSH PTY.
-func (s *Session) setupSSHPTY(client *ssh.Client) error {
+func (s *Session) setupSSHPTY(client *ssh.Client, x11 *ssh.X11Options) error {
 	ptyOpts := ssh.DefaultSSHPTYOptions()
+	ptyOpts.ReadBufferSize = s.readBufferSize()
+	ptyOpts.X11 = x11
 	sshPTY, err := ssh.NewSSHPTY(client, ptyOpts)
 	if err != nil {
 		return fmt.Errorf("create ssh pty: %w", err)
@@ -391,17 +762,23 @@ func (s *Session) setupSSHPTY(client *ssh.Client) error {
 }
 
 // initializeSSHShell initializes the shell environment.
+// The caller has already drained the startup output.
 func (s *Session) initializeSSHShell() {
-	s.clock.Sleep(500 * time.Millisecond)
-	buf := make([]byte, 8192)
-	s.readWithTimeout(buf, 500*time.Millisecond)
-
 	s.detectRemoteShell()
-	s.captureEnvAndPTY()
+	if s.autoCapture().Env {
+		s.captureEnvAndPTY()
+	} else {
+		s.capturePTYName()
+	}
 
-	s.pty.WriteString(s.shellPromptCommand())
-	s.clock.Sleep(200 * time.Millisecond)
-	s.readWithTimeout(buf, 300*time.Millisecond)
+	if s.normalizePrompt() {
+		s.pty.WriteString(s.shellPromptCommand())
+		s.clock.Sleep(200 * time.Millisecond)
+		buf := make([]byte, 8192)
+		s.readWithTimeout(buf, 300*time.Millisecond)
+	}
+	s.disablePagers()
+	s.captureOnConnect()
 }
 
 // extractPTYNumber extracts the PTY number from an SSH_TTY path like "/dev/pts/5".
@@ -437,10 +814,12 @@ func (s *Session) captureEnvAndPTY() {
 
 	envMap := parseEnvOutput(string(buf[:n]))
 	if len(envMap) > 0 {
-		s.EnvVars = envMap
+		s.EnvVars = s.filterEnv(envMap)
 	}
 
-	sshTTY, ok := s.EnvVars["SSH_TTY"]
+	// SSH_TTY is read before filtering: the PTY name is needed even when
+	// the variable itself is not kept.
+	sshTTY, ok := envMap["SSH_TTY"]
 	if !ok {
 		slog.Debug("SSH_TTY not found in environment")
 		return
@@ -472,19 +851,69 @@ func (s *Session) detectRemoteShell() {
 	}
 }
 
+// Default startup drain windows. Remote shells often print a banner and
+// MOTD after login, so SSH waits longer than a local PTY.
+const (
+	defaultLocalStartupDrain = 300 * time.Millisecond
+	defaultSSHStartupDrain   = 500 * time.Millisecond
+)
+
+// startupDrain returns how long to discard shell startup output, from
+// config.PTY.StartupDrainMs or the default for the session mode.
+func (s *Session) startupDrain() time.Duration {
+	if s.config != nil && s.config.PTY.StartupDrainMs > 0 {
+		return time.Duration(s.config.PTY.StartupDrainMs) * time.Millisecond
+	}
+	if s.Mode == "ssh" || s.Mode == "command" {
+		return defaultSSHStartupDrain
+	}
+	return defaultLocalStartupDrain
+}
+
+// maxStartupOutput bounds the startup output drainStartupOutput returns.
+const maxStartupOutput = 16 * 1024
+
+// drainStartupOutput discards the shell's startup output (banner, MOTD,
+// first prompt) until the drain window ends or the PTY goes quiet, and
+// returns the first maxStartupOutput bytes of it. Output still arriving
+// after the window shows up as async_output of the first command; raise
+// pty.startup_drain_ms if that happens.
+func (s *Session) drainStartupOutput() string {
+	buf := make([]byte, s.readBufferSize())
+	deadline := s.clock.Now().Add(s.startupDrain())
+	var out strings.Builder
+
+	for {
+		s.pty.SetReadDeadline(deadline)
+		n, err := s.pty.Read(buf)
+		if n > 0 && out.Len() < maxStartupOutput {
+			out.Write(buf[:n])
+		}
+		if err != nil || n == 0 || !s.clock.Now().Before(deadline) {
+			return out.String()
+		}
+	}
+}
+
 // readWithTimeout reads from PTY with a timeout, draining all available data.
 func (s *Session) readWithTimeout(buf []byte, timeout time.Duration) (int, error) {
 	totalRead := 0
 	deadline := s.clock.Now().Add(timeout)
+	// Drains stop once the PTY has been quiet for half a poll interval.
+	poll := s.pollInterval() / 2
 
-	for s.clock.Now().Before(deadline) {
-		s.pty.SetReadDeadline(s.clock.Now().Add(50 * time.Millisecond))
+	// Each empty read counts as a poll waited, so the loop ends even if the
+	// clock does not move.
+	for waited := time.Duration(0); waited < timeout && s.clock.Now().Before(deadline); {
+		s.pty.SetReadDeadline(s.clock.Now().Add(poll))
 		n, err := s.pty.Read(buf[totalRead:])
 		if n > 0 {
 			totalRead += n
 			if totalRead >= len(buf) {
 				break
 			}
+		} else {
+			waited += poll
 		}
 		if err != nil {
 			break
@@ -496,6 +925,9 @@ func (s *Session) readWithTimeout(buf []byte, timeout time.Duration) (int, error
 
 // reconnectSSH attempts to reconnect an SSH session with state restoration.
 func (s *Session) reconnectSSH() error {
+	// The root shell ends with the connection; the new one is the user's.
+	s.dropPrivileged()
+
 	// Save current state before reconnecting
 	savedCwd := s.Cwd
 	savedEnvVars := make(map[string]string)
@@ -507,9 +939,7 @@ func (s *Session) reconnectSSH() error {
 	if s.pty != nil {
 		s.pty.Close()
 	}
-	if s.sshClient != nil {
-		s.sshClient.Close()
-	}
+	s.releaseSSHClient()
 
 	// Re-initialize SSH with exponential backoff
 	var lastErr error
@@ -526,6 +956,7 @@ func (s *Session) reconnectSSH() error {
 
 		// Restore state after successful reconnect
 		s.restoreState(savedCwd, savedEnvVars)
+		s.setConnectionDropped("")
 		return nil
 	}
 
@@ -577,8 +1008,9 @@ func (s *Session) Status() SessionStatus {
 		Path: s.Shell,
 		Type: "bash", // Default assumption
 	}
-	if idx := strings.LastIndex(s.Shell, "/"); idx >= 0 {
-		shellName := s.Shell[idx+1:]
+	// Shell is a bare name after detectShellChange found e.g. "fish".
+	if s.Shell != "" {
+		shellName := path.Base(s.Shell)
 		shellInfo.Type = shellName
 		shellInfo.SupportsHistory = shellName == "bash" || shellName == "zsh"
 	}
@@ -597,18 +1029,35 @@ func (s *Session) Status() SessionStatus {
 		Connected:     s.pty != nil && s.State != StateClosed,
 	}
 
+	if s.Mode == "command" {
+		status.Command = s.Command
+	}
+	status.RawMode = s.RawMode
+	status.Privileged = s.Privileged
+	status.Container = s.Container
+	if s.Container != "" {
+		status.ContainerRuntime = s.containerRuntime()
+	}
 	if s.Mode == "ssh" {
 		status.Host = s.Host
 		status.User = s.User
+		status.ForwardX11 = s.forwardX11Enabled()
+		status.Proxy = s.Proxy
 		if s.sshClient != nil {
 			status.Connected = s.sshClient.IsConnected()
 		}
+		status.ConnectionError = s.connectionDroppedReason()
 	}
 
 	// Control plane info for debugging
 	status.PTYName = s.PTYName
 	status.HasControlSession = s.controlSession != nil
 
+	if maxCommands, maxBytes := s.quotaLimits(); maxCommands > 0 || maxBytes > 0 {
+		usage := s.quotaUsage()
+		status.Quota = &usage
+	}
+
 	// Include saved tunnels if any (from before MCP restart)
 	if len(s.SavedTunnels) > 0 {
 		status.SavedTunnels = s.SavedTunnels
@@ -646,23 +1095,86 @@ func (s *Session) ControlExecRaw(ctx context.Context, command string) (string, e
 
 // Exec executes a command in the session.
 func (s *Session) Exec(command string, timeoutMs int) (*ExecResult, error) {
+	return s.ExecWithOptions(command, timeoutMs, ExecOptions{})
+}
+
+// ExecWithOptions executes a command in the session with per-call options.
+func (s *Session) ExecWithOptions(command string, timeoutMs int, opts ExecOptions) (*ExecResult, error) {
+	if opts.Limits != nil {
+		if opts.Direct {
+			return nil, fmt.Errorf("limits cannot be combined with direct: they need a subshell, and direct runs in the session's shell")
+		}
+		if err := opts.Limits.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if err := s.validateExecPreconditions(); err != nil {
 		return nil, err
 	}
+	if opts.stdin != nil && s.RawMode {
+		return nil, fmt.Errorf("stdin is not supported in raw mode sessions")
+	}
+	if opts.Uncharged {
+		// The output is counted on the way out; put the usage back.
+		defer func(used int64) { s.outputBytes = used }(s.outputBytes)
+	} else if err := s.chargeCommand(); err != nil {
+		return nil, err
+	}
+	s.command = command
+	if opts.Shell != "" && !opts.Direct {
+		wrapped, err := WrapInShell(opts.Shell, command)
+		if err != nil {
+			return nil, err
+		}
+		command = wrapped
+	}
+	s.limits = opts.Limits
+	if opts.Limits != nil {
+		command = opts.Limits.wrap(command)
+	}
+	s.disarmPromptTimeout()
 
 	if err := s.ensureConnectionHealthy(); err != nil {
 		return nil, err
 	}
 
+	if opts.NoPTY && s.Mode == "ssh" && s.Container == "" {
+		result, err := s.execNoPTYLocked(command, s.getTimeout(timeoutMs))
+		s.markLimitExceeded(result)
+		s.chargeOutput(result)
+		return result, err
+	}
+
+	var shellChange *ShellChange
+	if !s.RawMode {
+		shellChange = s.detectShellChange()
+	}
+	if mayChangeShell(s.command) {
+		s.shellCheckPending = true
+	}
+
 	s.State = StateRunning
 	s.LastUsed = s.clock.Now()
 	s.outputBuffer.Reset()
+	s.outputBuffer.WriteString(s.startupOutput)
+	s.startupOutput = ""
+	s.collapseProgress = opts.CollapseProgress
+
+	if s.RawMode {
+		result, err := s.execRawLocked(command, s.getTimeout(timeoutMs))
+		s.chargeOutput(result)
+		return result, err
+	}
 
 	cmdID := s.generateCommandID()
 	fullCommand := s.buildWrappedCommand(command, cmdID)
+	if opts.Direct {
+		fullCommand = s.buildDirectCommand(command, cmdID)
+	}
 
 	if err := s.writeCommandWithReconnect(fullCommand); err != nil {
 		return nil, err
@@ -671,10 +1183,30 @@ func (s *Session) Exec(command string, timeoutMs int) (*ExecResult, error) {
 	s.applyMultilineDelay(command)
 
 	timeout := s.getTimeout(timeoutMs)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := s.commandContext(timeout)
 	defer cancel()
+	defer func() { s.adaptive = nil }()
 
-	return s.readOutputWithMarkers(ctx, command, cmdID)
+	s.autoReconnect = opts.AutoReconnect && s.Mode == "ssh"
+	defer func() { s.autoReconnect = false }()
+	s.killOnTimeout = opts.KillOnTimeout
+	defer func() { s.killOnTimeout = false }()
+	s.onOutput = opts.OnOutput
+	defer func() { s.onOutput = nil }()
+
+	result, err := s.readCommandOutput(ctx, command, cmdID, opts)
+	if errors.Is(err, errConnectionLost) {
+		result, err = s.recoverLostCommand(command, cmdID, timeout, opts, err)
+	}
+	s.trimInteractiveExit(result)
+	s.stripSudoOutput(result)
+	s.markLimitExceeded(result)
+	s.chargeOutput(result)
+	s.armPromptTimeout(result)
+	if result != nil {
+		result.ShellChanged = shellChange
+	}
+	return result, err
 }
 
 // validateExecPreconditions checks if session is ready for command execution.
@@ -702,7 +1234,10 @@ func (s *Session) checkSSHConnection() error {
 		return nil
 	}
 	if err := s.reconnectSSH(); err != nil {
-		return fmt.Errorf("reconnect failed: %w", err)
+		return &ConnectionError{
+			Code: s.lostConnectionCode(nil, err),
+			Err:  fmt.Errorf("reconnect failed: %w", err),
+		}
 	}
 	return nil
 }
@@ -728,19 +1263,94 @@ func (s *Session) checkPTYAlive() error {
 
 	if s.Mode == "ssh" {
 		if err := s.reconnectSSH(); err != nil {
-			return fmt.Errorf("session dead and reconnect failed: %w", err)
+			return &ConnectionError{
+				Code: s.lostConnectionCode(nil, err),
+				Err:  fmt.Errorf("session dead and reconnect failed: %w", err),
+			}
 		}
 		return nil
 	}
-	return fmt.Errorf("local session is dead (PTY has no processes)")
+	return &ConnectionError{Code: ConnLocalPTYDied, Err: errors.New("local session is dead (PTY has no processes)")}
+}
+
+// shellPathPattern matches an absolute shell path safe to splice into a
+// command line unquoted.
+var shellPathPattern = regexp.MustCompile(`^(/[A-Za-z0-9_.+-]+)+$`)
+
+// validShell reports whether shell is one of knownShells, by name or by a
+// clean absolute path to it. Other programs take -c too (python3, perl,
+// busybox) and would run the command as their own code, out of reach of
+// the command filter.
+func validShell(shell string) bool {
+	if knownShells[shell] {
+		return true
+	}
+	return shellPathPattern.MatchString(shell) && path.Clean(shell) == shell && knownShells[path.Base(shell)]
+}
+
+// WrapInShell returns command run through shell with -c. The command is
+// single-quoted here; buildWrappedCommand quotes the result again, so the
+// two layers of escaping compose.
+func WrapInShell(shell, command string) (string, error) {
+	if !validShell(shell) {
+		return "", fmt.Errorf("invalid shell %q: must be a known shell (%s), by name or absolute path", shell, strings.Join(slices.Sorted(maps.Keys(knownShells)), ", "))
+	}
+	return shell + " -c '" + strings.ReplaceAll(command, "'", "'\\''") + "'", nil
+}
+
+// commandWrapper returns the command_wrapper configured for the session's
+// server, or "" if there is none.
+func (s *Session) commandWrapper() string {
+	if s.Mode != "ssh" {
+		return ""
+	}
+	if srv := s.serverConfig(); srv != nil {
+		return srv.CommandWrapper
+	}
+	return ""
+}
+
+// applyCommandWrapper nests command inside the server's command_wrapper.
+// The markers stay outside, so the end marker reports the wrapper's exit
+// code, which for nice, timeout and the like is the command's own.
+func (s *Session) applyCommandWrapper(command string) string {
+	wrapper := s.commandWrapper()
+	if !strings.Contains(wrapper, config.CommandPlaceholder) {
+		return command
+	}
+	inner, _ := WrapInShell("bash", command)
+	return strings.ReplaceAll(wrapper, config.CommandPlaceholder, inner)
 }
 
 // buildWrappedCommand creates the full command with markers.
 func (s *Session) buildWrappedCommand(command, cmdID string) string {
+	command = s.applyCommandWrapper(s.applyContainer(command))
 	startMarker := startMarkerPrefix + cmdID + markerSuffix
 	endMarker := endMarkerPrefix + cmdID + markerSuffix
 	escapedCommand := strings.ReplaceAll(command, "'", "'\\''")
-	return fmt.Sprintf("echo '%s'; bash -c 'trap \"\" SIGTTOU; %s'; echo '%s'$?\n", startMarker, escapedCommand, endMarker)
+	return fmt.Sprintf("echo '%s'; %s -c 'trap \"\" TTOU; %s'; echo '%s'%s\n", startMarker, s.childShell(), escapedCommand, endMarker, s.exitStatusVar())
+}
+
+// childShell returns the shell buildWrappedCommand runs commands in: bash,
+// except in command mode, whose target (often a minimal container) may have
+// no bash; there it is the session's shell if that is POSIX, else /bin/sh.
+func (s *Session) childShell() string {
+	if s.Mode != "command" {
+		return "bash"
+	}
+	switch path.Base(s.Shell) {
+	case "sh", "bash", "zsh", "ash", "dash", "ksh", "mksh":
+		return s.Shell
+	}
+	return "/bin/sh"
+}
+
+// buildDirectCommand creates the command with markers, run by the session's
+// shell itself (see ExecOptions.Direct).
+func (s *Session) buildDirectCommand(command, cmdID string) string {
+	startMarker := startMarkerPrefix + cmdID + markerSuffix
+	endMarker := endMarkerPrefix + cmdID + markerSuffix
+	return fmt.Sprintf("echo '%s'; %s; echo '%s'%s\n", startMarker, command, endMarker, s.exitStatusVar())
 }
 
 // writeCommandWithReconnect writes command to PTY, reconnecting if needed.
@@ -752,7 +1362,7 @@ func (s *Session) writeCommandWithReconnect(fullCommand string) error {
 
 	if !isConnectionBroken(err) || s.Mode != "ssh" {
 		s.State = StateIdle
-		return fmt.Errorf("write command: %w", err)
+		return s.connectionError(fmt.Errorf("write command: %w", err))
 	}
 
 	slog.Warn("SSH connection broken, attempting reconnect",
@@ -762,12 +1372,15 @@ func (s *Session) writeCommandWithReconnect(fullCommand string) error {
 
 	if reconnErr := s.reconnectSSH(); reconnErr != nil {
 		s.State = StateIdle
-		return fmt.Errorf(errConnectionLostFmt, reconnErr, err)
+		return &ConnectionError{
+			Code: s.lostConnectionCode(err, reconnErr),
+			Err:  fmt.Errorf(errConnectionLostFmt, reconnErr, err),
+		}
 	}
 
 	if _, err := s.pty.WriteString(fullCommand); err != nil {
 		s.State = StateIdle
-		return fmt.Errorf("write command after reconnect: %w", err)
+		return s.connectionError(fmt.Errorf("write command after reconnect: %w", err))
 	}
 	return nil
 }
@@ -801,7 +1414,7 @@ func (s *Session) processLegacyRead(ctx context.Context, buf []byte, command str
 		return result, stallCount, nil
 	}
 
-	s.pty.SetReadDeadline(s.clock.Now().Add(100 * time.Millisecond))
+	s.pty.SetReadDeadline(s.clock.Now().Add(s.pollInterval()))
 
 	n, err := s.pty.Read(buf)
 	if err != nil {
@@ -813,25 +1426,41 @@ func (s *Session) processLegacyRead(ctx context.Context, buf []byte, command str
 			return nil, newStall, nil
 		}
 		s.State = StateIdle
-		return nil, newStall, fmt.Errorf("read output: %w", err)
+		return nil, newStall, s.connectionError(fmt.Errorf("read output: %w", err))
 	}
 
 	if n > 0 {
 		s.outputBuffer.Write(buf[:n])
+		s.feedScreen(buf[:n])
 		if result := s.checkLegacyOutputForResult(command); result != nil {
 			return result, 0, nil
 		}
+		if rate, runaway := s.checkRunaway(n); runaway {
+			return s.stopRunawayOutput(s.cleanOutput(s.outputBuffer.String(), command), "", "", rate), 0, nil
+		}
 		return nil, 0, nil
 	}
 	return nil, stallCount, nil
 }
 
+// readBufferSize returns the PTY read chunk size from config. Reads only
+// append to outputBuffer, so markers and escape sequences split across
+// chunks are still matched once the rest arrives.
+func (s *Session) readBufferSize() int {
+	if s.config != nil && s.config.PTY.ReadBufferBytes > 0 {
+		return s.config.PTY.ReadBufferBytes
+	}
+	return ssh.DefaultReadBufferSize
+}
+
 // readOutput reads output from PTY until completion or prompt detection.
 // Used by ProvideInput for continuing after user input.
 func (s *Session) readOutput(ctx context.Context, command string) (*ExecResult, error) {
-	buf := make([]byte, 4096)
+	buf := make([]byte, s.readBufferSize())
+	s.runaway = s.newRunawayGuard()
+	defer func() { s.runaway = nil }()
 	stallCount := 0
-	const stallThreshold = 15
+	stallThreshold := s.stallPolls()
 
 	for {
 		result, newStall, err := s.processLegacyRead(ctx, buf, command, stallCount, stallThreshold)
@@ -877,6 +1506,9 @@ func (s *Session) handleLegacyReadError(err error, command string, stallCount, s
 		if result := s.checkLegacyStallSignals(output, command); result != nil {
 			return result, stallCount, false
 		}
+		if result := s.checkLegacyAmbiguousStall(output, command); result != nil {
+			return result, stallCount, false
+		}
 		stallCount = stallThreshold / 2
 	}
 
@@ -902,7 +1534,7 @@ func (s *Session) checkLegacyCompletion(output, command string) *ExecResult {
 // checkLegacyStallSignals checks for input signals after stall threshold.
 func (s *Session) checkLegacyStallSignals(output, command string) *ExecResult {
 	cleanedStdout := s.cleanOutput(output, command)
-	strippedOutput := stripANSI(output)
+	strippedOutput := StripANSI(output)
 
 	// Check peak-tty signal
 	if containsPeakTTYSignal(output) {
@@ -913,7 +1545,7 @@ func (s *Session) checkLegacyStallSignals(output, command string) *ExecResult {
 			Stdout:        strings.ReplaceAll(cleanedStdout, "\x00", ""),
 			PromptType:    "interactive",
 			PromptText:    "",
-			ContextBuffer: stripANSI(strings.ReplaceAll(output, "\x00", "")),
+			ContextBuffer: StripANSI(strings.ReplaceAll(output, "\x00", "")),
 			Hint:          hintPeakTTYWaiting,
 		}
 	}
@@ -970,10 +1602,13 @@ func (s *Session) processMarkedRead(ctx context.Context, buf []byte, execCtx *ex
 		return result, stallCount, nil
 	}
 
-	s.pty.SetReadDeadline(s.clock.Now().Add(100 * time.Millisecond))
+	s.pty.SetReadDeadline(s.clock.Now().Add(s.pollInterval()))
 
 	n, err := s.pty.Read(buf)
 	if err != nil {
+		if s.autoReconnect && isConnectionBroken(err) {
+			return nil, stallCount, fmt.Errorf("%w: %w", errConnectionLost, err)
+		}
 		result, newStall, cont := s.handleReadError(err, execCtx, stallCount, stallThreshold)
 		if result != nil {
 			return result, newStall, nil
@@ -982,14 +1617,21 @@ func (s *Session) processMarkedRead(ctx context.Context, buf []byte, execCtx *ex
 			return nil, newStall, nil
 		}
 		s.State = StateIdle
-		return nil, newStall, fmt.Errorf("read output: %w", err)
+		return nil, newStall, s.connectionError(fmt.Errorf("read output: %w", err))
 	}
 
 	if n > 0 {
 		s.outputBuffer.Write(buf[:n])
+		s.feedScreen(buf[:n])
+		s.adaptive.outputArrived(s.clock.Now())
+		s.streamOutput(execCtx)
 		if result := s.checkOutputForResult(execCtx); result != nil {
 			return result, 0, nil
 		}
+		if rate, runaway := s.checkRunaway(n); runaway {
+			asyncOutput, stdout := s.parseMarkedOutput(s.outputBuffer.String(), execCtx.startMarker, execCtx.endMarker, execCtx.command)
+			return s.stopRunawayOutput(stdout, asyncOutput, execCtx.commandID, rate), 0, nil
+		}
 		return nil, 0, nil
 	}
 	return nil, stallCount, nil
@@ -1000,9 +1642,11 @@ func (s *Session) processMarkedRead(ctx context.Context, buf []byte, execCtx *ex
 // Output between start and end markers is the actual command output.
 func (s *Session) readOutputWithMarkers(ctx context.Context, command string, cmdID string) (*ExecResult, error) {
 	execCtx := newExecContext(cmdID, startMarkerPrefix+cmdID+markerSuffix, endMarkerPrefix+cmdID+markerSuffix, command)
-	buf := make([]byte, 4096)
+	buf := make([]byte, s.readBufferSize())
+	s.runaway = s.newRunawayGuard()
+	defer func() { s.runaway = nil }()
 	stallCount := 0
-	const stallThreshold = 15
+	stallThreshold := s.stallPolls()
 
 	for {
 		result, newStall, err := s.processMarkedRead(ctx, buf, execCtx, stallCount, stallThreshold)
@@ -1018,14 +1662,21 @@ func (s *Session) readOutputWithMarkers(ctx context.Context, command string, cmd
 
 // handleContextTimeout checks for context cancellation and returns timeout result.
 func (s *Session) handleContextTimeout(ctx context.Context, execCtx *execContext) *ExecResult {
-	select {
-	case <-ctx.Done():
+	if !s.commandTimedOut(ctx) {
+		return nil
+	}
+	var result *ExecResult
+	if s.killOnTimeout {
+		result = s.killTimedOutCommand(execCtx)
+	} else {
 		s.forceKillCommand()
 		s.State = StateIdle
-		return s.buildTimeoutResult(execCtx)
-	default:
-		return nil
+		result = s.buildTimeoutResult(execCtx)
 	}
+	if s.adaptive != nil && result.Hint == "" {
+		result.Hint = s.adaptive.hint()
+	}
+	return result
 }
 
 // handleReadError processes read errors and returns result if command completed.
@@ -1044,7 +1695,7 @@ func (s *Session) handleReadError(err error, execCtx *execContext, stallCount, s
 
 	// After stall threshold, check for prompts
 	if stallCount >= stallThreshold {
-		strippedOutput := stripANSI(s.outputBuffer.String())
+		strippedOutput := StripANSI(s.outputBuffer.String())
 
 		// Check for peak-tty signal first
 		if result, found := s.checkForPeakTTYSignal(execCtx); found {
@@ -1058,6 +1709,11 @@ func (s *Session) handleReadError(err error, execCtx *execContext, stallCount, s
 			return result, stallCount, false
 		}
 
+		// Nothing recognized - apply prompt_detection.on_ambiguous
+		if result, found := s.checkAmbiguousStall(execCtx); found {
+			return result, stallCount, false
+		}
+
 		// No signals detected - partially reset stall counter
 		stallCount = stallThreshold / 2
 	}
@@ -1090,14 +1746,13 @@ func (s *Session) checkOutputForResult(execCtx *execContext) *ExecResult {
 // parseMarkedOutput separates async output from command output using markers.
 // Returns (asyncOutput, commandOutput).
 func (s *Session) parseMarkedOutput(output, startMarker, endMarker, command string) (string, string) {
-	output = strings.ReplaceAll(output, "\r\n", "\n")
-	output = strings.ReplaceAll(output, "\r", "")
+	output = s.normalizeLineEndings(stripCommandEcho(output, startMarker, endMarker))
 
 	var asyncOutput, cmdOutput string
 
-	// Find start marker on its own line (not within the echoed command)
-	// The marker appears as: \n___CMD_START_xxx___\n (output from echo command)
-	// NOT within: echo '___CMD_START_xxx___'; ... (the command being echoed)
+	// Find start marker on its own line. The echoed command is gone, even
+	// when soft-wrapped, so only the marker printed by echo is left:
+	// \n___CMD_START_xxx___\n
 	startIdx := findMarkerOnOwnLine(output, startMarker)
 	if startIdx == -1 {
 		// No start marker yet - all output is async/pre-command
@@ -1223,16 +1878,18 @@ func containsPeakTTYSignal(s string) bool {
 	return strings.Contains(s, peakTTYSignal)
 }
 
-// stripANSI removes ANSI escape sequences from a string.
-func stripANSI(s string) string {
-	// Match ANSI escape sequences: ESC[ followed by parameters and a letter
-	ansiRegex := regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b\][^\x07]*\x07|\x1b[()][0-9A-Za-z]`)
+// ansiRegex matches ANSI escape sequences: CSI (ESC[ followed by parameters
+// and a letter), OSC and character set selection.
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b\][^\x07]*\x07|\x1b[()][0-9A-Za-z]`)
+
+// StripANSI removes ANSI escape sequences from a string.
+func StripANSI(s string) string {
 	return ansiRegex.ReplaceAllString(s, "")
 }
 
 // drainOutput drains any remaining output from the PTY after an interrupt or timeout.
 func (s *Session) drainOutput() {
-	buf := make([]byte, 4096)
+	buf := make([]byte, s.readBufferSize())
 	// Read with short deadline until we get no more data
 	for i := 0; i < 10; i++ { // Max 10 attempts (1 second total)
 		s.pty.SetReadDeadline(s.clock.Now().Add(100 * time.Millisecond))
@@ -1339,7 +1996,7 @@ func isConnectionBroken(err error) bool {
 	if err == nil {
 		return false
 	}
-	if err == io.EOF {
+	if isClosedError(err) {
 		return true
 	}
 	errStr := err.Error()
@@ -1351,19 +2008,39 @@ func isConnectionBroken(err error) bool {
 		strings.Contains(errStr, "channel closed")
 }
 
-// ProvideInput provides input to a session waiting for input.
+// ProvideInput provides input to a session waiting for input. Each input
+// counts as a command against the session's quotas.
 func (s *Session) ProvideInput(input string) (*ExecResult, error) {
+	return s.provideInput(input, true)
+}
+
+// ProvideInputUncharged is ProvideInput for input the server supplies on its
+// own, such as a cached sudo password; it does not count against quotas.
+func (s *Session) ProvideInputUncharged(input string) (*ExecResult, error) {
+	return s.provideInput(input, false)
+}
+
+func (s *Session) provideInput(input string, charge bool) (*ExecResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if err := s.validateAwaitingInputState(); err != nil {
 		return nil, err
 	}
+	if charge {
+		if err := s.chargeCommand(); err != nil {
+			return nil, err
+		}
+	}
+	s.disarmPromptTimeout()
 
 	s.State = StateRunning
 	s.LastUsed = s.clock.Now()
 
 	s.prepareForPasswordInput()
+	if mayChangeShell(input) {
+		s.shellCheckPending = true
+	}
 
 	toWrite := input + "\n"
 	if err := s.writeInputToPTY(toWrite); err != nil {
@@ -1376,7 +2053,15 @@ func (s *Session) ProvideInput(input string) (*ExecResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	return s.readOutput(ctx, "")
+	result, err := s.readOutput(ctx, "")
+	s.trimInteractiveExit(result)
+	s.stripSudoOutput(result)
+	s.markLimitExceeded(result)
+	if charge {
+		s.chargeOutput(result)
+	}
+	s.armPromptTimeout(result)
+	return result, err
 }
 
 // validateAwaitingInputState checks if session is ready for input.
@@ -1413,7 +2098,7 @@ func (s *Session) writeInputToPTY(toWrite string) error {
 	}
 
 	s.State = StateAwaitingInput
-	return fmt.Errorf("write input: %w", err)
+	return s.connectionError(fmt.Errorf("write input: %w", err))
 }
 
 // handleInputConnectionError handles broken connection during input.
@@ -1423,7 +2108,10 @@ func (s *Session) handleInputConnectionError(originalErr error) error {
 	)
 	s.State = StateIdle
 	if reconnErr := s.reconnectSSH(); reconnErr != nil {
-		return fmt.Errorf(errConnectionLostFmt, reconnErr, originalErr)
+		return &ConnectionError{
+			Code: s.lostConnectionCode(originalErr, reconnErr),
+			Err:  fmt.Errorf(errConnectionLostFmt, reconnErr, originalErr),
+		}
 	}
 	return fmt.Errorf("connection was lost (reconnected - please retry command)")
 }
@@ -1444,19 +2132,26 @@ func (s *Session) SendRaw(input string) (*ExecResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if s.State != StateAwaitingInput {
+	if s.State != StateAwaitingInput && !(s.RawMode && s.State == StateIdle) {
 		return nil, fmt.Errorf("session is not awaiting input (state: %s)", s.State)
 	}
 
 	if s.pty == nil {
 		return nil, fmt.Errorf(errSessionNotInitialized)
 	}
+	if err := s.chargeCommand(); err != nil {
+		return nil, err
+	}
+	s.disarmPromptTimeout()
 
 	s.State = StateRunning
 	s.LastUsed = s.clock.Now()
 
 	// Interpret escape sequences in the input
 	rawBytes := interpretEscapeSequences(input)
+	if mayChangeShell(string(rawBytes)) {
+		s.shellCheckPending = true
+	}
 
 	slog.Debug("sending raw bytes to PTY",
 		"len", len(rawBytes),
@@ -1471,12 +2166,15 @@ func (s *Session) SendRaw(input string) (*ExecResult, error) {
 			)
 			s.State = StateIdle
 			if reconnErr := s.reconnectSSH(); reconnErr != nil {
-				return nil, fmt.Errorf(errConnectionLostFmt, reconnErr, err)
+				return nil, &ConnectionError{
+					Code: s.lostConnectionCode(err, reconnErr),
+					Err:  fmt.Errorf(errConnectionLostFmt, reconnErr, err),
+				}
 			}
 			return nil, fmt.Errorf("connection was lost (reconnected - please retry)")
 		}
 		s.State = StateAwaitingInput
-		return nil, fmt.Errorf("write raw input: %w", err)
+		return nil, s.connectionError(fmt.Errorf("write raw input: %w", err))
 	}
 	slog.Debug("wrote raw bytes to PTY", "bytesWritten", n)
 
@@ -1488,7 +2186,19 @@ func (s *Session) SendRaw(input string) (*ExecResult, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	return s.readOutput(ctx, "")
+	if s.RawMode {
+		result, err := s.readRawOutput(ctx)
+		s.chargeOutput(result)
+		return result, err
+	}
+
+	result, err := s.readOutput(ctx, "")
+	s.trimInteractiveExit(result)
+	s.stripSudoOutput(result)
+	s.markLimitExceeded(result)
+	s.chargeOutput(result)
+	s.armPromptTimeout(result)
+	return result, err
 }
 
 // simpleEscapes maps single-character escape sequences to their byte values.
@@ -1619,26 +2329,29 @@ func (s *Session) waitForEchoDisabled() {
 	s.clock.Sleep(100 * time.Millisecond)
 }
 
-// Interrupt sends an interrupt signal to the session.
-func (s *Session) Interrupt() error {
+// Interrupt sends an interrupt signal to the session, then reads what the
+// command prints while it dies for up to session.interrupt_grace_period.
+func (s *Session) Interrupt() (*InterruptResult, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.State != StateRunning && s.State != StateAwaitingInput {
-		return fmt.Errorf("session is not running (state: %s)", s.State)
+		return nil, fmt.Errorf("session is not running (state: %s)", s.State)
 	}
 
 	if s.pty == nil {
-		return fmt.Errorf(errSessionNotInitialized)
+		return nil, fmt.Errorf(errSessionNotInitialized)
 	}
 
 	if err := s.pty.Interrupt(); err != nil {
-		return fmt.Errorf("send interrupt: %w", err)
+		return nil, fmt.Errorf("send interrupt: %w", err)
 	}
 
+	s.disarmPromptTimeout()
+	result := s.readInterruptOutput(s.interruptGracePeriod())
 	s.State = StateIdle
 	s.pendingPrompt = nil
-	return nil
+	return result, nil
 }
 
 // Close closes the session.
@@ -1649,6 +2362,7 @@ func (s *Session) Close() error {
 	if s.State == StateClosed {
 		return nil
 	}
+	s.disarmPromptTimeout()
 
 	var errs []error
 
@@ -1661,12 +2375,10 @@ func (s *Session) Close() error {
 		}
 	}
 
-	if s.sshClient != nil {
-		if err := s.sshClient.Close(); err != nil {
-			// Ignore EOF/broken connection errors - connection is already dead
-			if !isConnectionBroken(err) {
-				errs = append(errs, fmt.Errorf("close ssh: %w", err))
-			}
+	if err := s.releaseSSHClient(); err != nil {
+		// Ignore EOF/broken connection errors - connection is already dead
+		if !isConnectionBroken(err) {
+			errs = append(errs, fmt.Errorf("close ssh: %w", err))
 		}
 	}
 
@@ -1716,8 +2428,7 @@ func (s *Session) extractExitCode(output string) (int, bool) {
 
 // cleanOutput removes the command echo, end marker, and carriage returns from output.
 func (s *Session) cleanOutput(output, command string) string {
-	output = strings.ReplaceAll(output, "\r\n", "\n")
-	output = strings.ReplaceAll(output, "\r", "")
+	output = s.normalizeLineEndings(output)
 
 	lines := strings.Split(output, "\n")
 	var cleaned []string
@@ -1760,6 +2471,10 @@ func (s *Session) cleanOutput(output, command string) string {
 
 // updateCwd updates the current working directory.
 func (s *Session) updateCwd() {
+	if s.Container != "" {
+		// Commands run in the container's Cwd; the host shell's is unrelated.
+		return
+	}
 	s.pty.WriteString("pwd\n")
 	s.clock.Sleep(50 * time.Millisecond)
 
@@ -1768,18 +2483,38 @@ func (s *Session) updateCwd() {
 	n, _ := s.pty.Read(buf)
 
 	if n > 0 {
-		output := string(buf[:n])
-		lines := strings.Split(output, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line != "" && line != "pwd" && strings.HasPrefix(line, "/") {
-				s.Cwd = line
-				break
-			}
+		if cwd := parsePwdOutput(string(buf[:n])); cwd != "" {
+			s.Cwd = cwd
 		}
 	}
 }
 
+// parsePwdOutput returns the directory printed by pwd, or "" if there is
+// none. The prompt after pwd's output has no newline yet, so only complete
+// lines are considered and the last one naming a path wins; that keeps the
+// echoed command and prompts containing a path (e.g. PS1='\w$ ' with
+// shell.normalize_prompt off) out, while a directory that itself ends in
+// "$" or "#" is still read.
+func parsePwdOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	lines = lines[:len(lines)-1]
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "/") && !isPwdEcho(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+// isPwdEcho reports whether line is the pwd command echoed after a prompt
+// ("/srv/app $ pwd"), which is all there is when pwd's output has not
+// arrived yet.
+func isPwdEcho(line string) bool {
+	rest, ok := strings.CutSuffix(line, "pwd")
+	return ok && strings.HasSuffix(rest, " ")
+}
+
 // CaptureEnv captures current environment variables from the session.
 func (s *Session) CaptureEnv() map[string]string {
 	s.mu.Lock()
@@ -1788,9 +2523,13 @@ func (s *Session) CaptureEnv() map[string]string {
 	if s.pty == nil || s.State == StateClosed {
 		return s.EnvVars
 	}
+	return s.captureEnvLocked()
+}
 
-	// Send env command
-	s.pty.WriteString("env\n")
+// captureEnvLocked runs env in the session's shell (or container) and
+// stores the filtered result. Caller must hold s.mu.
+func (s *Session) captureEnvLocked() map[string]string {
+	s.pty.WriteString(s.containerCommand("env") + "\n")
 	s.clock.Sleep(100 * time.Millisecond)
 
 	// Read output
@@ -1807,7 +2546,7 @@ func (s *Session) CaptureEnv() map[string]string {
 
 	// Update stored env vars
 	if len(envMap) > 0 {
-		s.EnvVars = envMap
+		s.EnvVars = s.filterEnv(envMap)
 	}
 
 	return s.EnvVars
@@ -1848,8 +2587,12 @@ func (s *Session) CaptureAliases() map[string]string {
 	if s.pty == nil || s.State == StateClosed {
 		return s.Aliases
 	}
+	return s.captureAliasesLocked()
+}
 
-	// Send alias command
+// captureAliasesLocked runs alias in the session's shell and stores the
+// result. Caller must hold s.mu.
+func (s *Session) captureAliasesLocked() map[string]string {
 	s.pty.WriteString("alias\n")
 	s.clock.Sleep(100 * time.Millisecond)
 
@@ -1939,6 +2682,7 @@ func (s *Session) GetShellInfo() ShellInfo {
 type ShellInfo struct {
 	Type            string `json:"type"`
 	Path            string `json:"path"`
+	Version         string `json:"version,omitempty"`
 	SupportsHistory bool   `json:"supports_history"`
 }
 
@@ -1956,12 +2700,21 @@ type SessionStatus struct {
 	Aliases           map[string]string `json:"aliases,omitempty"`
 	Host              string            `json:"host,omitempty"`
 	User              string            `json:"user,omitempty"`
+	ForwardX11        bool              `json:"forward_x11,omitempty"`
+	Proxy             string            `json:"proxy,omitempty"` // proxy URL without credentials
+	Command           string            `json:"command,omitempty"`
+	RawMode           bool              `json:"raw_mode,omitempty"`   // commands are sent verbatim, exit codes unavailable
+	Privileged        bool              `json:"privileged,omitempty"` // in the root shell started by shell_sudo_shell
+	Container         string            `json:"container,omitempty"`
+	ContainerRuntime  string            `json:"container_runtime,omitempty"`
 	Connected         bool              `json:"connected"`
+	ConnectionError   string            `json:"connection_error,omitempty"` // why the connection was dropped, until reconnect
 	SudoCached        bool              `json:"sudo_cached,omitempty"`
 	SudoExpiresIn     int               `json:"sudo_expires_in_seconds,omitempty"`
 	PTYName           string            `json:"pty_name,omitempty"`
 	HasControlSession bool              `json:"has_control_session,omitempty"`
 	SavedTunnels      []TunnelConfig    `json:"saved_tunnels,omitempty"` // Tunnels from before MCP restart
+	Quota             *QuotaUsage       `json:"quota,omitempty"`         // usage vs security.max_*_per_session, when set
 }
 
 // ExecResult represents the result of command execution.
@@ -1987,10 +2740,34 @@ type ExecResult struct {
 	TruncatedBytes int    `json:"truncated_bytes,omitempty"` // Bytes shown after truncation
 	Warning        string `json:"warning,omitempty"`         // Warning message for large outputs
 	OutputFile     string `json:"output_file,omitempty"`     // Path to file with full output (when too large)
+	OutputResource string `json:"output_resource,omitempty"` // MCP resource URI for OutputFile
+	OutputStrategy string `json:"output_strategy,omitempty"` // where large output went: save_dir, temp_dir or inline_truncated
 	// Async output from background processes (not from this command)
 	AsyncOutput string `json:"async_output,omitempty"`
 	// Command ID used for marker-based output isolation
 	CommandID string `json:"command_id,omitempty"`
+	// Seconds left to answer an awaiting_input prompt before it is auto-interrupted
+	InputTimeoutSeconds int `json:"input_timeout_seconds,omitempty"`
+	// Set when auto_reconnect restored a connection that dropped mid-command
+	Reconnected bool `json:"reconnected,omitempty"`
+	// Set when an idempotent command was re-run after reconnecting
+	Replayed bool `json:"replayed,omitempty"`
+	// Why the connection was lost for a connection_lost result: remote_closed,
+	// network_timeout, auth_revoked or local_pty_died
+	ErrorCode ConnectionErrorCode `json:"error_code,omitempty"`
+	// Wall-clock time of the shell_exec call in milliseconds
+	DurationMs int64 `json:"duration_ms,omitempty"`
+	// Set when security.auto_sudo_patterns prefixed the command with sudo
+	AutoSudo bool `json:"auto_sudo,omitempty"`
+	// Whether a completed command's exit code is one of shell_exec's
+	// ok_exit_codes (default: 0)
+	Success *bool `json:"success,omitempty"`
+	// Set when the shell in the PTY was found to have changed (exec zsh,
+	// su) before this command ran; Shell is now the new one
+	ShellChanged *ShellChange `json:"shell_changed,omitempty"`
+	// For a "limit_exceeded" result, the limit the command ran into:
+	// cpu_seconds, max_memory_mb or max_file_size_mb
+	LimitExceeded string `json:"limit_exceeded,omitempty"`
 }
 
 // SFTPClient returns an SFTP client for file transfer operations.
@@ -2064,6 +2841,37 @@ func (s *Session) ClearSavedTunnels() {
 	s.SavedTunnels = nil
 }
 
+// RunningCommand returns the command the session is running or waiting on
+// input for, or "" when it is idle.
+func (s *Session) RunningCommand() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.State != StateRunning && s.State != StateAwaitingInput {
+		return ""
+	}
+	return s.command
+}
+
+// AwaitingMaskedInput reports whether the session is waiting at a prompt
+// whose input must not be echoed or logged, such as a password prompt.
+func (s *Session) AwaitingMaskedInput() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State == StateAwaitingInput && s.pendingPrompt != nil && s.pendingPrompt.Pattern.MaskInput
+}
+
+// AwaitingPromptType returns the type of the prompt the session is waiting
+// at, or "" if it is not waiting at a detected prompt (e.g. a command that
+// reads stdin or a nested shell went quiet).
+func (s *Session) AwaitingPromptType() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.State != StateAwaitingInput || s.pendingPrompt == nil {
+		return ""
+	}
+	return string(s.pendingPrompt.Pattern.Type)
+}
+
 // IsSSH returns true if this is an SSH session.
 func (s *Session) IsSSH() bool {
 	return s.Mode == "ssh"
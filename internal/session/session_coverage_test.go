@@ -519,6 +519,33 @@ func TestSession_Initialize_WithCustomPromptPatterns(t *testing.T) {
 	}
 }
 
+func TestSession_Initialize_CustomPatternPriority(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.PromptDetection.CustomPatterns = []config.PatternConfig{
+		{
+			Name:      "deploy_token",
+			Regex:     `(?i)do you want to continue\?\s*\[Y/n\]\s*$`,
+			Type:      "password",
+			MaskInput: true,
+			Priority:  5,
+		},
+	}
+
+	sess := NewSession("test_pattern_priority", "local",
+		WithPTY(fakepty.New()),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	det := sess.promptDetector.Detect("Do you want to continue? [Y/n] ")
+	if det == nil || det.Pattern.Name != "deploy_token" || det.Pattern.Priority != 5 {
+		t.Errorf("detection = %+v, want deploy_token with priority 5", det)
+	}
+}
+
 func TestSession_Initialize_WithInvalidCustomPattern(t *testing.T) {
 	pty := fakepty.New()
 	clock := fakeclock.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
@@ -1808,6 +1835,90 @@ func TestSession_BuildSSHAuthConfig_WithExplicitKeyPath(t *testing.T) {
 	}
 }
 
+func TestSession_BuildSSHAuthConfig_WithIdentity(t *testing.T) {
+	fs := fakefs.New()
+	fs.SetEnv("DEPLOY_PASSPHRASE", "deploy_passphrase")
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{
+			Name:    "web",
+			Host:    "web.example.com",
+			KeyPath: "/keys/personal",
+			Identities: []config.IdentityConfig{
+				{Name: "deploy", AuthConfig: config.AuthConfig{Path: "/keys/deploy", PassphraseEnv: "DEPLOY_PASSPHRASE"}},
+			},
+		},
+	}
+
+	sess := &Session{Host: "web", User: "deploy", Identity: "deploy", config: cfg, fs: fs}
+	authCfg := sess.buildSSHAuthConfig()
+	if authCfg.KeyPath != "/keys/deploy" || authCfg.KeyPassphrase != "deploy_passphrase" {
+		t.Errorf("KeyPath = %q, KeyPassphrase = %q; want the identity's", authCfg.KeyPath, authCfg.KeyPassphrase)
+	}
+	if authCfg.UseAgent {
+		t.Error("UseAgent should be false so agent keys are not offered before the identity's")
+	}
+
+	// Without an identity the server's own key and the agent are used.
+	sess.Identity = ""
+	authCfg = sess.buildSSHAuthConfig()
+	if authCfg.KeyPath != "/keys/personal" || !authCfg.UseAgent {
+		t.Errorf("KeyPath = %q, UseAgent = %v; want the server's key and the agent", authCfg.KeyPath, authCfg.UseAgent)
+	}
+}
+
+func TestSession_BuildSSHAuthConfig_Certificate(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{
+			Name: "web",
+			Host: "web.example.com",
+			Auth: config.AuthConfig{Path: "/keys/fleet", CertPath: "/keys/fleet-cert.pub"},
+			Identities: []config.IdentityConfig{
+				{Name: "deploy", AuthConfig: config.AuthConfig{Path: "/keys/deploy", CertPath: "/keys/deploy-cert.pub"}},
+			},
+		},
+	}
+	clk := fakeclock.New(time.Now())
+
+	sess := &Session{Host: "web", User: "alice", config: cfg, fs: fakefs.New(), clock: clk}
+	authCfg := sess.buildSSHAuthConfig()
+	if authCfg.KeyPath != "/keys/fleet" || authCfg.CertPath != "/keys/fleet-cert.pub" {
+		t.Errorf("KeyPath = %q, CertPath = %q; want the server's key and certificate", authCfg.KeyPath, authCfg.CertPath)
+	}
+	if authCfg.Clock != clk {
+		t.Error("Clock should be the session's, for the certificate validity check")
+	}
+
+	sess.Identity = "deploy"
+	authCfg = sess.buildSSHAuthConfig()
+	if authCfg.KeyPath != "/keys/deploy" || authCfg.CertPath != "/keys/deploy-cert.pub" {
+		t.Errorf("KeyPath = %q, CertPath = %q; want the identity's", authCfg.KeyPath, authCfg.CertPath)
+	}
+}
+
+func TestSession_ValidateSSHConfig_Identity(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "web", Host: "web.example.com", Identities: []config.IdentityConfig{{Name: "deploy"}}},
+	}
+
+	sess := &Session{Host: "web.example.com", User: "deploy", Identity: "deploy", config: cfg}
+	if err := sess.validateSSHConfig(); err != nil {
+		t.Errorf("validateSSHConfig() error: %v", err)
+	}
+
+	sess.Identity = "personal"
+	if err := sess.validateSSHConfig(); err == nil || !strings.Contains(err.Error(), "available: deploy") {
+		t.Errorf("validateSSHConfig() error = %v, want an unknown identity error", err)
+	}
+
+	sess = &Session{Host: "db.example.com", User: "deploy", Identity: "deploy", config: cfg}
+	if err := sess.validateSSHConfig(); err == nil || !strings.Contains(err.Error(), "not a configured server") {
+		t.Errorf("validateSSHConfig() error = %v, want an unconfigured server error", err)
+	}
+}
+
 // ============================================================================
 // ProvideInput with password prompt
 // ============================================================================
@@ -2111,8 +2222,7 @@ func TestSession_BuildCompletedResult(t *testing.T) {
 	}
 }
 
-// restoreState calls readWithTimeout internally, which loops forever with fakeclock.
-// Test only the nil-PTY early return path.
+// restoreState with a PTY is covered by TestRestoreState_WithFakeClock.
 
 func TestSession_RestoreState_NilPTY(t *testing.T) {
 	sess := &Session{pty: nil}
@@ -2120,9 +2230,8 @@ func TestSession_RestoreState_NilPTY(t *testing.T) {
 	sess.restoreState("/dir", map[string]string{"KEY": "val"})
 }
 
-// detectRemoteShell and captureEnvAndPTY both call readWithTimeout internally,
-// which loops forever with fakeclock (Now() never advances). These functions
-// are tested indirectly through integration tests that use real clocks.
+// detectRemoteShell and captureEnvAndPTY are tested indirectly through
+// integration tests that use real clocks.
 
 // min function is already tested in control_coverage_test.go
 
@@ -2160,7 +2269,7 @@ func TestSession_Interrupt_ClearsPendingPrompt(t *testing.T) {
 	// Set pendingPrompt to a non-nil value (will be cleared by Interrupt)
 	sess.pendingPrompt = &prompt.Detection{}
 
-	err := sess.Interrupt()
+	_, err := sess.Interrupt()
 	if err != nil {
 		t.Fatalf("Interrupt error: %v", err)
 	}
@@ -2182,3 +2291,19 @@ func TestSession_ShellPromptCommand_BareShellName(t *testing.T) {
 		t.Errorf("expected zsh prompt setting, got %q", cmd)
 	}
 }
+
+func TestSession_BuildSSHAuthConfig_NamedServer(t *testing.T) {
+	fs := fakefs.New()
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{
+		{Name: "web-admin", Host: "web.example.com", KeyPath: "/keys/admin_key"},
+		{Name: "web", Host: "web.example.com", KeyPath: "/keys/web_key"},
+	}
+
+	for _, host := range []string{"web.example.com", "10.0.0.5"} {
+		sess := &Session{Host: host, User: "user", Server: "web", config: cfg, fs: fs}
+		if authCfg := sess.buildSSHAuthConfig(); authCfg.KeyPath != "/keys/web_key" {
+			t.Errorf("host %s: KeyPath = %q, want the named server's", host, authCfg.KeyPath)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -282,9 +283,9 @@ func TestStripANSI(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := stripANSI(tt.input)
+			got := StripANSI(tt.input)
 			if got != tt.want {
-				t.Errorf("stripANSI(%q) = %q, want %q", tt.input, got, tt.want)
+				t.Errorf("StripANSI(%q) = %q, want %q", tt.input, got, tt.want)
 			}
 		})
 	}
@@ -546,3 +547,66 @@ func findSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestSession_Exec_SmallReadBufferSplitsMarkers(t *testing.T) {
+	pty := fakepty.New()
+	clock := fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.DefaultConfig()
+	cfg.PTY.ReadBufferBytes = 7
+
+	sess := NewSession("sess_small_buf", "local",
+		WithPTY(pty),
+		WithSessionClock(clock),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(cfg),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	// A single chunk far larger than the read buffer: both markers are
+	// split across several reads.
+	pty.AddResponse(buildCommandOutput("01020304", "line one\nline two", 3))
+
+	result, err := sess.Exec("cat file", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q, want completed", result.Status)
+	}
+	if result.ExitCode == nil || *result.ExitCode != 3 {
+		t.Errorf("ExitCode = %v, want 3", result.ExitCode)
+	}
+	if result.Stdout != "line one\nline two" {
+		t.Errorf("Stdout = %q, want both lines", result.Stdout)
+	}
+	if sizes := pty.ReadSizes(); len(sizes) == 0 || sizes[0] != 7 {
+		t.Errorf("read buffer sizes = %v, want first read of 7 bytes", sizes)
+	}
+}
+
+func TestExecWithOptions_DirectRunsInSessionShell(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{Host: "batch.example.com", CommandWrapper: "nice {{cmd}}"}}
+	pty := fakepty.New()
+	sess := NewSession("sess_direct", "ssh",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(cfg),
+	)
+	sess.Host = "batch.example.com"
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	pty.AddResponse(buildCommandOutput("00010203", "", 0))
+
+	if _, err := sess.ExecWithOptions("jobs -l", 1000, ExecOptions{Direct: true, Shell: "zsh"}); err != nil {
+		t.Fatalf("ExecWithOptions error: %v", err)
+	}
+	want := "echo '___CMD_START_00010203___'; jobs -l; echo '___CMD_END_00010203___'$?\n"
+	if written := pty.Written(); !strings.HasPrefix(written, want) {
+		t.Errorf("written = %q, want prefix %q", written, want)
+	}
+}
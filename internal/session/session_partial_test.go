@@ -1378,6 +1378,7 @@ func TestPartial_ProcessLegacyRead_FatalReadError(t *testing.T) {
 		WithSessionClock(clock),
 		WithConfig(cfg),
 	)
+	sess.localPTYFactory = fakePTYFactory
 	if err := sess.Initialize(); err != nil {
 		t.Fatalf("Initialize error: %v", err)
 	}
@@ -1411,6 +1412,7 @@ func TestPartial_ProcessMarkedRead_FatalReadError(t *testing.T) {
 		WithSessionClock(clock),
 		WithConfig(cfg),
 	)
+	sess.localPTYFactory = fakePTYFactory
 	if err := sess.Initialize(); err != nil {
 		t.Fatalf("Initialize error: %v", err)
 	}
@@ -461,30 +461,30 @@ func TestContainsPeakTTYSignal_Empty(t *testing.T) {
 func TestStripANSI_OSCSequences(t *testing.T) {
 	// OSC (Operating System Command) sequences like terminal title
 	input := "\x1b]0;title\x07some text"
-	got := stripANSI(input)
+	got := StripANSI(input)
 	if got != "some text" {
-		t.Errorf("stripANSI(%q) = %q, want %q", input, got, "some text")
+		t.Errorf("StripANSI(%q) = %q, want %q", input, got, "some text")
 	}
 }
 
 func TestStripANSI_EmptyInput(t *testing.T) {
-	got := stripANSI("")
+	got := StripANSI("")
 	if got != "" {
-		t.Errorf("stripANSI(\"\") = %q, want empty", got)
+		t.Errorf("StripANSI(\"\") = %q, want empty", got)
 	}
 }
 
 func TestStripANSI_NoEscapes(t *testing.T) {
 	input := "just plain text"
-	got := stripANSI(input)
+	got := StripANSI(input)
 	if got != input {
-		t.Errorf("stripANSI(%q) = %q, want %q", input, got, input)
+		t.Errorf("StripANSI(%q) = %q, want %q", input, got, input)
 	}
 }
 
 func TestStripANSI_MultipleSequences(t *testing.T) {
 	input := "\x1b[31m\x1b[1mred bold\x1b[0m \x1b[32mgreen\x1b[0m"
-	got := stripANSI(input)
+	got := StripANSI(input)
 	if got != "red bold green" {
 		t.Errorf("stripANSI = %q, want %q", got, "red bold green")
 	}
@@ -921,7 +921,7 @@ func TestCheckForPasswordPrompt_Found(t *testing.T) {
 	sess.State = StateRunning
 	sess.outputBuffer.WriteString(startM + "\n[sudo] password for user: ")
 
-	strippedOutput := stripANSI(sess.outputBuffer.String())
+	strippedOutput := StripANSI(sess.outputBuffer.String())
 	result, found := sess.checkForPasswordPrompt(ctx, strippedOutput)
 	if !found {
 		t.Fatal("expected password prompt to be detected")
@@ -955,7 +955,7 @@ func TestCheckForPasswordPrompt_NotFound(t *testing.T) {
 	ctx := newExecContext(cmdID, startM, endM, "ls")
 
 	sess.outputBuffer.WriteString(startM + "\nnormal output\n")
-	strippedOutput := stripANSI(sess.outputBuffer.String())
+	strippedOutput := StripANSI(sess.outputBuffer.String())
 
 	_, found := sess.checkForPasswordPrompt(ctx, strippedOutput)
 	if found {
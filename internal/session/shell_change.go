@@ -0,0 +1,126 @@
+package session
+
+import (
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ShellChange reports that the shell answering in a session's PTY is no
+// longer the one it had, e.g. after exec zsh or su.
+type ShellChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// mayChangeShell reports whether command can leave a different shell in
+// the PTY: exec, su, sudo -i/-s or sudo su, or a bare shell invocation.
+func mayChangeShell(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "exec":
+		return len(fields) > 1
+	case "su":
+		return true
+	case "sudo":
+		for i := 1; i < len(fields); i++ {
+			switch f := fields[i]; {
+			case f == "-i" || f == "-s" || f == "--login" || f == "--shell":
+				return true
+			case f == "-u" || f == "-g":
+				i++ // skip the flag's argument
+			case !strings.HasPrefix(f, "-"):
+				return f == "su" || mayChangeShell(strings.Join(fields[i:], " "))
+			}
+		}
+		return false
+	}
+	if !knownShells[path.Base(fields[0])] {
+		return false
+	}
+	// bash script.sh runs a script rather than starting a shell.
+	for _, f := range fields[1:] {
+		if !strings.HasPrefix(f, "-") {
+			return false
+		}
+	}
+	return true
+}
+
+// shellNameLine matches the output of the probe, $0 of the running shell:
+// a name or path, with a leading - for login shells.
+var shellNameLine = regexp.MustCompile(`^-?([\w./+-]+)$`)
+
+// detectShellChange asks the shell in the PTY for its name after a command
+// that may have changed it. If it differs from s.Shell, s.Shell is updated,
+// the prompt and pager settings are applied to the new shell, and the change
+// is returned; markers use the new shell's syntax from then on. Caller must
+// hold s.mu.
+func (s *Session) detectShellChange() *ShellChange {
+	if !s.shellCheckPending {
+		return nil
+	}
+	s.shellCheckPending = false
+
+	current := s.probeShell()
+	if current == "" || path.Base(current) == path.Base(s.Shell) {
+		return nil
+	}
+	change := &ShellChange{From: s.Shell, To: current}
+	slog.Warn("shell changed",
+		slog.String("session_id", s.ID),
+		slog.String("from", change.From),
+		slog.String("to", change.To),
+	)
+	s.Shell = current
+	s.setupShell()
+	return change
+}
+
+// setupShell applies the prompt and pager settings to the shell answering
+// in the PTY, for a shell that started after Initialize. Caller must hold
+// s.mu.
+func (s *Session) setupShell() {
+	if s.normalizePrompt() {
+		s.pty.WriteString(s.shellPromptCommand())
+		s.clock.Sleep(100 * time.Millisecond)
+		buf := make([]byte, 4096)
+		s.readWithTimeout(buf, 200*time.Millisecond)
+	}
+	s.disablePagers()
+}
+
+// probeShell returns the name of the shell answering in the PTY, or "" if
+// it cannot tell.
+func (s *Session) probeShell() string {
+	s.pty.WriteString("echo \"$0\"\n")
+	s.clock.Sleep(100 * time.Millisecond)
+	buf := make([]byte, 4096)
+	n, _ := s.readWithTimeout(buf, 300*time.Millisecond)
+	for _, line := range strings.Split(string(buf[:n]), "\n") {
+		line = strings.TrimSpace(line)
+		// fish rejects $0 with a message naming itself.
+		if strings.HasPrefix(line, "fish:") {
+			return "fish"
+		}
+		if m := shellNameLine.FindStringSubmatch(line); m != nil && knownShells[path.Base(m[1])] {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// exitStatusVar returns the variable holding the last exit status in the
+// session's shell, for the end marker.
+func (s *Session) exitStatusVar() string {
+	switch path.Base(s.Shell) {
+	case "fish", "csh", "tcsh":
+		return "$status"
+	}
+	return "$?"
+}
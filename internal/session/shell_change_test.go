@@ -0,0 +1,189 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/prompt"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestMayChangeShell(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"exec zsh", true},
+		{"exec /usr/bin/fish -l", true},
+		{"su", true},
+		{"su - deploy", true},
+		{"sudo -i", true},
+		{"sudo -s", true},
+		{"sudo -u deploy -i", true},
+		{"sudo su -", true},
+		{"sudo bash", true},
+		{"zsh", true},
+		{"bash -l", true},
+		{"/bin/sh", true},
+		{"exec", false},
+		{"bash script.sh", false},
+		{"sudo apt update", false},
+		{"sudo -u deploy whoami", false},
+		{"sudo bash -c 'make install'", false},
+		{"ls -la", false},
+		{"echo exec zsh", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := mayChangeShell(tt.command); got != tt.want {
+			t.Errorf("mayChangeShell(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+// shellSwitchingPTY answers command lines like a shell whose $0 probe
+// prints probe, and records the end markers it was sent.
+func shellSwitchingPTY(probe string) *fakepty.PTY {
+	return fakepty.New().SetResponder(func(written string) string {
+		switch {
+		case written == "echo \"$0\"\n":
+			return "echo \"$0\"\r\n" + probe + "\r\n$ "
+		case written == "pwd\n":
+			return "pwd\r\n/home/user\r\n$ "
+		}
+		m := pipelineStartPattern.FindStringSubmatch(written)
+		if m == nil {
+			return ""
+		}
+		return startMarkerPrefix + m[1] + markerSuffix + "\r\n" + endMarkerPrefix + m[1] + markerSuffix + "0\r\n$ "
+	})
+}
+
+func newShellChangeSession(pty *fakepty.PTY) *Session {
+	cfg := config.DefaultConfig()
+	cfg.Session.DisablePagers = nil
+	sess := NewSession("sess_shell", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(cfg),
+	)
+	sess.Shell = "/bin/bash"
+	sess.State = StateIdle
+	sess.promptDetector = prompt.NewDetector()
+	return sess
+}
+
+func TestExec_DetectsShellChange(t *testing.T) {
+	pty := shellSwitchingPTY("fish: $0 is not supported. In fish, please use 'status filename'.")
+	sess := newShellChangeSession(pty)
+
+	result, err := sess.Exec("exec fish", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.ShellChanged != nil {
+		t.Errorf("ShellChanged = %+v before the shell was probed", result.ShellChanged)
+	}
+
+	result, err = sess.Exec("ls", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.ShellChanged == nil || result.ShellChanged.From != "/bin/bash" || result.ShellChanged.To != "fish" {
+		t.Errorf("ShellChanged = %+v, want /bin/bash -> fish", result.ShellChanged)
+	}
+	if sess.Shell != "fish" {
+		t.Errorf("Shell = %q, want fish", sess.Shell)
+	}
+	written := pty.Written()
+	if strings.Count(written, "echo \"$0\"") != 1 {
+		t.Errorf("probe sent %d times, want once: %q", strings.Count(written, "echo \"$0\""), written)
+	}
+	if last := written[strings.LastIndex(written, "echo '"+startMarkerPrefix):]; !strings.Contains(last, markerSuffix+"'$status") {
+		t.Errorf("end marker after the change = %q, want fish's $status", last)
+	}
+	if status := sess.Status(); status.Shell != "fish" || status.ShellInfo.Type != "fish" {
+		t.Errorf("Status() shell = %q (%s), want fish", status.Shell, status.ShellInfo.Type)
+	}
+}
+
+func TestExec_ShellUnchangedAfterProbe(t *testing.T) {
+	pty := shellSwitchingPTY("-bash")
+	sess := newShellChangeSession(pty)
+
+	if _, err := sess.Exec("sudo -i", 5000); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	result, err := sess.Exec("whoami", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.ShellChanged != nil || sess.Shell != "/bin/bash" {
+		t.Errorf("ShellChanged = %+v, Shell = %q, want no change", result.ShellChanged, sess.Shell)
+	}
+	if !strings.Contains(pty.Written(), "echo \"$0\"") {
+		t.Error("shell was not probed after sudo -i")
+	}
+}
+
+func TestExec_NoProbeWithoutShellSwitch(t *testing.T) {
+	pty := shellSwitchingPTY("zsh")
+	sess := newShellChangeSession(pty)
+
+	for _, command := range []string{"ls", "bash build.sh"} {
+		if _, err := sess.Exec(command, 5000); err != nil {
+			t.Fatalf("Exec(%q) error = %v", command, err)
+		}
+	}
+	if strings.Contains(pty.Written(), "echo \"$0\"") {
+		t.Errorf("shell probed without a shell-switching command: %q", pty.Written())
+	}
+	if sess.Shell != "/bin/bash" {
+		t.Errorf("Shell = %q, want /bin/bash", sess.Shell)
+	}
+}
+
+func TestSendRaw_MarksShellCheck(t *testing.T) {
+	pty := fakepty.New().AddResponse("___CMD_END_MARKER___0\n")
+	sess := newShellChangeSession(pty)
+	// At the prompt of a su that was still running when the command timed out.
+	sess.State = StateAwaitingInput
+
+	if _, err := sess.SendRaw("exec zsh\\n"); err != nil {
+		t.Fatalf("SendRaw() error = %v", err)
+	}
+	if !sess.shellCheckPending {
+		t.Error("shellCheckPending not set after exec zsh was typed")
+	}
+}
+
+func TestExec_FishOutputParsed(t *testing.T) {
+	pty := fakepty.New().SetResponder(func(written string) string {
+		m := pipelineStartPattern.FindStringSubmatch(written)
+		if m == nil {
+			return ""
+		}
+		// fish echoes the command line, $status included, before running it.
+		return "> " + strings.TrimSuffix(written, "\n") + "\r\n" +
+			startMarkerPrefix + m[1] + markerSuffix + "\r\nhello from fish\r\n" +
+			endMarkerPrefix + m[1] + markerSuffix + "0\r\n> "
+	})
+	sess := newShellChangeSession(pty)
+	sess.Shell = "fish"
+
+	result, err := sess.Exec("echo hello from fish", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if !strings.Contains(pty.Written(), "'$status") {
+		t.Fatalf("written = %q, want fish's $status", pty.Written())
+	}
+	if result.Status != "completed" || strings.TrimSpace(result.Stdout) != "hello from fish" {
+		t.Errorf("Exec() = status %q, stdout %q, want the command's output", result.Status, result.Stdout)
+	}
+}
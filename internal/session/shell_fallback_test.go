@@ -0,0 +1,36 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+)
+
+func TestInitialize_ShellFallback(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Shell.Path = "/opt/broken/zsh"
+	cfg.PTY.ShellFallbacks = []string{"bash", "sh"}
+
+	var started localpty.PTYOptions
+	pty := newReadyPTY()
+	sess := newReadinessSession(pty, cfg)
+	sess.localPTYFactory = func(opts localpty.PTYOptions) (PTY, string, error) {
+		started = opts
+		return pty, "/bin/sh", nil
+	}
+
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if started.Shell != "/opt/broken/zsh" || !reflect.DeepEqual(started.Fallbacks, []string{"bash", "sh"}) {
+		t.Errorf("PTY started with shell %q, fallbacks %v", started.Shell, started.Fallbacks)
+	}
+	if sess.Shell != "/bin/sh" {
+		t.Errorf("Shell = %q, want the fallback that started", sess.Shell)
+	}
+	if info := sess.GetShellInfo(); info.Type != "sh" || info.Path != "/bin/sh" {
+		t.Errorf("GetShellInfo() = %+v, want the fallback shell", info)
+	}
+}
@@ -0,0 +1,95 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrInvalidStartDir is returned by Initialize when the session's StartDir
+// does not exist or cannot be entered.
+var ErrInvalidStartDir = errors.New("invalid_start_dir")
+
+// startDirMarker tags the line reporting the result of the cd into
+// StartDir. The echoed command shows the variables rather than their
+// values, so it does not match startDirResult.
+const startDirMarker = "__CSM_START_DIR__"
+
+var startDirResult = regexp.MustCompile(startDirMarker + `(\d+):([^\r\n]*)`)
+
+// startDir returns the directory a session created with opts starts in:
+// opts.Cwd, or for local sessions local_session.default_cwd. Container and
+// command sessions start where their container or command puts them.
+func (m *Manager) startDir(opts CreateOptions) string {
+	if opts.Cwd != "" || opts.Container != "" || opts.Mode != "local" && opts.Mode != "" {
+		return opts.Cwd
+	}
+	if m.config == nil {
+		return ""
+	}
+	return m.config.LocalSession.DefaultCwd
+}
+
+// validateStartDir checks the cwd option of a new session.
+func validateStartDir(opts CreateOptions) error {
+	if opts.Cwd == "" {
+		return nil
+	}
+	if opts.Mode == "command" {
+		return fmt.Errorf("cwd is not supported for command mode sessions")
+	}
+	if opts.Container != "" {
+		return fmt.Errorf("cwd cannot be combined with container; the container's working directory is used")
+	}
+	return nil
+}
+
+// quoteStartDir quotes dir for cd, leaving a leading ~ outside the quotes
+// so the shell expands it.
+func quoteStartDir(dir string) string {
+	if dir == "~" {
+		return dir
+	}
+	if rest, ok := strings.CutPrefix(dir, "~/"); ok {
+		return "~/" + shellQuote(rest)
+	}
+	return shellQuote(dir)
+}
+
+// enterStartDir changes the new shell's directory to StartDir and sets Cwd
+// to the directory it landed in. It fails with ErrInvalidStartDir if the cd
+// fails. Caller must hold s.mu.
+func (s *Session) enterStartDir() error {
+	if s.StartDir == "" {
+		return nil
+	}
+	s.pty.WriteString(fmt.Sprintf("cd %s 2>/dev/null; echo \"%s%s:$PWD\"\n",
+		quoteStartDir(s.StartDir), startDirMarker, s.exitStatusVar()))
+	s.clock.Sleep(100 * time.Millisecond)
+	buf := make([]byte, 4096)
+	n, _ := s.readWithTimeout(buf, 500*time.Millisecond)
+
+	m := startDirResult.FindStringSubmatch(string(buf[:n]))
+	if m == nil {
+		// The shell did not answer in time; the cd was still typed.
+		slog.Warn("could not confirm start directory",
+			slog.String("session_id", s.ID),
+			slog.String("dir", s.StartDir),
+		)
+		if strings.HasPrefix(s.StartDir, "/") {
+			s.Cwd = s.StartDir
+		}
+		return nil
+	}
+	if m[1] != "0" {
+		return fmt.Errorf("%w: cannot change to %s: no such directory or permission denied", ErrInvalidStartDir, s.StartDir)
+	}
+	if cwd := strings.TrimSpace(m[2]); strings.HasPrefix(cwd, "/") {
+		s.Cwd = cwd
+		s.noteCaptured("cwd")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+var startDirCd = regexp.MustCompile(`^cd (.+) 2>/dev/null; echo "` + startDirMarker)
+
+// startDirShell answers the readiness probe and the cd into the start
+// directory like a shell in which only the directories in dirs exist; keys
+// are the cd arguments as typed.
+func startDirShell(dirs map[string]string) *fakepty.PTY {
+	return fakepty.New().SetResponder(func(written string) string {
+		if m := startDirCd.FindStringSubmatch(written); m != nil {
+			if dir, ok := dirs[m[1]]; ok {
+				return startDirMarker + "0:" + dir + "\r\n$ "
+			}
+			return startDirMarker + "1:/home/dev\r\n$ "
+		}
+		return answerReadyProbe(written)
+	})
+}
+
+func newStartDirManager(cfg *config.Config, pty *fakepty.PTY) *Manager {
+	return NewManager(cfg,
+		WithManagerClock(fakeclock.New(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC))),
+		WithManagerRandom(fakerand.NewSequential()),
+		WithManagerStore(NewSessionStore(WithFileSystem(fakefs.New()), WithStorePath("/tmp/start-dir.json"))),
+		WithLocalPTYFactory(func(localpty.PTYOptions) (PTY, string, error) {
+			return pty, "/bin/bash", nil
+		}),
+	)
+}
+
+func TestCreate_DefaultCwd(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalSession.DefaultCwd = "/srv/app"
+	pty := startDirShell(map[string]string{"'/srv/app'": "/srv/app"})
+
+	sess, err := newStartDirManager(cfg, pty).Create(CreateOptions{Mode: "local"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sess.Cwd != "/srv/app" {
+		t.Errorf("Cwd = %q, want /srv/app", sess.Cwd)
+	}
+	if !strings.Contains(strings.Join(sess.Captured, ","), "cwd") {
+		t.Errorf("Captured = %v, want cwd", sess.Captured)
+	}
+}
+
+func TestCreate_CwdOverridesDefault(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalSession.DefaultCwd = "/srv/app"
+	pty := startDirShell(map[string]string{"~/'my work'": "/home/dev/my work"})
+
+	sess, err := newStartDirManager(cfg, pty).Create(CreateOptions{Mode: "local", Cwd: "~/my work"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if sess.Cwd != "/home/dev/my work" {
+		t.Errorf("Cwd = %q, want the expanded directory", sess.Cwd)
+	}
+	if strings.Contains(pty.Written(), "/srv/app") {
+		t.Error("default_cwd was used despite cwd")
+	}
+}
+
+func TestCreate_InvalidStartDir(t *testing.T) {
+	pty := startDirShell(nil)
+	mgr := newStartDirManager(config.DefaultConfig(), pty)
+
+	_, err := mgr.Create(CreateOptions{Mode: "local", Cwd: "/nope"})
+	if !errors.Is(err, ErrInvalidStartDir) || !strings.Contains(err.Error(), "invalid_start_dir") {
+		t.Fatalf("Create() error = %v, want invalid_start_dir", err)
+	}
+	if !pty.IsClosed() {
+		t.Error("PTY left open after the failed create")
+	}
+	if len(mgr.List()) != 0 {
+		t.Errorf("sessions = %v, want none", mgr.List())
+	}
+}
+
+func TestCreate_NoStartDir(t *testing.T) {
+	pty := startDirShell(nil)
+	if _, err := newStartDirManager(config.DefaultConfig(), pty).Create(CreateOptions{Mode: "local"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if strings.Contains(pty.Written(), startDirMarker) {
+		t.Errorf("written = %q, want no cd", pty.Written())
+	}
+}
+
+func TestManagerStartDir(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.LocalSession.DefaultCwd = "~/projects"
+	mgr := NewManager(cfg)
+
+	tests := []struct {
+		opts CreateOptions
+		want string
+	}{
+		{CreateOptions{Mode: "local"}, "~/projects"},
+		{CreateOptions{Mode: "local", Cwd: "/tmp"}, "/tmp"},
+		{CreateOptions{Mode: "ssh"}, ""},
+		{CreateOptions{Mode: "ssh", Cwd: "/var/www"}, "/var/www"},
+		{CreateOptions{Mode: "local", Container: "web"}, ""},
+		{CreateOptions{Mode: "command", Command: "bash"}, ""},
+	}
+	for _, tt := range tests {
+		if got := mgr.startDir(tt.opts); got != tt.want {
+			t.Errorf("startDir(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStartDir(t *testing.T) {
+	if err := validateStartDir(CreateOptions{Mode: "command", Command: "bash", Cwd: "/tmp"}); err == nil {
+		t.Error("cwd accepted for a command session")
+	}
+	if err := validateStartDir(CreateOptions{Mode: "local", Container: "web", Cwd: "/app"}); err == nil {
+		t.Error("cwd accepted with a container")
+	}
+	if err := validateStartDir(CreateOptions{Mode: "ssh", Cwd: "/var/www"}); err != nil {
+		t.Errorf("validateStartDir() error = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	localpty "github.com/acolita/claude-shell-mcp/internal/pty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestSession_StartupDrain_Defaults(t *testing.T) {
+	tests := []struct {
+		mode    string
+		drainMs int
+		want    time.Duration
+	}{
+		{"local", 0, defaultLocalStartupDrain},
+		{"ssh", 0, defaultSSHStartupDrain},
+		{"ssh", 2000, 2 * time.Second},
+		{"local", 50, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.mode, tt.drainMs), func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.PTY.StartupDrainMs = tt.drainMs
+			sess := NewSession("sess_drain", tt.mode, WithConfig(cfg))
+			if got := sess.startupDrain(); got != tt.want {
+				t.Errorf("startupDrain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// initWithBanner initializes a local session whose shell prints a banner in
+// ten chunks, each read taking 100ms, then runs one command.
+func initWithBanner(t *testing.T, drainMs int) *ExecResult {
+	t.Helper()
+	pty := newReadyPTY()
+	for i := 1; i <= 10; i++ {
+		pty.AddResponse(fmt.Sprintf("banner line %d\n", i))
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.PTY.StartupDrainMs = drainMs
+	sess := NewSession("sess_banner", "local",
+		WithSessionClock(&steppingClock{Clock: fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)), step: 100 * time.Millisecond}),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(cfg),
+	)
+	sess.localPTYFactory = func(localpty.PTYOptions) (PTY, string, error) {
+		return pty, "/bin/bash", nil
+	}
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+
+	pty.AddResponse(buildCommandOutput("01020304", "hello", 0))
+	result, err := sess.Exec("echo hello", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "completed" || result.Stdout != "hello" {
+		t.Fatalf("result = %q/%q, want completed/hello", result.Status, result.Stdout)
+	}
+	return result
+}
+
+func TestSession_StartupDrain_TooShortLeavesBanner(t *testing.T) {
+	result := initWithBanner(t, 300)
+	if !strings.Contains(result.AsyncOutput, "banner line 10") {
+		t.Errorf("AsyncOutput = %q, want leftover banner from a short drain", result.AsyncOutput)
+	}
+}
+
+func TestSession_StartupDrain_LongerWindowClearsBanner(t *testing.T) {
+	result := initWithBanner(t, 5000)
+	if strings.Contains(result.AsyncOutput, "banner") {
+		t.Errorf("AsyncOutput = %q, want banner drained at startup", result.AsyncOutput)
+	}
+}
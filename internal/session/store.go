@@ -32,6 +32,17 @@ type SessionMetadata struct {
 	KeyPath string         `json:"key_path,omitempty"`
 	Cwd     string         `json:"cwd,omitempty"`
 	Tunnels []TunnelConfig `json:"tunnels,omitempty"`
+	Tags    []string       `json:"tags,omitempty"`
+
+	Server     string `json:"server,omitempty"`
+	Identity   string `json:"identity,omitempty"`
+	ForwardX11 bool   `json:"forward_x11,omitempty"`
+	KeepPagers bool   `json:"keep_pagers,omitempty"`
+	Command    string `json:"command,omitempty"`
+	RawMode    bool   `json:"raw_mode,omitempty"`
+
+	Container        string `json:"container,omitempty"`
+	ContainerRuntime string `json:"container_runtime,omitempty"`
 }
 
 // SessionStore persists session metadata to enable recovery after MCP restart.
@@ -112,6 +123,17 @@ func (s *SessionStore) Save(sess *Session) {
 		KeyPath: sess.KeyPath,
 		Cwd:     sess.Cwd,
 		Tunnels: sess.GetTunnelConfigs(),
+		Tags:    sess.Tags,
+
+		Server:     sess.Server,
+		Identity:   sess.Identity,
+		ForwardX11: sess.ForwardX11,
+		KeepPagers: sess.KeepPagers,
+		Command:    sess.Command,
+		RawMode:    sess.RawMode,
+
+		Container:        sess.Container,
+		ContainerRuntime: sess.ContainerRuntime,
 	}
 
 	s.sessions[sess.ID] = meta
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"strings"
+	"time"
+)
+
+// streamInterval is the least time between two ExecOptions.OnOutput calls,
+// so a chatty command sends a few large chunks rather than one per read.
+const streamInterval = 500 * time.Millisecond
+
+// streamOutput passes the command output read since the last call to
+// s.onOutput. Only complete lines are passed, so a line is never split
+// across chunks and a partly received end marker is never passed on.
+func (s *Session) streamOutput(execCtx *execContext) {
+	if s.onOutput == nil {
+		return
+	}
+	now := s.clock.Now()
+	if !execCtx.streamedAt.IsZero() && now.Sub(execCtx.streamedAt) < streamInterval {
+		return
+	}
+	output := streamableOutput(s.normalizeLineEndings(stripCommandEcho(s.outputBuffer.String(), execCtx.startMarker, execCtx.endMarker)), execCtx)
+	if len(output) <= execCtx.streamed {
+		return
+	}
+	chunk := output[execCtx.streamed:]
+	execCtx.streamed = len(output)
+	execCtx.streamedAt = now
+	s.onOutput(chunk)
+}
+
+// streamableOutput returns the complete lines of command output in output,
+// which has its line endings normalized: those after the start marker, up
+// to the end marker or the last newline.
+func streamableOutput(output string, execCtx *execContext) string {
+	startIdx := findMarkerOnOwnLine(output, execCtx.startMarker)
+	if startIdx == -1 {
+		return ""
+	}
+	body := strings.TrimPrefix(output[startIdx+len(execCtx.startMarker):], "\n")
+	if endIdx := findMarkerOnOwnLine(body, execCtx.endMarker); endIdx != -1 {
+		return body[:endIdx]
+	}
+	return body[:strings.LastIndexByte(body, '\n')+1]
+}
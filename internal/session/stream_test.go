@@ -0,0 +1,101 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/prompt"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+// newStreamSession returns an idle session whose first command prints
+// chunks, one per read, between its markers.
+func newStreamSession(chunks ...string) (*Session, *fakeclock.Clock) {
+	// The command ID the session will generate for its first command.
+	id := NewSession("sess_id", "local", WithSessionRandom(fakerand.NewSequential())).generateCommandID()
+	start, end := startMarkerPrefix+id+markerSuffix, endMarkerPrefix+id+markerSuffix
+
+	pty := fakepty.New().AddResponse(start + "\r\n")
+	for _, chunk := range chunks {
+		pty.AddResponse(chunk)
+	}
+	pty.AddResponse(end + "0\r\n$ ")
+	clock := fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	sess := NewSession("sess_stream", "local",
+		WithPTY(pty),
+		WithSessionClock(clock),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithConfig(config.DefaultConfig()),
+	)
+	sess.State = StateIdle
+	sess.promptDetector = prompt.NewDetector()
+	return sess, clock
+}
+
+func TestExecWithOptions_OnOutput(t *testing.T) {
+	sess, clock := newStreamSession("line 1\r\nline 2 part", "ial\r\nline 3\r\n")
+	var chunks []string
+	opts := ExecOptions{OnOutput: func(chunk string) {
+		chunks = append(chunks, chunk)
+		clock.Advance(time.Second)
+	}}
+
+	result, err := sess.ExecWithOptions("build.sh", 5000, opts)
+	if err != nil {
+		t.Fatalf("ExecWithOptions() error = %v", err)
+	}
+	want := []string{"line 1\n", "line 2 partial\nline 3\n"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	if result.Stdout != "line 1\nline 2 partial\nline 3" {
+		t.Errorf("Stdout = %q, want all of the output", result.Stdout)
+	}
+	if sess.onOutput != nil {
+		t.Error("onOutput still set after the command")
+	}
+}
+
+func TestExecWithOptions_OnOutputThrottled(t *testing.T) {
+	sess, _ := newStreamSession("line 1\r\n", "line 2\r\n", "line 3\r\n")
+	var chunks []string
+	opts := ExecOptions{OnOutput: func(chunk string) { chunks = append(chunks, chunk) }}
+
+	result, err := sess.ExecWithOptions("build.sh", 5000, opts)
+	if err != nil {
+		t.Fatalf("ExecWithOptions() error = %v", err)
+	}
+	// The clock stands still, so only the first line goes out before the
+	// result.
+	if len(chunks) != 1 || chunks[0] != "line 1\n" {
+		t.Errorf("chunks = %q, want only the first line", chunks)
+	}
+	if result.Stdout != "line 1\nline 2\nline 3" {
+		t.Errorf("Stdout = %q", result.Stdout)
+	}
+}
+
+func TestStreamableOutput(t *testing.T) {
+	execCtx := newExecContext("abc", startMarkerPrefix+"abc"+markerSuffix, endMarkerPrefix+"abc"+markerSuffix, "cmd")
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"no start marker", "$ cmd\n", ""},
+		{"partial line", execCtx.startMarker + "\nline 1\nline", "line 1\n"},
+		{"partial end marker", execCtx.startMarker + "\nline 1\n___CMD_E", "line 1\n"},
+		{"end marker", execCtx.startMarker + "\nline 1\n" + execCtx.endMarker + "0\n$ ", "line 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := streamableOutput(tt.output, execCtx); got != tt.want {
+				t.Errorf("streamableOutput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"regexp"
+	"strings"
+)
+
+// sudoNoisePatterns match the lines sudo itself prints around a command:
+// its password prompt and the lecture shown the first time a user runs
+// sudo. They are matched with terminal control sequences removed.
+var sudoNoisePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\[sudo\] password for [^:]*:\s*$`),
+	regexp.MustCompile(`^We trust you have received the usual lecture from the local System$`),
+	regexp.MustCompile(`^Administrator\. It usually boils down to these three things:$`),
+	regexp.MustCompile(`^#1\) Respect the privacy of others\.$`),
+	regexp.MustCompile(`^#2\) Think before you type\.$`),
+	regexp.MustCompile(`^#3\) With great power comes great responsibility\.$`),
+	regexp.MustCompile(`^For security reasons, the password you type will not be visible\.$`),
+}
+
+// stripSudoOutput removes sudo's prompt and lecture from result's output,
+// unless config.Output.StripSudoPrompts is off.
+func (s *Session) stripSudoOutput(result *ExecResult) {
+	if result == nil || (s.config != nil && !s.config.Output.StripSudoPrompts) {
+		return
+	}
+	result.Stdout = stripSudoNoise(result.Stdout)
+	result.AsyncOutput = stripSudoNoise(result.AsyncOutput)
+}
+
+// stripSudoNoise drops the lines of output matching sudoNoisePatterns, and
+// blank lines that only separated them (the lecture is set off by blank
+// lines). Output without them is returned unchanged.
+func stripSudoNoise(output string) string {
+	if !strings.Contains(output, "[sudo] ") && !strings.Contains(output, "usual lecture") {
+		return output
+	}
+	lines := strings.Split(output, "\n")
+	noise := make([]bool, len(lines))
+	found := false
+	for i, line := range lines {
+		plain := strings.TrimSpace(terminalControlPattern.ReplaceAllString(line, ""))
+		for _, re := range sudoNoisePatterns {
+			if re.MatchString(plain) {
+				noise[i], found = true, true
+				break
+			}
+		}
+	}
+	if !found {
+		return output
+	}
+
+	// A blank line goes when the lines on both sides of its run of blanks
+	// are noise or the edge of the output.
+	nonBlankNoise := func(i, step int) bool {
+		for ; i >= 0 && i < len(lines); i += step {
+			if strings.TrimSpace(lines[i]) != "" {
+				return noise[i]
+			}
+		}
+		return true
+	}
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if noise[i] {
+			continue
+		}
+		if strings.TrimSpace(line) == "" && nonBlankNoise(i-1, -1) && nonBlankNoise(i+1, 1) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[0]) == "" {
+		kept = kept[1:]
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+	return strings.Join(kept, "\n")
+}
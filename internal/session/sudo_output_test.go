@@ -0,0 +1,109 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+const sudoLecture = `
+We trust you have received the usual lecture from the local System
+Administrator. It usually boils down to these three things:
+
+    #1) Respect the privacy of others.
+    #2) Think before you type.
+    #3) With great power comes great responsibility.
+
+For security reasons, the password you type will not be visible.
+
+`
+
+func TestStripSudoNoise(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"prompt", "[sudo] password for deploy: \nReading package lists...", "Reading package lists..."},
+		{"lecture and prompt", sudoLecture + "[sudo] password for deploy: \nHit:1 http://archive.ubuntu.com jammy InRelease\nDone", "Hit:1 http://archive.ubuntu.com jammy InRelease\nDone"},
+		{"colored prompt", "\x1b[1m[sudo] password for deploy: \x1b[0m\nok", "ok"},
+		{"keeps failed attempts", "[sudo] password for deploy: \nSorry, try again.\n[sudo] password for deploy: \nok", "Sorry, try again.\nok"},
+		{"keeps blank lines in output", "[sudo] password for deploy: \nline 1\n\nline 2", "line 1\n\nline 2"},
+		{"keeps indentation", "[sudo] password for deploy: \n  indented", "  indented"},
+		{"no sudo", "  plain output\n\nwith blanks\n", "  plain output\n\nwith blanks\n"},
+		{"mentions sudo", "[sudo] is configured in /etc/sudoers", "[sudo] is configured in /etc/sudoers"},
+		{"only noise", "[sudo] password for deploy: ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripSudoNoise(tt.output); got != tt.want {
+				t.Errorf("stripSudoNoise() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripSudoOutput_Disabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Output.StripSudoPrompts = false
+	sess := NewSession("sess_sudo", "local", WithConfig(cfg))
+
+	result := &ExecResult{Stdout: "[sudo] password for deploy: \nok"}
+	sess.stripSudoOutput(result)
+	if result.Stdout != "[sudo] password for deploy: \nok" {
+		t.Errorf("Stdout = %q, want it unchanged", result.Stdout)
+	}
+}
+
+// TestExec_SudoLectureOnFirstUse runs sudo the first time in a session: the
+// lecture and prompt come before the password is asked for, the command's
+// output after it is given.
+func TestExec_SudoLectureOnFirstUse(t *testing.T) {
+	pty := fakepty.New()
+	sess := NewSession("sess_sudo", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	startMarker := startMarkerPrefix + "01020304" + markerSuffix
+	endMarker := endMarkerPrefix + "01020304" + markerSuffix
+
+	lecture := strings.ReplaceAll(sudoLecture, "\n", "\r\n")
+	pty.AddResponse(fmt.Sprintf("%s\r\n%s[sudo] password for deploy: ", startMarker, lecture))
+	for i := 0; i < 20; i++ {
+		pty.AddResponse("")
+	}
+
+	result, err := sess.Exec("sudo apt update", 5000)
+	if err != nil {
+		t.Fatalf("Exec error: %v", err)
+	}
+	if result.Status != "awaiting_input" || result.PromptType != "password" {
+		t.Fatalf("Status = %q, PromptType = %q, want a password prompt", result.Status, result.PromptType)
+	}
+	if result.Stdout != "" {
+		t.Errorf("Stdout = %q, want the lecture and prompt stripped", result.Stdout)
+	}
+
+	pty.AddResponse(fmt.Sprintf("\r\nHit:1 http://archive.ubuntu.com jammy InRelease\r\nReading package lists... Done\r\n%s0\r\n$ ", endMarker))
+	result, err = sess.ProvideInput("secret")
+	if err != nil {
+		t.Fatalf("ProvideInput error: %v", err)
+	}
+	if result.Status != "completed" {
+		t.Fatalf("Status = %q, want completed", result.Status)
+	}
+	if result.Stdout != "Hit:1 http://archive.ubuntu.com jammy InRelease\nReading package lists... Done" {
+		t.Errorf("Stdout = %q, want only apt's output", result.Stdout)
+	}
+}
@@ -0,0 +1,250 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// uidMarkerPrefix starts the line the uid probe prints: the probe ID and
+// the uid of the shell answering, as ___UID_<id>_<uid>___. Like the
+// readiness probe it is printed with printf, so the echoed command line
+// never matches.
+const uidMarkerPrefix = "___UID_"
+
+// SudoShellMethods are the commands SudoShell accepts to start a root
+// shell.
+var SudoShellMethods = []string{"sudo -i", "sudo su -"}
+
+// ErrSudoPassword is returned by SudoShell when sudo asks for a password:
+// the caller has to validate the credentials (e.g. with sudo -v) first.
+var ErrSudoPassword = errors.New("sudo asked for a password")
+
+// unprivilegedState is what a session had before SudoShell, restored by
+// SudoExit.
+type unprivilegedState struct {
+	uid     string
+	shell   string
+	cwd     string
+	envVars map[string]string
+}
+
+// SudoShell starts a root login shell in the session's PTY with method,
+// one of SudoShellMethods. The session then reports the root shell's cwd
+// and environment, and its commands run as root with the usual markers,
+// until SudoExit. sudo must not need a password by then: if it asks for
+// one, the prompt is interrupted and ErrSudoPassword returned.
+func (s *Session) SudoShell(method string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkCanElevate(); err != nil {
+		return err
+	}
+	if !isSudoShellMethod(method) {
+		return fmt.Errorf("unsupported method %q: use %s", method, strings.Join(SudoShellMethods, " or "))
+	}
+
+	uid, err := s.probeUID()
+	if err != nil {
+		return fmt.Errorf("check user: %w", err)
+	}
+	if uid == "0" {
+		return fmt.Errorf("session is already running as root")
+	}
+	saved := &unprivilegedState{
+		uid:     uid,
+		shell:   s.Shell,
+		cwd:     s.Cwd,
+		envVars: maps.Clone(s.EnvVars),
+	}
+
+	slog.Info("starting root shell",
+		slog.String("session_id", s.ID),
+		slog.String("method", method),
+	)
+	if _, err := s.pty.WriteString(method + "\n"); err != nil {
+		return fmt.Errorf("write %s: %w", method, err)
+	}
+	s.LastUsed = s.clock.Now()
+
+	// Look for a password prompt before sending the probe, which sudo
+	// would otherwise read as the password.
+	s.clock.Sleep(100 * time.Millisecond)
+	buf := make([]byte, 4096)
+	n, _ := s.readWithTimeout(buf, 300*time.Millisecond)
+	if s.isPasswordPrompt(string(buf[:n])) {
+		s.abortSudoShell()
+		return ErrSudoPassword
+	}
+
+	uid, err = s.probeUID()
+	if err != nil {
+		s.abortSudoShell()
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if uid != "0" {
+		s.abortSudoShell()
+		return fmt.Errorf("%s: shell runs as uid %s, not root", method, uid)
+	}
+
+	s.Privileged = true
+	s.unprivileged = saved
+	if shell := s.probeShell(); shell != "" {
+		s.Shell = shell
+	}
+	s.setupShell()
+	s.updateCwd()
+	s.captureEnvLocked()
+	return nil
+}
+
+// SudoExit leaves the root shell started by SudoShell and restores the
+// shell, cwd and environment the session had before it.
+func (s *Session) SudoExit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkSudoShellPreconditions(); err != nil {
+		return err
+	}
+	if !s.Privileged {
+		return fmt.Errorf("session is not in a root shell")
+	}
+
+	slog.Info("leaving root shell", slog.String("session_id", s.ID))
+	if _, err := s.pty.WriteString("exit\n"); err != nil {
+		return fmt.Errorf("write exit: %w", err)
+	}
+	s.LastUsed = s.clock.Now()
+	s.clock.Sleep(100 * time.Millisecond)
+
+	uid, err := s.probeUID()
+	if err != nil {
+		return fmt.Errorf("exit: %w", err)
+	}
+	if uid != s.unprivileged.uid {
+		// e.g. a shell started inside the root shell answered the exit.
+		return fmt.Errorf("exit: shell still runs as uid %s, not %s; run exit until the root shell ends", uid, s.unprivileged.uid)
+	}
+	s.dropPrivileged()
+	return nil
+}
+
+// dropPrivileged restores the state saved by SudoShell, once the root shell
+// is gone. Caller must hold s.mu.
+func (s *Session) dropPrivileged() {
+	if !s.Privileged {
+		return
+	}
+	s.Shell = s.unprivileged.shell
+	s.Cwd = s.unprivileged.cwd
+	s.EnvVars = s.unprivileged.envVars
+	s.Privileged = false
+	s.unprivileged = nil
+}
+
+// CheckSudoShell returns the error SudoShell would fail with before running
+// anything, so callers can check the session before validating sudo
+// credentials in it.
+func (s *Session) CheckSudoShell() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.checkCanElevate()
+}
+
+func (s *Session) checkCanElevate() error {
+	if err := s.checkSudoShellPreconditions(); err != nil {
+		return err
+	}
+	if s.Privileged {
+		return fmt.Errorf("session is already in a root shell")
+	}
+	return nil
+}
+
+// checkSudoShellPreconditions checks that the session can switch shells:
+// initialized, idle and wrapping commands in markers in its own shell.
+func (s *Session) checkSudoShellPreconditions() error {
+	if err := s.validateExecPreconditions(); err != nil {
+		return err
+	}
+	if s.State != StateIdle {
+		return fmt.Errorf("session is busy (state: %s)", s.State)
+	}
+	if s.RawMode {
+		return fmt.Errorf("not supported in raw mode sessions")
+	}
+	if s.Container != "" {
+		return fmt.Errorf("not supported in container sessions")
+	}
+	return nil
+}
+
+func isSudoShellMethod(method string) bool {
+	for _, m := range SudoShellMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Session) isPasswordPrompt(output string) bool {
+	if s.promptDetector == nil {
+		return false
+	}
+	det := s.promptDetector.Detect(output)
+	return det != nil && det.IsPasswordPrompt()
+}
+
+// abortSudoShell interrupts a sudo that did not start a root shell, e.g.
+// one waiting for a password, and checks the shell on the next command in
+// case one started after all.
+func (s *Session) abortSudoShell() {
+	s.pty.Interrupt()
+	s.clock.Sleep(100 * time.Millisecond)
+	buf := make([]byte, 4096)
+	s.readWithTimeout(buf, 200*time.Millisecond)
+	s.shellCheckPending = true
+}
+
+// probeUID returns the uid of the shell answering in the PTY, waiting for
+// it as long as for a new shell to become ready.
+func (s *Session) probeUID() (string, error) {
+	id := s.generateCommandID()
+	probe := fmt.Sprintf("printf '%s%%s_%%s%s\\n' %s \"$(id -u)\"\n", uidMarkerPrefix, markerSuffix, id)
+	if _, err := s.pty.WriteString(probe); err != nil {
+		return "", fmt.Errorf("write uid probe: %w", err)
+	}
+	answer := regexp.MustCompile(regexp.QuoteMeta(uidMarkerPrefix+id+"_") + `(\d+)` + regexp.QuoteMeta(markerSuffix))
+
+	timeout := s.readyTimeout()
+	var output strings.Builder
+	buf := make([]byte, s.readBufferSize())
+	for waited := time.Duration(0); waited < timeout; {
+		s.pty.SetReadDeadline(s.clock.Now().Add(readyPollInterval))
+		n, err := s.pty.Read(buf)
+		if n > 0 {
+			output.Write(buf[:n])
+			if m := answer.FindStringSubmatch(output.String()); m != nil {
+				s.drainReadyPrompt()
+				return m[1], nil
+			}
+			if s.isPasswordPrompt(output.String()) {
+				return "", ErrSudoPassword
+			}
+			continue
+		}
+		if isConnectionBroken(err) {
+			return "", fmt.Errorf("shell exited: %w", err)
+		}
+		s.clock.Sleep(readyPollInterval)
+		waited += readyPollInterval
+	}
+	return "", fmt.Errorf("shell did not respond within %v", timeout)
+}
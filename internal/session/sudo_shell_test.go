@@ -0,0 +1,238 @@
+package session
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+)
+
+var uidProbePattern = regexp.MustCompile(`^printf '` + uidMarkerPrefix + `%s_%s` + markerSuffix + `\\n' ([0-9a-f]+) "\$\(id -u\)"\n$`)
+
+// fakeSudoShell answers like a user's bash in which sudo -i starts a root
+// bash, or asks for a password if askPassword is set. nested counts root
+// shells started inside the root shell, which exit leaves first. With
+// notRoot, the method starts a shell that is not root.
+type fakeSudoShell struct {
+	askPassword bool
+	notRoot     bool
+	root        bool
+	nested      int
+}
+
+func (f *fakeSudoShell) respond(written string) string {
+	if m := uidProbePattern.FindStringSubmatch(written); m != nil {
+		uid := "1000"
+		if f.root {
+			uid = "0"
+		}
+		return strings.TrimSuffix(written, "\n") + "\r\n" + uidMarkerPrefix + m[1] + "_" + uid + markerSuffix + "\r\n$ "
+	}
+	switch written {
+	case "sudo -i\n", "sudo su -\n":
+		if f.askPassword {
+			return "[sudo] password for user: "
+		}
+		f.root = !f.notRoot
+		return ""
+	case "exit\n":
+		if f.nested > 0 {
+			f.nested--
+			return "logout\r\n# "
+		}
+		f.root = false
+		return "logout\r\n$ "
+	case "echo \"$0\"\n":
+		if f.root {
+			return "echo \"$0\"\r\n-bash\r\n# "
+		}
+		return "echo \"$0\"\r\nbash\r\n$ "
+	case "pwd\n":
+		if f.root {
+			return "pwd\r\n/root\r\n# "
+		}
+		return "pwd\r\n/home/user\r\n$ "
+	case "env\n":
+		if f.root {
+			return "env\r\nHOME=/root\r\nUSER=root\r\n# "
+		}
+		return "env\r\nHOME=/home/user\r\nUSER=user\r\n$ "
+	}
+	if m := pipelineStartPattern.FindStringSubmatch(written); m != nil {
+		return startMarkerPrefix + m[1] + markerSuffix + "\r\n" + endMarkerPrefix + m[1] + markerSuffix + "0\r\n$ "
+	}
+	return ""
+}
+
+func newSudoShellSession(shell *fakeSudoShell) (*Session, *fakepty.PTY) {
+	pty := fakepty.New().SetResponder(shell.respond)
+	sess := newShellChangeSession(pty)
+	sess.Cwd = "/home/user"
+	sess.EnvVars = map[string]string{"HOME": "/home/user", "USER": "user"}
+	return sess, pty
+}
+
+func TestSudoShell_ElevatesAndExits(t *testing.T) {
+	shell := &fakeSudoShell{}
+	sess, pty := newSudoShellSession(shell)
+
+	if err := sess.SudoShell("sudo -i"); err != nil {
+		t.Fatalf("SudoShell() error = %v", err)
+	}
+	if !sess.Privileged || !sess.Status().Privileged {
+		t.Error("session not marked privileged")
+	}
+	if sess.Cwd != "/root" || sess.EnvVars["USER"] != "root" || sess.Shell != "bash" {
+		t.Errorf("Cwd = %q, USER = %q, Shell = %q, want the root shell's", sess.Cwd, sess.EnvVars["USER"], sess.Shell)
+	}
+
+	// Commands keep their markers in the root shell.
+	result, err := sess.Exec("whoami", 5000)
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if result.Status != "completed" || result.ExitCode == nil || *result.ExitCode != 0 {
+		t.Errorf("Exec in the root shell = %+v, want completed", result)
+	}
+	if result.ShellChanged != nil {
+		t.Errorf("ShellChanged = %+v after SudoShell", result.ShellChanged)
+	}
+
+	if err := sess.SudoExit(); err != nil {
+		t.Fatalf("SudoExit() error = %v", err)
+	}
+	if sess.Privileged || sess.Status().Privileged {
+		t.Error("session still marked privileged")
+	}
+	if sess.Cwd != "/home/user" || sess.EnvVars["USER"] != "user" || sess.Shell != "/bin/bash" {
+		t.Errorf("Cwd = %q, USER = %q, Shell = %q, want the user's restored", sess.Cwd, sess.EnvVars["USER"], sess.Shell)
+	}
+	if strings.Count(pty.Written(), "exit\n") != 1 {
+		t.Errorf("written = %q, want one exit", pty.Written())
+	}
+}
+
+func TestSudoShell_SuMethod(t *testing.T) {
+	sess, pty := newSudoShellSession(&fakeSudoShell{})
+
+	if err := sess.SudoShell("sudo su -"); err != nil {
+		t.Fatalf("SudoShell() error = %v", err)
+	}
+	if !strings.Contains(pty.Written(), "sudo su -\n") || !sess.Privileged {
+		t.Errorf("written = %q, Privileged = %v", pty.Written(), sess.Privileged)
+	}
+}
+
+func TestSudoShell_PasswordPrompt(t *testing.T) {
+	sess, pty := newSudoShellSession(&fakeSudoShell{askPassword: true})
+
+	err := sess.SudoShell("sudo -i")
+	if !errors.Is(err, ErrSudoPassword) {
+		t.Fatalf("SudoShell() error = %v, want ErrSudoPassword", err)
+	}
+	if !pty.WasInterrupted() {
+		t.Error("password prompt was not interrupted")
+	}
+	if strings.Count(pty.Written(), uidMarkerPrefix) != 1 {
+		t.Errorf("written = %q, want no probe after sudo (it would be read as the password)", pty.Written())
+	}
+	if sess.Privileged || sess.Cwd != "/home/user" {
+		t.Errorf("Privileged = %v, Cwd = %q after a failed elevation", sess.Privileged, sess.Cwd)
+	}
+}
+
+func TestSudoShell_NotRoot(t *testing.T) {
+	sess, pty := newSudoShellSession(&fakeSudoShell{notRoot: true})
+
+	err := sess.SudoShell("sudo -i")
+	if err == nil || !strings.Contains(err.Error(), "not root") {
+		t.Fatalf("SudoShell() error = %v, want not root", err)
+	}
+	if !pty.WasInterrupted() || !sess.shellCheckPending {
+		t.Errorf("interrupted = %v, shellCheckPending = %v, want the shell checked", pty.WasInterrupted(), sess.shellCheckPending)
+	}
+	if sess.Privileged {
+		t.Error("session marked privileged")
+	}
+}
+
+func TestSudoShell_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		setup  func(*Session, *fakeSudoShell)
+		want   string
+	}{
+		{"unknown method", "sudo bash", func(*Session, *fakeSudoShell) {}, "unsupported method"},
+		{"busy", "sudo -i", func(s *Session, _ *fakeSudoShell) { s.State = StateAwaitingInput }, "busy"},
+		{"raw mode", "sudo -i", func(s *Session, _ *fakeSudoShell) { s.RawMode = true }, "raw mode"},
+		{"container", "sudo -i", func(s *Session, _ *fakeSudoShell) { s.Container = "web" }, "container"},
+		{"already root", "sudo -i", func(_ *Session, f *fakeSudoShell) { f.root = true }, "already running as root"},
+		{"already privileged", "sudo -i", func(s *Session, _ *fakeSudoShell) { s.Privileged = true }, "already in a root shell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shell := &fakeSudoShell{}
+			sess, pty := newSudoShellSession(shell)
+			tt.setup(sess, shell)
+			if err := sess.SudoShell(tt.method); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("SudoShell() error = %v, want %q", err, tt.want)
+			}
+			if strings.Contains(pty.Written(), "sudo") {
+				t.Errorf("written = %q, want sudo not run", pty.Written())
+			}
+		})
+	}
+}
+
+func TestSudoExit_NotPrivileged(t *testing.T) {
+	sess, pty := newSudoShellSession(&fakeSudoShell{})
+	if err := sess.SudoExit(); err == nil || !strings.Contains(err.Error(), "not in a root shell") {
+		t.Errorf("SudoExit() error = %v", err)
+	}
+	if pty.Written() != "" {
+		t.Errorf("written = %q", pty.Written())
+	}
+}
+
+func TestSudoExit_StillRoot(t *testing.T) {
+	shell := &fakeSudoShell{}
+	sess, _ := newSudoShellSession(shell)
+	if err := sess.SudoShell("sudo -i"); err != nil {
+		t.Fatalf("SudoShell() error = %v", err)
+	}
+	shell.nested = 1
+	if err := sess.SudoExit(); err == nil || !strings.Contains(err.Error(), "still runs as uid 0") {
+		t.Errorf("SudoExit() error = %v", err)
+	}
+	if !sess.Privileged {
+		t.Error("session no longer privileged while still in a root shell")
+	}
+}
+
+func TestDropPrivileged_OnReconnect(t *testing.T) {
+	sess, _ := newSudoShellSession(&fakeSudoShell{})
+	if err := sess.SudoShell("sudo -i"); err != nil {
+		t.Fatalf("SudoShell() error = %v", err)
+	}
+	sess.dropPrivileged()
+	if sess.Privileged || sess.unprivileged != nil || sess.Cwd != "/home/user" {
+		t.Errorf("Privileged = %v, Cwd = %q after dropPrivileged", sess.Privileged, sess.Cwd)
+	}
+}
+
+func TestCheckSudoShell(t *testing.T) {
+	sess, pty := newSudoShellSession(&fakeSudoShell{})
+	if err := sess.CheckSudoShell(); err != nil {
+		t.Errorf("CheckSudoShell() = %v for an idle session", err)
+	}
+	sess.Privileged = true
+	if err := sess.CheckSudoShell(); err == nil || !strings.Contains(err.Error(), "already in a root shell") {
+		t.Errorf("CheckSudoShell() = %v, want already in a root shell", err)
+	}
+	if pty.Written() != "" {
+		t.Errorf("CheckSudoShell wrote %q", pty.Written())
+	}
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+)
+
+// systemInfoSeparator splits the uname/shell probe from the release file.
+const systemInfoSeparator = "___SYSINFO_RELEASE___"
+
+// systemInfoProbe gathers everything in one round-trip. /etc/os-release
+// covers Linux distros; sw_vers covers macOS.
+const systemInfoProbe = `printf '%s\n' "$(uname -s)" "$(uname -m)" "$(uname -r)" "${BASH_VERSION:-${ZSH_VERSION:-}}"; ` +
+	`echo ` + systemInfoSeparator + `; cat /etc/os-release 2>/dev/null || sw_vers 2>/dev/null`
+
+// SystemInfo describes the host a session runs on.
+type SystemInfo struct {
+	OS         string    `json:"os"`                    // lowercased uname -s, e.g. "linux", "darwin"
+	Distro     string    `json:"distro,omitempty"`      // os-release ID, e.g. "ubuntu", "rhel", "macos"
+	Family     string    `json:"family,omitempty"`      // first os-release ID_LIKE entry, e.g. "debian"
+	Version    string    `json:"version,omitempty"`     // distro version, e.g. "22.04"
+	PrettyName string    `json:"pretty_name,omitempty"` // human-readable distro name
+	Arch       string    `json:"arch"`                  // uname -m, e.g. "x86_64", "aarch64"
+	Kernel     string    `json:"kernel"`                // uname -r
+	Shell      ShellInfo `json:"shell"`
+}
+
+// SystemInfo returns the session host's OS, distro, arch, kernel and shell.
+// The probe runs once per session; refresh forces it to run again.
+func (s *Session) SystemInfo(refresh bool, timeoutMs int) (*SystemInfo, bool, error) {
+	s.mu.Lock()
+	cached := s.systemInfo
+	s.mu.Unlock()
+	if cached != nil && !refresh {
+		return cached, true, nil
+	}
+
+	result, err := s.Exec(systemInfoProbe, timeoutMs)
+	if err != nil {
+		return nil, false, fmt.Errorf("probe system info: %w", err)
+	}
+	if result.Status != "completed" {
+		return nil, false, fmt.Errorf("probe system info: command %s", result.Status)
+	}
+
+	info, shellVersion, err := parseSystemInfo(result.Stdout)
+	if err != nil {
+		return nil, false, err
+	}
+	info.Shell = s.GetShellInfo()
+	info.Shell.Version = shellVersion
+
+	s.mu.Lock()
+	s.systemInfo = info
+	s.mu.Unlock()
+	return info, false, nil
+}
+
+// parseSystemInfo parses the output of systemInfoProbe. The shell version
+// is returned separately; the shell type comes from GetShellInfo.
+func parseSystemInfo(output string) (*SystemInfo, string, error) {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	head, release, _ := strings.Cut(output, systemInfoSeparator)
+
+	lines := strings.Split(strings.TrimRight(head, "\n"), "\n")
+	if len(lines) < 3 || strings.TrimSpace(lines[0]) == "" {
+		return nil, "", fmt.Errorf("probe system info: unexpected output %q", output)
+	}
+
+	info := &SystemInfo{
+		OS:     strings.ToLower(strings.TrimSpace(lines[0])),
+		Arch:   strings.TrimSpace(lines[1]),
+		Kernel: strings.TrimSpace(lines[2]),
+	}
+	var shellVersion string
+	if len(lines) > 3 {
+		shellVersion = strings.TrimSpace(lines[3])
+	}
+
+	fields := parseReleaseFields(release)
+	switch {
+	case fields["ID"] != "":
+		info.Distro = fields["ID"]
+		info.Version = fields["VERSION_ID"]
+		info.PrettyName = fields["PRETTY_NAME"]
+		if like := strings.Fields(fields["ID_LIKE"]); len(like) > 0 {
+			info.Family = like[0]
+		} else {
+			info.Family = fields["ID"]
+		}
+	case fields["ProductVersion"] != "":
+		info.Distro = "macos"
+		info.Family = "macos"
+		info.Version = fields["ProductVersion"]
+		info.PrettyName = strings.TrimSpace(fields["ProductName"] + " " + fields["ProductVersion"])
+	}
+	return info, shellVersion, nil
+}
+
+// parseReleaseFields reads os-release (KEY="value") or sw_vers (Key:\tvalue)
+// output into a map.
+func parseReleaseFields(release string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(release, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return fields
+}
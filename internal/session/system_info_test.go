@@ -0,0 +1,122 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakepty"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+const ubuntuProbeOutput = "Linux\nx86_64\n5.15.0-91-generic\n5.1.16(1)-release\n" + systemInfoSeparator + "\n" +
+	`PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+ID=ubuntu
+ID_LIKE=debian
+`
+
+func TestParseSystemInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		output      string
+		want        SystemInfo
+		wantVersion string
+	}{
+		{
+			name:        "ubuntu",
+			output:      ubuntuProbeOutput,
+			want:        SystemInfo{OS: "linux", Distro: "ubuntu", Family: "debian", Version: "22.04", PrettyName: "Ubuntu 22.04.3 LTS", Arch: "x86_64", Kernel: "5.15.0-91-generic"},
+			wantVersion: "5.1.16(1)-release",
+		},
+		{
+			name: "rocky",
+			output: "Linux\naarch64\n5.14.0\n\n" + systemInfoSeparator + "\n" +
+				"ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n",
+			want: SystemInfo{OS: "linux", Distro: "rocky", Family: "rhel", Version: "9.3", Arch: "aarch64", Kernel: "5.14.0"},
+		},
+		{
+			name: "alpine without ID_LIKE",
+			output: "Linux\nx86_64\n6.1.0\n\n" + systemInfoSeparator + "\n" +
+				"ID=alpine\nVERSION_ID=3.19.0\n",
+			want: SystemInfo{OS: "linux", Distro: "alpine", Family: "alpine", Version: "3.19.0", Arch: "x86_64", Kernel: "6.1.0"},
+		},
+		{
+			name: "macos",
+			output: "Darwin\narm64\n23.1.0\n5.9\n" + systemInfoSeparator + "\n" +
+				"ProductName:\tmacOS\nProductVersion:\t14.1\nBuildVersion:\t23B74\n",
+			want:        SystemInfo{OS: "darwin", Distro: "macos", Family: "macos", Version: "14.1", PrettyName: "macOS 14.1", Arch: "arm64", Kernel: "23.1.0"},
+			wantVersion: "5.9",
+		},
+		{
+			name:   "no release file",
+			output: "FreeBSD\namd64\n14.0-RELEASE\n\n" + systemInfoSeparator + "\n",
+			want:   SystemInfo{OS: "freebsd", Arch: "amd64", Kernel: "14.0-RELEASE"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, shellVersion, err := parseSystemInfo(tt.output)
+			if err != nil {
+				t.Fatalf("parseSystemInfo error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("info = %+v\nwant   %+v", *got, tt.want)
+			}
+			if shellVersion != tt.wantVersion {
+				t.Errorf("shell version = %q, want %q", shellVersion, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestParseSystemInfo_UnexpectedOutput(t *testing.T) {
+	if _, _, err := parseSystemInfo("sh: uname: not found"); err == nil {
+		t.Error("expected error for unexpected output")
+	}
+}
+
+func TestSession_SystemInfo_Cached(t *testing.T) {
+	pty := fakepty.New()
+	sess := NewSession("sess_sysinfo", "local",
+		WithPTY(pty),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithSessionRandom(fakerand.New([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})),
+		WithConfig(config.DefaultConfig()),
+	)
+	if err := sess.Initialize(); err != nil {
+		t.Fatalf("Initialize error: %v", err)
+	}
+	sess.Shell = "/bin/bash"
+
+	pty.AddResponse(buildCommandOutput("01020304", strings.TrimRight(ubuntuProbeOutput, "\n"), 0))
+
+	info, cached, err := sess.SystemInfo(false, 5000)
+	if err != nil {
+		t.Fatalf("SystemInfo error: %v", err)
+	}
+	if cached {
+		t.Error("first call reported cached")
+	}
+	if info.Distro != "ubuntu" || info.Arch != "x86_64" {
+		t.Errorf("info = %+v", info)
+	}
+	if info.Shell.Type != "bash" || info.Shell.Version != "5.1.16(1)-release" {
+		t.Errorf("shell = %+v, want bash 5.1.16(1)-release", info.Shell)
+	}
+
+	written := len(pty.Written())
+	again, cached, err := sess.SystemInfo(false, 5000)
+	if err != nil {
+		t.Fatalf("SystemInfo (cached) error: %v", err)
+	}
+	if !cached || again != info {
+		t.Error("second call did not return the cached result")
+	}
+	if len(pty.Written()) != written {
+		t.Error("cached call ran the probe again")
+	}
+}
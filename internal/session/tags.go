@@ -0,0 +1,33 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// tagPattern restricts tags to simple identifiers such as "web", "prod" or
+// "region:eu-west-1".
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// NormalizeTags validates tags and returns them sorted without duplicates.
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use letters, digits and _ . : -", tag)
+		}
+		out = append(out, tag)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+// HasTag reports whether the session was created with tag. Tags are fixed
+// at creation, so this needs no lock.
+func (s *Session) HasTag(tag string) bool {
+	return slices.Contains(s.Tags, tag)
+}
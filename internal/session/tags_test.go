@@ -0,0 +1,81 @@
+package session
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := NormalizeTags([]string{"web", "region:eu-west-1", "prod", "web"})
+	if err != nil {
+		t.Fatalf("NormalizeTags() error: %v", err)
+	}
+	want := []string{"prod", "region:eu-west-1", "web"}
+	if !slices.Equal(got, want) {
+		t.Errorf("NormalizeTags() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"", "has space", "semi;colon"} {
+		if _, err := NormalizeTags([]string{bad}); err == nil {
+			t.Errorf("NormalizeTags(%q): expected error", bad)
+		}
+	}
+}
+
+func TestSession_HasTag(t *testing.T) {
+	sess := &Session{Tags: []string{"db", "prod"}}
+	if !sess.HasTag("prod") {
+		t.Error("HasTag(prod) = false, want true")
+	}
+	if sess.HasTag("web") {
+		t.Error("HasTag(web) = true, want false")
+	}
+}
+
+func TestSessionStore_PersistsTags(t *testing.T) {
+	fs := fakefs.New()
+	path := "/tmp/sessions.json"
+	store := NewSessionStore(WithFileSystem(fs), WithStorePath(path))
+	store.Save(&Session{ID: "sess_1", Mode: "ssh", Tags: []string{"web"}})
+
+	reloaded := NewSessionStore(WithFileSystem(fs), WithStorePath(path))
+	meta, ok := reloaded.Get("sess_1")
+	if !ok {
+		t.Fatal("expected to find session")
+	}
+	if !slices.Equal(meta.Tags, []string{"web"}) {
+		t.Errorf("Tags = %v, want [web]", meta.Tags)
+	}
+}
+
+func TestManager_Create_Tags(t *testing.T) {
+	cfg := config.DefaultConfig()
+	fs := fakefs.New()
+	mgr := NewManager(cfg,
+		WithManagerClock(fakeclock.New(time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC))),
+		WithManagerRandom(fakerand.NewSequential()),
+		WithManagerStore(NewSessionStore(WithFileSystem(fs), WithStorePath("/tmp/tags-test.json"))),
+		WithLocalPTYFactory(fakePTYFactory),
+	)
+
+	sess, err := mgr.Create(CreateOptions{Mode: "local", Tags: []string{"web", "prod"}})
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	defer mgr.Close(sess.ID)
+
+	infos := mgr.ListDetailed()
+	if len(infos) != 1 || !slices.Equal(infos[0].Tags, []string{"prod", "web"}) {
+		t.Errorf("ListDetailed() = %+v, want tags [prod web]", infos)
+	}
+
+	if _, err := mgr.Create(CreateOptions{Mode: "local", Tags: []string{"bad tag"}}); err == nil {
+		t.Error("expected error for invalid tag")
+	}
+}
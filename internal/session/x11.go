@@ -0,0 +1,78 @@
+package session
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+)
+
+// x11CookieSize is the length of the fake MIT-MAGIC-COOKIE-1 sent to the
+// server.
+const x11CookieSize = 16
+
+// forwardX11Enabled reports whether the session asked for X11 forwarding
+// or its server has forward_x11 set.
+func (s *Session) forwardX11Enabled() bool {
+	if s.ForwardX11 {
+		return true
+	}
+	if srv := s.serverConfig(); srv != nil {
+		return srv.ForwardX11
+	}
+	return false
+}
+
+// x11Options prepares forwarding to the local X server named by DISPLAY,
+// or returns nil when X11 forwarding is off.
+func (s *Session) x11Options() (*ssh.X11Options, error) {
+	if !s.forwardX11Enabled() {
+		return nil, nil
+	}
+	display := s.fs.Getenv("DISPLAY")
+	if display == "" {
+		return nil, fmt.Errorf("x11 forwarding requested but DISPLAY is not set: it needs a local X server (Xorg, XQuartz, VcXsrv) and DISPLAY pointing at it")
+	}
+	d, err := ssh.ParseDisplay(display)
+	if err != nil {
+		return nil, fmt.Errorf("x11 forwarding: %w", err)
+	}
+	fake := make([]byte, x11CookieSize)
+	if _, err := s.random.Read(fake); err != nil {
+		return nil, fmt.Errorf("x11 forwarding: generate cookie: %w", err)
+	}
+	return &ssh.X11Options{Display: d, Auth: s.xauthCookie(d), FakeCookie: fake}, nil
+}
+
+// xauthCookie reads the local X server's cookie from $XAUTHORITY or
+// ~/.Xauthority. Without one, the display is assumed to need no
+// authorization (e.g. "xhost +local:").
+func (s *Session) xauthCookie(d ssh.X11Display) ssh.X11Auth {
+	path := s.fs.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := s.fs.UserHomeDir()
+		if err != nil {
+			return ssh.X11Auth{}
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	data, err := s.fs.ReadFile(path)
+	if err != nil {
+		slog.Debug("x11 forwarding without a cookie",
+			slog.String("xauthority", path),
+			slog.String("error", err.Error()),
+		)
+		return ssh.X11Auth{}
+	}
+	hostname, _ := os.Hostname()
+	auth, ok := ssh.FindXauthCookie(data, d, hostname)
+	if !ok {
+		slog.Debug("x11 forwarding without a cookie: no entry for display",
+			slog.String("xauthority", path),
+			slog.String("display", d.Address),
+		)
+	}
+	return auth
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/config"
+	"github.com/acolita/claude-shell-mcp/internal/ssh"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakerand"
+)
+
+func newX11Session(fs *fakefs.FS, forward bool, cfg *config.Config) *Session {
+	s := NewSession("sess_x11", "ssh",
+		WithSessionFileSystem(fs),
+		WithSessionRandom(fakerand.NewSequential()),
+		WithSessionClock(fakeclock.New(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))),
+		WithConfig(cfg),
+	)
+	s.Host = "gui.example.com"
+	s.User = "dev"
+	s.ForwardX11 = forward
+	return s
+}
+
+func TestInitialize_X11WithoutDisplay(t *testing.T) {
+	sess := newX11Session(fakefs.New(), true, nil)
+
+	err := sess.Initialize()
+	if err == nil || !strings.Contains(err.Error(), "DISPLAY is not set") {
+		t.Fatalf("Initialize() error = %v, want DISPLAY is not set", err)
+	}
+}
+
+func TestX11Options_Disabled(t *testing.T) {
+	sess := newX11Session(fakefs.New(), false, config.DefaultConfig())
+	opts, err := sess.x11Options()
+	if err != nil || opts != nil {
+		t.Errorf("x11Options() = %+v, %v; want nil, nil", opts, err)
+	}
+}
+
+func TestX11Options_ServerConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Servers = []config.ServerConfig{{Name: "gui", Host: "gui.example.com", ForwardX11: true}}
+	fs := fakefs.New()
+	fs.SetEnv("DISPLAY", "localhost:10.0")
+	sess := newX11Session(fs, false, cfg)
+
+	opts, err := sess.x11Options()
+	if err != nil {
+		t.Fatalf("x11Options() error = %v", err)
+	}
+	if opts == nil {
+		t.Fatal("x11Options() = nil with forward_x11 in server config")
+	}
+	if opts.Display.Network != "tcp" || opts.Display.Address != "localhost:6010" {
+		t.Errorf("Display = %+v", opts.Display)
+	}
+	if len(opts.FakeCookie) != x11CookieSize {
+		t.Errorf("fake cookie is %d bytes, want %d", len(opts.FakeCookie), x11CookieSize)
+	}
+	if len(opts.Auth.Cookie) != 0 {
+		t.Errorf("Auth = %+v without an Xauthority file", opts.Auth)
+	}
+	if !sess.Status().ForwardX11 {
+		t.Error("Status().ForwardX11 = false")
+	}
+}
+
+func TestX11Options_XauthorityCookie(t *testing.T) {
+	var entry bytes.Buffer
+	binary.Write(&entry, binary.BigEndian, uint16(65535)) // FamilyWild
+	for _, f := range []string{"", "0", ssh.X11AuthProtocol, "realcookie"} {
+		binary.Write(&entry, binary.BigEndian, uint16(len(f)))
+		entry.WriteString(f)
+	}
+	fs := fakefs.New()
+	fs.SetEnv("DISPLAY", ":0")
+	fs.SetEnv("XAUTHORITY", "/run/user/1000/xauth")
+	fs.AddFile("/run/user/1000/xauth", entry.Bytes(), 0600)
+	sess := newX11Session(fs, true, nil)
+
+	opts, err := sess.x11Options()
+	if err != nil {
+		t.Fatalf("x11Options() error = %v", err)
+	}
+	if string(opts.Auth.Cookie) != "realcookie" {
+		t.Errorf("Auth.Cookie = %q, want realcookie", opts.Auth.Cookie)
+	}
+	if bytes.Equal(opts.FakeCookie, opts.Auth.Cookie) {
+		t.Error("the real cookie would be sent to the server")
+	}
+}
@@ -367,6 +367,35 @@ func (c *Client) PutFile(remotePath string, data []byte, perm os.FileMode) error
 	return nil
 }
 
+// PutFileFrom uploads everything read from r to a remote file without
+// holding it in memory. It returns the number of bytes written.
+func (c *Client) PutFileFrom(remotePath string, r io.Reader, perm os.FileMode) (int64, error) {
+	client, err := c.getClient()
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Unlock()
+
+	file, err := client.Create(remotePath)
+	if err != nil {
+		return 0, fmt.Errorf("create remote file: %w", err)
+	}
+	defer file.Close()
+
+	n, err := io.Copy(file, r)
+	if err != nil {
+		return n, fmt.Errorf("write remote file: %w", err)
+	}
+
+	if perm != 0 {
+		if err := file.Chmod(perm); err != nil {
+			return n, fmt.Errorf("chmod remote file: %w", err)
+		}
+	}
+
+	return n, nil
+}
+
 // GetFileStream opens a remote file for streaming reads.
 // Caller is responsible for closing the returned file.
 func (c *Client) GetFileStream(remotePath string) (*sftp.File, os.FileInfo, error) {
@@ -1,11 +1,13 @@
 package sftp
 
 import (
+	"errors"
 	"io"
 	"os"
 	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/pkg/sftp"
@@ -506,6 +508,40 @@ func TestPutFile_Connected_ZeroPerm(t *testing.T) {
 	}
 }
 
+// --- PutFileFrom on connected client ---
+
+func TestPutFileFrom_Connected(t *testing.T) {
+	client, cleanup := newConnectedClient(t)
+	defer cleanup()
+
+	data := strings.Repeat("streamed line\n", 10000)
+	n, err := client.PutFileFrom("/putfrom.txt", strings.NewReader(data), 0600)
+	if err != nil {
+		t.Fatalf("PutFileFrom should succeed: %v", err)
+	}
+	if n != int64(len(data)) {
+		t.Errorf("written: got %d, want %d", n, len(data))
+	}
+
+	got, err := client.ReadFile("/putfrom.txt")
+	if err != nil {
+		t.Fatalf("ReadFile after PutFileFrom failed: %v", err)
+	}
+	if string(got) != data {
+		t.Errorf("data mismatch: got %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestPutFileFrom_ReaderError(t *testing.T) {
+	client, cleanup := newConnectedClient(t)
+	defer cleanup()
+
+	_, err := client.PutFileFrom("/putfrom-err.txt", iotest.ErrReader(errors.New("disk gone")), 0644)
+	if err == nil || !strings.Contains(err.Error(), "disk gone") {
+		t.Errorf("PutFileFrom should surface reader error, got: %v", err)
+	}
+}
+
 // --- GetFileStream on connected client ---
 
 func TestGetFileStream_Connected_NonExistent(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realclock"
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realfs"
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realnet"
 	"github.com/acolita/claude-shell-mcp/internal/ports"
@@ -23,9 +24,14 @@ type AuthConfig struct {
 	Password      string // Password for password authentication
 	Host          string // Target host for SSH config lookup
 
+	// CertPath is an OpenSSH user certificate signed for the key at KeyPath.
+	// When set, the key is offered with the certificate instead of bare.
+	CertPath string
+
 	// Injected dependencies (optional, defaults to real implementations)
 	FS     ports.FileSystem    // File system for reading keys/config
 	Dialer ports.NetworkDialer // Network dialer for SSH agent connection
+	Clock  ports.Clock         // Clock for certificate validity checks
 }
 
 // BuildAuthMethods constructs SSH auth methods from config.
@@ -37,6 +43,9 @@ func BuildAuthMethods(cfg AuthConfig) ([]ssh.AuthMethod, error) {
 	if cfg.Dialer == nil {
 		cfg.Dialer = realnet.NewDialer()
 	}
+	if cfg.Clock == nil {
+		cfg.Clock = realclock.New()
+	}
 
 	var methods []ssh.AuthMethod
 
@@ -74,6 +83,13 @@ func trySSHAgentAuth(cfg AuthConfig, methods []ssh.AuthMethod) []ssh.AuthMethod
 
 // tryExplicitKeyAuth attempts authentication with explicitly configured key.
 func tryExplicitKeyAuth(cfg AuthConfig) (ssh.AuthMethod, error) {
+	if cfg.CertPath != "" {
+		certAuth, err := certificateAuth(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("certificate auth: %w", err)
+		}
+		return certAuth, nil
+	}
 	if cfg.KeyPath == "" {
 		return nil, nil
 	}
@@ -145,6 +161,15 @@ func sshAgentAuth(fs ports.FileSystem, dialer ports.NetworkDialer) (ssh.AuthMeth
 
 // privateKeyAuth returns a private key auth method.
 func privateKeyAuth(keyPath, passphrase string, fs ports.FileSystem) (ssh.AuthMethod, error) {
+	signer, err := loadSigner(keyPath, passphrase, fs)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.PublicKeys(signer), nil
+}
+
+// loadSigner reads and parses a private key file.
+func loadSigner(keyPath, passphrase string, fs ports.FileSystem) (ssh.Signer, error) {
 	expanded := expandPathWithFS(keyPath, fs)
 
 	keyData, err := fs.ReadFile(expanded)
@@ -161,8 +186,7 @@ func privateKeyAuth(keyPath, passphrase string, fs ports.FileSystem) (ssh.AuthMe
 	if err != nil {
 		return nil, fmt.Errorf("parse private key: %w", err)
 	}
-
-	return ssh.PublicKeys(signer), nil
+	return signer, nil
 }
 
 // BuildHostKeyCallback creates a host key callback from known_hosts.
@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrCertExpired is returned when the configured SSH certificate is past
+// its valid-before time.
+var ErrCertExpired = errors.New("cert_expired")
+
+// certificateAuth returns an auth method that presents the certificate at
+// cfg.CertPath for the private key at cfg.KeyPath. The certificate must be a
+// user certificate for that key and valid now.
+func certificateAuth(cfg AuthConfig) (ssh.AuthMethod, error) {
+	if cfg.KeyPath == "" {
+		return nil, fmt.Errorf("certificate %s needs its private key (auth.path)", cfg.CertPath)
+	}
+	signer, err := loadSigner(cfg.KeyPath, cfg.KeyPassphrase, cfg.FS)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := loadCertificate(cfg.CertPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCertificate(cfg.CertPath, cert, signer.PublicKey(), cfg.Clock.Now()); err != nil {
+		return nil, err
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("certificate signer: %w", err)
+	}
+	return ssh.PublicKeys(certSigner), nil
+}
+
+// loadCertificate reads an OpenSSH certificate file (e.g. id_ed25519-cert.pub).
+func loadCertificate(certPath string, cfg AuthConfig) (*ssh.Certificate, error) {
+	data, err := cfg.FS.ReadFile(expandPathWithFS(certPath, cfg.FS))
+	if err != nil {
+		return nil, fmt.Errorf("read certificate: %w", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %s public key, not a certificate", certPath, pub.Type())
+	}
+	return cert, nil
+}
+
+// checkCertificate checks that cert is a user certificate for key and
+// valid at now.
+func checkCertificate(certPath string, cert *ssh.Certificate, key ssh.PublicKey, now time.Time) error {
+	if cert.CertType != ssh.UserCert {
+		return fmt.Errorf("%s is a host certificate, not a user certificate", certPath)
+	}
+	if !bytes.Equal(cert.Key.Marshal(), key.Marshal()) {
+		return fmt.Errorf("certificate %s is for key %s, not the private key's %s",
+			certPath, ssh.FingerprintSHA256(cert.Key), ssh.FingerprintSHA256(key))
+	}
+	unix := uint64(now.Unix())
+	if unix < cert.ValidAfter {
+		return fmt.Errorf("cert_not_yet_valid: certificate %s is valid from %s",
+			certPath, certTime(cert.ValidAfter))
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && unix >= cert.ValidBefore {
+		return fmt.Errorf("%w: certificate %s (key id %q) expired at %s; have it re-signed",
+			ErrCertExpired, certPath, cert.KeyId, certTime(cert.ValidBefore))
+	}
+	return nil
+}
+
+// certTime formats a certificate validity bound.
+func certTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format(time.RFC3339)
+}
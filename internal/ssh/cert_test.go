@@ -0,0 +1,176 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakeclock"
+	"github.com/acolita/claude-shell-mcp/internal/testing/fakes/fakefs"
+	"github.com/acolita/claude-shell-mcp/internal/testing/mockssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+var certTestNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestCA returns a signer for a fresh user CA.
+func newTestCA(t *testing.T) gossh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := gossh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// certFixture writes a private key to /home/test/.ssh/id_ed25519 and a
+// certificate for it, signed by ca after edit adjusts it, to
+// /home/test/.ssh/id_ed25519-cert.pub.
+func certFixture(t *testing.T, ca gossh.Signer, edit func(*gossh.Certificate)) (AuthConfig, *fakefs.FS) {
+	t.Helper()
+	ffs := fakefs.New()
+	keyData := generateEd25519Key(t)
+	ffs.AddFile("/home/test/.ssh/id_ed25519", keyData, 0600)
+	signer, err := gossh.ParsePrivateKey(keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := &gossh.Certificate{
+		Key:             signer.PublicKey(),
+		KeyId:           "alice@fleet",
+		CertType:        gossh.UserCert,
+		ValidPrincipals: []string{"test"},
+		ValidAfter:      uint64(certTestNow.Add(-time.Hour).Unix()),
+		ValidBefore:     uint64(certTestNow.Add(time.Hour).Unix()),
+	}
+	if edit != nil {
+		edit(cert)
+	}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	ffs.AddFile("/home/test/.ssh/id_ed25519-cert.pub", gossh.MarshalAuthorizedKey(cert), 0644)
+
+	return AuthConfig{
+		KeyPath:  "~/.ssh/id_ed25519",
+		CertPath: "~/.ssh/id_ed25519-cert.pub",
+		FS:       ffs,
+		Dialer:   &mockDialer{},
+		Clock:    fakeclock.New(certTestNow),
+	}, ffs
+}
+
+func TestCertificateAuth_LogsIn(t *testing.T) {
+	ca := newTestCA(t)
+	server, err := mockssh.New(mockssh.WithUserCA(ca.PublicKey()))
+	if err != nil {
+		t.Fatalf("mockssh.New() error: %v", err)
+	}
+	defer server.Close()
+
+	// The server checks validity against the real clock.
+	cfg, _ := certFixture(t, ca, func(c *gossh.Certificate) {
+		c.ValidAfter = uint64(time.Now().Add(-time.Hour).Unix())
+		c.ValidBefore = uint64(time.Now().Add(time.Hour).Unix())
+	})
+	cfg.Clock = fakeclock.New(time.Now())
+
+	methods, err := BuildAuthMethods(cfg)
+	if err != nil {
+		t.Fatalf("BuildAuthMethods() error: %v", err)
+	}
+	conn, err := gossh.Dial("tcp", server.Addr(), &gossh.ClientConfig{
+		User:            "test",
+		Auth:            methods,
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("certificate login failed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestCertificateAuth_Expired(t *testing.T) {
+	cfg, _ := certFixture(t, newTestCA(t), func(c *gossh.Certificate) {
+		c.ValidBefore = uint64(certTestNow.Add(-time.Minute).Unix())
+	})
+
+	_, err := BuildAuthMethods(cfg)
+	if !errors.Is(err, ErrCertExpired) {
+		t.Fatalf("BuildAuthMethods() error = %v, want cert_expired", err)
+	}
+	for _, want := range []string{"cert_expired", "alice@fleet", "2026-03-01T11:59:00Z"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %q, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestCertificateAuth_Rejected(t *testing.T) {
+	ca := newTestCA(t)
+	tests := []struct {
+		name  string
+		setup func(t *testing.T) AuthConfig
+		want  string
+	}{
+		{"not yet valid", func(t *testing.T) AuthConfig {
+			cfg, _ := certFixture(t, ca, func(c *gossh.Certificate) {
+				c.ValidAfter = uint64(certTestNow.Add(time.Hour).Unix())
+			})
+			return cfg
+		}, "cert_not_yet_valid"},
+		{"host certificate", func(t *testing.T) AuthConfig {
+			cfg, _ := certFixture(t, ca, func(c *gossh.Certificate) { c.CertType = gossh.HostCert })
+			return cfg
+		}, "host certificate"},
+		{"other key", func(t *testing.T) AuthConfig {
+			cfg, ffs := certFixture(t, ca, nil)
+			ffs.AddFile("/home/test/.ssh/id_ed25519", generateEd25519Key(t), 0600)
+			return cfg
+		}, "is for key SHA256:"},
+		{"plain public key", func(t *testing.T) AuthConfig {
+			cfg, ffs := certFixture(t, ca, nil)
+			ffs.AddFile("/home/test/.ssh/id_ed25519-cert.pub", gossh.MarshalAuthorizedKey(ca.PublicKey()), 0644)
+			return cfg
+		}, "not a certificate"},
+		{"no private key", func(t *testing.T) AuthConfig {
+			cfg, _ := certFixture(t, ca, nil)
+			cfg.KeyPath = ""
+			return cfg
+		}, "needs its private key"},
+		{"missing file", func(t *testing.T) AuthConfig {
+			cfg, _ := certFixture(t, ca, nil)
+			cfg.CertPath = "~/.ssh/missing-cert.pub"
+			return cfg
+		}, "read certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildAuthMethods(tt.setup(t))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("BuildAuthMethods() error = %v, want one containing %q", err, tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), "certificate auth: ") || errors.Is(err, ErrCertExpired) {
+				t.Errorf("error = %q, want a certificate auth error other than cert_expired", err)
+			}
+		})
+	}
+}
+
+func TestCertificateAuth_NoExpiry(t *testing.T) {
+	cfg, _ := certFixture(t, newTestCA(t), func(c *gossh.Certificate) {
+		c.ValidBefore = gossh.CertTimeInfinity
+	})
+	if _, err := BuildAuthMethods(cfg); err != nil {
+		t.Errorf("BuildAuthMethods() error = %v for a certificate that never expires", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realclock"
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realnet"
 	"github.com/acolita/claude-shell-mcp/internal/adapters/realsshdialer"
 	"github.com/acolita/claude-shell-mcp/internal/ports"
 	"github.com/acolita/claude-shell-mcp/internal/sftp"
@@ -32,6 +33,9 @@ type Client struct {
 	// Tunnel manager (lazy initialized)
 	tunnelManager *TunnelManager
 
+	// X11 forwarder (started by the first session that requests X11)
+	x11 *x11Forwarder
+
 	// Injected dependencies
 	clock  ports.Clock
 	dialer ports.SSHDialer
@@ -48,6 +52,8 @@ type ClientOptions struct {
 	KeepaliveInterval time.Duration
 	Clock             ports.Clock
 	Dialer            ports.SSHDialer
+	BannerCallback    ssh.BannerCallback // receives the server's pre-auth banner (optional)
+	Proxy             *ProxyOptions      // connect through an HTTP CONNECT or SOCKS5 proxy (optional)
 }
 
 // DefaultClientOptions returns default client options.
@@ -89,6 +95,7 @@ func NewClient(opts ClientOptions) (*Client, error) {
 		Auth:            opts.AuthMethods,
 		HostKeyCallback: opts.HostKeyCallback,
 		Timeout:         opts.Timeout,
+		BannerCallback:  opts.BannerCallback,
 	}
 
 	clk := opts.Clock
@@ -96,6 +103,9 @@ func NewClient(opts ClientOptions) (*Client, error) {
 		clk = realclock.New()
 	}
 	dial := opts.Dialer
+	if dial == nil && opts.Proxy != nil {
+		dial = NewProxyDialer(*opts.Proxy, realnet.NewDialer())
+	}
 	if dial == nil {
 		dial = realsshdialer.New()
 	}
@@ -194,6 +204,8 @@ func (c *Client) Close() error {
 		c.tunnelManager = nil
 	}
 
+	c.x11 = nil
+
 	// Close SFTP client
 	if c.sftpClient != nil {
 		c.sftpClient.Close()
@@ -216,6 +228,17 @@ func (c *Client) IsConnected() bool {
 	return c.conn != nil
 }
 
+// ServerVersion returns the server's SSH version string (e.g.
+// "SSH-2.0-OpenSSH_9.6"), or "" if not connected.
+func (c *Client) ServerVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ""
+	}
+	return string(c.conn.ServerVersion())
+}
+
 // Host returns the target host.
 func (c *Client) Host() string {
 	return c.host
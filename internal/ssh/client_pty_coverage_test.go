@@ -787,3 +787,32 @@ func (m *mockWriteCloser) Close() error {
 	m.closed = true
 	return nil
 }
+
+func TestSSHPTY_Read_KeepsRemainderForSmallBuffer(t *testing.T) {
+	clk := fakeclock.New(time.Now())
+	pty := &SSHPTY{
+		dataCh:  make(chan []byte, 10),
+		errCh:   make(chan error, 1),
+		closeCh: make(chan struct{}),
+		clock:   clk,
+	}
+	pty.dataCh <- []byte("hello world")
+	pty.dataCh <- []byte("!")
+
+	var got []string
+	buf := make([]byte, 4)
+	for i := 0; i < 4; i++ {
+		n, err := pty.Read(buf)
+		if err != nil {
+			t.Fatalf("Read returned error: %v", err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	want := []string{"hell", "o wo", "rld", "!"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("read %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Exec runs command on its own session channel, without a PTY, copying its
+// stdout and stderr to the given writers. It returns the command's exit
+// status (128+n when killed by signal n). When ctx ends first, the command
+// is sent SIGKILL and ctx's error is returned.
+func (c *Client) Exec(ctx context.Context, command string, stdout, stderr io.Writer) (int, error) {
+	session, err := c.NewSession()
+	if err != nil {
+		return -1, err
+	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	if err := session.Start(command); err != nil {
+		return -1, fmt.Errorf("start command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
+
+	select {
+	case err := <-done:
+		return exitStatus(err)
+	case <-ctx.Done():
+		// Not every server honors signals; closing the channel ends the
+		// command's output either way.
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		<-done
+		return -1, ctx.Err()
+	}
+}
+
+// exitStatus returns the exit status reported in a session's Wait error.
+func exitStatus(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	var missing *ssh.ExitMissingError
+	if errors.As(err, &missing) {
+		return -1, fmt.Errorf("command ended without an exit status")
+	}
+	return -1, err
+}
@@ -0,0 +1,39 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/acolita/claude-shell-mcp/internal/testing/mockssh"
+)
+
+func TestClient_Exec_SeparatesStreams(t *testing.T) {
+	server, err := mockssh.New()
+	if err != nil {
+		t.Fatalf("mockssh.New() error: %v", err)
+	}
+	defer server.Close()
+	client := newTestSSHClient(t, server)
+	defer client.Close()
+
+	var stdout, stderr bytes.Buffer
+	code, err := client.Exec(context.Background(), "echo out; echo err >&2; exit 3", &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Exec() error: %v", err)
+	}
+	if code != 3 {
+		t.Errorf("exit status = %d, want 3", code)
+	}
+	if stdout.String() != "out\n" || stderr.String() != "err\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestClient_Exec_NotConnected(t *testing.T) {
+	client := &Client{}
+	var out bytes.Buffer
+	if _, err := client.Exec(context.Background(), "true", &out, &out); err == nil {
+		t.Error("Exec() on an unconnected client succeeded")
+	}
+}
@@ -0,0 +1,271 @@
+package ssh
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"golang.org/x/crypto/ssh"
+)
+
+// Proxy schemes supported by ProxyOptions.
+const (
+	ProxyHTTP   = "http"
+	ProxySOCKS5 = "socks5"
+)
+
+// ErrProxyAuth is returned when a proxy rejects the credentials or requires
+// credentials that were not configured.
+var ErrProxyAuth = errors.New("proxy authentication failed")
+
+// ProxyOptions routes an SSH connection through an HTTP CONNECT or SOCKS5
+// proxy.
+type ProxyOptions struct {
+	Scheme   string // ProxyHTTP or ProxySOCKS5
+	Address  string // host:port of the proxy
+	User     string // optional
+	Password string
+}
+
+// String returns the proxy as a URL without credentials, for logs and
+// results.
+func (p ProxyOptions) String() string {
+	return p.Scheme + "://" + p.Address
+}
+
+// ProxyDialer implements ports.SSHDialer by opening the TCP connection
+// through a proxy and running the SSH handshake over it.
+type ProxyDialer struct {
+	proxy  ProxyOptions
+	dialer ports.NetworkDialer
+}
+
+// NewProxyDialer creates a ProxyDialer that reaches the proxy with dialer.
+func NewProxyDialer(proxy ProxyOptions, dialer ports.NetworkDialer) *ProxyDialer {
+	return &ProxyDialer{proxy: proxy, dialer: dialer}
+}
+
+// Dial connects to addr through the proxy and establishes an SSH
+// connection. config.Timeout bounds the proxy and SSH handshakes.
+func (d *ProxyDialer) Dial(network, addr string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := d.dialer.Dial(network, d.proxy.Address)
+	if err != nil {
+		return nil, fmt.Errorf("dial proxy %s: %w", d.proxy, err)
+	}
+	if config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(config.Timeout))
+	}
+
+	tunnel, err := d.connect(conn, addr)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", d.proxy, err)
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(tunnel, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// connect asks the proxy on conn to open a tunnel to addr.
+func (d *ProxyDialer) connect(conn net.Conn, addr string) (net.Conn, error) {
+	switch d.proxy.Scheme {
+	case ProxyHTTP:
+		return httpConnect(conn, addr, d.proxy)
+	case ProxySOCKS5:
+		return conn, socks5Connect(conn, addr, d.proxy)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", d.proxy.Scheme)
+}
+
+// httpConnect opens a tunnel with an HTTP CONNECT request. The SSH server
+// speaks first, so bytes read past the response are kept in the returned
+// connection.
+func httpConnect(conn net.Conn, addr string, proxy ProxyOptions) (net.Conn, error) {
+	req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+	if proxy.User != "" {
+		creds := base64.StdEncoding.EncodeToString([]byte(proxy.User + ":" + proxy.Password))
+		req += "Proxy-Authorization: Basic " + creds + "\r\n"
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		return nil, fmt.Errorf("send CONNECT: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, fmt.Errorf("read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired && proxy.User == "":
+		return nil, fmt.Errorf("%w: proxy requires credentials (%s)", ErrProxyAuth, resp.Status)
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		return nil, fmt.Errorf("%w: credentials for %q rejected (%s)", ErrProxyAuth, proxy.User, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// bufferedConn reads through r, which may hold data already read from Conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// SOCKS5 protocol values (RFC 1928, RFC 1929).
+const (
+	socks5Version      = 0x05
+	socks5AuthNone     = 0x00
+	socks5AuthPassword = 0x02
+	socks5AuthNoMethod = 0xff
+	socks5CmdConnect   = 0x01
+	socks5AddrIPv4     = 0x01
+	socks5AddrDomain   = 0x03
+	socks5AddrIPv6     = 0x04
+)
+
+// socks5Replies describes the SOCKS5 reply codes.
+var socks5Replies = map[byte]string{
+	0x01: "general failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks5Connect opens a tunnel to addr with a SOCKS5 CONNECT. Host names
+// are sent to the proxy unresolved, so they resolve on its side.
+func socks5Connect(conn net.Conn, addr string, proxy ProxyOptions) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{socks5AuthNone}
+	if proxy.User != "" {
+		methods = []byte{socks5AuthPassword}
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return fmt.Errorf("send greeting: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("read greeting: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("not a SOCKS5 proxy (version %d)", reply[0])
+	}
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if err := socks5Authenticate(conn, proxy); err != nil {
+			return err
+		}
+	case socks5AuthNoMethod:
+		if proxy.User == "" {
+			return fmt.Errorf("%w: proxy requires credentials", ErrProxyAuth)
+		}
+		return fmt.Errorf("%w: proxy does not accept username/password authentication", ErrProxyAuth)
+	default:
+		return fmt.Errorf("proxy chose unsupported auth method %d", reply[1])
+	}
+
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+		req = append(append(req, socks5AddrIPv4), ip.To4()...)
+	} else if ip != nil {
+		req = append(append(req, socks5AddrIPv6), ip.To16()...)
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(append(req, socks5AddrDomain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("send CONNECT: %w", err)
+	}
+
+	// VER REP RSV ATYP, then the bound address and port.
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return fmt.Errorf("read CONNECT reply: %w", err)
+	}
+	if head[1] != 0x00 {
+		msg, ok := socks5Replies[head[1]]
+		if !ok {
+			msg = fmt.Sprintf("error %d", head[1])
+		}
+		return fmt.Errorf("CONNECT %s: %s", addr, msg)
+	}
+	var skip int
+	switch head[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len
+	case socks5AddrIPv6:
+		skip = net.IPv6len
+	case socks5AddrDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return fmt.Errorf("read CONNECT reply: %w", err)
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("CONNECT reply has unknown address type %d", head[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip+2)); err != nil {
+		return fmt.Errorf("read CONNECT reply: %w", err)
+	}
+	return nil
+}
+
+// socks5Authenticate runs the username/password sub-negotiation.
+func socks5Authenticate(conn net.Conn, proxy ProxyOptions) error {
+	if proxy.User == "" {
+		return fmt.Errorf("%w: proxy requires credentials", ErrProxyAuth)
+	}
+	if len(proxy.User) > 255 || len(proxy.Password) > 255 {
+		return fmt.Errorf("%w: username and password must be at most 255 bytes", ErrProxyAuth)
+	}
+	req := []byte{0x01, byte(len(proxy.User))}
+	req = append(req, proxy.User...)
+	req = append(req, byte(len(proxy.Password)))
+	req = append(req, proxy.Password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("send credentials: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("read auth reply: %w", err)
+	}
+	if reply[1] != 0x00 {
+		return fmt.Errorf("%w: credentials for %q rejected", ErrProxyAuth, proxy.User)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package ssh
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realnet"
+	"github.com/acolita/claude-shell-mcp/internal/testing/mockssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// startTestProxy serves one connection on a local port with handle, which
+// performs the proxy handshake and returns the upstream connection to pipe
+// to, or nil to hang up. It returns the proxy address and a channel with
+// the target each handshake asked for.
+func startTestProxy(t *testing.T, handle func(conn net.Conn, br *bufio.Reader, targets chan<- string) net.Conn) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		br := bufio.NewReader(conn)
+		upstream := handle(conn, br, targets)
+		if upstream == nil {
+			return
+		}
+		go func() {
+			io.Copy(upstream, br)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+	}()
+	return ln.Addr().String(), targets
+}
+
+// httpProxy answers CONNECT, requiring Basic credentials when user is set.
+func httpProxy(user, password string) func(net.Conn, *bufio.Reader, chan<- string) net.Conn {
+	return func(conn net.Conn, br *bufio.Reader, targets chan<- string) net.Conn {
+		req, err := http.ReadRequest(br)
+		if err != nil || req.Method != http.MethodConnect {
+			return nil
+		}
+		targets <- req.Host
+		if user != "" {
+			want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+			if req.Header.Get("Proxy-Authorization") != want {
+				io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+				return nil
+			}
+		}
+		upstream, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+			return nil
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return upstream
+	}
+}
+
+// socks5Proxy answers a SOCKS5 CONNECT, requiring username/password
+// authentication when user is set. reply overrides the CONNECT reply code.
+func socks5Proxy(user, password string, reply byte) func(net.Conn, *bufio.Reader, chan<- string) net.Conn {
+	return func(conn net.Conn, br *bufio.Reader, targets chan<- string) net.Conn {
+		head := make([]byte, 2)
+		if _, err := io.ReadFull(br, head); err != nil {
+			return nil
+		}
+		methods := make([]byte, head[1])
+		io.ReadFull(br, methods)
+		want := byte(socks5AuthNone)
+		if user != "" {
+			want = socks5AuthPassword
+		}
+		if !strings.ContainsRune(string(methods), rune(want)) {
+			conn.Write([]byte{socks5Version, socks5AuthNoMethod})
+			return nil
+		}
+		conn.Write([]byte{socks5Version, want})
+		if user != "" {
+			ver := make([]byte, 2)
+			io.ReadFull(br, ver)
+			u := make([]byte, ver[1])
+			io.ReadFull(br, u)
+			plen, _ := br.ReadByte()
+			p := make([]byte, plen)
+			io.ReadFull(br, p)
+			if string(u) != user || string(p) != password {
+				conn.Write([]byte{0x01, 0x01})
+				return nil
+			}
+			conn.Write([]byte{0x01, 0x00})
+		}
+
+		req := make([]byte, 4)
+		io.ReadFull(br, req)
+		var host string
+		switch req[3] {
+		case socks5AddrIPv4:
+			ip := make([]byte, net.IPv4len)
+			io.ReadFull(br, ip)
+			host = net.IP(ip).String()
+		case socks5AddrDomain:
+			n, _ := br.ReadByte()
+			name := make([]byte, n)
+			io.ReadFull(br, name)
+			host = string(name)
+		}
+		port := make([]byte, 2)
+		io.ReadFull(br, port)
+		target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+		targets <- target
+
+		if reply != 0 {
+			conn.Write([]byte{socks5Version, reply, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+			return nil
+		}
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			conn.Write([]byte{socks5Version, 0x05, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+			return nil
+		}
+		conn.Write([]byte{socks5Version, 0x00, 0, socks5AddrIPv4, 127, 0, 0, 1, 0x10, 0x00})
+		return upstream
+	}
+}
+
+func testSSHClientConfig() *gossh.ClientConfig {
+	return &gossh.ClientConfig{
+		User:            "test",
+		Auth:            []gossh.AuthMethod{gossh.Password("test")},
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	}
+}
+
+func startMockSSH(t *testing.T) *mockssh.Server {
+	t.Helper()
+	server, err := mockssh.New()
+	if err != nil {
+		t.Fatalf("mockssh.New() error: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestProxyDialer_HTTPConnect(t *testing.T) {
+	server := startMockSSH(t)
+	addr, targets := startTestProxy(t, httpProxy("alice", "s3cret"))
+
+	d := NewProxyDialer(ProxyOptions{Scheme: ProxyHTTP, Address: addr, User: "alice", Password: "s3cret"}, realnet.NewDialer())
+	client, err := d.Dial("tcp", server.Addr(), testSSHClientConfig())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	if got := <-targets; got != server.Addr() {
+		t.Errorf("proxy asked for %q, want %q", got, server.Addr())
+	}
+	sess, err := client.NewSession()
+	if err != nil {
+		t.Fatalf("NewSession() over the proxy error = %v", err)
+	}
+	sess.Close()
+}
+
+func TestProxyDialer_HTTPAuthRequired(t *testing.T) {
+	server := startMockSSH(t)
+	addr, _ := startTestProxy(t, httpProxy("alice", "s3cret"))
+
+	d := NewProxyDialer(ProxyOptions{Scheme: ProxyHTTP, Address: addr, User: "alice", Password: "wrong"}, realnet.NewDialer())
+	_, err := d.Dial("tcp", server.Addr(), testSSHClientConfig())
+	if !errors.Is(err, ErrProxyAuth) {
+		t.Fatalf("Dial() error = %v, want ErrProxyAuth", err)
+	}
+	if !strings.Contains(err.Error(), "http://"+addr) || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("error = %q, want the proxy named without the password", err)
+	}
+}
+
+func TestProxyDialer_SOCKS5(t *testing.T) {
+	server := startMockSSH(t)
+	addr, targets := startTestProxy(t, socks5Proxy("bob", "pw", 0))
+
+	d := NewProxyDialer(ProxyOptions{Scheme: ProxySOCKS5, Address: addr, User: "bob", Password: "pw"}, realnet.NewDialer())
+	client, err := d.Dial("tcp", server.Addr(), testSSHClientConfig())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer client.Close()
+
+	if got := <-targets; got != server.Addr() {
+		t.Errorf("proxy asked for %q, want %q", got, server.Addr())
+	}
+}
+
+func TestProxyDialer_SOCKS5Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(net.Conn, *bufio.Reader, chan<- string) net.Conn
+		user    string
+		wantErr string
+		auth    bool
+	}{
+		{"bad credentials", socks5Proxy("bob", "pw", 0), "bob", "credentials for \"bob\" rejected", true},
+		{"credentials required", socks5Proxy("bob", "pw", 0), "", "requires credentials", true},
+		{"connect refused", socks5Proxy("", "", 0x05), "", "connection refused", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := startTestProxy(t, tt.handler)
+			d := NewProxyDialer(ProxyOptions{Scheme: ProxySOCKS5, Address: addr, User: tt.user, Password: "nope"}, realnet.NewDialer())
+			_, err := d.Dial("tcp", "db.internal:22", testSSHClientConfig())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Dial() error = %v, want %q", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrProxyAuth) != tt.auth {
+				t.Errorf("errors.Is(err, ErrProxyAuth) = %v, want %v", !tt.auth, tt.auth)
+			}
+		})
+	}
+}
+
+func TestNewClient_UsesProxy(t *testing.T) {
+	server := startMockSSH(t)
+	addr, targets := startTestProxy(t, httpProxy("", ""))
+	port, _ := strconv.Atoi(server.Port())
+
+	client, err := NewClient(ClientOptions{
+		Host:        server.Host(),
+		Port:        port,
+		User:        "test",
+		AuthMethods: []gossh.AuthMethod{gossh.Password("test")},
+		Timeout:     5 * time.Second,
+		Proxy:       &ProxyOptions{Scheme: ProxyHTTP, Address: addr},
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer client.Close()
+
+	if got := <-targets; got != server.Addr() {
+		t.Errorf("proxy asked for %q, want %q", got, server.Addr())
+	}
+}
+
+func TestProxyOptions_String(t *testing.T) {
+	p := ProxyOptions{Scheme: ProxySOCKS5, Address: "proxy.corp:1080", User: "bob", Password: "pw"}
+	if got := p.String(); got != "socks5://proxy.corp:1080" {
+		t.Errorf("String() = %q, want the URL without credentials", got)
+	}
+}
@@ -25,11 +25,13 @@ type SSHPTY struct {
 	cols uint32
 
 	// Buffered reader for timeout support
-	dataCh  chan []byte   // Channel for incoming data chunks
-	errCh   chan error    // Channel for read errors
-	closeCh chan struct{} // Channel to signal close
-	closed  bool
-	closeMu sync.Mutex
+	readBufferSize int           // bytes per read from the SSH channel
+	pending        []byte        // unread remainder of the last chunk
+	dataCh         chan []byte   // Channel for incoming data chunks
+	errCh          chan error    // Channel for read errors
+	closeCh        chan struct{} // Channel to signal close
+	closed         bool
+	closeMu        sync.Mutex
 
 	// Read deadline support
 	readDeadline time.Time
@@ -39,20 +41,26 @@ type SSHPTY struct {
 	clock ports.Clock
 }
 
+// DefaultReadBufferSize is the default chunk size read from the SSH channel.
+const DefaultReadBufferSize = 4096
+
 // SSHPTYOptions configures SSH PTY allocation.
 type SSHPTYOptions struct {
-	Term string            // Terminal type (default: dumb)
-	Rows uint32            // Terminal rows (default: 24)
-	Cols uint32            // Terminal columns (default: 120)
-	Env  map[string]string // Environment variables to set
+	Term           string            // Terminal type (default: dumb)
+	Rows           uint32            // Terminal rows (default: 24)
+	Cols           uint32            // Terminal columns (default: 120)
+	Env            map[string]string // Environment variables to set
+	ReadBufferSize int               // Bytes per channel read (default: 4096)
+	X11            *X11Options       // Forward X11 to a local display (nil = off)
 }
 
 // DefaultSSHPTYOptions returns default SSH PTY options.
 func DefaultSSHPTYOptions() SSHPTYOptions {
 	return SSHPTYOptions{
-		Term: "dumb",
-		Rows: 24,
-		Cols: 120,
+		Term:           "dumb",
+		Rows:           24,
+		Cols:           120,
+		ReadBufferSize: DefaultReadBufferSize,
 		Env: map[string]string{
 			"PS1":            "$ ",
 			"PROMPT_COMMAND": "",
@@ -79,6 +87,9 @@ func NewSSHPTY(client *Client, opts SSHPTYOptions) (*SSHPTY, error) {
 	if opts.Cols == 0 {
 		opts.Cols = 120
 	}
+	if opts.ReadBufferSize <= 0 {
+		opts.ReadBufferSize = DefaultReadBufferSize
+	}
 
 	// Create new SSH session
 	session, err := client.NewSession()
@@ -105,6 +116,13 @@ func NewSSHPTY(client *Client, opts SSHPTYOptions) (*SSHPTY, error) {
 		return nil, fmt.Errorf("request pty: %w", err)
 	}
 
+	if opts.X11 != nil {
+		if err := client.requestX11(session, *opts.X11); err != nil {
+			session.Close()
+			return nil, fmt.Errorf("x11 forwarding: %w", err)
+		}
+	}
+
 	// Get stdin pipe
 	stdin, err := session.StdinPipe()
 	if err != nil {
@@ -133,10 +151,12 @@ func NewSSHPTY(client *Client, opts SSHPTYOptions) (*SSHPTY, error) {
 		term:    opts.Term,
 		rows:    opts.Rows,
 		cols:    opts.Cols,
-		dataCh:  make(chan []byte, 100), // Buffer up to 100 chunks
-		errCh:   make(chan error, 1),
-		closeCh: make(chan struct{}),
-		clock:   client.clock,
+
+		readBufferSize: opts.ReadBufferSize,
+		dataCh:         make(chan []byte, 100), // Buffer up to 100 chunks
+		errCh:          make(chan error, 1),
+		closeCh:        make(chan struct{}),
+		clock:          client.clock,
 	}
 
 	// Start background reader
@@ -147,7 +167,11 @@ func NewSSHPTY(client *Client, opts SSHPTYOptions) (*SSHPTY, error) {
 
 // backgroundReader continuously reads from stdout and sends data to the channel.
 func (p *SSHPTY) backgroundReader() {
-	buf := make([]byte, 4096)
+	size := p.readBufferSize
+	if size <= 0 {
+		size = DefaultReadBufferSize
+	}
+	buf := make([]byte, size)
 	for {
 		select {
 		case <-p.closeCh:
@@ -179,8 +203,14 @@ func (p *SSHPTY) backgroundReader() {
 	}
 }
 
-// Read reads from the PTY output with deadline support.
+// Read reads from the PTY output with deadline support. A chunk larger than
+// b is returned across several calls so no output is dropped when the
+// caller's buffer is smaller than the channel read size.
 func (p *SSHPTY) Read(b []byte) (int, error) {
+	if len(p.pending) > 0 {
+		return p.consume(b, p.pending), nil
+	}
+
 	p.deadlineMu.Lock()
 	deadline := p.readDeadline
 	p.deadlineMu.Unlock()
@@ -198,8 +228,7 @@ func (p *SSHPTY) Read(b []byte) (int, error) {
 	// Wait for data, error, or timeout
 	select {
 	case data := <-p.dataCh:
-		n := copy(b, data)
-		return n, nil
+		return p.consume(b, data), nil
 	case err := <-p.errCh:
 		return 0, err
 	case <-timeout:
@@ -207,6 +236,17 @@ func (p *SSHPTY) Read(b []byte) (int, error) {
 	}
 }
 
+// consume copies data into b and keeps any remainder for the next Read.
+func (p *SSHPTY) consume(b, data []byte) int {
+	n := copy(b, data)
+	if n < len(data) {
+		p.pending = data[n:]
+	} else {
+		p.pending = nil
+	}
+	return n
+}
+
 // timeoutError implements net.Error for timeout detection.
 type timeoutError struct{}
 
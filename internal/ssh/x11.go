@@ -0,0 +1,295 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/acolita/claude-shell-mcp/internal/adapters/realnet"
+	"github.com/acolita/claude-shell-mcp/internal/ports"
+	"golang.org/x/crypto/ssh"
+)
+
+// X11AuthProtocol is the only X authorization protocol forwarded.
+const X11AuthProtocol = "MIT-MAGIC-COOKIE-1"
+
+// X11Display is a local X server that forwarded X11 connections go to.
+type X11Display struct {
+	Host    string // "" for a local display
+	Number  string // display number, e.g. "0"
+	Screen  uint32
+	Network string // "unix" or "tcp"
+	Address string // socket path or host:port
+}
+
+// ParseDisplay parses a DISPLAY value such as ":0", ":1.0", "unix:0",
+// "localhost:10.0" or an XQuartz launchd socket ("/private/tmp/.../org.xquartz:0").
+func ParseDisplay(display string) (X11Display, error) {
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return X11Display{}, fmt.Errorf("invalid DISPLAY %q: missing display number", display)
+	}
+	host, rest := display[:i], display[i+1:]
+	number, screen, _ := strings.Cut(rest, ".")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return X11Display{}, fmt.Errorf("invalid DISPLAY %q: bad display number", display)
+	}
+	d := X11Display{Host: host, Number: number}
+	if screen != "" {
+		s, err := strconv.ParseUint(screen, 10, 32)
+		if err != nil {
+			return X11Display{}, fmt.Errorf("invalid DISPLAY %q: bad screen number", display)
+		}
+		d.Screen = uint32(s)
+	}
+
+	switch {
+	case strings.HasPrefix(host, "/"):
+		d.Network, d.Address = "unix", host+":"+number
+	case host == "" || host == "unix":
+		d.Host = ""
+		d.Network, d.Address = "unix", "/tmp/.X11-unix/X"+number
+	default:
+		d.Network, d.Address = "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n))
+	}
+	return d, nil
+}
+
+// X11Auth is the authorization the local X server expects.
+type X11Auth struct {
+	Protocol string
+	Cookie   []byte
+}
+
+// Xauthority address families (see Xauth.h).
+const (
+	xauthFamilyLocal = 256
+	xauthFamilyWild  = 65535
+)
+
+// FindXauthCookie looks up the MIT-MAGIC-COOKIE-1 for display in the
+// contents of an Xauthority file, as `xauth list $DISPLAY` would. hostname
+// is the local host name that local-display entries are keyed by; an entry
+// for another host is only used when none matches it.
+func FindXauthCookie(data []byte, display X11Display, hostname string) (X11Auth, bool) {
+	var fallback *X11Auth
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var family uint16
+		if err := binary.Read(r, binary.BigEndian, &family); err != nil {
+			break
+		}
+		fields := make([][]byte, 4) // address, number, name, data
+		for i := range fields {
+			f, err := readXauthField(r)
+			if err != nil {
+				return X11Auth{}, false
+			}
+			fields[i] = f
+		}
+		address, number, name, cookie := string(fields[0]), string(fields[1]), string(fields[2]), fields[3]
+		if name != X11AuthProtocol || (number != "" && number != display.Number) {
+			continue
+		}
+		auth := X11Auth{Protocol: name, Cookie: cookie}
+		switch {
+		case family == xauthFamilyWild:
+			return auth, true
+		case family == xauthFamilyLocal && address == xauthHost(display, hostname):
+			return auth, true
+		case family == xauthFamilyLocal && fallback == nil:
+			fallback = &auth
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return X11Auth{}, false
+}
+
+// xauthHost is the address local-family entries for display are stored under.
+func xauthHost(display X11Display, hostname string) string {
+	if display.Network == "unix" || display.Host == "localhost" {
+		return hostname
+	}
+	return display.Host
+}
+
+func readXauthField(r *bytes.Reader) ([]byte, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// X11Options enables X11 forwarding on an SSHPTY. The server is given
+// FakeCookie; each forwarded connection must present it and has it replaced
+// with Auth before reaching the local display, so the real cookie never
+// leaves this machine.
+type X11Options struct {
+	Display    X11Display
+	Auth       X11Auth // empty if the local X server needs no authorization
+	FakeCookie []byte
+	Dialer     ports.NetworkDialer // default: real network
+}
+
+// x11Forwarder relays the server's "x11" channels to the local display.
+type x11Forwarder struct {
+	opts X11Options
+}
+
+// x11Request is the payload of an "x11-req" channel request (RFC 4254 6.3.1).
+type x11Request struct {
+	SingleConnection bool
+	AuthProtocol     string
+	AuthCookie       string
+	ScreenNumber     uint32
+}
+
+// requestX11 asks the server to forward X11 connections for session. The
+// client's forwarder is started on first use and shared by every session on
+// the connection, since the SSH library accepts "x11" channels only once.
+func (c *Client) requestX11(session *ssh.Session, opts X11Options) error {
+	fwd, err := c.x11ForwarderFor(opts)
+	if err != nil {
+		return err
+	}
+	ok, err := session.SendRequest("x11-req", true, ssh.Marshal(x11Request{
+		AuthProtocol: X11AuthProtocol,
+		AuthCookie:   hex.EncodeToString(fwd.opts.FakeCookie),
+		ScreenNumber: opts.Display.Screen,
+	}))
+	if err != nil {
+		return fmt.Errorf("x11-req: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("server refused X11 forwarding (is X11Forwarding enabled in sshd_config and xauth installed?)")
+	}
+	return nil
+}
+
+func (c *Client) x11ForwarderFor(opts X11Options) (*x11Forwarder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.x11 != nil {
+		return c.x11, nil
+	}
+	if c.conn == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if len(opts.FakeCookie) == 0 {
+		return nil, fmt.Errorf("x11 forwarding needs a fake cookie")
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = realnet.NewDialer()
+	}
+	chans := c.conn.HandleChannelOpen("x11")
+	if chans == nil {
+		return nil, fmt.Errorf("x11 channels are already handled on this connection")
+	}
+	fwd := &x11Forwarder{opts: opts}
+	go fwd.serve(chans)
+	c.x11 = fwd
+	return fwd, nil
+}
+
+func (f *x11Forwarder) serve(chans <-chan ssh.NewChannel) {
+	for nc := range chans {
+		go f.handle(nc)
+	}
+}
+
+func (f *x11Forwarder) handle(nc ssh.NewChannel) {
+	local, err := f.opts.Dialer.Dial(f.opts.Display.Network, f.opts.Display.Address)
+	if err != nil {
+		slog.Warn("x11 forwarding: cannot connect to local display",
+			slog.String("address", f.opts.Display.Address),
+			slog.String("error", err.Error()),
+		)
+		nc.Reject(ssh.ConnectionFailed, "cannot connect to local X display")
+		return
+	}
+	defer local.Close()
+
+	ch, reqs, err := nc.Accept()
+	if err != nil {
+		return
+	}
+	defer ch.Close()
+	go ssh.DiscardRequests(reqs)
+
+	setup, err := rewriteX11Setup(ch, f.opts.FakeCookie, f.opts.Auth)
+	if err != nil {
+		slog.Warn("x11 forwarding: connection rejected", slog.String("error", err.Error()))
+		return
+	}
+	if _, err := local.Write(setup); err != nil {
+		return
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(local, ch)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(ch, local)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// rewriteX11Setup reads the X11 connection setup request from r, checks that
+// it carries fake, and returns it re-encoded with auth instead.
+func rewriteX11Setup(r io.Reader, fake []byte, auth X11Auth) ([]byte, error) {
+	hdr := make([]byte, 12)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("read setup: %w", err)
+	}
+	var order binary.ByteOrder
+	switch hdr[0] {
+	case 'B':
+		order = binary.BigEndian
+	case 'l':
+		order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("bad byte order %#x in setup", hdr[0])
+	}
+	nameLen, dataLen := int(order.Uint16(hdr[6:8])), int(order.Uint16(hdr[8:10]))
+	body := make([]byte, pad4(nameLen)+pad4(dataLen))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("read setup: %w", err)
+	}
+	name, data := body[:nameLen], body[pad4(nameLen):pad4(nameLen)+dataLen]
+	if string(name) != X11AuthProtocol || subtle.ConstantTimeCompare(data, fake) != 1 {
+		return nil, fmt.Errorf("wrong authentication cookie")
+	}
+
+	out := make([]byte, 12, 12+pad4(len(auth.Protocol))+pad4(len(auth.Cookie)))
+	copy(out, hdr[:6])
+	order.PutUint16(out[6:8], uint16(len(auth.Protocol)))
+	order.PutUint16(out[8:10], uint16(len(auth.Cookie)))
+	out = append(out, padded([]byte(auth.Protocol))...)
+	out = append(out, padded(auth.Cookie)...)
+	return out, nil
+}
+
+func pad4(n int) int { return (n + 3) &^ 3 }
+
+func padded(b []byte) []byte {
+	return append(append([]byte{}, b...), make([]byte, pad4(len(b))-len(b))...)
+}
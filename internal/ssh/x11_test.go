@@ -0,0 +1,142 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestParseDisplay(t *testing.T) {
+	tests := []struct {
+		display string
+		want    X11Display
+	}{
+		{":0", X11Display{Number: "0", Network: "unix", Address: "/tmp/.X11-unix/X0"}},
+		{"unix:1.2", X11Display{Number: "1", Screen: 2, Network: "unix", Address: "/tmp/.X11-unix/X1"}},
+		{"localhost:10.0", X11Display{Host: "localhost", Number: "10", Network: "tcp", Address: "localhost:6010"}},
+		{"/private/tmp/com.apple.launchd.x/org.xquartz:0", X11Display{
+			Host: "/private/tmp/com.apple.launchd.x/org.xquartz", Number: "0",
+			Network: "unix", Address: "/private/tmp/com.apple.launchd.x/org.xquartz:0",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.display, func(t *testing.T) {
+			got, err := ParseDisplay(tt.display)
+			if err != nil {
+				t.Fatalf("ParseDisplay() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDisplay() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"", "0", ":x", ":0.y"} {
+		if _, err := ParseDisplay(bad); err == nil {
+			t.Errorf("ParseDisplay(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+// xauthEntry encodes one Xauthority file entry.
+func xauthEntry(family uint16, address, number, name string, cookie []byte) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, family)
+	for _, f := range [][]byte{[]byte(address), []byte(number), []byte(name), cookie} {
+		binary.Write(&b, binary.BigEndian, uint16(len(f)))
+		b.Write(f)
+	}
+	return b.Bytes()
+}
+
+func TestFindXauthCookie(t *testing.T) {
+	display, _ := ParseDisplay(":1")
+	data := bytes.Join([][]byte{
+		xauthEntry(xauthFamilyLocal, "otherhost", "1", X11AuthProtocol, []byte("other")),
+		xauthEntry(xauthFamilyLocal, "myhost", "0", X11AuthProtocol, []byte("display0")),
+		xauthEntry(xauthFamilyLocal, "myhost", "1", "XDM-AUTHORIZATION-1", []byte("xdm")),
+		xauthEntry(xauthFamilyLocal, "myhost", "1", X11AuthProtocol, []byte("mine")),
+	}, nil)
+
+	auth, ok := FindXauthCookie(data, display, "myhost")
+	if !ok || string(auth.Cookie) != "mine" || auth.Protocol != X11AuthProtocol {
+		t.Errorf("FindXauthCookie() = %+v, %v; want the cookie for myhost:1", auth, ok)
+	}
+
+	// Without an entry for this host, another local entry is used.
+	auth, ok = FindXauthCookie(data, display, "renamed")
+	if !ok || string(auth.Cookie) != "other" {
+		t.Errorf("FindXauthCookie() fallback = %+v, %v", auth, ok)
+	}
+
+	wild := xauthEntry(xauthFamilyWild, "", "", X11AuthProtocol, []byte("any"))
+	if auth, ok := FindXauthCookie(wild, display, "myhost"); !ok || string(auth.Cookie) != "any" {
+		t.Errorf("FindXauthCookie() wildcard = %+v, %v", auth, ok)
+	}
+
+	if _, ok := FindXauthCookie(data[:5], display, "myhost"); ok {
+		t.Error("FindXauthCookie() succeeded on a truncated file")
+	}
+}
+
+// x11Setup encodes an X11 connection setup request.
+func x11Setup(order binary.ByteOrder, name string, data []byte) []byte {
+	hdr := make([]byte, 12)
+	if order == binary.BigEndian {
+		hdr[0] = 'B'
+	} else {
+		hdr[0] = 'l'
+	}
+	order.PutUint16(hdr[2:4], 11)
+	order.PutUint16(hdr[6:8], uint16(len(name)))
+	order.PutUint16(hdr[8:10], uint16(len(data)))
+	return append(append(hdr, padded([]byte(name))...), padded(data)...)
+}
+
+func TestRewriteX11Setup(t *testing.T) {
+	fake := []byte("0123456789abcdef")
+	real := X11Auth{Protocol: X11AuthProtocol, Cookie: []byte("REALCOOKIEREALCO")}
+
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			in := append(x11Setup(order, X11AuthProtocol, fake), "rest"...)
+			r := bytes.NewReader(in)
+			got, err := rewriteX11Setup(r, fake, real)
+			if err != nil {
+				t.Fatalf("rewriteX11Setup() error = %v", err)
+			}
+			if want := x11Setup(order, X11AuthProtocol, real.Cookie); !bytes.Equal(got, want) {
+				t.Errorf("rewriteX11Setup() = %q, want %q", got, want)
+			}
+			if r.Len() != len("rest") {
+				t.Errorf("read %d bytes past the setup request", len("rest")-r.Len())
+			}
+		})
+	}
+
+	t.Run("no local auth", func(t *testing.T) {
+		got, err := rewriteX11Setup(bytes.NewReader(x11Setup(binary.BigEndian, X11AuthProtocol, fake)), fake, X11Auth{})
+		if err != nil {
+			t.Fatalf("rewriteX11Setup() error = %v", err)
+		}
+		if want := x11Setup(binary.BigEndian, "", nil); !bytes.Equal(got, want) {
+			t.Errorf("rewriteX11Setup() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("wrong cookie", func(t *testing.T) {
+		_, err := rewriteX11Setup(bytes.NewReader(x11Setup(binary.BigEndian, X11AuthProtocol, []byte("guess"))), fake, real)
+		if err == nil || !strings.Contains(err.Error(), "wrong authentication cookie") {
+			t.Errorf("error = %v, want wrong cookie", err)
+		}
+	})
+
+	t.Run("bad byte order", func(t *testing.T) {
+		in := x11Setup(binary.BigEndian, X11AuthProtocol, fake)
+		in[0] = 'x'
+		if _, err := rewriteX11Setup(bytes.NewReader(in), fake, real); err == nil {
+			t.Error("expected error for bad byte order")
+		}
+	})
+}
@@ -21,11 +21,14 @@ type FS struct {
 	mu         sync.RWMutex
 	files      map[string]*fakeFile
 	dirs       map[string]bool
-	symlinks   map[string]string // target path for each symlink
+	dirModes   map[string]fs.FileMode // set by Chmod; others report 0755
+	dirTimes   map[string]time.Time   // set by Chtimes; others report the current time
+	symlinks   map[string]string      // target path for each symlink
 	homeDir    string
 	cwd        string
 	env        map[string]string
-	executable string // path returned by Executable()
+	executable string          // path returned by Executable()
+	readOnly   map[string]bool // directories new files cannot be created in
 }
 
 type fakeFile struct {
@@ -39,11 +42,14 @@ func New() *FS {
 	return &FS{
 		files:      make(map[string]*fakeFile),
 		dirs:       map[string]bool{"/": true},
+		dirModes:   make(map[string]fs.FileMode),
+		dirTimes:   make(map[string]time.Time),
 		symlinks:   make(map[string]string),
 		homeDir:    "/home/test",
 		cwd:        "/project",
 		env:        make(map[string]string),
 		executable: "/usr/local/bin/claude-shell-mcp",
+		readOnly:   make(map[string]bool),
 	}
 }
 
@@ -71,6 +77,9 @@ func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
 	defer f.mu.Unlock()
 
 	name = filepath.Clean(name)
+	if f.readOnly[filepath.Dir(name)] {
+		return &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
 
 	// Auto-create parent directories
 	dir := filepath.Dir(name)
@@ -120,8 +129,8 @@ func (f *FS) Stat(name string) (fs.FileInfo, error) {
 		return &fakeFileInfo{
 			name:    filepath.Base(name),
 			size:    0,
-			mode:    fs.ModeDir | 0755,
-			modTime: time.Now(),
+			mode:    fs.ModeDir | f.dirModeLocked(name),
+			modTime: f.dirModTimeLocked(name),
 			isDir:   true,
 		}, nil
 	}
@@ -150,7 +159,7 @@ func (f *FS) MkdirAll(path string, perm fs.FileMode) error {
 	return nil
 }
 
-// Remove removes the named file or empty directory.
+// Remove removes the named file, symlink or empty directory.
 func (f *FS) Remove(name string) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -161,6 +170,10 @@ func (f *FS) Remove(name string) error {
 		delete(f.files, name)
 		return nil
 	}
+	if _, ok := f.symlinks[name]; ok {
+		delete(f.symlinks, name)
+		return nil
+	}
 
 	if f.dirs[name] {
 		// Check if directory is empty
@@ -169,7 +182,18 @@ func (f *FS) Remove(name string) error {
 				return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
 			}
 		}
+		for path := range f.symlinks {
+			if strings.HasPrefix(path, name+"/") {
+				return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+			}
+		}
+		for dir := range f.dirs {
+			if strings.HasPrefix(dir, name+"/") {
+				return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+			}
+		}
 		delete(f.dirs, name)
+		delete(f.dirModes, name)
 		return nil
 	}
 
@@ -183,6 +207,9 @@ func (f *FS) Rename(oldpath, newpath string) error {
 
 	oldpath = filepath.Clean(oldpath)
 	newpath = filepath.Clean(newpath)
+	if f.readOnly[filepath.Dir(newpath)] {
+		return &fs.PathError{Op: "rename", Path: newpath, Err: fs.ErrPermission}
+	}
 
 	file, ok := f.files[oldpath]
 	if !ok {
@@ -200,6 +227,10 @@ func (f *FS) Chtimes(name string, atime, mtime time.Time) error {
 	defer f.mu.Unlock()
 
 	name = filepath.Clean(name)
+	if f.dirs[name] {
+		f.dirTimes[name] = mtime
+		return nil
+	}
 	file, ok := f.files[name]
 	if !ok {
 		return &fs.PathError{Op: "chtimes", Path: name, Err: fs.ErrNotExist}
@@ -209,6 +240,42 @@ func (f *FS) Chtimes(name string, atime, mtime time.Time) error {
 	return nil
 }
 
+// Chmod changes the permission bits of the named file or directory.
+func (f *FS) Chmod(name string, mode fs.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if f.dirs[name] {
+		f.dirModes[name] = mode.Perm()
+		return nil
+	}
+	file, ok := f.files[name]
+	if !ok {
+		return &fs.PathError{Op: "chmod", Path: name, Err: fs.ErrNotExist}
+	}
+	file.mode = file.mode&^fs.ModePerm | mode.Perm()
+	return nil
+}
+
+// dirModeLocked returns the permission bits of a directory (must be called
+// with lock held).
+func (f *FS) dirModeLocked(name string) fs.FileMode {
+	if mode, ok := f.dirModes[name]; ok {
+		return mode
+	}
+	return 0755
+}
+
+// dirModTimeLocked returns the modification time of a directory (must be
+// called with lock held).
+func (f *FS) dirModTimeLocked(name string) time.Time {
+	if mtime, ok := f.dirTimes[name]; ok {
+		return mtime
+	}
+	return time.Now()
+}
+
 // UserHomeDir returns the configured home directory.
 func (f *FS) UserHomeDir() (string, error) {
 	f.mu.RLock()
@@ -253,8 +320,8 @@ func (f *FS) Lstat(name string) (fs.FileInfo, error) {
 		return &fakeFileInfo{
 			name:    filepath.Base(name),
 			size:    0,
-			mode:    fs.ModeDir | 0755,
-			modTime: time.Now(),
+			mode:    fs.ModeDir | f.dirModeLocked(name),
+			modTime: f.dirModTimeLocked(name),
 			isDir:   true,
 		}, nil
 	}
@@ -309,6 +376,9 @@ func (f *FS) OpenFile(name string, flag int, perm fs.FileMode) (ports.FileHandle
 	name = filepath.Clean(name)
 
 	if flag&os.O_CREATE != 0 {
+		if f.readOnly[filepath.Dir(name)] {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
 		// Ensure parent dir exists
 		dir := filepath.Dir(name)
 		f.mkdirAllLocked(dir)
@@ -334,13 +404,17 @@ func (f *FS) OpenFile(name string, flag int, perm fs.FileMode) (ports.FileHandle
 		data = nil
 	}
 
-	return &fakeFileHandle{
+	fh := &fakeFileHandle{
 		name:   name,
 		data:   data,
 		reader: bytes.NewReader(data),
 		fs:     f,
 		flag:   flag,
-	}, nil
+	}
+	if flag&os.O_APPEND != 0 {
+		fh.reader.Seek(0, io.SeekEnd)
+	}
+	return fh, nil
 }
 
 // Symlink creates newname as a symbolic link to oldname.
@@ -366,6 +440,54 @@ func (f *FS) Readlink(name string) (string, error) {
 	return target, nil
 }
 
+// ReadDir returns the direct children of the named directory sorted by name.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	if !f.dirs[name] {
+		if _, ok := f.files[name]; ok {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []fs.DirEntry
+	for dir := range f.dirs {
+		if dir != name && filepath.Dir(dir) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(&fakeFileInfo{
+				name:    filepath.Base(dir),
+				mode:    fs.ModeDir | f.dirModeLocked(dir),
+				modTime: f.dirModTimeLocked(dir),
+				isDir:   true,
+			}))
+		}
+	}
+	for path, file := range f.files {
+		if filepath.Dir(path) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(&fakeFileInfo{
+				name:    filepath.Base(path),
+				size:    int64(len(file.data)),
+				mode:    file.mode,
+				modTime: file.modTime,
+			}))
+		}
+	}
+	for path := range f.symlinks {
+		if filepath.Dir(path) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(&fakeFileInfo{
+				name:    filepath.Base(path),
+				mode:    fs.ModeSymlink | 0777,
+				modTime: time.Now(),
+			}))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
 // Executable returns the path of the current executable.
 func (f *FS) Executable() (string, error) {
 	f.mu.RLock()
@@ -537,6 +659,16 @@ func (f *FS) SetExecutable(path string) {
 	f.executable = path
 }
 
+// SetReadOnly makes creating, writing or renaming files directly in dir
+// fail with fs.ErrPermission, like a directory owned by another user.
+func (f *FS) SetReadOnly(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dir = filepath.Clean(dir)
+	f.mkdirAllLocked(dir)
+	f.readOnly[dir] = true
+}
+
 // AddSymlink adds a symlink to the fake filesystem.
 func (f *FS) AddSymlink(name, target string) {
 	f.mu.Lock()
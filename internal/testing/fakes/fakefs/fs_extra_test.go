@@ -1,6 +1,7 @@
 package fakefs
 
 import (
+	"errors"
 	"io"
 	"io/fs"
 	"os"
@@ -47,6 +48,25 @@ func TestFS_Chtimes(t *testing.T) {
 	}
 }
 
+func TestFS_ChtimesDirectory(t *testing.T) {
+	f := New()
+	f.MkdirAll("/srv/app", 0755)
+
+	newTime := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	if err := f.Chtimes("/srv/app", newTime, newTime); err != nil {
+		t.Fatalf("Chtimes error: %v", err)
+	}
+	for _, stat := range []func(string) (fs.FileInfo, error){f.Stat, f.Lstat} {
+		info, err := stat("/srv/app")
+		if err != nil {
+			t.Fatalf("stat error: %v", err)
+		}
+		if !info.IsDir() || !info.ModTime().Equal(newTime) {
+			t.Errorf("IsDir() = %v, ModTime() = %v, want a directory from %v", info.IsDir(), info.ModTime(), newTime)
+		}
+	}
+}
+
 func TestFS_ChtimesNotExist(t *testing.T) {
 	f := New()
 
@@ -67,6 +87,57 @@ func TestFS_ChtimesNotExist(t *testing.T) {
 	}
 }
 
+func TestFS_Chmod(t *testing.T) {
+	f := New()
+	f.AddFile("/home/test/.ssh/authorized_keys", []byte("key"), 0644)
+
+	if err := f.Chmod("/home/test/.ssh/authorized_keys", 0600); err != nil {
+		t.Fatalf("Chmod file error: %v", err)
+	}
+	if err := f.Chmod("/home/test/.ssh", 0700); err != nil {
+		t.Fatalf("Chmod dir error: %v", err)
+	}
+
+	info, _ := f.Stat("/home/test/.ssh/authorized_keys")
+	if info.Mode() != 0600 {
+		t.Errorf("file Mode() = %v, want 0600", info.Mode())
+	}
+	info, _ = f.Stat("/home/test/.ssh")
+	if info.Mode() != fs.ModeDir|0700 {
+		t.Errorf("dir Mode() = %v, want drwx------", info.Mode())
+	}
+	info, _ = f.Stat("/home/test")
+	if info.Mode() != fs.ModeDir|0755 {
+		t.Errorf("untouched dir Mode() = %v, want drwxr-xr-x", info.Mode())
+	}
+}
+
+func TestFS_ReadDirSubdirectoryMode(t *testing.T) {
+	f := New()
+	f.AddFile("/srv/app/bin/run", []byte("x"), 0755)
+	_ = f.Chmod("/srv/app", 0750)
+	_ = f.Chmod("/srv/app/bin", 0700)
+
+	entries, err := f.ReadDir("/srv/app")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ReadDir = %v, %v", entries, err)
+	}
+	info, _ := entries[0].Info()
+	if info.Mode() != fs.ModeDir|0700 {
+		t.Errorf("subdirectory Mode() = %v, want its own drwx------", info.Mode())
+	}
+}
+
+func TestFS_ChmodNotExist(t *testing.T) {
+	f := New()
+
+	err := f.Chmod("/nonexistent.txt", 0600)
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || pathErr.Op != "chmod" || pathErr.Err != fs.ErrNotExist {
+		t.Errorf("Chmod error = %v, want chmod PathError with ErrNotExist", err)
+	}
+}
+
 func TestFS_UserHomeDir(t *testing.T) {
 	f := New()
 
@@ -185,6 +256,29 @@ func TestFS_RemoveDirectory(t *testing.T) {
 	}
 }
 
+func TestFS_RemoveSymlinkAndDirWithSubdir(t *testing.T) {
+	f := New()
+	f.MkdirAll("/a/b", 0755)
+	f.AddSymlink("/c/link", "/a")
+	f.MkdirAll("/c", 0755)
+
+	if err := f.Remove("/a"); err == nil {
+		t.Error("Remove should fail for a directory with a subdirectory")
+	}
+	if err := f.Remove("/c"); err == nil {
+		t.Error("Remove should fail for a directory with a symlink")
+	}
+	if err := f.Remove("/c/link"); err != nil {
+		t.Fatalf("Remove symlink error: %v", err)
+	}
+	if _, err := f.Lstat("/c/link"); err == nil {
+		t.Error("symlink should not exist after Remove")
+	}
+	if _, err := f.Stat("/a"); err != nil {
+		t.Error("symlink target should not be removed")
+	}
+}
+
 func TestFS_RemoveNonEmptyDirectory(t *testing.T) {
 	f := New()
 	f.AddFile("/mydir/file.txt", []byte("data"), 0644)
@@ -834,3 +928,26 @@ func TestFileHandle_Write_ExtendsBeyondData(t *testing.T) {
 		t.Errorf("content=%q, want 'abcdefgh'", string(data))
 	}
 }
+
+func TestFS_SetReadOnly(t *testing.T) {
+	f := New()
+	f.AddFile("/etc/hosts", []byte("x"), 0644)
+	f.SetReadOnly("/etc")
+
+	if err := f.WriteFile("/etc/new", nil, 0644); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("WriteFile error = %v, want ErrPermission", err)
+	}
+	if _, err := f.Create("/etc/new"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Create error = %v, want ErrPermission", err)
+	}
+	f.AddFile("/tmp/staged", []byte("y"), 0644)
+	if err := f.Rename("/tmp/staged", "/etc/hosts"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Rename error = %v, want ErrPermission", err)
+	}
+	if err := f.WriteFile("/etc/sub/new", nil, 0644); err != nil {
+		t.Errorf("WriteFile in a subdirectory error = %v", err)
+	}
+	if data, _ := f.ReadFile("/etc/hosts"); string(data) != "x" {
+		t.Errorf("read-only file changed to %q", data)
+	}
+}
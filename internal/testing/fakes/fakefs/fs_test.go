@@ -1,6 +1,7 @@
 package fakefs
 
 import (
+	"errors"
 	"io/fs"
 	"testing"
 )
@@ -150,3 +151,43 @@ func isNotExist(err error) bool {
 	}
 	return false
 }
+
+func TestFS_ReadDir(t *testing.T) {
+	f := New()
+	f.AddFile("/data/b.txt", []byte("bb"), 0644)
+	f.AddFile("/data/a.txt", []byte("a"), 0600)
+	f.AddFile("/data/sub/c.txt", []byte("c"), 0644)
+
+	entries, err := f.ReadDir("/data")
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := []string{"a.txt", "b.txt", "sub"}
+	if len(names) != len(want) {
+		t.Fatalf("ReadDir() names = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("ReadDir()[%d] = %q, want %q", i, names[i], want[i])
+		}
+	}
+	if !entries[2].IsDir() {
+		t.Error("sub should be a directory")
+	}
+	info, _ := entries[1].Info()
+	if info.Size() != 2 {
+		t.Errorf("b.txt size = %d, want 2", info.Size())
+	}
+
+	if _, err := f.ReadDir("/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDir(missing) error = %v, want ErrNotExist", err)
+	}
+	if _, err := f.ReadDir("/data/a.txt"); err == nil {
+		t.Error("ReadDir(file) should fail")
+	}
+}
@@ -19,6 +19,10 @@ type PTY struct {
 	readDeadline time.Time     // Current read deadline
 	blockReads   bool          // If true, Read blocks until deadline
 	readDelay    time.Duration // Artificial delay before returning data
+	readSizes    []int         // Buffer sizes passed to Read, in order
+	readErr      error         // Returned once queued responses are exhausted
+	responder    func(written string) string
+	onInterrupt  []byte // Queued as a response when Interrupt() is called
 }
 
 // New creates a new fake PTY.
@@ -54,6 +58,15 @@ func (p *PTY) SetBlockReads(block bool) *PTY {
 	return p
 }
 
+// SetReadError makes Read return err once all queued responses have been
+// read, e.g. io.EOF to simulate a dropped connection.
+func (p *PTY) SetReadError(err error) *PTY {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.readErr = err
+	return p
+}
+
 // SetReadDelay adds an artificial delay before Read returns data.
 func (p *PTY) SetReadDelay(d time.Duration) *PTY {
 	p.mu.Lock()
@@ -62,11 +75,30 @@ func (p *PTY) SetReadDelay(d time.Duration) *PTY {
 	return p
 }
 
+// SetResponder calls fn with everything written to the PTY and queues any
+// non-empty result as a response, like a shell answering its input.
+func (p *PTY) SetResponder(fn func(written string) string) *PTY {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.responder = fn
+	return p
+}
+
+// SetInterruptResponse queues data as a response whenever Interrupt is
+// called, like a command printing its cleanup messages on SIGINT.
+func (p *PTY) SetInterruptResponse(data string) *PTY {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onInterrupt = []byte(data)
+	return p
+}
+
 // Read implements io.Reader. Returns queued responses in order.
 // If blockReads is true, blocks until deadline.
 // If no responses are queued, returns io.EOF.
 func (p *PTY) Read(b []byte) (int, error) {
 	p.mu.Lock()
+	p.readSizes = append(p.readSizes, len(b))
 	blockReads := p.blockReads
 	deadline := p.readDeadline
 	delay := p.readDelay
@@ -95,17 +127,31 @@ func (p *PTY) Read(b []byte) (int, error) {
 	}
 
 	if p.responseIdx >= len(p.responses) {
+		if p.readErr != nil {
+			return 0, p.readErr
+		}
 		// No more responses - return 0 bytes (simulates no data available)
 		return 0, nil
 	}
 
+	// A response larger than b is returned across several reads.
 	response := p.responses[p.responseIdx]
-	p.responseIdx++
-
 	n := copy(b, response)
+	if n < len(response) {
+		p.responses[p.responseIdx] = response[n:]
+	} else {
+		p.responseIdx++
+	}
 	return n, nil
 }
 
+// ReadSizes returns the buffer sizes passed to Read, in call order.
+func (p *PTY) ReadSizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.readSizes...)
+}
+
 // Write implements io.Writer. Captures written data for later inspection.
 func (p *PTY) Write(b []byte) (int, error) {
 	p.mu.Lock()
@@ -115,6 +161,11 @@ func (p *PTY) Write(b []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 
+	if p.responder != nil {
+		if response := p.responder(string(b)); response != "" {
+			p.responses = append(p.responses, []byte(response))
+		}
+	}
 	return p.written.Write(b)
 }
 
@@ -128,6 +179,9 @@ func (p *PTY) Interrupt() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.interrupted = true
+	if len(p.onInterrupt) > 0 {
+		p.responses = append(p.responses, append([]byte(nil), p.onInterrupt...))
+	}
 	return nil
 }
 
@@ -189,5 +243,9 @@ func (p *PTY) Reset() *PTY {
 	p.readDeadline = time.Time{}
 	p.blockReads = false
 	p.readDelay = 0
+	p.readSizes = nil
+	p.readErr = nil
+	p.responder = nil
+	p.onInterrupt = nil
 	return p
 }
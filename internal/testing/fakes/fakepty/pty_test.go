@@ -1,6 +1,7 @@
 package fakepty
 
 import (
+	"io"
 	"testing"
 )
 
@@ -78,6 +79,20 @@ func TestFakePTY_Interrupt(t *testing.T) {
 	}
 }
 
+func TestFakePTY_InterruptResponse(t *testing.T) {
+	pty := New().SetInterruptResponse("^C\ncleaning up\n")
+
+	buf := make([]byte, 64)
+	if n, _ := pty.Read(buf); n != 0 {
+		t.Fatalf("Read before Interrupt returned %q", buf[:n])
+	}
+	pty.Interrupt()
+	n, _ := pty.Read(buf)
+	if got := string(buf[:n]); got != "^C\ncleaning up\n" {
+		t.Errorf("Read after Interrupt = %q", got)
+	}
+}
+
 func TestFakePTY_Close(t *testing.T) {
 	pty := New()
 
@@ -107,3 +122,63 @@ func TestFakePTY_Reset(t *testing.T) {
 		t.Error("Reset should clear interrupted flag")
 	}
 }
+
+func TestFakePTY_ReadSplitsLargeResponse(t *testing.T) {
+	pty := New()
+	pty.AddResponses("abcdef", "gh")
+
+	buf := make([]byte, 4)
+	var got []string
+	for i := 0; i < 3; i++ {
+		n, err := pty.Read(buf)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+
+	want := []string{"abcd", "ef", "gh"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("read %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if sizes := pty.ReadSizes(); len(sizes) != 3 || sizes[0] != 4 {
+		t.Errorf("ReadSizes = %v, want three reads of 4", sizes)
+	}
+}
+
+func TestFakePTY_ReadErrorAfterResponses(t *testing.T) {
+	pty := New()
+	pty.AddResponse("partial")
+	pty.SetReadError(io.EOF)
+
+	buf := make([]byte, 64)
+	n, err := pty.Read(buf)
+	if err != nil || string(buf[:n]) != "partial" {
+		t.Fatalf("first Read = %q, %v; want queued response", buf[:n], err)
+	}
+	if _, err := pty.Read(buf); err != io.EOF {
+		t.Errorf("second Read error = %v, want io.EOF", err)
+	}
+}
+
+func TestFakePTY_Responder(t *testing.T) {
+	pty := New().SetResponder(func(written string) string {
+		if written == "ping\n" {
+			return "pong\n"
+		}
+		return ""
+	})
+	pty.WriteString("other\n")
+	pty.WriteString("ping\n")
+
+	buf := make([]byte, 64)
+	n, _ := pty.Read(buf)
+	if string(buf[:n]) != "pong\n" {
+		t.Errorf("Read = %q, want the responder's answer", buf[:n])
+	}
+	if n, _ := pty.Read(buf); n != 0 {
+		t.Errorf("Read = %q, want nothing for the unanswered write", buf[:n])
+	}
+}
@@ -4,6 +4,7 @@ package fakesessionmgr
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/acolita/claude-shell-mcp/internal/session"
 )
@@ -17,6 +18,9 @@ func defaultListDetailedFunc(sessions map[string]*session.Session) []session.Ses
 			Mode: sess.Mode,
 			Host: sess.Host,
 			User: sess.User,
+			Tags: sess.Tags,
+
+			LastUsed: sess.LastUsed.Format(time.RFC3339),
 		})
 	}
 	return infos
@@ -31,6 +35,17 @@ type Manager struct {
 
 	// Hooks for customizing behavior
 	CreateFunc func(opts session.CreateOptions) (*session.Session, error)
+
+	// CloseFunc, if set, is called by Close for sessions that exist; an
+	// error leaves the session open.
+	CloseFunc func(id string) error
+
+	// Pool is returned by PoolStats.
+	Pool session.ConnectionPoolStats
+
+	// Conns is returned by Connections; CloseConnection removes entries
+	// from it and reports their sessions as dropped.
+	Conns []session.PooledConnection
 }
 
 // New creates a new fake Manager.
@@ -90,6 +105,11 @@ func (m *Manager) Close(id string) error {
 	if _, ok := m.sessions[id]; !ok {
 		return fmt.Errorf(errSessionNotFoundFmt, id)
 	}
+	if m.CloseFunc != nil {
+		if err := m.CloseFunc(id); err != nil {
+			return err
+		}
+	}
 
 	m.closed[id] = true
 	delete(m.sessions, id)
@@ -103,3 +123,54 @@ func (m *Manager) ListDetailed() []session.SessionInfo {
 
 	return defaultListDetailedFunc(m.sessions)
 }
+
+// PoolStats returns the configured connection pool stats.
+func (m *Manager) PoolStats() session.ConnectionPoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Pool
+}
+
+// Connections returns the configured pooled connections.
+func (m *Manager) Connections() []session.PooledConnection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Conns
+}
+
+// CloseConnection removes the connections with key from Conns and returns
+// the sessions that were using them.
+func (m *Manager) CloseConnection(key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []session.PooledConnection
+	var dropped []string
+	found := false
+	for _, c := range m.Conns {
+		if c.Key == key {
+			found = true
+			dropped = append(dropped, c.Sessions...)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	if !found {
+		return nil, fmt.Errorf("no pooled connection for %s", key)
+	}
+	m.Conns = kept
+	return dropped, nil
+}
+
+// CloseAll closes and removes every session.
+func (m *Manager) CloseAll() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, sess := range m.sessions {
+		sess.Close()
+		m.closed[id] = true
+		delete(m.sessions, id)
+	}
+	return nil
+}
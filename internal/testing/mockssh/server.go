@@ -2,6 +2,7 @@
 package mockssh
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -25,6 +26,7 @@ type Server struct {
 	addr       string
 	shell      string
 	users      map[string]string // username -> password
+	userCA     ssh.PublicKey     // CA trusted for user certificates (optional)
 	mu         sync.RWMutex
 	done       chan struct{}
 	wg         sync.WaitGroup
@@ -57,6 +59,14 @@ func WithUser(username, password string) Option {
 	}
 }
 
+// WithUserCA accepts public key logins with user certificates signed by ca
+// for the user logging in.
+func WithUserCA(ca ssh.PublicKey) Option {
+	return func(s *Server) {
+		s.userCA = ca
+	}
+}
+
 // New creates a new mock SSH server.
 func New(opts ...Option) (*Server, error) {
 	// Generate a temporary host key
@@ -94,6 +104,14 @@ func New(opts ...Option) (*Server, error) {
 			return nil, fmt.Errorf("password rejected for %q", c.User())
 		},
 	}
+	if s.userCA != nil {
+		checker := &ssh.CertChecker{
+			IsUserAuthority: func(auth ssh.PublicKey) bool {
+				return bytes.Equal(auth.Marshal(), s.userCA.Marshal())
+			},
+		}
+		config.PublicKeyCallback = checker.Authenticate
+	}
 	config.AddHostKey(signer)
 	s.config = config
 
@@ -357,14 +375,15 @@ func (s *Server) runWithPTY(sess *session, cmd *exec.Cmd, ptyReq *ptyRequest) {
 	sendExitStatus(sess.channel, exitCode)
 }
 
-// runWithoutPTY runs a command without PTY, capturing output directly.
+// runWithoutPTY runs a command without PTY, sending stdout and stderr on
+// their own streams.
 func (s *Server) runWithoutPTY(sess *session, cmd *exec.Cmd) {
-	output, err := cmd.CombinedOutput()
+	cmd.Stdout = sess.channel
+	cmd.Stderr = sess.channel.Stderr()
+	err := cmd.Run()
 	sess.cmd = cmd
 
-	exitCode := extractExitCode(err)
-	sess.channel.Write(output)
-	sendExitStatus(sess.channel, exitCode)
+	sendExitStatus(sess.channel, extractExitCode(err))
 }
 
 func (s *Server) runCommand(sess *session, name string, ptyReq *ptyRequest, args ...string) {
@@ -0,0 +1,461 @@
+// Package vt renders terminal output into text: a minimal VT100/xterm
+// screen model that applies cursor movement, erasing, scrolling and the
+// alternate screen, and ignores colors and other attributes.
+package vt
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parser states.
+const (
+	stateGround = iota
+	stateEscape
+	stateCharset // ESC ( and friends: one more byte designates a charset
+	stateCSI
+	stateString // OSC, DCS, APC, PM, SOS: skipped up to BEL or ST
+	stateStringEscape
+)
+
+// Limits on control sequence parameters, which come from whatever a
+// command prints: values are capped like xterm's, and parameter bytes past
+// maxParamBytes are dropped, so hostile sequences can neither overflow the
+// cursor arithmetic nor grow the parser's buffer.
+const (
+	maxParamValue = 65535
+	maxParamBytes = 256
+)
+
+// Screen is a terminal screen of fixed size fed with the bytes a program
+// writes to its terminal.
+type Screen struct {
+	rows, cols int
+
+	grid       [][]rune
+	x, y       int
+	wrapNext   bool // the last column was written; the next rune wraps
+	top        int  // scroll region, inclusive
+	bottom     int
+	savedX     int
+	savedY     int
+	scrollback []string
+	maxBack    int
+
+	alt           bool
+	mainGrid      [][]rune // the main screen while the alternate one is shown
+	mainX, mainY  int
+	lastAlternate []string
+
+	state  int
+	params []byte
+	utf8   []byte
+}
+
+// New returns a blank screen of rows by cols that keeps up to scrollback
+// lines scrolled off its top.
+func New(rows, cols, scrollback int) *Screen {
+	s := &Screen{rows: rows, cols: cols, maxBack: scrollback}
+	s.grid = s.blankGrid()
+	s.bottom = rows - 1
+	return s
+}
+
+// Size returns the screen's rows and columns.
+func (s *Screen) Size() (rows, cols int) {
+	return s.rows, s.cols
+}
+
+// Write feeds terminal output to the screen. It never fails.
+func (s *Screen) Write(p []byte) (int, error) {
+	for _, b := range p {
+		s.feed(b)
+	}
+	return len(p), nil
+}
+
+// Lines returns the screen as shown, without trailing blanks or blank rows
+// at the bottom.
+func (s *Screen) Lines() []string {
+	return renderGrid(s.grid)
+}
+
+// Scrollback returns the lines scrolled off the top of the main screen,
+// oldest first.
+func (s *Screen) Scrollback() []string {
+	return append([]string(nil), s.scrollback...)
+}
+
+// InAlternate reports whether a full-screen program has the alternate
+// screen up.
+func (s *Screen) InAlternate() bool {
+	return s.alt
+}
+
+// LastAlternate returns the alternate screen as it was when a full-screen
+// program last left it, i.e. the final frame of a TUI that has exited.
+func (s *Screen) LastAlternate() []string {
+	return append([]string(nil), s.lastAlternate...)
+}
+
+func (s *Screen) blankGrid() [][]rune {
+	grid := make([][]rune, s.rows)
+	for i := range grid {
+		grid[i] = s.blankRow()
+	}
+	return grid
+}
+
+func (s *Screen) blankRow() []rune {
+	row := make([]rune, s.cols)
+	for i := range row {
+		row[i] = ' '
+	}
+	return row
+}
+
+func renderGrid(grid [][]rune) []string {
+	lines := make([]string, len(grid))
+	last := -1
+	for i, row := range grid {
+		lines[i] = strings.TrimRight(string(row), " ")
+		if lines[i] != "" {
+			last = i
+		}
+	}
+	return lines[:last+1]
+}
+
+func (s *Screen) feed(b byte) {
+	switch s.state {
+	case stateEscape:
+		s.escape(b)
+		return
+	case stateCharset:
+		s.state = stateGround
+		return
+	case stateCSI:
+		s.csiByte(b)
+		return
+	case stateString:
+		switch b {
+		case 0x07:
+			s.state = stateGround
+		case 0x1b:
+			s.state = stateStringEscape
+		}
+		return
+	case stateStringEscape:
+		// ESC \ ends the string; anything else after ESC starts a new sequence.
+		s.state = stateGround
+		if b != '\\' {
+			s.feed(0x1b)
+			s.feed(b)
+		}
+		return
+	}
+
+	if len(s.utf8) > 0 || b >= 0x80 {
+		s.utf8 = append(s.utf8, b)
+		if !utf8.FullRune(s.utf8) {
+			return
+		}
+		r, _ := utf8.DecodeRune(s.utf8)
+		s.utf8 = s.utf8[:0]
+		s.print(r)
+		return
+	}
+	switch b {
+	case 0x1b:
+		s.state = stateEscape
+	case '\r':
+		s.x, s.wrapNext = 0, false
+	case '\n', '\v', '\f':
+		s.lineFeed()
+	case '\b':
+		if s.x > 0 {
+			s.x--
+		}
+		s.wrapNext = false
+	case '\t':
+		s.x = min((s.x/8+1)*8, s.cols-1)
+	default:
+		if b >= 0x20 && b != 0x7f {
+			s.print(rune(b))
+		}
+	}
+}
+
+func (s *Screen) print(r rune) {
+	if s.wrapNext {
+		s.x, s.wrapNext = 0, false
+		s.lineFeed()
+	}
+	s.grid[s.y][s.x] = r
+	if s.x == s.cols-1 {
+		s.wrapNext = true
+	} else {
+		s.x++
+	}
+}
+
+func (s *Screen) lineFeed() {
+	s.wrapNext = false
+	if s.y == s.bottom {
+		s.scrollUp(1)
+	} else if s.y < s.rows-1 {
+		s.y++
+	}
+}
+
+// scrollUp scrolls the scroll region up by n lines. Lines leaving the top
+// of the whole main screen go to the scrollback.
+func (s *Screen) scrollUp(n int) {
+	s.deleteLines(s.top, n, s.top == 0 && !s.alt)
+}
+
+// deleteLines moves the lines from row to the bottom of the scroll region
+// up by n, blanking the freed ones at the bottom; with keep, the lines
+// removed at row are added to the scrollback.
+func (s *Screen) deleteLines(row, n int, keep bool) {
+	for ; n > 0; n-- {
+		if keep && s.maxBack > 0 {
+			s.scrollback = append(s.scrollback, strings.TrimRight(string(s.grid[row]), " "))
+			if len(s.scrollback) > s.maxBack {
+				s.scrollback = s.scrollback[len(s.scrollback)-s.maxBack:]
+			}
+		}
+		copy(s.grid[row:s.bottom], s.grid[row+1:s.bottom+1])
+		s.grid[s.bottom] = s.blankRow()
+	}
+}
+
+// scrollDown scrolls the scroll region down by n lines.
+func (s *Screen) scrollDown(n int) {
+	s.insertLines(s.top, n)
+}
+
+// insertLines inserts n blank lines at row, pushing the lines below it down
+// to the bottom of the scroll region.
+func (s *Screen) insertLines(row, n int) {
+	for ; n > 0; n-- {
+		copy(s.grid[row+1:s.bottom+1], s.grid[row:s.bottom])
+		s.grid[row] = s.blankRow()
+	}
+}
+
+func (s *Screen) escape(b byte) {
+	s.state = stateGround
+	switch b {
+	case '[':
+		s.state = stateCSI
+		s.params = s.params[:0]
+	case ']', 'P', '_', '^', 'X':
+		s.state = stateString
+	case '(', ')', '*', '+', '#', '%':
+		s.state = stateCharset
+	case '7':
+		s.savedX, s.savedY = s.x, s.y
+	case '8':
+		s.x, s.y, s.wrapNext = s.savedX, s.savedY, false
+	case 'D':
+		s.lineFeed()
+	case 'E':
+		s.x = 0
+		s.lineFeed()
+	case 'M':
+		s.wrapNext = false
+		if s.y == s.top {
+			s.scrollDown(1)
+		} else if s.y > 0 {
+			s.y--
+		}
+	case 'c':
+		*s = *New(s.rows, s.cols, s.maxBack)
+	}
+}
+
+func (s *Screen) csiByte(b byte) {
+	if b >= 0x20 && b <= 0x3f {
+		if len(s.params) < maxParamBytes {
+			s.params = append(s.params, b)
+		}
+		return
+	}
+	s.state = stateGround
+	if b >= 0x40 && b <= 0x7e {
+		s.csi(b, string(s.params))
+	}
+}
+
+// csi applies a control sequence with final byte final and its parameter
+// bytes params.
+func (s *Screen) csi(final byte, params string) {
+	if strings.HasPrefix(params, ">") || strings.HasPrefix(params, "=") {
+		return // xterm extensions (modifier keys, device attributes)
+	}
+	private := strings.HasPrefix(params, "?")
+	if private {
+		params = params[1:]
+	}
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	if private {
+		if final == 'h' || final == 'l' {
+			for _, mode := range args {
+				s.privateMode(mode, final == 'h')
+			}
+		}
+		return
+	}
+
+	s.wrapNext = false
+	switch final {
+	case 'A':
+		s.y = max(s.y-arg(0, 1), 0)
+	case 'B':
+		s.y = min(s.y+arg(0, 1), s.rows-1)
+	case 'C':
+		s.x = min(s.x+arg(0, 1), s.cols-1)
+	case 'D':
+		s.x = max(s.x-arg(0, 1), 0)
+	case 'E':
+		s.x, s.y = 0, min(s.y+arg(0, 1), s.rows-1)
+	case 'F':
+		s.x, s.y = 0, max(s.y-arg(0, 1), 0)
+	case 'G', '`':
+		s.x = clamp(arg(0, 1)-1, s.cols)
+	case 'd':
+		s.y = clamp(arg(0, 1)-1, s.rows)
+	case 'H', 'f':
+		s.y, s.x = clamp(arg(0, 1)-1, s.rows), clamp(arg(1, 1)-1, s.cols)
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(arg(0, 0))
+	case 'L':
+		if s.y >= s.top && s.y <= s.bottom {
+			s.insertLines(s.y, min(arg(0, 1), s.bottom-s.y+1))
+		}
+	case 'M':
+		if s.y >= s.top && s.y <= s.bottom {
+			s.deleteLines(s.y, min(arg(0, 1), s.bottom-s.y+1), false)
+		}
+	case 'P':
+		row := s.grid[s.y]
+		n := min(arg(0, 1), s.cols-s.x)
+		copy(row[s.x:], row[s.x+n:])
+		for i := s.cols - n; i < s.cols; i++ {
+			row[i] = ' '
+		}
+	case '@':
+		row := s.grid[s.y]
+		n := min(arg(0, 1), s.cols-s.x)
+		copy(row[s.x+n:], row[s.x:])
+		for i := s.x; i < s.x+n; i++ {
+			row[i] = ' '
+		}
+	case 'X':
+		row := s.grid[s.y]
+		for i := s.x; i < min(s.x+arg(0, 1), s.cols); i++ {
+			row[i] = ' '
+		}
+	case 'S':
+		s.scrollUp(min(arg(0, 1), s.bottom-s.top+1))
+	case 'T':
+		s.scrollDown(min(arg(0, 1), s.bottom-s.top+1))
+	case 'r':
+		top, bottom := clamp(arg(0, 1)-1, s.rows), clamp(arg(1, s.rows)-1, s.rows)
+		if top < bottom {
+			s.top, s.bottom = top, bottom
+			s.x, s.y = 0, 0
+		}
+	case 's':
+		s.savedX, s.savedY = s.x, s.y
+	case 'u':
+		s.x, s.y = s.savedX, s.savedY
+	}
+}
+
+// privateMode applies DEC private mode mode; only the alternate screen
+// modes change what is shown.
+func (s *Screen) privateMode(mode int, set bool) {
+	switch mode {
+	case 47, 1047, 1049:
+		if set == s.alt {
+			return
+		}
+		if set {
+			s.mainGrid, s.mainX, s.mainY = s.grid, s.x, s.y
+			s.grid = s.blankGrid()
+			s.alt = true
+			return
+		}
+		s.lastAlternate = renderGrid(s.grid)
+		s.grid, s.alt = s.mainGrid, false
+		s.mainGrid = nil
+		if mode == 1049 {
+			s.x, s.y = s.mainX, s.mainY
+		}
+		s.wrapNext = false
+	}
+}
+
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		for y := s.y + 1; y < s.rows; y++ {
+			s.grid[y] = s.blankRow()
+		}
+	case 1:
+		s.eraseLine(1)
+		for y := 0; y < s.y; y++ {
+			s.grid[y] = s.blankRow()
+		}
+	case 2:
+		s.grid = s.blankGrid()
+	case 3:
+		s.scrollback = nil
+	}
+}
+
+func (s *Screen) eraseLine(mode int) {
+	row := s.grid[s.y]
+	from, to := s.x, s.cols
+	switch mode {
+	case 1:
+		from, to = 0, min(s.x+1, s.cols)
+	case 2:
+		from = 0
+	}
+	for i := from; i < to; i++ {
+		row[i] = ' '
+	}
+}
+
+// parseParams splits CSI parameters on ; and :, with missing ones as 0 and
+// values capped at maxParamValue.
+func parseParams(params string) []int {
+	if params == "" {
+		return nil
+	}
+	var args []int
+	for _, f := range strings.Split(strings.ReplaceAll(params, ":", ";"), ";") {
+		n, _ := strconv.Atoi(f) // out of range: the nearest int
+		args = append(args, max(0, min(n, maxParamValue)))
+	}
+	return args
+}
+
+// clamp limits a 0-based coordinate to [0, size).
+func clamp(v, size int) int {
+	return max(0, min(v, size-1))
+}
@@ -0,0 +1,187 @@
+package vt
+
+import (
+	"strings"
+	"testing"
+)
+
+func render(rows, cols int, output string) *Screen {
+	s := New(rows, cols, 100)
+	s.Write([]byte(output))
+	return s
+}
+
+func TestScreen_Text(t *testing.T) {
+	s := render(5, 20, "hello\r\nworld\r\n\x1b[1;31mred\x1b[0m text\r\n")
+	want := []string{"hello", "world", "red text"}
+	if got := s.Lines(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestScreen_Overwrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"carriage return", "progress 10%\rprogress 100%", "progress 100%"},
+		{"backspace", "abc\b\bX", "aXc"},
+		{"erase to end", "hello world\r\x1b[6C\x1b[K", "hello"},
+		{"cursor position", "\x1b[1;5Hx\x1b[1;1Hab", "ab  x"},
+		{"delete chars", "abcdef\x1b[1;2H\x1b[2P", "adef"},
+		{"insert chars", "abc\x1b[1;2H\x1b[2@", "a  bc"},
+		{"erase chars", "abcdef\x1b[1;2H\x1b[3X", "a   ef"},
+		{"tab", "a\tb", "a       b"},
+		{"utf-8", "caf\xc3\xa9 \xe2\x9c\x93", "café ✓"},
+		{"osc title", "\x1b]0;my title\x07prompt", "prompt"},
+		{"osc st", "\x1b]2;title\x1b\\prompt", "prompt"},
+		{"charset", "\x1b(Bplain", "plain"},
+		{"xterm extension", "\x1b[>4;1mtext", "text"},
+		{"nul signal", "wait\x00\x00\x00ing", "waiting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := render(3, 20, tt.output).Lines()
+			if len(lines) != 1 || lines[0] != tt.want {
+				t.Errorf("Lines() = %q, want [%q]", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestScreen_SplitSequences(t *testing.T) {
+	s := New(3, 20, 0)
+	for _, chunk := range []string{"\x1b", "[", "3", "1mre", "d\xe2", "\x9c", "\x93"} {
+		s.Write([]byte(chunk))
+	}
+	if got := s.Lines(); len(got) != 1 || got[0] != "red✓" {
+		t.Errorf("Lines() = %q, want [red✓]", got)
+	}
+}
+
+func TestScreen_WrapAndScrollback(t *testing.T) {
+	s := render(3, 5, "abcdefg\r\nl2\r\nl3\r\nl4")
+	if got := s.Lines(); strings.Join(got, "|") != "l2|l3|l4" {
+		t.Errorf("Lines() = %q", got)
+	}
+	if got := s.Scrollback(); strings.Join(got, "|") != "abcde|fg" {
+		t.Errorf("Scrollback() = %q, want the wrapped line", got)
+	}
+}
+
+func TestScreen_ScrollbackLimit(t *testing.T) {
+	s := New(2, 10, 3)
+	for i := 0; i < 10; i++ {
+		s.Write([]byte(strings.Repeat(string(rune('a'+i)), 3) + "\r\n"))
+	}
+	if got := s.Scrollback(); strings.Join(got, "|") != "ggg|hhh|iii" {
+		t.Errorf("Scrollback() = %q, want the last 3 lines", got)
+	}
+}
+
+func TestScreen_ClearScreen(t *testing.T) {
+	s := render(3, 10, "old\r\n\x1b[H\x1b[2Jnew")
+	if got := s.Lines(); len(got) != 1 || got[0] != "new" {
+		t.Errorf("Lines() = %q, want [new]", got)
+	}
+}
+
+func TestScreen_AlternateScreen(t *testing.T) {
+	s := New(4, 20, 100)
+	s.Write([]byte("$ htop\r\n"))
+	s.Write([]byte("\x1b[?1049h\x1b[H\x1b[2J  PID USER\x1b[2;1H    1 root"))
+	if !s.InAlternate() {
+		t.Fatal("InAlternate() = false after ?1049h")
+	}
+	if got := s.Lines(); strings.Join(got, "|") != "  PID USER|    1 root" {
+		t.Errorf("Lines() in the alternate screen = %q", got)
+	}
+
+	s.Write([]byte("\x1b[2;1H   42 dev \x1b[?1049l$ "))
+	if s.InAlternate() {
+		t.Error("InAlternate() = true after ?1049l")
+	}
+	if got := s.Lines(); strings.Join(got, "|") != "$ htop|$" {
+		t.Errorf("Lines() after exit = %q, want the main screen back", got)
+	}
+	if got := s.LastAlternate(); strings.Join(got, "|") != "  PID USER|   42 dev" {
+		t.Errorf("LastAlternate() = %q, want the final frame", got)
+	}
+	if len(s.Scrollback()) != 0 {
+		t.Errorf("Scrollback() = %q, want nothing from the alternate screen", s.Scrollback())
+	}
+}
+
+func TestScreen_ScrollRegion(t *testing.T) {
+	// A status line at the bottom stays put while the region above scrolls.
+	s := render(4, 10, "\x1b[4;1Hstatus\x1b[1;3r\x1b[1;1Ha\r\nb\r\nc\r\nd")
+	if got := s.Lines(); strings.Join(got, "|") != "b|c|d|status" {
+		t.Errorf("Lines() = %q", got)
+	}
+}
+
+func TestScreen_InsertDeleteLines(t *testing.T) {
+	s := render(4, 10, "1\r\n2\r\n3\r\n4\x1b[2;1H\x1b[L")
+	if got := s.Lines(); strings.Join(got, "|") != "1||2|3" {
+		t.Errorf("after insert Lines() = %q", got)
+	}
+	s.Write([]byte("\x1b[2M"))
+	if got := s.Lines(); strings.Join(got, "|") != "1|3" {
+		t.Errorf("after delete Lines() = %q", got)
+	}
+	if len(s.Scrollback()) != 0 {
+		t.Errorf("Scrollback() = %q, want deleted lines dropped", s.Scrollback())
+	}
+}
+
+func TestScreen_ReverseIndex(t *testing.T) {
+	s := render(3, 10, "a\r\nb\x1b[H\x1bMtop")
+	if got := s.Lines(); strings.Join(got, "|") != "top|a|b" {
+		t.Errorf("Lines() = %q", got)
+	}
+}
+
+func TestScreen_HostileParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"huge cursor down", "\x1b[9223372036854775807Bx", "|||x"},
+		{"beyond int", "\x1b[99999999999999999999999Cx", "         x"},
+		{"huge position", "\x1b[9223372036854775807;9223372036854775807Hx", "|||         x"},
+		{"negative", "ab\x1b[-5Dx", "ax"},
+		{"huge insert chars", "abc\x1b[1;2H\x1b[9223372036854775807@x", "ax"},
+		{"huge erase chars", "abc\x1b[1;2H\x1b[9223372036854775807Xx", "ax"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(render(4, 10, tt.output).Lines(), "|"); got != tt.want {
+				t.Errorf("Lines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScreen_HugeScrollIsBounded(t *testing.T) {
+	s := New(3, 10, 100)
+	s.Write([]byte("a\r\nb\r\nc\x1b[20000000S\x1b[20000000T\x1b[20000000L\x1b[20000000Mx"))
+	if got := strings.Join(s.Lines(), "|"); got != "|| x" {
+		t.Errorf("Lines() = %q", got)
+	}
+	if got := s.Scrollback(); len(got) != 3 {
+		t.Errorf("Scrollback() = %q, want the 3 lines scrolled off once", got)
+	}
+}
+
+func TestScreen_LongParamsDropped(t *testing.T) {
+	s := New(3, 10, 0)
+	s.Write([]byte("\x1b[" + strings.Repeat("1;", 1<<20) + "mok"))
+	if len(s.params) > maxParamBytes {
+		t.Errorf("params grew to %d bytes", len(s.params))
+	}
+	if got := s.Lines(); len(got) != 1 || got[0] != "ok" {
+		t.Errorf("Lines() = %q, want [ok]", got)
+	}
+}
@@ -0,0 +1,36 @@
+package xxhash
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSum64(t *testing.T) {
+	tests := []struct {
+		input string
+		want  uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+	for _, tt := range tests {
+		if got := Sum64([]byte(tt.input)); got != tt.want {
+			t.Errorf("Sum64(%q) = %#x, want %#x", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSum64_Lengths(t *testing.T) {
+	// Every tail length after the 32-byte stripes hashes differently.
+	data := []byte(strings.Repeat("0123456789abcdef", 8))
+	seen := make(map[uint64]int)
+	for n := 0; n <= len(data); n++ {
+		h := Sum64(data[:n])
+		if prev, ok := seen[h]; ok {
+			t.Fatalf("Sum64 of %d and %d bytes collide: %#x", prev, n, h)
+		}
+		seen[h] = n
+	}
+}